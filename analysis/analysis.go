@@ -0,0 +1,145 @@
+// Package analysis post-processes optimization results into cost breakdowns, so users can see
+// whether and why a schedule is cheaper than doing nothing, and into explanations of the limits
+// shaping the schedule. Flexibility derives the flexibility offers a schedule leaves room for,
+// Sensitivity assesses whether a schedule is safe to act upon under forecast errors.
+package analysis
+
+import (
+	"errors"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Interval is the energy and cost accounting of a single interval.
+type Interval struct {
+	Import        float64 // grid import [Wh]
+	Export        float64 // grid export [Wh]
+	ImportCost    float64 // [currency unit]
+	ExportRevenue float64 // [currency unit]
+	StoredValue   float64 // change in value of the stored energy at p_a [currency unit]
+	Baseline      float64 // net benefit without batteries, see Breakdown [currency unit]
+}
+
+// Net returns the net benefit of the interval.
+func (i Interval) Net() float64 {
+	return i.ExportRevenue - i.ImportCost + i.StoredValue
+}
+
+// Savings returns the net benefit over the baseline.
+func (i Interval) Savings() float64 {
+	return i.Net() - i.Baseline
+}
+
+// Breakdown is the cost accounting of a plan. Energy prices only are considered, demand rates,
+// peak prices, tiers, export caps and deviation charges are not.
+type Breakdown struct {
+	Intervals     []Interval
+	Import        float64 // [Wh]
+	Export        float64 // [Wh]
+	ImportCost    float64 // [currency unit]
+	ExportRevenue float64 // [currency unit]
+	StoredValue   float64 // [currency unit]
+	// Objective is the net benefit reconstructed from the intervals. It differs from the
+	// reported objective value by the stored value of the first interval and by the charges
+	// not considered.
+	Objective float64
+	// SelfConsumption is the share of generation consumed on site, zero without generation.
+	SelfConsumption float64
+	// SelfSufficiency is the share of demand not covered by grid import, zero without demand.
+	SelfSufficiency float64
+	// Baseline is the net benefit without batteries: demand is covered by generation first,
+	// then by import, surplus generation is exported up to the export limit.
+	Baseline float64
+	// Savings is the objective over the baseline.
+	Savings float64
+}
+
+// ErrMismatch is returned if the result does not match the request.
+var ErrMismatch = errors.New("result does not match request")
+
+// Analyze computes the cost breakdown of the plan res computed for req.
+func Analyze(req client.OptimizationInput, res client.OptimizationResult) (Breakdown, error) {
+	ts := req.TimeSeries
+	n := len(ts.Dt)
+
+	if len(ts.PN) != n || len(ts.PE) != n || len(res.GridImport) != n || len(res.GridExport) != n ||
+		len(res.Batteries) != len(req.Batteries) {
+		return Breakdown{}, ErrMismatch
+	}
+
+	value := func(s []float32, t int) float64 {
+		if t < len(s) {
+			return float64(s[t])
+		}
+		return 0
+	}
+
+	var b Breakdown
+	b.Intervals = make([]Interval, n)
+
+	// import beyond the limit is reported separately unless a demand rate applies
+	overshoot := req.Grid.PrcPExcImp == 0 && len(res.GridImportOvershoot) == n
+
+	var generation, demand float64
+
+	for t := range n {
+		iv := &b.Intervals[t]
+
+		iv.Import = float64(res.GridImport[t])
+		if overshoot {
+			iv.Import += float64(res.GridImportOvershoot[t])
+		}
+		iv.Export = float64(res.GridExport[t])
+		iv.ImportCost = iv.Import * float64(ts.PN[t])
+		iv.ExportRevenue = iv.Export * float64(ts.PE[t])
+
+		for i, bat := range res.Batteries {
+			// disabled batteries have zeroed series
+			if len(bat.StateOfCharge) != n || (req.Batteries[i].Enabled != nil && !*req.Batteries[i].Enabled) {
+				continue
+			}
+			prev := float64(req.Batteries[i].SInitial)
+			if t > 0 {
+				prev = float64(bat.StateOfCharge[t-1])
+			}
+			iv.StoredValue += (float64(bat.StateOfCharge[t]) - prev) * float64(req.Batteries[i].PA)
+		}
+
+		b.Import += iv.Import
+		b.Export += iv.Export
+		b.ImportCost += iv.ImportCost
+		b.ExportRevenue += iv.ExportRevenue
+		b.StoredValue += iv.StoredValue
+
+		// baseline without batteries, negative demand is uncontrolled generation
+		ft, gt := value(ts.Ft, t), value(ts.Gt, t)
+		if gt < 0 {
+			ft, gt = ft-gt, 0
+		}
+		generation += ft
+		demand += gt
+
+		if net := gt - ft; net > 0 {
+			iv.Baseline = -net * float64(ts.PN[t])
+		} else {
+			export := -net
+			if req.Grid.PMaxExp > 0 {
+				export = min(export, float64(req.Grid.PMaxExp)*float64(ts.Dt[t])/3600)
+			}
+			iv.Baseline = export * float64(ts.PE[t])
+		}
+		b.Baseline += iv.Baseline
+	}
+
+	b.Objective = b.ExportRevenue - b.ImportCost + b.StoredValue
+	b.Savings = b.Objective - b.Baseline
+
+	if generation > 0 {
+		b.SelfConsumption = max(0, 1-b.Export/generation)
+	}
+	if demand > 0 {
+		b.SelfSufficiency = max(0, 1-b.Import/demand)
+	}
+
+	return b, nil
+}
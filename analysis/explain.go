@@ -0,0 +1,242 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Limit is a constraint binding in an interval.
+type Limit string
+
+const (
+	ChargePower          Limit = "c_max"             // charging at maximum power
+	DischargePower       Limit = "d_max"             // discharging at maximum power
+	Full                 Limit = "s_max"             // state of charge at its maximum
+	Empty                Limit = "s_min"             // state of charge at its minimum
+	Unavailable          Limit = "unavailable"       // battery not available, e.g. vehicle away
+	GridChargeBlocked    Limit = "charge_from_grid"  // not charging during grid import, as charging from grid is disallowed
+	GridDischargeBlocked Limit = "discharge_to_grid" // not discharging during grid export, as discharging to grid is disallowed
+	GoalReached          Limit = "goal_reached"      // all later goals are met already
+	ImportLimit          Limit = "p_max_imp"         // grid import at its limit
+	ExportLimit          Limit = "p_max_exp"         // grid export at its limit
+	PreconditioningLoad  Limit = "preconditioning"   // vehicle preconditioning running
+)
+
+// Binding are the limits binding in an interval.
+type Binding struct {
+	Grid      []Limit
+	Batteries [][]Limit // per battery, nil for disabled batteries
+}
+
+// Violation is an interval in which a charging or discharging strategy appears not to be
+// followed. Strategies only apply to cost neutral choices, so violations are hints, not errors.
+type Violation struct {
+	Interval int
+	Battery  int
+	Strategy string
+	Reason   string
+}
+
+// Explanation reports why a plan looks the way it does.
+type Explanation struct {
+	Intervals  []Binding
+	Violations []Violation
+}
+
+// Explain reports the binding limits of the plan res computed for req per interval and the
+// intervals in which the requested strategies appear violated, e.g. for issue reports on
+// batteries not charging.
+func Explain(req client.OptimizationInput, res client.OptimizationResult) (Explanation, error) {
+	ts := req.TimeSeries
+	n := len(ts.Dt)
+
+	if len(res.GridImport) != n || len(res.GridExport) != n || len(res.Batteries) != len(req.Batteries) {
+		return Explanation{}, ErrMismatch
+	}
+
+	value := func(s []float32, t int) float64 {
+		if t < len(s) {
+			return float64(s[t])
+		}
+		return 0
+	}
+
+	// energy limit of a power in the interval [Wh]
+	energy := func(power float32, t int) float64 {
+		return float64(power) * float64(ts.Dt[t]) / 3600
+	}
+
+	// at compares with a tolerance for solver accuracy
+	at := func(v, limit float64) bool {
+		return v >= limit-max(1e-3, 1e-4*limit)
+	}
+
+	var e Explanation
+	e.Intervals = make([]Binding, n)
+
+	for t := range n {
+		b := &e.Intervals[t]
+		imp, exp := value(res.GridImport, t), value(res.GridExport, t)
+
+		if req.Grid.PMaxImp > 0 && (at(imp, energy(req.Grid.PMaxImp, t)) || value(res.GridImportOvershoot, t) > 0) {
+			b.Grid = append(b.Grid, ImportLimit)
+		}
+		if req.Grid.PMaxExp > 0 && (at(exp, energy(req.Grid.PMaxExp, t)) || value(res.GridExportOvershoot, t) > 0) {
+			b.Grid = append(b.Grid, ExportLimit)
+		}
+
+		b.Batteries = make([][]Limit, len(req.Batteries))
+
+		for i, bat := range req.Batteries {
+			r := res.Batteries[i]
+			if (bat.Enabled != nil && !*bat.Enabled) ||
+				len(r.StateOfCharge) != n || len(r.ChargingPower) != n || len(r.DischargingPower) != n {
+				continue
+			}
+
+			limits := []Limit{}
+
+			if t < len(bat.Available) && !bat.Available[t] {
+				limits = append(limits, Unavailable)
+			}
+			if value(r.PreconditioningPower, t) > 0 {
+				limits = append(limits, PreconditioningLoad)
+			}
+
+			c, d, soc := value(r.ChargingPower, t), value(r.DischargingPower, t), value(r.StateOfCharge, t)
+
+			if cMax := chargeLimit(bat); cMax > 0 && at(c, energy(cMax, t)) {
+				limits = append(limits, ChargePower)
+			}
+			if dMax := dischargeLimit(bat); dMax > 0 && at(d, energy(dMax, t)) {
+				limits = append(limits, DischargePower)
+			}
+			if at(soc, float64(bat.SMax)) {
+				limits = append(limits, Full)
+			}
+			if soc <= float64(bat.SMin)+max(1e-3, 1e-4*float64(bat.SMax)) {
+				limits = append(limits, Empty)
+			}
+			if !bat.ChargeFromGrid && imp > 0 && c < 1e-3 {
+				limits = append(limits, GridChargeBlocked)
+			}
+			if !bat.DischargeToGrid && exp > 0 && d < 1e-3 {
+				limits = append(limits, GridDischargeBlocked)
+			}
+
+			// later goals met by the state of charge before charging in this interval
+			if goal := maxGoal(bat.SGoal, t); goal > 0 {
+				prev := float64(bat.SInitial)
+				if t > 0 {
+					prev = value(r.StateOfCharge, t-1)
+				}
+				if at(prev, goal) {
+					limits = append(limits, GoalReached)
+				}
+			}
+
+			b.Batteries[i] = limits
+		}
+
+		e.Violations = append(e.Violations, violations(req, res, t, b.Batteries)...)
+	}
+
+	return e, nil
+}
+
+// violations flags batteries that could have followed the strategy in interval t but did not
+func violations(req client.OptimizationInput, res client.OptimizationResult, t int, limits [][]Limit) []Violation {
+	var v []Violation
+
+	for i, l := range limits {
+		if l == nil || slices.Contains(l, Unavailable) {
+			continue
+		}
+
+		c, d := res.Batteries[i].ChargingPower, res.Batteries[i].DischargingPower
+		if req.Strategy.ChargingStrategy == client.OptimizerStrategyChargingStrategyChargeBeforeExport && res.GridExport[t] > 0 &&
+			!slices.Contains(l, ChargePower) && !slices.Contains(l, Full) {
+			v = append(v, Violation{
+				Interval: t, Battery: i, Strategy: string(client.OptimizerStrategyChargingStrategyChargeBeforeExport),
+				Reason: fmt.Sprintf("exporting %.0f Wh while charging %.0f Wh below c_max and s_max", res.GridExport[t], c[t]),
+			})
+		}
+		if req.Strategy.DischargingStrategy == client.OptimizerStrategyDischargingStrategyDischargeBeforeImport && res.GridImport[t] > 0 &&
+			!slices.Contains(l, DischargePower) && !slices.Contains(l, Empty) && req.Batteries[i].DMax > 0 {
+			v = append(v, Violation{
+				Interval: t, Battery: i, Strategy: string(client.OptimizerStrategyDischargingStrategyDischargeBeforeImport),
+				Reason: fmt.Sprintf("importing %.0f Wh while discharging %.0f Wh below d_max and above s_min", res.GridImport[t], d[t]),
+			})
+		}
+	}
+
+	return v
+}
+
+// chargeLimit is the maximum charge power, limited by the efficiency curve if given
+func chargeLimit(bat client.BatteryConfig) float32 {
+	return curveLimit(bat.CMax, bat.CEtaCurve)
+}
+
+// dischargeLimit is the maximum discharge power, limited by the efficiency curve if given
+func dischargeLimit(bat client.BatteryConfig) float32 {
+	return curveLimit(bat.DMax, bat.DEtaCurve)
+}
+
+func curveLimit(power float32, curve []client.EfficiencyPoint) float32 {
+	if len(curve) == 0 {
+		return power
+	}
+	var peak float32
+	for _, p := range curve {
+		peak = max(peak, p.Power)
+	}
+	return min(power, peak)
+}
+
+// maxGoal is the largest goal from interval t on, zero without goals
+func maxGoal(goals []float32, t int) float64 {
+	var res float64
+	for k := t; k < len(goals); k++ {
+		res = max(res, float64(goals[k]))
+	}
+	return res
+}
+
+// String formats the explanation for issue reports, one line per interval with binding limits
+// followed by the strategy violations.
+func (e Explanation) String() string {
+	var b strings.Builder
+
+	for t, iv := range e.Intervals {
+		var parts []string
+		if len(iv.Grid) > 0 {
+			parts = append(parts, "grid: "+join(iv.Grid))
+		}
+		for i, l := range iv.Batteries {
+			if len(l) > 0 {
+				parts = append(parts, fmt.Sprintf("battery %d: %s", i, join(l)))
+			}
+		}
+		if len(parts) > 0 {
+			fmt.Fprintf(&b, "interval %d: %s\n", t, strings.Join(parts, "; "))
+		}
+	}
+
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "interval %d: battery %d violates %s: %s\n", v.Interval, v.Battery, v.Strategy, v.Reason)
+	}
+
+	return b.String()
+}
+
+func join(limits []Limit) string {
+	s := make([]string, len(limits))
+	for i, l := range limits {
+		s[i] = string(l)
+	}
+	return strings.Join(s, ", ")
+}
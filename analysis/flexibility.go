@@ -0,0 +1,163 @@
+package analysis
+
+import (
+	"math"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/units"
+)
+
+// Direction is the direction of a flexibility offer as seen from the grid.
+type Direction string
+
+const (
+	Up   Direction = "up"   // less import or more export by charging less or discharging more
+	Down Direction = "down" // more import or less export by charging more or discharging less
+)
+
+// eta is the default efficiency of the service. Deviations from the plan change the stored
+// energy by at most the deviation over eta.
+const eta = 0.95
+
+// Offer is the flexibility the site can offer in one direction from the start of an interval.
+type Offer struct {
+	Interval  int
+	Start     time.Duration // offset of the interval from the start of the plan
+	Direction Direction
+	Power     units.Power   // deviation from the plan sustainable for the duration, zero if none
+	Duration  time.Duration // duration of the intervals covering the requested duration
+}
+
+// Flexibility reports per interval the up and down flexibility the site can guarantee for
+// duration d, e.g. for capacity market or aggregator bids. The offers are deviations from the
+// plan res computed for req that keep the plan feasible without rescheduling: power limits,
+// state of charge limits, goals and grid limits hold for the remainder of the horizon if the
+// deviation is called. Charging and discharging strategies are not considered. Offers are
+// ordered by interval, up before down. Intervals without d remaining in the horizon are not
+// offered.
+//
+// No extra solves are needed, offers are derived from the plan alone. They are conservative,
+// rescheduling after a call may allow more.
+func Flexibility(req client.OptimizationInput, res client.OptimizationResult, d time.Duration) ([]Offer, error) {
+	ts := req.TimeSeries
+	n := len(ts.Dt)
+
+	if len(res.GridImport) != n || len(res.GridExport) != n || len(res.Batteries) != len(req.Batteries) {
+		return nil, ErrMismatch
+	}
+
+	value := func(s []float32, t int) float64 {
+		if t < len(s) {
+			return float64(s[t])
+		}
+		return 0
+	}
+
+	hours := func(t int) float64 {
+		return float64(ts.Dt[t]) / 3600
+	}
+
+	var (
+		offers []Offer
+		start  time.Duration
+	)
+
+	for t := range n {
+		// intervals covering d
+		end, window := t, time.Duration(0)
+		for end < n && (end == t || window < d) {
+			window += time.Duration(ts.Dt[end]) * time.Second
+			end++
+		}
+
+		if window >= d {
+			for _, dir := range []Direction{Up, Down} {
+				p := math.Inf(1)
+
+				// grid limits, the deviation first reduces the exchange in the opposite direction
+				for j := t; j < end; j++ {
+					imp, exp := value(res.GridImport, j), value(res.GridExport, j)
+					switch {
+					case dir == Up && req.Grid.PMaxExp > 0:
+						p = min(p, (imp+float64(req.Grid.PMaxExp)*hours(j)-exp)/hours(j))
+					case dir == Down && req.Grid.PMaxImp > 0:
+						p = min(p, (exp+float64(req.Grid.PMaxImp)*hours(j)-imp)/hours(j))
+					}
+				}
+
+				var batteries float64
+				for i, bat := range req.Batteries {
+					batteries += batteryFlexibility(req, bat, res.Batteries[i], dir, t, end)
+				}
+
+				offers = append(offers, Offer{
+					Interval:  t,
+					Start:     start,
+					Direction: dir,
+					Power:     units.Power(max(0, min(p, batteries))),
+					Duration:  window,
+				})
+			}
+		}
+
+		start += time.Duration(ts.Dt[t]) * time.Second
+	}
+
+	return offers, nil
+}
+
+// batteryFlexibility is the deviation from the plan in direction dir the battery can sustain
+// in the intervals from t to end [W]
+func batteryFlexibility(req client.OptimizationInput, bat client.BatteryConfig, r client.BatteryResult, dir Direction, t, end int) float64 {
+	ts := req.TimeSeries
+	n := len(ts.Dt)
+
+	if (bat.Enabled != nil && !*bat.Enabled) || len(r.StateOfCharge) != n || len(r.ChargingPower) != n || len(r.DischargingPower) != n {
+		return 0
+	}
+
+	hours := func(t int) float64 {
+		return float64(ts.Dt[t]) / 3600
+	}
+
+	p := math.Inf(1)
+
+	// power limits, the deviation first reduces the power in the opposite direction
+	for j := t; j < end; j++ {
+		if j < len(bat.Available) && !bat.Available[j] {
+			return 0
+		}
+
+		c, d := float64(r.ChargingPower[j]), float64(r.DischargingPower[j])
+		switch dir {
+		case Up:
+			p = min(p, (c+float64(dischargeLimit(bat))*hours(j)-d)/hours(j))
+		case Down:
+			p = min(p, (d+float64(chargeLimit(bat))*hours(j)-c)/hours(j))
+		}
+	}
+
+	// state of charge limits and goals for the remainder of the horizon, the deviation
+	// accumulates over the intervals called
+	var called float64
+	for j := t; j < n; j++ {
+		if j < end {
+			called += hours(j)
+		}
+
+		soc := float64(r.StateOfCharge[j])
+		switch dir {
+		case Up:
+			floor := float64(bat.SMin)
+			if j < len(bat.SGoal) {
+				floor = max(floor, float64(bat.SGoal[j]))
+			}
+			p = min(p, (soc-floor)*eta/called)
+		case Down:
+			p = min(p, (float64(bat.SMax)-soc)*eta/called)
+		}
+	}
+
+	return max(0, p)
+}
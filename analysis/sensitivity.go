@@ -0,0 +1,182 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/units"
+)
+
+// Decision is the net battery action of the first hour of a plan.
+type Decision string
+
+const (
+	Charge    Decision = "charge"
+	Discharge Decision = "discharge"
+	Idle      Decision = "idle"
+)
+
+// idleEnergy is the net energy of the first hour below which a battery counts as idle [Wh]
+const idleEnergy = 10
+
+// Perturbation are the input errors sampled by Sensitivity.
+type Perturbation struct {
+	// PV scales the generation forecast by a factor drawn uniformly from 1±PV per sample,
+	// e.g. 0.2 for ±20%. Forecast errors persist, so the factor applies to the whole horizon.
+	PV float64
+	// Price shifts import and export prices by an offset drawn uniformly from ±Price per
+	// interval, e.g. 5 * units.PerKWh.
+	Price units.Price
+	// Demand multiplies the demand of each interval by normal noise with mean 1 and standard
+	// deviation Demand, e.g. 0.1. Demand does not become negative.
+	Demand float64
+}
+
+// BatteryStability is the agreement of the first hour decisions of a battery.
+type BatteryStability struct {
+	Decision  Decision         // decision of the unperturbed plan
+	Counts    map[Decision]int // decisions of the samples solved
+	Agreement float64          // share of the samples solved deciding like the unperturbed plan
+}
+
+// Stability reports how stable the first hour decisions of a plan are under input errors.
+type Stability struct {
+	Batteries []BatteryStability // per battery, idle for disabled batteries
+	// Agreement is the share of the samples solved deciding like the unperturbed plan for
+	// all batteries.
+	Agreement      float64
+	Solved, Failed int // samples
+}
+
+// ErrNominal is returned if the unperturbed request cannot be solved.
+var ErrNominal = errors.New("unperturbed request not solved")
+
+// Sensitivity solves req and m samples of req with inputs perturbed by p using c and reports
+// how often the samples agree with the first hour decisions of the unperturbed plan. A high
+// agreement means the decisions are safe to act upon even if the forecasts are unreliable.
+// Samples are reproducible for the same random source.
+func Sensitivity(ctx context.Context, c *client.ClientWithResponses, r *rand.Rand, req client.OptimizationInput, p Perturbation, m int,
+	opts ...client.BatchOption,
+) (Stability, error) {
+	reqs := append([]client.OptimizationInput{req}, Perturb(r, req, p, m)...)
+	res := c.OptimizeBatch(ctx, reqs, opts...)
+
+	if res[0].Err != nil {
+		return Stability{}, errors.Join(ErrNominal, res[0].Err)
+	}
+
+	nominal := Decisions(req, *res[0].Result)
+
+	s := Stability{Batteries: make([]BatteryStability, len(nominal))}
+	for i, d := range nominal {
+		s.Batteries[i] = BatteryStability{Decision: d, Counts: make(map[Decision]int)}
+	}
+
+	var agreed int
+	for _, br := range res[1:] {
+		if br.Err != nil {
+			s.Failed++
+			continue
+		}
+		s.Solved++
+
+		all := true
+		for i, d := range Decisions(req, *br.Result) {
+			s.Batteries[i].Counts[d]++
+			all = all && d == nominal[i]
+		}
+		if all {
+			agreed++
+		}
+	}
+
+	if s.Solved == 0 {
+		return s, nil
+	}
+
+	s.Agreement = float64(agreed) / float64(s.Solved)
+	for i := range s.Batteries {
+		b := &s.Batteries[i]
+		b.Agreement = float64(b.Counts[b.Decision]) / float64(s.Solved)
+	}
+
+	return s, nil
+}
+
+// Perturb returns m copies of req with inputs perturbed by p, e.g. for solving with
+// client.OptimizeBatch. Series omitted from req are left empty.
+func Perturb(r *rand.Rand, req client.OptimizationInput, p Perturbation, m int) []client.OptimizationInput {
+	ts := req.TimeSeries
+	res := make([]client.OptimizationInput, m)
+
+	for k := range res {
+		pv := float32(1 + p.PV*(2*r.Float64()-1))
+
+		ft := make([]float32, len(ts.Ft))
+		for t, v := range ts.Ft {
+			ft[t] = v * pv
+		}
+
+		gt := make([]float32, len(ts.Gt))
+		for t, v := range ts.Gt {
+			gt[t] = v * float32(max(0, 1+p.Demand*r.NormFloat64()))
+		}
+
+		pn := make([]float32, len(ts.PN))
+		pe := make([]float32, len(ts.PE))
+		for t := range max(len(pn), len(pe)) {
+			offset := float32(p.Price.PerWh() * (2*r.Float64() - 1))
+			if t < len(pn) {
+				pn[t] = ts.PN[t] + offset
+			}
+			if t < len(pe) {
+				pe[t] = ts.PE[t] + offset
+			}
+		}
+
+		res[k] = req
+		res[k].TimeSeries.Ft = ft
+		res[k].TimeSeries.Gt = gt
+		res[k].TimeSeries.PN = pn
+		res[k].TimeSeries.PE = pe
+	}
+
+	return res
+}
+
+// Decisions returns the first hour decision per battery of the plan res computed for req. The
+// first hour are the intervals starting within it, at least the first interval.
+func Decisions(req client.OptimizationInput, res client.OptimizationResult) []Decision {
+	decisions := make([]Decision, len(req.Batteries))
+
+	for i := range decisions {
+		decisions[i] = Idle
+		if i >= len(res.Batteries) {
+			continue
+		}
+
+		b := res.Batteries[i]
+
+		var net float64
+		for t, elapsed := 0, 0; t < len(req.TimeSeries.Dt) && (t == 0 || elapsed < 3600); t++ {
+			if t < len(b.ChargingPower) {
+				net += float64(b.ChargingPower[t])
+			}
+			if t < len(b.DischargingPower) {
+				net -= float64(b.DischargingPower[t])
+			}
+			elapsed += req.TimeSeries.Dt[t]
+		}
+
+		switch {
+		case net > idleEnergy:
+			decisions[i] = Charge
+		case net < -idleEnergy:
+			decisions[i] = Discharge
+		}
+	}
+
+	return decisions
+}
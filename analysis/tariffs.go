@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/tariff"
+)
+
+// Solver solves an optimization problem, e.g. *client.ClientWithResponses.
+type Solver interface {
+	Solve(ctx context.Context, req client.OptimizationInput, reqEditors ...client.RequestEditorFn) (*client.OptimizationResult, error)
+}
+
+// Problem is a historical optimization problem, ideally with the measured demand and generation
+// instead of their forecasts.
+type Problem struct {
+	Start   time.Time // start of the first interval
+	Request client.OptimizationInput
+}
+
+// Tariff is an alternative tariff definition. Apply returns the problem's request under the
+// tariff, e.g. with replaced prices and grid charges.
+type Tariff struct {
+	Name  string
+	Apply func(p Problem) (client.OptimizationInput, error)
+}
+
+// FlatTariff is a tariff with fixed import and export prices [currency unit/Wh]. Grid charges
+// like demand rates or peak prices of the problems are kept.
+func FlatTariff(name string, importPrice, exportPrice float32) Tariff {
+	return Tariff{
+		Name: name,
+		Apply: func(p Problem) (client.OptimizationInput, error) {
+			req := p.Request
+			n := len(req.TimeSeries.Dt)
+			req.TimeSeries.PN = slices.Repeat([]float32{importPrice}, n)
+			req.TimeSeries.PE = slices.Repeat([]float32{exportPrice}, n)
+			return req, nil
+		},
+	}
+}
+
+// RatesTariff is a tariff with import and export prices given as rates, e.g. a time of use or
+// dynamic tariff. Rates must cover the problems. Grid charges of the problems are kept.
+func RatesTariff(name string, imp, exp tariff.Rates) Tariff {
+	return Tariff{
+		Name: name,
+		Apply: func(p Problem) (client.OptimizationInput, error) {
+			req := p.Request
+
+			pn, err := imp.Series(p.Start, req.TimeSeries.Dt)
+			if err != nil {
+				return req, fmt.Errorf("import: %w", err)
+			}
+			pe, err := exp.Series(p.Start, req.TimeSeries.Dt)
+			if err != nil {
+				return req, fmt.Errorf("export: %w", err)
+			}
+
+			req.TimeSeries.PN, req.TimeSeries.PE = pn, pe
+			return req, nil
+		},
+	}
+}
+
+// TariffCost is the cost of all problems under a tariff.
+type TariffCost struct {
+	Name string
+	// Cost is the negated sum of the objective values, i.e. the net cost including grid charges
+	// and the change in value of the stored energy [currency unit].
+	Cost float64
+}
+
+// CompareTariffs optimizes all problems under each tariff and returns the tariffs by ascending
+// cost, the first being the cheapest given the flexibility of the batteries. Problems are
+// optimized independently, the state of charge is not carried over between them.
+func CompareTariffs(ctx context.Context, solver Solver, problems []Problem, tariffs []Tariff) ([]TariffCost, error) {
+	res := make([]TariffCost, 0, len(tariffs))
+
+	for _, tf := range tariffs {
+		tc := TariffCost{Name: tf.Name}
+
+		for i, p := range problems {
+			req, err := tf.Apply(p)
+			if err != nil {
+				return nil, fmt.Errorf("%s: problem %d: %w", tf.Name, i, err)
+			}
+
+			r, err := solver.Solve(ctx, req)
+			if err != nil {
+				return nil, fmt.Errorf("%s: problem %d: %w", tf.Name, i, err)
+			}
+			if r.Status != client.Optimal {
+				return nil, fmt.Errorf("%s: problem %d: status %s", tf.Name, i, r.Status)
+			}
+
+			tc.Cost -= float64(r.ObjectiveValue)
+		}
+
+		res = append(res, tc)
+	}
+
+	slices.SortStableFunc(res, func(a, b TariffCost) int {
+		return cmp.Compare(a.Cost, b.Cost)
+	})
+
+	return res, nil
+}
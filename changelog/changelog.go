@@ -0,0 +1,274 @@
+// Package changelog records the revisions of the plan of a site, what triggered each
+// re-optimization and how its key figures changed, so that operators can tell why the plan
+// changed at 14:32 without digging through archived payloads:
+//
+//	l := changelog.New(backend, changelog.WithCipher(cph), changelog.WithRetention(30*24*time.Hour))
+//	go scheduler.Run(ctx)
+//	err := l.Run(ctx, scheduler.Updates())
+//	revs, err := l.List(ctx, time.Now().Add(-24*time.Hour))
+//
+// The figures of a revision cover the period it shares with the previous plan, from its start to
+// the end of the shorter horizon, so that the deltas compare like with like. Revisions are kept
+// in an append-only log of a storage backend, sealed by the cipher of WithCipher and removed
+// once older than the retention of WithRetention.
+package changelog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/crypt"
+	"github.com/evcc-io/optimizer/integrations/notify"
+	"github.com/evcc-io/optimizer/schedule"
+	"github.com/evcc-io/optimizer/storage"
+)
+
+// revisionsLog is the log of the revisions
+const revisionsLog = "revisions"
+
+// readPage is the number of entries read at once when listing revisions
+const readPage = 100
+
+// Figures are the planned key figures of a period.
+type Figures struct {
+	Import    float64 `json:"import"`    // grid import [Wh]
+	Export    float64 `json:"export"`    // grid export [Wh]
+	Charge    float64 `json:"charge"`    // battery charge [Wh]
+	Discharge float64 `json:"discharge"` // battery discharge [Wh]
+	// Cost is the import cost less the export revenue [currency unit].
+	Cost float64 `json:"cost"`
+	// Savings is the net benefit over the plan without batteries [currency unit].
+	Savings float64 `json:"savings"`
+}
+
+func figures(t notify.Totals) Figures {
+	return Figures{Import: t.Import, Export: t.Export, Charge: t.Charge, Discharge: t.Discharge, Cost: t.Cost, Savings: t.Savings}
+}
+
+// sub returns f less o
+func (f Figures) sub(o Figures) Figures {
+	return Figures{
+		Import:    f.Import - o.Import,
+		Export:    f.Export - o.Export,
+		Charge:    f.Charge - o.Charge,
+		Discharge: f.Discharge - o.Discharge,
+		Cost:      f.Cost - o.Cost,
+		Savings:   f.Savings - o.Savings,
+	}
+}
+
+// Revision is a recorded plan revision.
+type Revision struct {
+	// Seq is assigned when recording, starting at 1.
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// Start of the plan.
+	Start   time.Time                       `json:"start"`
+	Trigger schedule.Trigger                `json:"trigger,omitempty"`
+	Reason  string                          `json:"reason,omitempty"`
+	Status  client.OptimizationResultStatus `json:"status"`
+	// Until is the end of the period of the figures.
+	Until   time.Time `json:"until"`
+	Figures Figures   `json:"figures"`
+	// Delta are the figures less those of the previous plan over the same period, nil for the
+	// first revision or if the plans do not overlap.
+	Delta *Figures `json:"delta,omitempty"`
+}
+
+// Log records and lists revisions. It is safe for concurrent use.
+type Log struct {
+	backend   storage.Backend
+	cipher    *crypt.Cipher
+	clock     clock.Clock
+	logger    *slog.Logger
+	retention time.Duration
+}
+
+// Option configures a log.
+type Option func(*Log)
+
+// WithCipher seals the recorded revisions, e.g. with the cipher of crypt.FromEnv. Defaults to
+// no encryption.
+func WithCipher(c *crypt.Cipher) Option {
+	return func(l *Log) {
+		l.cipher = c
+	}
+}
+
+// WithClock sets the clock timing the recorded revisions. Defaults to the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(l *Log) {
+		l.clock = clk
+	}
+}
+
+// WithLogger sets the logger for failed records of Run. Defaults to slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(l *Log) {
+		l.logger = logger
+	}
+}
+
+// WithRetention removes revisions recorded more than d ago when recording. Defaults to keeping
+// all revisions.
+func WithRetention(d time.Duration) Option {
+	return func(l *Log) {
+		l.retention = d
+	}
+}
+
+// New creates a log keeping revisions in backend.
+func New(backend storage.Backend, opts ...Option) *Log {
+	l := &Log{backend: backend, clock: clock.Real, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Revise returns the revision of next over prev, which is nil for the first plan.
+func Revise(prev *schedule.Schedule, next schedule.Schedule) (Revision, error) {
+	rev := Revision{
+		Start:   next.Start,
+		Trigger: next.Trigger,
+		Reason:  next.Reason,
+		Status:  next.Result.Status,
+	}
+
+	// figures of the whole plan if there is no common period
+	overlap := prev != nil && end(*prev).After(next.Start)
+
+	to := end(next)
+	if overlap && end(*prev).Before(to) {
+		to = end(*prev)
+	}
+
+	t, err := notify.Summarize(next, next.Start, to)
+	if err != nil {
+		return Revision{}, err
+	}
+	rev.Until, rev.Figures = t.Until, figures(t)
+
+	if overlap {
+		p, err := notify.Summarize(*prev, next.Start, to)
+		if err != nil {
+			return Revision{}, err
+		}
+
+		delta := rev.Figures.sub(figures(p))
+		rev.Delta = &delta
+	}
+
+	return rev, nil
+}
+
+// end returns the end of the horizon of sched
+func end(sched schedule.Schedule) time.Time {
+	res := sched.Start
+	for _, d := range sched.Request.TimeSeries.Dt {
+		res = res.Add(time.Duration(d) * time.Second)
+	}
+	return res
+}
+
+// Record stores rev and returns its sequence number. The time of rev is set to now.
+func (l *Log) Record(ctx context.Context, rev Revision) (uint64, error) {
+	rev.Seq, rev.Time = 0, l.clock.Now()
+
+	b, err := json.Marshal(rev)
+	if err != nil {
+		return 0, err
+	}
+
+	if b, err = l.cipher.Seal(b); err != nil {
+		return 0, err
+	}
+
+	seq, err := l.backend.Append(ctx, revisionsLog, b)
+	if err != nil || l.retention <= 0 {
+		return seq, err
+	}
+
+	return seq, l.Prune(ctx, rev.Time.Add(-l.retention))
+}
+
+// Prune removes the revisions recorded before before.
+func (l *Log) Prune(ctx context.Context, before time.Time) error {
+	return storage.Prune(ctx, l.backend, revisionsLog, func(e storage.Entry) (bool, error) {
+		rev, err := l.decode(e)
+		return !rev.Time.Before(before), err
+	})
+}
+
+func (l *Log) decode(e storage.Entry) (Revision, error) {
+	b, err := l.cipher.Open(e.Value)
+	if err != nil {
+		return Revision{}, fmt.Errorf("revision %d: %w", e.Seq, err)
+	}
+
+	var rev Revision
+	if err := json.Unmarshal(b, &rev); err != nil {
+		return Revision{}, fmt.Errorf("revision %d: %w", e.Seq, err)
+	}
+	rev.Seq = e.Seq
+	return rev, nil
+}
+
+// List returns the revisions recorded at or after since in the order of recording.
+func (l *Log) List(ctx context.Context, since time.Time) ([]Revision, error) {
+	var (
+		res   []Revision
+		after uint64
+	)
+
+	for {
+		entries, err := l.backend.Read(ctx, revisionsLog, after, readPage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			rev, err := l.decode(e)
+			if err != nil {
+				return nil, err
+			}
+			if !rev.Time.Before(since) {
+				res = append(res, rev)
+			}
+			after = e.Seq
+		}
+
+		if len(entries) < readPage {
+			return res, nil
+		}
+	}
+}
+
+// Run records a revision for each schedule received from updates, e.g. Scheduler.Updates, until
+// ctx is cancelled. The first schedule is compared to none, failed records are logged.
+func (l *Log) Run(ctx context.Context, updates <-chan schedule.Schedule) error {
+	var prev *schedule.Schedule
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case sched := <-updates:
+			rev, err := Revise(prev, sched)
+			if err == nil {
+				_, err = l.Record(ctx, rev)
+			}
+			if err != nil {
+				l.logger.Error("changelog", "start", sched.Start, "error", err)
+			}
+
+			prev = &sched
+		}
+	}
+}
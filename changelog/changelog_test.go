@@ -0,0 +1,135 @@
+package changelog
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/schedule"
+	"github.com/evcc-io/optimizer/storage"
+)
+
+var t0 = time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// plan returns a schedule of hourly intervals from start importing imp per interval at 0.3 per
+// kWh
+func plan(start time.Time, trigger schedule.Trigger, imp ...float32) schedule.Schedule {
+	n := len(imp)
+	req := client.OptimizationInput{
+		TimeSeries: client.TimeSeries{Dt: make([]int, n), Gt: imp, PN: make([]float32, n), PE: make([]float32, n)},
+	}
+	for t := range n {
+		req.TimeSeries.Dt[t] = 3600
+		req.TimeSeries.PN[t] = 0.3e-3
+	}
+
+	return schedule.Schedule{
+		Start:   start,
+		Request: req,
+		Result:  client.OptimizationResult{Status: client.Optimal, GridImport: imp, GridExport: make([]float32, n)},
+		Trigger: trigger,
+	}
+}
+
+func TestRevise(t *testing.T) {
+	prev := plan(t0, schedule.Periodic, 1000, 1000, 1000, 1000)
+	next := plan(t0.Add(time.Hour), schedule.Injected, 500, 500, 500, 500)
+	next.Reason = "grid operator request"
+
+	rev, err := Revise(&prev, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rev.Trigger != schedule.Injected || rev.Reason != "grid operator request" {
+		t.Errorf("expected trigger and reason of the schedule, got %q %q", rev.Trigger, rev.Reason)
+	}
+
+	// the figures cover the 3 hours both plans share
+	if !rev.Until.Equal(t0.Add(4 * time.Hour)) {
+		t.Errorf("expected figures until the end of the previous plan, got %v", rev.Until)
+	}
+	if rev.Figures.Import != 1500 {
+		t.Errorf("expected import of 1500 Wh, got %v", rev.Figures.Import)
+	}
+	if rev.Delta == nil {
+		t.Fatal("expected delta")
+	}
+	if rev.Delta.Import != -1500 || math.Abs(rev.Delta.Cost+0.45) > 1e-6 {
+		t.Errorf("expected 1500 Wh and 0.45 less, got %v and %v", rev.Delta.Import, rev.Delta.Cost)
+	}
+
+	// plans without common period are not compared
+	rev, err = Revise(&prev, plan(t0.Add(4*time.Hour), schedule.Periodic, 500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev.Delta != nil || rev.Figures.Import != 500 {
+		t.Errorf("expected figures of the whole plan without delta, got %+v %v", rev.Figures, rev.Delta)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := New(storage.NewMemory(), WithClock(clock.NewFake(t0)))
+
+	updates := make(chan schedule.Schedule)
+	done := make(chan error, 1)
+	go func() { done <- l.Run(ctx, updates) }()
+
+	for k := range 3 {
+		updates <- plan(t0.Add(time.Duration(k)*time.Hour), schedule.Periodic, float32(1000*k), 0)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	revs, err := l.List(context.Background(), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(revs))
+	}
+
+	if revs[0].Delta != nil {
+		t.Errorf("expected no delta for the first revision, got %v", revs[0].Delta)
+	}
+	for _, rev := range revs[1:] {
+		// the previous plan imported nothing in the hour both share
+		if expected := 1000 * float64(rev.Seq-1); rev.Delta == nil || rev.Delta.Import != expected {
+			t.Errorf("revision %d: expected import delta %v, got %v", rev.Seq, expected, rev.Delta)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	ctx := context.Background()
+
+	clk := clock.NewFake(t0)
+	l := New(storage.NewMemory(), WithClock(clk), WithRetention(2*time.Hour))
+
+	for range 4 {
+		if _, err := l.Record(ctx, Revision{Trigger: schedule.Periodic}); err != nil {
+			t.Fatal(err)
+		}
+		clk.Advance(time.Hour)
+	}
+
+	revs, err := l.List(ctx, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// revisions older than 2 hours are removed
+	if len(revs) != 3 || revs[0].Seq != 2 || !revs[0].Time.Equal(t0.Add(time.Hour)) {
+		t.Errorf("expected revisions 2 to 4, got %+v", revs)
+	}
+}
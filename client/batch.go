@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Solve solves an optimization problem. Non-200 responses are returned as error.
+func (c *ClientWithResponses) Solve(ctx context.Context, req OptimizationInput, reqEditors ...RequestEditorFn) (*OptimizationResult, error) {
+	resp, err := c.PostOptimizeChargeScheduleWithResponse(ctx, req, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.JSON200 != nil:
+		return resp.JSON200, nil
+	case resp.JSON400 != nil:
+		return nil, fmt.Errorf("bad request: %s %v", resp.JSON400.Message, resp.JSON400.Details)
+	case resp.JSON500 != nil:
+		return nil, fmt.Errorf("server error: %s", resp.JSON500.Message)
+	default:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+}
+
+// BatchResult is the outcome of a single problem of a batch.
+type BatchResult struct {
+	Result   *OptimizationResult
+	Err      error
+	Duration time.Duration // request duration including retries, zero if not sent
+}
+
+type batchConfig struct {
+	concurrency int
+}
+
+// BatchOption configures OptimizeBatch.
+type BatchOption func(*batchConfig)
+
+// Concurrency limits the number of concurrent requests. Defaults to 4.
+func Concurrency(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.concurrency = max(1, n)
+	}
+}
+
+// OptimizeBatch solves a batch of problems with bounded parallelism. Results are returned in
+// the order of the problems with an error per problem. Retries are those configured for
+// the client. Cancelling ctx fails all problems not yet solved with the context's error.
+func (c *ClientWithResponses) OptimizeBatch(ctx context.Context, reqs []OptimizationInput, opts ...BatchOption) []BatchResult {
+	cfg := batchConfig{concurrency: 4}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	res := make([]BatchResult, len(reqs))
+	sem := make(chan struct{}, cfg.concurrency)
+	clk := c.clockOf()
+
+	var wg sync.WaitGroup
+
+	for i, req := range reqs {
+		select {
+		case <-ctx.Done():
+			res[i].Err = ctx.Err()
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := clk.Now()
+			res[i].Result, res[i].Err = c.Solve(ctx, req)
+			res[i].Duration = clk.Now().Sub(start)
+		}()
+	}
+
+	wg.Wait()
+
+	return res
+}
+
+// BatchStats summarizes the outcome of a batch.
+type BatchStats struct {
+	Solved, Failed int
+	// request durations of all problems sent
+	Min, Mean, Max time.Duration
+	P95            time.Duration
+}
+
+// Stats returns the aggregate outcome and timing of the results of OptimizeBatch.
+func Stats(res []BatchResult) BatchStats {
+	var (
+		s         BatchStats
+		durations []time.Duration
+		total     time.Duration
+	)
+
+	for _, r := range res {
+		if r.Err != nil {
+			s.Failed++
+		} else {
+			s.Solved++
+		}
+
+		if r.Duration > 0 {
+			durations = append(durations, r.Duration)
+			total += r.Duration
+		}
+	}
+
+	if len(durations) == 0 {
+		return s
+	}
+
+	slices.Sort(durations)
+
+	s.Min, s.Max = durations[0], durations[len(durations)-1]
+	s.Mean = total / time.Duration(len(durations))
+	s.P95 = durations[(len(durations)*95+99)/100-1]
+
+	return s
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/clock"
+)
+
+// ErrCircuitOpen is returned without contacting the server while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// WithCircuitBreaker stops sending requests for the cooldown period after the given number of
+// consecutive failed requests, i.e. transport errors or retryable status codes after all retry
+// attempts. Afterwards a single trial request is let through, closing the breaker on success.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(c *config) error {
+		if failures < 1 {
+			return errors.New("circuit breaker failures must be at least 1")
+		}
+		c.breakerFailures, c.breakerCooldown = failures, cooldown
+		return nil
+	}
+}
+
+type breaker struct {
+	mu       sync.Mutex
+	clock    clock.Clock
+	limit    int
+	cooldown time.Duration
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// allow reports whether a request may be sent, in half-open state only a single one
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.limit {
+		return true
+	}
+	if b.trial || b.clock.Now().Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+
+	b.trial = true
+	return true
+}
+
+// cancel releases a trial request without result
+func (b *breaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// done records the result of a request
+func (b *breaker) done(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.limit {
+		b.openedAt = b.clock.Now()
+	}
+}
+
+func breakerDoer(doer HttpRequestDoer, limit int, cooldown time.Duration, clk clock.Clock) HttpRequestDoer {
+	b := &breaker{clock: clk, limit: limit, cooldown: cooldown}
+
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if !b.allow() {
+			return nil, ErrCircuitOpen
+		}
+
+		resp, err := doer.Do(req)
+
+		// cancellation by the caller says nothing about the server
+		if err != nil && req.Context().Err() != nil {
+			b.cancel()
+			return nil, err
+		}
+
+		b.done(err != nil || retryable(resp.StatusCode))
+		return resp, err
+	})
+}
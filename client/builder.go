@@ -0,0 +1,355 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/evcc-io/optimizer/units"
+)
+
+// RequestBuilder builds an OptimizationInput. The first error is kept and returned by Build,
+// subsequent calls are ignored.
+type RequestBuilder struct {
+	req         OptimizationInput
+	start       time.Time
+	constraints []Constraint
+	err         error
+}
+
+// NewRequest creates a request builder.
+func NewRequest() *RequestBuilder {
+	return new(RequestBuilder)
+}
+
+func (b *RequestBuilder) fail(format string, args ...any) *RequestBuilder {
+	if b.err == nil {
+		b.err = fmt.Errorf(format, args...)
+	}
+	return b
+}
+
+// Horizon sets the interval durations in seconds. It must be called before any other series.
+func (b *RequestBuilder) Horizon(dt ...int) *RequestBuilder {
+	if b.err != nil {
+		return b
+	}
+	if len(dt) == 0 {
+		return b.fail("horizon: no intervals")
+	}
+	if b.req.TimeSeries.Dt != nil {
+		return b.fail("horizon: already set")
+	}
+	if i := slices.IndexFunc(dt, func(d int) bool { return d <= 0 }); i >= 0 {
+		return b.fail("horizon: interval %d: duration %d must be positive", i, dt[i])
+	}
+
+	b.req.TimeSeries.Dt = slices.Clone(dt)
+	return b
+}
+
+// UniformHorizon sets a horizon of n intervals of duration d.
+func (b *RequestBuilder) UniformHorizon(n int, d time.Duration) *RequestBuilder {
+	if d%time.Second != 0 {
+		return b.fail("horizon: duration %v is not a multiple of seconds", d)
+	}
+	return b.Horizon(slices.Repeat([]int{int(d / time.Second)}, n)...)
+}
+
+// Start sets the start of the first interval. It is required for loads.
+func (b *RequestBuilder) Start(t time.Time) *RequestBuilder {
+	b.start = t
+	return b
+}
+
+// series returns values matching the horizon, a single value applies to all intervals
+func (b *RequestBuilder) series(name string, values []float32) []float32 {
+	n := len(b.req.TimeSeries.Dt)
+
+	switch {
+	case n == 0:
+		b.fail("%s: horizon not set", name)
+	case len(values) == 1:
+		return slices.Repeat(values, n)
+	case len(values) != n:
+		b.fail("%s: %d values, horizon has %d intervals", name, len(values), n)
+	default:
+		return slices.Clone(values)
+	}
+
+	return nil
+}
+
+// SolarForecast sets the forecasted generation per interval (Wh).
+func (b *RequestBuilder) SolarForecast(ft ...float32) *RequestBuilder {
+	if b.err == nil {
+		if i := slices.IndexFunc(ft, func(f float32) bool { return f < 0 }); i >= 0 {
+			return b.fail("solar forecast: interval %d: %v must not be negative", i, ft[i])
+		}
+		b.req.TimeSeries.Ft = b.series("solar forecast", ft)
+	}
+	return b
+}
+
+// Demand sets the household demand per interval (Wh).
+func (b *RequestBuilder) Demand(gt ...float32) *RequestBuilder {
+	if b.err == nil {
+		b.req.TimeSeries.Gt = b.series("demand", gt)
+	}
+	return b
+}
+
+// ImportPrice sets the grid import price per interval (currency/Wh).
+func (b *RequestBuilder) ImportPrice(pN ...float32) *RequestBuilder {
+	if b.err == nil {
+		b.req.TimeSeries.PN = b.series("import price", pN)
+	}
+	return b
+}
+
+// ExportPrice sets the grid export remuneration per interval (currency/Wh).
+func (b *RequestBuilder) ExportPrice(pE ...float32) *RequestBuilder {
+	if b.err == nil {
+		b.req.TimeSeries.PE = b.series("export price", pE)
+	}
+	return b
+}
+
+// Emissions sets the grid import emissions per interval (gCO2/Wh).
+func (b *RequestBuilder) Emissions(emN ...float32) *RequestBuilder {
+	if b.err == nil {
+		b.req.TimeSeries.EmN = b.series("emissions", emN)
+	}
+	return b
+}
+
+// SolarPower sets the forecasted generation as average power per interval.
+func (b *RequestBuilder) SolarPower(p ...units.Power) *RequestBuilder {
+	return b.SolarForecast(b.energies(p)...)
+}
+
+// DemandPower sets the household demand as average power per interval.
+func (b *RequestBuilder) DemandPower(p ...units.Power) *RequestBuilder {
+	return b.Demand(b.energies(p)...)
+}
+
+// ImportPrices sets the grid import price per interval in any price unit.
+func (b *RequestBuilder) ImportPrices(p ...units.Price) *RequestBuilder {
+	return b.ImportPrice(perWh(p)...)
+}
+
+// ExportPrices sets the grid export remuneration per interval in any price unit.
+func (b *RequestBuilder) ExportPrices(p ...units.Price) *RequestBuilder {
+	return b.ExportPrice(perWh(p)...)
+}
+
+// energies converts average powers to energies per interval, a single value applies to all
+// intervals
+func (b *RequestBuilder) energies(p []units.Power) []float32 {
+	dt := b.req.TimeSeries.Dt
+	if len(p) == 1 {
+		p = slices.Repeat(p, len(dt))
+	}
+	if len(p) != len(dt) {
+		// fails with the length check of series
+		return make([]float32, len(p))
+	}
+
+	res := make([]float32, len(p))
+	for t, v := range p {
+		res[t] = float32(v.Over(time.Duration(dt[t]) * time.Second).Wh())
+	}
+	return res
+}
+
+func perWh(p []units.Price) []float32 {
+	res := make([]float32, len(p))
+	for t, v := range p {
+		res[t] = float32(v.PerWh())
+	}
+	return res
+}
+
+// Efficiency sets the default charge and discharge efficiencies.
+func (b *RequestBuilder) Efficiency(etaC, etaD float32) *RequestBuilder {
+	if etaC <= 0 || etaC > 1 || etaD <= 0 || etaD > 1 {
+		return b.fail("efficiency: %v/%v must be in (0, 1]", etaC, etaD)
+	}
+	b.req.EtaC, b.req.EtaD = etaC, etaD
+	return b
+}
+
+// Strategy sets the optimization strategy.
+func (b *RequestBuilder) Strategy(s OptimizerStrategy) *RequestBuilder {
+	b.req.Strategy = s
+	return b
+}
+
+// ObjectiveWeights trades the cost against battery wear, grid exchange and peak import, e.g.
+// to cycle a small battery less:
+//
+//	b.ObjectiveWeights(client.ObjectiveWeights{Cost: 1, BatteryWear: 0.2})
+func (b *RequestBuilder) ObjectiveWeights(w ObjectiveWeights) *RequestBuilder {
+	if err := validateObjectiveWeights(w); err != nil {
+		return b.fail("objective weights: %w", err)
+	}
+	b.req.ObjectiveWeights = &w
+	return b
+}
+
+// Grid sets the grid configuration.
+func (b *RequestBuilder) Grid(g GridConfig) *RequestBuilder {
+	b.req.Grid = g
+	return b
+}
+
+// Chp adds a combined heat and power unit covering the heat demand of HeatDemand, fueled at
+// the prices of GasPrice.
+func (b *RequestBuilder) Chp(chp ChpConfig) *RequestBuilder {
+	b.req.Chp = &chp
+	return b
+}
+
+// GasPrice sets the fuel price of the combined heat and power unit per interval (currency/Wh).
+func (b *RequestBuilder) GasPrice(pG ...float32) *RequestBuilder {
+	if b.err == nil {
+		b.req.TimeSeries.PG = b.series("gas price", pG)
+	}
+	return b
+}
+
+// HeatDemand sets the heat demand per interval (Wh).
+func (b *RequestBuilder) HeatDemand(qH ...float32) *RequestBuilder {
+	if b.err == nil {
+		if i := slices.IndexFunc(qH, func(q float32) bool { return q < 0 }); i >= 0 {
+			return b.fail("heat demand: interval %d: %v must not be negative", i, qH[i])
+		}
+		b.req.TimeSeries.QH = b.series("heat demand", qH)
+	}
+	return b
+}
+
+// ImportLimit limits the grid import power. A single value applies to all intervals, several
+// values limit each interval, e.g. for curtailment windows of the grid operator. It must be
+// called after Grid.
+func (b *RequestBuilder) ImportLimit(p ...units.Power) *RequestBuilder {
+	if b.err != nil {
+		return b
+	}
+	if i := slices.IndexFunc(p, func(v units.Power) bool { return v < 0 }); i >= 0 {
+		return b.fail("import limit: interval %d: %v must not be negative", i, p[i])
+	}
+
+	switch len(p) {
+	case 0:
+		return b.fail("import limit: no values")
+	case 1:
+		b.req.Grid.PMaxImp = float32(p[0].W())
+	default:
+		limits := make([]float32, len(p))
+		for t, v := range p {
+			limits[t] = float32(v.W())
+		}
+		b.req.TimeSeries.PMaxImp = b.series("import limit", limits)
+	}
+	return b
+}
+
+// ExportLimit limits the grid export power. It must be called after Grid.
+func (b *RequestBuilder) ExportLimit(p units.Power) *RequestBuilder {
+	if p < 0 {
+		return b.fail("export limit: %v must not be negative", p)
+	}
+	b.req.Grid.PMaxExp = float32(p.W())
+	return b
+}
+
+// ConnectionCapacity limits grid import and export to the capacity of the grid connection. It
+// must be called after Grid.
+func (b *RequestBuilder) ConnectionCapacity(p units.Power) *RequestBuilder {
+	if p <= 0 {
+		return b.fail("connection capacity: %v must be positive", p)
+	}
+	b.req.Grid.PConn = float32(p.W())
+	return b
+}
+
+// AddBattery adds a battery. Its series are checked against the horizon by Build.
+func (b *RequestBuilder) AddBattery(bat BatteryConfig) *RequestBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	if errs := validateBattery(bat); len(errs) > 0 {
+		name := fmt.Sprintf("battery %d", len(b.req.Batteries))
+		if bat.Id != "" {
+			name = fmt.Sprintf("battery %q", bat.Id)
+		}
+		return b.fail("%s: %w", name, errs[0])
+	}
+
+	b.req.Batteries = append(b.req.Batteries, bat)
+	return b
+}
+
+// AddLoad adds a controllable load mapped to a battery, see Load. Horizon and start must be
+// set before.
+func (b *RequestBuilder) AddLoad(l Load) *RequestBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	name := fmt.Sprintf("load %d", len(b.req.Batteries))
+	if l.Id != "" {
+		name = fmt.Sprintf("load %q", l.Id)
+	}
+
+	switch {
+	case b.req.TimeSeries.Dt == nil:
+		return b.fail("%s: horizon not set", name)
+	case b.start.IsZero():
+		return b.fail("%s: start not set", name)
+	}
+
+	bat, err := l.Battery(b.start, b.req.TimeSeries.Dt)
+	if err != nil {
+		return b.fail("%s: %w", name, err)
+	}
+
+	b.req.Batteries = append(b.req.Batteries, bat)
+	return b
+}
+
+// Constrain adds constraints on batteries. They are compiled into the battery series by Build,
+// the batteries need not be added yet.
+func (b *RequestBuilder) Constrain(c ...Constraint) *RequestBuilder {
+	b.constraints = append(b.constraints, c...)
+	return b
+}
+
+// Build returns the request, or the first error and the problems found by Validate.
+func (b *RequestBuilder) Build() (OptimizationInput, error) {
+	if b.err != nil {
+		return OptimizationInput{}, b.err
+	}
+
+	if len(b.req.Batteries) == 0 {
+		return OptimizationInput{}, errors.New("no batteries")
+	}
+
+	req := b.req
+	req.Batteries = slices.Clone(req.Batteries)
+
+	for _, c := range b.constraints {
+		if err := c.compile(&req); err != nil {
+			return OptimizationInput{}, err
+		}
+	}
+
+	if err := Validate(req); err != nil {
+		return OptimizationInput{}, err
+	}
+
+	return req, nil
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Canonical encodes v as JSON with sorted keys, without whitespace and with normalized numbers
+// like the optimizer, so equal values encode to equal text, e.g. for diffs and content-hash
+// caching. Unlike responses of the optimizer, numbers are not rounded. Integers keep their
+// precision, integral floats encode as integers, others in the shortest representation, in
+// exponent notation below 1e-4 and from 1e16.
+func Canonical(v any) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(&b)
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Digest returns the digest of the canonical encoding of v. The digest of a request equals
+// the digest recorded by the audit log of the optimizer.
+func Digest(v any) (string, error) {
+	b, err := Canonical(v)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
+func writeCanonical(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		buf.WriteByte('{')
+		for i, k := range slices.Sorted(maps.Keys(v)) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeString(buf, k); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case []any:
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+
+	case json.Number:
+		s, err := formatNumber(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(s)
+
+	case string:
+		return writeString(buf, v)
+
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+
+	return nil
+}
+
+// writeString writes s as JSON string like the optimizer, escaping neither HTML characters nor
+// the line and paragraph separators U+2028 and U+2029
+func writeString(buf *bytes.Buffer, s string) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+
+	// the escapes are scanned in order, so escaped backslashes are not mistaken for escapes
+	e := bytes.TrimSuffix(b.Bytes(), []byte{'\n'})
+	for {
+		i := bytes.IndexByte(e, '\\')
+		if i < 0 {
+			buf.Write(e)
+			return nil
+		}
+		buf.Write(e[:i])
+
+		switch e = e[i:]; {
+		case bytes.HasPrefix(e, []byte(`\u2028`)):
+			buf.WriteRune('\u2028')
+			e = e[6:]
+		case bytes.HasPrefix(e, []byte(`\u2029`)):
+			buf.WriteRune('\u2029')
+			e = e[6:]
+		default:
+			buf.Write(e[:2])
+			e = e[2:]
+		}
+	}
+}
+
+// formatNumber formats n like the optimizer: integers as they are, floats like formatCanonical
+func formatNumber(n json.Number) (string, error) {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if s == "-0" {
+			return "0", nil
+		}
+		return s, nil
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return "", err
+	}
+	return formatCanonical(f), nil
+}
+
+// formatCanonical formats f like the optimizer: integral values as integers, others in the
+// shortest representation, in exponent notation outside [1e-4, 1e16)
+func formatCanonical(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e16 {
+		return strconv.FormatFloat(f+0, 'f', 0, 64) // +0 drops the sign of negative zero
+	}
+
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	if exp, err := strconv.Atoi(s[strings.IndexByte(s, 'e')+1:]); err == nil && exp >= -4 && exp < 16 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return s
+}
@@ -12,6 +12,27 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/oapi-codegen/runtime"
+)
+
+// Defines values for GoalShortfallReason.
+const (
+	Capacity       GoalShortfallReason = "capacity"
+	ChargePower    GoalShortfallReason = "charge_power"
+	CompetingGoals GoalShortfallReason = "competing_goals"
+	Generation     GoalShortfallReason = "generation"
+	GridLimit      GoalShortfallReason = "grid_limit"
+	Other          GoalShortfallReason = "other"
+)
+
+// Defines values for JobStatus.
+const (
+	Cancelled JobStatus = "cancelled"
+	Done      JobStatus = "done"
+	Failed    JobStatus = "failed"
+	Queued    JobStatus = "queued"
+	Running   JobStatus = "running"
 )
 
 // Defines values for OptimizationResultFlowDirection.
@@ -20,11 +41,19 @@ const (
 	N1 OptimizationResultFlowDirection = 1
 )
 
+// Defines values for OptimizationResultObjectiveUnit.
+const (
+	Currency OptimizationResultObjectiveUnit = "currency"
+	GCO2     OptimizationResultObjectiveUnit = "gCO2"
+	Wh       OptimizationResultObjectiveUnit = "Wh"
+)
+
 // Defines values for OptimizationResultStatus.
 const (
 	Infeasible OptimizationResultStatus = "Infeasible"
 	NotSolved  OptimizationResultStatus = "Not Solved"
 	Optimal    OptimizationResultStatus = "Optimal"
+	Simulated  OptimizationResultStatus = "Simulated"
 	Unbounded  OptimizationResultStatus = "Unbounded"
 	Undefined  OptimizationResultStatus = "Undefined"
 )
@@ -42,16 +71,47 @@ const (
 	OptimizerStrategyDischargingStrategyNone                  OptimizerStrategyDischargingStrategy = "none"
 )
 
+// Defines values for OptimizerStrategyObjective.
+const (
+	Cost      OptimizerStrategyObjective = "cost"
+	Emissions OptimizerStrategyObjective = "emissions"
+	Energy    OptimizerStrategyObjective = "energy"
+)
+
+// Defines values for SimulationInputPolicy.
+const (
+	SelfConsumption SimulationInputPolicy = "self_consumption"
+)
+
 // BatteryConfig defines model for BatteryConfig.
 type BatteryConfig struct {
+	// Available Availability of the battery at each time step, e.g. during firmware updates or while a portable
+	// battery is away. Unavailable batteries neither charge nor discharge. Requests are rejected if a
+	// charge demand falls on an unavailable time step or a goal cannot be reached charging at full
+	// power in the available time steps before it.
+	Available []bool `json:"available,omitempty"`
+
+	// CEtaCurve Charging efficiency as piecewise linear function of the charge power. Overrides eta_c for this battery.
+	// A point at zero power is added automatically using the efficiency of the lowest power point.
+	// Charge power is limited to the highest power point of the curve.
+	CEtaCurve []EfficiencyPoint `json:"c_eta_curve,omitempty"`
+
+	// CEtaSeries Charging efficiency at each time step. Overrides eta_c for this battery and must not be combined
+	// with c_eta_curve. Values above 1 model a heat pump charging a thermal storage, with the coefficient
+	// of performance (COP) derived from the temperature forecast.
+	CEtaSeries []float32 `json:"c_eta_series,omitempty"`
+
 	// CMax Maximum charge power in W
 	CMax float32 `json:"c_max"`
 
 	// CMin Minimum charge power in W
 	CMin float32 `json:"c_min"`
 
-	// CPriority Charging and discharging priority 0..2 compared to other batteries. 2 = highest priority.
-	CPriority int `json:"c_priority,omitempty"`
+	// CPriority Charging and discharging priority compared to other batteries. Higher values take precedence
+	// in cost neutral situations, allowing an explicit order among any number of batteries. Only the
+	// order of the values matters, e.g. priorities 1 and 5 are equivalent to 0 and 1.
+	CPriority   int          `json:"c_priority,omitempty"`
+	Calibration *Calibration `json:"calibration,omitempty"`
 
 	// ChargeFromGrid Controls whether the battery can be charged from the grid.
 	//   - True: The battery can be charged from grid at any time. The actual decision is subject
@@ -59,20 +119,50 @@ type BatteryConfig struct {
 	//   - False: (default) The battery cannot be charged while power is retrieved from grid
 	ChargeFromGrid bool `json:"charge_from_grid,omitempty"`
 
+	// DEtaCurve Discharging efficiency as piecewise linear function of the discharge power. Overrides eta_d for this battery.
+	// A point at zero power is added automatically using the efficiency of the lowest power point.
+	// Discharge power is limited to the highest power point of the curve.
+	DEtaCurve []EfficiencyPoint `json:"d_eta_curve,omitempty"`
+
 	// DMax Maximum discharge power in W
 	DMax float32 `json:"d_max"`
 
+	// Departures Possible departures of a vehicle with their probabilities, e.g. usually at 8:00 but sometimes at
+	// 6:30. Instead of a single goal, the probability weighted shortfall of the goals at the possible
+	// departures is minimized, charging earlier if an early departure is likely enough. Availability
+	// should cover the latest departure.
+	Departures []Departure `json:"departures,omitempty"`
+
 	// DischargeToGrid Controls whether the battery can discharge to grid.
 	//   - True: The battery can discharge to the grid at any time. The actual decision is
 	//     subject to the optimization.
 	//   - False: (default) The battery cannot be discharged while power is exported to the grid.
 	DischargeToGrid bool `json:"discharge_to_grid,omitempty"`
 
+	// Enabled Include the battery in the optimization. Disabled batteries (e.g. during maintenance or
+	// while the vehicle is away) are excluded without reindexing the batteries array. The result
+	// keeps the battery at its index with zeroed series.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// GoalPriority Priority of the charge goals and departure goals compared to the goals of other batteries. When
+	// goals compete for limited grid, generation or charge power, goals of higher priority are met first
+	// and goals of lower priority are scaled back, see goal_shortfalls of the result. The number of
+	// levels is limited, more so for goals at many time steps, requests exceeding it are rejected.
+	GoalPriority int `json:"goal_priority,omitempty"`
+
+	// Id Stable identifier of the battery, returned with its result. Identifiers must be unique,
+	// clients look up results by id instead of position.
+	Id string `json:"id"`
+
+	// Name Human-readable name of the battery, returned with its result
+	Name string `json:"name"`
+
 	// PA Monetary value of the stored energy per Wh at end of time horizon
 	PA float32 `json:"p_a"`
 
 	// PDemand Minimum charge demand per time step (Wh)
-	PDemand []float32 `json:"p_demand,omitempty"`
+	PDemand         []float32        `json:"p_demand,omitempty"`
+	Preconditioning *Preconditioning `json:"preconditioning,omitempty"`
 
 	// SCapacity The capacity at 100% SOC in Wh. If not specified s_capacity will be set to s_max.
 	// s_initial must be less or equal s_capacity, otherwise the optimization will return an error.
@@ -84,6 +174,11 @@ type BatteryConfig struct {
 	// SInitial Initial state of charge in Wh
 	SInitial float32 `json:"s_initial"`
 
+	// SInitialStddev Standard deviation of the measured initial state of charge in Wh, e.g. for noisy BMS readings.
+	// Near-term state of charge bounds are tightened by twice this value, decreasing to no margin
+	// four hours ahead, so plans do not violate s_min or s_max in reality.
+	SInitialStddev float32 `json:"s_initial_stddev,omitempty"`
+
 	// SMax Maximum state of charge in Wh
 	SMax float32 `json:"s_max"`
 
@@ -93,16 +188,124 @@ type BatteryConfig struct {
 
 // BatteryResult defines model for BatteryResult.
 type BatteryResult struct {
+	// CalibrationStep Time step at the end of which the battery is full for calibration. Only present if a calibration is due within the horizon.
+	CalibrationStep *int `json:"calibration_step"`
+
 	// ChargingPower Optimal charging energy at each time step (Wh)
 	ChargingPower []float32 `json:"charging_power,omitempty"`
 
+	// Contribution Objective value lost when solving without this battery, if attribute_batteries is set. Contributions
+	// of batteries that can substitute each other do not add up to their joint contribution. Null for
+	// disabled batteries and if the problem is infeasible without the battery.
+	Contribution float32 `json:"contribution"`
+
 	// DischargingPower Optimal discharging energy at each time step (Wh)
 	DischargingPower []float32 `json:"discharging_power,omitempty"`
 
+	// ExpectedShortfall Probability weighted shortfall of the goals at the possible departures (Wh). Only present if departures are given.
+	ExpectedShortfall float32 `json:"expected_shortfall,omitempty"`
+
+	// GoalShortfalls Charge goals scaled back by the optimizer with the reason. Only present if charge goals are given.
+	GoalShortfalls []GoalShortfall `json:"goal_shortfalls,omitempty"`
+
+	// Id Identifier of the battery as given in the request
+	Id string `json:"id,omitempty"`
+
+	// Name Name of the battery as given in the request
+	Name string `json:"name,omitempty"`
+
+	// PreconditioningPower Preconditioning energy at each time step (Wh). Only present if preconditioning is given.
+	PreconditioningPower []float32 `json:"preconditioning_power,omitempty"`
+
 	// StateOfCharge State of charge at each time step (Wh)
 	StateOfCharge []float32 `json:"state_of_charge,omitempty"`
 }
 
+// Calibration Periodic full charge required by the battery management system, e.g. of LFP batteries every 14
+// days. Once due within the horizon, the battery reaches s_max at the end of the time step the
+// optimizer finds cheapest before, an overdue calibration anywhere within the horizon. A shortfall
+// is penalized like missed goals. The caller carries the time since the last full charge across
+// rolling horizons.
+type Calibration struct {
+	// Elapsed Time since the last full charge at the start of the horizon in seconds
+	Elapsed float32 `json:"elapsed,omitempty"`
+
+	// Interval Maximum time between full charges in seconds
+	Interval float32 `json:"interval"`
+}
+
+// ChpConfig Combined heat and power unit co-optimized with the electricity flows, e.g. for hybrid heating. The
+// heat demand time_series.q_H is covered by the unit, a gas boiler and electric heating such as a heat
+// pump, fuel is priced at time_series.p_G. Heat is not stored, the unit only runs while there is heat
+// demand. Requires the cost objective. Only used by the charge schedule.
+type ChpConfig struct {
+	// Cop Heat per electricity of electric heating, e.g. the heat pump COP or 1 for a heating rod
+	Cop float32 `json:"cop,omitempty"`
+
+	// EtaBoiler Boiler efficiency, heat per fuel energy
+	EtaBoiler float32 `json:"eta_boiler,omitempty"`
+
+	// EtaEl Electric efficiency, electricity per fuel energy
+	EtaEl float32 `json:"eta_el"`
+
+	// EtaTh Thermal efficiency, heat per fuel energy. eta_el plus eta_th must not exceed 1.
+	EtaTh float32 `json:"eta_th"`
+
+	// PBoilerMax Maximum heat output of a gas boiler in W, 0 without boiler
+	PBoilerMax float32 `json:"p_boiler_max,omitempty"`
+
+	// PElMax Maximum electric power of the unit in W
+	PElMax float32 `json:"p_el_max"`
+
+	// PElMin Minimum electric power while running in W, 0 for continuous modulation
+	PElMin float32 `json:"p_el_min,omitempty"`
+
+	// PHeatElMax Maximum electric power of electric heating in W, 0 without
+	PHeatElMax float32 `json:"p_heat_el_max,omitempty"`
+}
+
+// ChpResult Dispatch of the combined heat and power unit, if given
+type ChpResult struct {
+	// BoilerHeat Heat produced by the boiler at each time step (Wh)
+	BoilerHeat []float32 `json:"boiler_heat,omitempty"`
+
+	// ElectricHeating Electricity consumed by electric heating at each time step (Wh)
+	ElectricHeating []float32 `json:"electric_heating,omitempty"`
+
+	// Electricity Electricity generated by the unit at each time step (Wh)
+	Electricity []float32 `json:"electricity,omitempty"`
+
+	// Fuel Fuel consumed by the unit and the boiler at each time step (Wh)
+	Fuel []float32 `json:"fuel,omitempty"`
+
+	// FuelCost Fuel cost over the horizon
+	FuelCost float32 `json:"fuel_cost,omitempty"`
+
+	// Heat Heat produced by the unit at each time step (Wh)
+	Heat []float32 `json:"heat,omitempty"`
+}
+
+// Departure defines model for Departure.
+type Departure struct {
+	// Probability Probability of this departure, probabilities of a battery sum up to at most 1
+	Probability float32 `json:"probability"`
+
+	// SGoal Goal state of charge at this departure in Wh
+	SGoal float32 `json:"s_goal"`
+
+	// T Index of the time step the vehicle may depart after
+	T int `json:"t"`
+}
+
+// EfficiencyPoint defines model for EfficiencyPoint.
+type EfficiencyPoint struct {
+	// Eta Efficiency at this power (greater than 0 up to 1)
+	Eta float32 `json:"eta"`
+
+	// Power Charge or discharge power in W
+	Power float32 `json:"power"`
+}
+
 // Error defines model for Error.
 type Error struct {
 	// Details Field-specific validation errors. Keys are field paths (e.g., "batteries.0.s_max"), values are error messages.
@@ -112,21 +315,150 @@ type Error struct {
 	Message string `json:"message,omitempty"`
 }
 
+// EstimateResult defines model for EstimateResult.
+type EstimateResult struct {
+	// ActiveSolves Number of solves currently running on the serving host
+	ActiveSolves int `json:"active_solves,omitempty"`
+
+	// EstimatedQueueWait Estimated remaining time of all in-flight solves in seconds
+	EstimatedQueueWait float32 `json:"estimated_queue_wait,omitempty"`
+
+	// EstimatedSolveTime Estimated solve time for the given problem size in seconds
+	EstimatedSolveTime float32 `json:"estimated_solve_time,omitempty"`
+}
+
+// GoalShortfall A charge goal that could not be met.
+type GoalShortfall struct {
+	// Competitors Indices of the batteries charging while this battery could charge more, by descending goal priority
+	Competitors []int `json:"competitors,omitempty"`
+
+	// Goal Goal state of charge in Wh
+	Goal float32 `json:"goal"`
+
+	// Reason Why the goal was scaled back:
+	//   - capacity: the goal exceeds s_max
+	//   - charge_power: the battery charged at maximum power whenever available
+	//   - grid_limit: the grid import limit was reached while the battery could charge more
+	//   - generation: the battery only charges from surplus generation, which was not sufficient
+	//   - competing_goals: other batteries were charged instead, e.g. for goals of higher priority
+	//   - other: none of the above, e.g. conflicting constraints
+	Reason GoalShortfallReason `json:"reason"`
+
+	// Shortfall Energy missing to the goal in Wh
+	Shortfall float32 `json:"shortfall"`
+
+	// T Index of the time step of the goal
+	T int `json:"t"`
+}
+
+// GoalShortfallReason Why the goal was scaled back:
+//   - capacity: the goal exceeds s_max
+//   - charge_power: the battery charged at maximum power whenever available
+//   - grid_limit: the grid import limit was reached while the battery could charge more
+//   - generation: the battery only charges from surplus generation, which was not sufficient
+//   - competing_goals: other batteries were charged instead, e.g. for goals of higher priority
+//   - other: none of the above, e.g. conflicting constraints
+type GoalShortfallReason string
+
 // GridConfig defines model for GridConfig.
 type GridConfig struct {
+	// EExpCap Remunerated export energy per cap period in Wh, e.g. for contracts paying p_E only for the first
+	// X kWh fed in per day or year. Export beyond the cap earns nothing, so the optimizer stores rather
+	// than exports once the cap is spent. Within a period, the remaining cap is assigned to the best
+	// paid export intervals.
+	EExpCap float32 `json:"e_exp_cap,omitempty"`
+
+	// EExpToDate Energy exported so far in the current cap period in Wh. Requires e_exp_cap.
+	EExpToDate float32 `json:"e_exp_to_date,omitempty"`
+
+	// EImpTier Import energy allowance at base price (p_N) per billing period in Wh, e.g. for tariffs
+	// where the first X kWh per month are cheaper. Requires prc_e_exc_tier and vice versa.
+	EImpTier float32 `json:"e_imp_tier,omitempty"`
+
+	// EImpToDate Energy imported so far in the current billing period in Wh
+	EImpToDate float32 `json:"e_imp_to_date,omitempty"`
+
+	// ENetToDate Net import so far in the current netting period in Wh, negative for a net export. Requires prc_e_net_imp.
+	ENetToDate float32 `json:"e_net_to_date,omitempty"`
+
+	// PConn Capacity of the grid connection in W, limiting import and export in addition to p_max_imp,
+	// p_max_exp and time_series.p_max_imp. Cannot be combined with prc_p_exc_imp.
+	PConn float32 `json:"p_conn,omitempty"`
+
 	// PMaxExp Maximum grid export power in W
 	PMaxExp float32 `json:"p_max_exp,omitempty"`
 
 	// PMaxImp Maximum grid import power in W
 	PMaxImp float32 `json:"p_max_imp,omitempty"`
 
+	// PPeakToDate Peak import power reached so far in the current billing period in W. Only raising the peak above it is
+	// charged by the peak price curve prc_p_peak or the demand rate prc_p_exc_imp, which requires one of them.
+	PPeakToDate float32 `json:"p_peak_to_date,omitempty"`
+
+	// PrcEDev Price per Wh of deviation from the committed grid exchange time_series.n_commit in either
+	// direction, e.g. imbalance cost of a flexibility contract. Requires n_commit and vice versa.
+	PrcEDev float32 `json:"prc_e_dev,omitempty"`
+
+	// PrcEExcTier Price surcharge per Wh on top of p_N for energy imported beyond the tier allowance
+	PrcEExcTier float32 `json:"prc_e_exc_tier,omitempty"`
+
+	// PrcENetExp Credit per Wh of the net export per netting period, must not exceed prc_e_net_imp. Requires prc_e_net_imp.
+	PrcENetExp float32 `json:"prc_e_net_exp,omitempty"`
+
+	// PrcENetImp Price per Wh of the net import per netting period, enables net metering with an import and an export
+	// register offsetting each other before billing. Applies in addition to the per interval prices p_N and
+	// p_E, set them to zero for pure netting.
+	PrcENetImp float32 `json:"prc_e_net_imp,omitempty"`
+
 	// PrcPExcImp price per W to consider in case the import limit is exceeded.
 	// If not specified, the limit will be protected by a hard constraint.
 	PrcPExcImp float32 `json:"prc_p_exc_imp,omitempty"`
+
+	// PrcPPeak Price of the peak import power of the horizon as convex piecewise linear function, e.g. capacity
+	// based tariff components (Leistungspreis) growing superlinearly with the peak. Each point sets the
+	// price per W for the part of the peak above its power, so prices must not decrease with power.
+	// Only raising the peak above p_peak_to_date is charged. Cannot be combined with prc_p_exc_imp.
+	PrcPPeak []PeakPricePoint `json:"prc_p_peak,omitempty"`
+
+	// TCapReset Index of the first time step belonging to the next cap period. Export from this time step on
+	// counts against the full e_exp_cap of the next period. If not specified, the cap period does not
+	// end within the horizon. Requires e_exp_cap.
+	TCapReset int `json:"t_cap_reset,omitempty"`
+
+	// TNetReset Index of the first time step of the next netting period. Import and export from then on are netted in
+	// the next period. If not specified, the netting period does not end within the horizon. Requires
+	// prc_e_net_imp.
+	TNetReset int `json:"t_net_reset,omitempty"`
+
+	// TTierReset Index of the first time step belonging to the next billing period. Import from this time step
+	// on counts against the full e_imp_tier allowance of the next period instead of the remaining
+	// allowance of the current one. If not specified, the billing period does not end within the horizon.
+	TTierReset int `json:"t_tier_reset,omitempty"`
+}
+
+// Job defines model for Job.
+type Job struct {
+	// Id Job id
+	Id string `json:"id"`
+
+	// Message Failure message of failed jobs
+	Message string `json:"message,omitempty"`
+
+	// Status Job status, the result is available once done
+	Status JobStatus `json:"status"`
 }
 
+// JobStatus Job status, the result is available once done
+type JobStatus string
+
 // LimitViolationResult defines model for LimitViolationResult.
 type LimitViolationResult struct {
+	// CostBudgetExceeded The cost budget of the goal seeking mode could not be met.
+	CostBudgetExceeded bool `json:"cost_budget_exceeded,omitempty"`
+
+	// ExportCapReached Export beyond the remunerated export cap e_exp_cap is planned.
+	ExportCapReached bool `json:"export_cap_reached,omitempty"`
+
 	// GridExportLimitHit The solar yield in (Wh) that was reduced due to the limitation of grid export power.
 	GridExportLimitHit bool `json:"grid_export_limit_hit,omitempty"`
 
@@ -134,31 +466,114 @@ type LimitViolationResult struct {
 	GridImportLimitExceeded bool `json:"grid_import_limit_exceeded,omitempty"`
 }
 
+// ObjectiveWeights Weights trading the economic benefit of the strategy objective against battery wear, grid exchange
+// and peak import, e.g. to cycle small home batteries less than the final state of charge value p_a
+// alone expresses. Battery throughput (charge plus discharge), grid exchange (import plus export)
+// and the peak import power over one hour are valued at the mean import price, so that weights are
+// comparable to the cost weight. At least one weight must be positive. The weighted terms are not
+// part of the reported objective value. Cannot be combined with cost budget. Only used by the charge
+// schedule.
+type ObjectiveWeights struct {
+	// BatteryWear Weight of the battery throughput (Wh)
+	BatteryWear float32 `json:"battery_wear"`
+
+	// Cost Weight of the economic benefit of the strategy objective
+	Cost float32 `json:"cost"`
+
+	// PeakShaving Weight of the peak import power (W)
+	PeakShaving float32 `json:"peak_shaving"`
+
+	// SelfConsumption Weight of the grid import plus export (Wh)
+	SelfConsumption float32 `json:"self_consumption"`
+}
+
 // OptimizationInput defines model for OptimizationInput.
 type OptimizationInput struct {
+	// AttributeBatteries Report the contribution of each battery to the objective value, e.g. to decide whether a second,
+	// smaller battery is worth keeping. The problem is solved again without each enabled battery, so
+	// the latency grows with the number of batteries. Only used by the charge schedule.
+	AttributeBatteries bool `json:"attribute_batteries,omitempty"`
+
 	// Batteries Configuration for all batteries in the system
 	Batteries []BatteryConfig `json:"batteries"`
+	Chp       *ChpConfig      `json:"chp,omitempty"`
+
+	// CostBudget Maximum acceptable net cost (import cost minus export revenue) over the horizon in currency units.
+	// If given, the optimizer runs in goal seeking mode: instead of minimizing cost, it minimizes
+	// battery throughput (wear) while keeping the cost within the budget. If the budget cannot
+	// be met, it is exceeded as little as possible and limit_violations.cost_budget_exceeded is set.
+	CostBudget float32 `json:"cost_budget,omitempty"`
 
 	// EtaC Charging efficiency (0 to 1)
 	EtaC float32 `json:"eta_c,omitempty"`
 
 	// EtaD Discharging efficiency (0 to 1)
-	EtaD       float32           `json:"eta_d,omitempty"`
-	Grid       GridConfig        `json:"grid,omitempty"`
-	Strategy   OptimizerStrategy `json:"strategy,omitempty"`
-	TimeSeries TimeSeries        `json:"time_series"`
+	EtaD float32    `json:"eta_d,omitempty"`
+	Grid GridConfig `json:"grid,omitempty"`
+
+	// MaxLatencyMs Hard real-time mode for controllers that must act immediately. Instead of solving the MILP,
+	// the LP relaxation is solved and its integer variables are rounded, followed by a second LP
+	// with the rounded values fixed. The latency (ms) is measured from the receipt of the request
+	// and limits both solves, a solve is not started once it is used up. The estimated optimality
+	// loss is returned as optimality_loss. If rounding does not yield a feasible solution or the
+	// latency is used up, the status is not Optimal.
+	MaxLatencyMs float32 `json:"max_latency_ms,omitempty"`
+
+	// MipGap Relative optimality gap at which the solve stops, trading optimality for latency. The
+	// objective of the result is within this fraction of the optimum. Defaults to the solver's gap.
+	MipGap           float32           `json:"mip_gap,omitempty"`
+	ObjectiveWeights *ObjectiveWeights `json:"objective_weights,omitempty"`
+	Output           OutputOptions     `json:"output,omitempty"`
+
+	// Penalties Custom linear penalty terms added to the cost, covering niche objectives without dedicated fields.
+	// Terms are sums of numbers times the series grid_import, grid_export and charge[i], discharge[i],
+	// soc[i] of battery i given by index or id, all in Wh. A series with a time step is its value at
+	// that step, with a slice the sum over the steps, without either the sum over the horizon. Negative
+	// terms are bonuses. Penalties are not part of the reported objective value. Only used by the charge
+	// schedule.
+	Penalties []string `json:"penalties,omitempty"`
+
+	// RequireOptimal If the solve hits the time limit before proving optimality within mip_gap, return no plan
+	// with status Not Solved instead of the best solution found. Combined with simplify_on_timeout,
+	// the approximation is returned instead.
+	RequireOptimal bool `json:"require_optimal,omitempty"`
+
+	// SimplifyOnTimeout If the solve hits the server's time limit without a solution, e.g. during server congestion,
+	// retry with the approximation of max_latency_ms mode instead of returning no plan. The solve
+	// gets 80% of the time limit, the approximation the rest. Such results are marked as simplified.
+	// Has no effect without a time limit.
+	SimplifyOnTimeout bool              `json:"simplify_on_timeout,omitempty"`
+	Strategy          OptimizerStrategy `json:"strategy,omitempty"`
+
+	// TimeLimit Time limit of the solve in seconds, e.g. for controllers acting in a fixed loop. Capped by the
+	// server's time limit. If the limit is hit, the best solution found is returned with status
+	// Optimal unless require_optimal is set.
+	TimeLimit  float32    `json:"time_limit,omitempty"`
+	TimeSeries TimeSeries `json:"time_series"`
+	WarmStart  *WarmStart `json:"warm_start,omitempty"`
 }
 
 // OptimizationResult defines model for OptimizationResult.
 type OptimizationResult struct {
 	// Batteries Optimization results for each battery
 	Batteries []BatteryResult `json:"batteries,omitempty"`
+	Chp       *ChpResult      `json:"chp"`
+
+	// CurtailmentRisk Intervals at risk of export curtailment according to time_series.r_curt. Empty if not given.
+	CurtailmentRisk []bool `json:"curtailment_risk,omitempty"`
+
+	// ExportPreferenceScore Achieved export preference as sum of w_E times grid export (currency units). Null if w_E is not given.
+	ExportPreferenceScore float32 `json:"export_preference_score"`
 
 	// FlowDirection Binary flow direction at each time step:
 	// - 0: Import from grid
 	// - 1: Export to grid
 	FlowDirection []OptimizationResultFlowDirection `json:"flow_direction,omitempty"`
 
+	// GridDeviation Deviation of the net grid import from the committed schedule n_commit at each time step (Wh),
+	// positive for more import or less export than committed. Empty if no schedule is committed.
+	GridDeviation []float32 `json:"grid_deviation,omitempty"`
+
 	// GridExport Energy exported to grid at each time step (Wh)
 	GridExport []float32 `json:"grid_export,omitempty"`
 
@@ -169,30 +584,53 @@ type OptimizationResult struct {
 	GridImport []float32 `json:"grid_import,omitempty"`
 
 	// GridImportOvershoot Energy above the power limit imported from grid at each time step (Wh)
-	GridImportOvershoot []float32            `json:"grid_import_overshoot,omitempty"`
-	LimitViolations     LimitViolationResult `json:"limit_violations,omitempty"`
+	GridImportOvershoot []float32 `json:"grid_import_overshoot,omitempty"`
 
-	// ObjectiveValue Optimal objective function value (economic benefit in currency units). Null if not optimal.
+	// GridImportPeak Peak import power of the plan in W including p_peak_to_date, if a peak price curve is given.
+	GridImportPeak float32 `json:"grid_import_peak"`
+
+	// LatencyMs Time spent building and solving the model (ms)
+	LatencyMs       float32              `json:"latency_ms,omitempty"`
+	LimitViolations LimitViolationResult `json:"limit_violations,omitempty"`
+
+	// ObjectiveUnit Unit of the objective value depending on the strategy objective
+	ObjectiveUnit OptimizationResultObjectiveUnit `json:"objective_unit,omitempty"`
+
+	// ObjectiveValue Optimal objective function value (economic benefit in objective_unit). Null if not optimal.
 	ObjectiveValue float32 `json:"objective_value"`
 
+	// OptimalityLoss Estimated optimality loss of an approximate solution in max_latency_ms mode: the difference between the
+	// objective of the LP relaxation, which bounds the optimum, and the objective of the rounded solution.
+	// Includes penalties and strategy incentives. Null if the problem was solved exactly.
+	OptimalityLoss float32 `json:"optimality_loss"`
+
+	// Simplified The solve timed out and the result was computed by the approximation of simplify_on_timeout.
+	// The estimated optimality loss is returned as optimality_loss.
+	Simplified bool `json:"simplified,omitempty"`
+
 	// Status Optimization solver status:
 	// - Optimal: Problem solved to optimality
 	// - Infeasible: No feasible solution exists
 	// - Unbounded: Objective function is unbounded
 	// - Undefined: Problem status is undefined
 	// - Not Solved: Problem was not solved
+	// - Simulated: Result of a dispatch policy simulation
 	Status OptimizationResultStatus `json:"status,omitempty"`
 }
 
 // OptimizationResultFlowDirection defines model for OptimizationResult.FlowDirection.
 type OptimizationResultFlowDirection int
 
+// OptimizationResultObjectiveUnit Unit of the objective value depending on the strategy objective
+type OptimizationResultObjectiveUnit string
+
 // OptimizationResultStatus Optimization solver status:
 // - Optimal: Problem solved to optimality
 // - Infeasible: No feasible solution exists
 // - Unbounded: Objective function is unbounded
 // - Undefined: Problem status is undefined
 // - Not Solved: Problem was not solved
+// - Simulated: Result of a dispatch policy simulation
 type OptimizationResultStatus string
 
 // OptimizerStrategy defines model for OptimizerStrategy.
@@ -207,6 +645,22 @@ type OptimizerStrategy struct {
 	// - none (default): no strategy set
 	// - discharge_before_import: discharge batteries before importing from grid
 	DischargingStrategy OptimizerStrategyDischargingStrategy `json:"discharging_strategy,omitempty"`
+
+	// Objective Quantity to optimize. The unit of the objective value is returned as objective_unit.
+	// - cost (default): economic benefit based on p_N and p_E (currency)
+	// - energy: grid import energy, e.g. if no tariff is available (Wh)
+	// - emissions: emissions of grid import based on time_series.em_N (gCO2)
+	// For energy and emissions, grid export is not credited and the final state of charge is
+	// valued at the lowest import it avoids. They cannot be combined with demand rate,
+	// tiered tariff, deviation price or cost budget.
+	Objective OptimizerStrategyObjective `json:"objective,omitempty"`
+
+	// SpikeGuardHours Price spike guard. Determines the most expensive import time steps within the next 24 hours
+	// covering this many hours. At the start of each of them, batteries able to discharge keep
+	// enough energy stored to cover the net demand (gt - ft) of this and all later guarded time
+	// steps, even if pure cost optimization would discharge earlier. This protects against
+	// forecast errors around price spikes. 0 disables the guard.
+	SpikeGuardHours float32 `json:"spike_guard_hours,omitempty"`
 }
 
 // OptimizerStrategyChargingStrategy Sets a strategy for charging in situations where choices are cost neutral.
@@ -220,27 +674,332 @@ type OptimizerStrategyChargingStrategy string
 // - discharge_before_import: discharge batteries before importing from grid
 type OptimizerStrategyDischargingStrategy string
 
+// OptimizerStrategyObjective Quantity to optimize. The unit of the objective value is returned as objective_unit.
+// - cost (default): economic benefit based on p_N and p_E (currency)
+// - energy: grid import energy, e.g. if no tariff is available (Wh)
+// - emissions: emissions of grid import based on time_series.em_N (gCO2)
+// For energy and emissions, grid export is not credited and the final state of charge is
+// valued at the lowest import it avoids. They cannot be combined with demand rate,
+// tiered tariff, deviation price or cost budget.
+type OptimizerStrategyObjective string
+
+// OutputOptions defines model for OutputOptions.
+type OutputOptions struct {
+	// Fields Top-level result fields to return, e.g. batteries and grid_import. Status is always
+	// returned. All fields are returned if omitted.
+	Fields []string `json:"fields,omitempty"`
+
+	// Precision Round all result series to this number of decimals. With 0, series are returned as
+	// integers. Scalar values like the objective value are not rounded. Energy series like
+	// charging power and grid import are rounded without accumulating rounding errors, their
+	// sums equal the rounded sums of the exact series.
+	Precision *int `json:"precision,omitempty"`
+}
+
+// PeakPricePoint defines model for PeakPricePoint.
+type PeakPricePoint struct {
+	// Power Peak import power in W from which the price applies
+	Power float32 `json:"power"`
+
+	// Price Price per W of peak import power above this power
+	Price float32 `json:"price"`
+}
+
+// Preconditioning Vehicle preconditioning load, e.g. cabin heating before departure. It runs once for the given number
+// of contiguous time steps within its window, starting in the time step the optimizer finds cheapest.
+// While the vehicle is available, the load is supplied by the charger and adds to the grid exchange,
+// otherwise it is drawn from the battery and counts against the goals at departure.
+type Preconditioning struct {
+	// Power Preconditioning power in W
+	Power float32 `json:"power"`
+
+	// Steps Number of contiguous time steps to run for
+	Steps int `json:"steps"`
+
+	// TEnd Index of the time step the preconditioning must be finished by, e.g. the departure
+	TEnd int `json:"t_end"`
+
+	// TStart Index of the first time step the preconditioning may run in
+	TStart int `json:"t_start,omitempty"`
+}
+
+// SimulationInput defines model for SimulationInput.
+type SimulationInput struct {
+	// AttributeBatteries Report the contribution of each battery to the objective value, e.g. to decide whether a second,
+	// smaller battery is worth keeping. The problem is solved again without each enabled battery, so
+	// the latency grows with the number of batteries. Only used by the charge schedule.
+	AttributeBatteries bool `json:"attribute_batteries,omitempty"`
+
+	// Batteries Configuration for all batteries in the system
+	Batteries []BatteryConfig `json:"batteries"`
+	Chp       *ChpConfig      `json:"chp,omitempty"`
+
+	// CostBudget Maximum acceptable net cost (import cost minus export revenue) over the horizon in currency units.
+	// If given, the optimizer runs in goal seeking mode: instead of minimizing cost, it minimizes
+	// battery throughput (wear) while keeping the cost within the budget. If the budget cannot
+	// be met, it is exceeded as little as possible and limit_violations.cost_budget_exceeded is set.
+	CostBudget float32 `json:"cost_budget,omitempty"`
+
+	// EtaC Charging efficiency (0 to 1)
+	EtaC float32 `json:"eta_c,omitempty"`
+
+	// EtaD Discharging efficiency (0 to 1)
+	EtaD float32    `json:"eta_d,omitempty"`
+	Grid GridConfig `json:"grid,omitempty"`
+
+	// MaxLatencyMs Hard real-time mode for controllers that must act immediately. Instead of solving the MILP,
+	// the LP relaxation is solved and its integer variables are rounded, followed by a second LP
+	// with the rounded values fixed. The latency (ms) is measured from the receipt of the request
+	// and limits both solves, a solve is not started once it is used up. The estimated optimality
+	// loss is returned as optimality_loss. If rounding does not yield a feasible solution or the
+	// latency is used up, the status is not Optimal.
+	MaxLatencyMs float32 `json:"max_latency_ms,omitempty"`
+
+	// MipGap Relative optimality gap at which the solve stops, trading optimality for latency. The
+	// objective of the result is within this fraction of the optimum. Defaults to the solver's gap.
+	MipGap           float32           `json:"mip_gap,omitempty"`
+	ObjectiveWeights *ObjectiveWeights `json:"objective_weights,omitempty"`
+	Output           OutputOptions     `json:"output,omitempty"`
+
+	// Penalties Custom linear penalty terms added to the cost, covering niche objectives without dedicated fields.
+	// Terms are sums of numbers times the series grid_import, grid_export and charge[i], discharge[i],
+	// soc[i] of battery i given by index or id, all in Wh. A series with a time step is its value at
+	// that step, with a slice the sum over the steps, without either the sum over the horizon. Negative
+	// terms are bonuses. Penalties are not part of the reported objective value. Only used by the charge
+	// schedule.
+	Penalties []string `json:"penalties,omitempty"`
+
+	// Policy Fixed dispatch policy to simulate
+	Policy SimulationInputPolicy `json:"policy,omitempty"`
+
+	// RequireOptimal If the solve hits the time limit before proving optimality within mip_gap, return no plan
+	// with status Not Solved instead of the best solution found. Combined with simplify_on_timeout,
+	// the approximation is returned instead.
+	RequireOptimal bool `json:"require_optimal,omitempty"`
+
+	// SimplifyOnTimeout If the solve hits the server's time limit without a solution, e.g. during server congestion,
+	// retry with the approximation of max_latency_ms mode instead of returning no plan. The solve
+	// gets 80% of the time limit, the approximation the rest. Such results are marked as simplified.
+	// Has no effect without a time limit.
+	SimplifyOnTimeout bool              `json:"simplify_on_timeout,omitempty"`
+	Strategy          OptimizerStrategy `json:"strategy,omitempty"`
+
+	// TimeLimit Time limit of the solve in seconds, e.g. for controllers acting in a fixed loop. Capped by the
+	// server's time limit. If the limit is hit, the best solution found is returned with status
+	// Optimal unless require_optimal is set.
+	TimeLimit  float32    `json:"time_limit,omitempty"`
+	TimeSeries TimeSeries `json:"time_series"`
+	WarmStart  *WarmStart `json:"warm_start,omitempty"`
+}
+
+// SimulationInputPolicy Fixed dispatch policy to simulate
+type SimulationInputPolicy string
+
+// StrategyDescription defines model for StrategyDescription.
+type StrategyDescription struct {
+	// Default Value used if the parameter is omitted
+	Default string `json:"default"`
+
+	// Description Description of the parameter
+	Description string `json:"description,omitempty"`
+
+	// Parameter Name of the parameter in the strategy object of the request
+	Parameter string `json:"parameter"`
+
+	// Title Short display name
+	Title string `json:"title,omitempty"`
+
+	// Values Supported values of the parameter
+	Values []StrategyValue `json:"values"`
+}
+
+// StrategyValue defines model for StrategyValue.
+type StrategyValue struct {
+	// Description Description of the behaviour
+	Description string `json:"description,omitempty"`
+
+	// Title Short display name
+	Title string `json:"title,omitempty"`
+	Value string `json:"value"`
+}
+
+// Template Static site definition referenced by requests carrying the dynamic inputs only
+type Template struct {
+	// Batteries Battery configurations, dynamic fields like s_initial are given by the requests
+	Batteries []BatteryConfig `json:"batteries,omitempty"`
+
+	// EtaC Default charging efficiency
+	EtaC float32 `json:"eta_c,omitempty"`
+
+	// EtaD Default discharging efficiency
+	EtaD     float32           `json:"eta_d,omitempty"`
+	Grid     GridConfig        `json:"grid,omitempty"`
+	Strategy OptimizerStrategy `json:"strategy,omitempty"`
+}
+
+// TemplateBattery Dynamic inputs of a battery, merged into the template battery of the same index
+type TemplateBattery struct {
+	// Available Availability at each time step
+	Available []bool `json:"available,omitempty"`
+
+	// CEtaSeries Charging efficiency at each time step
+	CEtaSeries []float32 `json:"c_eta_series,omitempty"`
+
+	// Enabled Include the battery in the optimization
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// PDemand Minimum charge demand at each time step (Wh)
+	PDemand         []float32        `json:"p_demand,omitempty"`
+	Preconditioning *Preconditioning `json:"preconditioning,omitempty"`
+
+	// SGoal Goal state of charge at each time step (Wh)
+	SGoal []float32 `json:"s_goal,omitempty"`
+
+	// SInitial Initial state of charge (Wh)
+	SInitial float32 `json:"s_initial"`
+
+	// SInitialStddev Standard deviation of the measured initial state of charge (Wh)
+	SInitialStddev float32 `json:"s_initial_stddev,omitempty"`
+}
+
+// TemplateId defines model for TemplateId.
+type TemplateId struct {
+	// Id Template id to reference in requests
+	Id string `json:"id,omitempty"`
+}
+
+// TemplateInput defines model for TemplateInput.
+type TemplateInput struct {
+	// Batteries Dynamic battery inputs by index of the template batteries
+	Batteries  []TemplateBattery `json:"batteries,omitempty"`
+	Grid       GridConfig        `json:"grid,omitempty"`
+	Output     OutputOptions     `json:"output,omitempty"`
+	TimeSeries TimeSeries        `json:"time_series"`
+}
+
+// TemplateList defines model for TemplateList.
+type TemplateList struct {
+	// Ids Ids of all templates of the caller
+	Ids []string `json:"ids,omitempty"`
+}
+
 // TimeSeries defines model for TimeSeries.
 type TimeSeries struct {
 	// Dt Duration in seconds for each time step (s)
 	Dt []int `json:"dt"`
 
-	// Ft Forecasted energy generation (e.g., solar PV) at each time step (Wh)
-	Ft []float32 `json:"ft"`
+	// EmN Emissions of grid import per Wh at each time step (gCO2/Wh). Required for the emissions objective.
+	EmN []float32 `json:"em_N,omitempty"`
+
+	// Ft Forecasted energy generation (e.g., solar PV) at each time step (Wh). Defaults to no
+	// generation, e.g. for pure energy arbitrage.
+	Ft []float32 `json:"ft,omitempty"`
 
-	// Gt Household energy demand at each time step (Wh)
-	Gt []float32 `json:"gt"`
+	// Gt Household energy demand at each time step (Wh). Negative values denote uncontrolled
+	// generation not covered by ft (e.g. balcony PV folded into the load measurement) exceeding
+	// the household demand. This energy has to be consumed, stored or exported and is treated
+	// like generation from ft, including reduction by the export limit. Defaults to no demand,
+	// e.g. for pure energy arbitrage.
+	Gt []float32 `json:"gt,omitempty"`
+
+	// NCommit Committed net grid import at each time step (Wh), negative for export, e.g. a schedule
+	// pre-committed under a balancing responsibility. Deviations are charged with grid.prc_e_dev
+	// and reported as grid_deviation. Requires grid.prc_e_dev and vice versa.
+	NCommit []float32 `json:"n_commit,omitempty"`
 
 	// PE Grid export remuneration per Wh at each time step (currency units/Wh)
 	PE []float32 `json:"p_E"`
 
+	// PG Gas price per Wh of fuel at each time step (currency units/Wh) of the combined heat and power
+	// unit chp and its boiler. Requires chp and q_H.
+	PG []float32 `json:"p_G,omitempty"`
+
 	// PN Grid import price per Wh at each time step (currency units/Wh)
 	PN []float32 `json:"p_N"`
+
+	// PMaxImp Maximum grid import power at each time step (W), e.g. curtailment windows of controllable
+	// devices announced by the grid operator under §14a EnWG. Applies in addition to grid.p_max_imp,
+	// the lower limit wins. Cannot be combined with grid.prc_p_exc_imp.
+	PMaxImp []float32 `json:"p_max_imp,omitempty"`
+
+	// QH Heat demand at each time step (Wh), covered by the combined heat and power unit chp, its boiler
+	// and electric heating. Requires chp and p_G.
+	QH []float32 `json:"q_H,omitempty"`
+
+	// RCurt Probability of export curtailment by the grid operator at each time step (0 to 1), e.g. from a
+	// curtailment forecast. Export revenue is discounted by this probability, favouring storage of
+	// generation over export in risky intervals. Intervals with a probability of at least 0.5 are
+	// flagged in curtailment_risk of the result.
+	RCurt []float32 `json:"r_curt,omitempty"`
+
+	// WE Soft export preference at each time step (currency units/Wh), e.g. for community programs rewarding
+	// export during local high demand. The weight is a bonus per Wh exported that biases the dispatch
+	// towards export in preferred intervals without forcing it. The bonus is not part of the objective
+	// value and is reported as export_preference_score.
+	WE []float32 `json:"w_E,omitempty"`
+}
+
+// ValidationResult defines model for ValidationResult.
+type ValidationResult struct {
+	Request OptimizationInput `json:"request,omitempty"`
+
+	// Warnings Hints on inputs that are valid but likely not intended
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// WarmStart Previous schedule shifted to the time steps of the request as starting solution of the solver,
+// e.g. for successive rolling horizon runs. Series may be shorter than the horizon, later time
+// steps are not hinted. The hint only speeds up the solve, it does not change the optimum.
+type WarmStart struct {
+	// Batteries Previous schedule of each battery by index of the request batteries
+	Batteries []WarmStartBattery `json:"batteries,omitempty"`
+
+	// GridExport Previous grid export at each time step (Wh)
+	GridExport []float32 `json:"grid_export,omitempty"`
+
+	// GridImport Previous grid import at each time step (Wh)
+	GridImport []float32 `json:"grid_import,omitempty"`
+}
+
+// WarmStartBattery defines model for WarmStartBattery.
+type WarmStartBattery struct {
+	// ChargingPower Previous charging energy at each time step (Wh)
+	ChargingPower []float32 `json:"charging_power,omitempty"`
+
+	// DischargingPower Previous discharging energy at each time step (Wh)
+	DischargingPower []float32 `json:"discharging_power,omitempty"`
+}
+
+// GetOptimizeEstimateParams defines parameters for GetOptimizeEstimate.
+type GetOptimizeEstimateParams struct {
+	// TimeSteps Number of time steps of the problem
+	TimeSteps int `form:"time_steps" json:"time_steps"`
+
+	// Batteries Number of batteries of the problem
+	Batteries *int `form:"batteries,omitempty" json:"batteries,omitempty"`
 }
 
 // PostOptimizeChargeScheduleJSONRequestBody defines body for PostOptimizeChargeSchedule for application/json ContentType.
 type PostOptimizeChargeScheduleJSONRequestBody = OptimizationInput
 
+// PostOptimizeJobsJSONRequestBody defines body for PostOptimizeJobs for application/json ContentType.
+type PostOptimizeJobsJSONRequestBody = OptimizationInput
+
+// PostOptimizeSimulateJSONRequestBody defines body for PostOptimizeSimulate for application/json ContentType.
+type PostOptimizeSimulateJSONRequestBody = SimulationInput
+
+// PostOptimizeStreamJSONRequestBody defines body for PostOptimizeStream for application/json ContentType.
+type PostOptimizeStreamJSONRequestBody = OptimizationInput
+
+// PostOptimizeTemplatesJSONRequestBody defines body for PostOptimizeTemplates for application/json ContentType.
+type PostOptimizeTemplatesJSONRequestBody = Template
+
+// PostOptimizeTemplatesIdChargeScheduleJSONRequestBody defines body for PostOptimizeTemplatesIdChargeSchedule for application/json ContentType.
+type PostOptimizeTemplatesIdChargeScheduleJSONRequestBody = TemplateInput
+
+// PostOptimizeValidateJSONRequestBody defines body for PostOptimizeValidate for application/json ContentType.
+type PostOptimizeValidateJSONRequestBody = OptimizationInput
+
 // RequestEditorFn  is the function signature for the RequestEditor callback function
 type RequestEditorFn func(ctx context.Context, req *http.Request) error
 
@@ -319,8 +1078,62 @@ type ClientInterface interface {
 
 	PostOptimizeChargeSchedule(ctx context.Context, body PostOptimizeChargeScheduleJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
 
+	// GetOptimizeEstimate request
+	GetOptimizeEstimate(ctx context.Context, params *GetOptimizeEstimateParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// GetOptimizeHealth request
 	GetOptimizeHealth(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostOptimizeJobsWithBody request with any body
+	PostOptimizeJobsWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostOptimizeJobs(ctx context.Context, body PostOptimizeJobsJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DeleteOptimizeJobsId request
+	DeleteOptimizeJobsId(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetOptimizeJobsId request
+	GetOptimizeJobsId(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetOptimizeJobsIdResult request
+	GetOptimizeJobsIdResult(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostOptimizeSimulateWithBody request with any body
+	PostOptimizeSimulateWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostOptimizeSimulate(ctx context.Context, body PostOptimizeSimulateJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetOptimizeStrategies request
+	GetOptimizeStrategies(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostOptimizeStreamWithBody request with any body
+	PostOptimizeStreamWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostOptimizeStream(ctx context.Context, body PostOptimizeStreamJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetOptimizeTemplates request
+	GetOptimizeTemplates(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostOptimizeTemplatesWithBody request with any body
+	PostOptimizeTemplatesWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostOptimizeTemplates(ctx context.Context, body PostOptimizeTemplatesJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DeleteOptimizeTemplatesId request
+	DeleteOptimizeTemplatesId(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetOptimizeTemplatesId request
+	GetOptimizeTemplatesId(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostOptimizeTemplatesIdChargeScheduleWithBody request with any body
+	PostOptimizeTemplatesIdChargeScheduleWithBody(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostOptimizeTemplatesIdChargeSchedule(ctx context.Context, id string, body PostOptimizeTemplatesIdChargeScheduleJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// PostOptimizeValidateWithBody request with any body
+	PostOptimizeValidateWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PostOptimizeValidate(ctx context.Context, body PostOptimizeValidateJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
 }
 
 func (c *Client) PostOptimizeChargeScheduleWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
@@ -347,8 +1160,8 @@ func (c *Client) PostOptimizeChargeSchedule(ctx context.Context, body PostOptimi
 	return c.Client.Do(req)
 }
 
-func (c *Client) GetOptimizeHealth(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	req, err := NewGetOptimizeHealthRequest(c.Server)
+func (c *Client) GetOptimizeEstimate(ctx context.Context, params *GetOptimizeEstimateParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetOptimizeEstimateRequest(c.Server, params)
 	if err != nil {
 		return nil, err
 	}
@@ -359,209 +1172,1990 @@ func (c *Client) GetOptimizeHealth(ctx context.Context, reqEditors ...RequestEdi
 	return c.Client.Do(req)
 }
 
-// NewPostOptimizeChargeScheduleRequest calls the generic PostOptimizeChargeSchedule builder with application/json body
-func NewPostOptimizeChargeScheduleRequest(server string, body PostOptimizeChargeScheduleJSONRequestBody) (*http.Request, error) {
-	var bodyReader io.Reader
-	buf, err := json.Marshal(body)
+func (c *Client) GetOptimizeHealth(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetOptimizeHealthRequest(c.Server)
 	if err != nil {
 		return nil, err
 	}
-	bodyReader = bytes.NewReader(buf)
-	return NewPostOptimizeChargeScheduleRequestWithBody(server, "application/json", bodyReader)
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
 }
 
-// NewPostOptimizeChargeScheduleRequestWithBody generates requests for PostOptimizeChargeSchedule with any type of body
-func NewPostOptimizeChargeScheduleRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
-	var err error
-
-	serverURL, err := url.Parse(server)
+func (c *Client) PostOptimizeJobsWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeJobsRequestWithBody(c.Server, contentType, body)
 	if err != nil {
 		return nil, err
 	}
-
-	operationPath := fmt.Sprintf("/optimize/charge-schedule")
-	if operationPath[0] == '/' {
-		operationPath = "." + operationPath
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
 	}
+	return c.Client.Do(req)
+}
 
-	queryURL, err := serverURL.Parse(operationPath)
+func (c *Client) PostOptimizeJobs(ctx context.Context, body PostOptimizeJobsJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeJobsRequest(c.Server, body)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
 
-	req, err := http.NewRequest("POST", queryURL.String(), body)
+func (c *Client) DeleteOptimizeJobsId(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDeleteOptimizeJobsIdRequest(c.Server, id)
 	if err != nil {
 		return nil, err
 	}
-
-	req.Header.Add("Content-Type", contentType)
-
-	return req, nil
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
 }
 
-// NewGetOptimizeHealthRequest generates requests for GetOptimizeHealth
-func NewGetOptimizeHealthRequest(server string) (*http.Request, error) {
-	var err error
-
-	serverURL, err := url.Parse(server)
+func (c *Client) GetOptimizeJobsId(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetOptimizeJobsIdRequest(c.Server, id)
 	if err != nil {
 		return nil, err
 	}
-
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetOptimizeJobsIdResult(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetOptimizeJobsIdResultRequest(c.Server, id)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostOptimizeSimulateWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeSimulateRequestWithBody(c.Server, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostOptimizeSimulate(ctx context.Context, body PostOptimizeSimulateJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeSimulateRequest(c.Server, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetOptimizeStrategies(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetOptimizeStrategiesRequest(c.Server)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostOptimizeStreamWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeStreamRequestWithBody(c.Server, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostOptimizeStream(ctx context.Context, body PostOptimizeStreamJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeStreamRequest(c.Server, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetOptimizeTemplates(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetOptimizeTemplatesRequest(c.Server)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostOptimizeTemplatesWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeTemplatesRequestWithBody(c.Server, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostOptimizeTemplates(ctx context.Context, body PostOptimizeTemplatesJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeTemplatesRequest(c.Server, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) DeleteOptimizeTemplatesId(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDeleteOptimizeTemplatesIdRequest(c.Server, id)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) GetOptimizeTemplatesId(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetOptimizeTemplatesIdRequest(c.Server, id)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostOptimizeTemplatesIdChargeScheduleWithBody(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeTemplatesIdChargeScheduleRequestWithBody(c.Server, id, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostOptimizeTemplatesIdChargeSchedule(ctx context.Context, id string, body PostOptimizeTemplatesIdChargeScheduleJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeTemplatesIdChargeScheduleRequest(c.Server, id, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostOptimizeValidateWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeValidateRequestWithBody(c.Server, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) PostOptimizeValidate(ctx context.Context, body PostOptimizeValidateJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostOptimizeValidateRequest(c.Server, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+// NewPostOptimizeChargeScheduleRequest calls the generic PostOptimizeChargeSchedule builder with application/json body
+func NewPostOptimizeChargeScheduleRequest(server string, body PostOptimizeChargeScheduleJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostOptimizeChargeScheduleRequestWithBody(server, "application/json", bodyReader)
+}
+
+// NewPostOptimizeChargeScheduleRequestWithBody generates requests for PostOptimizeChargeSchedule with any type of body
+func NewPostOptimizeChargeScheduleRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/charge-schedule")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewGetOptimizeEstimateRequest generates requests for GetOptimizeEstimate
+func NewGetOptimizeEstimateRequest(server string, params *GetOptimizeEstimateParams) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/estimate")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if params != nil {
+		queryValues := queryURL.Query()
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "time_steps", runtime.ParamLocationQuery, params.TimeSteps); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+		if params.Batteries != nil {
+
+			if queryFrag, err := runtime.StyleParamWithLocation("form", true, "batteries", runtime.ParamLocationQuery, *params.Batteries); err != nil {
+				return nil, err
+			} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+				return nil, err
+			} else {
+				for k, v := range parsed {
+					for _, v2 := range v {
+						queryValues.Add(k, v2)
+					}
+				}
+			}
+
+		}
+
+		queryURL.RawQuery = queryValues.Encode()
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetOptimizeHealthRequest generates requests for GetOptimizeHealth
+func NewGetOptimizeHealthRequest(server string) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
 	operationPath := fmt.Sprintf("/optimize/health")
 	if operationPath[0] == '/' {
 		operationPath = "." + operationPath
 	}
 
-	queryURL, err := serverURL.Parse(operationPath)
-	if err != nil {
-		return nil, err
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewPostOptimizeJobsRequest calls the generic PostOptimizeJobs builder with application/json body
+func NewPostOptimizeJobsRequest(server string, body PostOptimizeJobsJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostOptimizeJobsRequestWithBody(server, "application/json", bodyReader)
+}
+
+// NewPostOptimizeJobsRequestWithBody generates requests for PostOptimizeJobs with any type of body
+func NewPostOptimizeJobsRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/jobs")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewDeleteOptimizeJobsIdRequest generates requests for DeleteOptimizeJobsId
+func NewDeleteOptimizeJobsIdRequest(server string, id string) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/jobs/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("DELETE", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetOptimizeJobsIdRequest generates requests for GetOptimizeJobsId
+func NewGetOptimizeJobsIdRequest(server string, id string) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/jobs/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetOptimizeJobsIdResultRequest generates requests for GetOptimizeJobsIdResult
+func NewGetOptimizeJobsIdResultRequest(server string, id string) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/jobs/%s/result", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewPostOptimizeSimulateRequest calls the generic PostOptimizeSimulate builder with application/json body
+func NewPostOptimizeSimulateRequest(server string, body PostOptimizeSimulateJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostOptimizeSimulateRequestWithBody(server, "application/json", bodyReader)
+}
+
+// NewPostOptimizeSimulateRequestWithBody generates requests for PostOptimizeSimulate with any type of body
+func NewPostOptimizeSimulateRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/simulate")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewGetOptimizeStrategiesRequest generates requests for GetOptimizeStrategies
+func NewGetOptimizeStrategiesRequest(server string) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/strategies")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewPostOptimizeStreamRequest calls the generic PostOptimizeStream builder with application/json body
+func NewPostOptimizeStreamRequest(server string, body PostOptimizeStreamJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostOptimizeStreamRequestWithBody(server, "application/json", bodyReader)
+}
+
+// NewPostOptimizeStreamRequestWithBody generates requests for PostOptimizeStream with any type of body
+func NewPostOptimizeStreamRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/stream")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewGetOptimizeTemplatesRequest generates requests for GetOptimizeTemplates
+func NewGetOptimizeTemplatesRequest(server string) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/templates")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewPostOptimizeTemplatesRequest calls the generic PostOptimizeTemplates builder with application/json body
+func NewPostOptimizeTemplatesRequest(server string, body PostOptimizeTemplatesJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostOptimizeTemplatesRequestWithBody(server, "application/json", bodyReader)
+}
+
+// NewPostOptimizeTemplatesRequestWithBody generates requests for PostOptimizeTemplates with any type of body
+func NewPostOptimizeTemplatesRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/templates")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewDeleteOptimizeTemplatesIdRequest generates requests for DeleteOptimizeTemplatesId
+func NewDeleteOptimizeTemplatesIdRequest(server string, id string) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/templates/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("DELETE", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetOptimizeTemplatesIdRequest generates requests for GetOptimizeTemplatesId
+func NewGetOptimizeTemplatesIdRequest(server string, id string) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/templates/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewPostOptimizeTemplatesIdChargeScheduleRequest calls the generic PostOptimizeTemplatesIdChargeSchedule builder with application/json body
+func NewPostOptimizeTemplatesIdChargeScheduleRequest(server string, id string, body PostOptimizeTemplatesIdChargeScheduleJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostOptimizeTemplatesIdChargeScheduleRequestWithBody(server, id, "application/json", bodyReader)
+}
+
+// NewPostOptimizeTemplatesIdChargeScheduleRequestWithBody generates requests for PostOptimizeTemplatesIdChargeSchedule with any type of body
+func NewPostOptimizeTemplatesIdChargeScheduleRequestWithBody(server string, id string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "id", runtime.ParamLocationPath, id)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/templates/%s/charge-schedule", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewPostOptimizeValidateRequest calls the generic PostOptimizeValidate builder with application/json body
+func NewPostOptimizeValidateRequest(server string, body PostOptimizeValidateJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPostOptimizeValidateRequestWithBody(server, "application/json", bodyReader)
+}
+
+// NewPostOptimizeValidateRequestWithBody generates requests for PostOptimizeValidate with any type of body
+func NewPostOptimizeValidateRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/optimize/validate")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
+	for _, r := range c.RequestEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	for _, r := range additionalEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClientWithResponses builds on ClientInterface to offer response payloads
+type ClientWithResponses struct {
+	ClientInterface
+}
+
+// NewClientWithResponses creates a new ClientWithResponses, which wraps
+// Client with return type handling
+func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
+	client, err := NewClient(server, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientWithResponses{client}, nil
+}
+
+// WithBaseURL overrides the baseURL.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) error {
+		newBaseURL, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+		c.Server = newBaseURL.String()
+		return nil
+	}
+}
+
+// ClientWithResponsesInterface is the interface specification for the client with responses above.
+type ClientWithResponsesInterface interface {
+	// PostOptimizeChargeScheduleWithBodyWithResponse request with any body
+	PostOptimizeChargeScheduleWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeChargeScheduleResponse, error)
+
+	PostOptimizeChargeScheduleWithResponse(ctx context.Context, body PostOptimizeChargeScheduleJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeChargeScheduleResponse, error)
+
+	// GetOptimizeEstimateWithResponse request
+	GetOptimizeEstimateWithResponse(ctx context.Context, params *GetOptimizeEstimateParams, reqEditors ...RequestEditorFn) (*GetOptimizeEstimateResponse, error)
+
+	// GetOptimizeHealthWithResponse request
+	GetOptimizeHealthWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetOptimizeHealthResponse, error)
+
+	// PostOptimizeJobsWithBodyWithResponse request with any body
+	PostOptimizeJobsWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeJobsResponse, error)
+
+	PostOptimizeJobsWithResponse(ctx context.Context, body PostOptimizeJobsJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeJobsResponse, error)
+
+	// DeleteOptimizeJobsIdWithResponse request
+	DeleteOptimizeJobsIdWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*DeleteOptimizeJobsIdResponse, error)
+
+	// GetOptimizeJobsIdWithResponse request
+	GetOptimizeJobsIdWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetOptimizeJobsIdResponse, error)
+
+	// GetOptimizeJobsIdResultWithResponse request
+	GetOptimizeJobsIdResultWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetOptimizeJobsIdResultResponse, error)
+
+	// PostOptimizeSimulateWithBodyWithResponse request with any body
+	PostOptimizeSimulateWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeSimulateResponse, error)
+
+	PostOptimizeSimulateWithResponse(ctx context.Context, body PostOptimizeSimulateJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeSimulateResponse, error)
+
+	// GetOptimizeStrategiesWithResponse request
+	GetOptimizeStrategiesWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetOptimizeStrategiesResponse, error)
+
+	// PostOptimizeStreamWithBodyWithResponse request with any body
+	PostOptimizeStreamWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeStreamResponse, error)
+
+	PostOptimizeStreamWithResponse(ctx context.Context, body PostOptimizeStreamJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeStreamResponse, error)
+
+	// GetOptimizeTemplatesWithResponse request
+	GetOptimizeTemplatesWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetOptimizeTemplatesResponse, error)
+
+	// PostOptimizeTemplatesWithBodyWithResponse request with any body
+	PostOptimizeTemplatesWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeTemplatesResponse, error)
+
+	PostOptimizeTemplatesWithResponse(ctx context.Context, body PostOptimizeTemplatesJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeTemplatesResponse, error)
+
+	// DeleteOptimizeTemplatesIdWithResponse request
+	DeleteOptimizeTemplatesIdWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*DeleteOptimizeTemplatesIdResponse, error)
+
+	// GetOptimizeTemplatesIdWithResponse request
+	GetOptimizeTemplatesIdWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetOptimizeTemplatesIdResponse, error)
+
+	// PostOptimizeTemplatesIdChargeScheduleWithBodyWithResponse request with any body
+	PostOptimizeTemplatesIdChargeScheduleWithBodyWithResponse(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeTemplatesIdChargeScheduleResponse, error)
+
+	PostOptimizeTemplatesIdChargeScheduleWithResponse(ctx context.Context, id string, body PostOptimizeTemplatesIdChargeScheduleJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeTemplatesIdChargeScheduleResponse, error)
+
+	// PostOptimizeValidateWithBodyWithResponse request with any body
+	PostOptimizeValidateWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeValidateResponse, error)
+
+	PostOptimizeValidateWithResponse(ctx context.Context, body PostOptimizeValidateJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeValidateResponse, error)
+}
+
+type PostOptimizeChargeScheduleResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *OptimizationResult
+	JSON400      *Error
+	JSON500      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r PostOptimizeChargeScheduleResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostOptimizeChargeScheduleResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetOptimizeEstimateResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *EstimateResult
+	JSON400      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r GetOptimizeEstimateResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetOptimizeEstimateResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetOptimizeHealthResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *struct {
+		Message string `json:"message,omitempty"`
+		Status  string `json:"status,omitempty"`
+	}
+}
+
+// Status returns HTTPResponse.Status
+func (r GetOptimizeHealthResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetOptimizeHealthResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostOptimizeJobsResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON202      *Job
+	JSON400      *Error
+	JSON429      *Error
+	JSON503      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r PostOptimizeJobsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostOptimizeJobsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DeleteOptimizeJobsIdResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON404      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r DeleteOptimizeJobsIdResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DeleteOptimizeJobsIdResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetOptimizeJobsIdResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *Job
+	JSON404      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r GetOptimizeJobsIdResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetOptimizeJobsIdResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetOptimizeJobsIdResultResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *OptimizationResult
+	JSON404      *Error
+	JSON409      *Error
+	JSON500      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r GetOptimizeJobsIdResultResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetOptimizeJobsIdResultResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostOptimizeSimulateResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *OptimizationResult
+	JSON400      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r PostOptimizeSimulateResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostOptimizeSimulateResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetOptimizeStrategiesResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *[]StrategyDescription
+}
+
+// Status returns HTTPResponse.Status
+func (r GetOptimizeStrategiesResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetOptimizeStrategiesResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostOptimizeStreamResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON400      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r PostOptimizeStreamResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostOptimizeStreamResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetOptimizeTemplatesResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *TemplateList
+}
+
+// Status returns HTTPResponse.Status
+func (r GetOptimizeTemplatesResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetOptimizeTemplatesResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostOptimizeTemplatesResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON201      *TemplateId
+	JSON400      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r PostOptimizeTemplatesResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostOptimizeTemplatesResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DeleteOptimizeTemplatesIdResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON404      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r DeleteOptimizeTemplatesIdResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DeleteOptimizeTemplatesIdResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetOptimizeTemplatesIdResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *Template
+	JSON404      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r GetOptimizeTemplatesIdResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetOptimizeTemplatesIdResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostOptimizeTemplatesIdChargeScheduleResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *OptimizationResult
+	JSON400      *Error
+	JSON404      *Error
+	JSON500      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r PostOptimizeTemplatesIdChargeScheduleResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostOptimizeTemplatesIdChargeScheduleResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostOptimizeValidateResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ValidationResult
+	JSON400      *Error
+}
+
+// Status returns HTTPResponse.Status
+func (r PostOptimizeValidateResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostOptimizeValidateResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+// PostOptimizeChargeScheduleWithBodyWithResponse request with arbitrary body returning *PostOptimizeChargeScheduleResponse
+func (c *ClientWithResponses) PostOptimizeChargeScheduleWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeChargeScheduleResponse, error) {
+	rsp, err := c.PostOptimizeChargeScheduleWithBody(ctx, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeChargeScheduleResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostOptimizeChargeScheduleWithResponse(ctx context.Context, body PostOptimizeChargeScheduleJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeChargeScheduleResponse, error) {
+	rsp, err := c.PostOptimizeChargeSchedule(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeChargeScheduleResponse(rsp)
+}
+
+// GetOptimizeEstimateWithResponse request returning *GetOptimizeEstimateResponse
+func (c *ClientWithResponses) GetOptimizeEstimateWithResponse(ctx context.Context, params *GetOptimizeEstimateParams, reqEditors ...RequestEditorFn) (*GetOptimizeEstimateResponse, error) {
+	rsp, err := c.GetOptimizeEstimate(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetOptimizeEstimateResponse(rsp)
+}
+
+// GetOptimizeHealthWithResponse request returning *GetOptimizeHealthResponse
+func (c *ClientWithResponses) GetOptimizeHealthWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetOptimizeHealthResponse, error) {
+	rsp, err := c.GetOptimizeHealth(ctx, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetOptimizeHealthResponse(rsp)
+}
+
+// PostOptimizeJobsWithBodyWithResponse request with arbitrary body returning *PostOptimizeJobsResponse
+func (c *ClientWithResponses) PostOptimizeJobsWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeJobsResponse, error) {
+	rsp, err := c.PostOptimizeJobsWithBody(ctx, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeJobsResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostOptimizeJobsWithResponse(ctx context.Context, body PostOptimizeJobsJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeJobsResponse, error) {
+	rsp, err := c.PostOptimizeJobs(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeJobsResponse(rsp)
+}
+
+// DeleteOptimizeJobsIdWithResponse request returning *DeleteOptimizeJobsIdResponse
+func (c *ClientWithResponses) DeleteOptimizeJobsIdWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*DeleteOptimizeJobsIdResponse, error) {
+	rsp, err := c.DeleteOptimizeJobsId(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDeleteOptimizeJobsIdResponse(rsp)
+}
+
+// GetOptimizeJobsIdWithResponse request returning *GetOptimizeJobsIdResponse
+func (c *ClientWithResponses) GetOptimizeJobsIdWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetOptimizeJobsIdResponse, error) {
+	rsp, err := c.GetOptimizeJobsId(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetOptimizeJobsIdResponse(rsp)
+}
+
+// GetOptimizeJobsIdResultWithResponse request returning *GetOptimizeJobsIdResultResponse
+func (c *ClientWithResponses) GetOptimizeJobsIdResultWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetOptimizeJobsIdResultResponse, error) {
+	rsp, err := c.GetOptimizeJobsIdResult(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetOptimizeJobsIdResultResponse(rsp)
+}
+
+// PostOptimizeSimulateWithBodyWithResponse request with arbitrary body returning *PostOptimizeSimulateResponse
+func (c *ClientWithResponses) PostOptimizeSimulateWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeSimulateResponse, error) {
+	rsp, err := c.PostOptimizeSimulateWithBody(ctx, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeSimulateResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostOptimizeSimulateWithResponse(ctx context.Context, body PostOptimizeSimulateJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeSimulateResponse, error) {
+	rsp, err := c.PostOptimizeSimulate(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeSimulateResponse(rsp)
+}
+
+// GetOptimizeStrategiesWithResponse request returning *GetOptimizeStrategiesResponse
+func (c *ClientWithResponses) GetOptimizeStrategiesWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetOptimizeStrategiesResponse, error) {
+	rsp, err := c.GetOptimizeStrategies(ctx, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetOptimizeStrategiesResponse(rsp)
+}
+
+// PostOptimizeStreamWithBodyWithResponse request with arbitrary body returning *PostOptimizeStreamResponse
+func (c *ClientWithResponses) PostOptimizeStreamWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeStreamResponse, error) {
+	rsp, err := c.PostOptimizeStreamWithBody(ctx, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeStreamResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostOptimizeStreamWithResponse(ctx context.Context, body PostOptimizeStreamJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeStreamResponse, error) {
+	rsp, err := c.PostOptimizeStream(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeStreamResponse(rsp)
+}
+
+// GetOptimizeTemplatesWithResponse request returning *GetOptimizeTemplatesResponse
+func (c *ClientWithResponses) GetOptimizeTemplatesWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetOptimizeTemplatesResponse, error) {
+	rsp, err := c.GetOptimizeTemplates(ctx, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetOptimizeTemplatesResponse(rsp)
+}
+
+// PostOptimizeTemplatesWithBodyWithResponse request with arbitrary body returning *PostOptimizeTemplatesResponse
+func (c *ClientWithResponses) PostOptimizeTemplatesWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeTemplatesResponse, error) {
+	rsp, err := c.PostOptimizeTemplatesWithBody(ctx, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeTemplatesResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostOptimizeTemplatesWithResponse(ctx context.Context, body PostOptimizeTemplatesJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeTemplatesResponse, error) {
+	rsp, err := c.PostOptimizeTemplates(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeTemplatesResponse(rsp)
+}
+
+// DeleteOptimizeTemplatesIdWithResponse request returning *DeleteOptimizeTemplatesIdResponse
+func (c *ClientWithResponses) DeleteOptimizeTemplatesIdWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*DeleteOptimizeTemplatesIdResponse, error) {
+	rsp, err := c.DeleteOptimizeTemplatesId(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDeleteOptimizeTemplatesIdResponse(rsp)
+}
+
+// GetOptimizeTemplatesIdWithResponse request returning *GetOptimizeTemplatesIdResponse
+func (c *ClientWithResponses) GetOptimizeTemplatesIdWithResponse(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*GetOptimizeTemplatesIdResponse, error) {
+	rsp, err := c.GetOptimizeTemplatesId(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetOptimizeTemplatesIdResponse(rsp)
+}
+
+// PostOptimizeTemplatesIdChargeScheduleWithBodyWithResponse request with arbitrary body returning *PostOptimizeTemplatesIdChargeScheduleResponse
+func (c *ClientWithResponses) PostOptimizeTemplatesIdChargeScheduleWithBodyWithResponse(ctx context.Context, id string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeTemplatesIdChargeScheduleResponse, error) {
+	rsp, err := c.PostOptimizeTemplatesIdChargeScheduleWithBody(ctx, id, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeTemplatesIdChargeScheduleResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostOptimizeTemplatesIdChargeScheduleWithResponse(ctx context.Context, id string, body PostOptimizeTemplatesIdChargeScheduleJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeTemplatesIdChargeScheduleResponse, error) {
+	rsp, err := c.PostOptimizeTemplatesIdChargeSchedule(ctx, id, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeTemplatesIdChargeScheduleResponse(rsp)
+}
+
+// PostOptimizeValidateWithBodyWithResponse request with arbitrary body returning *PostOptimizeValidateResponse
+func (c *ClientWithResponses) PostOptimizeValidateWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeValidateResponse, error) {
+	rsp, err := c.PostOptimizeValidateWithBody(ctx, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeValidateResponse(rsp)
+}
+
+func (c *ClientWithResponses) PostOptimizeValidateWithResponse(ctx context.Context, body PostOptimizeValidateJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeValidateResponse, error) {
+	rsp, err := c.PostOptimizeValidate(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostOptimizeValidateResponse(rsp)
+}
+
+// ParsePostOptimizeChargeScheduleResponse parses an HTTP response from a PostOptimizeChargeScheduleWithResponse call
+func ParsePostOptimizeChargeScheduleResponse(rsp *http.Response) (*PostOptimizeChargeScheduleResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PostOptimizeChargeScheduleResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest OptimizationResult
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseGetOptimizeEstimateResponse parses an HTTP response from a GetOptimizeEstimateWithResponse call
+func ParseGetOptimizeEstimateResponse(rsp *http.Response) (*GetOptimizeEstimateResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetOptimizeEstimateResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest EstimateResult
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseGetOptimizeHealthResponse parses an HTTP response from a GetOptimizeHealthWithResponse call
+func ParseGetOptimizeHealthResponse(rsp *http.Response) (*GetOptimizeHealthResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetOptimizeHealthResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest struct {
+			Message string `json:"message,omitempty"`
+			Status  string `json:"status,omitempty"`
+		}
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParsePostOptimizeJobsResponse parses an HTTP response from a PostOptimizeJobsWithResponse call
+func ParsePostOptimizeJobsResponse(rsp *http.Response) (*PostOptimizeJobsResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PostOptimizeJobsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 202:
+		var dest Job
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON202 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 429:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON429 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 503:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON503 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseDeleteOptimizeJobsIdResponse parses an HTTP response from a DeleteOptimizeJobsIdWithResponse call
+func ParseDeleteOptimizeJobsIdResponse(rsp *http.Response) (*DeleteOptimizeJobsIdResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DeleteOptimizeJobsIdResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseGetOptimizeJobsIdResponse parses an HTTP response from a GetOptimizeJobsIdWithResponse call
+func ParseGetOptimizeJobsIdResponse(rsp *http.Response) (*GetOptimizeJobsIdResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetOptimizeJobsIdResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest Job
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseGetOptimizeJobsIdResultResponse parses an HTTP response from a GetOptimizeJobsIdResultWithResponse call
+func ParseGetOptimizeJobsIdResultResponse(rsp *http.Response) (*GetOptimizeJobsIdResultResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetOptimizeJobsIdResultResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest OptimizationResult
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 409:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON409 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON500 = &dest
+
 	}
 
-	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	return response, nil
+}
+
+// ParsePostOptimizeSimulateResponse parses an HTTP response from a PostOptimizeSimulateWithResponse call
+func ParsePostOptimizeSimulateResponse(rsp *http.Response) (*PostOptimizeSimulateResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	return req, nil
-}
+	response := &PostOptimizeSimulateResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
 
-func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
-	for _, r := range c.RequestEditors {
-		if err := r(ctx, req); err != nil {
-			return err
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest OptimizationResult
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
 		}
-	}
-	for _, r := range additionalEditors {
-		if err := r(ctx, req); err != nil {
-			return err
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
 		}
+		response.JSON400 = &dest
+
 	}
-	return nil
-}
 
-// ClientWithResponses builds on ClientInterface to offer response payloads
-type ClientWithResponses struct {
-	ClientInterface
+	return response, nil
 }
 
-// NewClientWithResponses creates a new ClientWithResponses, which wraps
-// Client with return type handling
-func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
-	client, err := NewClient(server, opts...)
+// ParseGetOptimizeStrategiesResponse parses an HTTP response from a GetOptimizeStrategiesWithResponse call
+func ParseGetOptimizeStrategiesResponse(rsp *http.Response) (*GetOptimizeStrategiesResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
-	return &ClientWithResponses{client}, nil
-}
 
-// WithBaseURL overrides the baseURL.
-func WithBaseURL(baseURL string) ClientOption {
-	return func(c *Client) error {
-		newBaseURL, err := url.Parse(baseURL)
-		if err != nil {
-			return err
-		}
-		c.Server = newBaseURL.String()
-		return nil
+	response := &GetOptimizeStrategiesResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
 	}
-}
 
-// ClientWithResponsesInterface is the interface specification for the client with responses above.
-type ClientWithResponsesInterface interface {
-	// PostOptimizeChargeScheduleWithBodyWithResponse request with any body
-	PostOptimizeChargeScheduleWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeChargeScheduleResponse, error)
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest []StrategyDescription
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
 
-	PostOptimizeChargeScheduleWithResponse(ctx context.Context, body PostOptimizeChargeScheduleJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeChargeScheduleResponse, error)
+	}
 
-	// GetOptimizeHealthWithResponse request
-	GetOptimizeHealthWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetOptimizeHealthResponse, error)
+	return response, nil
 }
 
-type PostOptimizeChargeScheduleResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *OptimizationResult
-	JSON400      *Error
-	JSON500      *Error
-}
+// ParsePostOptimizeStreamResponse parses an HTTP response from a PostOptimizeStreamWithResponse call
+func ParsePostOptimizeStreamResponse(rsp *http.Response) (*PostOptimizeStreamResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
 
-// Status returns HTTPResponse.Status
-func (r PostOptimizeChargeScheduleResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+	response := &PostOptimizeStreamResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
 	}
-	return http.StatusText(0)
-}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r PostOptimizeChargeScheduleResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
 	}
-	return 0
+
+	return response, nil
 }
 
-type GetOptimizeHealthResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *struct {
-		Message string `json:"message,omitempty"`
-		Status  string `json:"status,omitempty"`
+// ParseGetOptimizeTemplatesResponse parses an HTTP response from a GetOptimizeTemplatesWithResponse call
+func ParseGetOptimizeTemplatesResponse(rsp *http.Response) (*GetOptimizeTemplatesResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
 	}
-}
 
-// Status returns HTTPResponse.Status
-func (r GetOptimizeHealthResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+	response := &GetOptimizeTemplatesResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
 	}
-	return http.StatusText(0)
-}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r GetOptimizeHealthResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest TemplateList
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
 	}
-	return 0
+
+	return response, nil
 }
 
-// PostOptimizeChargeScheduleWithBodyWithResponse request with arbitrary body returning *PostOptimizeChargeScheduleResponse
-func (c *ClientWithResponses) PostOptimizeChargeScheduleWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostOptimizeChargeScheduleResponse, error) {
-	rsp, err := c.PostOptimizeChargeScheduleWithBody(ctx, contentType, body, reqEditors...)
+// ParsePostOptimizeTemplatesResponse parses an HTTP response from a PostOptimizeTemplatesWithResponse call
+func ParsePostOptimizeTemplatesResponse(rsp *http.Response) (*PostOptimizeTemplatesResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
-	return ParsePostOptimizeChargeScheduleResponse(rsp)
+
+	response := &PostOptimizeTemplatesResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 201:
+		var dest TemplateId
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON201 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	}
+
+	return response, nil
 }
 
-func (c *ClientWithResponses) PostOptimizeChargeScheduleWithResponse(ctx context.Context, body PostOptimizeChargeScheduleJSONRequestBody, reqEditors ...RequestEditorFn) (*PostOptimizeChargeScheduleResponse, error) {
-	rsp, err := c.PostOptimizeChargeSchedule(ctx, body, reqEditors...)
+// ParseDeleteOptimizeTemplatesIdResponse parses an HTTP response from a DeleteOptimizeTemplatesIdWithResponse call
+func ParseDeleteOptimizeTemplatesIdResponse(rsp *http.Response) (*DeleteOptimizeTemplatesIdResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
-	return ParsePostOptimizeChargeScheduleResponse(rsp)
+
+	response := &DeleteOptimizeTemplatesIdResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
+	}
+
+	return response, nil
 }
 
-// GetOptimizeHealthWithResponse request returning *GetOptimizeHealthResponse
-func (c *ClientWithResponses) GetOptimizeHealthWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetOptimizeHealthResponse, error) {
-	rsp, err := c.GetOptimizeHealth(ctx, reqEditors...)
+// ParseGetOptimizeTemplatesIdResponse parses an HTTP response from a GetOptimizeTemplatesIdWithResponse call
+func ParseGetOptimizeTemplatesIdResponse(rsp *http.Response) (*GetOptimizeTemplatesIdResponse, error) {
+	bodyBytes, err := io.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
-	return ParseGetOptimizeHealthResponse(rsp)
+
+	response := &GetOptimizeTemplatesIdResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest Template
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
+	}
+
+	return response, nil
 }
 
-// ParsePostOptimizeChargeScheduleResponse parses an HTTP response from a PostOptimizeChargeScheduleWithResponse call
-func ParsePostOptimizeChargeScheduleResponse(rsp *http.Response) (*PostOptimizeChargeScheduleResponse, error) {
+// ParsePostOptimizeTemplatesIdChargeScheduleResponse parses an HTTP response from a PostOptimizeTemplatesIdChargeScheduleWithResponse call
+func ParsePostOptimizeTemplatesIdChargeScheduleResponse(rsp *http.Response) (*PostOptimizeTemplatesIdChargeScheduleResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &PostOptimizeChargeScheduleResponse{
+	response := &PostOptimizeTemplatesIdChargeScheduleResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
@@ -581,6 +3175,13 @@ func ParsePostOptimizeChargeScheduleResponse(rsp *http.Response) (*PostOptimizeC
 		}
 		response.JSON400 = &dest
 
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 404:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON404 = &dest
+
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 500:
 		var dest Error
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
@@ -593,30 +3194,34 @@ func ParsePostOptimizeChargeScheduleResponse(rsp *http.Response) (*PostOptimizeC
 	return response, nil
 }
 
-// ParseGetOptimizeHealthResponse parses an HTTP response from a GetOptimizeHealthWithResponse call
-func ParseGetOptimizeHealthResponse(rsp *http.Response) (*GetOptimizeHealthResponse, error) {
+// ParsePostOptimizeValidateResponse parses an HTTP response from a PostOptimizeValidateWithResponse call
+func ParsePostOptimizeValidateResponse(rsp *http.Response) (*PostOptimizeValidateResponse, error) {
 	bodyBytes, err := io.ReadAll(rsp.Body)
 	defer func() { _ = rsp.Body.Close() }()
 	if err != nil {
 		return nil, err
 	}
 
-	response := &GetOptimizeHealthResponse{
+	response := &PostOptimizeValidateResponse{
 		Body:         bodyBytes,
 		HTTPResponse: rsp,
 	}
 
 	switch {
 	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
-		var dest struct {
-			Message string `json:"message,omitempty"`
-			Status  string `json:"status,omitempty"`
-		}
+		var dest ValidationResult
 		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
 			return nil, err
 		}
 		response.JSON200 = &dest
 
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest Error
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
 	}
 
 	return response, nil
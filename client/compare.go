@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+)
+
+// Strategies are named charging and discharging strategies for comparisons.
+var Strategies = map[string]OptimizerStrategy{
+	"cost": {
+		ChargingStrategy:    OptimizerStrategyChargingStrategyNone,
+		DischargingStrategy: OptimizerStrategyDischargingStrategyNone,
+	},
+	"charge_before_export": {
+		ChargingStrategy:    OptimizerStrategyChargingStrategyChargeBeforeExport,
+		DischargingStrategy: OptimizerStrategyDischargingStrategyNone,
+	},
+	"discharge_before_import": {
+		ChargingStrategy:    OptimizerStrategyChargingStrategyNone,
+		DischargingStrategy: OptimizerStrategyDischargingStrategyDischargeBeforeImport,
+	},
+	"self_consumption": {
+		ChargingStrategy:    OptimizerStrategyChargingStrategyChargeBeforeExport,
+		DischargingStrategy: OptimizerStrategyDischargingStrategyDischargeBeforeImport,
+	},
+	"attenuate_grid_peaks": {
+		ChargingStrategy:    OptimizerStrategyChargingStrategyAttenuateGridPeaks,
+		DischargingStrategy: OptimizerStrategyDischargingStrategyNone,
+	},
+}
+
+// Comparison is the outcome of a request under a strategy.
+type Comparison struct {
+	Strategy OptimizerStrategy
+	Request  OptimizationInput
+	Result   *OptimizationResult
+	Err      error
+}
+
+// Compare solves req under each of the strategies concurrently. The charging and discharging
+// strategies of req are replaced, its objective is kept unless the strategy sets one. Results
+// are returned in the order of the strategies.
+func (c *ClientWithResponses) Compare(ctx context.Context, req OptimizationInput, strategies []OptimizerStrategy, opts ...BatchOption) []Comparison {
+	reqs := make([]OptimizationInput, len(strategies))
+	for i, s := range strategies {
+		r := req
+		r.Strategy.ChargingStrategy = s.ChargingStrategy
+		r.Strategy.DischargingStrategy = s.DischargingStrategy
+		if s.Objective != "" {
+			r.Strategy.Objective = s.Objective
+		}
+		reqs[i] = r
+	}
+
+	res := make([]Comparison, len(strategies))
+	for i, r := range c.OptimizeBatch(ctx, reqs, append([]BatchOption{Concurrency(len(reqs))}, opts...)...) {
+		res[i] = Comparison{
+			Strategy: strategies[i],
+			Request:  reqs[i],
+			Result:   r.Result,
+			Err:      r.Err,
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Constraint is a requirement on a battery over a range of intervals. Constraints are compiled
+// into the battery series by RequestBuilder.Build, e.g.
+//
+//	b.Constrain(client.Battery("lfp").SoC().AtLeast(0.5).Between(6, 9))
+//
+// Without Between or At a constraint applies to all intervals.
+type Constraint struct {
+	battery  string
+	desc     string
+	from, to int // intervals [from, to), to < 0 is the end of the horizon
+	apply    func(bat *BatteryConfig, n, from, to int) error
+}
+
+// BatterySelector selects the battery of a constraint by id.
+type BatterySelector struct {
+	id string
+}
+
+// Battery selects the battery with id.
+func Battery(id string) BatterySelector {
+	return BatterySelector{id: id}
+}
+
+// SoCSelector selects the state of charge of a battery.
+type SoCSelector struct {
+	id string
+}
+
+// SoC selects the state of charge of the battery.
+func (s BatterySelector) SoC() SoCSelector {
+	return SoCSelector(s)
+}
+
+// Unavailable requires the battery to be unavailable, e.g. a vehicle away from home.
+func (s BatterySelector) Unavailable() Constraint {
+	return Constraint{
+		battery: s.id,
+		desc:    "unavailable",
+		to:      -1,
+		apply: func(bat *BatteryConfig, n, from, to int) error {
+			if bat.Available == nil {
+				bat.Available = slices.Repeat([]bool{true}, n)
+			} else {
+				bat.Available = slices.Clone(bat.Available)
+			}
+			for t := from; t < to; t++ {
+				bat.Available[t] = false
+			}
+			return nil
+		},
+	}
+}
+
+// FullEvery requires a full charge to s_max at least every interval for calibration of the
+// battery management system, elapsed being the time since the last full charge at the start
+// of the horizon. The optimizer plans the full charge in the cheapest interval once it is due
+// within the horizon, e.g.
+//
+//	b.Constrain(client.Battery("lfp").FullEvery(14*24*time.Hour, time.Since(lastFull)))
+//
+// Between and At do not apply.
+func (s BatterySelector) FullEvery(interval, elapsed time.Duration) Constraint {
+	return Constraint{
+		battery: s.id,
+		desc:    fmt.Sprintf("full every %v", interval),
+		to:      -1,
+		apply: func(bat *BatteryConfig, _, _, _ int) error {
+			if interval <= 0 || elapsed < 0 {
+				return fmt.Errorf("interval %v must be positive and elapsed %v not negative", interval, elapsed)
+			}
+			bat.Calibration = &Calibration{
+				Interval: float32(interval.Seconds()),
+				Elapsed:  float32(elapsed.Seconds()),
+			}
+			return nil
+		},
+	}
+}
+
+// AtLeast requires a state of charge of at least fraction of the capacity. The capacity is
+// s_capacity, or s_max if not set.
+func (s SoCSelector) AtLeast(fraction float32) Constraint {
+	return s.atLeast(fmt.Sprintf("soc at least %v", fraction), func(bat *BatteryConfig) (float32, error) {
+		if fraction < 0 || fraction > 1 {
+			return 0, fmt.Errorf("fraction %v must be in [0, 1]", fraction)
+		}
+		return fraction * max(bat.SCapacity, bat.SMax), nil
+	})
+}
+
+// AtLeastWh requires a state of charge of at least e (Wh).
+func (s SoCSelector) AtLeastWh(e float32) Constraint {
+	return s.atLeast(fmt.Sprintf("soc at least %vWh", e), func(bat *BatteryConfig) (float32, error) {
+		if e < 0 {
+			return 0, fmt.Errorf("%vWh must not be negative", e)
+		}
+		return e, nil
+	})
+}
+
+// atLeast raises the charge goal of the intervals, existing higher goals are kept
+func (s SoCSelector) atLeast(desc string, energy func(bat *BatteryConfig) (float32, error)) Constraint {
+	return Constraint{
+		battery: s.id,
+		desc:    desc,
+		to:      -1,
+		apply: func(bat *BatteryConfig, n, from, to int) error {
+			e, err := energy(bat)
+			if err != nil {
+				return err
+			}
+			if e > bat.SMax {
+				return fmt.Errorf("%vWh exceeds s_max %vWh", e, bat.SMax)
+			}
+
+			if bat.SGoal == nil {
+				bat.SGoal = make([]float32, n)
+			} else {
+				bat.SGoal = slices.Clone(bat.SGoal)
+			}
+			for t := from; t < to; t++ {
+				bat.SGoal[t] = max(bat.SGoal[t], e)
+			}
+			return nil
+		},
+	}
+}
+
+// Between restricts the constraint to the intervals [from, to).
+func (c Constraint) Between(from, to int) Constraint {
+	c.from, c.to = from, to
+	return c
+}
+
+// At restricts the constraint to interval t.
+func (c Constraint) At(t int) Constraint {
+	return c.Between(t, t+1)
+}
+
+// String describes the constraint.
+func (c Constraint) String() string {
+	if c.to < 0 {
+		return fmt.Sprintf("battery %q: %s", c.battery, c.desc)
+	}
+	return fmt.Sprintf("battery %q: %s between %d and %d", c.battery, c.desc, c.from, c.to)
+}
+
+// compile applies the constraint to the battery with matching id
+func (c Constraint) compile(req *OptimizationInput) error {
+	if c.apply == nil {
+		return fmt.Errorf("%v: empty constraint", c)
+	}
+
+	i := slices.IndexFunc(req.Batteries, func(bat BatteryConfig) bool { return bat.Id == c.battery })
+	if i < 0 {
+		return fmt.Errorf("%v: no such battery", c)
+	}
+
+	n := len(req.TimeSeries.Dt)
+	from, to := c.from, c.to
+	if to < 0 {
+		to = n
+	}
+	if from < 0 || from >= to || to > n {
+		return fmt.Errorf("%v: invalid intervals, horizon has %d intervals", c, n)
+	}
+
+	if err := c.apply(&req.Batteries[i], n, from, to); err != nil {
+		return fmt.Errorf("%v: %w", c, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Doer performs HTTP requests. It is implemented by http.Client.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Client calls the optimizer at Server using the standard library only, for embedding in
+// dependency-sensitive projects. Retries, batching and other helpers are provided by the client
+// package, whose models are generated separately from the same specification.
+type Client struct {
+	Server string
+	// Doer defaults to http.DefaultClient.
+	Doer Doer
+	// Token authorizes requests using a bearer token if not empty.
+	Token string
+}
+
+// Solve optimizes req. Results with a status other than Optimal are returned without error.
+func (c *Client) Solve(ctx context.Context, req OptimizationInput) (OptimizationResult, error) {
+	var res OptimizationResult
+	err := c.post(ctx, "/optimize/charge-schedule", req, &res)
+	return res, err
+}
+
+// Simulate simulates the dispatch policy of req without optimization.
+func (c *Client) Simulate(ctx context.Context, req SimulationInput) (OptimizationResult, error) {
+	var res OptimizationResult
+	err := c.post(ctx, "/optimize/simulate", req, &res)
+	return res, err
+}
+
+// Health checks that the optimizer is available.
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/optimize/health", nil, nil)
+}
+
+func (c *Client) post(ctx context.Context, path string, req, res any) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, path, b, res)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, res any) error {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.Server, "/")+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	doer := c.Doer
+	if doer == nil {
+		doer = http.DefaultClient
+	}
+
+	resp, err := doer.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e Error
+		if err := json.NewDecoder(resp.Body).Decode(&e); err == nil && e.Message != "" {
+			return fmt.Errorf("%s: %s", resp.Status, e.Message)
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(res)
+}
@@ -0,0 +1,988 @@
+// Package core provides primitives to interact with the openapi HTTP API.
+//
+// Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.5.0 DO NOT EDIT.
+package core
+
+// Defines values for GoalShortfallReason.
+const (
+	Capacity       GoalShortfallReason = "capacity"
+	ChargePower    GoalShortfallReason = "charge_power"
+	CompetingGoals GoalShortfallReason = "competing_goals"
+	Generation     GoalShortfallReason = "generation"
+	GridLimit      GoalShortfallReason = "grid_limit"
+	Other          GoalShortfallReason = "other"
+)
+
+// Defines values for JobStatus.
+const (
+	Cancelled JobStatus = "cancelled"
+	Done      JobStatus = "done"
+	Failed    JobStatus = "failed"
+	Queued    JobStatus = "queued"
+	Running   JobStatus = "running"
+)
+
+// Defines values for OptimizationResultFlowDirection.
+const (
+	N0 OptimizationResultFlowDirection = 0
+	N1 OptimizationResultFlowDirection = 1
+)
+
+// Defines values for OptimizationResultObjectiveUnit.
+const (
+	Currency OptimizationResultObjectiveUnit = "currency"
+	GCO2     OptimizationResultObjectiveUnit = "gCO2"
+	Wh       OptimizationResultObjectiveUnit = "Wh"
+)
+
+// Defines values for OptimizationResultStatus.
+const (
+	Infeasible OptimizationResultStatus = "Infeasible"
+	NotSolved  OptimizationResultStatus = "Not Solved"
+	Optimal    OptimizationResultStatus = "Optimal"
+	Simulated  OptimizationResultStatus = "Simulated"
+	Unbounded  OptimizationResultStatus = "Unbounded"
+	Undefined  OptimizationResultStatus = "Undefined"
+)
+
+// Defines values for OptimizerStrategyChargingStrategy.
+const (
+	OptimizerStrategyChargingStrategyAttenuateGridPeaks OptimizerStrategyChargingStrategy = "attenuate_grid_peaks"
+	OptimizerStrategyChargingStrategyChargeBeforeExport OptimizerStrategyChargingStrategy = "charge_before_export"
+	OptimizerStrategyChargingStrategyNone               OptimizerStrategyChargingStrategy = "none"
+)
+
+// Defines values for OptimizerStrategyDischargingStrategy.
+const (
+	OptimizerStrategyDischargingStrategyDischargeBeforeImport OptimizerStrategyDischargingStrategy = "discharge_before_import"
+	OptimizerStrategyDischargingStrategyNone                  OptimizerStrategyDischargingStrategy = "none"
+)
+
+// Defines values for OptimizerStrategyObjective.
+const (
+	Cost      OptimizerStrategyObjective = "cost"
+	Emissions OptimizerStrategyObjective = "emissions"
+	Energy    OptimizerStrategyObjective = "energy"
+)
+
+// Defines values for SimulationInputPolicy.
+const (
+	SelfConsumption SimulationInputPolicy = "self_consumption"
+)
+
+// BatteryConfig defines model for BatteryConfig.
+type BatteryConfig struct {
+	// Available Availability of the battery at each time step, e.g. during firmware updates or while a portable
+	// battery is away. Unavailable batteries neither charge nor discharge. Requests are rejected if a
+	// charge demand falls on an unavailable time step or a goal cannot be reached charging at full
+	// power in the available time steps before it.
+	Available []bool `json:"available,omitempty"`
+
+	// CEtaCurve Charging efficiency as piecewise linear function of the charge power. Overrides eta_c for this battery.
+	// A point at zero power is added automatically using the efficiency of the lowest power point.
+	// Charge power is limited to the highest power point of the curve.
+	CEtaCurve []EfficiencyPoint `json:"c_eta_curve,omitempty"`
+
+	// CEtaSeries Charging efficiency at each time step. Overrides eta_c for this battery and must not be combined
+	// with c_eta_curve. Values above 1 model a heat pump charging a thermal storage, with the coefficient
+	// of performance (COP) derived from the temperature forecast.
+	CEtaSeries []float32 `json:"c_eta_series,omitempty"`
+
+	// CMax Maximum charge power in W
+	CMax float32 `json:"c_max"`
+
+	// CMin Minimum charge power in W
+	CMin float32 `json:"c_min"`
+
+	// CPriority Charging and discharging priority compared to other batteries. Higher values take precedence
+	// in cost neutral situations, allowing an explicit order among any number of batteries. Only the
+	// order of the values matters, e.g. priorities 1 and 5 are equivalent to 0 and 1.
+	CPriority   int          `json:"c_priority,omitempty"`
+	Calibration *Calibration `json:"calibration,omitempty"`
+
+	// ChargeFromGrid Controls whether the battery can be charged from the grid.
+	//   - True: The battery can be charged from grid at any time. The actual decision is subject
+	//     to the optimization.
+	//   - False: (default) The battery cannot be charged while power is retrieved from grid
+	ChargeFromGrid bool `json:"charge_from_grid,omitempty"`
+
+	// DEtaCurve Discharging efficiency as piecewise linear function of the discharge power. Overrides eta_d for this battery.
+	// A point at zero power is added automatically using the efficiency of the lowest power point.
+	// Discharge power is limited to the highest power point of the curve.
+	DEtaCurve []EfficiencyPoint `json:"d_eta_curve,omitempty"`
+
+	// DMax Maximum discharge power in W
+	DMax float32 `json:"d_max"`
+
+	// Departures Possible departures of a vehicle with their probabilities, e.g. usually at 8:00 but sometimes at
+	// 6:30. Instead of a single goal, the probability weighted shortfall of the goals at the possible
+	// departures is minimized, charging earlier if an early departure is likely enough. Availability
+	// should cover the latest departure.
+	Departures []Departure `json:"departures,omitempty"`
+
+	// DischargeToGrid Controls whether the battery can discharge to grid.
+	//   - True: The battery can discharge to the grid at any time. The actual decision is
+	//     subject to the optimization.
+	//   - False: (default) The battery cannot be discharged while power is exported to the grid.
+	DischargeToGrid bool `json:"discharge_to_grid,omitempty"`
+
+	// Enabled Include the battery in the optimization. Disabled batteries (e.g. during maintenance or
+	// while the vehicle is away) are excluded without reindexing the batteries array. The result
+	// keeps the battery at its index with zeroed series.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// GoalPriority Priority of the charge goals and departure goals compared to the goals of other batteries. When
+	// goals compete for limited grid, generation or charge power, goals of higher priority are met first
+	// and goals of lower priority are scaled back, see goal_shortfalls of the result. The number of
+	// levels is limited, more so for goals at many time steps, requests exceeding it are rejected.
+	GoalPriority int `json:"goal_priority,omitempty"`
+
+	// Id Stable identifier of the battery, returned with its result. Identifiers must be unique,
+	// clients look up results by id instead of position.
+	Id string `json:"id"`
+
+	// Name Human-readable name of the battery, returned with its result
+	Name string `json:"name"`
+
+	// PA Monetary value of the stored energy per Wh at end of time horizon
+	PA float32 `json:"p_a"`
+
+	// PDemand Minimum charge demand per time step (Wh)
+	PDemand         []float32        `json:"p_demand,omitempty"`
+	Preconditioning *Preconditioning `json:"preconditioning,omitempty"`
+
+	// SCapacity The capacity at 100% SOC in Wh. If not specified s_capacity will be set to s_max.
+	// s_initial must be less or equal s_capacity, otherwise the optimization will return an error.
+	SCapacity float32 `json:"s_capacity,omitempty"`
+
+	// SGoal Goal state of charge for this battery at each time step (Wh)
+	SGoal []float32 `json:"s_goal,omitempty"`
+
+	// SInitial Initial state of charge in Wh
+	SInitial float32 `json:"s_initial"`
+
+	// SInitialStddev Standard deviation of the measured initial state of charge in Wh, e.g. for noisy BMS readings.
+	// Near-term state of charge bounds are tightened by twice this value, decreasing to no margin
+	// four hours ahead, so plans do not violate s_min or s_max in reality.
+	SInitialStddev float32 `json:"s_initial_stddev,omitempty"`
+
+	// SMax Maximum state of charge in Wh
+	SMax float32 `json:"s_max"`
+
+	// SMin Minimum state of charge in Wh
+	SMin float32 `json:"s_min"`
+}
+
+// BatteryResult defines model for BatteryResult.
+type BatteryResult struct {
+	// CalibrationStep Time step at the end of which the battery is full for calibration. Only present if a calibration is due within the horizon.
+	CalibrationStep *int `json:"calibration_step"`
+
+	// ChargingPower Optimal charging energy at each time step (Wh)
+	ChargingPower []float32 `json:"charging_power,omitempty"`
+
+	// Contribution Objective value lost when solving without this battery, if attribute_batteries is set. Contributions
+	// of batteries that can substitute each other do not add up to their joint contribution. Null for
+	// disabled batteries and if the problem is infeasible without the battery.
+	Contribution float32 `json:"contribution"`
+
+	// DischargingPower Optimal discharging energy at each time step (Wh)
+	DischargingPower []float32 `json:"discharging_power,omitempty"`
+
+	// ExpectedShortfall Probability weighted shortfall of the goals at the possible departures (Wh). Only present if departures are given.
+	ExpectedShortfall float32 `json:"expected_shortfall,omitempty"`
+
+	// GoalShortfalls Charge goals scaled back by the optimizer with the reason. Only present if charge goals are given.
+	GoalShortfalls []GoalShortfall `json:"goal_shortfalls,omitempty"`
+
+	// Id Identifier of the battery as given in the request
+	Id string `json:"id,omitempty"`
+
+	// Name Name of the battery as given in the request
+	Name string `json:"name,omitempty"`
+
+	// PreconditioningPower Preconditioning energy at each time step (Wh). Only present if preconditioning is given.
+	PreconditioningPower []float32 `json:"preconditioning_power,omitempty"`
+
+	// StateOfCharge State of charge at each time step (Wh)
+	StateOfCharge []float32 `json:"state_of_charge,omitempty"`
+}
+
+// Calibration Periodic full charge required by the battery management system, e.g. of LFP batteries every 14
+// days. Once due within the horizon, the battery reaches s_max at the end of the time step the
+// optimizer finds cheapest before, an overdue calibration anywhere within the horizon. A shortfall
+// is penalized like missed goals. The caller carries the time since the last full charge across
+// rolling horizons.
+type Calibration struct {
+	// Elapsed Time since the last full charge at the start of the horizon in seconds
+	Elapsed float32 `json:"elapsed,omitempty"`
+
+	// Interval Maximum time between full charges in seconds
+	Interval float32 `json:"interval"`
+}
+
+// ChpConfig Combined heat and power unit co-optimized with the electricity flows, e.g. for hybrid heating. The
+// heat demand time_series.q_H is covered by the unit, a gas boiler and electric heating such as a heat
+// pump, fuel is priced at time_series.p_G. Heat is not stored, the unit only runs while there is heat
+// demand. Requires the cost objective. Only used by the charge schedule.
+type ChpConfig struct {
+	// Cop Heat per electricity of electric heating, e.g. the heat pump COP or 1 for a heating rod
+	Cop float32 `json:"cop,omitempty"`
+
+	// EtaBoiler Boiler efficiency, heat per fuel energy
+	EtaBoiler float32 `json:"eta_boiler,omitempty"`
+
+	// EtaEl Electric efficiency, electricity per fuel energy
+	EtaEl float32 `json:"eta_el"`
+
+	// EtaTh Thermal efficiency, heat per fuel energy. eta_el plus eta_th must not exceed 1.
+	EtaTh float32 `json:"eta_th"`
+
+	// PBoilerMax Maximum heat output of a gas boiler in W, 0 without boiler
+	PBoilerMax float32 `json:"p_boiler_max,omitempty"`
+
+	// PElMax Maximum electric power of the unit in W
+	PElMax float32 `json:"p_el_max"`
+
+	// PElMin Minimum electric power while running in W, 0 for continuous modulation
+	PElMin float32 `json:"p_el_min,omitempty"`
+
+	// PHeatElMax Maximum electric power of electric heating in W, 0 without
+	PHeatElMax float32 `json:"p_heat_el_max,omitempty"`
+}
+
+// ChpResult Dispatch of the combined heat and power unit, if given
+type ChpResult struct {
+	// BoilerHeat Heat produced by the boiler at each time step (Wh)
+	BoilerHeat []float32 `json:"boiler_heat,omitempty"`
+
+	// ElectricHeating Electricity consumed by electric heating at each time step (Wh)
+	ElectricHeating []float32 `json:"electric_heating,omitempty"`
+
+	// Electricity Electricity generated by the unit at each time step (Wh)
+	Electricity []float32 `json:"electricity,omitempty"`
+
+	// Fuel Fuel consumed by the unit and the boiler at each time step (Wh)
+	Fuel []float32 `json:"fuel,omitempty"`
+
+	// FuelCost Fuel cost over the horizon
+	FuelCost float32 `json:"fuel_cost,omitempty"`
+
+	// Heat Heat produced by the unit at each time step (Wh)
+	Heat []float32 `json:"heat,omitempty"`
+}
+
+// Departure defines model for Departure.
+type Departure struct {
+	// Probability Probability of this departure, probabilities of a battery sum up to at most 1
+	Probability float32 `json:"probability"`
+
+	// SGoal Goal state of charge at this departure in Wh
+	SGoal float32 `json:"s_goal"`
+
+	// T Index of the time step the vehicle may depart after
+	T int `json:"t"`
+}
+
+// EfficiencyPoint defines model for EfficiencyPoint.
+type EfficiencyPoint struct {
+	// Eta Efficiency at this power (greater than 0 up to 1)
+	Eta float32 `json:"eta"`
+
+	// Power Charge or discharge power in W
+	Power float32 `json:"power"`
+}
+
+// Error defines model for Error.
+type Error struct {
+	// Details Field-specific validation errors. Keys are field paths (e.g., "batteries.0.s_max"), values are error messages.
+	Details map[string]string `json:"details,omitempty"`
+
+	// Message Error message describing what went wrong
+	Message string `json:"message,omitempty"`
+}
+
+// EstimateResult defines model for EstimateResult.
+type EstimateResult struct {
+	// ActiveSolves Number of solves currently running on the serving host
+	ActiveSolves int `json:"active_solves,omitempty"`
+
+	// EstimatedQueueWait Estimated remaining time of all in-flight solves in seconds
+	EstimatedQueueWait float32 `json:"estimated_queue_wait,omitempty"`
+
+	// EstimatedSolveTime Estimated solve time for the given problem size in seconds
+	EstimatedSolveTime float32 `json:"estimated_solve_time,omitempty"`
+}
+
+// GoalShortfall A charge goal that could not be met.
+type GoalShortfall struct {
+	// Competitors Indices of the batteries charging while this battery could charge more, by descending goal priority
+	Competitors []int `json:"competitors,omitempty"`
+
+	// Goal Goal state of charge in Wh
+	Goal float32 `json:"goal"`
+
+	// Reason Why the goal was scaled back:
+	//   - capacity: the goal exceeds s_max
+	//   - charge_power: the battery charged at maximum power whenever available
+	//   - grid_limit: the grid import limit was reached while the battery could charge more
+	//   - generation: the battery only charges from surplus generation, which was not sufficient
+	//   - competing_goals: other batteries were charged instead, e.g. for goals of higher priority
+	//   - other: none of the above, e.g. conflicting constraints
+	Reason GoalShortfallReason `json:"reason"`
+
+	// Shortfall Energy missing to the goal in Wh
+	Shortfall float32 `json:"shortfall"`
+
+	// T Index of the time step of the goal
+	T int `json:"t"`
+}
+
+// GoalShortfallReason Why the goal was scaled back:
+//   - capacity: the goal exceeds s_max
+//   - charge_power: the battery charged at maximum power whenever available
+//   - grid_limit: the grid import limit was reached while the battery could charge more
+//   - generation: the battery only charges from surplus generation, which was not sufficient
+//   - competing_goals: other batteries were charged instead, e.g. for goals of higher priority
+//   - other: none of the above, e.g. conflicting constraints
+type GoalShortfallReason string
+
+// GridConfig defines model for GridConfig.
+type GridConfig struct {
+	// EExpCap Remunerated export energy per cap period in Wh, e.g. for contracts paying p_E only for the first
+	// X kWh fed in per day or year. Export beyond the cap earns nothing, so the optimizer stores rather
+	// than exports once the cap is spent. Within a period, the remaining cap is assigned to the best
+	// paid export intervals.
+	EExpCap float32 `json:"e_exp_cap,omitempty"`
+
+	// EExpToDate Energy exported so far in the current cap period in Wh. Requires e_exp_cap.
+	EExpToDate float32 `json:"e_exp_to_date,omitempty"`
+
+	// EImpTier Import energy allowance at base price (p_N) per billing period in Wh, e.g. for tariffs
+	// where the first X kWh per month are cheaper. Requires prc_e_exc_tier and vice versa.
+	EImpTier float32 `json:"e_imp_tier,omitempty"`
+
+	// EImpToDate Energy imported so far in the current billing period in Wh
+	EImpToDate float32 `json:"e_imp_to_date,omitempty"`
+
+	// ENetToDate Net import so far in the current netting period in Wh, negative for a net export. Requires prc_e_net_imp.
+	ENetToDate float32 `json:"e_net_to_date,omitempty"`
+
+	// PConn Capacity of the grid connection in W, limiting import and export in addition to p_max_imp,
+	// p_max_exp and time_series.p_max_imp. Cannot be combined with prc_p_exc_imp.
+	PConn float32 `json:"p_conn,omitempty"`
+
+	// PMaxExp Maximum grid export power in W
+	PMaxExp float32 `json:"p_max_exp,omitempty"`
+
+	// PMaxImp Maximum grid import power in W
+	PMaxImp float32 `json:"p_max_imp,omitempty"`
+
+	// PPeakToDate Peak import power reached so far in the current billing period in W. Only raising the peak above it is
+	// charged by the peak price curve prc_p_peak or the demand rate prc_p_exc_imp, which requires one of them.
+	PPeakToDate float32 `json:"p_peak_to_date,omitempty"`
+
+	// PrcEDev Price per Wh of deviation from the committed grid exchange time_series.n_commit in either
+	// direction, e.g. imbalance cost of a flexibility contract. Requires n_commit and vice versa.
+	PrcEDev float32 `json:"prc_e_dev,omitempty"`
+
+	// PrcEExcTier Price surcharge per Wh on top of p_N for energy imported beyond the tier allowance
+	PrcEExcTier float32 `json:"prc_e_exc_tier,omitempty"`
+
+	// PrcENetExp Credit per Wh of the net export per netting period, must not exceed prc_e_net_imp. Requires prc_e_net_imp.
+	PrcENetExp float32 `json:"prc_e_net_exp,omitempty"`
+
+	// PrcENetImp Price per Wh of the net import per netting period, enables net metering with an import and an export
+	// register offsetting each other before billing. Applies in addition to the per interval prices p_N and
+	// p_E, set them to zero for pure netting.
+	PrcENetImp float32 `json:"prc_e_net_imp,omitempty"`
+
+	// PrcPExcImp price per W to consider in case the import limit is exceeded.
+	// If not specified, the limit will be protected by a hard constraint.
+	PrcPExcImp float32 `json:"prc_p_exc_imp,omitempty"`
+
+	// PrcPPeak Price of the peak import power of the horizon as convex piecewise linear function, e.g. capacity
+	// based tariff components (Leistungspreis) growing superlinearly with the peak. Each point sets the
+	// price per W for the part of the peak above its power, so prices must not decrease with power.
+	// Only raising the peak above p_peak_to_date is charged. Cannot be combined with prc_p_exc_imp.
+	PrcPPeak []PeakPricePoint `json:"prc_p_peak,omitempty"`
+
+	// TCapReset Index of the first time step belonging to the next cap period. Export from this time step on
+	// counts against the full e_exp_cap of the next period. If not specified, the cap period does not
+	// end within the horizon. Requires e_exp_cap.
+	TCapReset int `json:"t_cap_reset,omitempty"`
+
+	// TNetReset Index of the first time step of the next netting period. Import and export from then on are netted in
+	// the next period. If not specified, the netting period does not end within the horizon. Requires
+	// prc_e_net_imp.
+	TNetReset int `json:"t_net_reset,omitempty"`
+
+	// TTierReset Index of the first time step belonging to the next billing period. Import from this time step
+	// on counts against the full e_imp_tier allowance of the next period instead of the remaining
+	// allowance of the current one. If not specified, the billing period does not end within the horizon.
+	TTierReset int `json:"t_tier_reset,omitempty"`
+}
+
+// Job defines model for Job.
+type Job struct {
+	// Id Job id
+	Id string `json:"id"`
+
+	// Message Failure message of failed jobs
+	Message string `json:"message,omitempty"`
+
+	// Status Job status, the result is available once done
+	Status JobStatus `json:"status"`
+}
+
+// JobStatus Job status, the result is available once done
+type JobStatus string
+
+// LimitViolationResult defines model for LimitViolationResult.
+type LimitViolationResult struct {
+	// CostBudgetExceeded The cost budget of the goal seeking mode could not be met.
+	CostBudgetExceeded bool `json:"cost_budget_exceeded,omitempty"`
+
+	// ExportCapReached Export beyond the remunerated export cap e_exp_cap is planned.
+	ExportCapReached bool `json:"export_cap_reached,omitempty"`
+
+	// GridExportLimitHit The solar yield in (Wh) that was reduced due to the limitation of grid export power.
+	GridExportLimitHit bool `json:"grid_export_limit_hit,omitempty"`
+
+	// GridImportLimitExceeded The energy demand could only be satisfied by violating the grid import limit.
+	GridImportLimitExceeded bool `json:"grid_import_limit_exceeded,omitempty"`
+}
+
+// ObjectiveWeights Weights trading the economic benefit of the strategy objective against battery wear, grid exchange
+// and peak import, e.g. to cycle small home batteries less than the final state of charge value p_a
+// alone expresses. Battery throughput (charge plus discharge), grid exchange (import plus export)
+// and the peak import power over one hour are valued at the mean import price, so that weights are
+// comparable to the cost weight. At least one weight must be positive. The weighted terms are not
+// part of the reported objective value. Cannot be combined with cost budget. Only used by the charge
+// schedule.
+type ObjectiveWeights struct {
+	// BatteryWear Weight of the battery throughput (Wh)
+	BatteryWear float32 `json:"battery_wear"`
+
+	// Cost Weight of the economic benefit of the strategy objective
+	Cost float32 `json:"cost"`
+
+	// PeakShaving Weight of the peak import power (W)
+	PeakShaving float32 `json:"peak_shaving"`
+
+	// SelfConsumption Weight of the grid import plus export (Wh)
+	SelfConsumption float32 `json:"self_consumption"`
+}
+
+// OptimizationInput defines model for OptimizationInput.
+type OptimizationInput struct {
+	// AttributeBatteries Report the contribution of each battery to the objective value, e.g. to decide whether a second,
+	// smaller battery is worth keeping. The problem is solved again without each enabled battery, so
+	// the latency grows with the number of batteries. Only used by the charge schedule.
+	AttributeBatteries bool `json:"attribute_batteries,omitempty"`
+
+	// Batteries Configuration for all batteries in the system
+	Batteries []BatteryConfig `json:"batteries"`
+	Chp       *ChpConfig      `json:"chp,omitempty"`
+
+	// CostBudget Maximum acceptable net cost (import cost minus export revenue) over the horizon in currency units.
+	// If given, the optimizer runs in goal seeking mode: instead of minimizing cost, it minimizes
+	// battery throughput (wear) while keeping the cost within the budget. If the budget cannot
+	// be met, it is exceeded as little as possible and limit_violations.cost_budget_exceeded is set.
+	CostBudget float32 `json:"cost_budget,omitempty"`
+
+	// EtaC Charging efficiency (0 to 1)
+	EtaC float32 `json:"eta_c,omitempty"`
+
+	// EtaD Discharging efficiency (0 to 1)
+	EtaD float32    `json:"eta_d,omitempty"`
+	Grid GridConfig `json:"grid,omitempty"`
+
+	// MaxLatencyMs Hard real-time mode for controllers that must act immediately. Instead of solving the MILP,
+	// the LP relaxation is solved and its integer variables are rounded, followed by a second LP
+	// with the rounded values fixed. The latency (ms) is measured from the receipt of the request
+	// and limits both solves, a solve is not started once it is used up. The estimated optimality
+	// loss is returned as optimality_loss. If rounding does not yield a feasible solution or the
+	// latency is used up, the status is not Optimal.
+	MaxLatencyMs float32 `json:"max_latency_ms,omitempty"`
+
+	// MipGap Relative optimality gap at which the solve stops, trading optimality for latency. The
+	// objective of the result is within this fraction of the optimum. Defaults to the solver's gap.
+	MipGap           float32           `json:"mip_gap,omitempty"`
+	ObjectiveWeights *ObjectiveWeights `json:"objective_weights,omitempty"`
+	Output           OutputOptions     `json:"output,omitempty"`
+
+	// Penalties Custom linear penalty terms added to the cost, covering niche objectives without dedicated fields.
+	// Terms are sums of numbers times the series grid_import, grid_export and charge[i], discharge[i],
+	// soc[i] of battery i given by index or id, all in Wh. A series with a time step is its value at
+	// that step, with a slice the sum over the steps, without either the sum over the horizon. Negative
+	// terms are bonuses. Penalties are not part of the reported objective value. Only used by the charge
+	// schedule.
+	Penalties []string `json:"penalties,omitempty"`
+
+	// RequireOptimal If the solve hits the time limit before proving optimality within mip_gap, return no plan
+	// with status Not Solved instead of the best solution found. Combined with simplify_on_timeout,
+	// the approximation is returned instead.
+	RequireOptimal bool `json:"require_optimal,omitempty"`
+
+	// SimplifyOnTimeout If the solve hits the server's time limit without a solution, e.g. during server congestion,
+	// retry with the approximation of max_latency_ms mode instead of returning no plan. The solve
+	// gets 80% of the time limit, the approximation the rest. Such results are marked as simplified.
+	// Has no effect without a time limit.
+	SimplifyOnTimeout bool              `json:"simplify_on_timeout,omitempty"`
+	Strategy          OptimizerStrategy `json:"strategy,omitempty"`
+
+	// TimeLimit Time limit of the solve in seconds, e.g. for controllers acting in a fixed loop. Capped by the
+	// server's time limit. If the limit is hit, the best solution found is returned with status
+	// Optimal unless require_optimal is set.
+	TimeLimit  float32    `json:"time_limit,omitempty"`
+	TimeSeries TimeSeries `json:"time_series"`
+	WarmStart  *WarmStart `json:"warm_start,omitempty"`
+}
+
+// OptimizationResult defines model for OptimizationResult.
+type OptimizationResult struct {
+	// Batteries Optimization results for each battery
+	Batteries []BatteryResult `json:"batteries,omitempty"`
+	Chp       *ChpResult      `json:"chp"`
+
+	// CurtailmentRisk Intervals at risk of export curtailment according to time_series.r_curt. Empty if not given.
+	CurtailmentRisk []bool `json:"curtailment_risk,omitempty"`
+
+	// ExportPreferenceScore Achieved export preference as sum of w_E times grid export (currency units). Null if w_E is not given.
+	ExportPreferenceScore float32 `json:"export_preference_score"`
+
+	// FlowDirection Binary flow direction at each time step:
+	// - 0: Import from grid
+	// - 1: Export to grid
+	FlowDirection []OptimizationResultFlowDirection `json:"flow_direction,omitempty"`
+
+	// GridDeviation Deviation of the net grid import from the committed schedule n_commit at each time step (Wh),
+	// positive for more import or less export than committed. Empty if no schedule is committed.
+	GridDeviation []float32 `json:"grid_deviation,omitempty"`
+
+	// GridExport Energy exported to grid at each time step (Wh)
+	GridExport []float32 `json:"grid_export,omitempty"`
+
+	// GridExportOvershoot Energy not exported due to hitting the grid export power limit at each time step (Wh)
+	GridExportOvershoot []float32 `json:"grid_export_overshoot,omitempty"`
+
+	// GridImport Energy imported from grid at each time step (Wh)
+	GridImport []float32 `json:"grid_import,omitempty"`
+
+	// GridImportOvershoot Energy above the power limit imported from grid at each time step (Wh)
+	GridImportOvershoot []float32 `json:"grid_import_overshoot,omitempty"`
+
+	// GridImportPeak Peak import power of the plan in W including p_peak_to_date, if a peak price curve is given.
+	GridImportPeak float32 `json:"grid_import_peak"`
+
+	// LatencyMs Time spent building and solving the model (ms)
+	LatencyMs       float32              `json:"latency_ms,omitempty"`
+	LimitViolations LimitViolationResult `json:"limit_violations,omitempty"`
+
+	// ObjectiveUnit Unit of the objective value depending on the strategy objective
+	ObjectiveUnit OptimizationResultObjectiveUnit `json:"objective_unit,omitempty"`
+
+	// ObjectiveValue Optimal objective function value (economic benefit in objective_unit). Null if not optimal.
+	ObjectiveValue float32 `json:"objective_value"`
+
+	// OptimalityLoss Estimated optimality loss of an approximate solution in max_latency_ms mode: the difference between the
+	// objective of the LP relaxation, which bounds the optimum, and the objective of the rounded solution.
+	// Includes penalties and strategy incentives. Null if the problem was solved exactly.
+	OptimalityLoss float32 `json:"optimality_loss"`
+
+	// Simplified The solve timed out and the result was computed by the approximation of simplify_on_timeout.
+	// The estimated optimality loss is returned as optimality_loss.
+	Simplified bool `json:"simplified,omitempty"`
+
+	// Status Optimization solver status:
+	// - Optimal: Problem solved to optimality
+	// - Infeasible: No feasible solution exists
+	// - Unbounded: Objective function is unbounded
+	// - Undefined: Problem status is undefined
+	// - Not Solved: Problem was not solved
+	// - Simulated: Result of a dispatch policy simulation
+	Status OptimizationResultStatus `json:"status,omitempty"`
+}
+
+// OptimizationResultFlowDirection defines model for OptimizationResult.FlowDirection.
+type OptimizationResultFlowDirection int
+
+// OptimizationResultObjectiveUnit Unit of the objective value depending on the strategy objective
+type OptimizationResultObjectiveUnit string
+
+// OptimizationResultStatus Optimization solver status:
+// - Optimal: Problem solved to optimality
+// - Infeasible: No feasible solution exists
+// - Unbounded: Objective function is unbounded
+// - Undefined: Problem status is undefined
+// - Not Solved: Problem was not solved
+// - Simulated: Result of a dispatch policy simulation
+type OptimizationResultStatus string
+
+// OptimizerStrategy defines model for OptimizerStrategy.
+type OptimizerStrategy struct {
+	// ChargingStrategy Sets a strategy for charging in situations where choices are cost neutral.
+	// - none (default): no strategy set
+	// - charge_before_export: charge batteries before exporting to grid
+	// - attenuate_grid_peaks: charge at times with high solar yield to reduce the grid load
+	ChargingStrategy OptimizerStrategyChargingStrategy `json:"charging_strategy,omitempty"`
+
+	// DischargingStrategy Sets a strategy for charging in situations where choices are cost neutral.
+	// - none (default): no strategy set
+	// - discharge_before_import: discharge batteries before importing from grid
+	DischargingStrategy OptimizerStrategyDischargingStrategy `json:"discharging_strategy,omitempty"`
+
+	// Objective Quantity to optimize. The unit of the objective value is returned as objective_unit.
+	// - cost (default): economic benefit based on p_N and p_E (currency)
+	// - energy: grid import energy, e.g. if no tariff is available (Wh)
+	// - emissions: emissions of grid import based on time_series.em_N (gCO2)
+	// For energy and emissions, grid export is not credited and the final state of charge is
+	// valued at the lowest import it avoids. They cannot be combined with demand rate,
+	// tiered tariff, deviation price or cost budget.
+	Objective OptimizerStrategyObjective `json:"objective,omitempty"`
+
+	// SpikeGuardHours Price spike guard. Determines the most expensive import time steps within the next 24 hours
+	// covering this many hours. At the start of each of them, batteries able to discharge keep
+	// enough energy stored to cover the net demand (gt - ft) of this and all later guarded time
+	// steps, even if pure cost optimization would discharge earlier. This protects against
+	// forecast errors around price spikes. 0 disables the guard.
+	SpikeGuardHours float32 `json:"spike_guard_hours,omitempty"`
+}
+
+// OptimizerStrategyChargingStrategy Sets a strategy for charging in situations where choices are cost neutral.
+// - none (default): no strategy set
+// - charge_before_export: charge batteries before exporting to grid
+// - attenuate_grid_peaks: charge at times with high solar yield to reduce the grid load
+type OptimizerStrategyChargingStrategy string
+
+// OptimizerStrategyDischargingStrategy Sets a strategy for charging in situations where choices are cost neutral.
+// - none (default): no strategy set
+// - discharge_before_import: discharge batteries before importing from grid
+type OptimizerStrategyDischargingStrategy string
+
+// OptimizerStrategyObjective Quantity to optimize. The unit of the objective value is returned as objective_unit.
+// - cost (default): economic benefit based on p_N and p_E (currency)
+// - energy: grid import energy, e.g. if no tariff is available (Wh)
+// - emissions: emissions of grid import based on time_series.em_N (gCO2)
+// For energy and emissions, grid export is not credited and the final state of charge is
+// valued at the lowest import it avoids. They cannot be combined with demand rate,
+// tiered tariff, deviation price or cost budget.
+type OptimizerStrategyObjective string
+
+// OutputOptions defines model for OutputOptions.
+type OutputOptions struct {
+	// Fields Top-level result fields to return, e.g. batteries and grid_import. Status is always
+	// returned. All fields are returned if omitted.
+	Fields []string `json:"fields,omitempty"`
+
+	// Precision Round all result series to this number of decimals. With 0, series are returned as
+	// integers. Scalar values like the objective value are not rounded. Energy series like
+	// charging power and grid import are rounded without accumulating rounding errors, their
+	// sums equal the rounded sums of the exact series.
+	Precision *int `json:"precision,omitempty"`
+}
+
+// PeakPricePoint defines model for PeakPricePoint.
+type PeakPricePoint struct {
+	// Power Peak import power in W from which the price applies
+	Power float32 `json:"power"`
+
+	// Price Price per W of peak import power above this power
+	Price float32 `json:"price"`
+}
+
+// Preconditioning Vehicle preconditioning load, e.g. cabin heating before departure. It runs once for the given number
+// of contiguous time steps within its window, starting in the time step the optimizer finds cheapest.
+// While the vehicle is available, the load is supplied by the charger and adds to the grid exchange,
+// otherwise it is drawn from the battery and counts against the goals at departure.
+type Preconditioning struct {
+	// Power Preconditioning power in W
+	Power float32 `json:"power"`
+
+	// Steps Number of contiguous time steps to run for
+	Steps int `json:"steps"`
+
+	// TEnd Index of the time step the preconditioning must be finished by, e.g. the departure
+	TEnd int `json:"t_end"`
+
+	// TStart Index of the first time step the preconditioning may run in
+	TStart int `json:"t_start,omitempty"`
+}
+
+// SimulationInput defines model for SimulationInput.
+type SimulationInput struct {
+	// AttributeBatteries Report the contribution of each battery to the objective value, e.g. to decide whether a second,
+	// smaller battery is worth keeping. The problem is solved again without each enabled battery, so
+	// the latency grows with the number of batteries. Only used by the charge schedule.
+	AttributeBatteries bool `json:"attribute_batteries,omitempty"`
+
+	// Batteries Configuration for all batteries in the system
+	Batteries []BatteryConfig `json:"batteries"`
+	Chp       *ChpConfig      `json:"chp,omitempty"`
+
+	// CostBudget Maximum acceptable net cost (import cost minus export revenue) over the horizon in currency units.
+	// If given, the optimizer runs in goal seeking mode: instead of minimizing cost, it minimizes
+	// battery throughput (wear) while keeping the cost within the budget. If the budget cannot
+	// be met, it is exceeded as little as possible and limit_violations.cost_budget_exceeded is set.
+	CostBudget float32 `json:"cost_budget,omitempty"`
+
+	// EtaC Charging efficiency (0 to 1)
+	EtaC float32 `json:"eta_c,omitempty"`
+
+	// EtaD Discharging efficiency (0 to 1)
+	EtaD float32    `json:"eta_d,omitempty"`
+	Grid GridConfig `json:"grid,omitempty"`
+
+	// MaxLatencyMs Hard real-time mode for controllers that must act immediately. Instead of solving the MILP,
+	// the LP relaxation is solved and its integer variables are rounded, followed by a second LP
+	// with the rounded values fixed. The latency (ms) is measured from the receipt of the request
+	// and limits both solves, a solve is not started once it is used up. The estimated optimality
+	// loss is returned as optimality_loss. If rounding does not yield a feasible solution or the
+	// latency is used up, the status is not Optimal.
+	MaxLatencyMs float32 `json:"max_latency_ms,omitempty"`
+
+	// MipGap Relative optimality gap at which the solve stops, trading optimality for latency. The
+	// objective of the result is within this fraction of the optimum. Defaults to the solver's gap.
+	MipGap           float32           `json:"mip_gap,omitempty"`
+	ObjectiveWeights *ObjectiveWeights `json:"objective_weights,omitempty"`
+	Output           OutputOptions     `json:"output,omitempty"`
+
+	// Penalties Custom linear penalty terms added to the cost, covering niche objectives without dedicated fields.
+	// Terms are sums of numbers times the series grid_import, grid_export and charge[i], discharge[i],
+	// soc[i] of battery i given by index or id, all in Wh. A series with a time step is its value at
+	// that step, with a slice the sum over the steps, without either the sum over the horizon. Negative
+	// terms are bonuses. Penalties are not part of the reported objective value. Only used by the charge
+	// schedule.
+	Penalties []string `json:"penalties,omitempty"`
+
+	// Policy Fixed dispatch policy to simulate
+	Policy SimulationInputPolicy `json:"policy,omitempty"`
+
+	// RequireOptimal If the solve hits the time limit before proving optimality within mip_gap, return no plan
+	// with status Not Solved instead of the best solution found. Combined with simplify_on_timeout,
+	// the approximation is returned instead.
+	RequireOptimal bool `json:"require_optimal,omitempty"`
+
+	// SimplifyOnTimeout If the solve hits the server's time limit without a solution, e.g. during server congestion,
+	// retry with the approximation of max_latency_ms mode instead of returning no plan. The solve
+	// gets 80% of the time limit, the approximation the rest. Such results are marked as simplified.
+	// Has no effect without a time limit.
+	SimplifyOnTimeout bool              `json:"simplify_on_timeout,omitempty"`
+	Strategy          OptimizerStrategy `json:"strategy,omitempty"`
+
+	// TimeLimit Time limit of the solve in seconds, e.g. for controllers acting in a fixed loop. Capped by the
+	// server's time limit. If the limit is hit, the best solution found is returned with status
+	// Optimal unless require_optimal is set.
+	TimeLimit  float32    `json:"time_limit,omitempty"`
+	TimeSeries TimeSeries `json:"time_series"`
+	WarmStart  *WarmStart `json:"warm_start,omitempty"`
+}
+
+// SimulationInputPolicy Fixed dispatch policy to simulate
+type SimulationInputPolicy string
+
+// StrategyDescription defines model for StrategyDescription.
+type StrategyDescription struct {
+	// Default Value used if the parameter is omitted
+	Default string `json:"default"`
+
+	// Description Description of the parameter
+	Description string `json:"description,omitempty"`
+
+	// Parameter Name of the parameter in the strategy object of the request
+	Parameter string `json:"parameter"`
+
+	// Title Short display name
+	Title string `json:"title,omitempty"`
+
+	// Values Supported values of the parameter
+	Values []StrategyValue `json:"values"`
+}
+
+// StrategyValue defines model for StrategyValue.
+type StrategyValue struct {
+	// Description Description of the behaviour
+	Description string `json:"description,omitempty"`
+
+	// Title Short display name
+	Title string `json:"title,omitempty"`
+	Value string `json:"value"`
+}
+
+// Template Static site definition referenced by requests carrying the dynamic inputs only
+type Template struct {
+	// Batteries Battery configurations, dynamic fields like s_initial are given by the requests
+	Batteries []BatteryConfig `json:"batteries,omitempty"`
+
+	// EtaC Default charging efficiency
+	EtaC float32 `json:"eta_c,omitempty"`
+
+	// EtaD Default discharging efficiency
+	EtaD     float32           `json:"eta_d,omitempty"`
+	Grid     GridConfig        `json:"grid,omitempty"`
+	Strategy OptimizerStrategy `json:"strategy,omitempty"`
+}
+
+// TemplateBattery Dynamic inputs of a battery, merged into the template battery of the same index
+type TemplateBattery struct {
+	// Available Availability at each time step
+	Available []bool `json:"available,omitempty"`
+
+	// CEtaSeries Charging efficiency at each time step
+	CEtaSeries []float32 `json:"c_eta_series,omitempty"`
+
+	// Enabled Include the battery in the optimization
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// PDemand Minimum charge demand at each time step (Wh)
+	PDemand         []float32        `json:"p_demand,omitempty"`
+	Preconditioning *Preconditioning `json:"preconditioning,omitempty"`
+
+	// SGoal Goal state of charge at each time step (Wh)
+	SGoal []float32 `json:"s_goal,omitempty"`
+
+	// SInitial Initial state of charge (Wh)
+	SInitial float32 `json:"s_initial"`
+
+	// SInitialStddev Standard deviation of the measured initial state of charge (Wh)
+	SInitialStddev float32 `json:"s_initial_stddev,omitempty"`
+}
+
+// TemplateId defines model for TemplateId.
+type TemplateId struct {
+	// Id Template id to reference in requests
+	Id string `json:"id,omitempty"`
+}
+
+// TemplateInput defines model for TemplateInput.
+type TemplateInput struct {
+	// Batteries Dynamic battery inputs by index of the template batteries
+	Batteries  []TemplateBattery `json:"batteries,omitempty"`
+	Grid       GridConfig        `json:"grid,omitempty"`
+	Output     OutputOptions     `json:"output,omitempty"`
+	TimeSeries TimeSeries        `json:"time_series"`
+}
+
+// TemplateList defines model for TemplateList.
+type TemplateList struct {
+	// Ids Ids of all templates of the caller
+	Ids []string `json:"ids,omitempty"`
+}
+
+// TimeSeries defines model for TimeSeries.
+type TimeSeries struct {
+	// Dt Duration in seconds for each time step (s)
+	Dt []int `json:"dt"`
+
+	// EmN Emissions of grid import per Wh at each time step (gCO2/Wh). Required for the emissions objective.
+	EmN []float32 `json:"em_N,omitempty"`
+
+	// Ft Forecasted energy generation (e.g., solar PV) at each time step (Wh). Defaults to no
+	// generation, e.g. for pure energy arbitrage.
+	Ft []float32 `json:"ft,omitempty"`
+
+	// Gt Household energy demand at each time step (Wh). Negative values denote uncontrolled
+	// generation not covered by ft (e.g. balcony PV folded into the load measurement) exceeding
+	// the household demand. This energy has to be consumed, stored or exported and is treated
+	// like generation from ft, including reduction by the export limit. Defaults to no demand,
+	// e.g. for pure energy arbitrage.
+	Gt []float32 `json:"gt,omitempty"`
+
+	// NCommit Committed net grid import at each time step (Wh), negative for export, e.g. a schedule
+	// pre-committed under a balancing responsibility. Deviations are charged with grid.prc_e_dev
+	// and reported as grid_deviation. Requires grid.prc_e_dev and vice versa.
+	NCommit []float32 `json:"n_commit,omitempty"`
+
+	// PE Grid export remuneration per Wh at each time step (currency units/Wh)
+	PE []float32 `json:"p_E"`
+
+	// PG Gas price per Wh of fuel at each time step (currency units/Wh) of the combined heat and power
+	// unit chp and its boiler. Requires chp and q_H.
+	PG []float32 `json:"p_G,omitempty"`
+
+	// PN Grid import price per Wh at each time step (currency units/Wh)
+	PN []float32 `json:"p_N"`
+
+	// PMaxImp Maximum grid import power at each time step (W), e.g. curtailment windows of controllable
+	// devices announced by the grid operator under §14a EnWG. Applies in addition to grid.p_max_imp,
+	// the lower limit wins. Cannot be combined with grid.prc_p_exc_imp.
+	PMaxImp []float32 `json:"p_max_imp,omitempty"`
+
+	// QH Heat demand at each time step (Wh), covered by the combined heat and power unit chp, its boiler
+	// and electric heating. Requires chp and p_G.
+	QH []float32 `json:"q_H,omitempty"`
+
+	// RCurt Probability of export curtailment by the grid operator at each time step (0 to 1), e.g. from a
+	// curtailment forecast. Export revenue is discounted by this probability, favouring storage of
+	// generation over export in risky intervals. Intervals with a probability of at least 0.5 are
+	// flagged in curtailment_risk of the result.
+	RCurt []float32 `json:"r_curt,omitempty"`
+
+	// WE Soft export preference at each time step (currency units/Wh), e.g. for community programs rewarding
+	// export during local high demand. The weight is a bonus per Wh exported that biases the dispatch
+	// towards export in preferred intervals without forcing it. The bonus is not part of the objective
+	// value and is reported as export_preference_score.
+	WE []float32 `json:"w_E,omitempty"`
+}
+
+// ValidationResult defines model for ValidationResult.
+type ValidationResult struct {
+	Request OptimizationInput `json:"request,omitempty"`
+
+	// Warnings Hints on inputs that are valid but likely not intended
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// WarmStart Previous schedule shifted to the time steps of the request as starting solution of the solver,
+// e.g. for successive rolling horizon runs. Series may be shorter than the horizon, later time
+// steps are not hinted. The hint only speeds up the solve, it does not change the optimum.
+type WarmStart struct {
+	// Batteries Previous schedule of each battery by index of the request batteries
+	Batteries []WarmStartBattery `json:"batteries,omitempty"`
+
+	// GridExport Previous grid export at each time step (Wh)
+	GridExport []float32 `json:"grid_export,omitempty"`
+
+	// GridImport Previous grid import at each time step (Wh)
+	GridImport []float32 `json:"grid_import,omitempty"`
+}
+
+// WarmStartBattery defines model for WarmStartBattery.
+type WarmStartBattery struct {
+	// ChargingPower Previous charging energy at each time step (Wh)
+	ChargingPower []float32 `json:"charging_power,omitempty"`
+
+	// DischargingPower Previous discharging energy at each time step (Wh)
+	DischargingPower []float32 `json:"discharging_power,omitempty"`
+}
+
+// GetOptimizeEstimateParams defines parameters for GetOptimizeEstimate.
+type GetOptimizeEstimateParams struct {
+	// TimeSteps Number of time steps of the problem
+	TimeSteps int `form:"time_steps" json:"time_steps"`
+
+	// Batteries Number of batteries of the problem
+	Batteries *int `form:"batteries,omitempty" json:"batteries,omitempty"`
+}
+
+// PostOptimizeChargeScheduleJSONRequestBody defines body for PostOptimizeChargeSchedule for application/json ContentType.
+type PostOptimizeChargeScheduleJSONRequestBody = OptimizationInput
+
+// PostOptimizeJobsJSONRequestBody defines body for PostOptimizeJobs for application/json ContentType.
+type PostOptimizeJobsJSONRequestBody = OptimizationInput
+
+// PostOptimizeSimulateJSONRequestBody defines body for PostOptimizeSimulate for application/json ContentType.
+type PostOptimizeSimulateJSONRequestBody = SimulationInput
+
+// PostOptimizeStreamJSONRequestBody defines body for PostOptimizeStream for application/json ContentType.
+type PostOptimizeStreamJSONRequestBody = OptimizationInput
+
+// PostOptimizeTemplatesJSONRequestBody defines body for PostOptimizeTemplates for application/json ContentType.
+type PostOptimizeTemplatesJSONRequestBody = Template
+
+// PostOptimizeTemplatesIdChargeScheduleJSONRequestBody defines body for PostOptimizeTemplatesIdChargeSchedule for application/json ContentType.
+type PostOptimizeTemplatesIdChargeScheduleJSONRequestBody = TemplateInput
+
+// PostOptimizeValidateJSONRequestBody defines body for PostOptimizeValidate for application/json ContentType.
+type PostOptimizeValidateJSONRequestBody = OptimizationInput
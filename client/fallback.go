@@ -0,0 +1,85 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Fallback solves optimization requests locally, e.g. fallback.Greedy.
+type Fallback func(ctx context.Context, req OptimizationInput) (OptimizationResult, error)
+
+// WithFallback solves optimizations using fb if the server fails with a 5xx status, cannot be
+// reached or times out, including while the circuit breaker is open, so that control loops
+// never run without a plan. Fallback results have status Simulated. Cancelled requests and
+// asynchronous jobs do not fall back.
+func WithFallback(fb Fallback) Option {
+	return func(c *config) error {
+		if fb == nil {
+			return errors.New("nil fallback")
+		}
+		c.fallback = fb
+		return nil
+	}
+}
+
+func fallbackDoer(doer HttpRequestDoer, fb Fallback) HttpRequestDoer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodPost || req.Body == nil || !strings.HasSuffix(req.URL.Path, "/optimize/charge-schedule") {
+			return doer.Do(req)
+		}
+
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+
+		resp, err := doer.Do(req)
+		switch {
+		case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+			return nil, err
+		case err == nil && resp.StatusCode < http.StatusInternalServerError:
+			return resp, nil
+		}
+
+		var in OptimizationInput
+		if json.Unmarshal(body, &in) != nil {
+			return resp, err
+		}
+
+		res, fbErr := fb(req.Context(), in)
+		if fbErr != nil {
+			return resp, err
+		}
+
+		out, fbErr := json.Marshal(res)
+		if fbErr != nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader(out)),
+			ContentLength: int64(len(out)),
+			Request:       req,
+		}, nil
+	})
+}
@@ -0,0 +1,21 @@
+package client
+
+// BatteryByID returns the result of the battery with the given id.
+func (r OptimizationResult) BatteryByID(id string) (BatteryResult, bool) {
+	for _, b := range r.Batteries {
+		if b.Id == id {
+			return b, true
+		}
+	}
+	return BatteryResult{}, false
+}
+
+// BatteryByID returns the configuration of the battery with the given id.
+func (r OptimizationInput) BatteryByID(id string) (BatteryConfig, bool) {
+	for _, b := range r.Batteries {
+		if b.Id == id {
+			return b, true
+		}
+	}
+	return BatteryConfig{}, false
+}
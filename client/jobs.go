@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrJobNotFound is returned if the serving host does not know the job. Jobs are kept on the
+// accepting host for an hour after finishing.
+var ErrJobNotFound = errors.New("job not found")
+
+// polling interval bounds of OptimizeAsync
+const (
+	jobPollMin = 500 * time.Millisecond
+	jobPollMax = 10 * time.Second
+)
+
+// SubmitOptimization submits req as asynchronous job and returns its id.
+func (c *ClientWithResponses) SubmitOptimization(ctx context.Context, req OptimizationInput, reqEditors ...RequestEditorFn) (string, error) {
+	resp, err := c.PostOptimizeJobsWithResponse(ctx, req, reqEditors...)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case resp.JSON202 != nil:
+		return resp.JSON202.Id, nil
+	case resp.JSON400 != nil:
+		return "", fmt.Errorf("bad request: %s %v", resp.JSON400.Message, resp.JSON400.Details)
+	case resp.JSON429 != nil:
+		if after := resp.HTTPResponse.Header.Get("Retry-After"); after != "" {
+			return "", fmt.Errorf("too many jobs: %s, retry after %ss", resp.JSON429.Message, after)
+		}
+		return "", fmt.Errorf("too many jobs: %s", resp.JSON429.Message)
+	case resp.JSON503 != nil:
+		return "", fmt.Errorf("service unavailable: %s", resp.JSON503.Message)
+	default:
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+}
+
+// GetOptimizationStatus returns the job submitted as id.
+func (c *ClientWithResponses) GetOptimizationStatus(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*Job, error) {
+	resp, err := c.GetOptimizeJobsIdWithResponse(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.JSON200 != nil:
+		return resp.JSON200, nil
+	case resp.StatusCode() == http.StatusNotFound:
+		return nil, ErrJobNotFound
+	default:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+}
+
+// GetOptimizationResult returns the result of the job submitted as id. Jobs not done yet,
+// failed or cancelled jobs are returned as error.
+func (c *ClientWithResponses) GetOptimizationResult(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*OptimizationResult, error) {
+	resp, err := c.GetOptimizeJobsIdResultWithResponse(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.JSON200 != nil:
+		return resp.JSON200, nil
+	case resp.JSON409 != nil:
+		return nil, fmt.Errorf("conflict: %s", resp.JSON409.Message)
+	case resp.JSON500 != nil:
+		return nil, fmt.Errorf("server error: %s", resp.JSON500.Message)
+	case resp.StatusCode() == http.StatusNotFound:
+		return nil, ErrJobNotFound
+	default:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+}
+
+// CancelOptimization cancels the job submitted as id. Finished jobs are not changed.
+func (c *ClientWithResponses) CancelOptimization(ctx context.Context, id string, reqEditors ...RequestEditorFn) error {
+	resp, err := c.DeleteOptimizeJobsIdWithResponse(ctx, id, reqEditors...)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return ErrJobNotFound
+	default:
+		return fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+}
+
+// OptimizeAsync solves req as asynchronous job for horizons exceeding the request timeout. The
+// job is polled with increasing intervals until done and cancelled if ctx is done first.
+func (c *ClientWithResponses) OptimizeAsync(ctx context.Context, req OptimizationInput, reqEditors ...RequestEditorFn) (*OptimizationResult, error) {
+	id, err := c.SubmitOptimization(ctx, req, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	clk := c.clockOf()
+	wait := jobPollMin
+
+	for {
+		select {
+		case <-ctx.Done():
+			// ctx is done, cancel with a fresh context
+			_ = c.CancelOptimization(context.Background(), id, reqEditors...)
+			return nil, ctx.Err()
+		case <-clk.After(wait):
+		}
+
+		job, err := c.GetOptimizationStatus(ctx, id, reqEditors...)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			return nil, err
+		}
+
+		switch job.Status {
+		case Done:
+			return c.GetOptimizationResult(ctx, id, reqEditors...)
+		case Failed:
+			return nil, fmt.Errorf("server error: %s", job.Message)
+		case Cancelled:
+			return nil, fmt.Errorf("job %s cancelled", id)
+		}
+
+		wait = min(2*wait, jobPollMax)
+	}
+}
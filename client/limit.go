@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxResponseSize is the response size limit applied by the streaming decode helpers
+// if no limit is configured.
+const DefaultMaxResponseSize = 32 << 20
+
+// ResponseTooLargeError is returned when a response body exceeds the configured maximum size.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response body exceeds limit of %d bytes", e.Limit)
+}
+
+// limitedBody fails reads beyond the limit with a ResponseTooLargeError.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// probe for further data
+		var probe [1]byte
+		if n, _ := b.ReadCloser.Read(probe[:]); n > 0 {
+			return 0, &ResponseTooLargeError{Limit: b.limit}
+		}
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+
+	return n, err
+}
+
+type limitDoer struct {
+	doer  HttpRequestDoer
+	limit int64
+}
+
+func (d *limitDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := d.doer.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.ContentLength > d.limit {
+		_ = resp.Body.Close()
+		return nil, &ResponseTooLargeError{Limit: d.limit}
+	}
+
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: d.limit, limit: d.limit}
+
+	return resp, nil
+}
+
+// WithMaxResponseSize limits the size of response bodies. Reading beyond the limit fails
+// with a ResponseTooLargeError, protecting small devices from running out of memory.
+func WithMaxResponseSize(limit int64) Option {
+	return func(c *config) error {
+		if limit <= 0 {
+			return errors.New("response size limit must be positive")
+		}
+		c.maxResponse = limit
+		return nil
+	}
+}
+
+// DecodeOptimizationResult decodes an optimization result directly from r without
+// buffering the complete body. At most limit bytes are read, zero means DefaultMaxResponseSize.
+func DecodeOptimizationResult(r io.Reader, limit int64) (*OptimizationResult, error) {
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+
+	lr := &limitedBody{ReadCloser: io.NopCloser(r), remaining: limit, limit: limit}
+
+	var res OptimizationResult
+	if err := json.NewDecoder(lr).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// PostOptimizeChargeScheduleStream solves an optimization problem and decodes the result
+// while streaming the response body. Unlike PostOptimizeChargeScheduleWithResponse the
+// response body is not buffered in memory. Non-200 responses are returned as error.
+func (c *Client) PostOptimizeChargeScheduleStream(ctx context.Context, body PostOptimizeChargeScheduleJSONRequestBody, limit int64, reqEditors ...RequestEditorFn) (*OptimizationResult, error) {
+	resp, err := c.PostOptimizeChargeSchedule(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e Error
+		if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+			_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+		}
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, e.Message)
+	}
+
+	return DecodeOptimizationResult(resp.Body, limit)
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Load is a controllable load like an EV charger or a heat pump boost which needs a given energy
+// by a deadline. Loads are mapped to batteries without discharge and stored value, the required
+// energy being the charge goal at the deadline.
+type Load struct {
+	Id   string
+	Name string
+	// MinPower is the minimum power when running [W], 0 if the power can be reduced to zero.
+	MinPower float32
+	// MaxPower is the maximum power [W].
+	MaxPower float32
+	// Energy is the energy required by the deadline [Wh].
+	Energy float32
+	// EarliestStart is the earliest start of the load, zero to start immediately.
+	EarliestStart time.Time
+	// Deadline is the time the energy is required by, e.g. departure. Zero for the end of the
+	// horizon.
+	Deadline time.Time
+	// PhaseSwitching allows switching from three phases to a single phase, reducing the minimum
+	// power to a third. The gap between the maximum single phase and the minimum three phase power
+	// is not modeled.
+	PhaseSwitching bool
+	// Interruptible allows pausing the load. Loads which are not interruptible run at maximum
+	// power for the contiguous intervals needed to deliver the energy, which requires intervals of
+	// the same duration between earliest start and deadline.
+	Interruptible bool
+	// Priority is the charging priority among batteries and loads, see c_priority.
+	Priority int
+}
+
+// window returns the intervals [from, to) between earliest start and deadline
+func (l Load) window(start time.Time, dt []int) (int, int) {
+	from, to := len(dt), 0
+
+	ts := start
+	for t, d := range dt {
+		te := ts.Add(time.Duration(d) * time.Second)
+		if !ts.Before(l.EarliestStart) && (l.Deadline.IsZero() || !te.After(l.Deadline)) {
+			from, to = min(from, t), t+1
+		}
+		ts = te
+	}
+
+	return from, to
+}
+
+// Battery maps the load to a battery for a horizon starting at start with interval durations dt
+// in seconds.
+func (l Load) Battery(start time.Time, dt []int) (BatteryConfig, error) {
+	if l.MaxPower <= 0 || l.MinPower < 0 || l.MinPower > l.MaxPower {
+		return BatteryConfig{}, fmt.Errorf("power %v-%vW: must be positive and min not above max", l.MinPower, l.MaxPower)
+	}
+	if l.Energy < 0 {
+		return BatteryConfig{}, fmt.Errorf("energy %vWh must not be negative", l.Energy)
+	}
+	if !l.Deadline.IsZero() && !l.Deadline.After(l.EarliestStart) {
+		return BatteryConfig{}, errors.New("deadline must be after earliest start")
+	}
+
+	from, to := l.window(start, dt)
+	if from >= to {
+		return BatteryConfig{}, errors.New("no interval between earliest start and deadline")
+	}
+
+	bat := BatteryConfig{
+		Id:             l.Id,
+		Name:           l.Name,
+		ChargeFromGrid: true,
+		CPriority:      l.Priority,
+	}
+
+	if !l.Interruptible {
+		d := dt[from]
+		for t := from; t < to; t++ {
+			if dt[t] != d {
+				return BatteryConfig{}, errors.New("not interruptible: intervals must have the same duration")
+			}
+		}
+
+		steps := max(1, int(math.Ceil(float64(l.Energy)/(float64(l.MaxPower)*float64(d)/3600))))
+		if steps > to-from {
+			return BatteryConfig{}, fmt.Errorf("not interruptible: %d intervals required, %d available", steps, to-from)
+		}
+
+		// the load is supplied while available, the battery itself is empty
+		bat.Preconditioning = &Preconditioning{
+			Power:  l.MaxPower,
+			Steps:  steps,
+			TStart: from,
+			TEnd:   to,
+		}
+
+		return bat, nil
+	}
+
+	minPower := l.MinPower
+	if l.PhaseSwitching {
+		minPower /= 3
+	}
+
+	bat.CMin, bat.CMax = minPower, l.MaxPower
+	bat.SMax, bat.SCapacity = l.Energy, l.Energy
+
+	bat.Available = make([]bool, len(dt))
+	for t := from; t < to; t++ {
+		bat.Available[t] = true
+	}
+
+	bat.SGoal = make([]float32, len(dt))
+	bat.SGoal[to-1] = l.Energy
+
+	return bat, nil
+}
+
+// Schedule returns the energy of the load per interval [Wh] from the result of its battery.
+func (l Load) Schedule(res BatteryResult) []float32 {
+	if !l.Interruptible {
+		return res.PreconditioningPower
+	}
+	return res.ChargingPower
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// LowBandwidthFields are the result fields requested in low bandwidth mode.
+var LowBandwidthFields = []string{"status", "objective_value", "limit_violations", "batteries", "grid_import", "grid_export"}
+
+// WithLowBandwidth reduces the transferred data for sites on metered connections. Request
+// bodies are gzip compressed and compressed responses are requested. Optimization and
+// simulation requests without output options ask for LowBandwidthFields only, with result
+// series rounded to whole Wh.
+func WithLowBandwidth() Option {
+	return func(c *config) error {
+		c.lowBandwidth = true
+		return nil
+	}
+}
+
+func lowBandwidthDoer(doer HttpRequestDoer) HttpRequestDoer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil && req.Header.Get("Content-Encoding") == "" {
+			body, err := io.ReadAll(req.Body)
+			_ = req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+
+			if req.Method == http.MethodPost && (strings.HasSuffix(req.URL.Path, "/optimize/charge-schedule") ||
+				strings.HasSuffix(req.URL.Path, "/optimize/simulate")) {
+				body = withOutputOptions(body)
+			}
+
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			_, _ = zw.Write(body)
+			if err := zw.Close(); err != nil {
+				return nil, err
+			}
+
+			compressed := buf.Bytes()
+
+			req = req.Clone(req.Context())
+			req.Body = io.NopCloser(bytes.NewReader(compressed))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(compressed)), nil
+			}
+			req.ContentLength = int64(len(compressed))
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+
+		// setting the header disables transparent decompression of the transport
+		if req.Header.Get("Accept-Encoding") == "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("Accept-Encoding", "gzip")
+		}
+
+		resp, err := doer.Do(req)
+		if err != nil || resp.Header.Get("Content-Encoding") != "gzip" {
+			return resp, err
+		}
+
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, err
+		}
+
+		resp.Body = &gzipBody{Reader: zr, body: resp.Body}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+
+		return resp, nil
+	})
+}
+
+// withOutputOptions adds the low bandwidth output options to a request body without output
+// options. Bodies that are not JSON objects are returned unchanged.
+func withOutputOptions(body []byte) []byte {
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(body, &req); err != nil {
+		return body
+	}
+
+	if out, ok := req["output"]; ok && string(out) != "{}" && string(out) != "null" {
+		return body
+	}
+
+	precision := 0
+	out, err := json.Marshal(OutputOptions{Fields: LowBandwidthFields, Precision: &precision})
+	if err != nil {
+		return body
+	}
+	req["output"] = out
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return body
+	}
+
+	return b
+}
+
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+	_ = b.Reader.Close()
+	return b.body.Close()
+}
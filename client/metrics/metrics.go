@@ -0,0 +1,110 @@
+// Package metrics exports client request metrics to Prometheus, e.g. for operators watching
+// which callers hit timeouts:
+//
+//	c, err := client.New(uri, metrics.WithMetrics(prometheus.DefaultRegisterer))
+//
+// It is a separate package so that the client does not depend on Prometheus.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "evopt_client"
+
+// outcomes of requests without response
+const (
+	timeout = "timeout"
+	failed  = "error"
+)
+
+type collector struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	retries      *prometheus.CounterVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	solverStatus *prometheus.CounterVec
+}
+
+// WithMetrics registers the client metrics with reg and records all requests:
+//
+//   - evopt_client_requests_total by route and code, the code being timeout or error without response
+//   - evopt_client_request_duration_seconds by route and code
+//   - evopt_client_retries_total by route
+//   - evopt_client_request_size_bytes and evopt_client_response_size_bytes by route
+//   - evopt_client_solver_status_total by status of the optimization results
+//
+// Clients sharing reg share the metrics. It panics like prometheus.MustRegister if the metrics
+// conflict with other collectors of reg.
+func WithMetrics(reg prometheus.Registerer) client.Option {
+	sizes := prometheus.ExponentialBuckets(256, 4, 8)
+
+	c := collector{
+		requests: register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "requests_total", Help: "Requests by route and status code.",
+		}, []string{"route", "code"})),
+		duration: register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "request_duration_seconds", Help: "Request durations including retries.",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 25, 60},
+		}, []string{"route", "code"})),
+		retries: register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "retries_total", Help: "Retried request attempts by route.",
+		}, []string{"route"})),
+		requestSize: register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "request_size_bytes", Help: "Request body sizes.", Buckets: sizes,
+		}, []string{"route"})),
+		responseSize: register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "response_size_bytes", Help: "Response body sizes as received.", Buckets: sizes,
+		}, []string{"route"})),
+		solverStatus: register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "solver_status_total", Help: "Optimization results by solver status.",
+		}, []string{"status"})),
+	}
+
+	return client.WithObserver(c.observe)
+}
+
+// register registers col with reg, returning the collector registered before if any
+func register[T prometheus.Collector](reg prometheus.Registerer, col T) T {
+	if err := reg.Register(col); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return col
+}
+
+func (c collector) observe(_ context.Context, o client.Observation) {
+	code := strconv.Itoa(o.StatusCode)
+	if o.StatusCode == 0 {
+		code = failed
+		var ne net.Error
+		if errors.Is(o.Err, context.DeadlineExceeded) || (errors.As(o.Err, &ne) && ne.Timeout()) {
+			code = timeout
+		}
+	}
+
+	c.requests.WithLabelValues(o.Route, code).Inc()
+	c.duration.WithLabelValues(o.Route, code).Observe(o.Duration.Seconds())
+	if o.Attempts > 1 {
+		c.retries.WithLabelValues(o.Route).Add(float64(o.Attempts - 1))
+	}
+	c.requestSize.WithLabelValues(o.Route).Observe(float64(o.RequestSize))
+	if o.StatusCode != 0 {
+		c.responseSize.WithLabelValues(o.Route).Observe(float64(o.ResponseSize))
+	}
+	if o.SolverStatus != "" {
+		c.solverStatus.WithLabelValues(string(o.SolverStatus)).Inc()
+	}
+}
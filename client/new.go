@@ -0,0 +1,324 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/crypt"
+)
+
+// Middleware wraps a request doer, e.g. for tracing or metrics.
+type Middleware func(HttpRequestDoer) HttpRequestDoer
+
+// DoerFunc adapts a function to the HttpRequestDoer interface.
+type DoerFunc func(*http.Request) (*http.Response, error)
+
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type config struct {
+	doer            HttpRequestDoer
+	timeout         time.Duration
+	token           string
+	attempts        int
+	backoff         time.Duration
+	breakerFailures int
+	breakerCooldown time.Duration
+	maxResponse     int64
+	lowBandwidth    bool
+	clock           clock.Clock
+	logger          *slog.Logger
+	middleware      []Middleware
+	observers       []Observer
+	fallback        Fallback
+	postMortemDir   string
+	postMortemCph   *crypt.Cipher
+	postMortem      func(string, error)
+	editors         []RequestEditorFn
+	transforms      []func(*OptimizationInput) error
+}
+
+// Option configures a client created by New.
+type Option func(*config) error
+
+// WithDoer sets the underlying HTTP client. Defaults to an http.Client using the configured timeout.
+func WithDoer(doer HttpRequestDoer) Option {
+	return func(c *config) error {
+		if doer == nil {
+			return errors.New("nil doer")
+		}
+		c.doer = doer
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of the default HTTP client. Defaults to one minute.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) error {
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// WithToken authorizes requests using a bearer token.
+func WithToken(token string) Option {
+	return func(c *config) error {
+		c.token = token
+		return nil
+	}
+}
+
+// WithRetry retries requests failing to connect, by a reset connection or with status 502, 503
+// or 504 up to attempts times in total, doubling the backoff between attempts. Requests rate
+// limited with status 429 are retried after the time of the Retry-After header at least.
+// Optimization requests are side effect free and therefore safe to retry, job submissions are
+// never retried. Timeouts are not retried.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(c *config) error {
+		if attempts < 1 {
+			return errors.New("retry attempts must be at least 1")
+		}
+		c.attempts, c.backoff = attempts, backoff
+		return nil
+	}
+}
+
+// WithLogger logs each request attempt at debug level.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithClock sets the clock used for retry backoff and request durations. Defaults to the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(c *config) error {
+		if clk == nil {
+			return errors.New("nil clock")
+		}
+		c.clock = clk
+		return nil
+	}
+}
+
+// WithMiddleware adds middleware around all other request handling. The first middleware is the outermost.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *config) error {
+		c.middleware = append(c.middleware, middleware...)
+		return nil
+	}
+}
+
+// WithEditor adds a request editor applied to all requests.
+func WithEditor(fn RequestEditorFn) Option {
+	return func(c *config) error {
+		c.editors = append(c.editors, fn)
+		return nil
+	}
+}
+
+// WithOptions combines options, e.g. for packages providing several options as one.
+func WithOptions(opts ...Option) Option {
+	return func(c *config) error {
+		for _, opt := range opts {
+			if err := opt(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// New creates a client for the optimizer at server. Options are independent of their order.
+// Request handling is layered from the outside in: middleware, fallback, observers, post-mortem
+// capture, circuit breaker, retries, logging, response limit, low bandwidth encoding.
+func New(server string, opts ...Option) (*ClientWithResponses, error) {
+	c := config{
+		timeout:  time.Minute,
+		attempts: 1,
+		clock:    clock.Real,
+	}
+
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return nil, err
+		}
+	}
+
+	doer := c.doer
+	if doer == nil {
+		doer = &http.Client{Timeout: c.timeout}
+	}
+
+	if c.lowBandwidth {
+		doer = lowBandwidthDoer(doer)
+	}
+
+	if c.maxResponse > 0 {
+		doer = &limitDoer{doer: doer, limit: c.maxResponse}
+	}
+
+	if c.logger != nil {
+		doer = logDoer(doer, c.logger, c.clock)
+	}
+
+	if len(c.observers) > 0 {
+		doer = countDoer(doer)
+	}
+
+	if c.attempts > 1 {
+		doer = retryDoer(doer, c.attempts, c.backoff, c.clock)
+	}
+
+	if c.breakerFailures > 0 {
+		doer = breakerDoer(doer, c.breakerFailures, c.breakerCooldown, c.clock)
+	}
+
+	if c.postMortemDir != "" {
+		doer = postMortemDoer(doer, c.postMortemDir, c.postMortemCph, c.postMortem, c.clock)
+	}
+
+	if len(c.observers) > 0 {
+		doer = observeDoer(doer, c.observers, c.clock)
+	}
+
+	if c.fallback != nil {
+		doer = fallbackDoer(doer, c.fallback)
+	}
+
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		doer = c.middleware[i](doer)
+	}
+
+	clientOpts := []ClientOption{WithHTTPClient(clockDoer{HttpRequestDoer: doer, clock: c.clock})}
+
+	if len(c.transforms) > 0 {
+		clientOpts = append(clientOpts, WithRequestEditorFn(transformEditor(c.transforms)))
+	}
+
+	if c.token != "" {
+		clientOpts = append(clientOpts, WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+			return nil
+		}))
+	}
+
+	for _, fn := range c.editors {
+		clientOpts = append(clientOpts, WithRequestEditorFn(fn))
+	}
+
+	return NewClientWithResponses(server, clientOpts...)
+}
+
+// clockDoer carries the clock of the client to methods timing requests themselves, see clockOf
+type clockDoer struct {
+	HttpRequestDoer
+	clock clock.Clock
+}
+
+// clockOf returns the clock of a client created by New, the system clock otherwise
+func (c *ClientWithResponses) clockOf() clock.Clock {
+	if cl, ok := c.ClientInterface.(*Client); ok {
+		if d, ok := cl.Client.(clockDoer); ok {
+			return d.clock
+		}
+	}
+	return clock.Real
+}
+
+func logDoer(doer HttpRequestDoer, logger *slog.Logger, clk clock.Clock) HttpRequestDoer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		start := clk.Now()
+		resp, err := doer.Do(req)
+
+		attrs := []any{"method", req.Method, "url", req.URL.String(), "duration", clk.Now().Sub(start)}
+		if err != nil {
+			logger.DebugContext(req.Context(), "request failed", append(attrs, "error", err)...)
+			return nil, err
+		}
+
+		logger.DebugContext(req.Context(), "request", append(attrs, "status", resp.StatusCode)...)
+		return resp, nil
+	})
+}
+
+// retryable reports whether a response indicates a transient server condition. Rate limited
+// requests are retried after the time of their Retry-After header.
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryableError reports whether a request failed before reaching the server or by a reset
+// connection. Timeouts are not retried, the server may still be solving.
+func retryableError(err error) bool {
+	var opErr *net.OpError
+	return (errors.As(err, &opErr) && opErr.Op == "dial") || errors.Is(err, syscall.ECONNRESET)
+}
+
+// idempotent reports whether req may be sent again. Submitting a job is not, a retry would
+// create another job.
+func idempotent(req *http.Request) bool {
+	return req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/optimize/jobs")
+}
+
+// retryAfter returns the delay of the Retry-After header given in seconds or as date, zero if
+// missing or invalid
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+func retryDoer(doer HttpRequestDoer, attempts int, backoff time.Duration, clk clock.Clock) HttpRequestDoer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		for attempt := 1; ; attempt++ {
+			resp, err := doer.Do(req)
+
+			last := attempt == attempts || (req.Body != nil && req.GetBody == nil) || !idempotent(req)
+			if last || (err == nil && !retryable(resp.StatusCode)) || (err != nil && !retryableError(err)) || req.Context().Err() != nil {
+				return resp, err
+			}
+
+			wait := backoff << (attempt - 1)
+			if err == nil {
+				if resp.StatusCode == http.StatusTooManyRequests {
+					wait = max(wait, retryAfter(resp, clk.Now()))
+				}
+				_ = resp.Body.Close()
+			}
+
+			select {
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			case <-clk.After(wait):
+			}
+
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/evcc-io/optimizer/clock"
+)
+
+// Observation is the outcome of a request, e.g. for metrics. Durations and sizes include all
+// attempts of retried requests.
+type Observation struct {
+	Method string
+	// Route is the path of the request with ids replaced by {id}, e.g. /optimize/jobs/{id}.
+	Route string
+	// StatusCode of the response, zero if no response was received.
+	StatusCode int
+	// Err is the transport error if no response was received.
+	Err      error
+	Duration time.Duration
+	Attempts int
+	// RequestSize and ResponseSize are the body sizes [bytes], the response size as received.
+	// Event streams are not read, their response size is zero and their duration ends with the
+	// response headers.
+	RequestSize, ResponseSize int64
+	// SolverStatus is the status of optimization results, empty for other responses.
+	SolverStatus OptimizationResultStatus
+}
+
+// Observer receives the observation of each request with the context of the request.
+type Observer func(ctx context.Context, o Observation)
+
+// WithObserver reports each request to fn after its response body has been read, e.g. for
+// metrics or tracing. Observers run inside the middleware.
+func WithObserver(fn Observer) Option {
+	return func(c *config) error {
+		c.observers = append(c.observers, fn)
+		return nil
+	}
+}
+
+type attemptsKey struct{}
+
+// countDoer counts the attempts of requests observed by observeDoer
+func countDoer(doer HttpRequestDoer) HttpRequestDoer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if n, ok := req.Context().Value(attemptsKey{}).(*atomic.Int32); ok {
+			n.Add(1)
+		}
+		return doer.Do(req)
+	})
+}
+
+func observeDoer(doer HttpRequestDoer, observers []Observer, clk clock.Clock) HttpRequestDoer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		start := clk.Now()
+
+		var attempts atomic.Int32
+		resp, err := doer.Do(req.WithContext(context.WithValue(ctx, attemptsKey{}, &attempts)))
+
+		o := Observation{
+			Method:      req.Method,
+			Route:       route(req.URL.Path),
+			Err:         err,
+			RequestSize: max(0, req.ContentLength),
+		}
+
+		// the body is read here to observe its size and the solver status, the generated
+		// client reads it completely anyway
+		if err == nil {
+			o.StatusCode = resp.StatusCode
+		}
+		if err == nil && !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+
+			var body []byte
+			body, err = io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+
+			o.ResponseSize = int64(len(body))
+			if resp.StatusCode == http.StatusOK && (strings.HasSuffix(o.Route, "/charge-schedule") ||
+				strings.HasSuffix(o.Route, "/simulate") || strings.HasSuffix(o.Route, "/result")) {
+				var res struct {
+					Status OptimizationResultStatus `json:"status"`
+				}
+				if json.Unmarshal(body, &res) == nil {
+					o.SolverStatus = res.Status
+				}
+			}
+
+			if err != nil {
+				o.Err = err
+				resp = nil
+			}
+		}
+
+		o.Duration = clk.Now().Sub(start)
+		o.Attempts = int(attempts.Load())
+
+		for _, fn := range observers {
+			fn(ctx, o)
+		}
+
+		return resp, err
+	})
+}
+
+// route replaces the ids in path by {id}, keeping the cardinality of routes low
+func route(path string) string {
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i-1] == "jobs" || segments[i-1] == "templates" {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
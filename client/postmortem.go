@@ -0,0 +1,195 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/crypt"
+)
+
+// postMortemBodyLimit is the maximum size of request and response bodies in post-mortem
+// bundles [bytes]
+const postMortemBodyLimit = 64 << 10
+
+// PostMortem is a bundle of a failed request for issue reports, saved by WithPostMortem.
+type PostMortem struct {
+	Time          time.Time   `json:"time"`
+	ClientVersion string      `json:"client_version"`
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	Reason        string      `json:"reason"`
+	Error         string      `json:"error,omitempty"`
+	Duration      string      `json:"duration"`
+	RequestDigest string      `json:"request_digest,omitempty"` // see Digest
+	RequestHeader http.Header `json:"request_header"`
+	// RequestBody and ResponseBody are truncated to 64 KiB, the sizes are the full sizes.
+	RequestBody    string      `json:"request_body,omitempty"`
+	RequestSize    int         `json:"request_size"`
+	StatusCode     int         `json:"status_code,omitempty"`
+	ResponseHeader http.Header `json:"response_header,omitempty"`
+	ResponseBody   string      `json:"response_body,omitempty"`
+	ResponseSize   int         `json:"response_size,omitempty"`
+}
+
+// WithPostMortem saves a post-mortem bundle of each request failing with a server error, a
+// timeout or a malformed JSON response as file in dir, created if missing. fn is called with
+// the path of each saved bundle or the error saving it, e.g. to print the path for an issue
+// report. The authorization header is redacted. Bundles contain the full request and are sealed
+// by cph, e.g. the cipher of crypt.FromEnv, read them with cph.ReadFile. A nil cipher saves them
+// unencrypted.
+func WithPostMortem(dir string, cph *crypt.Cipher, fn func(path string, err error)) Option {
+	return func(c *config) error {
+		if dir == "" {
+			return errors.New("empty post-mortem directory")
+		}
+		c.postMortemDir, c.postMortemCph, c.postMortem = dir, cph, fn
+		return nil
+	}
+}
+
+func postMortemDoer(doer HttpRequestDoer, dir string, cph *crypt.Cipher, fn func(string, error), clk clock.Clock) HttpRequestDoer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		var body []byte
+		if req.GetBody != nil {
+			if rc, err := req.GetBody(); err == nil {
+				body, _ = io.ReadAll(rc)
+				_ = rc.Close()
+			}
+		}
+
+		start := clk.Now()
+		resp, err := doer.Do(req)
+
+		pm := PostMortem{
+			Time:          start.UTC(),
+			ClientVersion: clientVersion(),
+			Method:        req.Method,
+			URL:           req.URL.Redacted(),
+			RequestHeader: req.Header.Clone(),
+			RequestBody:   truncateBody(body),
+			RequestSize:   len(body),
+		}
+		if pm.RequestHeader.Get("Authorization") != "" {
+			pm.RequestHeader.Set("Authorization", "REDACTED")
+		}
+		if json.Valid(body) {
+			pm.RequestDigest, _ = Digest(json.RawMessage(body))
+		}
+
+		switch {
+		case err != nil:
+			var netErr net.Error
+			if !errors.Is(err, context.DeadlineExceeded) && !(errors.As(err, &netErr) && netErr.Timeout()) {
+				return resp, err
+			}
+			pm.Reason, pm.Error = "timeout", err.Error()
+
+		case strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") && resp.StatusCode < 500:
+			return resp, err
+
+		default:
+			// the response is buffered for the bundle, the generated client reads it completely anyway
+			var b []byte
+			b, err = io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case resp.StatusCode >= 500:
+				pm.Reason = "server error"
+			case strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") && !json.Valid(b):
+				pm.Reason = "malformed response"
+			default:
+				return resp, nil
+			}
+
+			pm.StatusCode = resp.StatusCode
+			pm.ResponseHeader = resp.Header.Clone()
+			pm.ResponseBody = truncateBody(b)
+			pm.ResponseSize = len(b)
+		}
+
+		pm.Duration = clk.Now().Sub(start).String()
+
+		path, saveErr := savePostMortem(dir, cph, pm)
+		if fn != nil {
+			fn(path, saveErr)
+		}
+
+		return resp, err
+	})
+}
+
+// savePostMortem writes the bundle sealed by cph to a new file in dir and returns its path
+func savePostMortem(dir string, cph *crypt.Cipher, pm PostMortem) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(pm, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if b, err = cph.Seal(b); err != nil {
+		return "", err
+	}
+
+	name := "postmortem-" + pm.Time.Format("20060102T150405.000Z")
+	if pm.RequestDigest != "" {
+		name += "-" + pm.RequestDigest
+	}
+
+	f, err := os.CreateTemp(dir, name+"-*.json")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+
+	return f.Name(), f.Close()
+}
+
+func truncateBody(b []byte) string {
+	if len(b) > postMortemBodyLimit {
+		return fmt.Sprintf("%s... (%d bytes truncated)", b[:postMortemBodyLimit], len(b)-postMortemBodyLimit)
+	}
+	return string(b)
+}
+
+// clientVersion returns the version of the client module as built into the binary
+func clientVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	const module = "github.com/evcc-io/optimizer"
+	if info.Main.Path == module {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == module {
+			return dep.Version
+		}
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Phase is the phase of a solve.
+type Phase string
+
+// solver phases
+const (
+	PhaseModel      Phase = "model"
+	PhaseRelaxation Phase = "relaxation"
+	PhaseSearch     Phase = "search"
+	PhaseResult     Phase = "result"
+)
+
+// Progress is the progress of a streamed optimization.
+type Progress struct {
+	Phase   Phase
+	Elapsed time.Duration
+	// Objective of the incumbent solution in the terms of the solver, nil until a solution is
+	// found. Only its change is meaningful.
+	Objective *float64
+	// Gap of the incumbent to the best bound, relative, nil until a solution is found.
+	Gap *float64
+}
+
+type progressEvent struct {
+	Phase     Phase    `json:"phase"`
+	Elapsed   float64  `json:"elapsed"`
+	Objective *float64 `json:"objective"`
+	Gap       *float64 `json:"gap"`
+}
+
+type streamError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// OptimizeWithProgress solves req like PostOptimizeChargeSchedule and calls fn with the
+// progress of the solver about every second until the result arrives, e.g. for horizons with
+// several batteries taking minutes to solve. The timeout of the HTTP client covers the
+// complete solve.
+func (c *ClientWithResponses) OptimizeWithProgress(ctx context.Context, req OptimizationInput, fn func(Progress), reqEditors ...RequestEditorFn) (*OptimizationResult, error) {
+	resp, err := c.PostOptimizeStream(ctx, req, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e Error
+		if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+			_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+		}
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, e.Message)
+	}
+
+	var event string
+	var data bytes.Buffer
+
+	// results of long horizons exceed the default line length of the scanner
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(nil, int(DefaultMaxResponseSize))
+
+	for sc.Scan() {
+		line := sc.Text()
+
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			continue
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		case line != "":
+			// comments and unknown fields
+			continue
+		}
+
+		// an empty line dispatches the event
+		switch event {
+		case "progress":
+			var p progressEvent
+			if err := json.Unmarshal(data.Bytes(), &p); err != nil {
+				return nil, fmt.Errorf("progress: %w", err)
+			}
+			if fn != nil {
+				fn(Progress{
+					Phase:     p.Phase,
+					Elapsed:   time.Duration(p.Elapsed * float64(time.Second)),
+					Objective: p.Objective,
+					Gap:       p.Gap,
+				})
+			}
+
+		case "result":
+			var res OptimizationResult
+			if err := json.Unmarshal(data.Bytes(), &res); err != nil {
+				return nil, fmt.Errorf("result: %w", err)
+			}
+			return &res, nil
+
+		case "error":
+			var e streamError
+			if err := json.Unmarshal(data.Bytes(), &e); err != nil {
+				return nil, fmt.Errorf("error: %w", err)
+			}
+			if e.Code >= http.StatusInternalServerError {
+				return nil, fmt.Errorf("server error: %s", e.Message)
+			}
+			return nil, fmt.Errorf("bad request: %s", e.Message)
+		}
+
+		event = ""
+		data.Reset()
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, errors.New("stream ended without result")
+}
@@ -0,0 +1,108 @@
+package client
+
+import "fmt"
+
+// Policy reconciles an optional per time step series whose length differs from dt.
+type Policy int
+
+const (
+	// Reject fails reconciliation. This is the default.
+	Reject Policy = iota
+	// Pad extends short series and truncates long ones.
+	Pad
+	// Truncate shortens long series, short series are rejected.
+	Truncate
+)
+
+// Optional series reconciled by Reconcile. Battery series apply to all batteries.
+const (
+	FieldSGoal      = "s_goal"
+	FieldPDemand    = "p_demand"
+	FieldCEtaSeries = "c_eta_series"
+	FieldEmN        = "em_N"
+	FieldRCurt      = "r_curt"
+)
+
+// Policies selects the policy per field. Fields without policy are rejected.
+type Policies map[string]Policy
+
+// ReconcileWarning reports a series whose length was changed.
+type ReconcileWarning struct {
+	Field   string
+	Battery int // battery index, -1 for time series fields
+	Length  int // original length
+	Want    int // length of dt
+}
+
+func (w ReconcileWarning) path() string {
+	if w.Battery >= 0 {
+		return fmt.Sprintf("batteries.%d.%s", w.Battery, w.Field)
+	}
+	return w.Field
+}
+
+func (w ReconcileWarning) String() string {
+	return fmt.Sprintf("%s: reconciled %d values to %d", w.path(), w.Length, w.Want)
+}
+
+// Reconcile aligns the optional series of req to the length of dt according to policies,
+// returning warnings for changed series. Padding uses neutral values: no goal or demand and no
+// curtailment risk, efficiency and emissions repeat their last value. Empty series are left
+// unchanged as they are omitted from the request.
+func Reconcile(req *OptimizationInput, policies Policies) ([]ReconcileWarning, error) {
+	n := len(req.TimeSeries.Dt)
+
+	var warnings []ReconcileWarning
+
+	apply := func(field string, battery int, series []float32, repeatLast bool) ([]float32, error) {
+		if len(series) == 0 || len(series) == n {
+			return series, nil
+		}
+
+		w := ReconcileWarning{Field: field, Battery: battery, Length: len(series), Want: n}
+		policy := policies[field]
+
+		switch {
+		case len(series) > n && policy != Reject:
+			series = series[:n]
+		case len(series) < n && policy == Pad:
+			var pad float32
+			if repeatLast {
+				pad = series[len(series)-1]
+			}
+			for len(series) < n {
+				series = append(series, pad)
+			}
+		default:
+			return nil, fmt.Errorf("%s has %d values, dt has %d", w.path(), w.Length, w.Want)
+		}
+
+		warnings = append(warnings, w)
+
+		return series, nil
+	}
+
+	var err error
+
+	for i := range req.Batteries {
+		b := &req.Batteries[i]
+		if b.SGoal, err = apply(FieldSGoal, i, b.SGoal, false); err != nil {
+			return nil, err
+		}
+		if b.PDemand, err = apply(FieldPDemand, i, b.PDemand, false); err != nil {
+			return nil, err
+		}
+		if b.CEtaSeries, err = apply(FieldCEtaSeries, i, b.CEtaSeries, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.TimeSeries.EmN, err = apply(FieldEmN, -1, req.TimeSeries.EmN, true); err != nil {
+		return nil, err
+	}
+	if req.TimeSeries.RCurt, err = apply(FieldRCurt, -1, req.TimeSeries.RCurt, false); err != nil {
+		return nil, err
+	}
+
+	return warnings, nil
+}
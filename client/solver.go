@@ -0,0 +1,87 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Solver tunes the solve of optimization requests, trading optimality for latency.
+type Solver struct {
+	// TimeLimit limits the solve, capped by the time limit of the server. The best solution
+	// found is returned if the limit is hit, unless RequireOptimal is set.
+	TimeLimit time.Duration
+	// MipGap is the relative optimality gap at which the solve stops, e.g. 0.01.
+	MipGap float64
+	// RequireOptimal returns no plan instead of the best solution found at the time limit.
+	RequireOptimal bool
+}
+
+// WithSolver applies s to optimization requests not setting the solver fields themselves,
+// e.g. for controllers acting in a fixed loop:
+//
+//	client.New(uri, client.WithSolver(client.Solver{TimeLimit: 20 * time.Second, MipGap: 0.01}))
+func WithSolver(s Solver) Option {
+	return func(c *config) error {
+		if s.TimeLimit < 0 || s.MipGap < 0 || s.MipGap > 1 {
+			return errors.New("solver time limit must not be negative and gap must be between 0 and 1")
+		}
+		c.editors = append(c.editors, solverEditor(s))
+		return nil
+	}
+}
+
+// solverEditor adds the solver fields to optimization request bodies
+func solverEditor(s Solver) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		if req.Method != http.MethodPost || req.GetBody == nil || !(strings.HasSuffix(req.URL.Path, "/optimize/charge-schedule") ||
+			strings.HasSuffix(req.URL.Path, "/optimize/jobs")) {
+			return nil
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		var in map[string]json.RawMessage
+		if err := json.NewDecoder(body).Decode(&in); err != nil {
+			return nil
+		}
+
+		set := func(key string, value any) {
+			if _, ok := in[key]; !ok {
+				in[key], _ = json.Marshal(value)
+			}
+		}
+
+		if s.TimeLimit > 0 {
+			set("time_limit", s.TimeLimit.Seconds())
+		}
+		if s.MipGap > 0 {
+			set("mip_gap", s.MipGap)
+		}
+		if s.RequireOptimal {
+			set("require_optimal", true)
+		}
+
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(b)), nil
+		}
+		req.ContentLength = int64(len(b))
+
+		return nil
+	}
+}
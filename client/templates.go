@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrTemplateNotFound is returned if the serving host does not know the template. Templates are
+// stored per host, register the template again and retry.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// CreateTemplate registers the static site definition t and returns its id. Registering the
+// same definition again returns the same id.
+func (c *ClientWithResponses) CreateTemplate(ctx context.Context, t Template, reqEditors ...RequestEditorFn) (string, error) {
+	resp, err := c.PostOptimizeTemplatesWithResponse(ctx, t, reqEditors...)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case resp.JSON201 != nil:
+		return resp.JSON201.Id, nil
+	case resp.JSON400 != nil:
+		return "", fmt.Errorf("bad request: %s %v", resp.JSON400.Message, resp.JSON400.Details)
+	default:
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+}
+
+// GetTemplate returns the template registered as id.
+func (c *ClientWithResponses) GetTemplate(ctx context.Context, id string, reqEditors ...RequestEditorFn) (*Template, error) {
+	resp, err := c.GetOptimizeTemplatesIdWithResponse(ctx, id, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.JSON200 != nil:
+		return resp.JSON200, nil
+	case resp.StatusCode() == http.StatusNotFound:
+		return nil, ErrTemplateNotFound
+	default:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+}
+
+// DeleteTemplate deletes the template registered as id.
+func (c *ClientWithResponses) DeleteTemplate(ctx context.Context, id string, reqEditors ...RequestEditorFn) error {
+	resp, err := c.DeleteOptimizeTemplatesIdWithResponse(ctx, id, reqEditors...)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return ErrTemplateNotFound
+	default:
+		return fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+}
+
+// SolveTemplate solves the template registered as id with the dynamic inputs of in. Non-200
+// responses are returned as error, ErrTemplateNotFound if the template needs to be registered.
+func (c *ClientWithResponses) SolveTemplate(ctx context.Context, id string, in TemplateInput, reqEditors ...RequestEditorFn) (*OptimizationResult, error) {
+	resp, err := c.PostOptimizeTemplatesIdChargeScheduleWithResponse(ctx, id, in, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.JSON200 != nil:
+		return resp.JSON200, nil
+	case resp.JSON400 != nil:
+		return nil, fmt.Errorf("bad request: %s %v", resp.JSON400.Message, resp.JSON400.Details)
+	case resp.JSON404 != nil:
+		return nil, ErrTemplateNotFound
+	case resp.JSON500 != nil:
+		return nil, fmt.Errorf("server error: %s", resp.JSON500.Message)
+	default:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+}
@@ -0,0 +1,75 @@
+// Package tracing traces client requests with OpenTelemetry, one client span per request
+// including its retries:
+//
+//	c, err := client.New(uri, tracing.WithTracerProvider(otel.GetTracerProvider()))
+//
+// The trace context is propagated to the server using the global propagator. It is a separate
+// package so that the client does not depend on OpenTelemetry.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/evcc-io/optimizer/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const scope = "github.com/evcc-io/optimizer/client"
+
+// WithTracerProvider traces requests using spans of tp. Spans carry the request method, route,
+// status code, attempts, body sizes and the solver status of optimization results.
+func WithTracerProvider(tp trace.TracerProvider) client.Option {
+	tracer := tp.Tracer(scope)
+
+	return client.WithOptions(
+		client.WithMiddleware(func(next client.HttpRequestDoer) client.HttpRequestDoer {
+			return client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+				ctx, span := tracer.Start(req.Context(), req.Method, trace.WithSpanKind(trace.SpanKindClient))
+				defer span.End()
+
+				req = req.WithContext(ctx)
+				otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+				return next.Do(req)
+			})
+		}),
+		client.WithObserver(observe),
+	)
+}
+
+// observe annotates the span of the request with its observation
+func observe(ctx context.Context, o client.Observation) {
+	span := trace.SpanFromContext(ctx)
+
+	span.SetName(o.Method + " " + o.Route)
+	span.SetAttributes(
+		attribute.String("http.request.method", o.Method),
+		attribute.String("http.route", o.Route),
+		attribute.Int("http.request.resend_count", max(0, o.Attempts-1)),
+		attribute.Int64("http.request.body.size", o.RequestSize),
+	)
+
+	switch {
+	case o.StatusCode == 0:
+		span.RecordError(o.Err)
+		span.SetStatus(codes.Error, fmt.Sprint(o.Err))
+	default:
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", o.StatusCode),
+			attribute.Int64("http.response.body.size", o.ResponseSize),
+		)
+		if o.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(o.StatusCode))
+		}
+	}
+
+	if o.SolverStatus != "" {
+		span.SetAttributes(attribute.String("evopt.solver.status", string(o.SolverStatus)))
+	}
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WithTransform applies fn to the optimization requests of the charge schedule, job, stream
+// and validate endpoints before they are sent, e.g. a transform.Pipeline. Requests are
+// rejected with the error returned by fn. Transforms run before the request editors.
+func WithTransform(fn func(req *OptimizationInput) error) Option {
+	return func(c *config) error {
+		c.transforms = append(c.transforms, fn)
+		return nil
+	}
+}
+
+// transformEditor decodes optimization request bodies, applies the transforms and encodes
+// the result
+func transformEditor(transforms []func(*OptimizationInput) error) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		if req.Method != http.MethodPost || req.GetBody == nil || !(strings.HasSuffix(req.URL.Path, "/optimize/charge-schedule") ||
+			strings.HasSuffix(req.URL.Path, "/optimize/jobs") || strings.HasSuffix(req.URL.Path, "/optimize/stream") ||
+			strings.HasSuffix(req.URL.Path, "/optimize/validate")) {
+			return nil
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+
+		var in OptimizationInput
+		if err := json.NewDecoder(body).Decode(&in); err != nil {
+			return err
+		}
+
+		for _, fn := range transforms {
+			if err := fn(&in); err != nil {
+				return err
+			}
+		}
+
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(b)), nil
+		}
+		req.ContentLength = int64(len(b))
+
+		return nil
+	}
+}
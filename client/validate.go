@@ -0,0 +1,370 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Validate checks req for inconsistencies the optimizer would reject or fail on, like series
+// not matching the horizon, inconsistent battery bounds or unsupported combinations of
+// objective and grid configuration. All problems found are returned joined.
+func Validate(req OptimizationInput) error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	ts := req.TimeSeries
+	n := len(ts.Dt)
+
+	if n == 0 {
+		fail("time_series.dt: no intervals")
+	}
+	for t, d := range ts.Dt {
+		if d <= 0 {
+			fail("time_series.dt[%d]: duration %d must be positive", t, d)
+			break
+		}
+	}
+
+	length := func(name string, l int, required bool) {
+		if (required || l > 0) && l != n {
+			fail("%s: %d values, time_series.dt has %d", name, l, n)
+		}
+	}
+
+	length("time_series.p_N", len(ts.PN), true)
+	length("time_series.p_E", len(ts.PE), true)
+	length("time_series.ft", len(ts.Ft), false)
+	length("time_series.gt", len(ts.Gt), false)
+	length("time_series.em_N", len(ts.EmN), false)
+	length("time_series.r_curt", len(ts.RCurt), false)
+	length("time_series.w_E", len(ts.WE), false)
+	length("time_series.n_commit", len(ts.NCommit), false)
+	length("time_series.p_max_imp", len(ts.PMaxImp), false)
+	length("time_series.p_G", len(ts.PG), false)
+	length("time_series.q_H", len(ts.QH), false)
+
+	for t, f := range ts.Ft {
+		if f < 0 {
+			fail("time_series.ft[%d]: %v must not be negative, uncontrolled generation can be given as negative gt", t, f)
+			break
+		}
+	}
+	for t, r := range ts.RCurt {
+		if r < 0 || r > 1 {
+			fail("time_series.r_curt[%d]: %v must be between 0 and 1", t, r)
+			break
+		}
+	}
+	for t, w := range ts.WE {
+		if w < 0 {
+			fail("time_series.w_E[%d]: %v must not be negative", t, w)
+			break
+		}
+	}
+	for t, p := range ts.PMaxImp {
+		if p < 0 {
+			fail("time_series.p_max_imp[%d]: %v must not be negative", t, p)
+			break
+		}
+	}
+
+	ids := make(map[string]bool)
+	for i, bat := range req.Batteries {
+		name := fmt.Sprintf("batteries[%d]", i)
+
+		for _, err := range validateBattery(bat) {
+			fail("%s: %w", name, err)
+		}
+
+		length(name+".p_demand", len(bat.PDemand), false)
+		length(name+".s_goal", len(bat.SGoal), false)
+		length(name+".available", len(bat.Available), false)
+		length(name+".c_eta_series", len(bat.CEtaSeries), false)
+
+		if len(bat.Available) == len(bat.PDemand) {
+			for t, available := range bat.Available {
+				if !available && bat.PDemand[t] > 0 {
+					fail("%s.p_demand[%d]: charge demand while unavailable", name, t)
+					break
+				}
+			}
+		}
+
+		if pre := bat.Preconditioning; pre != nil {
+			if pre.Power < 0 || pre.Steps < 1 {
+				fail("%s.preconditioning: power must not be negative and steps must be at least 1", name)
+			}
+			if pre.TStart < 0 || pre.TEnd > n || pre.TEnd-pre.TStart < pre.Steps {
+				fail("%s.preconditioning: %d steps do not fit into the window [%d, %d) of %d intervals", name, pre.Steps, pre.TStart, pre.TEnd, n)
+			}
+		}
+
+		if cal := bat.Calibration; cal != nil && (cal.Interval <= 0 || cal.Elapsed < 0) {
+			fail("%s.calibration: interval must be positive and elapsed must not be negative", name)
+		}
+
+		var probability float32
+		for k, dep := range bat.Departures {
+			if dep.T < 0 || dep.T >= n {
+				fail("%s.departures[%d]: time step %d outside of %d intervals", name, k, dep.T, n)
+			}
+			if dep.Probability < 0 || dep.Probability > 1 {
+				fail("%s.departures[%d]: probability %v must be between 0 and 1", name, k, dep.Probability)
+			}
+			probability += dep.Probability
+		}
+		if probability > 1+1e-6 {
+			fail("%s.departures: probabilities sum up to %v, must not exceed 1", name, probability)
+		}
+
+		if bat.Id != "" && ids[bat.Id] {
+			fail("%s.id: %q is not unique", name, bat.Id)
+		}
+		ids[bat.Id] = true
+	}
+
+	g := req.Grid
+	if (g.EImpTier != 0) != (g.PrcEExcTier != 0) {
+		fail("grid: tiered tariff requires both e_imp_tier and prc_e_exc_tier")
+	}
+	if g.EImpTier == 0 && (g.EImpToDate != 0 || g.TTierReset != 0) {
+		fail("grid: e_imp_to_date and t_tier_reset require a tiered tariff")
+	}
+	if g.EExpCap == 0 && (g.EExpToDate != 0 || g.TCapReset != 0) {
+		fail("grid: e_exp_to_date and t_cap_reset require an export cap")
+	}
+	if g.PrcENetImp == 0 && (g.PrcENetExp != 0 || g.ENetToDate != 0 || g.TNetReset != 0) {
+		fail("grid: prc_e_net_exp, e_net_to_date and t_net_reset require net metering by prc_e_net_imp")
+	}
+	if g.PrcENetExp > g.PrcENetImp {
+		fail("grid.prc_e_net_exp: %v must not exceed prc_e_net_imp", g.PrcENetExp)
+	}
+	if len(g.PrcPPeak) == 0 && g.PrcPExcImp == 0 && g.PPeakToDate != 0 {
+		fail("grid: p_peak_to_date requires a peak price curve or a demand rate")
+	}
+	if len(g.PrcPPeak) > 0 && g.PrcPExcImp != 0 {
+		fail("grid: peak price curve cannot be combined with the demand rate prc_p_exc_imp")
+	}
+	for k := 1; k < len(g.PrcPPeak); k++ {
+		if a, b := g.PrcPPeak[k-1], g.PrcPPeak[k]; b.Power <= a.Power || b.Price < a.Price {
+			fail("grid.prc_p_peak[%d]: powers must increase and prices must not decrease", k)
+			break
+		}
+	}
+	if g.PConn < 0 {
+		fail("grid.p_conn: %v must not be negative", g.PConn)
+	}
+	if g.PrcPExcImp != 0 && (len(ts.PMaxImp) > 0 || g.PConn != 0) {
+		fail("grid: demand rate prc_p_exc_imp cannot be combined with time_series.p_max_imp or grid.p_conn")
+	}
+	if (len(ts.NCommit) > 0) != (g.PrcEDev != 0) {
+		fail("grid: committed schedule requires both time_series.n_commit and grid.prc_e_dev")
+	}
+
+	if req.TimeLimit < 0 {
+		fail("time_limit: %v must not be negative", req.TimeLimit)
+	}
+	if req.MipGap < 0 || req.MipGap > 1 {
+		fail("mip_gap: %v must be between 0 and 1", req.MipGap)
+	}
+
+	if w := req.ObjectiveWeights; w != nil {
+		if err := validateObjectiveWeights(*w); err != nil {
+			fail("objective_weights: %w", err)
+		}
+		if req.CostBudget != 0 {
+			fail("objective_weights: cannot be combined with cost budget")
+		}
+	}
+
+	if chp := req.Chp; chp != nil {
+		for _, err := range validateChp(*chp, ts) {
+			fail("chp: %w", err)
+		}
+	} else if len(ts.PG) > 0 || len(ts.QH) > 0 {
+		fail("time_series: p_G and q_H require a combined heat and power unit chp")
+	}
+
+	switch obj := req.Strategy.Objective; obj {
+	case "", Cost:
+	default:
+		if req.Chp != nil {
+			fail("strategy.objective: %s objective cannot be combined with a combined heat and power unit", obj)
+		}
+		if obj == Emissions && len(ts.EmN) == 0 {
+			fail("strategy.objective: emissions objective requires time_series.em_N")
+		}
+		if g.PrcPExcImp != 0 || len(g.PrcPPeak) > 0 || g.EImpTier != 0 || g.PrcENetImp != 0 || g.PrcEDev != 0 || req.CostBudget != 0 {
+			fail("strategy.objective: %s objective cannot be combined with demand rate, peak price, tiered tariff, net metering, deviation price or cost budget", obj)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateObjectiveWeights checks that weights are not negative and at least one is positive
+func validateObjectiveWeights(w ObjectiveWeights) error {
+	if w.Cost < 0 || w.BatteryWear < 0 || w.SelfConsumption < 0 || w.PeakShaving < 0 {
+		return errors.New("weights must not be negative")
+	}
+	if w.Cost == 0 && w.BatteryWear == 0 && w.SelfConsumption == 0 && w.PeakShaving == 0 {
+		return errors.New("at least one weight must be positive")
+	}
+	return nil
+}
+
+// validateChp checks the efficiencies of a combined heat and power unit and that it can cover
+// the heat demand of ts
+func validateChp(chp ChpConfig, ts TimeSeries) []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if len(ts.PG) == 0 || len(ts.QH) == 0 {
+		fail("requires time_series.p_G and time_series.q_H")
+	}
+	if chp.EtaEl <= 0 || chp.EtaTh <= 0 || chp.EtaEl+chp.EtaTh > 1 {
+		fail("eta_el %v and eta_th %v must be positive and not exceed 1 in total", chp.EtaEl, chp.EtaTh)
+	}
+	if chp.EtaBoiler < 0 || chp.EtaBoiler > 1 {
+		fail("eta_boiler %v must be between 0 and 1", chp.EtaBoiler)
+	}
+	if chp.PElMin < 0 || chp.PElMin > chp.PElMax {
+		fail("p_el_min %v must be between 0 and p_el_max %v", chp.PElMin, chp.PElMax)
+	}
+	if chp.PBoilerMax < 0 || chp.PHeatElMax < 0 || chp.Cop < 0 {
+		fail("p_boiler_max, p_heat_el_max and cop must not be negative")
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// heat is neither stored nor bought, the demand must be coverable
+	cop := cmp.Or(chp.Cop, 1)
+	heat := float64(chp.PElMax/chp.EtaEl*chp.EtaTh + chp.PBoilerMax + chp.PHeatElMax*cop)
+	for t, q := range ts.QH {
+		if q < 0 {
+			fail("time_series.q_H[%d]: %v must not be negative", t, q)
+			break
+		}
+		if t < len(ts.Dt) && float64(q) > heat*float64(ts.Dt[t])/3600+1e-3 {
+			fail("time_series.q_H[%d]: %v exceeds the heat output of unit, boiler and electric heating", t, q)
+			break
+		}
+	}
+
+	return errs
+}
+
+// validateBattery checks the bounds of a battery. Initial states of charge outside s_min and
+// s_max are valid, the optimizer recovers from them.
+func validateBattery(bat BatteryConfig) []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if bat.Id == "" {
+		fail("id is required")
+	}
+	if bat.Name == "" {
+		fail("name is required")
+	}
+
+	capacity := bat.SMax
+	if bat.SCapacity != 0 {
+		capacity = bat.SCapacity
+	}
+
+	if bat.SMin < 0 || bat.SMin > bat.SMax {
+		fail("s_min %v must be between 0 and s_max %v", bat.SMin, bat.SMax)
+	}
+	if bat.SMax > capacity {
+		fail("s_max %v must not exceed s_capacity %v", bat.SMax, capacity)
+	}
+	if bat.SInitial < 0 || bat.SInitial > capacity {
+		fail("s_initial %v must be between 0 and s_capacity %v", bat.SInitial, capacity)
+	}
+	if bat.CMin < 0 || bat.CMin > bat.CMax {
+		fail("c_min %v must be between 0 and c_max %v", bat.CMin, bat.CMax)
+	}
+	if bat.DMax < 0 {
+		fail("d_max %v must not be negative", bat.DMax)
+	}
+	if bat.GoalPriority < 0 {
+		fail("goal_priority %v must not be negative", bat.GoalPriority)
+	}
+	if len(bat.CEtaSeries) > 0 && len(bat.CEtaCurve) > 0 {
+		fail("c_eta_series and c_eta_curve are mutually exclusive")
+	}
+	for t, eta := range bat.CEtaSeries {
+		if eta <= 0 {
+			fail("c_eta_series[%d]: %v must be greater than 0", t, eta)
+			break
+		}
+	}
+
+	return errs
+}
+
+// WithValidation validates optimization and simulation requests using Validate before sending
+// them. Invalid requests fail without contacting the server.
+func WithValidation() Option {
+	return func(c *config) error {
+		c.editors = append(c.editors, ValidateRequest)
+		return nil
+	}
+}
+
+// ValidateRequest is a request editor validating optimization and simulation requests, e.g. for
+// a single call of PostOptimizeChargeScheduleWithResponse. Other requests are not modified.
+func ValidateRequest(ctx context.Context, req *http.Request) error {
+	if req.Method != http.MethodPost || req.GetBody == nil || !(strings.HasSuffix(req.URL.Path, "/optimize/charge-schedule") ||
+		strings.HasSuffix(req.URL.Path, "/optimize/simulate")) {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var in OptimizationInput
+	if err := json.NewDecoder(body).Decode(&in); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	if err := Validate(in); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	return nil
+}
+
+// Normalize validates req on the server without solving it and returns the request with
+// defaults filled in and warnings on inputs that are likely not intended. Non-200 responses
+// are returned as error.
+func (c *ClientWithResponses) Normalize(ctx context.Context, req OptimizationInput, reqEditors ...RequestEditorFn) (*ValidationResult, error) {
+	resp, err := c.PostOptimizeValidateWithResponse(ctx, req, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.JSON200 != nil:
+		return resp.JSON200, nil
+	case resp.JSON400 != nil:
+		return nil, fmt.Errorf("bad request: %s %v", resp.JSON400.Message, resp.JSON400.Details)
+	default:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode())
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"time"
+
+	"github.com/evcc-io/optimizer/timeseries"
+)
+
+// WithWarmStart returns the request with the previous result prev as warm start hint, e.g. for
+// successive rolling horizon runs. The previous schedule with interval durations dt is shifted
+// by the time elapsed since its start and resampled to the intervals of the request, assuming
+// constant power within each previous interval. Intervals not covered by the previous schedule
+// are not hinted. Batteries are matched by id if given, by index otherwise.
+//
+// The hint is omitted if the previous schedule does not cover the first interval.
+func (r OptimizationInput) WithWarmStart(prev OptimizationResult, dt []int, elapsed time.Duration) OptimizationInput {
+	r.WarmStart = nil
+
+	// previous intervals relative to the start of the request
+	var start time.Time
+	prevStart := start.Add(-elapsed)
+
+	end := prevStart
+	for _, d := range dt {
+		end = end.Add(time.Duration(d) * time.Second)
+	}
+
+	// intervals of the request covered completely
+	var n int
+	for ts := start; n < len(r.TimeSeries.Dt); n++ {
+		ts = ts.Add(time.Duration(r.TimeSeries.Dt[n]) * time.Second)
+		if ts.After(end) {
+			break
+		}
+	}
+
+	if elapsed < 0 || n == 0 {
+		return r
+	}
+
+	shift := func(series []float32) []float32 {
+		if len(series) != len(dt) {
+			return nil
+		}
+
+		slots := make([]timeseries.Slot, len(dt))
+		ts := prevStart
+		for t, d := range dt {
+			te := ts.Add(time.Duration(d) * time.Second)
+			slots[t] = timeseries.Slot{Start: ts, End: te, Value: float64(series[t])}
+			ts = te
+		}
+
+		res, err := timeseries.Distribute(slots, start, r.TimeSeries.Dt[:n])
+		if err != nil {
+			return nil
+		}
+		return res
+	}
+
+	ws := WarmStart{
+		GridImport: shift(prev.GridImport),
+		GridExport: shift(prev.GridExport),
+	}
+
+	for i, bat := range r.Batteries {
+		var (
+			b  BatteryResult
+			ok bool
+		)
+		if bat.Id != "" {
+			b, ok = prev.BatteryByID(bat.Id)
+		} else if i < len(prev.Batteries) {
+			b, ok = prev.Batteries[i], true
+		}
+
+		var hint WarmStartBattery
+		if ok {
+			hint = WarmStartBattery{
+				ChargingPower:    shift(b.ChargingPower),
+				DischargingPower: shift(b.DischargingPower),
+			}
+		}
+		ws.Batteries = append(ws.Batteries, hint)
+	}
+
+	r.WarmStart = &ws
+
+	return r
+}
@@ -0,0 +1,81 @@
+// Command evopt-bench benchmarks the optimizer service with synthetic problems of a fixed size,
+// sent by concurrent workers as fast as the server answers, e.g. for sizing the gunicorn
+// workers of a deployment:
+//
+//	evopt-bench -horizon 48h -dt 15m -batteries 2 -concurrency 8 -duration 5m
+//
+// It reports latency percentiles, error and timeout rates and the throughput, see loadtest. Each
+// worker waits for its response before sending the next request, so the concurrency is the
+// number of requests in flight. evopt stress -concurrency does the same with problems of mixed
+// size.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/loadtest"
+	"github.com/evcc-io/optimizer/problem"
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/samber/lo"
+)
+
+func main() {
+	token := flag.String("token", os.Getenv("TOKEN"), "authorization token")
+	uri := flag.String("uri", lo.CoalesceOrEmpty(os.Getenv("URI"), "http://localhost:7050"), "optimizer uri")
+	horizon := flag.Duration("horizon", 24*time.Hour, "horizon of the generated problems")
+	dt := flag.Duration("dt", time.Hour, "interval duration of the generated problems")
+	batteries := flag.Int("batteries", 1, "batteries of the generated problems")
+	concurrency := flag.Int("concurrency", 4, "concurrent requests")
+	duration := flag.Duration("duration", time.Minute, "benchmark duration")
+	timeout := flag.Duration("timeout", 60*time.Second, "request timeout")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "random seed")
+	flag.Parse()
+
+	if *dt < time.Second || *dt%time.Second != 0 {
+		log.Fatal("dt must be a positive multiple of seconds")
+	}
+	steps := int(*horizon / *dt)
+	if steps < 1 {
+		log.Fatal("horizon must cover at least one interval")
+	}
+	if *batteries < 1 {
+		log.Fatal("batteries must be positive")
+	}
+	if *concurrency < 1 {
+		log.Fatal("concurrency must be positive")
+	}
+
+	c, err := client.New(*uri, client.WithTimeout(*timeout), client.WithToken(*token))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	ctx, cancel = context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	fmt.Printf("benchmarking %s with %d concurrent requests of %d intervals and %d batteries for %v (seed %d)\n",
+		*uri, *concurrency, steps, *batteries, *duration, *seed)
+
+	gen := func(r *rand.Rand) client.OptimizationInput {
+		return problem.Generate(r, steps, int(*dt/time.Second), *batteries)
+	}
+
+	start := time.Now()
+	results := loadtest.Concurrent(ctx, c, gen, *seed, *concurrency, *timeout)
+	loadtest.Report(os.Stdout, results, time.Since(start))
+
+	if loadtest.Failed(results) {
+		os.Exit(1)
+	}
+}
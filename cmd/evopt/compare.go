@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/analysis"
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/quantize"
+	"github.com/guptarohit/asciigraph"
+	"github.com/olekukonko/tablewriter"
+	"github.com/samber/lo"
+)
+
+// compare optimizes a request under multiple strategies and prints cost, grid exchange and
+// state of charge side by side.
+func compare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	cwFlag := fs.Int("cw", 150, "chart width")
+	chFlag := fs.Int("ch", 20, "chart height")
+	format := fs.String("format", "table", "output format (table, json)")
+	jsonData := fs.String("json", "", "json request")
+	strategies := fs.String("strategies", "cost,charge_before_export,self_consumption", "comma separated strategies ("+strings.Join(slices.Sorted(maps.Keys(client.Strategies)), ", ")+")")
+	token := fs.String("token", os.Getenv("TOKEN"), "authorization token")
+	uri := fs.String("uri", lo.CoalesceOrEmpty(os.Getenv("URI"), "http://localhost:7050"), "optimizer uri")
+	file := parseFileArgs(fs, args)
+
+	if *format != "table" && *format != "json" {
+		log.Fatalf("invalid format %q, expected table or json", *format)
+	}
+
+	names := strings.Split(*strategies, ",")
+	ss := make([]client.OptimizerStrategy, 0, len(names))
+	for _, name := range names {
+		s, ok := client.Strategies[name]
+		if !ok {
+			log.Fatalf("invalid strategy %q", name)
+		}
+		ss = append(ss, s)
+	}
+
+	req, err := readRequest(*jsonData, file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c, err := client.New(*uri, client.WithTimeout(10*time.Second), client.WithToken(*token))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res := c.Compare(context.TODO(), req, ss)
+
+	if *format == "json" {
+		out := make(map[string]*client.OptimizationResult, len(res))
+		for i, r := range res {
+			if r.Err != nil {
+				log.Fatalf("%s: %v", names[i], r.Err)
+			}
+			out[names[i]] = r.Result
+		}
+
+		b, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Println(string(b))
+		return
+	}
+
+	summaryTable(names, res)
+
+	// failed strategies are reported by the summary only
+	var ok []int
+	for i, r := range res {
+		if r.Err == nil && r.Result.Status == client.Optimal {
+			ok = append(ok, i)
+		}
+	}
+	if len(ok) == 0 {
+		return
+	}
+
+	exchangeTable(names, res, ok)
+
+	for j, bat := range req.Batteries {
+		div := float32(1)
+		if bat.SMax > 0 {
+			div = bat.SMax / 100
+		}
+
+		var soc [][]float64
+		for _, i := range ok {
+			soc = append(soc, toFloat64Slice(res[i].Result.Batteries[j].StateOfCharge, div))
+		}
+
+		fmt.Println(asciigraph.PlotMany(soc, asciigraph.Precision(1),
+			asciigraph.Width(*cwFlag),
+			asciigraph.Height(*chFlag/2),
+			asciigraph.Caption(fmt.Sprintf("Bat %d SoC", j+1)),
+			asciigraph.SeriesLegends(lo.Map(ok, func(i, _ int) string { return names[i] })...),
+			asciigraph.SeriesColors(lo.RepeatBy(len(ok), func(i int) asciigraph.AnsiColor {
+				return []asciigraph.AnsiColor{
+					asciigraph.Green, asciigraph.DarkOrange, asciigraph.Magenta, asciigraph.LightBlue, asciigraph.Yellow,
+				}[i%5]
+			})...),
+		))
+	}
+}
+
+// summaryTable prints cost and grid exchange per strategy
+func summaryTable(names []string, res []client.Comparison) {
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+	table.Header([]string{"Strategy", "Status", "Objective", "NetCost", "GridImport", "GridExport", "SelfConsumption"})
+
+	for i, r := range res {
+		if r.Err != nil {
+			table.Append([]string{names[i], r.Err.Error(), "", "", "", "", ""})
+			continue
+		}
+
+		row := []string{names[i], string(r.Result.Status), fmt.Sprintf("%.4f", r.Result.ObjectiveValue)}
+
+		if b, err := analysis.Analyze(r.Request, *r.Result); err == nil {
+			row = append(row,
+				fmt.Sprintf("%.4f", b.ImportCost-b.ExportRevenue),
+				fmt.Sprintf("%.0f", b.Import),
+				fmt.Sprintf("%.0f", b.Export),
+				fmt.Sprintf("%.0f%%", b.SelfConsumption*100),
+			)
+		} else {
+			row = append(row, "", "", "", "")
+		}
+
+		table.Append(row)
+	}
+
+	table.Render()
+}
+
+// exchangeTable prints grid exchange and state of charge per interval and strategy
+func exchangeTable(names []string, res []client.Comparison, ok []int) {
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+
+	headers := []string{"Hour"}
+	for _, i := range ok {
+		headers = append(headers, names[i]+" Grid")
+		for j := range res[i].Result.Batteries {
+			headers = append(headers, fmt.Sprintf("%s Bat %d Soc", names[i], j))
+		}
+	}
+	table.Header(headers)
+
+	for t := range len(res[ok[0]].Result.GridImport) {
+		row := []string{strconv.Itoa(t + 1)}
+		for _, i := range ok {
+			r := quantize.Result(*res[i].Result, 1)
+			row = append(row, str(r.GridImport[t]-r.GridExport[t]))
+			for _, b := range r.Batteries {
+				// disabled batteries have no schedule
+				row = append(row, str(lo.NthOr(b.StateOfCharge, t, 0)))
+			}
+		}
+		table.Append(row)
+	}
+
+	table.Render()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/evcc-io/optimizer/analysis"
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/explain"
+	"github.com/evcc-io/optimizer/plan"
+	"github.com/olekukonko/tablewriter"
+)
+
+// detail levels of the table format
+const (
+	detailSummary = "summary"
+	detailHourly  = "hourly"
+	detailFull    = "full"
+)
+
+// number of upcoming actions of the summary
+const summaryActions = 3
+
+// summaryOutput prints the key figures of the plan and the next battery actions with their
+// explanation
+func summaryOutput(req client.OptimizationInput, res client.OptimizationResult, start time.Time) {
+	fmt.Printf("Status: %s, objective value: %.4f\n", res.Status, res.ObjectiveValue)
+
+	if b, err := analysis.Analyze(req, res); err == nil {
+		fmt.Printf("Grid import: %.1f kWh, export: %.1f kWh\n", b.Import/1e3, b.Export/1e3)
+		fmt.Printf("Import cost: %.2f, export revenue: %.2f, savings: %.2f\n", b.ImportCost, b.ExportRevenue, b.Savings)
+		fmt.Printf("Self consumption: %.0f%%, self sufficiency: %.0f%%\n", b.SelfConsumption*100, b.SelfSufficiency*100)
+	}
+
+	windows := plan.Windows(req, res, start, 1)
+	slices.SortStableFunc(windows, func(a, b plan.Window) int { return a.Start.Compare(b.Start) })
+
+	fmt.Println()
+	if len(windows) == 0 {
+		fmt.Println("No battery actions planned")
+		return
+	}
+
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+	table.Header([]string{"Battery", "Action", "From", "To", "Energy"})
+
+	for _, w := range windows[:min(summaryActions, len(windows))] {
+		name := fmt.Sprintf("Bat %d", w.Battery)
+		if w.Battery < len(req.Batteries) {
+			name = cmp.Or(req.Batteries[w.Battery].Id, name)
+		}
+
+		table.Append([]string{
+			name,
+			string(w.Kind),
+			w.Start.Format("Mon 15:04"),
+			w.End.Format("Mon 15:04"),
+			fmt.Sprintf("%.2f kWh", w.Energy/1e3),
+		})
+	}
+
+	table.Render()
+
+	fmt.Println()
+	for _, s := range explain.Render(res, req, start)[:min(summaryActions, len(windows))] {
+		fmt.Println(s)
+	}
+}
+
+// seriesTable prints every series of the request time series and of the result, one row per
+// interval
+func seriesTable(req client.OptimizationInput, res client.OptimizationResult) {
+	n := len(req.TimeSeries.Dt)
+
+	var (
+		names  []string
+		series [][]string
+	)
+
+	// series are found by their JSON representation, covering all fields without listing them
+	add := func(prefix string, v any) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+
+		var fields map[string]any
+		if json.Unmarshal(b, &fields) != nil {
+			return
+		}
+
+		for _, key := range slices.Sorted(maps.Keys(fields)) {
+			values, ok := fields[key].([]any)
+			if !ok || len(values) != n {
+				continue
+			}
+
+			column := make([]string, n)
+			for t, v := range values {
+				switch v := v.(type) {
+				case float64:
+					column[t] = strconv.FormatFloat(v, 'g', 6, 64)
+				case bool:
+					column[t] = strconv.FormatBool(v)
+				default:
+					column[t] = fmt.Sprint(v)
+				}
+			}
+
+			names = append(names, prefix+key)
+			series = append(series, column)
+		}
+	}
+
+	add("", req.TimeSeries)
+	add("", res)
+	for i, b := range res.Batteries {
+		add(fmt.Sprintf("Bat %d ", i), b)
+	}
+	if res.Chp != nil {
+		add("Chp ", *res.Chp)
+	}
+
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+	table.Header(append([]string{"Hour"}, names...))
+
+	for t := range n {
+		row := []string{strconv.Itoa(t + 1)}
+		for _, column := range series {
+			row = append(row, column[t])
+		}
+		table.Append(row)
+	}
+
+	table.Render()
+}
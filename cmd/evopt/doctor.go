@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/crypt"
+	"github.com/samber/lo"
+)
+
+// apiVersion is the major API version this client is built for
+const apiVersion = "1"
+
+type check struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+	hint string
+}
+
+// doctor checks server reachability, API compatibility and solver availability and
+// validates an optional request file, printing actionable diagnostics.
+func doctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	token := fs.String("token", os.Getenv("TOKEN"), "authorization token")
+	uri := fs.String("uri", lo.CoalesceOrEmpty(os.Getenv("URI"), "http://localhost:7050"), "optimizer uri")
+	file := fs.String("file", "", "request file to validate")
+	_ = fs.Parse(args)
+
+	hc := &http.Client{Timeout: 10 * time.Second}
+
+	c, err := client.New(*uri, client.WithDoer(hc), client.WithToken(*token))
+	if err != nil {
+		fmt.Println("✗ invalid uri:", err)
+		os.Exit(1)
+	}
+
+	checks := []check{
+		{
+			name: "server reachable",
+			hint: "check the -uri flag or URI environment variable and that the optimizer service is running",
+			run: func(ctx context.Context) (string, error) {
+				resp, err := c.GetOptimizeHealthWithResponse(ctx)
+				if err != nil {
+					return "", err
+				}
+				if resp.StatusCode() != http.StatusOK || resp.JSON200 == nil {
+					return "", fmt.Errorf("unexpected status %d", resp.StatusCode())
+				}
+				return resp.JSON200.Status, nil
+			},
+		},
+		{
+			name: "api version compatible",
+			hint: "upgrade the client or server so both use the same major API version",
+			run: func(ctx context.Context) (string, error) {
+				version, err := serverVersion(ctx, hc, *uri)
+				if err != nil {
+					return "", err
+				}
+				if major, _, _ := strings.Cut(version, "."); major != apiVersion {
+					return "", fmt.Errorf("server api version %s, client expects %s.x", version, apiVersion)
+				}
+				return version, nil
+			},
+		},
+		{
+			name: "solver available",
+			hint: "the server could not solve a trivial problem, check the server logs for solver errors",
+			run: func(ctx context.Context) (string, error) {
+				start := time.Now()
+				res, err := c.Solve(ctx, canary())
+				if err != nil {
+					return "", err
+				}
+				if res.Status != client.Optimal {
+					return "", fmt.Errorf("canary problem status %s", res.Status)
+				}
+				return fmt.Sprintf("canary solved in %v", time.Since(start).Round(time.Millisecond)), nil
+			},
+		},
+	}
+
+	if *file != "" {
+		checks = append(checks, check{
+			name: "request file valid",
+			hint: "fix the reported problem in the request file",
+			run: func(ctx context.Context) (string, error) {
+				cph, err := crypt.FromEnv()
+				if err != nil {
+					return "", err
+				}
+				b, err := cph.ReadFile(*file)
+				if err != nil {
+					return "", err
+				}
+				var req client.OptimizationInput
+				if err := json.Unmarshal(b, &req); err != nil {
+					return "", err
+				}
+				if err := validate(req); err != nil {
+					return "", err
+				}
+				if _, err := c.Solve(ctx, req); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("%d time steps, %d batteries", len(req.TimeSeries.Dt), len(req.Batteries)), nil
+			},
+		})
+	}
+
+	var failed bool
+	for _, chk := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		msg, err := chk.run(ctx)
+		cancel()
+
+		if err != nil {
+			failed = true
+			fmt.Printf("✗ %s: %v\n  → %s\n", chk.name, err, chk.hint)
+			continue
+		}
+		fmt.Printf("✓ %s: %s\n", chk.name, msg)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// serverVersion reads the api version from the server's swagger document
+func serverVersion(ctx context.Context, hc *http.Client, uri string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(uri, "/")+"/swagger.json", nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Info struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", err
+	}
+
+	return doc.Info.Version, nil
+}
+
+// canary is a trivial problem any working solver solves instantly
+func canary() client.OptimizationInput {
+	return client.OptimizationInput{
+		Batteries: []client.BatteryConfig{{
+			Id: "canary", Name: "Canary", SMax: 1000, SInitial: 500, CMax: 1000, DMax: 1000, PA: 0.0001,
+		}},
+		TimeSeries: client.TimeSeries{
+			Dt: []int{3600, 3600},
+			Ft: []float32{500, 0},
+			Gt: []float32{0, 500},
+			PN: []float32{0.0003, 0.0003},
+			PE: []float32{0.0001, 0.0001},
+		},
+	}
+}
+
+// validate checks the request for inconsistencies the server would reject
+func validate(req client.OptimizationInput) error {
+	ts := req.TimeSeries
+	n := len(ts.Dt)
+
+	if n == 0 {
+		return fmt.Errorf("time_series.dt is empty")
+	}
+	if len(req.Batteries) == 0 {
+		return fmt.Errorf("no batteries configured")
+	}
+
+	lengths := map[string]int{"ft": len(ts.Ft), "gt": len(ts.Gt), "p_N": len(ts.PN), "p_E": len(ts.PE)}
+	for _, name := range slices.Sorted(maps.Keys(lengths)) {
+		l := lengths[name]
+		// demand and generation may be omitted for pure arbitrage
+		if l == 0 && (name == "ft" || name == "gt") {
+			continue
+		}
+		if l != n {
+			return fmt.Errorf("time_series.%s has %d values, dt has %d", name, l, n)
+		}
+	}
+
+	for i, b := range req.Batteries {
+		if b.SMin > b.SMax {
+			return fmt.Errorf("batteries.%d: s_min %.0f exceeds s_max %.0f", i, b.SMin, b.SMax)
+		}
+		if b.SGoal != nil && len(b.SGoal) != n {
+			return fmt.Errorf("batteries.%d.s_goal has %d values, dt has %d", i, len(b.SGoal), n)
+		}
+		if b.PDemand != nil && len(b.PDemand) != n {
+			return fmt.Errorf("batteries.%d.p_demand has %d values, dt has %d", i, len(b.PDemand), n)
+		}
+		if b.Available != nil && len(b.Available) != n {
+			return fmt.Errorf("batteries.%d.available has %d values, dt has %d", i, len(b.Available), n)
+		}
+	}
+
+	return nil
+}
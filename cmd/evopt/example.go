@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+
+	"gopkg.in/yaml.v3"
+)
+
+// exampleRequest is the example request of the API documentation: an EV with a charge goal and
+// a home battery over 6 hourly intervals.
+const exampleRequest = `batteries:
+  - id: ev-garage
+    name: EV
+    s_capacity: 52000
+    s_min: 5000
+    s_max: 50000
+    s_initial: 15000
+    s_goal: [0, 0, 40000, 0, 0, 0]
+    c_min: 4200
+    c_max: 11000
+    d_max: 0
+    p_a: 0.25
+  - id: lfp-garage
+    name: Home battery
+    s_min: 1000
+    s_max: 8000
+    s_initial: 5000
+    c_min: 0
+    c_max: 5000
+    d_max: 5000
+    p_a: 0.20
+time_series:
+  dt: [3600, 3600, 3600, 3600, 3600, 3600]
+  gt: [3000, 4000, 5000, 4500, 3500, 3000]
+  ft: [2000, 6000, 8000, 7000, 4000, 1000]
+  p_N: [0.30, 0.25, 0.20, 0.22, 0.28, 0.32]
+  p_E: [0.15, 0.12, 0.10, 0.11, 0.14, 0.16]
+eta_c: 0.95
+eta_d: 0.95
+M: 1000000
+`
+
+// example prints an example request as starting point for own requests
+func example(args []string) {
+	fs := flag.NewFlagSet("example", flag.ExitOnError)
+	format := fs.String("format", "json", "output format (json, yaml)")
+	_ = fs.Parse(args)
+
+	switch *format {
+	case "yaml":
+		fmt.Print(exampleRequest)
+
+	case "json":
+		var v any
+		if err := yaml.Unmarshal([]byte(exampleRequest), &v); err != nil {
+			log.Fatal(err)
+		}
+
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(b))
+
+	default:
+		log.Fatalf("invalid format %q, expected json or yaml", *format)
+	}
+}
@@ -0,0 +1,69 @@
+// Command evopt runs optimization problems against the optimizer service and renders their
+// results as tables and charts.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+const usage = `usage: evopt <command> [flags]
+
+commands:
+  run [file|scenario] [flags]   optimize a request from a JSON or YAML file, stored scenario or stdin
+  example [-format yaml]        print an example request
+  plot <result.json> [flags]    render the charts of a stored result
+  compare [file|scenario]       compare a request under multiple strategies
+  size -battery 5..20kWh [...]  sweep the capacity of a battery over stored scenarios
+  share [file|scenario] [flags] publish the plan redacted at a shareable token url
+  scenario <command>            manage stored scenarios
+  doctor [flags]                check server and request
+  stress [flags]                load test the server
+  shadow [flags]                report the savings of shadow operation
+  revisions [flags]             list the plan revisions of evoptd and why they changed
+  telemetry [flags]             summarize the statistics recorded with run -telemetry
+
+Flags of a command are listed with evopt <command> -h.`
+
+func main() {
+	args := os.Args[1:]
+
+	// flags without command run the request read from stdin
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		run(args)
+		return
+	}
+
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "run":
+		run(args)
+	case "example":
+		example(args)
+	case "plot":
+		plot(args)
+	case "compare":
+		compare(args)
+	case "size":
+		size(args)
+	case "share":
+		share(args)
+	case "scenario":
+		scenarioCmd(args)
+	case "doctor":
+		doctor(args)
+	case "stress":
+		stress(args)
+	case "shadow":
+		shadowCmd(args)
+	case "revisions":
+		revisionsCmd(args)
+	case "telemetry":
+		telemetryCmd(args)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/quantize"
+	"github.com/guptarohit/asciigraph"
+	"github.com/samber/lo"
+)
+
+// plot renders the charts of a stored result. The request is optional and adds the forecast
+// and the state of charge in percent.
+func plot(args []string) {
+	fs := flag.NewFlagSet("plot", flag.ExitOnError)
+	cwFlag := fs.Int("cw", 150, "chart width")
+	chFlag := fs.Int("ch", 20, "chart height")
+	reqFile := fs.String("request", "", "request file of the result")
+	file := parseFileArgs(fs, args)
+
+	if file == "" {
+		log.Fatal("missing result file")
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var res client.OptimizationResult
+	if err := json.Unmarshal(b, &res); err != nil {
+		log.Fatalf("invalid result: %v", err)
+	}
+
+	var req *client.OptimizationInput
+	if *reqFile != "" {
+		b, err := os.ReadFile(*reqFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		r, err := decodeRequest(b, *reqFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(r.Batteries) != len(res.Batteries) {
+			log.Fatalf("request has %d batteries, result has %d", len(r.Batteries), len(res.Batteries))
+		}
+
+		req = &r
+	}
+
+	resultTable(quantize.Result(res, 1))
+	charts(req, res, *cwFlag, *chFlag)
+
+	fmt.Printf("\nObjective value: %.4f\n", res.ObjectiveValue)
+}
+
+// charts prints the state of charge and power flow charts of a result. Without request the
+// forecast is omitted and the state of charge is shown in Wh.
+func charts(req *client.OptimizationInput, res client.OptimizationResult, width, height int) {
+	var power, soc [][]float64
+
+	power = append(power, toFloat64Slice(res.GridImport, 1))
+	power = append(power, toFloat64Slice(res.GridExport, 1))
+	powerSeries := []string{"Grid Import", "Grid Export"}
+
+	if req != nil {
+		ft := req.TimeSeries.Ft
+		if len(ft) == 0 {
+			ft = make([]float32, len(req.TimeSeries.Dt))
+		}
+
+		power = append(power, toFloat64Slice(ft, 1))
+		powerSeries = append(powerSeries, "Forecast")
+	}
+
+	var socSeries []string
+
+	for i, b := range res.Batteries {
+		powerSeries = append(powerSeries,
+			fmt.Sprintf("Bat %d Charge Power", i+1),
+			fmt.Sprintf("Bat %d Discharge Power", i+1),
+		)
+		socSeries = append(socSeries, fmt.Sprintf("Bat %d SoC", i+1))
+
+		div := float32(1)
+		if req != nil && req.Batteries[i].SMax > 0 {
+			div = req.Batteries[i].SMax / 100
+		}
+
+		power = append(power, toFloat64Slice(b.ChargingPower, 1))
+		power = append(power, toFloat64Slice(b.DischargingPower, 1))
+		soc = append(soc, toFloat64Slice(b.StateOfCharge, div))
+	}
+
+	if len(soc) > 0 {
+		fmt.Println(asciigraph.PlotMany(soc, asciigraph.Precision(1),
+			asciigraph.Width(width),
+			asciigraph.Height(height/2),
+			asciigraph.Caption("Optimization - SoC"),
+			asciigraph.SeriesLegends(socSeries...),
+			asciigraph.SeriesColors(lo.RepeatBy(len(socSeries), func(i int) asciigraph.AnsiColor {
+				switch i % 3 {
+				case 0:
+					return asciigraph.Green
+				case 1:
+					return asciigraph.DarkOrange
+				default:
+					return asciigraph.Magenta
+				}
+			})...),
+		))
+	}
+
+	colors := []asciigraph.AnsiColor{asciigraph.LightBlue, asciigraph.Blue}
+	if req != nil {
+		colors = append(colors, asciigraph.Yellow)
+	}
+	colors = append(colors,
+		asciigraph.Green, asciigraph.DarkGreen,
+		asciigraph.DarkOrange, asciigraph.DarkRed,
+		asciigraph.Magenta, asciigraph.DarkMagenta,
+	)
+
+	fmt.Println(asciigraph.PlotMany(power, asciigraph.Precision(0),
+		asciigraph.Width(width),
+		asciigraph.Height(height),
+		asciigraph.Caption("Optimization - Power Flow"),
+		asciigraph.SeriesLegends(powerSeries...),
+		asciigraph.SeriesColors(lo.RepeatBy(len(powerSeries), func(i int) asciigraph.AnsiColor {
+			if i < len(colors) {
+				return colors[i]
+			}
+			return asciigraph.White
+		})...),
+	))
+}
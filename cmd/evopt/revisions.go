@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/changelog"
+	"github.com/evcc-io/optimizer/units"
+	"github.com/olekukonko/tablewriter"
+	"github.com/samber/lo"
+)
+
+// revisionsCmd lists the plan revisions recorded by evoptd with their trigger and the change of
+// the key figures over the period shared with the previous plan.
+func revisionsCmd(args []string) {
+	fs := flag.NewFlagSet("revisions", flag.ExitOnError)
+	daemon := fs.String("daemon", lo.CoalesceOrEmpty(os.Getenv("EVOPTD_URL"), "http://localhost:8080"), "evoptd uri")
+	since := fs.Duration("since", 24*time.Hour, "list the revisions of the given period")
+	_ = fs.Parse(args)
+
+	u := strings.TrimSuffix(*daemon, "/") + "/revisions?since=" + url.QueryEscape(time.Now().Add(-*since).Format(time.RFC3339))
+
+	resp, err := http.Get(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+
+	var revs []changelog.Revision
+	if err := json.NewDecoder(resp.Body).Decode(&revs); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(revs) == 0 {
+		fmt.Println("no revisions recorded")
+		return
+	}
+
+	delta := func(rev changelog.Revision, v func(changelog.Figures) float64, format func(float64) string) string {
+		if rev.Delta == nil {
+			return format(v(rev.Figures))
+		}
+		d := format(v(*rev.Delta))
+		if !strings.HasPrefix(d, "-") {
+			d = "+" + d
+		}
+		return fmt.Sprintf("%s (%s)", format(v(rev.Figures)), d)
+	}
+	kwh := func(v float64) string { return strconv.FormatFloat(units.Energy(v).KWh(), 'f', 1, 64) }
+	cost := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+	table.Header([]string{"Time", "Trigger", "Reason", "Until", "Import kWh", "Charge kWh", "Cost", "Savings"})
+	for _, rev := range revs {
+		table.Append([]string{
+			rev.Time.Local().Format(time.DateTime),
+			string(rev.Trigger),
+			rev.Reason,
+			rev.Until.Local().Format("15:04"),
+			delta(rev, func(f changelog.Figures) float64 { return f.Import }, kwh),
+			delta(rev, func(f changelog.Figures) float64 { return f.Charge }, kwh),
+			delta(rev, func(f changelog.Figures) float64 { return f.Cost }, cost),
+			delta(rev, func(f changelog.Figures) float64 { return f.Savings }, cost),
+		})
+	}
+	table.Render()
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/analysis"
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/crypt"
+	"github.com/evcc-io/optimizer/plan"
+	"github.com/evcc-io/optimizer/quantize"
+	"github.com/evcc-io/optimizer/units"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// run optimizes a request read from a file, a stored scenario or stdin and prints the result
+// as tables and charts, JSON, CSV, setpoint schedule or iCal.
+func run(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	vFlag := fs.Bool("v", false, "verbose output")
+	cwFlag := fs.Int("cw", 150, "chart width")
+	chFlag := fs.Int("ch", 20, "chart height")
+	format := fs.String("format", "table", "output format (table, "+strings.Join(plan.Formats(), ", ")+")")
+	detail := fs.String("detail", detailHourly, "detail of the table format: summary prints key figures and the next actions, hourly the tables and charts, full adds every series")
+	jsonData := fs.String("json", "", "json request")
+	icalFile := fs.String("ical", "", "write charge and discharge windows of the next 7 days to iCal file")
+	explainFlag := fs.Bool("explain", false, "print binding limits and strategy violations per interval")
+	flexFlag := fs.Duration("flex", 0, "print the up and down flexibility per interval sustainable for the duration, e.g. 1h")
+	latencyFlag := fs.String("latency", "", "actuation latency per battery shifting the schedule format earlier, e.g. 30s,2m")
+	postMortem := fs.String("postmortem", os.Getenv("EVOPT_POSTMORTEM"), "directory to save a post-mortem bundle of failed requests to for issue reports")
+	telemetryFlag := fs.Bool("telemetry", false, "record anonymized problem statistics, submitted to EVOPT_TELEMETRY_URL if set, see evopt telemetry")
+	token := fs.String("token", os.Getenv("TOKEN"), "authorization token")
+	uri := fs.String("uri", lo.CoalesceOrEmpty(os.Getenv("URI"), "http://localhost:7050"), "optimizer uri")
+	file := parseFileArgs(fs, args)
+
+	enc, ok := plan.Lookup(*format)
+	if !ok && *format != "table" {
+		log.Fatalf("invalid format %q, expected table, %s", *format, strings.Join(plan.Formats(), ", "))
+	}
+
+	switch *detail {
+	case detailSummary, detailHourly, detailFull:
+	default:
+		log.Fatalf("invalid detail %q, expected summary, hourly or full", *detail)
+	}
+
+	latency, err := parseLatency(*latencyFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	req, err := readRequest(*jsonData, file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := []client.Option{client.WithTimeout(10 * time.Second), client.WithToken(*token)}
+	if *telemetryFlag {
+		opt, flush := withTelemetry()
+		opts = append(opts, opt)
+		defer flush()
+	}
+	if *postMortem != "" {
+		cph, err := crypt.FromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, client.WithPostMortem(*postMortem, cph, func(path string, err error) {
+			if err != nil {
+				log.Printf("saving post-mortem bundle: %v", err)
+				return
+			}
+			fmt.Fprintln(os.Stderr, "post-mortem bundle saved to", path)
+		}))
+	}
+
+	c, err := client.New(*uri, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// demand and generation may be omitted for pure arbitrage
+	ft, gt := req.TimeSeries.Ft, req.TimeSeries.Gt
+	if len(ft) == 0 {
+		ft = make([]float32, len(req.TimeSeries.Dt))
+	}
+	if len(gt) == 0 {
+		gt = make([]float32, len(req.TimeSeries.Dt))
+	}
+
+	if *format == "table" && *detail != detailSummary {
+		inputTable(req, ft, gt)
+	}
+
+	resp, err := c.PostOptimizeChargeScheduleWithResponse(context.TODO(), req)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if resp.StatusCode() == http.StatusInternalServerError {
+		log.Fatalf("Expected HTTP 200 but received %d\n%s", resp.StatusCode(), resp.JSON500.Message)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		log.Fatalf("Expected HTTP 200 but received %d\n%s", resp.StatusCode(), string(resp.Body))
+	}
+
+	res := *resp.JSON200
+
+	// the plan starts with the current interval
+	var start time.Time
+	if len(req.TimeSeries.Dt) > 0 {
+		start = time.Now().Truncate(time.Duration(req.TimeSeries.Dt[0]) * time.Second)
+	}
+
+	if enc != nil {
+		e := plan.Export{Request: req, Result: res, Start: start, Names: batteryNames(req), Latency: latency}
+		if err := enc.Encode(os.Stdout, e); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *vFlag {
+		b, _ := json.MarshalIndent(res, "", "  ")
+		fmt.Println(string(b))
+	}
+
+	if res.Status != "Optimal" {
+		log.Fatal("Optimization failed:", string(res.Status))
+	}
+
+	if *icalFile != "" && len(req.TimeSeries.Dt) > 0 {
+		windows := plan.Within(plan.Windows(req, res, start, 1), start, 7*24*time.Hour)
+
+		f, err := os.Create(*icalFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := plan.WriteICal(f, windows, batteryNames(req)); err != nil {
+			log.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *detail == detailSummary {
+		summaryOutput(req, res, start)
+	} else {
+		resultTable(quantize.Result(res, 1))
+		if *detail == detailFull {
+			seriesTable(req, res)
+		}
+		charts(&req, res, *cwFlag, *chFlag)
+
+		fmt.Printf("\nObjective value: %.4f\n", res.ObjectiveValue)
+
+		if b, err := analysis.Analyze(req, res); err == nil {
+			fmt.Printf("Import cost: %.4f, export revenue: %.4f, stored value: %.4f\n", b.ImportCost, b.ExportRevenue, b.StoredValue)
+			fmt.Printf("Without batteries: %.4f, savings: %.4f\n", b.Baseline, b.Savings)
+			fmt.Printf("Self consumption: %.0f%%, self sufficiency: %.0f%%\n", b.SelfConsumption*100, b.SelfSufficiency*100)
+		}
+	}
+
+	shortfalls(req, res)
+
+	if *explainFlag {
+		e, err := analysis.Explain(req, res)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print("\n", e)
+	}
+
+	if *flexFlag > 0 {
+		offers, err := analysis.Flexibility(req, res, *flexFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println()
+		flexTable(offers)
+	}
+}
+
+// shortfalls prints the charge goals scaled back by the optimizer
+func shortfalls(req client.OptimizationInput, res client.OptimizationResult) {
+	names := batteryNames(req)
+	name := func(i int) string {
+		if i < len(names) && names[i] != "" {
+			return names[i]
+		}
+		return fmt.Sprintf("Bat %d", i)
+	}
+
+	for i, b := range res.Batteries {
+		for _, s := range b.GoalShortfalls {
+			fmt.Printf("Goal of %s at hour %d scaled back by %.1f kWh to %.1f kWh: %s", name(i), s.T+1,
+				s.Shortfall/1e3, (s.Goal-s.Shortfall)/1e3, s.Reason)
+			if len(s.Competitors) > 0 {
+				fmt.Printf(", charging %s instead", strings.Join(lo.Map(s.Competitors, func(j, _ int) string { return name(j) }), ", "))
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// flexTable prints the flexibility offers, one row per interval
+func flexTable(offers []analysis.Offer) {
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+	table.Header([]string{"Hour", "Start", "Duration", "Up kW", "Down kW"})
+
+	for k := 0; k+1 < len(offers); k += 2 {
+		up, down := offers[k], offers[k+1]
+		table.Append([]string{
+			strconv.Itoa(up.Interval + 1),
+			up.Start.String(),
+			up.Duration.String(),
+			str2(float32(up.Power.KW())),
+			str2(float32(down.Power.KW())),
+		})
+	}
+
+	table.Render()
+}
+
+// parseFileArgs parses flags before and after an optional file argument
+func parseFileArgs(fs *flag.FlagSet, args []string) string {
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		return ""
+	}
+
+	file := fs.Arg(0)
+	_ = fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 {
+		log.Fatalf("unexpected arguments %v", fs.Args())
+	}
+
+	return file
+}
+
+// readRequest reads a request given as JSON, from a file or stored scenario or from stdin
+func readRequest(jsonData, file string) (client.OptimizationInput, error) {
+	var (
+		data []byte
+		name = file
+		err  error
+	)
+
+	switch {
+	case jsonData != "":
+		data, name = []byte(jsonData), ""
+	case file != "":
+		data, err = readScenarioFile(file)
+	default:
+		if fi, _ := os.Stdin.Stat(); fi.Mode()&os.ModeNamedPipe != 0 || fi.Mode()&os.ModeCharDevice == 0 {
+			data, err = io.ReadAll(os.Stdin)
+		}
+	}
+	if err != nil {
+		return client.OptimizationInput{}, err
+	}
+	if len(data) == 0 {
+		return client.OptimizationInput{}, errors.New("missing request, use a file, a scenario, -json or stdin")
+	}
+
+	return decodeRequest(data, name)
+}
+
+// readScenarioFile reads a request file, or the request of a stored scenario by name
+func readScenarioFile(file string) ([]byte, error) {
+	cph, err := crypt.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(file); err == nil || !scenarioName.MatchString(file) {
+		return cph.ReadFile(file)
+	}
+
+	sc, err := loadScenario(cph, scenarioDir(), file)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(sc.Request)
+}
+
+// decodeRequest decodes a JSON or YAML request, YAML is detected by the file extension or if
+// the data is no JSON. Exported scenarios are unwrapped.
+func decodeRequest(data []byte, name string) (client.OptimizationInput, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".yaml" || ext == ".yml" || !json.Valid(data) {
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return client.OptimizationInput{}, fmt.Errorf("invalid request: %w", err)
+		}
+
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return client.OptimizationInput{}, fmt.Errorf("invalid request: %w", err)
+		}
+	}
+
+	var wrapped struct {
+		Request json.RawMessage `json:"request"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil && len(wrapped.Request) > 0 {
+		data = wrapped.Request
+	}
+
+	var req client.OptimizationInput
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&req); err != nil {
+		return req, fmt.Errorf("invalid request: %w", err)
+	}
+
+	return req, nil
+}
+
+// tableConfig aligns table cells to the right
+var tableConfig = tablewriter.WithConfig(tablewriter.Config{
+	Row: tw.CellConfig{
+		Alignment: tw.CellAlignment{Global: tw.AlignRight},
+	},
+})
+
+// inputTable prints forecasts, prices and goals
+func inputTable(req client.OptimizationInput, ft, gt []float32) {
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+	headers := []string{"Hour", "Forecast", "TotalDemand", "GridImportCost", "GridExportCost"}
+
+	for i, bat := range req.Batteries {
+		if bat.SGoal != nil && lo.Sum(bat.SGoal) > 0 {
+			headers = append(headers,
+				fmt.Sprintf("Bat %d Goal", i),
+			)
+		}
+	}
+
+	table.Header(headers)
+
+	for t := range len(ft) {
+		row := []string{
+			strconv.Itoa(t + 1),
+			str(ft[t]),
+			str(gt[t]),
+			str2(float32(units.Price(req.TimeSeries.PN[t]).PerKWh())),
+			str2(float32(units.Price(req.TimeSeries.PE[t]).PerKWh())),
+		}
+
+		for _, bat := range req.Batteries {
+			if bat.SGoal != nil && lo.Sum(bat.SGoal) > 0 {
+				row = append(row, str((bat.SGoal)[t]))
+			}
+		}
+
+		table.Append(row)
+	}
+
+	table.Render()
+}
+
+// resultTable prints grid exchange and battery schedules
+func resultTable(res client.OptimizationResult) {
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+	headers := []string{
+		"Hour",
+		"GridImport", "GridExport",
+	}
+
+	for i := range res.Batteries {
+		headers = append(headers,
+			fmt.Sprintf("Bat %d Cha", i), // ChargingPower
+			fmt.Sprintf("Bat %d Dis", i), // DischargingPower
+			fmt.Sprintf("Bat %d Soc", i),
+		)
+	}
+
+	table.Header(headers)
+
+	for t := range len(res.FlowDirection) {
+		row := []string{
+			strconv.Itoa(t + 1),
+			str((res.GridImport)[t]),
+			str((res.GridExport)[t]),
+		}
+
+		for _, b := range res.Batteries {
+			row = append(row,
+				str((b.ChargingPower)[t]),
+				str((b.DischargingPower)[t]),
+				str((b.StateOfCharge)[t]),
+			)
+		}
+
+		table.Append(row)
+	}
+
+	table.Render()
+}
+
+// batteryNames names the batteries by id
+func batteryNames(req client.OptimizationInput) plan.Names {
+	names := make(plan.Names, len(req.Batteries))
+	for i, bat := range req.Batteries {
+		names[i] = bat.Id
+	}
+	return names
+}
+
+// parseLatency parses comma-separated actuation latencies per battery
+func parseLatency(s string) ([]time.Duration, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var res []time.Duration
+	for _, v := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid latency %q", v)
+		}
+		res = append(res, d)
+	}
+
+	return res, nil
+}
+
+func str(f float32) string {
+	if f == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f", f)
+}
+
+func str2(f float32) string {
+	if f == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", f)
+}
+
+// toFloat64Slice converts a slice of float32 to a slice of float64.
+func toFloat64Slice(in []float32, div float32) []float64 {
+	out := make([]float64, len(in))
+	for i, v := range in {
+		out[i] = float64(v / div)
+	}
+	return out
+}
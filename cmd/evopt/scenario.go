@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/crypt"
+)
+
+// scenario is a named problem snapshot
+type scenario struct {
+	Name    string                   `json:"name"`
+	Created time.Time                `json:"created"`
+	Note    string                   `json:"note,omitempty"`
+	Request client.OptimizationInput `json:"request"`
+}
+
+var scenarioName = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+const scenarioUsage = `usage: scenario <command> [flags]
+
+commands:
+  save <name> [-file request.json] [-note text]   store a problem read from file or stdin
+  load <name|file>                                 print the problem request to stdout
+  list                                             list stored scenarios
+  export <name> [-o file]                          write a single shareable file`
+
+// scenarioCmd manages a local library of named problem snapshots. Scenarios are stored in
+// the user config directory or EVOPT_SCENARIOS and encrypted if EVOPT_KEY is set.
+func scenarioCmd(args []string) {
+	if len(args) == 0 {
+		log.Fatal(scenarioUsage)
+	}
+
+	dir := scenarioDir()
+
+	cph, err := crypt.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case "save":
+		fs := flag.NewFlagSet("save", flag.ExitOnError)
+		file := fs.String("file", "", "request file, defaults to stdin")
+		note := fs.String("note", "", "description of the scenario")
+		name := parseScenarioArgs(fs, args)
+
+		var b []byte
+		if *file != "" {
+			b, err = cph.ReadFile(*file)
+		} else {
+			b, err = io.ReadAll(os.Stdin)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		sc := scenario{Name: name, Created: time.Now().UTC(), Note: *note}
+		if err := json.Unmarshal(b, &sc.Request); err != nil {
+			log.Fatal(err)
+		}
+		if err := validate(sc.Request); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			log.Fatal(err)
+		}
+
+		b, err = json.MarshalIndent(sc, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := cph.WriteFile(filepath.Join(dir, name+".json"), b); err != nil {
+			log.Fatal(err)
+		}
+
+	case "load":
+		if len(args) != 1 {
+			log.Fatal(scenarioUsage)
+		}
+
+		sc, err := loadScenario(cph, dir, args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		b, _ := json.MarshalIndent(sc.Request, "", "  ")
+		fmt.Println(string(b))
+
+	case "list":
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		slices.Sort(files)
+
+		for _, file := range files {
+			sc, err := loadScenario(cph, dir, file)
+			if err != nil {
+				fmt.Printf("%-30s error: %v\n", filepath.Base(file), err)
+				continue
+			}
+			fmt.Printf("%-30s %s  %3d steps  %d batteries  %s\n", sc.Name, sc.Created.Local().Format(time.DateTime),
+				len(sc.Request.TimeSeries.Dt), len(sc.Request.Batteries), sc.Note)
+		}
+
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		out := fs.String("o", "", "output file, defaults to <name>.evopt.json")
+		name := parseScenarioArgs(fs, args)
+
+		sc, err := loadScenario(cph, dir, name)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if *out == "" {
+			*out = name + ".evopt.json"
+		}
+
+		// exports are meant for sharing and therefore never encrypted
+		b, _ := json.MarshalIndent(sc, "", "  ")
+		if err := os.WriteFile(*out, append(b, '\n'), 0o644); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(*out)
+
+	default:
+		log.Fatal(scenarioUsage)
+	}
+}
+
+// parseScenarioArgs parses flags following the scenario name
+func parseScenarioArgs(fs *flag.FlagSet, args []string) string {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		log.Fatal(scenarioUsage)
+	}
+
+	name := args[0]
+	if !scenarioName.MatchString(name) {
+		log.Fatalf("invalid scenario name %q", name)
+	}
+
+	_ = fs.Parse(args[1:])
+
+	return name
+}
+
+// scenarioDir returns the directory of stored scenarios
+func scenarioDir() string {
+	if dir := os.Getenv("EVOPT_SCENARIOS"); dir != "" {
+		return dir
+	}
+
+	cfg, err := os.UserConfigDir()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return filepath.Join(cfg, "evopt", "scenarios")
+}
+
+// loadScenario loads a stored scenario by name, or an exported scenario file by path
+func loadScenario(cph *crypt.Cipher, dir, name string) (scenario, error) {
+	file := name
+	if scenarioName.MatchString(name) && !strings.HasSuffix(name, ".json") {
+		file = filepath.Join(dir, name+".json")
+	}
+
+	b, err := cph.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return scenario{}, fmt.Errorf("scenario %s not found", name)
+	}
+	if err != nil {
+		return scenario{}, err
+	}
+
+	var sc scenario
+	err = json.Unmarshal(b, &sc)
+
+	return sc, err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/evcc-io/optimizer/crypt"
+	"github.com/evcc-io/optimizer/shadow"
+	"github.com/evcc-io/optimizer/storage"
+	"github.com/olekukonko/tablewriter"
+)
+
+// shadowCmd reports the hypothetical savings of the shadow operation recorded in a database.
+func shadowCmd(args []string) {
+	fs := flag.NewFlagSet("shadow", flag.ExitOnError)
+	db := fs.String("db", "optimizer.db", "database of the shadow operation, a bbolt file locked while it runs, sqlite:path or a postgres:// URL")
+	since := fs.Duration("since", 0, "only report the given period, e.g. 168h")
+	_ = fs.Parse(args)
+
+	backend, err := storage.Open(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer backend.Close()
+
+	var from time.Time
+	if *since > 0 {
+		from = time.Now().Add(-*since)
+	}
+
+	cph, err := crypt.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rep, err := shadow.New(backend, nil, shadow.WithCipher(cph)).Report(context.Background(), from, time.Local)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(rep.Days) == 0 {
+		fmt.Println("no intervals recorded")
+		return
+	}
+
+	cost := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+	table.Header([]string{"Day", "Intervals", "Cost", "Shadow cost", "Savings"})
+	for _, day := range rep.Days {
+		table.Append([]string{day.Date.Format(time.DateOnly), strconv.Itoa(day.Intervals), cost(day.Cost), cost(day.ShadowCost), cost(day.Savings())})
+	}
+	table.Footer([]string{"Total", strconv.Itoa(rep.Total.Intervals), cost(rep.Total.Cost), cost(rep.Total.ShadowCost), cost(rep.Total.Savings())})
+	table.Render()
+}
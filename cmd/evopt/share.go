@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/handlers"
+	"github.com/samber/lo"
+)
+
+// share optimizes a request and publishes the plan redacted for public sharing at a token url,
+// or writes the redacted plan to a file. Consumption, grid exchange, costs and battery sizes
+// are not shared, see handlers.Redact.
+func share(args []string) {
+	fs := flag.NewFlagSet("share", flag.ExitOnError)
+	jsonData := fs.String("json", "", "json request")
+	listen := fs.String("listen", ":7060", "address to serve the shared plan on")
+	public := fs.String("public", "", "public base url of the listen address for the printed link, e.g. https://example.org")
+	output := fs.String("o", "", "write the redacted plan to file instead of serving it")
+	token := fs.String("token", os.Getenv("TOKEN"), "authorization token")
+	uri := fs.String("uri", lo.CoalesceOrEmpty(os.Getenv("URI"), "http://localhost:7050"), "optimizer uri")
+	file := parseFileArgs(fs, args)
+
+	req, err := readRequest(*jsonData, file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c, err := client.New(*uri, client.WithTimeout(time.Minute), client.WithToken(*token))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res, err := c.Solve(context.TODO(), req)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the plan starts with the current interval
+	var start time.Time
+	if len(req.TimeSeries.Dt) > 0 {
+		start = time.Now().Truncate(time.Duration(req.TimeSeries.Dt[0]) * time.Second)
+	}
+
+	p := handlers.Plan{Start: start, Request: req, Result: *res}
+
+	if *output != "" {
+		b, err := json.MarshalIndent(handlers.Redact(p), "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*output, append(b, '\n'), 0o644); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	store := new(handlers.Store)
+	if err := store.Set(p); err != nil {
+		log.Fatal(err)
+	}
+
+	secret, err := handlers.NewShareToken()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	base := *public
+	if base == "" {
+		host, port, _ := net.SplitHostPort(ln.Addr().String())
+		if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+			host = "localhost"
+		}
+		base = "http://" + net.JoinHostPort(host, port)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/share/", handlers.Share(store, secret))
+
+	fmt.Printf("sharing plan at %s/share/%s, stop with Ctrl-C\n", strings.TrimSuffix(base, "/"), secret)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/analysis"
+	"github.com/evcc-io/optimizer/client"
+	"github.com/guptarohit/asciigraph"
+	"github.com/olekukonko/tablewriter"
+	"github.com/samber/lo"
+)
+
+// sizePoint is the outcome of a battery size over all problems of a sweep
+type sizePoint struct {
+	Size    float64 `json:"size"`              // usable capacity [kWh]
+	Cost    float64 `json:"cost"`              // net cost of all problems [currency unit]
+	Savings float64 `json:"savings"`           // over the problems without the battery [currency unit]
+	Annual  float64 `json:"annual_savings"`    // savings extrapolated to a year [currency unit]
+	Invest  float64 `json:"investment"`        // [currency unit]
+	Payback float64 `json:"payback,omitempty"` // [years], zero without investment or savings
+}
+
+// size sweeps the capacity of a battery over stored problems and prints the cost per size with
+// payback estimates. Problems are stored scenarios or files, all stored scenarios by default.
+func size(args []string) {
+	fs := flag.NewFlagSet("size", flag.ExitOnError)
+	cwFlag := fs.Int("cw", 150, "chart width")
+	chFlag := fs.Int("ch", 20, "chart height")
+	format := fs.String("format", "table", "output format (table, json)")
+	battery := fs.String("battery", "", "capacity range of the battery, e.g. 5..20kWh")
+	step := fs.String("step", "1kWh", "capacity step, e.g. 2.5kWh")
+	index := fs.String("bat", "0", "battery to size by index or id")
+	scalePower := fs.Bool("scale-power", false, "scale charge and discharge power with the capacity, keeping the C-rate")
+	price := fs.Float64("price", 0, "investment per kWh of capacity for the payback estimate")
+	token := fs.String("token", os.Getenv("TOKEN"), "authorization token")
+	uri := fs.String("uri", lo.CoalesceOrEmpty(os.Getenv("URI"), "http://localhost:7050"), "optimizer uri")
+	_ = fs.Parse(args)
+
+	if *format != "table" && *format != "json" {
+		log.Fatalf("invalid format %q, expected table or json", *format)
+	}
+
+	from, to, err := parseCapacityRange(*battery)
+	if err != nil {
+		log.Fatal(err)
+	}
+	inc, err := parseCapacity(*step)
+	if err != nil || inc <= 0 {
+		log.Fatalf("invalid step %q", *step)
+	}
+
+	names, reqs, err := sizeProblems(fs.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bat := make([]int, len(reqs))
+	var hours float64
+	for k, req := range reqs {
+		if bat[k], err = batteryIndex(req, *index); err != nil {
+			log.Fatalf("%s: %v", names[k], err)
+		}
+		hours += float64(lo.Sum(req.TimeSeries.Dt)) / 3600
+	}
+
+	c, err := client.New(*uri, client.WithTimeout(time.Minute), client.WithToken(*token))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// the cost of all problems with the battery sized by fn
+	cost := func(label string, fn func(*client.BatteryConfig)) float64 {
+		sized := make([]client.OptimizationInput, len(reqs))
+		for k, req := range reqs {
+			req.Batteries = slices.Clone(req.Batteries)
+			fn(&req.Batteries[bat[k]])
+			sized[k] = req
+		}
+
+		var total float64
+		for k, r := range c.OptimizeBatch(context.TODO(), sized) {
+			if r.Err == nil && r.Result.Status != client.Optimal {
+				r.Err = fmt.Errorf("status %s", r.Result.Status)
+			}
+			if r.Err != nil {
+				log.Fatalf("%s %s: %v", names[k], label, r.Err)
+			}
+
+			b, err := analysis.Analyze(sized[k], *r.Result)
+			if err != nil {
+				log.Fatalf("%s %s: %v", names[k], label, err)
+			}
+			total -= b.Objective
+		}
+
+		return total
+	}
+
+	reference := cost("without battery", func(bat *client.BatteryConfig) { bat.Enabled = lo.ToPtr(false) })
+
+	var points []sizePoint
+	for n := 0; ; n++ {
+		kwh := from + float64(n)*inc
+		if kwh > to+1e-9 {
+			break
+		}
+
+		p := sizePoint{Size: kwh, Invest: kwh * *price}
+		p.Cost = cost(fmt.Sprintf("at %g kWh", kwh), func(bat *client.BatteryConfig) { resize(bat, kwh*1e3, *scalePower) })
+		p.Savings = reference - p.Cost
+		if hours > 0 {
+			p.Annual = p.Savings * 365 * 24 / hours
+		}
+		if p.Invest > 0 && p.Annual > 0 {
+			p.Payback = p.Invest / p.Annual
+		}
+
+		points = append(points, p)
+	}
+
+	if *format == "json" {
+		b, _ := json.MarshalIndent(points, "", "  ")
+		fmt.Println(string(b))
+		return
+	}
+
+	fmt.Printf("%d problems over %.0f hours, cost without battery: %.2f\n\n", len(reqs), hours, reference)
+
+	money := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+	table.Header([]string{"Capacity", "Cost", "Savings", "Per year", "Investment", "Payback years"})
+	for _, p := range points {
+		payback := "-"
+		if p.Payback > 0 {
+			payback = strconv.FormatFloat(p.Payback, 'f', 1, 64)
+		}
+		table.Append([]string{
+			fmt.Sprintf("%g kWh", p.Size), money(p.Cost), money(p.Savings), money(p.Annual), money(p.Invest), payback,
+		})
+	}
+	table.Render()
+
+	if len(points) > 1 {
+		fmt.Println(asciigraph.Plot(lo.Map(points, func(p sizePoint, _ int) float64 { return p.Cost }),
+			asciigraph.Precision(2),
+			asciigraph.Width(*cwFlag),
+			asciigraph.Height(*chFlag/2),
+			asciigraph.Caption(fmt.Sprintf("Cost from %g to %g kWh", from, to)),
+		))
+	}
+}
+
+// resize sets the usable capacity of bat to capacity Wh, scaling the states of charge and
+// goals, and the power limits if scalePower is set
+func resize(bat *client.BatteryConfig, capacity float64, scalePower bool) {
+	f := float32(1)
+	if bat.SMax > 0 {
+		f = float32(capacity) / bat.SMax
+	}
+
+	bat.SMax = float32(capacity)
+	bat.SMin *= f
+	bat.SInitial *= f
+	bat.SCapacity *= f
+	bat.SGoal = lo.Map(bat.SGoal, func(s float32, _ int) float32 { return s * f })
+
+	if scalePower {
+		bat.CMin *= f
+		bat.CMax *= f
+		bat.DMax *= f
+	}
+}
+
+// batteryIndex returns the index of the battery given by index or id
+func batteryIndex(req client.OptimizationInput, s string) (int, error) {
+	if i, err := strconv.Atoi(s); err == nil {
+		if i < 0 || i >= len(req.Batteries) {
+			return 0, fmt.Errorf("battery %d not found", i)
+		}
+		return i, nil
+	}
+
+	if i := slices.IndexFunc(req.Batteries, func(bat client.BatteryConfig) bool { return bat.Id == s }); i >= 0 {
+		return i, nil
+	}
+
+	return 0, fmt.Errorf("battery %q not found", s)
+}
+
+// sizeProblems reads the problems given as stored scenario names or files, or all stored
+// scenarios if none are given
+func sizeProblems(args []string) ([]string, []client.OptimizationInput, error) {
+	if len(args) == 0 {
+		files, err := filepath.Glob(filepath.Join(scenarioDir(), "*.json"))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(files) == 0 {
+			return nil, nil, fmt.Errorf("no stored scenarios in %s", scenarioDir())
+		}
+		slices.Sort(files)
+		args = files
+	}
+
+	names := make([]string, 0, len(args))
+	reqs := make([]client.OptimizationInput, 0, len(args))
+	for _, arg := range args {
+		// stored scenarios are unwrapped by decodeRequest like exported ones
+		b, err := readScenarioFile(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		req, err := decodeRequest(b, arg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", arg, err)
+		}
+
+		names = append(names, strings.TrimSuffix(filepath.Base(arg), ".json"))
+		reqs = append(reqs, req)
+	}
+
+	return names, reqs, nil
+}
+
+// parseCapacityRange parses a capacity range like 5..20kWh, the unit applies to both bounds
+func parseCapacityRange(s string) (float64, float64, error) {
+	lower, upper, ok := strings.Cut(s, "..")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid capacity range %q, expected e.g. 5..20kWh", s)
+	}
+
+	unit := strings.TrimLeft(upper, "0123456789.")
+	from, err := parseCapacity(strings.TrimSuffix(lower, unit) + unit)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid capacity range %q: %w", s, err)
+	}
+	to, err := parseCapacity(upper)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid capacity range %q: %w", s, err)
+	}
+	if from < 0 || to < from {
+		return 0, 0, fmt.Errorf("invalid capacity range %q", s)
+	}
+
+	return from, to, nil
+}
+
+// parseCapacity parses a capacity in kWh with an optional unit kWh or Wh
+func parseCapacity(s string) (float64, error) {
+	div := 1.0
+	switch {
+	case strings.HasSuffix(strings.ToLower(s), "kwh"):
+		s = s[:len(s)-3]
+	case strings.HasSuffix(strings.ToLower(s), "wh"):
+		s, div = s[:len(s)-2], 1e3
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid capacity %q", s)
+	}
+
+	return v / div, nil
+}
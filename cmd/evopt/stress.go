@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/loadtest"
+	"github.com/evcc-io/optimizer/problem"
+	"github.com/samber/lo"
+)
+
+// stress sends randomized problems at a fixed rate, or by concurrent workers as fast as the
+// server answers, and reports latency percentiles, error classes and malformed responses.
+func stress(args []string) {
+	fs := flag.NewFlagSet("stress", flag.ExitOnError)
+	token := fs.String("token", os.Getenv("TOKEN"), "authorization token")
+	uri := fs.String("uri", lo.CoalesceOrEmpty(os.Getenv("URI"), "http://localhost:7050"), "optimizer uri")
+	rps := fs.Float64("rps", 5, "requests per second")
+	concurrency := fs.Int("concurrency", 0, "concurrent requests sent as fast as answered instead of at rps")
+	duration := fs.Duration("duration", time.Minute, "test duration")
+	sizeFlag := fs.String("size", string(problem.Mixed), "problem size (small, medium, large, mixed)")
+	timeout := fs.Duration("timeout", 60*time.Second, "request timeout")
+	seed := fs.Uint64("seed", uint64(time.Now().UnixNano()), "random seed")
+	_ = fs.Parse(args)
+
+	size, err := problem.ParseSize(*sizeFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *rps <= 0 {
+		log.Fatal("rps must be positive")
+	}
+	if *concurrency < 0 {
+		log.Fatal("concurrency must not be negative")
+	}
+
+	c, err := client.New(*uri, client.WithTimeout(*timeout), client.WithToken(*token))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	ctx, cancel = context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	gen := func(r *rand.Rand) client.OptimizationInput { return problem.Random(r, size) }
+	start := time.Now()
+
+	var results []loadtest.Result
+	if *concurrency > 0 {
+		fmt.Printf("stressing %s with %d concurrent requests of %s problems for %v (seed %d)\n", *uri, *concurrency, size, *duration, *seed)
+		results = loadtest.Concurrent(ctx, c, gen, *seed, *concurrency, *timeout)
+	} else {
+		fmt.Printf("stressing %s with %.1f rps of %s problems for %v (seed %d)\n", *uri, *rps, size, *duration, *seed)
+		results = loadtest.Rate(ctx, c, gen, *seed, *rps, *timeout)
+	}
+
+	loadtest.Report(os.Stdout, results, time.Since(start))
+
+	if loadtest.Failed(results) {
+		os.Exit(1)
+	}
+}
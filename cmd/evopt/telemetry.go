@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/telemetry"
+	"github.com/olekukonko/tablewriter"
+)
+
+// telemetryFile returns the file of the recorded statistics
+func telemetryFile() string {
+	if file := os.Getenv("EVOPT_TELEMETRY_FILE"); file != "" {
+		return file
+	}
+
+	cfg, err := os.UserConfigDir()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return filepath.Join(cfg, "evopt", "telemetry.jsonl")
+}
+
+// withTelemetry records the statistics of the optimizations of a client in the telemetry file
+// and submits them to EVOPT_TELEMETRY_URL if set. The returned function submits the statistics.
+func withTelemetry() (client.Option, func()) {
+	file := telemetryFile()
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		log.Fatal(err)
+	}
+
+	r := telemetry.New(telemetry.Config{
+		File:     file,
+		Endpoint: os.Getenv("EVOPT_TELEMETRY_URL"),
+		OnError:  func(err error) { log.Println(err) },
+	})
+
+	return client.WithMiddleware(r.Middleware()), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := r.Flush(ctx); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// telemetryCmd summarizes the statistics recorded by commands run with -telemetry.
+func telemetryCmd(args []string) {
+	fs := flag.NewFlagSet("telemetry", flag.ExitOnError)
+	file := fs.String("file", telemetryFile(), "statistics file")
+	since := fs.Duration("since", 0, "only summarize statistics of the given period, e.g. 168h")
+	_ = fs.Parse(args)
+
+	stats, err := telemetry.Load(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *since > 0 {
+		from := time.Now().Add(-*since)
+		stats = slices.DeleteFunc(stats, func(s telemetry.Stats) bool { return s.Time.Before(from.Truncate(time.Hour)) })
+	}
+
+	sum := telemetry.Summarize(stats)
+
+	fmt.Printf("%d optimizations, %d failed\n", sum.Count, sum.Failed)
+	for _, status := range slices.Sorted(maps.Keys(sum.Status)) {
+		fmt.Printf("  %-12s %d\n", status, sum.Status[status])
+	}
+
+	table := tablewriter.NewTable(os.Stdout, tableConfig)
+	table.Header([]string{"", "Median", "P95", "Max"})
+	table.Append([]string{"Intervals", fmt.Sprint(sum.Intervals.P50), fmt.Sprint(sum.Intervals.P95), fmt.Sprint(sum.Intervals.Max)})
+	table.Append([]string{"Batteries", fmt.Sprint(sum.Batteries.P50), fmt.Sprint(sum.Batteries.P95), fmt.Sprint(sum.Batteries.Max)})
+	table.Append([]string{"Duration", duration(sum.Duration.P50), duration(sum.Duration.P95), duration(sum.Duration.Max)})
+	table.Append([]string{"Solve time", duration(sum.SolveTime.P50), duration(sum.SolveTime.P95), duration(sum.SolveTime.Max)})
+	table.Render()
+}
+
+func duration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/evcc-io/optimizer/integrations/mqtt"
+	"github.com/evcc-io/optimizer/integrations/notify"
+	"gopkg.in/yaml.v3"
+)
+
+// config is the YAML configuration of a site, ${VAR} is replaced by the environment variable,
+// e.g. for tokens:
+//
+//	site: home
+//	uri: http://localhost:7050
+//	request: site.json
+//	storage: sqlite:evoptd.db
+//	listen: 127.0.0.1:8080
+//	api_token: ${EVOPTD_TOKEN}
+//	mqtt:
+//	  broker: tcp://localhost:1883
+//	notify:
+//	  ntfy:
+//	    topic: evopt-home
+type config struct {
+	// Site names the site in notifications, defaults to evopt.
+	Site  string `yaml:"site"`
+	URI   string `yaml:"uri"`
+	Token string `yaml:"token"` // authorization token of the optimizer
+	// Request is the file of the request, re-read before each optimization, see readSite.
+	Request string `yaml:"request"`
+	// Slot is the length of the intervals of the request, defaults to 15m.
+	Slot time.Duration `yaml:"slot"`
+	// Interval of re-optimization, defaults to 15m.
+	Interval time.Duration `yaml:"interval"`
+	// Latency is the actuation latency of each battery.
+	Latency []time.Duration `yaml:"latency"`
+	// Storage of the journal, runs, revisions and shadow operation, see storage.Open. Defaults
+	// to evoptd.db.
+	Storage string `yaml:"storage"`
+	// Retention of runs and revisions, defaults to 30 days.
+	Retention time.Duration `yaml:"retention"`
+	// Listen is the address of the HTTP API, defaults to 127.0.0.1:8080.
+	Listen string `yaml:"listen"`
+	// APIToken authorizes overrides and pins, which are disabled without.
+	APIToken string `yaml:"api_token"`
+	// Measure is the URL of the measured state of charge of each battery as JSON array [Wh].
+	// Without, the state of charge of the request is used.
+	Measure string `yaml:"measure"`
+	// Emulate commands emulated batteries of the request instead of a site, e.g. for trying the
+	// configuration. The emulated state of charge is measured.
+	Emulate bool `yaml:"emulate"`
+
+	MQTT   *mqtt.Config `yaml:"mqtt"`
+	Notify struct {
+		Telegram *notify.Telegram `yaml:"telegram"`
+		Pushover *notify.Pushover `yaml:"pushover"`
+		Ntfy     *notify.Ntfy     `yaml:"ntfy"`
+		// SummaryAt is the time of day of the daily summary, defaults to 7h.
+		SummaryAt time.Duration `yaml:"summary_at"`
+	} `yaml:"notify"`
+	// Shadow runs the plans in shadow operation instead of publishing them to MQTT.
+	Shadow *struct {
+		// Meter is the URL of the energies measured between the RFC 3339 times of the from and
+		// to query parameters as JSON, see shadow.Measurement.
+		Meter  string        `yaml:"meter"`
+		Period time.Duration `yaml:"period"`
+	} `yaml:"shadow"`
+}
+
+// readConfig reads the configuration file and applies the defaults
+func readConfig(name string) (config, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return config{}, err
+	}
+
+	var cfg config
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(b))), &cfg); err != nil {
+		return config{}, fmt.Errorf("%s: %w", name, err)
+	}
+
+	switch {
+	case cfg.URI == "":
+		return config{}, errors.New("missing uri")
+	case cfg.Request == "":
+		return config{}, errors.New("missing request")
+	case cfg.Shadow != nil && cfg.Shadow.Meter == "":
+		return config{}, errors.New("missing shadow meter")
+	case cfg.Shadow != nil && cfg.Emulate:
+		return config{}, errors.New("shadow operation cannot emulate batteries")
+	}
+
+	if cfg.Site == "" {
+		cfg.Site = "evopt"
+	}
+	if cfg.Slot <= 0 {
+		cfg.Slot = 15 * time.Minute
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Minute
+	}
+	if cfg.Storage == "" {
+		cfg.Storage = "evoptd.db"
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 30 * 24 * time.Hour
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = "127.0.0.1:8080"
+	}
+	if cfg.Notify.SummaryAt <= 0 {
+		cfg.Notify.SummaryAt = 7 * time.Hour
+	}
+
+	return cfg, nil
+}
+
+// senders returns the configured notification senders
+func (cfg config) senders() []notify.Sender {
+	var res []notify.Sender
+	if cfg.Notify.Telegram != nil {
+		res = append(res, *cfg.Notify.Telegram)
+	}
+	if cfg.Notify.Pushover != nil {
+		res = append(res, *cfg.Notify.Pushover)
+	}
+	if cfg.Notify.Ntfy != nil {
+		res = append(res, *cfg.Notify.Ntfy)
+	}
+	return res
+}
@@ -0,0 +1,257 @@
+// Command evoptd optimizes a site in a rolling horizon and serves the plan: the request file is
+// re-optimized periodically, plans are journaled, published to MQTT, summarized by
+// notifications and served over HTTP, and every plan revision is recorded with its trigger and
+// key figure deltas, see evopt revisions. With shadow operation the plans are not published to
+// MQTT and their hypothetical savings are recorded instead, see evopt shadow.
+//
+//	evoptd -config evoptd.yaml
+//
+// The API serves
+//
+//	/plan.json, /plan.svg, /plan/explain  the latest plan, see handlers
+//	/plans/next                           the plan following a revision, for controllers
+//	/revisions                            the plan revisions, see changelog
+//	/overrides, /pins                     overrides and pinned setpoints with the api token
+//
+// Overrides and pins change what is commanded, the API listens on localhost by default and must
+// not be exposed publicly.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/evcc-io/optimizer/changelog"
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/crypt"
+	"github.com/evcc-io/optimizer/handlers"
+	"github.com/evcc-io/optimizer/integrations/mqtt"
+	"github.com/evcc-io/optimizer/integrations/notify"
+	"github.com/evcc-io/optimizer/override"
+	"github.com/evcc-io/optimizer/schedule"
+	"github.com/evcc-io/optimizer/shadow"
+	"github.com/evcc-io/optimizer/sim/device"
+	"github.com/evcc-io/optimizer/storage"
+	"github.com/evcc-io/optimizer/store"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// plansKept is the number of plans kept for /plans/next
+const plansKept = 100
+
+func main() {
+	configFile := flag.String("config", "evoptd.yaml", "configuration file")
+	flag.Parse()
+
+	cfg, err := readConfig(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, cfg); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatal(err)
+	}
+}
+
+// run runs the daemon until ctx is cancelled or a component fails
+func run(ctx context.Context, cfg config) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	clk := clock.Real
+
+	cph, err := crypt.FromEnv()
+	if err != nil {
+		return err
+	}
+
+	backend, err := storage.Open(cfg.Storage)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+
+	runs := store.New(backend, store.WithCipher(cph), store.WithRetention(cfg.Retention))
+	c, err := client.New(cfg.URI, client.WithTimeout(time.Minute), client.WithToken(cfg.Token),
+		client.WithMiddleware(runs.Middleware(func(err error) { slog.Warn("recording run failed", "error", err) })))
+	if err != nil {
+		return err
+	}
+
+	notifier := notify.New(cfg.Site, cfg.senders(), notify.WithSummaryAt(cfg.Notify.SummaryAt))
+	pins := override.New()
+
+	opts := []schedule.Option{
+		schedule.WithSlot(cfg.Slot),
+		schedule.WithInterval(cfg.Interval),
+		schedule.WithJournal(schedule.NewJournal(backend, cfg.Site, schedule.WithJournalClock(clk), schedule.WithJournalCipher(cph))),
+		schedule.WithLatency(cfg.Latency...),
+	}
+
+	var emulator *device.Emulator
+	switch {
+	case cfg.Emulate:
+		s, err := readSite(cph, cfg.Request)
+		if err != nil {
+			return err
+		}
+
+		batteries := make([]device.Config, len(s.Request.Batteries))
+		for i, b := range s.Request.Batteries {
+			batteries[i] = device.Config{Capacity: float64(b.SMax), SoC: float64(b.SInitial), MaxCharge: float64(b.CMax), MaxDischarge: float64(b.DMax)}
+		}
+
+		emulator = device.New(batteries)
+		opts = append(opts, schedule.WithMeasure(emulator.Measure))
+
+	case cfg.Measure != "":
+		opts = append(opts, schedule.WithMeasure(measureURL(cfg.Measure)))
+	}
+
+	sched := schedule.New(notifier.Solver(c), fileSource(cph, cfg.Request, pins), opts...)
+
+	if rec, err := sched.Resume(ctx); err != nil {
+		slog.Warn("resuming journal failed", "error", err)
+	} else if rec.Schedule != nil {
+		slog.Info("resumed plan", "start", rec.Schedule.Start)
+	}
+
+	plans, err := handlers.NewStore(ctx, backend, plansKept)
+	if err != nil {
+		return err
+	}
+
+	revisions := changelog.New(backend, changelog.WithCipher(cph), changelog.WithRetention(cfg.Retention))
+
+	var wg sync.WaitGroup
+
+	// consumers of the schedules, each failure stops the daemon
+	var consumers []func(context.Context, <-chan schedule.Schedule) error
+	consumers = append(consumers, revisions.Run, notifier.Run, func(ctx context.Context, updates <-chan schedule.Schedule) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case s := <-updates:
+				if err := plans.Set(handlers.Plan{Start: s.Start, Request: s.Request, Result: s.Result}); err != nil {
+					slog.Warn("recording plan failed", "error", err)
+				}
+			}
+		}
+	})
+
+	switch {
+	case cfg.Shadow != nil:
+		measure, err := meterURL(cfg.Shadow.Meter)
+		if err != nil {
+			return err
+		}
+
+		// shadow operation ends after its period, the plans are served on
+		s := shadow.New(backend, measure, shadow.WithSlot(cfg.Slot), shadow.WithPeriod(cfg.Shadow.Period), shadow.WithCipher(cph))
+		consumers = append(consumers, s.Run)
+
+	case cfg.MQTT != nil:
+		p, err := mqtt.New(*cfg.MQTT)
+		if err != nil {
+			return err
+		}
+		defer p.Close()
+
+		consumers = append(consumers, p.Run)
+	}
+
+	start := func(name string, fn func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				cancel(fmt.Errorf("%s: %w", name, err))
+			}
+		}()
+	}
+
+	for i, updates := range fanout(ctx, sched.Updates(), len(consumers)) {
+		consume := consumers[i]
+		start("consumer", func(ctx context.Context) error { return consume(ctx, updates) })
+	}
+
+	start("scheduler", sched.Run)
+
+	if emulator != nil {
+		start("emulator", func(ctx context.Context) error { return emulator.Control(ctx, sched, time.Minute) })
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/plan.json", handlers.PlanJSON(plans))
+	mux.Handle("/plan.svg", handlers.PlanChartSVG(plans))
+	mux.Handle("/plan/explain", handlers.PlanExplain(plans))
+	mux.Handle("/plans/next", handlers.PlanNext(plans, time.Minute))
+	mux.Handle("/revisions", handlers.Revisions(revisions))
+	mux.Handle("/overrides", handlers.Overrides(sched, cfg.APIToken, clk))
+	mux.Handle("/pins", handlers.RequireToken(cfg.APIToken, pinsHandler(pins, sched, clk)))
+
+	srv := &http.Server{Addr: cfg.Listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	start("api", func(ctx context.Context) error {
+		go func() {
+			<-ctx.Done()
+			_ = srv.Shutdown(context.Background())
+		}()
+
+		slog.Info("serving api", "addr", cfg.Listen)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	})
+
+	<-ctx.Done()
+	wg.Wait()
+
+	return context.Cause(ctx)
+}
+
+// fanout forwards the schedules of in to n channels, keeping only the latest schedule for slow
+// consumers like Scheduler.Updates
+func fanout(ctx context.Context, in <-chan schedule.Schedule, n int) []<-chan schedule.Schedule {
+	outs := make([]chan schedule.Schedule, n)
+	res := make([]<-chan schedule.Schedule, n)
+	for i := range outs {
+		outs[i] = make(chan schedule.Schedule, 1)
+		res[i] = outs[i]
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sched := <-in:
+				for _, out := range outs {
+					select {
+					case <-out:
+					default:
+					}
+					out <- sched
+				}
+			}
+		}
+	}()
+
+	return res
+}
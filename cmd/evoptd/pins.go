@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/override"
+	"github.com/evcc-io/optimizer/schedule"
+)
+
+// pin is a pinned battery setpoint of the pins API
+type pin struct {
+	ID      int       `json:"id,omitempty"`
+	Battery int       `json:"battery"`
+	Mode    string    `json:"mode"`            // hold or charge
+	Power   float64   `json:"power,omitempty"` // minimum charge power [W]
+	Until   time.Time `json:"until,omitzero"`
+	// Duration from now, e.g. 2h, instead of until.
+	Duration string `json:"duration,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// pinsHandler lists the pinned setpoints on GET, pins a setpoint on POST and cancels the pin of
+// the id query parameter on DELETE. Changes re-optimize immediately, the revision records the
+// reason.
+func pinsHandler(pins *override.Manager, sched *schedule.Scheduler, clk clock.Clock) http.Handler {
+	reoptimize := func(ctx context.Context, w http.ResponseWriter, reason string) bool {
+		if _, err := sched.Optimize(ctx, reason); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return false
+		}
+		return true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v any
+
+		switch r.Method {
+		case http.MethodGet:
+			res := []pin{}
+			for _, o := range pins.Active() {
+				for _, sp := range o.Setpoints {
+					res = append(res, pin{ID: o.ID, Battery: sp.Battery, Mode: sp.Mode.String(), Power: sp.Power, Until: o.Until, Reason: o.Reason})
+				}
+			}
+			v = res
+
+		case http.MethodPost:
+			var p pin
+			if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			sp := override.Setpoint{Battery: p.Battery, Power: p.Power}
+			switch p.Mode {
+			case "hold":
+				sp.Mode = override.Hold
+			case "charge":
+				sp.Mode = override.Charge
+			default:
+				http.Error(w, "invalid mode, expected hold or charge", http.StatusBadRequest)
+				return
+			}
+
+			if p.Duration != "" {
+				d, err := time.ParseDuration(p.Duration)
+				if err != nil || !p.Until.IsZero() {
+					http.Error(w, "invalid duration, expected e.g. 2h without until", http.StatusBadRequest)
+					return
+				}
+				p.Until = clk.Now().Add(d)
+			}
+
+			id, err := pins.OverrideUntil(p.Until, p.Reason, sp)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if !reoptimize(r.Context(), w, strings.TrimSpace(fmt.Sprintf("pin %d: battery %d %s %s", id, p.Battery, p.Mode, p.Reason))) {
+				pins.Cancel(id)
+				return
+			}
+
+			v = struct {
+				ID int `json:"id"`
+			}{id}
+
+		case http.MethodDelete:
+			id, err := strconv.Atoi(r.URL.Query().Get("id"))
+			if err != nil {
+				http.Error(w, "invalid id", http.StatusBadRequest)
+				return
+			}
+			if !pins.Cancel(id) {
+				http.Error(w, "pin not found", http.StatusNotFound)
+				return
+			}
+
+			if !reoptimize(r.Context(), w, fmt.Sprintf("pin %d cancelled", id)) {
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return
+
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(b)
+	})
+}
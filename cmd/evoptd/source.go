@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/crypt"
+	"github.com/evcc-io/optimizer/override"
+	"github.com/evcc-io/optimizer/schedule"
+	"github.com/evcc-io/optimizer/shadow"
+)
+
+// site is a request file with the start of its first interval, e.g. written by the script
+// updating forecasts and prices. Plain requests start with the current slot.
+type site struct {
+	Start   time.Time                `json:"start"`
+	Request client.OptimizationInput `json:"request"`
+}
+
+// readSite reads the request file, sealed files are opened with cph
+func readSite(cph *crypt.Cipher, name string) (site, error) {
+	b, err := cph.ReadFile(name)
+	if err != nil {
+		return site{}, err
+	}
+
+	var s site
+	if err := json.Unmarshal(b, &s); err != nil {
+		return site{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if s.Request.TimeSeries.Dt == nil {
+		s = site{}
+		if err := json.Unmarshal(b, &s.Request); err != nil {
+			return site{}, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return s, nil
+}
+
+// fileSource returns the request of the file trimmed to start with the pinned setpoints applied
+func fileSource(cph *crypt.Cipher, name string, pins *override.Manager) schedule.Source {
+	return func(ctx context.Context, start time.Time) (client.OptimizationInput, error) {
+		s, err := readSite(cph, name)
+		if err != nil {
+			return client.OptimizationInput{}, err
+		}
+
+		req := s.Request
+		if !s.Start.IsZero() {
+			if s.Start.After(start) {
+				return client.OptimizationInput{}, fmt.Errorf("%s starts at %s", name, s.Start.Format(time.RFC3339))
+			}
+			if req = schedule.Trim(req, s.Start, start); len(req.TimeSeries.Dt) == 0 {
+				return client.OptimizationInput{}, fmt.Errorf("%s elapsed", name)
+			}
+		}
+
+		if err := pins.Apply(&req, start); err != nil {
+			return client.OptimizationInput{}, err
+		}
+
+		return req, nil
+	}
+}
+
+// getJSON decodes the response of a GET request of u into v
+func getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %d", u, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// measureURL measures the state of charge of each battery from a JSON array at u
+func measureURL(u string) schedule.Measure {
+	return func(ctx context.Context) ([]float64, error) {
+		var soc []float64
+		if err := getJSON(ctx, u, &soc); err != nil {
+			return nil, err
+		}
+		if soc == nil {
+			return nil, errors.New("missing state of charge")
+		}
+		return soc, nil
+	}
+}
+
+// meterURL measures the energies of shadow operation at u
+func meterURL(u string) (shadow.Measure, error) {
+	base, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, from, to time.Time) (shadow.Measurement, error) {
+		u := *base
+		q := u.Query()
+		q.Set("from", from.Format(time.RFC3339))
+		q.Set("to", to.Format(time.RFC3339))
+		u.RawQuery = q.Encode()
+
+		var m shadow.Measurement
+		err := getJSON(ctx, u.String(), &m)
+		return m, err
+	}, nil
+}
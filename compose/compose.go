@@ -0,0 +1,380 @@
+// Package compose merges partial optimization requests contributed by independent services,
+// e.g. prices by a tariff service, forecasts by a forecast service and batteries by a device
+// service:
+//
+//	tariff := compose.New("tariff").Set("time_series.p_N", prices).Set("time_series.p_E", feedIn)
+//	device := compose.New("device").Set("batteries[home].s_max", 10000).Set("batteries[home].c_max", 5000)
+//	req, prov, err := compose.Compose(horizon, forecast, tariff, device)
+//
+// Fields are addressed by their JSON path in client.OptimizationInput, batteries by their id.
+// A field contributed by several fragments must have the same value in all of them. The
+// sources of each field are tracked as provenance.
+package compose
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Fragment is a part of a request contributed by a source. The first error is kept and
+// returned by Compose, subsequent calls are ignored.
+type Fragment struct {
+	Source string
+	fields map[string]any // JSON values by path
+	order  []string       // paths in order of contribution
+	err    error
+}
+
+// New creates an empty fragment of source.
+func New(source string) *Fragment {
+	return &Fragment{Source: source, fields: make(map[string]any)}
+}
+
+// Parse creates a fragment of source from a partial request in JSON. Batteries must have ids.
+func Parse(source string, b []byte) (*Fragment, error) {
+	var v map[string]any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	f := New(source)
+	f.flatten("", v)
+	return f, f.err
+}
+
+// FromRequest creates a fragment of source from the non-zero fields of req. Zero values are
+// not contributed, use Set for fields that are zero on purpose. Batteries must have ids.
+func FromRequest(source string, req client.OptimizationInput) (*Fragment, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var v map[string]any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+
+	f := New(source)
+	f.flatten("", prune(v).(map[string]any))
+	return f, f.err
+}
+
+func (f *Fragment) fail(format string, args ...any) *Fragment {
+	if f.err == nil {
+		f.err = fmt.Errorf("%s: %w", f.Source, fmt.Errorf(format, args...))
+	}
+	return f
+}
+
+// Set contributes the value of a field, e.g. time_series.p_N or batteries[ev].s_goal. Objects
+// are contributed field by field, e.g. a battery by batteries[ev]. Structs contribute all
+// marshaled fields including zero values of fields without omitempty, see FromRequest.
+func (f *Fragment) Set(path string, value any) *Fragment {
+	if f.err != nil {
+		return f
+	}
+
+	// normalize to JSON values for comparison
+	b, err := json.Marshal(value)
+	if err != nil {
+		return f.fail("%s: %v", path, err)
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return f.fail("%s: %v", path, err)
+	}
+
+	f.flatten(path, v)
+	return f
+}
+
+// flatten contributes v at path, descending into objects and batteries
+func (f *Fragment) flatten(path string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			// the id of a battery is part of its path
+			if k == "id" && strings.HasPrefix(path, "batteries[") && !strings.Contains(path, ".") {
+				continue
+			}
+			f.flatten(join(path, k), v[k])
+		}
+
+	case []any:
+		if path != "batteries" {
+			f.put(path, v)
+			return
+		}
+
+		for i, bat := range v {
+			obj, ok := bat.(map[string]any)
+			id, _ := obj["id"].(string)
+			if !ok || id == "" {
+				f.fail("battery %d: id required", i)
+				return
+			}
+			f.flatten(fmt.Sprintf("batteries[%s]", id), obj)
+		}
+
+	default:
+		f.put(path, v)
+	}
+}
+
+func (f *Fragment) put(path string, v any) {
+	if _, err := parsePath(path); err != nil {
+		f.fail("%v", err)
+		return
+	}
+	if old, ok := f.fields[path]; ok && !reflect.DeepEqual(old, v) {
+		f.fail("%s: set twice", path)
+		return
+	}
+	if _, ok := f.fields[path]; !ok {
+		f.order = append(f.order, path)
+	}
+	f.fields[path] = v
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// prune removes zero values, empty objects and empty arrays from v, keeping battery ids
+func prune(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if e = prune(e); e == nil {
+				delete(v, k)
+			} else {
+				v[k] = e
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+		return v
+
+	case []any:
+		if len(v) == 0 {
+			return nil
+		}
+		for i, e := range v {
+			if obj, ok := e.(map[string]any); ok {
+				id := obj["id"]
+				if e = prune(obj); e == nil {
+					e = map[string]any{}
+				}
+				if id != nil {
+					e.(map[string]any)["id"] = id
+				}
+				v[i] = e
+			}
+		}
+		return v
+
+	case float64:
+		if v == 0 {
+			return nil
+		}
+	case string:
+		if v == "" {
+			return nil
+		}
+	case bool:
+		if !v {
+			return nil
+		}
+	}
+
+	return v
+}
+
+// segment is an element of a field path, battery segments carry the id
+type segment struct {
+	key     string
+	battery string
+}
+
+// parsePath splits a path like batteries[ev].s_goal into segments
+func parsePath(path string) ([]segment, error) {
+	if path == "" {
+		return nil, errors.New("empty path")
+	}
+
+	var segs []segment
+	for i, key := range strings.Split(path, ".") {
+		id, ok := strings.CutPrefix(key, "batteries[")
+		switch {
+		case ok && i == 0 && strings.HasSuffix(id, "]") && len(id) > 1:
+			segs = append(segs, segment{key: "batteries", battery: strings.TrimSuffix(id, "]")})
+		case key == "" || key == "batteries" || strings.ContainsAny(key, "[]"):
+			return nil, fmt.Errorf("invalid path %q", path)
+		default:
+			segs = append(segs, segment{key: key})
+		}
+	}
+
+	if len(segs) == 1 && segs[0].battery != "" {
+		return nil, fmt.Errorf("invalid path %q: battery field required", path)
+	}
+
+	return segs, nil
+}
+
+// Provenance holds the sources contributing each field by path.
+type Provenance map[string][]string
+
+// Sources returns the sources contributing the field at path or any field below it, e.g. of
+// batteries[ev] or time_series.
+func (p Provenance) Sources(path string) []string {
+	var res []string
+	for field, sources := range p {
+		if field == path || strings.HasPrefix(field, path+".") {
+			for _, s := range sources {
+				if !slices.Contains(res, s) {
+					res = append(res, s)
+				}
+			}
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+// Conflict is a field contributed with different values.
+type Conflict struct {
+	Path    string
+	Sources []string // sources in order of the fragments
+	Values  []any    // value of each source
+}
+
+// ConflictError is returned by Compose for fragments contributing different values.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	s := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		s = append(s, fmt.Sprintf("%s: %s disagree", c.Path, strings.Join(c.Sources, ", ")))
+	}
+	return "conflicting fields: " + strings.Join(s, "; ")
+}
+
+// Compose merges the fragments into a request and validates it, see client.Validate.
+// Batteries are ordered by their first contribution. Conflicting fields are reported as
+// *ConflictError.
+func Compose(fragments ...*Fragment) (client.OptimizationInput, Provenance, error) {
+	var (
+		values    = make(map[string]any)
+		order     []string
+		prov      = make(Provenance)
+		conflicts = make(map[string]*Conflict)
+		batteries []string // ids in order of contribution
+	)
+
+	for _, f := range fragments {
+		if f.err != nil {
+			return client.OptimizationInput{}, nil, f.err
+		}
+
+		for _, path := range f.order {
+			v := f.fields[path]
+
+			if segs, _ := parsePath(path); segs[0].battery != "" && !slices.Contains(batteries, segs[0].battery) {
+				batteries = append(batteries, segs[0].battery)
+			}
+
+			old, ok := values[path]
+			if !ok {
+				values[path] = v
+				order = append(order, path)
+			} else if !reflect.DeepEqual(old, v) {
+				c := conflicts[path]
+				if c == nil {
+					c = &Conflict{Path: path, Sources: slices.Clone(prov[path]), Values: slices.Repeat([]any{old}, len(prov[path]))}
+					conflicts[path] = c
+				}
+				c.Sources = append(c.Sources, f.Source)
+				c.Values = append(c.Values, v)
+			}
+
+			if !slices.Contains(prov[path], f.Source) {
+				prov[path] = append(prov[path], f.Source)
+			}
+		}
+	}
+
+	if len(conflicts) > 0 {
+		err := new(ConflictError)
+		for _, path := range order {
+			if c, ok := conflicts[path]; ok {
+				err.Conflicts = append(err.Conflicts, *c)
+			}
+		}
+		return client.OptimizationInput{}, prov, err
+	}
+
+	// unflatten into the JSON structure of the request
+	root := map[string]any{"batteries": []any{}}
+	bats := make(map[string]map[string]any)
+	for _, id := range batteries {
+		bat := map[string]any{"id": id}
+		bats[id] = bat
+		root["batteries"] = append(root["batteries"].([]any), bat)
+	}
+
+	for _, path := range order {
+		segs, _ := parsePath(path)
+
+		obj := root
+		if segs[0].battery != "" {
+			obj, segs = bats[segs[0].battery], segs[1:]
+		}
+		for _, s := range segs[:len(segs)-1] {
+			next, ok := obj[s.key].(map[string]any)
+			if !ok {
+				if _, set := obj[s.key]; set {
+					return client.OptimizationInput{}, prov, fmt.Errorf("%s: %s is not an object", path, s.key)
+				}
+				next = make(map[string]any)
+				obj[s.key] = next
+			}
+			obj = next
+		}
+
+		key := segs[len(segs)-1].key
+		if _, ok := obj[key].(map[string]any); ok {
+			return client.OptimizationInput{}, prov, fmt.Errorf("%s: object expected", path)
+		}
+		obj[key] = values[path]
+	}
+
+	b, err := json.Marshal(root)
+	if err != nil {
+		return client.OptimizationInput{}, prov, err
+	}
+
+	var req client.OptimizationInput
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return client.OptimizationInput{}, prov, err
+	}
+
+	return req, prov, client.Validate(req)
+}
@@ -0,0 +1,79 @@
+// Package cop learns the coefficient of performance of a heat pump over the outdoor temperature
+// from past operation, e.g. for the c_eta_series of a thermal storage modelled as battery.
+package cop
+
+import (
+	"errors"
+	"math"
+)
+
+// Sample is a past heat pump operating point.
+type Sample struct {
+	// Temperature is the outdoor temperature in °C.
+	Temperature float64
+	// Electric is the electric energy consumed in Wh.
+	Electric float64
+	// Thermal is the thermal energy delivered in Wh.
+	Thermal float64
+}
+
+// Model is a linear regression of the coefficient of performance (COP) over the outdoor
+// temperature: COP = Intercept + Slope * temperature.
+type Model struct {
+	Intercept float64
+	Slope     float64
+	// Min and Max bound the COP, avoiding implausible values when extrapolating
+	// beyond the temperature range of the samples.
+	Min, Max float64
+}
+
+// Fit fits a COP model to past operation. Samples are weighted by their electric energy,
+// so that short low-load periods with noisy measurements contribute less. The bounds are
+// the lowest and highest COP observed.
+func Fit(samples []Sample) (Model, error) {
+	var sw, st, sc, stt, stc float64
+	m := Model{Min: math.Inf(1), Max: math.Inf(-1)}
+
+	for _, s := range samples {
+		if s.Electric <= 0 || s.Thermal <= 0 {
+			continue
+		}
+
+		c := s.Thermal / s.Electric
+		w := s.Electric
+
+		sw += w
+		st += w * s.Temperature
+		sc += w * c
+		stt += w * s.Temperature * s.Temperature
+		stc += w * s.Temperature * c
+
+		m.Min = min(m.Min, c)
+		m.Max = max(m.Max, c)
+	}
+
+	if sw == 0 {
+		return Model{}, errors.New("no samples with energy consumption")
+	}
+
+	if d := sw*stt - st*st; d > 1e-9*sw*sw {
+		m.Slope = (sw*stc - st*sc) / d
+	}
+	m.Intercept = (sc - m.Slope*st) / sw
+
+	return m, nil
+}
+
+// COP returns the coefficient of performance at the given outdoor temperature.
+func (m Model) COP(temperature float64) float64 {
+	return min(m.Max, max(m.Min, m.Intercept+m.Slope*temperature))
+}
+
+// Series converts a temperature forecast into a COP series suitable as c_eta_series.
+func (m Model) Series(temperatures []float64) []float32 {
+	res := make([]float32, len(temperatures))
+	for i, t := range temperatures {
+		res[i] = float32(m.COP(t))
+	}
+	return res
+}
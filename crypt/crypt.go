@@ -0,0 +1,117 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// KeyEnv is the environment variable holding the hex or base64 encoded 256 bit key.
+const KeyEnv = "EVOPT_KEY"
+
+// magic prefixes encrypted payloads, allowing plain and encrypted data to be mixed
+var magic = []byte("evopt-gcm1\x00")
+
+// ErrNoKey is returned when decrypting data without a key configured.
+var ErrNoKey = errors.New("encrypted payload but no key configured in " + KeyEnv)
+
+// Cipher encrypts payloads using AES-GCM. A nil Cipher passes data through unencrypted.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New creates a cipher from a 16, 24 or 32 byte key.
+func New(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// FromEnv creates a cipher from the key in KeyEnv. If the variable is not set, encryption
+// is disabled and a nil Cipher is returned.
+func FromEnv() (*Cipher, error) {
+	s := os.Getenv(KeyEnv)
+	if s == "" {
+		return nil, nil
+	}
+
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		if key, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("%s: key must be hex or base64 encoded", KeyEnv)
+		}
+	}
+
+	return New(key)
+}
+
+// IsEncrypted reports whether data is an encrypted payload.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Seal encrypts data. A nil Cipher returns data unchanged.
+func (c *Cipher) Seal(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append(append([]byte{}, magic...), nonce...)
+	return c.aead.Seal(out, nonce, data, magic), nil
+}
+
+// Open decrypts data sealed by Seal. Unencrypted data is returned unchanged, so that
+// payloads persisted before enabling encryption remain readable.
+func (c *Cipher) Open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, ErrNoKey
+	}
+
+	data = data[len(magic):]
+	if len(data) < c.aead.NonceSize() {
+		return nil, errors.New("encrypted payload too short")
+	}
+
+	nonce, data := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	return c.aead.Open(nil, nonce, data, magic)
+}
+
+// WriteFile encrypts data and writes it to a file readable by the owner only.
+func (c *Cipher) WriteFile(name string, data []byte) error {
+	b, err := c.Seal(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, b, 0o600)
+}
+
+// ReadFile reads a file and transparently decrypts it.
+func (c *Cipher) ReadFile(name string) ([]byte, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Open(b)
+}
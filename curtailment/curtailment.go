@@ -0,0 +1,61 @@
+// Package curtailment annotates optimization requests with export curtailment forecasts,
+// e.g. from a grid operator API.
+package curtailment
+
+import (
+	"context"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Risk is the probability of export curtailment within a period.
+type Risk struct {
+	Start, End  time.Time
+	Probability float64
+}
+
+// Source provides curtailment forecasts.
+type Source interface {
+	Forecast(ctx context.Context, from, to time.Time) ([]Risk, error)
+}
+
+// Apply sets the curtailment risk series of req with the first interval starting at start.
+// Each interval gets the highest probability of the risks overlapping it. The intervals at risk
+// are flagged by the server in CurtailmentRisk of the result.
+func Apply(req *client.OptimizationInput, start time.Time, risks []Risk) {
+	dt := req.TimeSeries.Dt
+	series := make([]float32, len(dt))
+
+	ts := start
+	for t := range dt {
+		end := ts.Add(time.Duration(dt[t]) * time.Second)
+
+		for _, r := range risks {
+			if r.Start.Before(end) && r.End.After(ts) {
+				series[t] = max(series[t], float32(min(1, max(0, r.Probability))))
+			}
+		}
+
+		ts = end
+	}
+
+	req.TimeSeries.RCurt = series
+}
+
+// Fetch retrieves the forecast for the horizon of req from source and applies it.
+func Fetch(ctx context.Context, source Source, req *client.OptimizationInput, start time.Time) error {
+	var horizon time.Duration
+	for _, dt := range req.TimeSeries.Dt {
+		horizon += time.Duration(dt) * time.Second
+	}
+
+	risks, err := source.Forecast(ctx, start, start.Add(horizon))
+	if err != nil {
+		return err
+	}
+
+	Apply(req, start, risks)
+
+	return nil
+}
@@ -0,0 +1,138 @@
+// Package ensemble turns point forecasts into forecast ensembles using error statistics, for
+// evaluating plans against forecast uncertainty without sourcing ensemble forecasts.
+package ensemble
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Spread is the forecast error at a horizon given as quantiles of the ratio of actual to
+// forecasted value.
+type Spread struct {
+	Horizon       time.Duration
+	P10, P50, P90 float64
+}
+
+// Model describes the forecast error by horizon. Spreads between horizons are interpolated
+// linearly, beyond the last horizon the last spread applies. Errors of consecutive intervals
+// are correlated by Correlation per hour, as forecast errors typically persist.
+type Model struct {
+	Spreads     []Spread
+	Correlation float64
+}
+
+// PV is a typical error model of day-ahead PV forecasts.
+var PV = Model{
+	Spreads: []Spread{
+		{Horizon: 0, P10: 0.9, P50: 1, P90: 1.1},
+		{Horizon: 6 * time.Hour, P10: 0.6, P50: 1, P90: 1.3},
+		{Horizon: 24 * time.Hour, P10: 0.4, P50: 1, P90: 1.5},
+	},
+	Correlation: 0.8,
+}
+
+// Load is a typical error model of household load forecasts.
+var Load = Model{
+	Spreads: []Spread{
+		{Horizon: 0, P10: 0.85, P50: 1, P90: 1.2},
+		{Horizon: 24 * time.Hour, P10: 0.7, P50: 1, P90: 1.5},
+	},
+	Correlation: 0.5,
+}
+
+// spread returns the spread at horizon h
+func (m Model) spread(h time.Duration) Spread {
+	s := m.Spreads
+	if len(s) == 0 {
+		return Spread{P10: 1, P50: 1, P90: 1}
+	}
+
+	i, _ := slices.BinarySearchFunc(s, h, func(s Spread, h time.Duration) int {
+		return int(s.Horizon - h)
+	})
+	if i == 0 {
+		return s[0]
+	}
+	if i == len(s) {
+		return s[len(s)-1]
+	}
+
+	a, b := s[i-1], s[i]
+	f := float64(h-a.Horizon) / float64(b.Horizon-a.Horizon)
+	lerp := func(x, y float64) float64 { return x + f*(y-x) }
+
+	return Spread{Horizon: h, P10: lerp(a.P10, b.P10), P50: lerp(a.P50, b.P50), P90: lerp(a.P90, b.P90)}
+}
+
+// ratio maps the standard normal z to the ratio of the spread, interpolating linearly between
+// the quantiles and extrapolating beyond them. Ratios are never negative.
+func (s Spread) ratio(z float64) float64 {
+	// standard normal 90% quantile
+	const z90 = 1.2816
+
+	var r float64
+	if z < 0 {
+		r = s.P50 + (s.P50-s.P10)*z/z90
+	} else {
+		r = s.P50 + (s.P90-s.P50)*z/z90
+	}
+
+	return max(0, r)
+}
+
+// Members generates n ensemble members of the point forecast with intervals of dt seconds,
+// the first interval starting now. Members are reproducible for the same random source.
+func (m Model) Members(r *rand.Rand, forecast []float32, dt []int, n int) [][]float32 {
+	res := make([][]float32, n)
+
+	for k := range res {
+		member := make([]float32, len(forecast))
+
+		var z float64
+		var elapsed time.Duration
+
+		for t, v := range forecast {
+			d := time.Hour
+			if t < len(dt) {
+				d = time.Duration(dt[t]) * time.Second
+			}
+
+			// correlated standard normal noise
+			rho := math.Pow(m.Correlation, d.Hours())
+			if t == 0 {
+				z = r.NormFloat64()
+			} else {
+				z = rho*z + math.Sqrt(1-rho*rho)*r.NormFloat64()
+			}
+
+			member[t] = float32(float64(v) * m.spread(elapsed).ratio(z))
+			elapsed += d
+		}
+
+		res[k] = member
+	}
+
+	return res
+}
+
+// Requests returns n copies of req with generation ft and demand gt replaced by ensemble
+// members of the pv and load models, e.g. for solving with client.OptimizeBatch. Series omitted
+// from req are left empty.
+func Requests(r *rand.Rand, req client.OptimizationInput, pv, load Model, n int) []client.OptimizationInput {
+	ft := pv.Members(r, req.TimeSeries.Ft, req.TimeSeries.Dt, n)
+	gt := load.Members(r, req.TimeSeries.Gt, req.TimeSeries.Dt, n)
+
+	res := make([]client.OptimizationInput, n)
+	for k := range res {
+		res[k] = req
+		res[k].TimeSeries.Ft = ft[k]
+		res[k].TimeSeries.Gt = gt[k]
+	}
+
+	return res
+}
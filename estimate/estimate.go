@@ -0,0 +1,151 @@
+// Package estimate fits battery parameters from recorded operation, so that request
+// parameters reflect the actual hardware instead of datasheet values.
+package estimate
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Sample is a recorded battery measurement.
+type Sample struct {
+	Time  time.Time
+	Power float64 // measured power at the battery terminals, positive for charging [W]
+	SoC   float64 // state of charge [Wh]
+}
+
+// Parameters are the fitted battery parameters.
+type Parameters struct {
+	EtaC    float64 // charging efficiency
+	EtaD    float64 // discharging efficiency
+	Standby float64 // standby loss [W]
+	// Residual is the root mean square error of the fitted state of charge changes [Wh].
+	Residual float64
+	// Intervals is the number of intervals used for fitting.
+	Intervals int
+}
+
+// Fit fits charging and discharging efficiency and standby losses to the recorded samples
+// of one battery using least squares. Between consecutive samples the state of charge
+// changes by
+//
+//	ΔSoC = EtaC·Ec − Ed/EtaD − Standby·Δt
+//
+// with Ec and Ed the charged and discharged energy, assuming the power of a sample holds
+// until the next one. Samples should be ordered by time, cover both charge and discharge
+// cycles as well as idle periods, and use a SoC resolution well below the energy per interval.
+// Intervals longer than maxGap are skipped, e.g. gaps in the recording.
+func Fit(samples []Sample, maxGap time.Duration) (Parameters, error) {
+	// normal equations of the least squares problem in x = (EtaC, 1/EtaD, Standby)
+	var ata [3][3]float64
+	var atb [3]float64
+	var rows [][4]float64
+
+	var charge, discharge, idle bool
+
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+
+		dt := cur.Time.Sub(prev.Time)
+		if dt <= 0 || dt > maxGap {
+			continue
+		}
+
+		h := dt.Hours()
+		ec := math.Max(0, prev.Power) * h
+		ed := math.Max(0, -prev.Power) * h
+
+		charge = charge || ec > 0
+		discharge = discharge || ed > 0
+		idle = idle || (ec == 0 && ed == 0)
+
+		row := [4]float64{ec, -ed, -h, cur.SoC - prev.SoC}
+		rows = append(rows, row)
+
+		for j := range 3 {
+			for k := range 3 {
+				ata[j][k] += row[j] * row[k]
+			}
+			atb[j] += row[j] * row[3]
+		}
+	}
+
+	if !charge || !discharge || !idle {
+		return Parameters{}, errors.New("samples must cover charging, discharging and idle periods")
+	}
+
+	x, err := solve3(ata, atb)
+	if err != nil {
+		return Parameters{}, err
+	}
+
+	if x[0] <= 0 || x[1] <= 0 {
+		return Parameters{}, errors.New("implausible efficiency, check power sign and SoC unit")
+	}
+
+	var sse float64
+	for _, r := range rows {
+		e := r[0]*x[0] + r[1]*x[1] + r[2]*x[2] - r[3]
+		sse += e * e
+	}
+
+	return Parameters{
+		EtaC:      x[0],
+		EtaD:      1 / x[1],
+		Standby:   x[2],
+		Residual:  math.Sqrt(sse / float64(len(rows))),
+		Intervals: len(rows),
+	}, nil
+}
+
+// solve3 solves a 3x3 linear system using Gaussian elimination with partial pivoting
+func solve3(a [3][3]float64, b [3]float64) ([3]float64, error) {
+	for col := range 3 {
+		pivot := col
+		for row := col + 1; row < 3; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return [3]float64{}, errors.New("samples do not determine the parameters")
+		}
+
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for row := col + 1; row < 3; row++ {
+			f := a[row][col] / a[col][col]
+			for k := col; k < 3; k++ {
+				a[row][k] -= f * a[col][k]
+			}
+			b[row] -= f * b[col]
+		}
+	}
+
+	var x [3]float64
+	for row := 2; row >= 0; row-- {
+		sum := b[row]
+		for k := row + 1; k < 3; k++ {
+			sum -= a[row][k] * x[k]
+		}
+		x[row] = sum / a[row][row]
+	}
+
+	return x, nil
+}
+
+// Apply sets the fitted efficiencies as per battery efficiency of bat, overriding the
+// request's eta_c and eta_d. Batteries with a charging efficiency series keep it.
+// Standby losses are not part of the request model.
+func (p Parameters) Apply(bat *client.BatteryConfig) {
+	if bat.CMax > 0 && len(bat.CEtaSeries) == 0 {
+		bat.CEtaCurve = []client.EfficiencyPoint{{Power: bat.CMax, Eta: float32(p.EtaC)}}
+	}
+	if bat.DMax > 0 {
+		bat.DEtaCurve = []client.EfficiencyPoint{{Power: bat.DMax, Eta: float32(p.EtaD)}}
+	}
+}
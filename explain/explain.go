@@ -0,0 +1,227 @@
+// Package explain renders plans as short sentences answering why batteries charge, hold and
+// discharge when they do, e.g. for the CLI summary and for user interfaces:
+//
+//	for _, s := range explain.Render(res, req, start) {
+//		fmt.Println(s)
+//	}
+//
+// Reasons are derived from the forecast, demand and prices of the request around each charge
+// and discharge window. They are plausible readings of the plan, not the optimizer's reasoning.
+package explain
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/plan"
+)
+
+// minEnergy ignores intervals with less energy charged or discharged [Wh], see plan.Windows
+const minEnergy = 1
+
+// Render describes the charge and discharge windows of the plan res computed for req with the
+// first interval starting at start, one sentence per window in order of time, e.g.
+//
+//	Charging 6 kWh between 12:00–15:00 because PV surplus exceeds demand; holding until the 19:00 price peak.
+//
+// Sentences are prefixed by the battery name for requests of several batteries.
+func Render(res client.OptimizationResult, req client.OptimizationInput, start time.Time) []string {
+	windows := plan.Windows(req, res, start, minEnergy)
+	if len(windows) == 0 {
+		return []string{"No battery actions planned."}
+	}
+	slices.SortStableFunc(windows, func(a, b plan.Window) int { return a.Start.Compare(b.Start) })
+
+	r := renderer{req: req, res: res, start: start}
+	r.starts = make([]time.Time, len(req.TimeSeries.Dt)+1)
+	r.starts[0] = start
+	for t, dt := range req.TimeSeries.Dt {
+		r.starts[t+1] = r.starts[t].Add(time.Duration(dt) * time.Second)
+	}
+
+	var sentences []string
+	for i, w := range windows {
+		s := r.window(w, windows[i+1:])
+		if len(req.Batteries) > 1 {
+			s = r.name(w.Battery) + ": " + s
+		}
+		sentences = append(sentences, s)
+	}
+
+	return sentences
+}
+
+type renderer struct {
+	req    client.OptimizationInput
+	res    client.OptimizationResult
+	start  time.Time
+	starts []time.Time // start of each interval and end of the last
+}
+
+// window describes w, followed by the later windows
+func (r renderer) window(w plan.Window, later []plan.Window) string {
+	from, to := r.index(w.Start), r.index(w.End)
+
+	if w.Kind == plan.Charge {
+		s := fmt.Sprintf("Charging %s between %s", energy(w.Energy), r.period(w.Start, w.End))
+		if reason := r.chargeReason(w, from, to); reason != "" {
+			s += " " + reason
+		}
+		if hold := r.hold(w, later); hold != "" {
+			s += "; " + hold
+		}
+		return s + "."
+	}
+
+	s := fmt.Sprintf("Discharging %s between %s", energy(w.Energy), r.period(w.Start, w.End))
+	return s + " " + r.dischargeReason(w, from, to) + "."
+}
+
+// chargeReason explains charging in the intervals [from, to)
+func (r renderer) chargeReason(w plan.Window, from, to int) string {
+	ts := r.req.TimeSeries
+
+	var surplus float64
+	for t := from; t < to; t++ {
+		surplus += max(value(ts.Ft, t)-value(ts.Gt, t), 0)
+	}
+	if surplus >= w.Energy/2 {
+		return "because PV surplus exceeds demand"
+	}
+
+	if len(ts.PN) > 0 && mean(ts.PN, from, to) < mean(ts.PN, 0, len(ts.Dt)) {
+		return "because import prices are low"
+	}
+
+	if t, ok := r.goal(w.Battery, to); ok {
+		return "to reach the charge goal at " + r.clock(r.starts[t+1])
+	}
+
+	return ""
+}
+
+// dischargeReason explains discharging in the intervals [from, to)
+func (r renderer) dischargeReason(w plan.Window, from, to int) string {
+	ts := r.req.TimeSeries
+
+	var export float64
+	for t := from; t < to; t++ {
+		export += value(r.res.GridExport, t)
+	}
+	if export >= w.Energy/2 {
+		return "to export at high remuneration"
+	}
+
+	if len(ts.PN) > 0 && mean(ts.PN, from, to) > mean(ts.PN, 0, len(ts.Dt)) {
+		return "to cover demand at the " + r.clock(r.starts[peak(ts.PN, from, to)]) + " price peak"
+	}
+
+	return "to cover demand"
+}
+
+// hold describes holding the energy charged in w until the next discharge of the battery
+func (r renderer) hold(w plan.Window, later []plan.Window) string {
+	// the next window of the battery, not holding if charging again first
+	i := slices.IndexFunc(later, func(l plan.Window) bool { return l.Battery == w.Battery })
+	if i < 0 || later[i].Kind != plan.Discharge || !later[i].Start.After(w.End) {
+		return ""
+	}
+
+	next := later[i]
+	from, to := r.index(next.Start), r.index(next.End)
+	if pn := r.req.TimeSeries.PN; len(pn) > 0 && mean(pn, from, to) > mean(pn, 0, len(r.req.TimeSeries.Dt)) {
+		return "holding until the " + r.clock(r.starts[peak(pn, from, to)]) + " price peak"
+	}
+
+	return "holding until " + r.clock(next.Start)
+}
+
+// goal returns the first interval from t with a charge goal or departure of the battery
+func (r renderer) goal(battery, t int) (int, bool) {
+	if battery >= len(r.req.Batteries) {
+		return 0, false
+	}
+	b := r.req.Batteries[battery]
+
+	goal := -1
+	for k := t; k < len(b.SGoal) && k < len(r.req.TimeSeries.Dt); k++ {
+		if b.SGoal[k] > 0 {
+			goal = k
+			break
+		}
+	}
+	for _, d := range b.Departures {
+		if d.T >= t && d.T < len(r.req.TimeSeries.Dt) && (goal < 0 || d.T < goal) {
+			goal = d.T
+		}
+	}
+
+	return goal, goal >= 0
+}
+
+func (r renderer) name(battery int) string {
+	if battery < len(r.req.Batteries) && r.req.Batteries[battery].Id != "" {
+		return r.req.Batteries[battery].Id
+	}
+	return fmt.Sprintf("Bat %d", battery)
+}
+
+// index returns the interval starting at ts
+func (r renderer) index(ts time.Time) int {
+	i, _ := slices.BinarySearchFunc(r.starts, ts, time.Time.Compare)
+	return i
+}
+
+// clock formats ts as time of day, with the weekday if not on the day of the plan start
+func (r renderer) clock(ts time.Time) string {
+	if y, m, d := ts.Date(); y != r.start.Year() || m != r.start.Month() || d != r.start.Day() {
+		return ts.Format("Mon 15:04")
+	}
+	return ts.Format("15:04")
+}
+
+func (r renderer) period(from, to time.Time) string {
+	end := to.Format("15:04")
+	if to.Sub(from) >= 24*time.Hour {
+		end = to.Format("Mon 15:04")
+	}
+	return r.clock(from) + "–" + end
+}
+
+// energy formats e [Wh] in kWh with at most one decimal
+func energy(e float64) string {
+	return strconv.FormatFloat(float64(int64(e/100+0.5))/10, 'f', -1, 64) + " kWh"
+}
+
+func value(s []float32, t int) float64 {
+	if t < len(s) {
+		return float64(s[t])
+	}
+	return 0
+}
+
+// mean returns the mean of s in [from, to), extending s by its last value
+func mean(s []float32, from, to int) float64 {
+	if to <= from {
+		return 0
+	}
+	var sum float64
+	for t := from; t < to; t++ {
+		sum += float64(s[min(t, len(s)-1)])
+	}
+	return sum / float64(to-from)
+}
+
+// peak returns the interval of the maximum of s in [from, to)
+func peak(s []float32, from, to int) int {
+	res := from
+	for t := from; t < to; t++ {
+		if s[min(t, len(s)-1)] > s[min(res, len(s)-1)] {
+			res = t
+		}
+	}
+	return res
+}
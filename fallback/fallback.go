@@ -0,0 +1,233 @@
+// Package fallback solves optimization requests locally by simple heuristics, so that control
+// loops keep a plan while the optimizer is unreachable:
+//
+//	c, err := client.New(uri, client.WithFallback(fallback.Greedy()))
+//
+// Heuristic results are feasible but not optimal. They have status Simulated.
+package fallback
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Greedy returns a deterministic heuristic. Surplus generation charges the batteries in order
+// and deficits are discharged from them in intervals priced at or above the median import price.
+// Batteries charging from grid reach their goals by charging in the cheapest intervals before
+// each goal, charge demands are imported if not covered by the surplus. Batteries are not
+// discharged below their upcoming goals. Power limits and bounds of the state of charge are
+// respected, efficiencies, minimum charge powers and strategies are ignored.
+func Greedy() client.Fallback {
+	return func(_ context.Context, req client.OptimizationInput) (client.OptimizationResult, error) {
+		if len(req.TimeSeries.Dt) == 0 {
+			return client.OptimizationResult{}, errors.New("empty horizon")
+		}
+		return greedy(req), nil
+	}
+}
+
+func at[T any](series []T, t int, def T) T {
+	if t < len(series) {
+		return series[t]
+	}
+	return def
+}
+
+// goal is a goal state of charge at the end of interval t
+type goal struct {
+	t   int
+	soc float32
+}
+
+// goals returns the goals of bat ordered by time, departures are goals at their time step
+func goals(bat client.BatteryConfig, n int) []goal {
+	var res []goal
+	for t, v := range bat.SGoal {
+		if v > 0 && t < n {
+			res = append(res, goal{t, v})
+		}
+	}
+	for _, d := range bat.Departures {
+		if d.SGoal > 0 && d.T >= 0 && d.T < n {
+			res = append(res, goal{d.T, d.SGoal})
+		}
+	}
+	slices.SortStableFunc(res, func(a, b goal) int { return a.t - b.t })
+	return res
+}
+
+// battery plans a single battery against the surplus left by the batteries before
+type battery struct {
+	bat       client.BatteryConfig
+	dt        []int
+	surplus   []float32 // surplus generation, negative for deficits [Wh]
+	expensive []bool    // intervals to discharge in
+	floor     []float32 // minimum state of charge when discharging [Wh]
+	grid      []float32 // planned charging from grid [Wh]
+
+	res      client.BatteryResult
+	imported []float32 // charging not covered by the surplus [Wh]
+}
+
+func (b *battery) available(t int) bool {
+	return (b.bat.Enabled == nil || *b.bat.Enabled) && at(b.bat.Available, t, true)
+}
+
+// simulate computes the schedule of the battery and the surplus left
+func (b *battery) simulate() []float32 {
+	n := len(b.dt)
+	left := slices.Clone(b.surplus)
+	b.imported = make([]float32, n)
+	b.res.ChargingPower = make([]float32, n)
+	b.res.DischargingPower = make([]float32, n)
+	b.res.StateOfCharge = make([]float32, n)
+
+	soc := b.bat.SInitial
+	for t, dt := range b.dt {
+		if b.available(t) {
+			h := float32(dt) / 3600
+
+			switch want := max(left[t], at(b.bat.PDemand, t, 0), b.grid[t]); {
+			case want > 0:
+				c := min(want, b.bat.CMax*h, max(0, b.bat.SMax-soc))
+				b.res.ChargingPower[t] = c
+				soc += c
+
+				fromSurplus := min(c, max(0, left[t]))
+				left[t] -= fromSurplus
+				b.imported[t] = c - fromSurplus
+			case left[t] < 0 && b.expensive[t]:
+				d := min(-left[t], b.bat.DMax*h, max(0, soc-b.floor[t]))
+				b.res.DischargingPower[t] = d
+				soc -= d
+				left[t] += d
+			}
+		}
+
+		b.res.StateOfCharge[t] = soc
+	}
+
+	return left
+}
+
+// chargeForGoals plans charging from grid in the cheapest intervals until the goals are reached
+// or no capacity is left
+func (b *battery) chargeForGoals(goals []goal, prices []float32) {
+	for _, g := range goals {
+		for {
+			b.simulate()
+
+			shortfall := g.soc - b.res.StateOfCharge[g.t]
+			if shortfall <= 0 {
+				break
+			}
+
+			// cheapest interval with capacity left, the latest of equal prices
+			best := -1
+			for t := g.t; t >= 0; t-- {
+				if !b.available(t) || b.grid[t] >= b.bat.CMax*float32(b.dt[t])/3600 {
+					continue
+				}
+				if best < 0 || at(prices, t, 0) < at(prices, best, 0) {
+					best = t
+				}
+			}
+			if best < 0 {
+				break
+			}
+
+			limit := b.bat.CMax * float32(b.dt[best]) / 3600
+			// charging is limited by the state of charge, planning more would not progress
+			if b.grid[best]+shortfall >= limit || b.res.ChargingPower[best] < b.grid[best] {
+				b.grid[best] = limit
+			} else {
+				b.grid[best] = max(b.grid[best], b.res.ChargingPower[best]) + shortfall
+			}
+		}
+	}
+}
+
+func greedy(req client.OptimizationInput) client.OptimizationResult {
+	ts := req.TimeSeries
+	n := len(ts.Dt)
+
+	res := client.OptimizationResult{
+		Status:        client.Simulated,
+		Batteries:     make([]client.BatteryResult, len(req.Batteries)),
+		GridImport:    make([]float32, n),
+		GridExport:    make([]float32, n),
+		FlowDirection: make([]client.OptimizationResultFlowDirection, n),
+		ObjectiveUnit: client.Currency,
+	}
+
+	surplus := make([]float32, n)
+	for t := range n {
+		surplus[t] = at(ts.Ft, t, 0) - at(ts.Gt, t, 0)
+	}
+
+	// intervals priced at or above the median import price
+	sorted := slices.Clone(ts.PN)
+	slices.Sort(sorted)
+	median := at(sorted, len(sorted)/2, 0)
+	expensive := make([]bool, n)
+	for t := range n {
+		expensive[t] = at(ts.PN, t, 0) >= median
+	}
+
+	imported := make([]float32, n)
+
+	for i, bat := range req.Batteries {
+		gs := goals(bat, n)
+
+		floor := make([]float32, n)
+		for t := range n {
+			floor[t] = bat.SMin
+			for _, g := range gs {
+				if g.t >= t {
+					floor[t] = max(floor[t], g.soc)
+				}
+			}
+		}
+
+		b := &battery{
+			bat:       bat,
+			dt:        ts.Dt,
+			surplus:   surplus,
+			expensive: expensive,
+			floor:     floor,
+			grid:      make([]float32, n),
+			res:       client.BatteryResult{Id: bat.Id},
+		}
+
+		if bat.ChargeFromGrid {
+			b.chargeForGoals(gs, ts.PN)
+		}
+		surplus = b.simulate()
+
+		for t := range n {
+			imported[t] += b.imported[t]
+		}
+		res.Batteries[i] = b.res
+	}
+
+	for t := range n {
+		switch net := surplus[t] - imported[t]; {
+		case net > 0:
+			res.GridExport[t] = net
+			res.FlowDirection[t] = 1
+		case net < 0:
+			res.GridImport[t] = -net
+		}
+
+		res.ObjectiveValue += res.GridExport[t]*at(ts.PE, t, 0) - res.GridImport[t]*at(ts.PN, t, 0)
+	}
+
+	for i, bat := range req.Batteries {
+		res.ObjectiveValue += at(res.Batteries[i].StateOfCharge, n-1, bat.SInitial) * bat.PA
+	}
+
+	return res
+}
@@ -0,0 +1,143 @@
+// Package fleet aggregates the optimization results of multiple sites into fleet KPIs, e.g. for
+// aggregators reporting to utilities.
+package fleet
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Site is the optimization of a single site.
+type Site struct {
+	Name    string
+	Request client.OptimizationInput
+	Result  client.OptimizationResult
+	// Baseline is an optional reference result, e.g. the simulation of the same request, to
+	// compute savings against.
+	Baseline *client.OptimizationResult
+}
+
+// KPI are the key figures of a site or the fleet.
+type KPI struct {
+	Name           string
+	Capacity       float64 // usable storage capacity s_max - s_min [Wh]
+	ChargePower    float64 // maximum charge power [W]
+	DischargePower float64 // maximum discharge power [W]
+	Throughput     float64 // planned charged and discharged energy [Wh]
+	Import         float64 // planned grid import [Wh]
+	Export         float64 // planned grid export [Wh]
+	PeakImport     float64 // maximum grid import power [W]
+	Objective      float64 // objective value [currency unit]
+	Savings        float64 // objective value over baseline [currency unit]
+}
+
+// Report is the fleet aggregation with per site drill-down.
+type Report struct {
+	Sites []KPI
+	// Total sums the site KPIs. Its peak import is the peak of the aggregated grid import,
+	// which is below the sum of the site peaks if the peaks do not coincide.
+	Total KPI
+	// SumOfPeaks is the sum of the site peak imports [W].
+	SumOfPeaks float64
+}
+
+// ErrMisaligned is returned if sites do not share the same intervals.
+var ErrMisaligned = errors.New("sites have different intervals")
+
+// Aggregate computes the KPIs of all sites and the fleet. Sites must share the same intervals
+// for the aggregate peak. Sites without result, e.g. failed solves, contribute their
+// flexibility only.
+func Aggregate(sites []Site) (Report, error) {
+	var r Report
+	r.Total.Name = "fleet"
+
+	var dt []int
+	var aggregate []float64
+
+	for _, s := range sites {
+		k := KPI{Name: s.Name}
+
+		for _, b := range s.Request.Batteries {
+			if b.Enabled != nil && !*b.Enabled {
+				continue
+			}
+			k.Capacity += float64(b.SMax - b.SMin)
+			k.ChargePower += float64(b.CMax)
+			k.DischargePower += float64(b.DMax)
+		}
+
+		for _, b := range s.Result.Batteries {
+			for t := range b.ChargingPower {
+				k.Throughput += float64(b.ChargingPower[t]) + float64(b.DischargingPower[t])
+			}
+		}
+
+		if len(s.Result.GridImport) > 0 {
+			if dt == nil {
+				dt = s.Request.TimeSeries.Dt
+				aggregate = make([]float64, len(dt))
+			}
+			if !slices.Equal(dt, s.Request.TimeSeries.Dt) || len(s.Result.GridImport) != len(dt) {
+				return Report{}, ErrMisaligned
+			}
+
+			for t, e := range s.Result.GridImport {
+				p := float64(e) * 3600 / float64(dt[t])
+				k.PeakImport = max(k.PeakImport, p)
+				aggregate[t] += p
+				k.Import += float64(e)
+			}
+			for _, e := range s.Result.GridExport {
+				k.Export += float64(e)
+			}
+		}
+
+		k.Objective = float64(s.Result.ObjectiveValue)
+		if s.Baseline != nil {
+			k.Savings = float64(s.Result.ObjectiveValue - s.Baseline.ObjectiveValue)
+		}
+
+		r.Sites = append(r.Sites, k)
+
+		r.Total.Capacity += k.Capacity
+		r.Total.ChargePower += k.ChargePower
+		r.Total.DischargePower += k.DischargePower
+		r.Total.Throughput += k.Throughput
+		r.Total.Import += k.Import
+		r.Total.Export += k.Export
+		r.Total.Objective += k.Objective
+		r.Total.Savings += k.Savings
+		r.SumOfPeaks += k.PeakImport
+	}
+
+	if len(aggregate) > 0 {
+		r.Total.PeakImport = slices.Max(aggregate)
+	}
+
+	return r, nil
+}
+
+// WriteCSV writes the site KPIs followed by the fleet total as CSV.
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	_ = cw.Write([]string{"site", "capacity_wh", "charge_power_w", "discharge_power_w", "throughput_wh",
+		"import_wh", "export_wh", "peak_import_w", "objective", "savings"})
+
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	for _, k := range append(slices.Clone(r.Sites), r.Total) {
+		_ = cw.Write([]string{k.Name, f(k.Capacity), f(k.ChargePower), f(k.DischargePower), f(k.Throughput),
+			f(k.Import), f(k.Export), f(k.PeakImport), f(k.Objective), f(k.Savings)})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,514 @@
+// Package handlers serves the latest plan over HTTP, e.g. for showing the schedule on a local
+// web page:
+//
+//	store := new(handlers.Store)
+//	http.Handle("/plan.json", handlers.PlanJSON(store))
+//	http.Handle("/plan.svg", handlers.PlanChartSVG(store))
+//	http.Handle("/plan/explain", handlers.PlanExplain(store))
+//	http.Handle("/plans/next", handlers.PlanNext(store, time.Minute))
+//	http.Handle("/overrides", handlers.Overrides(scheduler, token, clock.Real))
+//	http.Handle("/revisions", handlers.Revisions(changelog))
+//	http.Handle("/share/", handlers.Share(store, token))
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/explain"
+	"github.com/evcc-io/optimizer/schedule"
+	"github.com/evcc-io/optimizer/storage"
+)
+
+// Plan is an optimization result with the request it was computed for.
+type Plan struct {
+	Start   time.Time // start of the first interval
+	Request client.OptimizationInput
+	Result  client.OptimizationResult
+}
+
+// Source provides the latest plan.
+type Source interface {
+	Latest() (Plan, bool)
+}
+
+// plansLog is the log of the plans recorded by stores of NewStore
+const plansLog = "plans"
+
+// Store keeps the latest plan and its revision, counting the plans set. It is safe for
+// concurrent use.
+type Store struct {
+	mu      sync.RWMutex
+	plan    *Plan
+	rev     uint64
+	changed chan struct{} // closed and replaced by Set
+
+	backend storage.Backend
+	keep    int
+}
+
+// NewStore creates a store recording the plans set in backend, keeping the latest keep or all
+// if keep is 0. The latest recorded plan is restored, revisions continue after restarts.
+func NewStore(ctx context.Context, backend storage.Backend, keep int) (*Store, error) {
+	s := &Store{backend: backend, keep: keep}
+
+	var last *storage.Entry
+	for {
+		entries, err := backend.Read(ctx, plansLog, s.rev, readPage)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			last = &entries[len(entries)-1]
+			s.rev = last.Seq
+		}
+		if len(entries) < readPage {
+			break
+		}
+	}
+
+	if last != nil {
+		var p Plan
+		if err := json.Unmarshal(last.Value, &p); err != nil {
+			return nil, fmt.Errorf("plan %d: %w", last.Seq, err)
+		}
+		s.plan = &p
+	}
+
+	return s, nil
+}
+
+// readPage is the number of plans read at once when restoring
+const readPage = 100
+
+// Set replaces the latest plan. Stores of NewStore record it first and keep the previous plan
+// if recording fails.
+func (s *Store) Set(p Plan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx := context.Background()
+
+	rev := s.rev + 1
+	if s.backend != nil {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if rev, err = s.backend.Append(ctx, plansLog, b); err != nil {
+			return err
+		}
+	}
+
+	s.plan = &p
+	s.rev = rev
+
+	if s.changed != nil {
+		close(s.changed)
+		s.changed = nil
+	}
+
+	if s.backend != nil && s.keep > 0 && rev > uint64(s.keep) {
+		return s.backend.Truncate(ctx, plansLog, rev-uint64(s.keep)+1)
+	}
+
+	return nil
+}
+
+// Next returns the latest plan and its revision once the revision is greater than after,
+// blocking until a plan is set or ctx is done.
+func (s *Store) Next(ctx context.Context, after uint64) (Plan, uint64, error) {
+	for {
+		s.mu.Lock()
+		if s.plan != nil && s.rev > after {
+			p, rev := *s.plan, s.rev
+			s.mu.Unlock()
+			return p, rev, nil
+		}
+		if s.changed == nil {
+			s.changed = make(chan struct{})
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Plan{}, 0, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Latest returns the latest plan, if any.
+func (s *Store) Latest() (Plan, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.plan == nil {
+		return Plan{}, false
+	}
+	return *s.plan, true
+}
+
+// serve handles GET requests for the latest plan, responding 503 until a plan is available
+func serve(src Source, contentType string, render func(Plan) ([]byte, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		p, ok := src.Latest()
+		if !ok {
+			http.Error(w, "no plan available", http.StatusServiceUnavailable)
+			return
+		}
+
+		b, err := render(p)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(b)
+	})
+}
+
+// PlanJSON serves the latest result as JSON, amended by the start and the interval durations.
+func PlanJSON(src Source) http.Handler {
+	return serve(src, "application/json", func(p Plan) ([]byte, error) {
+		return json.Marshal(struct {
+			Start time.Time `json:"start"`
+			Dt    []int     `json:"dt"`
+			client.OptimizationResult
+		}{p.Start, p.Request.TimeSeries.Dt, p.Result})
+	})
+}
+
+// PlanExplain serves the latest plan as JSON array of sentences explaining its charge and
+// discharge windows, see explain.Render.
+func PlanExplain(src Source) http.Handler {
+	return serve(src, "application/json", func(p Plan) ([]byte, error) {
+		return json.Marshal(explain.Render(p.Result, p.Request, p.Start))
+	})
+}
+
+// NextPlan is a plan served by PlanNext.
+type NextPlan struct {
+	Revision uint64    `json:"revision"`
+	Start    time.Time `json:"start"`
+	Dt       []int     `json:"dt"`
+	client.OptimizationResult
+}
+
+// PlanNext serves the plan following the revision given by the after query parameter as JSON
+// like PlanJSON, amended by its revision. Requests are held until a newer plan is set, allowing
+// clients without push support to follow plan updates by long polling. If there is no newer
+// plan within timeout, the response is 204 No Content and the client repeats the request.
+// Without after the latest plan is returned, waiting for the first plan if there is none.
+func PlanNext(store *Store, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		var after uint64
+		if v := r.URL.Query().Get("after"); v != "" {
+			var err error
+			if after, err = strconv.ParseUint(v, 10, 64); err != nil {
+				http.Error(w, "invalid revision", http.StatusBadRequest)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		w.Header().Set("Cache-Control", "no-store")
+
+		p, rev, err := store.Next(ctx, after)
+		if err != nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		b, err := json.Marshal(NextPlan{rev, p.Start, p.Request.TimeSeries.Dt, p.Result})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	})
+}
+
+// WaitNext requests the plan following revision after from a PlanNext handler at url, blocking
+// until it is available. Responses without a newer plan are repeated until ctx is done. The
+// revision of the returned plan is the after of the following call. A nil hc uses
+// http.DefaultClient.
+func WaitNext(ctx context.Context, hc *http.Client, url string, after uint64) (*NextPlan, error) {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s?after=%d", url, after), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := hc.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			var p NextPlan
+			err := json.NewDecoder(resp.Body).Decode(&p)
+			resp.Body.Close()
+			return &p, err
+
+		case http.StatusNoContent:
+			resp.Body.Close()
+
+		default:
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+	}
+}
+
+// Injector applies overrides to the executing plan, e.g. a schedule.Scheduler.
+type Injector interface {
+	Inject(ctx context.Context, o schedule.Override) (schedule.Schedule, error)
+	Overrides() []schedule.Override
+}
+
+// OverrideRequest is an override posted to Overrides. The end is given by until or by the
+// duration from the start, e.g. 2h.
+type OverrideRequest struct {
+	schedule.Override
+	Duration string `json:"duration,omitempty"`
+}
+
+// RequireToken serves requests authorized by the bearer token with next and responds 401
+// Unauthorized otherwise. An empty token authorizes no request.
+func RequireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Overrides serves the active overrides as JSON on GET and injects an override on POST,
+// responding with the adjusted plan as JSON like PlanJSON, e.g. capping the grid import to 8 kW
+// for the next 2 hours:
+//
+//	curl -H "Authorization: Bearer $TOKEN" -d '{"max_import":8000,"duration":"2h"}' http://localhost:8080/overrides
+//
+// Overrides change what the controller commands, requests must carry token as bearer token,
+// see RequireToken. Serve it on a local network only, never expose it publicly. Durations start
+// now by clk unless from is given.
+func Overrides(inj Injector, token string, clk clock.Clock) http.Handler {
+	return RequireToken(token, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v any
+
+		switch r.Method {
+		case http.MethodGet:
+			overrides := inj.Overrides()
+			if overrides == nil {
+				overrides = []schedule.Override{}
+			}
+			v = overrides
+
+		case http.MethodPost:
+			var o OverrideRequest
+			if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if o.Duration != "" {
+				d, err := time.ParseDuration(o.Duration)
+				if err != nil || !o.Until.IsZero() {
+					http.Error(w, "invalid duration, expected e.g. 2h without until", http.StatusBadRequest)
+					return
+				}
+				o.Until = o.From
+				if o.Until.IsZero() {
+					o.Until = clk.Now()
+				}
+				o.Until = o.Until.Add(d)
+			}
+
+			sched, err := inj.Inject(r.Context(), o.Override)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+
+			v = struct {
+				Start time.Time `json:"start"`
+				Dt    []int     `json:"dt"`
+				client.OptimizationResult
+			}{sched.Start, sched.Request.TimeSeries.Dt, sched.Result}
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(b)
+	}))
+}
+
+const (
+	chartWidth  = 800
+	chartHeight = 300
+	chartMargin = 40
+)
+
+var chartColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd"}
+
+// PlanChartSVG serves the latest plan as SVG chart of the grid import and export power as bars
+// and the state of charge of each battery in percent of its maximum as lines.
+func PlanChartSVG(src Source) http.Handler {
+	return serve(src, "image/svg+xml", func(p Plan) ([]byte, error) {
+		return chartSVG(p), nil
+	})
+}
+
+// chartSVG renders the plan, the time axis is proportional to the interval durations
+func chartSVG(p Plan) []byte {
+	dt := p.Request.TimeSeries.Dt
+	res := p.Result
+
+	var total int
+	for _, d := range dt {
+		total += d
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		chartWidth, chartHeight)
+
+	if total == 0 {
+		b.WriteString("</svg>\n")
+		return b.Bytes()
+	}
+
+	plotW := float64(chartWidth - 2*chartMargin)
+	plotH := float64(chartHeight - 2*chartMargin)
+	mid := chartMargin + plotH/2
+
+	x := func(seconds int) float64 {
+		return chartMargin + plotW*float64(seconds)/float64(total)
+	}
+
+	// grid power scaled to the largest import or export, drawn around the middle axis
+	power := func(series []float32, t int) float64 {
+		if t >= len(series) {
+			return 0
+		}
+		return float64(series[t]) * 3600 / float64(dt[t])
+	}
+
+	var peak float64
+	for t := range dt {
+		peak = max(peak, power(res.GridImport, t), power(res.GridExport, t))
+	}
+
+	var elapsed int
+	for t, d := range dt {
+		x0, w := x(elapsed), x(elapsed+d)-x(elapsed)
+		if peak > 0 {
+			if h := power(res.GridImport, t) / peak * plotH / 2; h > 0 {
+				fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="#d0d0d0"/>`+"\n", x0, mid-h, w, h)
+			}
+			if h := power(res.GridExport, t) / peak * plotH / 2; h > 0 {
+				fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="#a8d8a8"/>`+"\n", x0, mid, w, h)
+			}
+		}
+		elapsed += d
+	}
+
+	fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#808080"/>`+"\n", chartMargin, mid, chartWidth-chartMargin, mid)
+	fmt.Fprintf(&b, `<text x="%d" y="%d">import %.0f W</text>`+"\n", chartMargin, chartMargin-8, peak)
+	fmt.Fprintf(&b, `<text x="%d" y="%d">export</text>`+"\n", chartMargin, chartHeight-chartMargin+14)
+
+	// state of charge at the end of each interval
+	for i, bat := range res.Batteries {
+		if i >= len(p.Request.Batteries) || p.Request.Batteries[i].SMax <= 0 || len(bat.StateOfCharge) == 0 {
+			continue
+		}
+		sMax := float64(p.Request.Batteries[i].SMax)
+		color := chartColors[i%len(chartColors)]
+
+		points := []string{fmt.Sprintf("%.1f,%.1f", x(0), chartMargin+plotH*(1-float64(p.Request.Batteries[i].SInitial)/sMax))}
+		elapsed := 0
+		for t, s := range bat.StateOfCharge {
+			if t >= len(dt) {
+				break
+			}
+			elapsed += dt[t]
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(elapsed), chartMargin+plotH*(1-float64(s)/sMax)))
+		}
+
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(points, " "), color)
+
+		name := bat.Id
+		if name == "" {
+			name = fmt.Sprintf("Battery %d", i+1)
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="%s" text-anchor="end">%s</text>`+"\n",
+			chartWidth-chartMargin-120*i, chartMargin-8, color, html.EscapeString(name))
+	}
+
+	// time axis labels at start, middle and end
+	for _, s := range []int{0, total / 2, total} {
+		anchor := "middle"
+		switch s {
+		case 0:
+			anchor = "start"
+		case total:
+			anchor = "end"
+		}
+		label := p.Start.Add(time.Duration(s) * time.Second).Format("Jan 2 15:04")
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="%s">%s</text>`+"\n", x(s), chartHeight-8, anchor, label)
+	}
+
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
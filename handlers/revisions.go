@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/evcc-io/optimizer/changelog"
+)
+
+// Changelog lists recorded plan revisions, e.g. a changelog.Log.
+type Changelog interface {
+	List(ctx context.Context, since time.Time) ([]changelog.Revision, error)
+}
+
+// Revisions serves the plan revisions recorded at or after the RFC 3339 time of the since query
+// parameter as JSON array, all revisions without, e.g. for telling why the plan changed:
+//
+//	curl http://localhost:8080/revisions?since=2025-10-01T14:00:00Z
+func Revisions(cl Changelog) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var since time.Time
+		if v := r.URL.Query().Get("since"); v != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339, v); err != nil {
+				http.Error(w, "invalid since, expected RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+		}
+
+		revs, err := cl.List(r.Context(), since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if revs == nil {
+			revs = []changelog.Revision{}
+		}
+
+		b, err := json.Marshal(revs)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(b)
+	})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+)
+
+// SharedPlan is a plan redacted for public sharing. Consumption, generation, grid exchange,
+// costs and battery sizes are removed, battery power and state of charge are relative.
+type SharedPlan struct {
+	Start       time.Time       `json:"start"`
+	Dt          []int           `json:"dt"`
+	PriceImport []float32       `json:"price_import,omitempty"` // [currency unit/Wh]
+	PriceExport []float32       `json:"price_export,omitempty"` // [currency unit/Wh]
+	Batteries   []SharedBattery `json:"batteries"`
+}
+
+// SharedBattery is the redacted schedule of a battery.
+type SharedBattery struct {
+	Name string `json:"name"`
+	// Power is the net charging power relative to the maximum charge power, or the discharging
+	// power relative to the maximum discharge power as negative value (-1 to 1).
+	Power []float32 `json:"power"`
+	// SoC is the state of charge at the end of each interval relative to the maximum (0 to 1).
+	SoC []float32 `json:"soc"`
+}
+
+// Redact returns the plan redacted for public sharing. Batteries are named by their position,
+// as ids may identify devices or persons.
+func Redact(p Plan) SharedPlan {
+	dt := p.Request.TimeSeries.Dt
+	sp := SharedPlan{
+		Start:       p.Start,
+		Dt:          dt,
+		PriceImport: p.Request.TimeSeries.PN,
+		PriceExport: p.Request.TimeSeries.PE,
+		Batteries:   make([]SharedBattery, 0, len(p.Result.Batteries)),
+	}
+
+	for i, b := range p.Result.Batteries {
+		if i >= len(p.Request.Batteries) {
+			break
+		}
+		cfg := p.Request.Batteries[i]
+
+		sb := SharedBattery{
+			Name:  fmt.Sprintf("Battery %d", i+1),
+			Power: make([]float32, len(dt)),
+			SoC:   make([]float32, 0, len(b.StateOfCharge)),
+		}
+
+		for t, d := range dt {
+			var net float32
+			if t < len(b.ChargingPower) {
+				net += b.ChargingPower[t]
+			}
+			if t < len(b.DischargingPower) {
+				net -= b.DischargingPower[t]
+			}
+			// energy per interval to power
+			net *= 3600 / float32(d)
+
+			switch {
+			case net > 0 && cfg.CMax > 0:
+				sb.Power[t] = min(net/cfg.CMax, 1)
+			case net < 0 && cfg.DMax > 0:
+				sb.Power[t] = max(net/cfg.DMax, -1)
+			}
+		}
+
+		if cfg.SMax > 0 {
+			for _, s := range b.StateOfCharge {
+				sb.SoC = append(sb.SoC, min(max(s/cfg.SMax, 0), 1))
+			}
+		}
+
+		sp.Batteries = append(sp.Batteries, sb)
+	}
+
+	return sp
+}
+
+// NewShareToken returns a random token for Share.
+func NewShareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Share serves the latest plan redacted for public sharing as JSON at any path ending with
+// token, e.g. to show the schedule in a forum without exposing the server:
+//
+//	token, err := handlers.NewShareToken()
+//	http.Handle("/share/", handlers.Share(store, token))
+//
+// Other paths respond 404 Not Found. The plan may be read from any origin and is not indexed
+// by search engines.
+func Share(src Source, token string) http.Handler {
+	plan := serve(src, "application/json", func(p Plan) ([]byte, error) {
+		return json.Marshal(Redact(p))
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(path.Base(r.URL.Path)), []byte(token)) != 1 {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("X-Robots-Tag", "noindex")
+		plan.ServeHTTP(w, r)
+	})
+}
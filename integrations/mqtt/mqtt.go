@@ -0,0 +1,220 @@
+// Package mqtt publishes optimized schedules to an MQTT broker, so that Home Assistant, Node-RED
+// or other MQTT consumers can follow the plan without a custom bridge:
+//
+//	p, err := mqtt.New(mqtt.Config{Broker: "tcp://localhost:1883"})
+//	go p.Run(ctx, scheduler.Updates())
+//
+// Each schedule publishes the setpoints of its current interval and the whole plan. Messages
+// are retained, so consumers connecting later receive the latest schedule.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/evcc-io/optimizer/plan"
+	"github.com/evcc-io/optimizer/schedule"
+)
+
+// Topics are the topics published. Battery topics contain %s, replaced by the battery id or,
+// without id, its index. Empty topics are not published.
+type Topics struct {
+	ChargePower string // power of the current interval, positive for charging, negative for discharging [W]
+	SocTarget   string // state of charge at the end of the current interval [Wh]
+	ImportLimit string // planned grid import power of the current interval as import limit [W]
+	Schedule    string // setpoints of the whole plan as JSON, see plan.Setpoints
+}
+
+// DefaultTopics returns the topics below prefix.
+func DefaultTopics(prefix string) Topics {
+	return Topics{
+		ChargePower: prefix + "/battery/%s/charge_power",
+		SocTarget:   prefix + "/battery/%s/soc_target",
+		ImportLimit: prefix + "/grid/import_limit",
+		Schedule:    prefix + "/schedule",
+	}
+}
+
+// Config configures the broker connection and topics.
+type Config struct {
+	Broker   string // e.g. tcp://localhost:1883
+	ClientID string // defaults to evopt
+	User     string
+	Password string
+	// Topics defaults to DefaultTopics("evopt") if zero.
+	Topics Topics
+	// QoS of published messages, 0 to 2.
+	QoS byte
+}
+
+// Publisher publishes schedules.
+type Publisher struct {
+	client paho.Client
+	topics Topics
+	qos    byte
+	now    func() time.Time
+}
+
+// timeout of connecting and publishing
+const timeout = 10 * time.Second
+
+// New connects to the broker. The connection is re-established automatically if lost.
+func New(cfg Config) (*Publisher, error) {
+	if cfg.QoS > 2 {
+		return nil, fmt.Errorf("invalid qos %d", cfg.QoS)
+	}
+
+	if cfg.ClientID == "" {
+		cfg.ClientID = "evopt"
+	}
+	if cfg.Topics == (Topics{}) {
+		cfg.Topics = DefaultTopics("evopt")
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.User).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectTimeout(timeout)
+
+	c := paho.NewClient(opts)
+	if err := wait(c.Connect()); err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	return &Publisher{client: c, topics: cfg.Topics, qos: cfg.QoS, now: time.Now}, nil
+}
+
+// wait waits for the token to complete
+func wait(token paho.Token) error {
+	if !token.WaitTimeout(timeout) {
+		return fmt.Errorf("timeout after %v", timeout)
+	}
+	return token.Error()
+}
+
+// Close disconnects from the broker.
+func (p *Publisher) Close() {
+	p.client.Disconnect(250)
+}
+
+// Run publishes the schedules received from updates, e.g. Scheduler.Updates, until ctx is
+// cancelled. Failed publishes are retried with the next schedule.
+func (p *Publisher) Run(ctx context.Context, updates <-chan schedule.Schedule) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case sched := <-updates:
+			_ = p.Publish(sched)
+		}
+	}
+}
+
+// Publish publishes the setpoints of the interval of sched containing the current time and
+// the whole plan.
+func (p *Publisher) Publish(sched schedule.Schedule) error {
+	msgs, err := messages(p.topics, sched, p.now())
+	if err != nil {
+		return err
+	}
+
+	for _, m := range msgs {
+		if err := wait(p.client.Publish(m.topic, p.qos, true, m.payload)); err != nil {
+			return fmt.Errorf("publish %s: %w", m.topic, err)
+		}
+	}
+
+	return nil
+}
+
+type message struct {
+	topic   string
+	payload string
+}
+
+// messages returns the messages of sched at now. Setpoints of the current interval are omitted
+// outside the planned horizon.
+func messages(topics Topics, sched schedule.Schedule, now time.Time) ([]message, error) {
+	var msgs []message
+
+	add := func(topic string, value float64) {
+		if topic != "" {
+			value = math.Round(value)
+			if value == 0 {
+				value = 0 // no negative zero
+			}
+			msgs = append(msgs, message{topic, strconv.FormatFloat(value, 'f', -1, 64)})
+		}
+	}
+
+	req, res := sched.Request, sched.Result
+
+	// current interval
+	t, from := -1, sched.Start
+	if !now.Before(sched.Start) {
+		for k, d := range req.TimeSeries.Dt {
+			to := from.Add(time.Duration(d) * time.Second)
+			if now.Before(to) {
+				t = k
+				break
+			}
+			from = to
+		}
+	}
+
+	if t >= 0 {
+		hours := float64(req.TimeSeries.Dt[t]) / 3600
+
+		if t < len(res.GridImport) {
+			add(topics.ImportLimit, float64(res.GridImport[t])/hours)
+		}
+
+		for i, b := range res.Batteries {
+			name := b.Id
+			if name == "" {
+				name = strconv.Itoa(i)
+			}
+
+			var power float64
+			if t < len(b.ChargingPower) {
+				power += float64(b.ChargingPower[t])
+			}
+			if t < len(b.DischargingPower) {
+				power -= float64(b.DischargingPower[t])
+			}
+
+			add(batteryTopic(topics.ChargePower, name), power/hours)
+			if t < len(b.StateOfCharge) {
+				add(batteryTopic(topics.SocTarget, name), float64(b.StateOfCharge[t]))
+			}
+		}
+	}
+
+	if topics.Schedule != "" {
+		b, err := json.Marshal(plan.Setpoints(req, res, sched.Start))
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, message{topics.Schedule, string(b)})
+	}
+
+	return msgs, nil
+}
+
+// batteryTopic replaces the battery placeholder of topic by name
+func batteryTopic(topic, name string) string {
+	if topic == "" {
+		return ""
+	}
+	return strings.Replace(topic, "%s", name, 1)
+}
@@ -0,0 +1,345 @@
+// Package notify sends plan summaries and alerts of a site to messaging services, Telegram,
+// Pushover or ntfy, so that users are informed without writing glue scripts:
+//
+//	n := notify.New("home", []notify.Sender{notify.Ntfy{Topic: "evopt-home"}})
+//	scheduler := schedule.New(n.Solver(c), source)
+//	go scheduler.Run(ctx)
+//	err := n.Run(ctx, scheduler.Updates())
+//
+// A summary of the plan for the next day is sent daily. Alerts are sent when optimizations start
+// failing, e.g. for infeasible problems, and when the plans executed over a day miss the savings
+// expected by its summary by a large margin. A notifier serves a single site, sites with
+// different settings use notifiers of their own.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/evcc-io/optimizer/analysis"
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/schedule"
+)
+
+// Message is a notification.
+type Message struct {
+	Title string
+	Body  string
+	// Alert marks messages requiring attention, sent with priority.
+	Alert bool
+}
+
+// Sender sends messages to a messaging service.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Notifier sends the notifications of a site. It is safe for concurrent use.
+type Notifier struct {
+	site      string
+	senders   []Sender
+	summaryAt time.Duration
+	miss      float64
+	loc       *time.Location
+	clock     clock.Clock
+	logger    *slog.Logger
+
+	failing atomic.Bool
+}
+
+// Option configures a notifier.
+type Option func(*Notifier)
+
+// WithSummaryAt sets the time of day of the daily summary as duration since midnight. Default
+// is 7:00.
+func WithSummaryAt(d time.Duration) Option {
+	return func(n *Notifier) {
+		n.summaryAt = d
+	}
+}
+
+// WithSavingsMiss alerts if the plans executed over a day save less than fraction of the
+// savings expected by its summary, zero disables the alert. Default is 0.5.
+func WithSavingsMiss(fraction float64) Option {
+	return func(n *Notifier) {
+		n.miss = fraction
+	}
+}
+
+// WithLocation sets the time zone of the summary time and of the times in messages. Defaults
+// to time.Local.
+func WithLocation(loc *time.Location) Option {
+	return func(n *Notifier) {
+		n.loc = loc
+	}
+}
+
+// WithClock sets the clock. Defaults to the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(n *Notifier) {
+		n.clock = clk
+	}
+}
+
+// WithLogger sets the logger for failed notifications. Defaults to slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(n *Notifier) {
+		n.logger = logger
+	}
+}
+
+// New creates a notifier of site sending to senders.
+func New(site string, senders []Sender, opts ...Option) *Notifier {
+	n := &Notifier{
+		site:      site,
+		senders:   senders,
+		summaryAt: 7 * time.Hour,
+		miss:      0.5,
+		loc:       time.Local,
+		clock:     clock.Real,
+		logger:    slog.Default(),
+	}
+
+	for _, o := range opts {
+		o(n)
+	}
+
+	return n
+}
+
+// Notify sends msg to all senders with the site prefixed to its title. A failing sender does
+// not keep the others from sending.
+func (n *Notifier) Notify(ctx context.Context, msg Message) error {
+	msg.Title = n.site + ": " + msg.Title
+
+	var errs []error
+	for _, s := range n.senders {
+		if err := s.Send(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) notify(ctx context.Context, msg Message) {
+	if err := n.Notify(ctx, msg); err != nil {
+		n.logger.Error("notification failed", "site", n.site, "title", msg.Title, "error", err)
+	}
+}
+
+// Solver wraps solver for the scheduler, alerting once when optimizations start failing or are
+// not solved to optimality, e.g. infeasible, and once they succeed again. Simulated results of
+// a client fallback count as success.
+func (n *Notifier) Solver(solver schedule.Solver) schedule.Solver {
+	return alertSolver{Solver: solver, n: n}
+}
+
+type alertSolver struct {
+	schedule.Solver
+	n *Notifier
+}
+
+func (s alertSolver) Solve(ctx context.Context, req client.OptimizationInput, reqEditors ...client.RequestEditorFn) (*client.OptimizationResult, error) {
+	res, err := s.Solver.Solve(ctx, req, reqEditors...)
+	if ctx.Err() != nil {
+		return res, err
+	}
+
+	var cause string
+	switch {
+	case err != nil:
+		cause = err.Error()
+	case res.Status != client.Optimal && res.Status != client.Simulated:
+		cause = "status " + string(res.Status)
+	}
+
+	if failing := cause != ""; s.n.failing.Swap(failing) != failing {
+		if failing {
+			s.n.notify(ctx, Message{
+				Title: "optimization failed",
+				Body:  cause + "\nThe current plan is followed until it elapses.",
+				Alert: true,
+			})
+		} else {
+			s.n.notify(ctx, Message{Title: "optimization recovered", Body: "Plans are updated again."})
+		}
+	}
+
+	return res, err
+}
+
+// Run sends the daily summary of the latest schedule received from updates, e.g.
+// Scheduler.Updates, until ctx is cancelled. The savings of each schedule are accounted from
+// its arrival until the next arrives, and checked against the summary of the day.
+func (n *Notifier) Run(ctx context.Context, updates <-chan schedule.Schedule) error {
+	var (
+		current *schedule.Schedule
+		since   time.Time // start of the execution of current
+		summary *day      // day of the last summary, nil if none was sent
+	)
+
+	next := n.nextSummary(n.clock.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case sched := <-updates:
+			now := n.clock.Now()
+			if current != nil && summary != nil {
+				summary.execute(*current, since, now)
+			}
+			current, since = &sched, now
+
+		case <-n.clock.After(next.Sub(n.clock.Now())):
+			now := n.clock.Now()
+
+			if summary != nil {
+				if current != nil {
+					summary.execute(*current, since, now)
+				}
+				if msg, ok := n.missed(*summary); ok {
+					n.notify(ctx, msg)
+				}
+				summary = nil
+			}
+
+			if current != nil {
+				since = now
+
+				t, err := Summarize(*current, now, now.Add(24*time.Hour))
+				switch {
+				case err != nil:
+					n.logger.Error("summary failed", "site", n.site, "error", err)
+				case !t.Until.IsZero():
+					summary = &day{from: now, to: t.Until, expected: t.Savings}
+					n.notify(ctx, n.summary(now, t))
+				}
+			}
+
+			next = n.nextSummary(now)
+		}
+	}
+}
+
+// nextSummary returns the first summary time after now
+func (n *Notifier) nextSummary(now time.Time) time.Time {
+	local := now.In(n.loc)
+	for d := 0; ; d++ {
+		// normalized as wall clock time, independent of daylight saving time changes
+		at := time.Date(local.Year(), local.Month(), local.Day()+d, 0, 0, int(n.summaryAt/time.Second), 0, n.loc)
+		if at.After(now) {
+			return at
+		}
+	}
+}
+
+func (n *Notifier) summary(from time.Time, t Totals) Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan from %s to %s\n", from.In(n.loc).Format("Mon 15:04"), t.Until.In(n.loc).Format("Mon 15:04"))
+	fmt.Fprintf(&b, "Cost %.2f, saving %.2f over no battery\n", t.Cost, t.Savings)
+	fmt.Fprintf(&b, "Grid import %.1f kWh, export %.1f kWh\n", t.Import/1e3, t.Export/1e3)
+	fmt.Fprintf(&b, "Battery charge %.1f kWh, discharge %.1f kWh", t.Charge/1e3, t.Discharge/1e3)
+
+	return Message{Title: "daily plan", Body: b.String()}
+}
+
+// missed returns the alert if the executed savings of d missed its expected savings
+func (n *Notifier) missed(d day) (Message, bool) {
+	if n.miss <= 0 || d.expected <= 0 || d.executed >= d.expected*(1-n.miss) {
+		return Message{}, false
+	}
+
+	return Message{
+		Title: "savings missed",
+		Body: fmt.Sprintf("Plans from %s to %s saved %.2f, the summary expected %.2f.",
+			d.from.In(n.loc).Format("Mon 15:04"), d.to.In(n.loc).Format("Mon 15:04"), d.executed, d.expected),
+		Alert: true,
+	}, true
+}
+
+// day is the period of a summary with its expected savings and those of the plans executed
+type day struct {
+	from, to           time.Time
+	expected, executed float64
+}
+
+// execute accounts the savings of sched executed from from to to within the day
+func (d *day) execute(sched schedule.Schedule, from, to time.Time) {
+	if t, err := Summarize(sched, later(from, d.from), earlier(to, d.to)); err == nil {
+		d.executed += t.Savings
+	}
+}
+
+// Totals are the planned energies and costs of a period.
+type Totals struct {
+	// Until is the end of the period covered by the plan, zero if it covers none.
+	Until     time.Time
+	Import    float64 // grid import [Wh]
+	Export    float64 // grid export [Wh]
+	Charge    float64 // battery charge [Wh]
+	Discharge float64 // battery discharge [Wh]
+	// Cost is the import cost less the export revenue [currency unit].
+	Cost float64
+	// Savings is the net benefit over the plan without batteries, see analysis.Breakdown
+	// [currency unit].
+	Savings float64
+}
+
+// Summarize returns the totals of sched from from to to. Intervals partially within the period
+// count in proportion.
+func Summarize(sched schedule.Schedule, from, to time.Time) (Totals, error) {
+	b, err := analysis.Analyze(sched.Request, sched.Result)
+	if err != nil {
+		return Totals{}, err
+	}
+
+	var t Totals
+
+	start := sched.Start
+	for k, iv := range b.Intervals {
+		end := start.Add(time.Duration(sched.Request.TimeSeries.Dt[k]) * time.Second)
+
+		if overlap := earlier(end, to).Sub(later(start, from)); overlap > 0 {
+			f := overlap.Seconds() / end.Sub(start).Seconds()
+
+			t.Import += f * iv.Import
+			t.Export += f * iv.Export
+			t.Cost += f * (iv.ImportCost - iv.ExportRevenue)
+			t.Savings += f * iv.Savings()
+			for _, bat := range sched.Result.Batteries {
+				if k < len(bat.ChargingPower) && k < len(bat.DischargingPower) {
+					t.Charge += f * float64(bat.ChargingPower[k])
+					t.Discharge += f * float64(bat.DischargingPower[k])
+				}
+			}
+			t.Until = earlier(end, to)
+		}
+
+		start = end
+	}
+
+	return t, nil
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// timeout of sending a message
+const timeout = 30 * time.Second
+
+// Telegram sends messages to a chat by a bot.
+type Telegram struct {
+	Token  string // bot token
+	ChatID string
+	// URL of the bot API, defaults to https://api.telegram.org.
+	URL string
+}
+
+// Send sends msg, alerts with notification.
+func (t Telegram) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string]any{
+		"chat_id":              t.ChatID,
+		"text":                 msg.Title + "\n\n" + msg.Body,
+		"disable_notification": !msg.Alert,
+	})
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(cmp.Or(t.URL, "https://api.telegram.org"), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/bot"+t.Token+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return send("telegram", req)
+}
+
+// Pushover sends messages to a user or group of the Pushover service.
+type Pushover struct {
+	Token string // application token
+	User  string // user or group key
+	// URL of the messages API, defaults to https://api.pushover.net/1/messages.json.
+	URL string
+}
+
+// Send sends msg, alerts with high priority.
+func (p Pushover) Send(ctx context.Context, msg Message) error {
+	priority := "0"
+	if msg.Alert {
+		priority = "1"
+	}
+
+	form := url.Values{
+		"token":    {p.Token},
+		"user":     {p.User},
+		"title":    {msg.Title},
+		"message":  {msg.Body},
+		"priority": {priority},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cmp.Or(p.URL, "https://api.pushover.net/1/messages.json"),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return send("pushover", req)
+}
+
+// Ntfy publishes messages to a topic of an ntfy server.
+type Ntfy struct {
+	Topic string
+	// Token is the access token of protected topics, optional.
+	Token string
+	// URL of the server, defaults to https://ntfy.sh.
+	URL string
+}
+
+// Send sends msg, alerts with high priority.
+func (n Ntfy) Send(ctx context.Context, msg Message) error {
+	base := strings.TrimSuffix(cmp.Or(n.URL, "https://ntfy.sh"), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/"+url.PathEscape(n.Topic), strings.NewReader(msg.Body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Title", msg.Title)
+	if msg.Alert {
+		req.Header.Set("Priority", "high")
+	}
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	return send("ntfy", req)
+}
+
+// send sends req and fails for non-2xx responses
+func send(service string, req *http.Request) error {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%s: %w", service, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("%s: unexpected status %d: %s", service, resp.StatusCode, bytes.TrimSpace(b))
+	}
+
+	return nil
+}
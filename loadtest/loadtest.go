@@ -0,0 +1,218 @@
+// Package loadtest sends generated problems to the optimizer service and reports latency
+// percentiles, outcome classes and throughput, e.g. for evopt stress and evopt-bench:
+//
+//	gen := func(r *rand.Rand) client.OptimizationInput { return problem.Random(r, problem.Mixed) }
+//	results := loadtest.Concurrent(ctx, c, gen, seed, 8, time.Minute)
+//	loadtest.Report(os.Stdout, results, time.Since(start))
+//
+// Requests are not cancelled with ctx, requests in flight at the end complete within their
+// timeout like under production load.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/olekukonko/tablewriter"
+	"github.com/samber/lo"
+)
+
+// OK is the class of successful requests.
+const OK = "ok"
+
+// Result is the outcome of a request.
+type Result struct {
+	Latency time.Duration
+	// Class is OK, timeout, transport, malformed, 4xx, 5xx or another status code.
+	Class  string
+	Detail string
+}
+
+// Generate returns the next problem to send using r.
+type Generate func(r *rand.Rand) client.OptimizationInput
+
+// Rate sends problems of gen at rps requests per second until ctx is done.
+func Rate(ctx context.Context, c *client.ClientWithResponses, gen Generate, seed uint64, rps float64, timeout time.Duration) []Result {
+	r := rand.New(rand.NewPCG(seed, 0))
+	tick := time.NewTicker(time.Duration(float64(time.Second) / rps))
+	defer tick.Stop()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results []Result
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return results
+
+		case <-tick.C:
+			req := gen(r)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res := Solve(c, req, timeout)
+				mu.Lock()
+				results = append(results, res)
+				mu.Unlock()
+			}()
+		}
+	}
+}
+
+// Concurrent sends problems of gen by n workers until ctx is done. Each worker waits for its
+// response before sending the next request, n is the number of requests in flight.
+func Concurrent(ctx context.Context, c *client.ClientWithResponses, gen Generate, seed uint64, n int, timeout time.Duration) []Result {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results []Result
+	)
+
+	for w := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// the random source is not safe for concurrent use
+			r := rand.New(rand.NewPCG(seed, uint64(w)))
+
+			for ctx.Err() == nil {
+				res := Solve(c, gen(r), timeout)
+				mu.Lock()
+				results = append(results, res)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+// Solve solves req and classifies the outcome.
+func Solve(c *client.ClientWithResponses, req client.OptimizationInput, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := c.PostOptimizeChargeScheduleWithResponse(ctx, req)
+	res := Result{Latency: time.Since(start)}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err):
+		res.Class = "timeout"
+	case err != nil:
+		// includes bodies the client failed to decode and connections closed by killed workers
+		res.Class, res.Detail = "transport", err.Error()
+	case resp.StatusCode() == http.StatusOK:
+		if err := check(req, resp.JSON200); err != nil {
+			res.Class, res.Detail = "malformed", err.Error()
+		} else {
+			res.Class = OK
+		}
+	case resp.StatusCode() == http.StatusBadRequest:
+		// generated problems are valid, a 400 is a server defect
+		res.Class, res.Detail = "4xx", string(resp.Body)
+	case resp.StatusCode() >= 500:
+		// gunicorn answers 502 or 504 through proxies for timed out workers
+		res.Class, res.Detail = "5xx", string(resp.Body)
+	default:
+		res.Class, res.Detail = strconv.Itoa(resp.StatusCode()), string(resp.Body)
+	}
+
+	return res
+}
+
+// check validates the shape of a successful response
+func check(req client.OptimizationInput, res *client.OptimizationResult) error {
+	if res == nil {
+		return errors.New("missing response body")
+	}
+	if res.Status != client.Optimal {
+		return fmt.Errorf("status %s", res.Status)
+	}
+
+	n := len(req.TimeSeries.Dt)
+	for name, l := range map[string]int{"grid_import": len(res.GridImport), "grid_export": len(res.GridExport), "flow_direction": len(res.FlowDirection)} {
+		if l != n {
+			return fmt.Errorf("%s has %d values, expected %d", name, l, n)
+		}
+	}
+
+	if len(res.Batteries) != len(req.Batteries) {
+		return fmt.Errorf("%d batteries, expected %d", len(res.Batteries), len(req.Batteries))
+	}
+
+	for i, b := range res.Batteries {
+		if len(b.ChargingPower) != n || len(b.DischargingPower) != n || len(b.StateOfCharge) != n {
+			return fmt.Errorf("batteries.%d: series length mismatch", i)
+		}
+	}
+
+	return nil
+}
+
+// Failed returns whether any request failed.
+func Failed(results []Result) bool {
+	return lo.ContainsBy(results, func(r Result) bool { return r.Class != OK })
+}
+
+// Report writes the throughput, the latency percentiles of successful requests, the rate of
+// each class and the first failure of each class to w.
+func Report(w io.Writer, results []Result, elapsed time.Duration) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "no requests completed")
+		return
+	}
+
+	fmt.Fprintf(w, "\n%d requests in %v, %.2f requests/s\n\n", len(results), elapsed.Round(time.Second),
+		float64(len(results))/elapsed.Seconds())
+
+	// failures are mostly timeouts or immediate errors
+	latencies := lo.FilterMap(results, func(r Result, _ int) (time.Duration, bool) { return r.Latency, r.Class == OK })
+	slices.Sort(latencies)
+
+	if len(latencies) > 0 {
+		percentile := func(p float64) string {
+			i := min(len(latencies)-1, int(p*float64(len(latencies))))
+			return latencies[i].Round(time.Millisecond).String()
+		}
+
+		table := tablewriter.NewTable(w)
+		table.Header([]string{"p50", "p90", "p95", "p99", "max"})
+		table.Append([]string{percentile(0.5), percentile(0.9), percentile(0.95), percentile(0.99), percentile(1)})
+		table.Render()
+	}
+
+	classes := lo.GroupBy(results, func(r Result) string { return r.Class })
+
+	table := tablewriter.NewTable(w)
+	table.Header([]string{"Class", "Count", "Rate"})
+	for _, class := range slices.Sorted(maps.Keys(classes)) {
+		n := len(classes[class])
+		table.Append([]string{class, strconv.Itoa(n), fmt.Sprintf("%.1f%%", 100*float64(n)/float64(len(results)))})
+	}
+	table.Render()
+
+	for _, class := range slices.Sorted(maps.Keys(classes)) {
+		if r := classes[class][0]; class != OK && r.Detail != "" {
+			fmt.Fprintf(w, "%s: %s\n", class, r.Detail)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+// Package mock provides an in-process optimizer for integration tests of downstream projects,
+// running without the Python service or network access:
+//
+//	srv := mock.NewServer()
+//	defer srv.Close()
+//
+//	c, err := client.New(srv.URL)
+//	srv.Fail(http.StatusInternalServerError, 1) // fail the next request
+//
+// Requests are validated using client.Validate and solved by a deterministic greedy schedule
+// unless a handler is set. Surplus generation charges the batteries in order, deficits are
+// discharged from them, remaining energy is exchanged with the grid. Efficiencies, goals and
+// strategies are ignored, see Greedy.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Handler solves an optimization request, e.g. returning a canned result.
+type Handler func(req client.OptimizationInput) (client.OptimizationResult, error)
+
+// Server serves the optimize endpoints. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handler  Handler
+	status   int // status of injected failures
+	failures int // number of requests left to fail
+	delay    time.Duration
+	requests []client.OptimizationInput
+}
+
+// Option configures a server created by NewServer.
+type Option func(*Server)
+
+// WithHandler solves requests using h instead of the greedy schedule. Errors returned by h
+// respond 500 like failed optimizations.
+func WithHandler(h Handler) Option {
+	return func(s *Server) {
+		s.handler = h
+	}
+}
+
+// WithResult responds to all requests with res.
+func WithResult(res client.OptimizationResult) Option {
+	return WithHandler(func(client.OptimizationInput) (client.OptimizationResult, error) {
+		return res, nil
+	})
+}
+
+// NewServer starts a server serving the optimize endpoints. Close it after use.
+func NewServer(opts ...Option) *Server {
+	s := &Server{handler: Greedy}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /optimize/charge-schedule", s.solve(client.Optimal))
+	mux.HandleFunc("POST /optimize/simulate", s.solve(client.Simulated))
+	mux.HandleFunc("POST /optimize/stream", s.stream)
+	mux.HandleFunc("POST /optimize/validate", s.validate)
+	mux.HandleFunc("GET /optimize/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "healthy", "message": "Mock optimizer is running"})
+	})
+
+	s.Server = httptest.NewServer(s.fault(mux))
+
+	return s
+}
+
+// Fail responds to the next n requests with status, e.g. 500 or 503.
+func (s *Server) Fail(status, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.failures = status, n
+}
+
+// SetDelay delays all responses by d, e.g. to simulate slow solves or, exceeding the client
+// timeout, timeouts. Requests cancelled by the client return early.
+func (s *Server) SetDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+// Requests returns the valid optimization and simulation requests received.
+func (s *Server) Requests() []client.OptimizationInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]client.OptimizationInput(nil), s.requests...)
+}
+
+// fault applies the delay and injected failures before serving requests
+func (s *Server) fault(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		delay := s.delay
+		status := 0
+		if s.failures > 0 {
+			s.failures--
+			status = s.status
+		}
+		s.mu.Unlock()
+
+		if delay > 0 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(delay):
+			}
+		}
+
+		if status != 0 {
+			writeJSON(w, status, client.Error{Message: http.StatusText(status)})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decode reads and validates the request, responding 400 like the service if it is invalid
+func decode(w http.ResponseWriter, r *http.Request) (client.OptimizationInput, bool) {
+	var req client.OptimizationInput
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, client.Error{Message: "Failed to decode JSON object: " + err.Error()})
+		return req, false
+	}
+
+	if err := client.Validate(req); err != nil {
+		// joined errors are prefixed by their field path
+		details := make(map[string]string)
+		for _, line := range strings.Split(err.Error(), "\n") {
+			path, msg, _ := strings.Cut(line, ": ")
+			details[path] = msg
+		}
+
+		writeJSON(w, http.StatusBadRequest, client.Error{Message: "Input payload validation failed", Details: details})
+		return req, false
+	}
+
+	return req, true
+}
+
+func (s *Server) solve(status client.OptimizationResultStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decode(w, r)
+		if !ok {
+			return
+		}
+
+		res, err := s.handle(req, status)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, client.Error{Message: err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, res)
+	}
+}
+
+// handle records and solves a valid request, results without status get status
+func (s *Server) handle(req client.OptimizationInput, status client.OptimizationResultStatus) (client.OptimizationResult, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	handler := s.handler
+	s.mu.Unlock()
+
+	res, err := handler(req)
+	if err == nil && res.Status == "" {
+		res.Status = status
+	}
+
+	return res, err
+}
+
+// stream responds with a progress event of the model phase followed by the result or error event
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	req, ok := decode(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	writeEvent(w, "progress", map[string]any{"phase": "model", "elapsed": 0})
+
+	res, err := s.handle(req, client.Optimal)
+	if err != nil {
+		writeEvent(w, "error", map[string]any{"code": http.StatusInternalServerError, "message": err.Error()})
+		return
+	}
+
+	writeEvent(w, "result", res)
+}
+
+func writeEvent(w http.ResponseWriter, event string, v any) {
+	b, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *Server) validate(w http.ResponseWriter, r *http.Request) {
+	if req, ok := decode(w, r); ok {
+		writeJSON(w, http.StatusOK, client.ValidationResult{Request: req})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Greedy solves req by the deterministic greedy schedule of the server. Charge demands are
+// charged from the surplus, the remainder is imported from the grid.
+func Greedy(req client.OptimizationInput) (client.OptimizationResult, error) {
+	ts := req.TimeSeries
+	n := len(ts.Dt)
+
+	res := client.OptimizationResult{
+		Batteries:     make([]client.BatteryResult, len(req.Batteries)),
+		GridImport:    make([]float32, n),
+		GridExport:    make([]float32, n),
+		FlowDirection: make([]client.OptimizationResultFlowDirection, n),
+		ObjectiveUnit: client.Currency,
+	}
+
+	soc := make([]float32, len(req.Batteries))
+	for i, bat := range req.Batteries {
+		soc[i] = bat.SInitial
+		res.Batteries[i] = client.BatteryResult{
+			Id:               bat.Id,
+			Name:             bat.Name,
+			ChargingPower:    make([]float32, n),
+			DischargingPower: make([]float32, n),
+			StateOfCharge:    make([]float32, n),
+		}
+	}
+
+	at := func(series []float32, t int) float32 {
+		if t < len(series) {
+			return series[t]
+		}
+		return 0
+	}
+
+	for t, dt := range ts.Dt {
+		h := float32(dt) / 3600
+
+		// surplus of generation over demand, negative for a deficit
+		surplus := at(ts.Ft, t) - at(ts.Gt, t)
+		// charge demands not covered by the surplus
+		var imported float32
+
+		for i, bat := range req.Batteries {
+			br := &res.Batteries[i]
+
+			if (bat.Enabled == nil || *bat.Enabled) && (len(bat.Available) == 0 || bat.Available[t]) {
+				demand := at(bat.PDemand, t)
+
+				switch {
+				case surplus > 0 || demand > 0:
+					c := min(max(surplus, demand), bat.CMax*h, max(0, bat.SMax-soc[i]))
+					br.ChargingPower[t] = c
+					soc[i] += c
+
+					fromSurplus := min(c, max(0, surplus))
+					surplus -= fromSurplus
+					imported += c - fromSurplus
+				case surplus < 0:
+					d := min(-surplus, bat.DMax*h, max(0, soc[i]-bat.SMin))
+					br.DischargingPower[t] = d
+					soc[i] -= d
+					surplus += d
+				}
+			}
+
+			br.StateOfCharge[t] = soc[i]
+		}
+
+		switch net := surplus - imported; {
+		case net > 0:
+			res.GridExport[t] = net
+			res.FlowDirection[t] = 1
+		case net < 0:
+			res.GridImport[t] = -net
+		}
+
+		res.ObjectiveValue += res.GridExport[t]*ts.PE[t] - res.GridImport[t]*ts.PN[t]
+	}
+
+	for i, bat := range req.Batteries {
+		res.ObjectiveValue += soc[i] * bat.PA
+	}
+
+	return res, nil
+}
@@ -0,0 +1,132 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+func request() client.OptimizationInput {
+	return client.OptimizationInput{
+		Batteries: []client.BatteryConfig{{Id: "home", Name: "Home", SMin: 1000, SMax: 5000, SInitial: 2000, CMax: 4000, DMax: 4000, PA: 0.0001}},
+		TimeSeries: client.TimeSeries{
+			Dt: []int{3600, 3600, 3600},
+			Ft: []float32{3000, 0, 0},
+			Gt: []float32{500, 1500, 2500},
+			PN: []float32{0.0003, 0.0003, 0.0003},
+			PE: []float32{0.0001, 0.0001, 0.0001},
+		},
+	}
+}
+
+func TestGreedy(t *testing.T) {
+	res, err := Greedy(request())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := res.Batteries[0]
+
+	// the surplus of 2500 Wh is charged, the deficits are discharged down to s_min
+	for _, tc := range []struct {
+		name     string
+		got, exp []float32
+	}{
+		{"charging", b.ChargingPower, []float32{2500, 0, 0}},
+		{"discharging", b.DischargingPower, []float32{0, 1500, 2000}},
+		{"state of charge", b.StateOfCharge, []float32{4500, 3000, 1000}},
+		{"grid import", res.GridImport, []float32{0, 0, 500}},
+		{"grid export", res.GridExport, []float32{0, 0, 0}},
+	} {
+		for k := range tc.exp {
+			if tc.got[k] != tc.exp[k] {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.exp, tc.got)
+				break
+			}
+		}
+	}
+
+	if b.Id != "home" {
+		t.Errorf("expected battery id home, got %q", b.Id)
+	}
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	c, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	res, err := c.Solve(ctx, request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != client.Optimal {
+		t.Errorf("expected status Optimal, got %s", res.Status)
+	}
+
+	// invalid requests respond 400 with details
+	invalid := request()
+	invalid.TimeSeries.PN = nil
+	resp, err := c.PostOptimizeChargeScheduleWithResponse(ctx, invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusBadRequest || resp.JSON400 == nil || len(resp.JSON400.Details) == 0 {
+		t.Errorf("expected 400 with details, got %d %s", resp.StatusCode(), resp.Body)
+	}
+
+	// only valid requests are recorded
+	if n := len(srv.Requests()); n != 1 {
+		t.Errorf("expected 1 recorded request, got %d", n)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	srv := NewServer(WithHandler(func(client.OptimizationInput) (client.OptimizationResult, error) {
+		return client.OptimizationResult{}, errors.New("solver failed")
+	}))
+	defer srv.Close()
+
+	c, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	srv.Fail(http.StatusServiceUnavailable, 1)
+	resp, err := c.PostOptimizeChargeScheduleWithResponse(ctx, request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("expected injected 503, got %d", resp.StatusCode())
+	}
+
+	// handler errors respond 500
+	resp, err = c.PostOptimizeChargeScheduleWithResponse(ctx, request())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode() != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", resp.StatusCode())
+	}
+
+	// delays exceeding the request deadline time out
+	srv.SetDelay(200 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.PostOptimizeChargeScheduleWithResponse(ctx, request()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+// Package override temporarily pins battery setpoints, e.g. holding a battery during a storm
+// warning. Active overrides are applied to subsequent optimization requests until they expire.
+package override
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/clock"
+)
+
+// Mode is the pinned behaviour of a battery.
+type Mode int
+
+const (
+	// Hold neither charges nor discharges the battery.
+	Hold Mode = iota
+	// Charge charges the battery with at least the given power.
+	Charge
+)
+
+func (m Mode) String() string {
+	if m == Charge {
+		return "charge"
+	}
+	return "hold"
+}
+
+// Setpoint pins a battery.
+type Setpoint struct {
+	Battery int
+	Mode    Mode
+	Power   float64 // minimum charge power for Charge [W]
+}
+
+// Override pins setpoints until it expires.
+type Override struct {
+	ID        int
+	Created   time.Time
+	Until     time.Time
+	Reason    string
+	Setpoints []Setpoint
+}
+
+// Manager keeps the active overrides. It is safe for concurrent use.
+type Manager struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	logger    *slog.Logger
+	overrides []Override
+	next      int
+}
+
+// Option configures the manager.
+type Option func(*Manager)
+
+// WithClock sets the clock used for expiry. Defaults to the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(m *Manager) {
+		m.clock = clk
+	}
+}
+
+// WithLogger sets the audit logger. Creation, cancellation and expiry of overrides are logged
+// at info level. Defaults to slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Manager) {
+		m.logger = logger
+	}
+}
+
+// New creates an override manager.
+func New(opts ...Option) *Manager {
+	m := &Manager{
+		clock:  clock.Real,
+		logger: slog.Default(),
+		next:   1,
+	}
+
+	for _, o := range opts {
+		o(m)
+	}
+
+	return m
+}
+
+// OverrideUntil pins the setpoints until the given time and returns the override id.
+// Later overrides take precedence over earlier ones for the same battery.
+func (m *Manager) OverrideUntil(until time.Time, reason string, setpoints ...Setpoint) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	if !until.After(now) {
+		return 0, errors.New("override expires in the past")
+	}
+	if len(setpoints) == 0 {
+		return 0, errors.New("no setpoints")
+	}
+	for _, sp := range setpoints {
+		if sp.Battery < 0 {
+			return 0, fmt.Errorf("invalid battery %d", sp.Battery)
+		}
+		if sp.Mode == Charge && sp.Power <= 0 {
+			return 0, fmt.Errorf("battery %d: charge power must be positive", sp.Battery)
+		}
+	}
+
+	o := Override{ID: m.next, Created: now, Until: until, Reason: reason, Setpoints: slices.Clone(setpoints)}
+	m.next++
+	m.overrides = append(m.overrides, o)
+
+	for _, sp := range setpoints {
+		m.logger.Info("override created", "id", o.ID, "battery", sp.Battery, "mode", sp.Mode,
+			"power", sp.Power, "until", until, "reason", reason)
+	}
+
+	return o.ID, nil
+}
+
+// Cancel removes an override before its expiry.
+func (m *Manager) Cancel(id int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.IndexFunc(m.overrides, func(o Override) bool { return o.ID == id })
+	if i < 0 {
+		return false
+	}
+
+	m.overrides = slices.Delete(m.overrides, i, i+1)
+	m.logger.Info("override cancelled", "id", id)
+
+	return true
+}
+
+// expire removes expired overrides, the lock must be held
+func (m *Manager) expire() {
+	now := m.clock.Now()
+	m.overrides = slices.DeleteFunc(m.overrides, func(o Override) bool {
+		if o.Until.After(now) {
+			return false
+		}
+		m.logger.Info("override expired", "id", o.ID, "reason", o.Reason)
+		return true
+	})
+}
+
+// Active returns the overrides that have not expired.
+func (m *Manager) Active() []Override {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire()
+
+	return slices.Clone(m.overrides)
+}
+
+// Apply pins the active setpoints in req for the intervals starting before their expiry, with
+// the first interval starting at start. Held batteries are marked unavailable and lose their
+// charge demand, charging batteries get a charge demand. Overrides for batteries not in req
+// are returned as error.
+func (m *Manager) Apply(req *client.OptimizationInput, start time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire()
+
+	dt := req.TimeSeries.Dt
+
+	// series of the request may be shared with the caller
+	req.Batteries = slices.Clone(req.Batteries)
+	for i := range req.Batteries {
+		req.Batteries[i].Available = slices.Clone(req.Batteries[i].Available)
+		req.Batteries[i].PDemand = slices.Clone(req.Batteries[i].PDemand)
+	}
+
+	for _, o := range m.overrides {
+		for _, sp := range o.Setpoints {
+			if sp.Battery >= len(req.Batteries) {
+				return fmt.Errorf("override %d: battery %d not found", o.ID, sp.Battery)
+			}
+			bat := &req.Batteries[sp.Battery]
+			if (bat.Available != nil && len(bat.Available) != len(dt)) || (bat.PDemand != nil && len(bat.PDemand) != len(dt)) {
+				return fmt.Errorf("override %d: battery %d series do not match dt", o.ID, sp.Battery)
+			}
+
+			ts := start
+			for t := range dt {
+				if !ts.Before(o.Until) {
+					break
+				}
+
+				switch sp.Mode {
+				case Hold:
+					if bat.Available == nil {
+						bat.Available = slices.Repeat([]bool{true}, len(dt))
+					}
+					bat.Available[t] = false
+					if bat.PDemand != nil {
+						bat.PDemand[t] = 0
+					}
+
+				case Charge:
+					if bat.Available != nil {
+						bat.Available[t] = true
+					}
+					if bat.PDemand == nil {
+						bat.PDemand = make([]float32, len(dt))
+					}
+					bat.PDemand[t] = max(bat.PDemand[t], float32(sp.Power*float64(dt[t])/3600))
+				}
+
+				ts = ts.Add(time.Duration(dt[t]) * time.Second)
+			}
+		}
+	}
+
+	return nil
+}
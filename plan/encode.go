@@ -0,0 +1,205 @@
+package plan
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Export is a plan to be encoded.
+type Export struct {
+	Request client.OptimizationInput
+	Result  client.OptimizationResult
+	// Start is the start of the first interval.
+	Start time.Time
+	Names Names
+	// Latency is the actuation latency per battery of the schedule format, see Advance.
+	Latency []time.Duration
+}
+
+// Encoder writes a plan in an export format.
+type Encoder interface {
+	Encode(w io.Writer, e Export) error
+}
+
+// EncoderFunc adapts a function to the Encoder interface.
+type EncoderFunc func(w io.Writer, e Export) error
+
+// Encode calls f.
+func (f EncoderFunc) Encode(w io.Writer, e Export) error {
+	return f(w, e)
+}
+
+var (
+	mu       sync.RWMutex
+	encoders = map[string]Encoder{
+		"json":     EncoderFunc(encodeJSON),
+		"csv":      EncoderFunc(encodeCSV),
+		"schedule": EncoderFunc(encodeSchedule),
+		"ical":     EncoderFunc(encodeICal),
+		"parquet":  EncoderFunc(encodeParquet),
+		"influx":   EncoderFunc(encodeInflux),
+	}
+)
+
+// Register registers an encoder by format name, replacing an encoder of the same name.
+func Register(name string, enc Encoder) {
+	mu.Lock()
+	defer mu.Unlock()
+	encoders[name] = enc
+}
+
+// Lookup returns the encoder of a format.
+func Lookup(name string) (Encoder, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	enc, ok := encoders[name]
+	return enc, ok
+}
+
+// Formats returns the sorted names of all registered formats.
+func Formats() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Sorted(maps.Keys(encoders))
+}
+
+// encodeJSON writes the result as indented JSON
+func encodeJSON(w io.Writer, e Export) error {
+	b, err := json.MarshalIndent(e.Result, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// encodeSchedule writes the setpoints advanced by the latencies as indented JSON
+func encodeSchedule(w io.Writer, e Export) error {
+	b, err := json.MarshalIndent(Advance(Setpoints(e.Request, e.Result, e.Start), e.Latency), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// encodeICal writes the charge and discharge windows as iCalendar events
+func encodeICal(w io.Writer, e Export) error {
+	return WriteICal(w, Windows(e.Request, e.Result, e.Start, 1), e.Names)
+}
+
+// encodeCSV writes forecasts, prices, grid exchange and battery schedules per interval
+func encodeCSV(w io.Writer, e Export) error {
+	req, res, start := e.Request, e.Result, e.Start
+	cw := csv.NewWriter(w)
+
+	header := []string{"interval", "start", "forecast", "demand", "price_import", "price_export", "grid_import", "grid_export"}
+	for i := range res.Batteries {
+		header = append(header,
+			fmt.Sprintf("battery_%d_goal", i),
+			fmt.Sprintf("battery_%d_charge", i),
+			fmt.Sprintf("battery_%d_discharge", i),
+			fmt.Sprintf("battery_%d_soc", i),
+		)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	value := func(s []float32, t int) string {
+		if t < len(s) {
+			return strconv.FormatFloat(float64(s[t]), 'f', -1, 32)
+		}
+		return ""
+	}
+
+	ts := req.TimeSeries
+	for t := range res.GridImport {
+		row := []string{
+			strconv.Itoa(t), start.Format(time.RFC3339),
+			value(ts.Ft, t), value(ts.Gt, t), value(ts.PN, t), value(ts.PE, t),
+			value(res.GridImport, t), value(res.GridExport, t),
+		}
+		for i, b := range res.Batteries {
+			var goal []float32
+			if i < len(req.Batteries) {
+				goal = req.Batteries[i].SGoal
+			}
+			row = append(row, value(goal, t), value(b.ChargingPower, t), value(b.DischargingPower, t), value(b.StateOfCharge, t))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+
+		if t < len(ts.Dt) {
+			start = start.Add(time.Duration(ts.Dt[t]) * time.Second)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// column is a series of a plan
+type column struct {
+	name    string
+	battery int // index of the battery, -1 for site series
+	values  []float64
+}
+
+// columns returns the start times of the intervals and the series of the plan covering all
+// intervals, named like the CSV columns without battery prefix
+func columns(e Export) ([]time.Time, []column) {
+	req, res := e.Request, e.Result
+	n := len(res.GridImport)
+
+	times := make([]time.Time, n)
+	start := e.Start
+	for t := range n {
+		times[t] = start
+		if t < len(req.TimeSeries.Dt) {
+			start = start.Add(time.Duration(req.TimeSeries.Dt[t]) * time.Second)
+		}
+	}
+
+	var cols []column
+	add := func(name string, battery int, s []float32) {
+		if len(s) < n {
+			return
+		}
+		values := make([]float64, n)
+		for t := range n {
+			// shortest representation, e.g. 0.3 instead of 0.30000001192092896
+			values[t], _ = strconv.ParseFloat(strconv.FormatFloat(float64(s[t]), 'g', -1, 32), 64)
+		}
+		cols = append(cols, column{name: name, battery: battery, values: values})
+	}
+
+	ts := req.TimeSeries
+	add("forecast", -1, ts.Ft)
+	add("demand", -1, ts.Gt)
+	add("price_import", -1, ts.PN)
+	add("price_export", -1, ts.PE)
+	add("grid_import", -1, res.GridImport)
+	add("grid_export", -1, res.GridExport)
+
+	for i, b := range res.Batteries {
+		if i < len(req.Batteries) {
+			add("goal", i, req.Batteries[i].SGoal)
+		}
+		add("charge", i, b.ChargingPower)
+		add("discharge", i, b.DischargingPower)
+		add("soc", i, b.StateOfCharge)
+	}
+
+	return times, cols
+}
@@ -0,0 +1,111 @@
+package plan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/quantize"
+)
+
+const icalTime = "20060102T150405Z"
+
+// Names maps battery indices to display names. Batteries without name are named by index.
+type Names []string
+
+func (n Names) name(i int) string {
+	if i < len(n) && n[i] != "" {
+		return n[i]
+	}
+	return fmt.Sprintf("Battery %d", i+1)
+}
+
+// icalEscape escapes text values according to RFC 5545
+func icalEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// roundEnergy rounds the window energies to Wh, conserving the sums per battery and kind
+func roundEnergy(windows []Window) []float64 {
+	type key struct {
+		battery int
+		kind    Kind
+	}
+
+	groups := make(map[key][]int)
+	for j, win := range windows {
+		k := key{win.Battery, win.Kind}
+		groups[k] = append(groups[k], j)
+	}
+
+	res := make([]float64, len(windows))
+	for _, idx := range groups {
+		energy := make([]float64, len(idx))
+		for n, j := range idx {
+			energy[n] = windows[j].Energy
+		}
+		for n, e := range quantize.Round(energy, 1) {
+			res[idx[n]] = e
+		}
+	}
+
+	return res
+}
+
+// WriteICal writes the windows as iCalendar events. Events get stable UIDs derived from
+// battery, direction and start, so re-importing an updated plan replaces existing events.
+func WriteICal(w io.Writer, windows []Window, names Names) error {
+	bw := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format(icalTime)
+
+	line := func(format string, args ...any) {
+		fmt.Fprintf(bw, format+"\r\n", args...)
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//evcc-io//optimizer//EN")
+	line("CALSCALE:GREGORIAN")
+
+	energy := roundEnergy(windows)
+
+	for j, win := range windows {
+		name := names.name(win.Battery)
+
+		line("BEGIN:VEVENT")
+		line("UID:%d-%s-%d@optimizer", win.Battery, win.Kind, win.Start.Unix())
+		line("DTSTAMP:%s", stamp)
+		line("DTSTART:%s", win.Start.UTC().Format(icalTime))
+		line("DTEND:%s", win.End.UTC().Format(icalTime))
+		line("SUMMARY:%s", icalEscape(fmt.Sprintf("%s %s", name, win.Kind)))
+		line("DESCRIPTION:%s", icalEscape(fmt.Sprintf("%.0f Wh", energy[j])))
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+
+	return bw.Flush()
+}
+
+// WriteCron writes the windows as crontab lines invoking command with the battery name,
+// direction and on/off at the start and end of each window, in the time zone of loc.
+// Crontab entries have minute resolution and repeat yearly, the file is meant to be
+// replaced whenever a new plan is available.
+func WriteCron(w io.Writer, windows []Window, names Names, command string, loc *time.Location) error {
+	bw := bufio.NewWriter(w)
+
+	entry := func(ts time.Time, name string, kind Kind, state string) {
+		ts = ts.In(loc)
+		fmt.Fprintf(bw, "%d %d %d %d * %s %q %s %s\n", ts.Minute(), ts.Hour(), ts.Day(), int(ts.Month()), command, name, kind, state)
+	}
+
+	for _, win := range windows {
+		name := names.name(win.Battery)
+		entry(win.Start, name, win.Kind, "on")
+		entry(win.End, name, win.Kind, "off")
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,54 @@
+package plan
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// influxEscape escapes tag values of the InfluxDB line protocol
+var influxEscape = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// encodeInflux writes the series per interval in InfluxDB line protocol with nanosecond
+// timestamps, site series as measurement evopt and battery series as measurement
+// evopt_battery tagged with the battery name
+func encodeInflux(w io.Writer, e Export) error {
+	bw := bufio.NewWriter(w)
+	times, cols := columns(e)
+
+	for t, ts := range times {
+		line := func(measurement string, battery int) {
+			first := true
+			for _, c := range cols {
+				if c.battery != battery {
+					continue
+				}
+
+				if first {
+					bw.WriteString(measurement)
+					if battery >= 0 {
+						bw.WriteString(",battery=" + influxEscape.Replace(e.Names.name(battery)))
+					}
+					bw.WriteByte(' ')
+					first = false
+				} else {
+					bw.WriteByte(',')
+				}
+
+				bw.WriteString(c.name + "=" + strconv.FormatFloat(c.values[t], 'g', -1, 64))
+			}
+
+			if !first {
+				bw.WriteString(" " + strconv.FormatInt(ts.UnixNano(), 10) + "\n")
+			}
+		}
+
+		line("evopt", -1)
+		for i := range e.Result.Batteries {
+			line("evopt_battery", i)
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,197 @@
+package plan
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Parquet physical types, converted types and Thrift compact protocol types used by the
+// writer, see https://github.com/apache/parquet-format
+const (
+	parquetInt64  = 2
+	parquetDouble = 5
+
+	parquetTimestampMillis = 9
+
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// encodeParquet writes the series per interval as Parquet file with a single row group of
+// uncompressed, plain encoded columns: start as timestamp and the series like the CSV columns
+// as doubles. Series not covering all intervals are omitted.
+func encodeParquet(w io.Writer, e Export) error {
+	times, cols := columns(e)
+	n := len(times)
+
+	type chunk struct {
+		name   string
+		typ    int32
+		offset int64
+		size   int64
+	}
+
+	file := []byte("PAR1")
+	var chunks []chunk
+
+	page := func(name string, typ int32, data []byte) {
+		var h compact
+		h.begin(func() {
+			h.i32(1, 0) // data page
+			h.i32(2, int32(len(data)))
+			h.i32(3, int32(len(data)))
+			h.structField(5, func() {
+				h.i32(1, int32(n))
+				h.i32(2, 0) // plain
+				h.i32(3, 3) // rle definition levels
+				h.i32(4, 3) // rle repetition levels
+			})
+		})
+
+		chunks = append(chunks, chunk{name: name, typ: typ, offset: int64(len(file)), size: int64(len(h.b) + len(data))})
+		file = append(append(file, h.b...), data...)
+	}
+
+	data := make([]byte, 0, 8*n)
+	for _, t := range times {
+		data = binary.LittleEndian.AppendUint64(data, uint64(t.UnixMilli()))
+	}
+	page("start", parquetInt64, data)
+
+	for _, c := range cols {
+		name := c.name
+		if c.battery >= 0 {
+			name = fmt.Sprintf("battery_%d_%s", c.battery, c.name)
+		}
+
+		data := make([]byte, 0, 8*n)
+		for _, v := range c.values {
+			data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+		}
+		page(name, parquetDouble, data)
+	}
+
+	var total int64
+	for _, c := range chunks {
+		total += c.size
+	}
+
+	var m compact
+	m.begin(func() {
+		m.i32(1, 1) // version
+		m.list(2, thriftStruct, len(chunks)+1)
+		m.begin(func() {
+			m.str(4, "schema")
+			m.i32(5, int32(len(chunks)))
+		})
+		for k, c := range chunks {
+			m.begin(func() {
+				m.i32(1, c.typ)
+				m.i32(3, 0) // required
+				m.str(4, c.name)
+				if k == 0 {
+					m.i32(6, parquetTimestampMillis)
+				}
+			})
+		}
+		m.i64(3, int64(n))
+		m.list(4, thriftStruct, 1)
+		m.begin(func() {
+			m.list(1, thriftStruct, len(chunks))
+			for _, c := range chunks {
+				m.begin(func() {
+					m.i64(2, c.offset)
+					m.structField(3, func() {
+						m.i32(1, c.typ)
+						m.list(2, thriftI32, 1)
+						m.varint(0) // plain
+						m.list(3, thriftBinary, 1)
+						m.varint(uint64(len(c.name)))
+						m.b = append(m.b, c.name...)
+						m.i32(4, 0) // uncompressed
+						m.i64(5, int64(n))
+						m.i64(6, c.size)
+						m.i64(7, c.size)
+						m.i64(9, c.offset)
+					})
+				})
+			}
+			m.i64(2, total)
+			m.i64(3, int64(n))
+		})
+		m.str(6, "evopt")
+	})
+
+	file = append(file, m.b...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(m.b)))
+	file = append(file, "PAR1"...)
+
+	_, err := w.Write(file)
+	return err
+}
+
+// compact encodes Thrift structs in the compact protocol
+type compact struct {
+	b    []byte
+	last []int16 // last field id per nested struct
+}
+
+// begin encodes the struct written by fn
+func (c *compact) begin(fn func()) {
+	c.last = append(c.last, 0)
+	fn()
+	c.b = append(c.b, 0) // stop
+	c.last = c.last[:len(c.last)-1]
+}
+
+func (c *compact) field(id int16, typ byte) {
+	last := &c.last[len(c.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		c.b = append(c.b, byte(delta)<<4|typ)
+	} else {
+		c.b = append(c.b, typ)
+		c.varint(uint64(uint16((id << 1) ^ (id >> 15))))
+	}
+	*last = id
+}
+
+func (c *compact) varint(v uint64) {
+	c.b = binary.AppendUvarint(c.b, v)
+}
+
+func (c *compact) i32(id int16, v int32) {
+	c.field(id, thriftI32)
+	c.varint(uint64(uint32((v << 1) ^ (v >> 31))))
+}
+
+func (c *compact) i64(id int16, v int64) {
+	c.field(id, thriftI64)
+	c.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (c *compact) str(id int16, s string) {
+	c.field(id, thriftBinary)
+	c.varint(uint64(len(s)))
+	c.b = append(c.b, s...)
+}
+
+func (c *compact) structField(id int16, fn func()) {
+	c.field(id, thriftStruct)
+	c.begin(fn)
+}
+
+// list starts a list field of n elements of typ, the elements are written by the caller
+func (c *compact) list(id int16, typ byte, n int) {
+	c.field(id, thriftList)
+	if n < 15 {
+		c.b = append(c.b, byte(n)<<4|typ)
+	} else {
+		c.b = append(c.b, 0xf0|typ)
+		c.varint(uint64(n))
+	}
+}
@@ -0,0 +1,84 @@
+// Package plan converts optimization results into schedules of charge and discharge windows
+// for calendar apps (iCal) and external schedulers (cron), and into setpoint schedules for
+// controllers.
+package plan
+
+import (
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Kind is the direction of a window.
+type Kind string
+
+const (
+	Charge    Kind = "charge"
+	Discharge Kind = "discharge"
+)
+
+// Window is a contiguous period of charging or discharging of a battery.
+type Window struct {
+	Battery int
+	Kind    Kind
+	Start   time.Time
+	End     time.Time
+	Energy  float64 // energy charged or discharged [Wh]
+}
+
+// Windows extracts the charge and discharge windows of all batteries from the plan res
+// computed for req, with the first interval starting at start. Intervals with less than
+// minEnergy Wh are ignored, avoiding windows from numerical noise.
+func Windows(req client.OptimizationInput, res client.OptimizationResult, start time.Time, minEnergy float64) []Window {
+	var windows []Window
+
+	for i, b := range res.Batteries {
+		for _, kind := range []Kind{Charge, Discharge} {
+			series := b.ChargingPower
+			if kind == Discharge {
+				series = b.DischargingPower
+			}
+
+			var current *Window
+			ts := start
+
+			for t, e := range series {
+				if t >= len(req.TimeSeries.Dt) {
+					break
+				}
+				end := ts.Add(time.Duration(req.TimeSeries.Dt[t]) * time.Second)
+
+				if float64(e) >= minEnergy {
+					if current == nil {
+						current = &Window{Battery: i, Kind: kind, Start: ts}
+					}
+					current.End = end
+					current.Energy += float64(e)
+				} else if current != nil {
+					windows = append(windows, *current)
+					current = nil
+				}
+
+				ts = end
+			}
+
+			if current != nil {
+				windows = append(windows, *current)
+			}
+		}
+	}
+
+	return windows
+}
+
+// Within returns the windows starting before the end of the period from start.
+// Windows ending after the period are kept unchanged.
+func Within(windows []Window, start time.Time, period time.Duration) []Window {
+	var res []Window
+	for _, w := range windows {
+		if w.End.After(start) && w.Start.Before(start.Add(period)) {
+			res = append(res, w)
+		}
+	}
+	return res
+}
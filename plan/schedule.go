@@ -0,0 +1,102 @@
+package plan
+
+import (
+	"math"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Setpoint is the power of a battery from its time on, positive for charging and negative for
+// discharging [W].
+type Setpoint struct {
+	Time  time.Time `json:"time"`
+	Power float64   `json:"power"`
+}
+
+// BatterySchedule are the setpoints of a battery.
+type BatterySchedule struct {
+	Id        string     `json:"id,omitempty"`
+	Setpoints []Setpoint `json:"setpoints"`
+}
+
+// Schedule is a compact plan of timestamped battery setpoints for controllers, e.g. encoded
+// as JSON.
+type Schedule struct {
+	Start     time.Time         `json:"start"`
+	End       time.Time         `json:"end"`
+	Batteries []BatterySchedule `json:"batteries"`
+}
+
+// Setpoints converts the plan res computed for req into a schedule with the first interval
+// starting at start. Setpoints are rounded to W and only added if the power changes, the last
+// setpoint holds until the end of the plan.
+func Setpoints(req client.OptimizationInput, res client.OptimizationResult, start time.Time) Schedule {
+	s := Schedule{Start: start, End: start}
+	for _, dt := range req.TimeSeries.Dt {
+		s.End = s.End.Add(time.Duration(dt) * time.Second)
+	}
+
+	value := func(series []float32, t int) float64 {
+		if t < len(series) {
+			return float64(series[t])
+		}
+		return 0
+	}
+
+	for _, b := range res.Batteries {
+		bs := BatterySchedule{Id: b.Id, Setpoints: []Setpoint{}}
+		ts := start
+
+		for t, dt := range req.TimeSeries.Dt {
+			// average power of the interval energies
+			power := (value(b.ChargingPower, t) - value(b.DischargingPower, t)) * 3600 / float64(dt)
+			if power = math.Round(power); power == 0 {
+				power = 0 // no negative zero
+			}
+
+			if n := len(bs.Setpoints); n == 0 || bs.Setpoints[n-1].Power != power {
+				bs.Setpoints = append(bs.Setpoints, Setpoint{Time: ts, Power: power})
+			}
+
+			ts = ts.Add(time.Duration(dt) * time.Second)
+		}
+
+		s.Batteries = append(s.Batteries, bs)
+	}
+
+	return s
+}
+
+// Advance shifts the setpoints of each battery earlier by its actuation latency, indexed like
+// the batteries of s, so that slow devices reach the planned power at the start of the
+// interval and deliver the planned energy. For devices ramping linearly, half the ramp time
+// is a good estimate. Setpoints shifted before the start of s replace the first setpoint.
+func Advance(s Schedule, latency []time.Duration) Schedule {
+	res := Schedule{Start: s.Start, End: s.End, Batteries: make([]BatterySchedule, 0, len(s.Batteries))}
+
+	for i, b := range s.Batteries {
+		var lead time.Duration
+		if i < len(latency) {
+			lead = max(latency[i], 0)
+		}
+
+		bs := BatterySchedule{Id: b.Id, Setpoints: make([]Setpoint, 0, len(b.Setpoints))}
+		for _, sp := range b.Setpoints {
+			sp.Time = sp.Time.Add(-lead)
+			if sp.Time.Before(s.Start) {
+				sp.Time = s.Start
+			}
+
+			if n := len(bs.Setpoints); n > 0 && !bs.Setpoints[n-1].Time.Before(sp.Time) {
+				bs.Setpoints[n-1] = sp
+			} else {
+				bs.Setpoints = append(bs.Setpoints, sp)
+			}
+		}
+
+		res.Batteries = append(res.Batteries, bs)
+	}
+
+	return res
+}
@@ -0,0 +1,100 @@
+package problem
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Size is the size class of a generated problem
+type Size string
+
+const (
+	Small  Size = "small"  // one battery, up to one day
+	Medium Size = "medium" // up to three batteries, up to two days
+	Large  Size = "large"  // up to five batteries, up to four days in 15 minute steps
+	Mixed  Size = "mixed"  // any of the above
+)
+
+// ParseSize parses a size class
+func ParseSize(s string) (Size, error) {
+	switch size := Size(s); size {
+	case Small, Medium, Large, Mixed:
+		return size, nil
+	default:
+		return "", fmt.Errorf("invalid size %q, expected small, medium, large or mixed", s)
+	}
+}
+
+// Random generates a valid randomized optimization problem of the given size class
+func Random(r *rand.Rand, size Size) client.OptimizationInput {
+	if size == Mixed {
+		size = []Size{Small, Medium, Large}[r.IntN(3)]
+	}
+
+	var batteries, steps, dt int
+	switch size {
+	case Small:
+		batteries, steps, dt = 1, 4+r.IntN(21), 3600
+	case Medium:
+		batteries, steps, dt = 1+r.IntN(3), 24+r.IntN(25), 3600
+	default:
+		batteries, steps, dt = 1+r.IntN(5), 96+r.IntN(289), 900
+	}
+
+	return Generate(r, steps, dt, batteries)
+}
+
+// Generate generates a valid randomized optimization problem of steps intervals of dt seconds
+// with the given number of batteries
+func Generate(r *rand.Rand, steps, dt, batteries int) client.OptimizationInput {
+	ts := client.TimeSeries{
+		Dt: make([]int, steps),
+		Ft: make([]float32, steps),
+		Gt: make([]float32, steps),
+		PN: make([]float32, steps),
+		PE: make([]float32, steps),
+	}
+
+	// scale energy per step to its duration
+	scale := float64(dt) / 3600
+	peak := 2000 + r.Float64()*8000
+	export := float64(r.IntN(2)) * 0.00008
+
+	for t := range steps {
+		hour := math.Mod(float64(t*dt)/3600, 24)
+		solar := math.Max(0, math.Sin((hour-6)/12*math.Pi))
+
+		ts.Dt[t] = dt
+		ts.Ft[t] = float32(peak * solar * (0.5 + r.Float64()/2) * scale)
+		ts.Gt[t] = float32((300 + r.Float64()*1200) * scale)
+		ts.PN[t] = float32(0.0002 + r.Float64()*0.0002)
+		ts.PE[t] = float32(export)
+	}
+
+	req := client.OptimizationInput{
+		TimeSeries: ts,
+		Batteries:  make([]client.BatteryConfig, batteries),
+	}
+
+	for i := range req.Batteries {
+		capacity := float32(5000 + r.IntN(75)*1000)
+		power := float32(3000 + r.IntN(8)*1000)
+
+		req.Batteries[i] = client.BatteryConfig{
+			Id:             fmt.Sprintf("battery-%d", i),
+			Name:           fmt.Sprintf("Battery %d", i+1),
+			SMin:           capacity * 0.1,
+			SMax:           capacity,
+			SInitial:       capacity * (0.1 + r.Float32()*0.9),
+			CMax:           power,
+			DMax:           power * float32(r.IntN(2)),
+			PA:             0.0001 + r.Float32()*0.0002,
+			ChargeFromGrid: r.IntN(2) == 1,
+		}
+	}
+
+	return req
+}
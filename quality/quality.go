@@ -0,0 +1,243 @@
+// Package quality tracks realized savings against the savings expected from the plans and
+// alerts when they degrade, catching silent issues like stale tariffs, broken forecasts or
+// changed battery behaviour.
+package quality
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+const week = 7 * 24 * time.Hour
+
+// Record are the savings of one period, e.g. a day, compared to a baseline like the simulated
+// self consumption policy.
+type Record struct {
+	Time     time.Time
+	Expected float64 // savings expected from the plans [currency unit]
+	Realized float64 // savings realized from the measurements [currency unit]
+}
+
+// Savings returns the savings of plan res over baseline, e.g. the simulation of the same
+// request. Objective values are net benefits, so the savings are their difference.
+func Savings(baseline, res client.OptimizationResult) float64 {
+	return float64(res.ObjectiveValue) - float64(baseline.ObjectiveValue)
+}
+
+// Alert is raised when the realized savings of the rolling window fall significantly short of
+// the expected savings.
+type Alert struct {
+	From, To time.Time
+	Expected float64
+	Realized float64
+	Ratio    float64 // realized / expected
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("realized savings %.2f are %.0f%% of expected %.2f from %s to %s",
+		a.Realized, a.Ratio*100, a.Expected, a.From.Format(time.DateOnly), a.To.Format(time.DateOnly))
+}
+
+// Detector aggregates records into weeks and compares the rolling window of complete weeks.
+type Detector struct {
+	mu          sync.Mutex
+	weeks       int
+	threshold   float64
+	minExpected float64
+	callback    func(Alert)
+	buckets     map[time.Time]*Record
+	latest      time.Time
+	alarmed     bool
+}
+
+// Option configures the detector.
+type Option func(*Detector)
+
+// WithWeeks sets the number of complete weeks in the rolling window. Default is 4.
+func WithWeeks(weeks int) Option {
+	return func(d *Detector) {
+		d.weeks = max(1, weeks)
+	}
+}
+
+// WithThreshold sets the relative shortfall of realized versus expected savings that raises
+// an alert. Default is 0.3.
+func WithThreshold(fraction float64) Option {
+	return func(d *Detector) {
+		d.threshold = fraction
+	}
+}
+
+// WithMinExpected suppresses alerts while the expected savings of the window are below this
+// value, as the ratio is meaningless for small savings. Default is 1.
+func WithMinExpected(savings float64) Option {
+	return func(d *Detector) {
+		d.minExpected = savings
+	}
+}
+
+// WithCallback registers a callback invoked synchronously for every alert.
+func WithCallback(fn func(Alert)) Option {
+	return func(d *Detector) {
+		d.callback = fn
+	}
+}
+
+// New creates a detector.
+func New(opts ...Option) *Detector {
+	d := &Detector{
+		weeks:       4,
+		threshold:   0.3,
+		minExpected: 1,
+		buckets:     make(map[time.Time]*Record),
+	}
+
+	for _, o := range opts {
+		o(d)
+	}
+
+	return d
+}
+
+// weekStart returns the start of the week containing ts, weeks start on Monday UTC
+func weekStart(ts time.Time) time.Time {
+	day := ts.UTC().Truncate(24 * time.Hour)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// Add adds the savings of a period. Records are evaluated once their week is complete, i.e.
+// when a record of a later week is added. Late records are added to their week and evaluated
+// with the next later week, records older than the window are ignored. An alert is raised once
+// when the window degrades and again only after it has recovered.
+func (d *Detector) Add(r Record) (Alert, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ws := weekStart(r.Time)
+	if ws.Before(d.latest.Add(-time.Duration(d.weeks) * week)) {
+		return Alert{}, false
+	}
+	later := ws.After(d.latest)
+	if later {
+		d.latest = ws
+	}
+
+	b, ok := d.buckets[ws]
+	if !ok {
+		b = &Record{Time: ws}
+		d.buckets[ws] = b
+	}
+	b.Expected += r.Expected
+	b.Realized += r.Realized
+
+	// the window only changes when a later week starts
+	if !later {
+		return Alert{}, false
+	}
+
+	// complete weeks, oldest first
+	var complete []time.Time
+	for start := range d.buckets {
+		if start.Before(d.latest) {
+			complete = append(complete, start)
+		}
+	}
+	slices.SortFunc(complete, func(a, b time.Time) int { return a.Compare(b) })
+
+	// expire weeks outside the window
+	if len(complete) > d.weeks {
+		for _, start := range complete[:len(complete)-d.weeks] {
+			delete(d.buckets, start)
+		}
+		complete = complete[len(complete)-d.weeks:]
+	}
+
+	if len(complete) < d.weeks {
+		return Alert{}, false
+	}
+
+	a := Alert{From: complete[0], To: complete[len(complete)-1].Add(week)}
+	for _, start := range complete {
+		a.Expected += d.buckets[start].Expected
+		a.Realized += d.buckets[start].Realized
+	}
+
+	if a.Expected < d.minExpected {
+		return Alert{}, false
+	}
+
+	a.Ratio = a.Realized / a.Expected
+	if a.Ratio >= 1-d.threshold {
+		d.alarmed = false
+		return Alert{}, false
+	}
+
+	if d.alarmed {
+		return Alert{}, false
+	}
+	d.alarmed = true
+
+	if d.callback != nil {
+		d.callback(a)
+	}
+
+	return a, true
+}
+
+// LogAlerts returns a callback logging alerts as warnings.
+func LogAlerts(logger *slog.Logger) func(Alert) {
+	return func(a Alert) {
+		logger.Warn("plan quality degraded", "from", a.From, "to", a.To,
+			"expected", a.Expected, "realized", a.Realized, "ratio", a.Ratio)
+	}
+}
+
+// WebhookAlerts returns a callback posting alerts as JSON to url. Delivery errors are passed
+// to onError if not nil.
+func WebhookAlerts(hc *http.Client, url string, onError func(error)) func(Alert) {
+	return func(a Alert) {
+		b, _ := json.Marshal(map[string]any{
+			"message":  a.String(),
+			"from":     a.From,
+			"to":       a.To,
+			"expected": a.Expected,
+			"realized": a.Realized,
+			"ratio":    a.Ratio,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		err := func() error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := hc.Do(req)
+			if err != nil {
+				return err
+			}
+			_ = resp.Body.Close()
+
+			if resp.StatusCode >= 300 {
+				return fmt.Errorf("webhook: %s", resp.Status)
+			}
+			return nil
+		}()
+
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Package quantize rounds optimization results for display and device commands. Rounding each
+// interval on its own lets small errors add up to a visible drift between the rounded energies
+// and the state of charge, the energy series are therefore rounded conserving their sums.
+package quantize
+
+import (
+	"math"
+	"slices"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Round rounds values to multiples of step without accumulating rounding errors. The running
+// sums are rounded instead of the values, so the running sum of the rounded values deviates by
+// at most half a step and the total equals the rounded total. A step of zero or less returns
+// the values unchanged.
+func Round[T ~float32 | ~float64](values []T, step float64) []T {
+	if step <= 0 || values == nil {
+		return slices.Clone(values)
+	}
+
+	res := make([]T, len(values))
+
+	var sum, prev float64
+	for t, v := range values {
+		sum += float64(v)
+		rounded := math.Round(sum/step) * step
+		res[t] = T(rounded - prev)
+		prev = rounded
+	}
+
+	return res
+}
+
+// Result returns res with its energy series rounded to multiples of step by Round and the state
+// of charge rounded per interval.
+func Result(res client.OptimizationResult, step float64) client.OptimizationResult {
+	if step <= 0 {
+		return res
+	}
+
+	out := res
+	out.GridImport = Round(res.GridImport, step)
+	out.GridExport = Round(res.GridExport, step)
+
+	out.Batteries = make([]client.BatteryResult, len(res.Batteries))
+	for i, b := range res.Batteries {
+		b.ChargingPower = Round(b.ChargingPower, step)
+		b.DischargingPower = Round(b.DischargingPower, step)
+		b.PreconditioningPower = Round(b.PreconditioningPower, step)
+
+		soc := make([]float32, len(b.StateOfCharge))
+		for t, s := range b.StateOfCharge {
+			soc[t] = float32(math.Round(float64(s)/step) * step)
+		}
+		b.StateOfCharge = soc
+
+		out.Batteries[i] = b
+	}
+
+	return out
+}
@@ -0,0 +1,87 @@
+package quantize
+
+import (
+	"math"
+	"testing"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+func TestRoundConservesSums(t *testing.T) {
+	values := []float64{0.4, 0.4, 0.4, 0.4, 0.4, 1.6, 2.5, 0.3}
+
+	res := Round(values, 1)
+
+	var sum, rounded float64
+	for k, v := range values {
+		sum += v
+		rounded += res[k]
+
+		if res[k] != math.Round(res[k]) {
+			t.Errorf("value %d: %v is not a multiple of the step", k, res[k])
+		}
+		// the running sums deviate by at most half a step
+		if math.Abs(rounded-sum) > 0.5+1e-9 {
+			t.Errorf("value %d: running sum %v deviates from %v", k, rounded, sum)
+		}
+	}
+
+	if rounded != math.Round(sum) {
+		t.Errorf("expected total %v, got %v", math.Round(sum), rounded)
+	}
+
+	// rounding each value on its own loses the small values
+	var naive float64
+	for _, v := range values {
+		naive += math.Round(v)
+	}
+	if naive == rounded {
+		t.Error("expected the example to drift when rounding per value")
+	}
+}
+
+func TestRoundStep(t *testing.T) {
+	res := Round([]float32{120, 130, 260}, 100)
+
+	expected := []float32{100, 200, 200}
+	for k := range expected {
+		if res[k] != expected[k] {
+			t.Fatalf("expected %v, got %v", expected, res)
+		}
+	}
+
+	if res := Round([]float32{1.5}, 0); res[0] != 1.5 {
+		t.Errorf("expected values unchanged without step, got %v", res)
+	}
+	if res := Round[float64](nil, 1); res != nil {
+		t.Errorf("expected nil, got %v", res)
+	}
+}
+
+func TestResult(t *testing.T) {
+	res := client.OptimizationResult{
+		GridImport: []float32{0.4, 0.4, 0.4},
+		Batteries: []client.BatteryResult{{
+			ChargingPower: []float32{333.3, 333.3, 333.4},
+			StateOfCharge: []float32{1333.3, 1666.6, 2000},
+		}},
+	}
+
+	out := Result(res, 10)
+
+	b := out.Batteries[0]
+	if got := b.ChargingPower[0] + b.ChargingPower[1] + b.ChargingPower[2]; got != 1000 {
+		t.Errorf("expected charged energy of 1000, got %v", got)
+	}
+	if b.StateOfCharge[0] != 1330 || b.StateOfCharge[2] != 2000 {
+		t.Errorf("expected state of charge rounded per interval, got %v", b.StateOfCharge)
+	}
+	if got := out.GridImport[0] + out.GridImport[1] + out.GridImport[2]; got != 0 {
+		t.Errorf("expected grid import of 0, got %v", got)
+	}
+
+	// the input is not modified
+	if res.Batteries[0].ChargingPower[0] != 333.3 {
+		t.Error("input modified")
+	}
+}
@@ -0,0 +1,124 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Handoff replaces battery i of sched by next, e.g. when new firmware reports the state of
+// charge on a different scale, and keeps the plan instead of discarding it. next defines the
+// asset: its limits, efficiencies and capacity. The state of charge and the goals of the old
+// battery are carried over unless next sets them, rescaled by the ratio of the maximum states
+// of charge and clamped to the range of next so that the request stays feasible. The planned
+// states of charge are rescaled alike, planned powers are clamped to the limits of next.
+func Handoff(sched Schedule, i int, next client.BatteryConfig) (Schedule, error) {
+	if i < 0 || i >= len(sched.Request.Batteries) {
+		return Schedule{}, fmt.Errorf("battery %d does not exist", i)
+	}
+
+	prev := sched.Request.Batteries[i]
+	if prev.SMax <= 0 || next.SMax <= 0 {
+		return Schedule{}, errors.New("maximum state of charge must be positive")
+	}
+
+	scale := next.SMax / prev.SMax
+	soc := func(v float32) float32 {
+		return min(max(v*scale, 0), next.SMax)
+	}
+
+	if next.Id == "" {
+		next.Id = prev.Id
+	}
+	if next.SInitial == 0 {
+		next.SInitial = soc(prev.SInitial)
+	}
+	if next.SInitialStddev == 0 {
+		next.SInitialStddev = prev.SInitialStddev * scale
+	}
+	if next.SGoal == nil && prev.SGoal != nil {
+		next.SGoal = make([]float32, len(prev.SGoal))
+		for t, v := range prev.SGoal {
+			next.SGoal[t] = soc(v)
+		}
+	}
+	if next.Departures == nil && prev.Departures != nil {
+		next.Departures = slices.Clone(prev.Departures)
+		for k := range next.Departures {
+			next.Departures[k].SGoal = soc(next.Departures[k].SGoal)
+		}
+	}
+	if next.Available == nil {
+		next.Available = prev.Available
+	}
+
+	req := sched.Request
+	req.Batteries = slices.Clone(req.Batteries)
+	req.Batteries[i] = next
+	sched.Request = req
+
+	if i < len(sched.Result.Batteries) {
+		res := sched.Result
+		res.Batteries = slices.Clone(res.Batteries)
+
+		b := res.Batteries[i]
+		b.Id = next.Id
+		b.StateOfCharge = slices.Clone(b.StateOfCharge)
+		for t, v := range b.StateOfCharge {
+			b.StateOfCharge[t] = soc(v)
+		}
+
+		// planned energies per interval within the power limits of next
+		limit := func(s []float32, power float32) []float32 {
+			s = slices.Clone(s)
+			for t := range min(len(s), len(req.TimeSeries.Dt)) {
+				s[t] = min(s[t], power*float32(req.TimeSeries.Dt[t])/3600)
+			}
+			return s
+		}
+		b.ChargingPower = limit(b.ChargingPower, next.CMax)
+		b.DischargingPower = limit(b.DischargingPower, next.DMax)
+
+		res.Batteries[i] = b
+		sched.Result = res
+	}
+
+	return sched, nil
+}
+
+// Handoff replaces battery i of the current schedule by next, see Handoff, and publishes the
+// result as update without re-optimizing. The source must describe next for later
+// optimizations.
+func (s *Scheduler) Handoff(ctx context.Context, i int, next client.BatteryConfig) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil {
+		return Schedule{}, errors.New("no schedule")
+	}
+
+	sched, err := Handoff(*s.current, i, next)
+	if err != nil {
+		return Schedule{}, err
+	}
+	sched.Trigger, sched.Reason = HandedOff, fmt.Sprintf("battery %d replaced", i)
+
+	if s.journal != nil {
+		if err := s.journal.Plan(ctx, sched); err != nil {
+			s.logger.Warn("journal failed", "error", err)
+		}
+	}
+
+	s.current = &sched
+
+	select {
+	case <-s.updates:
+	default:
+	}
+	s.updates <- sched
+
+	return sched, nil
+}
@@ -0,0 +1,211 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/crypt"
+	"github.com/evcc-io/optimizer/storage"
+)
+
+// journalBucket keeps the sequence number of the latest schedule per journal
+const journalBucket = "journal"
+
+// kinds of journal entries
+const (
+	journalPlan   = "plan"
+	journalIntent = "intent"
+	journalAck    = "ack"
+)
+
+type journalEntry struct {
+	Kind      string     `json:"kind"`
+	Time      time.Time  `json:"time"`
+	Schedule  *Schedule  `json:"schedule,omitempty"`
+	Setpoints []Setpoint `json:"setpoints,omitempty"`
+	Intent    uint64     `json:"intent,omitempty"` // sequence number of the acknowledged intent
+	Error     string     `json:"error,omitempty"`
+}
+
+// Journal records schedules and the setpoints applied by the controller in an append-only log
+// of a storage backend, so that a restarted controller resumes the current plan from the
+// current interval instead of waiting for the next optimization:
+//
+//	seq, err := j.Intend(ctx, setpoints)
+//	err = apply(setpoints)
+//	j.Ack(ctx, seq, err)
+//
+// Entries before the latest schedule are removed when recording a schedule, the log is bound by
+// the setpoints applied per schedule. It is safe for concurrent use.
+type Journal struct {
+	mu      sync.Mutex
+	backend storage.Backend
+	name    string
+	clock   clock.Clock
+	cipher  *crypt.Cipher
+}
+
+// Recovery is the state of the controller recorded by a journal.
+type Recovery struct {
+	// Schedule is the latest schedule, nil if none was recorded. It may have elapsed.
+	Schedule *Schedule
+	// Applied are the setpoints last acknowledged as applied since the latest schedule.
+	Applied []Setpoint
+	// Pending are setpoints intended after the last acknowledgement but not acknowledged, e.g.
+	// interrupted by the restart. Their application state is unknown, they should be applied
+	// again.
+	Pending []Setpoint
+}
+
+// JournalOption configures a Journal.
+type JournalOption func(*Journal)
+
+// WithJournalClock sets the clock timing the entries. Defaults to the system clock.
+func WithJournalClock(clk clock.Clock) JournalOption {
+	return func(j *Journal) {
+		j.clock = clk
+	}
+}
+
+// WithJournalCipher seals the entries, e.g. with the cipher of crypt.FromEnv. Entries recorded
+// without cipher remain readable.
+func WithJournalCipher(c *crypt.Cipher) JournalOption {
+	return func(j *Journal) {
+		j.cipher = c
+	}
+}
+
+// NewJournal creates a journal named name in backend.
+func NewJournal(backend storage.Backend, name string, opts ...JournalOption) *Journal {
+	j := &Journal{backend: backend, name: name, clock: clock.Real}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+func (j *Journal) append(ctx context.Context, e journalEntry) (uint64, error) {
+	e.Time = j.clock.Now()
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+
+	if b, err = j.cipher.Seal(b); err != nil {
+		return 0, err
+	}
+
+	return j.backend.Append(ctx, j.name, b)
+}
+
+// Plan records a new schedule.
+func (j *Journal) Plan(ctx context.Context, sched Schedule) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	seq, err := j.append(ctx, journalEntry{Kind: journalPlan, Schedule: &sched})
+	if err != nil {
+		return err
+	}
+
+	if err := j.backend.Put(ctx, journalBucket, j.name, []byte(strconv.FormatUint(seq, 10))); err != nil {
+		return err
+	}
+
+	// recovery starts at the latest schedule
+	return j.backend.Truncate(ctx, j.name, seq)
+}
+
+// Intend records setpoints about to be applied and returns the sequence number to acknowledge.
+func (j *Journal) Intend(ctx context.Context, setpoints []Setpoint) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.append(ctx, journalEntry{Kind: journalIntent, Setpoints: setpoints})
+}
+
+// Ack records the outcome of applying the setpoints intended as seq. Failed applications are
+// recorded with their error and stay pending.
+func (j *Journal) Ack(ctx context.Context, seq uint64, applyErr error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e := journalEntry{Kind: journalAck, Intent: seq}
+	if applyErr != nil {
+		e.Error = applyErr.Error()
+	}
+
+	_, err := j.append(ctx, e)
+	return err
+}
+
+// Recover returns the recorded state, read from the latest schedule on.
+func (j *Journal) Recover(ctx context.Context) (Recovery, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var after uint64
+
+	b, err := j.backend.Get(ctx, journalBucket, j.name)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return Recovery{}, nil
+	case err != nil:
+		return Recovery{}, err
+	default:
+		seq, err := strconv.ParseUint(string(b), 10, 64)
+		if err != nil || seq == 0 {
+			return Recovery{}, fmt.Errorf("journal %s: invalid schedule sequence %q", j.name, b)
+		}
+		after = seq - 1
+	}
+
+	entries, err := j.backend.Read(ctx, j.name, after, 0)
+	if err != nil {
+		return Recovery{}, err
+	}
+
+	var (
+		rec     Recovery
+		intents = make(map[uint64][]Setpoint)
+		last    uint64 // latest intent
+	)
+
+	for _, entry := range entries {
+		b, err := j.cipher.Open(entry.Value)
+		if err != nil {
+			return Recovery{}, fmt.Errorf("journal %s: entry %d: %w", j.name, entry.Seq, err)
+		}
+
+		var e journalEntry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return Recovery{}, fmt.Errorf("journal %s: entry %d: %w", j.name, entry.Seq, err)
+		}
+
+		switch e.Kind {
+		case journalPlan:
+			rec = Recovery{Schedule: e.Schedule}
+			clear(intents)
+		case journalIntent:
+			intents[entry.Seq], last = e.Setpoints, entry.Seq
+			rec.Pending = e.Setpoints
+		case journalAck:
+			if sp, ok := intents[e.Intent]; ok && e.Error == "" {
+				rec.Applied = sp
+				// acknowledging an earlier intent leaves the latest pending
+				if e.Intent == last {
+					rec.Pending = nil
+				}
+			}
+		}
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,403 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Override is a temporary constraint injected between optimizations, e.g. capping the grid
+// import to 8 kW for the next 2 hours:
+//
+//	sched, err := scheduler.Inject(ctx, schedule.Override{Until: now.Add(2 * time.Hour), MaxImport: 8000})
+type Override struct {
+	// From is the start of the override, zero for now.
+	From time.Time `json:"from,omitzero"`
+	// Until is the end of the override.
+	Until time.Time `json:"until"`
+	// MaxImport caps the grid import power [W], zero for no cap.
+	MaxImport float64 `json:"max_import,omitempty"`
+	// Idle holds the batteries with these indices idle, e.g. during maintenance.
+	Idle []int `json:"idle,omitempty"`
+	// Reason is recorded with the re-solved schedule, e.g. "grid operator request".
+	Reason string `json:"reason,omitempty"`
+}
+
+// Validate checks the override against a request.
+func (o Override) Validate(req client.OptimizationInput) error {
+	switch {
+	case !o.Until.After(o.From):
+		return errors.New("until must be after from")
+	case o.MaxImport < 0:
+		return fmt.Errorf("max import %v must not be negative", o.MaxImport)
+	case o.MaxImport == 0 && len(o.Idle) == 0:
+		return errors.New("neither max import nor idle batteries given")
+	}
+
+	for _, i := range o.Idle {
+		if i < 0 || i >= len(req.Batteries) {
+			return fmt.Errorf("idle battery %d not found", i)
+		}
+	}
+
+	return nil
+}
+
+// intervals returns the number of intervals of req starting at start overlapping the override
+// and the overlap of each
+func (o Override) intervals(req client.OptimizationInput, start time.Time) (int, []bool) {
+	overlap := make([]bool, len(req.TimeSeries.Dt))
+	last := 0
+
+	from := start
+	for t, dt := range req.TimeSeries.Dt {
+		to := from.Add(time.Duration(dt) * time.Second)
+		if from.Before(o.Until) && to.After(o.From) {
+			overlap[t] = true
+			last = t + 1
+		}
+		from = to
+	}
+
+	return last, overlap
+}
+
+// Apply returns req with the override applied to the intervals it overlaps, the first interval
+// starting at start. Modified series are copies.
+func (o Override) Apply(req client.OptimizationInput, start time.Time) client.OptimizationInput {
+	_, overlap := o.intervals(req, start)
+	if !slices.Contains(overlap, true) {
+		return req
+	}
+
+	n := len(req.TimeSeries.Dt)
+
+	if o.MaxImport > 0 {
+		limit := slices.Clone(req.TimeSeries.PMaxImp)
+		if len(limit) != n {
+			limit = slices.Repeat([]float32{unlimited(req)}, n)
+		}
+		for t, ok := range overlap {
+			if ok {
+				limit[t] = min(limit[t], float32(o.MaxImport))
+			}
+		}
+		req.TimeSeries.PMaxImp = limit
+	}
+
+	if len(o.Idle) > 0 {
+		req.Batteries = slices.Clone(req.Batteries)
+		for _, i := range o.Idle {
+			if i < 0 || i >= len(req.Batteries) {
+				continue
+			}
+			b := &req.Batteries[i]
+
+			available := slices.Clone(b.Available)
+			if len(available) != n {
+				available = slices.Repeat([]bool{true}, n)
+			}
+			for t, ok := range overlap {
+				if ok {
+					available[t] = false
+				}
+			}
+			b.Available = available
+
+			// goals cannot be charged for while idle
+			if len(b.PDemand) == n {
+				b.PDemand = slices.Clone(b.PDemand)
+				for t, ok := range overlap {
+					if ok {
+						b.PDemand[t] = 0
+					}
+				}
+			}
+		}
+	}
+
+	return req
+}
+
+// unlimited returns an import power limit never binding for req, for intervals without
+// override: twice the peak demand and the charge power of all batteries. The optimizer treats
+// limits with big-M constraints, which rules out arbitrarily high values.
+func unlimited(req client.OptimizationInput) float32 {
+	var peak float32
+	for t, e := range req.TimeSeries.Gt {
+		if t < len(req.TimeSeries.Dt) && req.TimeSeries.Dt[t] > 0 {
+			peak = max(peak, e*3600/float32(req.TimeSeries.Dt[t]))
+		}
+	}
+
+	var charge float32
+	for _, b := range req.Batteries {
+		charge += b.CMax
+		if b.Preconditioning != nil {
+			charge += b.Preconditioning.Power
+		}
+	}
+
+	return 2*(peak+charge) + 1000
+}
+
+// Inject applies o to the current schedule immediately and to all later optimizations until
+// it ends. Only the intervals up to the end of the override are re-solved, from the measured
+// or planned state of charge now to the planned state of charge at its end, and the rest of the
+// current plan is kept with its states of charge shifted by the difference. The objective
+// value of the result covers the re-solved intervals only. The override applies to later
+// optimizations even if the re-solve fails.
+func (s *Scheduler) Inject(ctx context.Context, o Override) (Schedule, error) {
+	now := s.clock.Now()
+	if o.From.IsZero() {
+		o.From = now
+	}
+
+	s.mu.Lock()
+	current := s.current
+	if current == nil {
+		s.mu.Unlock()
+		return Schedule{}, errors.New("no schedule")
+	}
+	if err := o.Validate(current.Request); err != nil {
+		s.mu.Unlock()
+		return Schedule{}, err
+	}
+	s.overrides = append(s.overrides, o)
+	s.mu.Unlock()
+
+	// the remaining plan from now on
+	cur, skip := *current, elapsedIntervals(current.Request, current.Start, now)
+	req := Trim(cur.Request, cur.Start, now)
+	if len(req.TimeSeries.Dt) == 0 {
+		return Schedule{}, errors.New("schedule elapsed")
+	}
+
+	soc := plannedSoC(cur, skip, now)
+	if s.measure != nil {
+		measured, err := s.measure(ctx)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("measure: %w", err)
+		}
+		if len(measured) != len(req.Batteries) {
+			return Schedule{}, fmt.Errorf("measure: %d values for %d batteries", len(measured), len(req.Batteries))
+		}
+		soc = measured
+	}
+	for i, v := range soc {
+		req.Batteries[i].SInitial = float32(v)
+	}
+
+	req = o.Apply(req, now)
+
+	// overrides beyond the horizon apply to later optimizations only
+	k, _ := o.intervals(req, now)
+	if k == 0 {
+		return cur, nil
+	}
+
+	// the restricted problem ends with the planned state of charge
+	sub := truncate(req, k)
+	for i := range sub.Batteries {
+		b := &sub.Batteries[i]
+		if i >= len(cur.Result.Batteries) || skip+k-1 >= len(cur.Result.Batteries[i].StateOfCharge) {
+			continue
+		}
+		if len(b.SGoal) != k {
+			b.SGoal = make([]float32, k)
+		}
+		b.SGoal[k-1] = max(b.SGoal[k-1], min(cur.Result.Batteries[i].StateOfCharge[skip+k-1], b.SMax))
+	}
+
+	res, err := s.solver.Solve(ctx, sub)
+	if err != nil {
+		return Schedule{}, err
+	}
+	if res.Status != client.Optimal && res.Status != client.Simulated {
+		return Schedule{}, fmt.Errorf("status %s", res.Status)
+	}
+
+	sched := Schedule{Start: now.Truncate(time.Second), Request: req, Result: splice(*res, cur.Result, skip+k, req), Trigger: Injected, Reason: o.Reason}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a schedule optimized meanwhile is not replaced by the splice of the previous one
+	if s.current != current {
+		return Schedule{}, errors.New("schedule changed during re-solve")
+	}
+
+	if s.journal != nil {
+		if err := s.journal.Plan(ctx, sched); err != nil {
+			s.logger.Warn("journal failed", "error", err)
+		}
+	}
+
+	s.current = &sched
+
+	select {
+	case <-s.updates:
+	default:
+	}
+	s.updates <- sched
+
+	return sched, nil
+}
+
+// Overrides returns the overrides not yet ended.
+func (s *Scheduler) Overrides() []Override {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.overrides = slices.DeleteFunc(s.overrides, func(o Override) bool { return !o.Until.After(now) })
+
+	return slices.Clone(s.overrides)
+}
+
+// elapsedIntervals returns the number of intervals of req fully elapsed at now, the first
+// interval starting at start
+func elapsedIntervals(req client.OptimizationInput, start, now time.Time) int {
+	skip := 0
+	for _, dt := range req.TimeSeries.Dt {
+		start = start.Add(time.Duration(dt) * time.Second)
+		if start.After(now) {
+			break
+		}
+		skip++
+	}
+	return skip
+}
+
+// plannedSoC returns the planned state of charge of each battery at now within interval t,
+// interpolated linearly within the interval
+func plannedSoC(sched Schedule, t int, now time.Time) []float64 {
+	start := sched.Start
+	for _, dt := range sched.Request.TimeSeries.Dt[:t] {
+		start = start.Add(time.Duration(dt) * time.Second)
+	}
+	frac := 1.0
+	if t < len(sched.Request.TimeSeries.Dt) {
+		frac = now.Sub(start).Seconds() / float64(sched.Request.TimeSeries.Dt[t])
+	}
+
+	soc := make([]float64, len(sched.Request.Batteries))
+	for i, b := range sched.Request.Batteries {
+		from := float64(b.SInitial)
+		if i >= len(sched.Result.Batteries) {
+			soc[i] = from
+			continue
+		}
+		series := sched.Result.Batteries[i].StateOfCharge
+		if t > 0 && t-1 < len(series) {
+			from = float64(series[t-1])
+		}
+		to := from
+		if t < len(series) {
+			to = float64(series[t])
+		}
+		soc[i] = from + frac*(to-from)
+	}
+
+	return soc
+}
+
+// truncate returns req restricted to its first k intervals
+func truncate(req client.OptimizationInput, k int) client.OptimizationInput {
+	cut := func(s []float32) []float32 {
+		if len(s) > k {
+			return s[:k:k]
+		}
+		return s
+	}
+
+	ts := &req.TimeSeries
+	ts.Dt = ts.Dt[:k:k]
+	ts.Ft, ts.Gt, ts.NCommit, ts.PMaxImp, ts.QH = cut(ts.Ft), cut(ts.Gt), cut(ts.NCommit), cut(ts.PMaxImp), cut(ts.QH)
+	ts.PN, ts.PE, ts.EmN, ts.RCurt, ts.WE, ts.PG = cut(ts.PN), cut(ts.PE), cut(ts.EmN), cut(ts.RCurt), cut(ts.WE), cut(ts.PG)
+
+	req.Batteries = slices.Clone(req.Batteries)
+	for i := range req.Batteries {
+		b := &req.Batteries[i]
+		b.PDemand, b.SGoal, b.CEtaSeries = cut(b.PDemand), slices.Clone(cut(b.SGoal)), cut(b.CEtaSeries)
+		if len(b.Available) > k {
+			b.Available = b.Available[:k:k]
+		}
+		// departures and preconditioning beyond the restricted horizon are planned already
+		b.Departures = slices.DeleteFunc(slices.Clone(b.Departures), func(d client.Departure) bool { return d.T >= k })
+		if pre := b.Preconditioning; pre != nil && pre.TEnd > k {
+			b.Preconditioning = nil
+		}
+		b.Calibration = nil
+	}
+
+	return req
+}
+
+// splice returns the result sub of the restricted problem followed by the intervals of old
+// from interval from on, with the states of charge of old shifted to continue those of sub.
+// Series not covered by both are dropped.
+func splice(sub, old client.OptimizationResult, from int, req client.OptimizationInput) client.OptimizationResult {
+	tail := func(s []float32) []float32 {
+		if from > len(s) {
+			return nil
+		}
+		return s[from:]
+	}
+	join := func(a, b []float32) []float32 {
+		return append(slices.Clone(a), b...)
+	}
+
+	res := sub
+	res.GridImport = join(sub.GridImport, tail(old.GridImport))
+	res.GridExport = join(sub.GridExport, tail(old.GridExport))
+	if from <= len(old.FlowDirection) {
+		res.FlowDirection = append(slices.Clone(sub.FlowDirection), old.FlowDirection[from:]...)
+	}
+	res.GridImportOvershoot, res.GridExportOvershoot, res.GridDeviation, res.CurtailmentRisk = nil, nil, nil, nil
+	res.Chp = nil
+
+	res.Batteries = slices.Clone(sub.Batteries)
+	for i := range res.Batteries {
+		if i >= len(old.Batteries) {
+			continue
+		}
+		b, o := &res.Batteries[i], old.Batteries[i]
+
+		var shift float32
+		if n := len(b.StateOfCharge); n > 0 && from > 0 && from-1 < len(o.StateOfCharge) {
+			shift = b.StateOfCharge[n-1] - o.StateOfCharge[from-1]
+		}
+
+		var capacity float32
+		if i < len(req.Batteries) {
+			capacity = max(req.Batteries[i].SCapacity, req.Batteries[i].SMax)
+		}
+
+		soc := slices.Clone(tail(o.StateOfCharge))
+		for t := range soc {
+			soc[t] = max(0, soc[t]+shift)
+			if capacity > 0 {
+				soc[t] = min(soc[t], capacity)
+			}
+		}
+
+		if len(b.PreconditioningPower) > 0 || len(o.PreconditioningPower) > 0 {
+			pre := b.PreconditioningPower
+			if len(pre) == 0 {
+				pre = make([]float32, len(b.ChargingPower))
+			}
+			b.PreconditioningPower = join(pre, tail(o.PreconditioningPower))
+		}
+		b.ChargingPower = join(b.ChargingPower, tail(o.ChargingPower))
+		b.DischargingPower = join(b.DischargingPower, tail(o.DischargingPower))
+		b.StateOfCharge = append(b.StateOfCharge, soc...)
+		b.GoalShortfalls = nil
+	}
+
+	return res
+}
@@ -0,0 +1,416 @@
+// Package schedule runs the optimizer in a rolling horizon: plans are re-optimized periodically
+// with the measured state of charge, and the setpoints of the current interval are exposed to
+// the controller. An optional journal lets restarted controllers resume the current plan.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/clock"
+)
+
+// Solver solves an optimization problem, e.g. *client.ClientWithResponses.
+type Solver interface {
+	Solve(ctx context.Context, req client.OptimizationInput, reqEditors ...client.RequestEditorFn) (*client.OptimizationResult, error)
+}
+
+// Source returns the request for the horizon starting at start, the start of the current slot.
+// Its first interval is a full slot, it is shortened to the remaining time by the scheduler.
+type Source func(ctx context.Context, start time.Time) (client.OptimizationInput, error)
+
+// Measure returns the measured state of charge of each battery of the request [Wh].
+type Measure func(ctx context.Context) ([]float64, error)
+
+// Setpoint is the planned power of a battery in the current interval.
+type Setpoint struct {
+	Battery   int
+	Charge    float64 // charge power [W]
+	Discharge float64 // discharge power [W]
+	Until     time.Time
+}
+
+// Trigger is what caused a schedule.
+type Trigger string
+
+const (
+	// Periodic schedules are optimized by Run.
+	Periodic Trigger = "periodic"
+	// Early schedules are optimized by Optimize before the next periodic optimization.
+	Early Trigger = "early"
+	// Injected schedules are re-solved for an override, see Inject.
+	Injected Trigger = "override"
+	// HandedOff schedules replace a battery without re-optimizing, see Handoff.
+	HandedOff Trigger = "handoff"
+)
+
+// Schedule is an optimized plan.
+type Schedule struct {
+	Start   time.Time // start of the first interval
+	Request client.OptimizationInput
+	Result  client.OptimizationResult
+	// Trigger and Reason tell why the schedule was computed, empty if unknown.
+	Trigger Trigger `json:",omitempty"`
+	Reason  string  `json:",omitempty"`
+}
+
+// Setpoints returns the setpoints of all batteries for the interval containing at. ok is false
+// outside the planned horizon.
+func (s Schedule) Setpoints(at time.Time) ([]Setpoint, bool) {
+	return s.SetpointsAhead(at, nil)
+}
+
+// SetpointsAhead returns the setpoints of all batteries to command at, with each battery
+// looking ahead by its actuation latency, indexed like the batteries of the result. Slow
+// devices are commanded the power of the next interval early, so that they reach it at its
+// start. Until is shifted by the latency accordingly, setpoints beyond the horizon are zero
+// until its end. ok is false outside the planned horizon.
+func (s Schedule) SetpointsAhead(at time.Time, latency []time.Duration) ([]Setpoint, bool) {
+	end := s.Start
+	for _, d := range s.Request.TimeSeries.Dt {
+		end = end.Add(time.Duration(d) * time.Second)
+	}
+	if at.Before(s.Start) || !at.Before(end) {
+		return nil, false
+	}
+
+	res := make([]Setpoint, 0, len(s.Result.Batteries))
+	for i, b := range s.Result.Batteries {
+		var lead time.Duration
+		if i < len(latency) {
+			lead = max(latency[i], 0)
+		}
+
+		sp := Setpoint{Battery: i, Until: end}
+
+		from := s.Start
+		for t, d := range s.Request.TimeSeries.Dt {
+			to := from.Add(time.Duration(d) * time.Second)
+			if at.Add(lead).Before(to) {
+				sp.Until = to.Add(-lead)
+				if t < len(b.ChargingPower) {
+					sp.Charge = float64(b.ChargingPower[t]) * 3600 / float64(d)
+				}
+				if t < len(b.DischargingPower) {
+					sp.Discharge = float64(b.DischargingPower[t]) * 3600 / float64(d)
+				}
+				break
+			}
+			from = to
+		}
+
+		res = append(res, sp)
+	}
+
+	return res, true
+}
+
+// Trim removes the intervals of req elapsed at now, with the first interval starting at start.
+// The interval containing now is shortened to the remaining time, its energies are scaled
+// accordingly while prices and goals are kept. Trimmed series are copies, preconditioning
+// windows are shifted.
+func Trim(req client.OptimizationInput, start, now time.Time) client.OptimizationInput {
+	dt := req.TimeSeries.Dt
+	elapsed := int(now.Sub(start) / time.Second)
+
+	skip := 0
+	for skip < len(dt) && elapsed >= dt[skip] {
+		elapsed -= dt[skip]
+		skip++
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	// fraction of the first remaining interval left
+	frac := 1.0
+	if skip < len(dt) {
+		frac = float64(dt[skip]-elapsed) / float64(dt[skip])
+	}
+
+	energy := func(s []float32) []float32 {
+		if len(s) <= skip {
+			return nil
+		}
+		res := slices.Clone(s[skip:])
+		res[0] = float32(float64(res[0]) * frac)
+		return res
+	}
+	tail := func(s []float32) []float32 {
+		if len(s) <= skip {
+			return nil
+		}
+		return slices.Clone(s[skip:])
+	}
+
+	ts := req.TimeSeries
+	if skip < len(dt) {
+		ts.Dt = slices.Clone(dt[skip:])
+		ts.Dt[0] -= elapsed
+	} else {
+		ts.Dt = []int{}
+	}
+	ts.Ft, ts.Gt, ts.NCommit, ts.QH = energy(ts.Ft), energy(ts.Gt), energy(ts.NCommit), energy(ts.QH)
+	ts.PN, ts.PE, ts.EmN, ts.RCurt, ts.WE = tail(ts.PN), tail(ts.PE), tail(ts.EmN), tail(ts.RCurt), tail(ts.WE)
+	ts.PMaxImp, ts.PG = tail(ts.PMaxImp), tail(ts.PG)
+	if ts.PN == nil {
+		ts.PN = []float32{}
+	}
+	if ts.PE == nil {
+		ts.PE = []float32{}
+	}
+	req.TimeSeries = ts
+
+	req.Batteries = slices.Clone(req.Batteries)
+	for i := range req.Batteries {
+		b := &req.Batteries[i]
+		b.PDemand = energy(b.PDemand)
+		b.SGoal = tail(b.SGoal)
+		b.CEtaSeries = tail(b.CEtaSeries)
+		if len(b.Available) > skip {
+			b.Available = slices.Clone(b.Available[skip:])
+		} else {
+			b.Available = nil
+		}
+		// preconditioning already running is shortened to the rest of its window
+		if pre := b.Preconditioning; pre != nil {
+			if pre.TEnd <= skip {
+				b.Preconditioning = nil
+			} else {
+				p := *pre
+				p.TStart, p.TEnd = max(0, p.TStart-skip), p.TEnd-skip
+				p.Steps = min(p.Steps, p.TEnd-p.TStart)
+				b.Preconditioning = &p
+			}
+		}
+	}
+
+	return req
+}
+
+// Scheduler re-optimizes periodically. It is safe for concurrent use.
+type Scheduler struct {
+	solver   Solver
+	source   Source
+	measure  Measure
+	slot     time.Duration
+	interval time.Duration
+	clock    clock.Clock
+	logger   *slog.Logger
+	journal  *Journal
+	latency  []time.Duration
+	updates  chan Schedule
+
+	mu        sync.Mutex
+	current   *Schedule
+	overrides []Override
+}
+
+// Option configures the scheduler.
+type Option func(*Scheduler)
+
+// WithSlot sets the length of the intervals of the source, the horizon starts at the beginning
+// of the current slot. Default is 15 minutes.
+func WithSlot(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.slot = d
+	}
+}
+
+// WithInterval sets the period of re-optimization. Default is 15 minutes.
+func WithInterval(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.interval = d
+	}
+}
+
+// WithMeasure sets the initial state of charge of each battery from measurements before each
+// optimization. Without, the state of charge of the source is used.
+func WithMeasure(fn Measure) Option {
+	return func(s *Scheduler) {
+		s.measure = fn
+	}
+}
+
+// WithClock sets the clock. Defaults to the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(s *Scheduler) {
+		s.clock = clk
+	}
+}
+
+// WithLogger sets the logger for failed optimizations. Defaults to slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Scheduler) {
+		s.logger = logger
+	}
+}
+
+// WithJournal records the schedules in j, so that Resume restores the current schedule after a
+// restart. Failed records are logged.
+func WithJournal(j *Journal) Option {
+	return func(s *Scheduler) {
+		s.journal = j
+	}
+}
+
+// WithLatency sets the actuation latency of each battery, indexed like the batteries of the
+// request. Current looks ahead by the latency, see Schedule.SetpointsAhead.
+func WithLatency(latency ...time.Duration) Option {
+	return func(s *Scheduler) {
+		s.latency = latency
+	}
+}
+
+// New creates a scheduler solving the requests of source.
+func New(solver Solver, source Source, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		solver:   solver,
+		source:   source,
+		slot:     15 * time.Minute,
+		interval: 15 * time.Minute,
+		clock:    clock.Real,
+		logger:   slog.Default(),
+		updates:  make(chan Schedule, 1),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+// Optimize computes a new schedule starting now and makes it current, e.g. for re-optimizing
+// early after drift from the plan. reason is recorded with the schedule.
+func (s *Scheduler) Optimize(ctx context.Context, reason string) (Schedule, error) {
+	return s.optimize(ctx, Early, reason)
+}
+
+func (s *Scheduler) optimize(ctx context.Context, trigger Trigger, reason string) (Schedule, error) {
+	now := s.clock.Now()
+	start := now.Truncate(s.slot)
+
+	req, err := s.source(ctx, start)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("source: %w", err)
+	}
+
+	req = Trim(req, start, now)
+	if len(req.TimeSeries.Dt) == 0 {
+		return Schedule{}, errors.New("source: horizon elapsed")
+	}
+
+	if s.measure != nil {
+		soc, err := s.measure(ctx)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("measure: %w", err)
+		}
+		if len(soc) != len(req.Batteries) {
+			return Schedule{}, fmt.Errorf("measure: %d values for %d batteries", len(soc), len(req.Batteries))
+		}
+		for i, v := range soc {
+			req.Batteries[i].SInitial = float32(v)
+		}
+	}
+
+	for _, o := range s.Overrides() {
+		req = o.Apply(req, now)
+	}
+
+	res, err := s.solver.Solve(ctx, req)
+	if err != nil {
+		return Schedule{}, err
+	}
+	// simulated results are heuristic plans, e.g. of a client fallback
+	if res.Status != client.Optimal && res.Status != client.Simulated {
+		return Schedule{}, fmt.Errorf("status %s", res.Status)
+	}
+
+	sched := Schedule{Start: now.Truncate(time.Second), Request: req, Result: *res, Trigger: trigger, Reason: reason}
+
+	if s.journal != nil {
+		if err := s.journal.Plan(ctx, sched); err != nil {
+			s.logger.Warn("journal failed", "error", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current = &sched
+
+	// keep only the latest update for slow consumers
+	select {
+	case <-s.updates:
+	default:
+	}
+	s.updates <- sched
+
+	return sched, nil
+}
+
+// Resume restores the latest schedule of the journal as current unless it has elapsed, e.g. on
+// startup before Run, and returns the recorded state for re-applying pending setpoints.
+func (s *Scheduler) Resume(ctx context.Context) (Recovery, error) {
+	if s.journal == nil {
+		return Recovery{}, errors.New("no journal")
+	}
+
+	rec, err := s.journal.Recover(ctx)
+	if err != nil || rec.Schedule == nil {
+		return rec, err
+	}
+
+	if _, ok := rec.Schedule.Setpoints(s.clock.Now()); ok {
+		s.mu.Lock()
+		if s.current == nil {
+			sched := *rec.Schedule
+			s.current = &sched
+		}
+		s.mu.Unlock()
+	}
+
+	return rec, nil
+}
+
+// Run optimizes immediately and then periodically until ctx is cancelled. Failed optimizations
+// are logged, the previous schedule stays current until it elapses.
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		if _, err := s.optimize(ctx, Periodic, ""); err != nil && ctx.Err() == nil {
+			s.logger.Warn("optimization failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.clock.After(s.interval):
+		}
+	}
+}
+
+// Current returns the setpoints of the current schedule to command now, ahead by the latencies
+// of WithLatency. ok is false if there is no schedule or it has elapsed.
+func (s *Scheduler) Current() ([]Setpoint, bool) {
+	s.mu.Lock()
+	sched := s.current
+	s.mu.Unlock()
+
+	if sched == nil {
+		return nil, false
+	}
+
+	return sched.SetpointsAhead(s.clock.Now(), s.latency)
+}
+
+// Updates returns the channel of new schedules. Only the latest schedule is buffered.
+func (s *Scheduler) Updates() <-chan Schedule {
+	return s.updates
+}
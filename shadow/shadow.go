@@ -0,0 +1,392 @@
+// Package shadow runs optimized plans in shadow operation: the setpoints the controller would
+// have commanded are recorded alongside the measured operation of the site without actuating
+// anything, and a report estimates the savings the plans would have achieved, e.g. to try
+// optimization for some weeks before handing over control:
+//
+//	s := shadow.New(backend, measure, shadow.WithPeriod(14*24*time.Hour))
+//	go scheduler.Run(ctx)
+//	err := s.Run(ctx, scheduler.Updates())
+//	rep, err := s.Report(ctx, time.Time{}, time.Local)
+//
+// Intervals are kept in an append-only log of a storage backend, so that shadow operation
+// continues after a restart, and removed once older than the retention of WithRetention.
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/crypt"
+	"github.com/evcc-io/optimizer/schedule"
+	"github.com/evcc-io/optimizer/storage"
+)
+
+// intervalsLog is the log of the recorded intervals
+const intervalsLog = "shadow"
+
+// readPage is the number of entries read at once when reporting
+const readPage = 100
+
+// Measurement are the energies measured in an interval [Wh].
+type Measurement struct {
+	GridImport float64 `json:"grid_import"`
+	GridExport float64 `json:"grid_export"`
+	// Charge and Discharge of each battery under its own control, indexed like the batteries
+	// of the request.
+	Charge    []float64 `json:"charge,omitempty"`
+	Discharge []float64 `json:"discharge,omitempty"`
+}
+
+// Measure returns the energies measured from from to to.
+type Measure func(ctx context.Context, from, to time.Time) (Measurement, error)
+
+// Interval is a recorded interval of shadow operation.
+type Interval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Setpoints would have been commanded in the interval.
+	Setpoints []schedule.Setpoint `json:"setpoints"`
+	Measured  Measurement         `json:"measured"`
+	// ImportPrice and ExportPrice of the interval as planned [currency unit/Wh].
+	ImportPrice float64 `json:"import_price"`
+	ExportPrice float64 `json:"export_price"`
+}
+
+// battery returns the net energy charged into the batteries by the setpoints and as measured [Wh]
+func (i Interval) battery() (planned, measured float64) {
+	hours := i.End.Sub(i.Start).Hours()
+	for _, sp := range i.Setpoints {
+		planned += (sp.Charge - sp.Discharge) * hours
+	}
+	for _, e := range i.Measured.Charge {
+		measured += e
+	}
+	for _, e := range i.Measured.Discharge {
+		measured -= e
+	}
+	return planned, measured
+}
+
+// Cost is the measured cost of the interval [currency unit].
+func (i Interval) Cost() float64 {
+	return i.Measured.GridImport*i.ImportPrice - i.Measured.GridExport*i.ExportPrice
+}
+
+// ShadowCost is the cost of the interval had the setpoints been applied [currency unit]. The
+// grid exchange is the measured one with the measured battery energies replaced by the
+// setpoints, so that the actual demand and generation apply instead of their forecasts.
+func (i Interval) ShadowCost() float64 {
+	planned, measured := i.battery()
+
+	net := i.Measured.GridImport - i.Measured.GridExport + planned - measured
+	return math.Max(net, 0)*i.ImportPrice - math.Max(-net, 0)*i.ExportPrice
+}
+
+// Shadow records the intervals of shadow operation. It is safe for concurrent use.
+type Shadow struct {
+	backend   storage.Backend
+	measure   Measure
+	slot      time.Duration
+	period    time.Duration
+	retention time.Duration
+	clock     clock.Clock
+	cipher    *crypt.Cipher
+	logger    *slog.Logger
+}
+
+// Option configures shadow operation.
+type Option func(*Shadow)
+
+// WithSlot sets the length of the recorded intervals, matching the slot of the scheduler.
+// Default is 15 minutes.
+func WithSlot(d time.Duration) Option {
+	return func(s *Shadow) {
+		s.slot = d
+	}
+}
+
+// WithPeriod ends shadow operation once the recorded intervals span d, e.g. 14 days. Without,
+// Run continues until cancelled.
+func WithPeriod(d time.Duration) Option {
+	return func(s *Shadow) {
+		s.period = d
+	}
+}
+
+// WithRetention removes intervals starting more than d ago when recording. It should exceed
+// the period of WithPeriod, which counts from the first interval kept. Defaults to keeping all
+// intervals.
+func WithRetention(d time.Duration) Option {
+	return func(s *Shadow) {
+		s.retention = d
+	}
+}
+
+// WithClock sets the clock. Defaults to the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(s *Shadow) {
+		s.clock = clk
+	}
+}
+
+// WithCipher seals the recorded intervals, e.g. with the cipher of crypt.FromEnv. Intervals
+// recorded without cipher remain readable.
+func WithCipher(c *crypt.Cipher) Option {
+	return func(s *Shadow) {
+		s.cipher = c
+	}
+}
+
+// WithLogger sets the logger for failed measurements and records. Defaults to slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Shadow) {
+		s.logger = logger
+	}
+}
+
+// New creates shadow operation recording to backend with the energies of measure.
+func New(backend storage.Backend, measure Measure, opts ...Option) *Shadow {
+	s := &Shadow{
+		backend: backend,
+		measure: measure,
+		slot:    15 * time.Minute,
+		clock:   clock.Real,
+		logger:  slog.Default(),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+// Run records an interval at the end of each slot with the setpoints of the latest schedule
+// received from updates, e.g. Scheduler.Updates, until ctx is cancelled or the period of
+// WithPeriod has passed. The interval running at the start is recorded from then on. Intervals
+// without schedule or failed measurements are logged and skipped.
+func (s *Shadow) Run(ctx context.Context, updates <-chan schedule.Schedule) error {
+	first, err := s.first(ctx)
+	if err != nil {
+		return err
+	}
+
+	var current *schedule.Schedule
+
+	from := s.clock.Now()
+	to := from.Truncate(s.slot).Add(s.slot)
+
+	for {
+		if s.period > 0 && !first.IsZero() && !from.Before(first.Add(s.period)) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case sched := <-updates:
+			current = &sched
+
+		case <-s.clock.After(to.Sub(s.clock.Now())):
+			iv, err := s.interval(ctx, current, from, to)
+			if err == nil {
+				err = s.Record(ctx, iv)
+			}
+
+			switch {
+			case err != nil:
+				s.logger.Error("shadow operation", "from", from, "error", err)
+			case first.IsZero():
+				first = from
+			}
+
+			from, to = to, to.Add(s.slot)
+		}
+	}
+}
+
+// first returns the start of the first recorded interval, zero if none
+func (s *Shadow) first(ctx context.Context) (time.Time, error) {
+	entries, err := s.backend.Read(ctx, intervalsLog, 0, 1)
+	if err != nil || len(entries) == 0 {
+		return time.Time{}, err
+	}
+
+	iv, err := s.decode(entries[0])
+	return iv.Start, err
+}
+
+// interval returns the interval from from to to with the setpoints and prices of sched
+func (s *Shadow) interval(ctx context.Context, sched *schedule.Schedule, from, to time.Time) (Interval, error) {
+	if sched == nil {
+		return Interval{}, errors.New("no schedule")
+	}
+
+	setpoints, ok := sched.Setpoints(from)
+	if !ok {
+		return Interval{}, fmt.Errorf("schedule does not cover %v", from)
+	}
+
+	iv := Interval{Start: from, End: to, Setpoints: setpoints}
+
+	// prices of the planned interval containing from
+	ts := sched.Request.TimeSeries
+	end := sched.Start
+	for t, d := range ts.Dt {
+		if end = end.Add(time.Duration(d) * time.Second); from.Before(end) {
+			if t < len(ts.PN) {
+				iv.ImportPrice = float64(ts.PN[t])
+			}
+			if t < len(ts.PE) {
+				iv.ExportPrice = float64(ts.PE[t])
+			}
+			break
+		}
+	}
+
+	m, err := s.measure(ctx, from, to)
+	if err != nil {
+		return Interval{}, fmt.Errorf("measure: %w", err)
+	}
+	iv.Measured = m
+
+	return iv, nil
+}
+
+// Record stores an interval, e.g. when driving shadow operation without Run.
+func (s *Shadow) Record(ctx context.Context, iv Interval) error {
+	b, err := json.Marshal(iv)
+	if err != nil {
+		return err
+	}
+
+	if b, err = s.cipher.Seal(b); err != nil {
+		return err
+	}
+
+	if _, err := s.backend.Append(ctx, intervalsLog, b); err != nil || s.retention <= 0 {
+		return err
+	}
+
+	return s.Prune(ctx, s.clock.Now().Add(-s.retention))
+}
+
+// Prune removes the intervals starting before before.
+func (s *Shadow) Prune(ctx context.Context, before time.Time) error {
+	return storage.Prune(ctx, s.backend, intervalsLog, func(e storage.Entry) (bool, error) {
+		iv, err := s.decode(e)
+		return !iv.Start.Before(before), err
+	})
+}
+
+func (s *Shadow) decode(e storage.Entry) (Interval, error) {
+	b, err := s.cipher.Open(e.Value)
+	if err != nil {
+		return Interval{}, fmt.Errorf("interval %d: %w", e.Seq, err)
+	}
+
+	var iv Interval
+	if err := json.Unmarshal(b, &iv); err != nil {
+		return Interval{}, fmt.Errorf("interval %d: %w", e.Seq, err)
+	}
+	return iv, nil
+}
+
+// Intervals returns the intervals starting at or after since in the order of recording.
+func (s *Shadow) Intervals(ctx context.Context, since time.Time) ([]Interval, error) {
+	var (
+		res   []Interval
+		after uint64
+	)
+
+	for {
+		entries, err := s.backend.Read(ctx, intervalsLog, after, readPage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			iv, err := s.decode(e)
+			if err != nil {
+				return nil, err
+			}
+			if !iv.Start.Before(since) {
+				res = append(res, iv)
+			}
+			after = e.Seq
+		}
+
+		if len(entries) < readPage {
+			return res, nil
+		}
+	}
+}
+
+// Day are the costs of a day [currency unit].
+type Day struct {
+	Date       time.Time // midnight
+	Intervals  int
+	Cost       float64
+	ShadowCost float64
+}
+
+// Savings are the costs saved had the setpoints been applied, negative for additional cost.
+func (d Day) Savings() float64 {
+	return d.Cost - d.ShadowCost
+}
+
+// Report are the hypothetical savings of shadow operation. The battery states of charge of
+// the plans are measured before each optimization, so the savings are an estimate assuming the
+// batteries could have followed the setpoints.
+type Report struct {
+	Days []Day
+	// Total are the costs of all days, dated at the first day.
+	Total Day
+}
+
+// Report summarizes the intervals starting at or after since by day in loc.
+func (s *Shadow) Report(ctx context.Context, since time.Time, loc *time.Location) (Report, error) {
+	intervals, err := s.Intervals(ctx, since)
+	if err != nil {
+		return Report{}, err
+	}
+
+	return Summarize(intervals, loc), nil
+}
+
+// Summarize summarizes intervals by day in loc.
+func Summarize(intervals []Interval, loc *time.Location) Report {
+	var rep Report
+
+	for _, iv := range intervals {
+		start := iv.Start.In(loc)
+		date := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+
+		if n := len(rep.Days); n == 0 || !rep.Days[n-1].Date.Equal(date) {
+			rep.Days = append(rep.Days, Day{Date: date})
+		}
+
+		day := &rep.Days[len(rep.Days)-1]
+		day.Intervals++
+		day.Cost += iv.Cost()
+		day.ShadowCost += iv.ShadowCost()
+	}
+
+	for i, day := range rep.Days {
+		if i == 0 {
+			rep.Total.Date = day.Date
+		}
+		rep.Total.Intervals += day.Intervals
+		rep.Total.Cost += day.Cost
+		rep.Total.ShadowCost += day.ShadowCost
+	}
+
+	return rep
+}
@@ -0,0 +1,255 @@
+// Package device emulates batteries behind the setpoint interface of real integrations, for
+// end-to-end tests of the control loop of solving, commanding, measuring and re-solving
+// without hardware:
+//
+//	clk := clock.NewFake(time.Now())
+//	dev := device.New([]device.Config{{Capacity: 10000, SoC: 5000, MaxCharge: 5000, MaxDischarge: 5000}}, device.WithClock(clk))
+//	sched := schedule.New(solver, source, schedule.WithClock(clk), schedule.WithMeasure(dev.Measure))
+//	go dev.Control(ctx, sched, time.Minute)
+//
+// The state of charge is integrated over the time of the clock from the power in effect.
+package device
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/schedule"
+)
+
+// Config describes an emulated battery.
+type Config struct {
+	Capacity     float64 // usable capacity [Wh]
+	SoC          float64 // initial state of charge [Wh]
+	MaxCharge    float64 // charge power limit [W], zero for none
+	MaxDischarge float64 // discharge power limit [W], zero for none
+	EtaC, EtaD   float64 // charge and discharge efficiency, zero for lossless
+	// Noise is the standard deviation of the measured state of charge [Wh].
+	Noise float64
+	// Latency delays commanded setpoints until they take effect.
+	Latency time.Duration
+}
+
+// command is a power setpoint taking effect at a time
+type command struct {
+	at    time.Time
+	power float64 // positive for charging [W]
+	until time.Time
+}
+
+type battery struct {
+	cfg      Config
+	soc      float64
+	power    float64   // power in effect [W]
+	until    time.Time // end of the setpoint in effect, zero for none
+	commands []command // pending commands by time
+}
+
+// Emulator emulates batteries. It is safe for concurrent use.
+type Emulator struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	rand      *rand.Rand
+	batteries []battery
+	last      time.Time // time the state of charge is integrated to
+}
+
+// Option configures an emulator.
+type Option func(*Emulator)
+
+// WithClock sets the clock the state of charge is integrated over. Defaults to the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(e *Emulator) {
+		e.clock = clk
+	}
+}
+
+// WithSeed seeds the measurement noise for reproducible tests. Defaults to a random seed.
+func WithSeed(seed uint64) Option {
+	return func(e *Emulator) {
+		e.rand = rand.New(rand.NewPCG(seed, seed))
+	}
+}
+
+// New creates an emulator of batteries, indexed like the batteries of the request.
+func New(batteries []Config, opts ...Option) *Emulator {
+	e := &Emulator{
+		clock: clock.Real,
+		rand:  rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	for _, cfg := range batteries {
+		e.batteries = append(e.batteries, battery{cfg: cfg, soc: min(max(cfg.SoC, 0), cfg.Capacity)})
+	}
+	e.last = e.clock.Now()
+
+	return e
+}
+
+// Apply commands the setpoints, each taking effect after the latency of its battery until the
+// end of the setpoint. Batteries without setpoint in effect are idle, like devices falling back
+// to idle when commands stop.
+func (e *Emulator) Apply(ctx context.Context, setpoints []schedule.Setpoint) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.clock.Now()
+	e.advance(now)
+
+	for _, sp := range setpoints {
+		if sp.Battery < 0 || sp.Battery >= len(e.batteries) {
+			return fmt.Errorf("battery %d not found", sp.Battery)
+		}
+		if sp.Charge < 0 || sp.Discharge < 0 {
+			return fmt.Errorf("battery %d: negative setpoint", sp.Battery)
+		}
+
+		b := &e.batteries[sp.Battery]
+		c := command{at: now.Add(b.cfg.Latency), power: sp.Charge - sp.Discharge, until: sp.Until}
+		if b.cfg.Latency == 0 {
+			b.power, b.until = c.power, c.until
+			continue
+		}
+		b.commands = append(b.commands, c)
+	}
+
+	return nil
+}
+
+// Measure returns the state of charge of each battery with measurement noise, see
+// schedule.WithMeasure.
+func (e *Emulator) Measure(ctx context.Context) ([]float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.advance(e.clock.Now())
+
+	soc := make([]float64, len(e.batteries))
+	for i, b := range e.batteries {
+		soc[i] = b.soc
+		if b.cfg.Noise > 0 {
+			soc[i] = min(max(soc[i]+e.rand.NormFloat64()*b.cfg.Noise, 0), b.cfg.Capacity)
+		}
+	}
+
+	return soc, nil
+}
+
+// SoC returns the state of charge of each battery without measurement noise.
+func (e *Emulator) SoC() []float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.advance(e.clock.Now())
+
+	soc := make([]float64, len(e.batteries))
+	for i, b := range e.batteries {
+		soc[i] = b.soc
+	}
+	return soc
+}
+
+// Power returns the power in effect of each battery within its limits [W], positive for
+// charging. Full or empty batteries report the commanded power.
+func (e *Emulator) Power() []float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.clock.Now()
+	e.advance(now)
+
+	power := make([]float64, len(e.batteries))
+	for i, b := range e.batteries {
+		if b.until.IsZero() || now.Before(b.until) {
+			power[i] = b.limit()
+		}
+	}
+	return power
+}
+
+// advance integrates the state of charge up to now, applying commands taking effect meanwhile
+func (e *Emulator) advance(now time.Time) {
+	for i := range e.batteries {
+		b := &e.batteries[i]
+		from := e.last
+
+		for len(b.commands) > 0 && !b.commands[0].at.After(now) {
+			c := b.commands[0]
+			b.integrate(from, c.at)
+			b.power, b.until = c.power, c.until
+			from, b.commands = c.at, b.commands[1:]
+		}
+
+		b.integrate(from, now)
+	}
+
+	if now.After(e.last) {
+		e.last = now
+	}
+}
+
+// integrate charges or discharges the battery from from to to with the power in effect,
+// limited by the power limits and the capacity
+func (b *battery) integrate(from, to time.Time) {
+	// the setpoint ended
+	if !b.until.IsZero() && b.until.Before(to) {
+		to = b.until
+	}
+	if !to.After(from) || b.power == 0 {
+		return
+	}
+
+	energy := b.limit() * to.Sub(from).Hours()
+	switch {
+	case energy > 0 && b.cfg.EtaC > 0:
+		energy *= b.cfg.EtaC
+	case energy < 0 && b.cfg.EtaD > 0:
+		energy /= b.cfg.EtaD
+	}
+
+	b.soc = min(max(b.soc+energy, 0), b.cfg.Capacity)
+}
+
+// limit returns the power in effect within the power limits
+func (b *battery) limit() float64 {
+	power := b.power
+	if b.cfg.MaxCharge > 0 {
+		power = min(power, b.cfg.MaxCharge)
+	}
+	if b.cfg.MaxDischarge > 0 {
+		power = max(power, -b.cfg.MaxDischarge)
+	}
+	return power
+}
+
+// Control applies the current setpoints of the scheduler every interval until ctx is
+// cancelled, like the control loop of a controller. The scheduler runs separately, e.g. by
+// schedule.Scheduler.Run.
+func (e *Emulator) Control(ctx context.Context, s *schedule.Scheduler, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+
+	for {
+		if setpoints, ok := s.Current(); ok {
+			if err := e.Apply(ctx, setpoints); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.clock.After(interval):
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package device
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/mock"
+	"github.com/evcc-io/optimizer/schedule"
+)
+
+const slot = 15 * time.Minute
+
+// source plans an hour of quarter hours from t0, charging the battery from a surplus of 1000 Wh
+// per slot to its maximum of 6000 Wh in the first half hour and covering a demand of 250 Wh per
+// slot in the second
+func source(t0 time.Time) schedule.Source {
+	ft := []float32{1000, 1000, 0, 0}
+	gt := []float32{0, 0, 250, 250}
+
+	return func(ctx context.Context, start time.Time) (client.OptimizationInput, error) {
+		k := min(int(start.Sub(t0)/slot), len(ft))
+		n := len(ft) - k
+
+		dt := make([]int, n)
+		for t := range dt {
+			dt[t] = int(slot / time.Second)
+		}
+
+		return client.OptimizationInput{
+			Batteries: []client.BatteryConfig{{Id: "home", Name: "Home", SMin: 0, SMax: 6000, SInitial: 5000, CMax: 2000, DMax: 2000}},
+			TimeSeries: client.TimeSeries{
+				Dt: dt,
+				Ft: ft[k:],
+				Gt: gt[k:],
+				PN: make([]float32, n),
+				PE: make([]float32, n),
+			},
+		}, nil
+	}
+}
+
+// waitFor waits until cond holds, e.g. until goroutines block on the fake clock
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEmulatorControlLoop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := mock.NewServer()
+	defer srv.Close()
+
+	c, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t0 := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(t0)
+
+	dev := New([]Config{{Capacity: 10000, SoC: 5000, MaxCharge: 5000, MaxDischarge: 5000}}, WithClock(clk))
+	sched := schedule.New(c, source(t0), schedule.WithClock(clk), schedule.WithMeasure(dev.Measure))
+
+	if _, err := sched.Optimize(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- dev.Control(ctx, sched, time.Minute) }()
+
+	// state of charge at the start of each slot: charging at 2000 W until full, then
+	// discharging at 1000 W to cover the demand
+	expected := []float64{5000, 5500, 6000, 5750, 5500}
+
+	for k, soc := range expected {
+		if got := dev.SoC()[0]; math.Abs(got-soc) > 1e-6 {
+			t.Errorf("slot %d: expected state of charge %v, got %v", k, soc, got)
+		}
+		if k == len(expected)-1 {
+			break
+		}
+
+		// re-optimize with the measured state of charge at the start of the slot
+		if k > 0 {
+			if _, err := sched.Optimize(ctx, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for range slot / time.Minute {
+			waitFor(t, func() bool { return clk.Waiters() == 1 })
+			clk.Advance(time.Minute)
+		}
+	}
+
+	// each optimization started from the measured state of charge
+	reqs := srv.Requests()
+	if len(reqs) != len(expected)-1 {
+		t.Fatalf("expected %d requests, got %d", len(expected)-1, len(reqs))
+	}
+	for k, req := range reqs {
+		if got := req.Batteries[0].SInitial; math.Abs(float64(got)-expected[k]) > 1e-3 {
+			t.Errorf("request %d: expected initial state of charge %v, got %v", k, expected[k], got)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestEmulatorLatencyAndLimits(t *testing.T) {
+	t0 := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(t0)
+
+	dev := New([]Config{{Capacity: 1000, SoC: 900, MaxCharge: 2000, Latency: time.Minute}}, WithClock(clk))
+
+	// commanded above the charge limit, taking effect after a minute
+	if err := dev.Apply(context.Background(), []schedule.Setpoint{{Battery: 0, Charge: 6000, Until: t0.Add(time.Hour)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	clk.Advance(time.Minute)
+	if got := dev.SoC()[0]; got != 900 {
+		t.Errorf("expected no change before the latency elapsed, got %v", got)
+	}
+
+	// 2000 W for 1.5 minutes charge 50 Wh
+	clk.Advance(90 * time.Second)
+	if got := dev.SoC()[0]; math.Abs(got-950) > 1e-6 {
+		t.Errorf("expected 950, got %v", got)
+	}
+
+	// charging stops at the capacity
+	clk.Advance(time.Hour)
+	if got := dev.SoC()[0]; got != 1000 {
+		t.Errorf("expected full battery, got %v", got)
+	}
+
+	if err := dev.Apply(context.Background(), []schedule.Setpoint{{Battery: 1}}); err == nil {
+		t.Error("expected error for unknown battery")
+	}
+}
@@ -0,0 +1,241 @@
+// Package smooth post-processes optimization results, smoothing small rapid oscillations of
+// battery setpoints for hardware that reacts badly to jittery commands.
+package smooth
+
+import (
+	"slices"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Filter smooths a series of net battery energies per interval (positive for charging).
+type Filter func([]float64) []float64
+
+// MovingAverage averages each interval with its neighbours within a centered window of
+// the given number of intervals.
+func MovingAverage(window int) Filter {
+	return func(in []float64) []float64 {
+		half := max(0, window/2)
+		out := make([]float64, len(in))
+
+		for t := range in {
+			lo, hi := max(0, t-half), min(len(in), t+half+1)
+
+			var sum float64
+			for _, v := range in[lo:hi] {
+				sum += v
+			}
+			out[t] = sum / float64(hi-lo)
+		}
+
+		return out
+	}
+}
+
+// MinDwell holds setpoint changes for at least the given number of intervals. Changes
+// smaller than tolerance Wh are not considered a change.
+func MinDwell(intervals int, tolerance float64) Filter {
+	return func(in []float64) []float64 {
+		out := slices.Clone(in)
+
+		held := 0
+		for t := 1; t < len(out); t++ {
+			held++
+			if abs(out[t]-out[t-1]) <= tolerance {
+				out[t] = out[t-1]
+				continue
+			}
+			if held < intervals {
+				out[t] = out[t-1]
+				continue
+			}
+			held = 0
+		}
+
+		return out
+	}
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+// Report lists the intervals per battery where the smoothed setpoint was changed to stay
+// within the constraints, and batteries reverted to the original plan.
+type Report struct {
+	Adjusted [][]int
+	Reverted []int
+}
+
+// Apply smooths the battery setpoints of res computed for req and returns the smoothed result.
+// Constraints are re-verified on the smoothed plan: power and state of charge limits are
+// enforced by limiting the smoothed setpoints, intervals whose grid power would exceed a grid
+// limit keep their original setpoint within the state of charge limits, and batteries missing a
+// state of charge goal the original plan reaches or exceeding a grid limit either way keep their
+// original plan. Grid import and export are recomputed from the changed
+// battery energies. The objective value is not updated.
+func Apply(req client.OptimizationInput, res client.OptimizationResult, filter Filter) (client.OptimizationResult, Report) {
+	out := res
+	out.Batteries = slices.Clone(res.Batteries)
+	out.GridImport = slices.Clone(res.GridImport)
+	out.GridExport = slices.Clone(res.GridExport)
+	out.FlowDirection = slices.Clone(res.FlowDirection)
+
+	report := Report{Adjusted: make([][]int, len(res.Batteries))}
+
+	// net grid import per interval, updated as batteries change
+	grid := make([]float64, len(res.GridImport))
+	for t := range grid {
+		grid[t] = float64(res.GridImport[t])
+		if t < len(res.GridExport) {
+			grid[t] -= float64(res.GridExport[t])
+		}
+	}
+
+	for i, b := range res.Batteries {
+		if i >= len(req.Batteries) || len(b.ChargingPower) != len(req.TimeSeries.Dt) || len(grid) != len(req.TimeSeries.Dt) {
+			continue
+		}
+		bat := req.Batteries[i]
+
+		net := make([]float64, len(b.ChargingPower))
+		for t := range net {
+			net[t] = float64(b.ChargingPower[t]) - float64(b.DischargingPower[t])
+		}
+
+		smoothed := filter(net)
+
+		sb := client.BatteryResult{
+			Id:               b.Id,
+			ChargingPower:    make([]float32, len(net)),
+			DischargingPower: make([]float32, len(net)),
+			StateOfCharge:    make([]float32, len(net)),
+		}
+
+		candidate := slices.Clone(grid)
+		soc := float64(bat.SInitial)
+		goalMissed, infeasible := false, false
+
+		for t, dt := range req.TimeSeries.Dt {
+			h := float64(dt) / 3600
+			v := smoothed[t]
+
+			// power limits
+			v = min(v, float64(bat.CMax)*h)
+			v = max(v, -float64(bat.DMax)*h)
+
+			// state of charge limits, never tighter than the original plan
+			etaC, etaD := efficiency(req, bat, t)
+			sMax := max(float64(bat.SMax), float64(b.StateOfCharge[t]))
+			sMin := min(float64(bat.SMin), float64(b.StateOfCharge[t]))
+			limitSoc := func(v float64) float64 {
+				if v > 0 && soc+v*etaC > sMax {
+					v = max(0, (sMax-soc)/etaC)
+				}
+				if v < 0 && soc+v/etaD < sMin {
+					v = min(0, -(soc-sMin)*etaD)
+				}
+				return v
+			}
+			v = limitSoc(v)
+
+			// grid charging and discharging to grid only where allowed
+			g := grid[t] + v - net[t]
+			if !bat.ChargeFromGrid && v > 0 && g > 1e-6 {
+				v, g = max(0, v-g), max(0, g-v)
+			}
+			if !bat.DischargeToGrid && v < 0 && g < -1e-6 {
+				v, g = min(0, v-g), min(0, g-v)
+			}
+
+			// grid limits
+			if exceedsGrid(req.Grid, g, h) && !exceedsGrid(req.Grid, grid[t], h) {
+				v, g = net[t], grid[t]
+
+				// the original setpoint may leave the state of charge limits as the smoothed
+				// state of charge differs from the original one
+				if l := limitSoc(v); abs(l-v) > 1e-6 {
+					v, g = l, grid[t]+l-net[t]
+					infeasible = infeasible || exceedsGrid(req.Grid, g, h)
+				}
+			}
+
+			if abs(v-smoothed[t]) > 1e-6 {
+				report.Adjusted[i] = append(report.Adjusted[i], t)
+			}
+
+			if v > 0 {
+				sb.ChargingPower[t] = float32(v)
+				soc += v * etaC
+			} else {
+				sb.DischargingPower[t] = float32(-v)
+				soc += v / etaD
+			}
+			sb.StateOfCharge[t] = float32(soc)
+			candidate[t] = g
+
+			if t < len(bat.SGoal) && bat.SGoal[t] > 0 && soc < float64(bat.SGoal[t]) && b.StateOfCharge[t] >= bat.SGoal[t] {
+				goalMissed = true
+			}
+		}
+
+		if goalMissed || infeasible {
+			report.Reverted = append(report.Reverted, i)
+			continue
+		}
+
+		out.Batteries[i] = sb
+		grid = candidate
+	}
+
+	for t, g := range grid {
+		out.GridImport[t] = float32(max(0, g))
+		out.GridExport[t] = float32(max(0, -g))
+		if t < len(out.FlowDirection) {
+			out.FlowDirection[t] = client.OptimizationResultFlowDirection(0)
+			if g < 0 {
+				out.FlowDirection[t] = client.OptimizationResultFlowDirection(1)
+			}
+		}
+	}
+
+	return out, report
+}
+
+// efficiency returns the charging and discharging efficiency of bat in interval t
+func efficiency(req client.OptimizationInput, bat client.BatteryConfig, t int) (float64, float64) {
+	etaC, etaD := 0.95, 0.95
+	if req.EtaC > 0 {
+		etaC = float64(req.EtaC)
+	}
+	if req.EtaD > 0 {
+		etaD = float64(req.EtaD)
+	}
+
+	// curves are approximated by their best efficiency
+	if len(bat.CEtaCurve) > 0 {
+		etaC = 0
+		for _, p := range bat.CEtaCurve {
+			etaC = max(etaC, float64(p.Eta))
+		}
+	}
+	if len(bat.DEtaCurve) > 0 {
+		etaD = 0
+		for _, p := range bat.DEtaCurve {
+			etaD = max(etaD, float64(p.Eta))
+		}
+	}
+	if t < len(bat.CEtaSeries) {
+		etaC = float64(bat.CEtaSeries[t])
+	}
+
+	return etaC, etaD
+}
+
+func exceedsGrid(grid client.GridConfig, g, h float64) bool {
+	return (grid.PMaxImp > 0 && g > float64(grid.PMaxImp)*h+1e-6) ||
+		(grid.PMaxExp > 0 && -g > float64(grid.PMaxExp)*h+1e-6)
+}
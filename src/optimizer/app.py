@@ -1,18 +1,93 @@
-import os
+import atexit
+import queue
+import signal
+import threading
+import time
+from dataclasses import asdict
 
 import jwt
-from flask import Flask, jsonify, request
-from flask_restx import Api, Resource, fields
-from werkzeug.exceptions import BadRequest
+from flask import Flask, Response, g, jsonify, make_response, request
+from flask_restx import Api, Resource, fields, marshal
+from werkzeug.exceptions import BadRequest, HTTPException, TooManyRequests
 
-from .optimizer import BatteryConfig, GridConfig, OptimizationStrategy, Optimizer, TimeSeriesData
+from .attribution import attribute_batteries
+from .audit import AuditLog, digest, utc_now
+from .canonical import RESPONSE_DIGITS, canonical_json
+from .capacity import SolveStatistics
+from .compression import GzipRequestMiddleware, apply_output_options, compress_response
+from .expressions import ExpressionError, compile_penalty
+from .jobs import DONE, FAILED, JobLimitExceeded, JobRunner, JobStore
+from .optimizer import (MAX_GOAL_WEIGHT, OBJECTIVE_UNITS, BatteryConfig, Calibration, ChpConfig, Departure, EfficiencyPoint, GridConfig, ObjectiveWeights,
+                        OptimizationStrategy, Optimizer, PeakPricePoint, Preconditioning, TimeSeriesData, goal_weights)
+from .settings import OptimizerSettings
+from .simulate import POLICIES, Simulator
+from .strategies import STRATEGIES
+from .templates import TemplateStore, merge_template
 
 app = Flask(__name__)
 
+# gzip compressed requests and responses for clients on metered connections
+app.wsgi_app = GzipRequestMiddleware(app.wsgi_app)
+app.after_request(compress_response)
+
+# solve statistics shared by all workers of this host
+solve_stats = SolveStatistics(OptimizerSettings().stats_file)
+
+# request templates shared by all workers of this host
+template_store = TemplateStore(OptimizerSettings().template_dir)
+
+# asynchronous jobs shared by all workers and the job runner of this host
+job_store = JobStore(OptimizerSettings().job_dir, OptimizerSettings().max_jobs)
+
+# workers running jobs in threads exit after finishing their requests on SIGTERM, drain their
+# jobs before
+atexit.register(job_store.drain, OptimizerSettings().drain_timeout)
+
+# clients retry submitting jobs after this time when the job limit is reached [s]
+JOB_RETRY_AFTER = 30
+
+# solve requests of all workers of this host, disabled without a file
+audit_log = AuditLog(OptimizerSettings().audit_file)
+
+
+def is_solve_request() -> bool:
+    return request.method == 'POST' and (request.path in ('/optimize/charge-schedule', '/optimize/simulate', '/optimize/jobs', '/optimize/stream')
+                                         or request.path.endswith('/charge-schedule'))
+
+
+@app.before_request
+def audit_start():
+    # registered before authorization to record rejected requests too
+    g.audit_start = time.monotonic()
+
+
+@app.after_request
+def audit_response(response):
+    """
+    Record solve requests in the audit log, registered after compress_response to see the
+    uncompressed response
+    """
+    if audit_log.path and is_solve_request():
+        # streamed responses are not buffered, their outcome is not recorded
+        body = {} if response.is_streamed else response.get_json(silent=True) or {}
+        start = g.get('audit_start')
+        audit_log.record({
+            'time': utc_now(),
+            'subject': g.get('subject'),
+            'client': request.access_route[0] if request.access_route else request.remote_addr,
+            'path': request.path,
+            'digest': digest(request.get_json(silent=True)),
+            'status': response.status_code,
+            # solver status, job status or error message
+            'outcome': body.get('status') or body.get('message') if isinstance(body, dict) else None,
+            'duration_ms': round((time.monotonic() - start) * 1000, 1) if start is not None else None,
+        })
+    return response
+
 
 @app.before_request
 def before_request_func():
-    secret_key = os.environ.get('JWT_TOKEN_SECRET')
+    secret_key = OptimizerSettings().jwt_token_secret
     if secret_key:
         auth_header = request.headers.get('Authorization')
         if not auth_header:
@@ -25,6 +100,8 @@ def before_request_func():
 
             payload = jwt.decode(token, secret_key, algorithms=["HS256"])
             print("subject:", payload.get('sub'))
+            # templates are scoped to the subject
+            g.subject = payload.get('sub')
         except jwt.ExpiredSignatureError:
             return jsonify({"message": "Token has expired"}), 401
         except jwt.InvalidTokenError:
@@ -33,11 +110,35 @@ def before_request_func():
             return jsonify({"message": str(e)}), 401
 
 
+@app.before_request
+def replay_job_request():
+    """
+    Replay requests for jobs of other machines to their machine, jobs are kept on the host
+    accepting them
+    """
+    if not request.path.startswith('/optimize/jobs/') or job_store.instance is None:
+        return None
+    owner = job_store.owner_instance(request.path.split('/')[3])
+    if owner is None or owner == job_store.instance:
+        return None
+    response = jsonify({"message": "Job is kept on another machine, replaying"})
+    response.headers['fly-replay'] = f'instance={owner}'
+    return response
+
+
 api = Api(app, version='1.0', title='EV Charging Optimization API',
           description='Mixed Integer Linear Programming model for EV charging optimization',
           validate=True)
 
 
+@api.representation('application/json')
+def output_json(data, code, headers=None):
+    """Encode responses canonically, so responses to equal requests are equal text."""
+    response = make_response(canonical_json(data, RESPONSE_DIGITS) + '\n', code)
+    response.headers.extend(headers or {})
+    return response
+
+
 @api.errorhandler(BadRequest)
 def handle_validation_error(error):
     """Rename 'errors' to 'details' in validation responses."""
@@ -49,43 +150,538 @@ def handle_validation_error(error):
         raise error
 
 
+def parse_efficiency_curve(data):
+    """Parse an optional list of efficiency curve points."""
+    if not data:
+        return None
+    curve = [EfficiencyPoint(power=p['power'], eta=p['eta']) for p in data]
+    if any(p.eta <= 0 for p in curve):
+        api.abort(400, "Efficiency curve values must be greater than 0")
+    if max(p.power for p in curve) <= 0:
+        api.abort(400, "Efficiency curve requires at least one point with power greater than 0")
+    return curve
+
+
+def parse_peak_price_curve(data):
+    """Parse an optional peak price curve, which must be convex to be charged correctly."""
+    if not data:
+        return None
+    curve = [PeakPricePoint(power=p['power'], price=p['price']) for p in data]
+    for a, b in zip(curve, curve[1:]):
+        if b.power <= a.power:
+            api.abort(400, "Peak price curve powers must be increasing")
+        if b.price < a.price:
+            api.abort(400, "Peak price curve prices must not decrease with power")
+    return curve
+
+
+def parse_preconditioning(data):
+    """Parse an optional vehicle preconditioning load."""
+    if not data:
+        return None
+    return Preconditioning(power=data['power'], steps=data['steps'], t_start=data.get('t_start', 0), t_end=data['t_end'])
+
+
+def parse_calibration(data):
+    """Parse an optional periodic full charge calibration."""
+    if not data:
+        return None
+    return Calibration(interval=data['interval'], elapsed=data.get('elapsed', 0))
+
+
+def parse_departures(data):
+    """Parse optional possible departures."""
+    if not data:
+        return None
+    return [Departure(t=d['t'], probability=d['probability'], s_goal=d['s_goal']) for d in data]
+
+
+def parse_penalties(data, batteries, time_series):
+    """Compile the optional custom penalty terms of a request."""
+    ids = [bat.id for bat in batteries]
+    penalties = []
+    for k, expression in enumerate(data.get('penalties') or []):
+        try:
+            penalties.append(compile_penalty(expression, len(time_series.dt), ids))
+        except ExpressionError as e:
+            api.abort(400, f"Penalty {k} is invalid: {e}")
+    return penalties
+
+
+def parse_objective_weights(data):
+    """Parse and validate the optional objective weights of a request."""
+    weights_data = data.get('objective_weights')
+    if weights_data is None:
+        return None
+
+    weights = ObjectiveWeights(
+        cost=weights_data['cost'],
+        battery_wear=weights_data['battery_wear'],
+        self_consumption=weights_data['self_consumption'],
+        peak_shaving=weights_data['peak_shaving'],
+    )
+    values = asdict(weights).values()
+    if any(w < 0 for w in values):
+        api.abort(400, "Objective weights must not be negative")
+    if not any(w > 0 for w in values):
+        api.abort(400, "At least one objective weight must be positive")
+    if data.get('cost_budget') is not None:
+        api.abort(400, "Objective weights cannot be combined with cost budget")
+    return weights
+
+
+def parse_chp(data, time_series):
+    """Parse and validate the optional combined heat and power unit of a request."""
+    chp_data = data.get('chp')
+    if chp_data is None:
+        if time_series.p_G is not None or time_series.q_H is not None:
+            api.abort(400, "time_series.p_G and time_series.q_H require a combined heat and power unit")
+        return None
+
+    chp = ChpConfig(
+        p_el_max=chp_data['p_el_max'],
+        eta_el=chp_data['eta_el'],
+        eta_th=chp_data['eta_th'],
+        p_el_min=chp_data.get('p_el_min', 0),
+        p_boiler_max=chp_data.get('p_boiler_max', 0),
+        eta_boiler=chp_data.get('eta_boiler', 0.9),
+        p_heat_el_max=chp_data.get('p_heat_el_max', 0),
+        cop=chp_data.get('cop', 1),
+    )
+    if time_series.p_G is None or time_series.q_H is None:
+        api.abort(400, "Combined heat and power unit requires time_series.p_G and time_series.q_H")
+    if not (0 < chp.eta_el <= 1 and 0 < chp.eta_th <= 1 and chp.eta_el + chp.eta_th <= 1 and 0 < chp.eta_boiler <= 1):
+        api.abort(400, "Combined heat and power efficiencies must be in (0, 1] with eta_el + eta_th not exceeding 1")
+    if chp.p_el_min > chp.p_el_max or chp.cop <= 0:
+        api.abort(400, "Combined heat and power unit requires p_el_min not exceeding p_el_max and a positive cop")
+    if any(q < 0 for q in time_series.q_H):
+        api.abort(400, "Heat demand must not be negative")
+
+    # the heat demand must be coverable, as heat is neither stored nor bought
+    p_heat_max = chp.p_el_max / chp.eta_el * chp.eta_th + chp.p_boiler_max + chp.p_heat_el_max * chp.cop
+    for t, (q, dt) in enumerate(zip(time_series.q_H, time_series.dt)):
+        if q > p_heat_max * dt / 3600 + 1e-6:
+            api.abort(400, f"Heat demand at time step {t} exceeds the heat output of unit, boiler and electric heating")
+
+    strategy = data.get('strategy') or {}
+    if strategy.get('objective', 'cost') != 'cost':
+        api.abort(400, "Combined heat and power unit requires the cost objective")
+    return chp
+
+
+def validate_availability(i, bat, time_series, eta_c):
+    """
+    Validate that charge demands and goals of battery i remain achievable with its availability.
+    Goals are checked against charging at full power in all available time steps before.
+    """
+    s = bat.s_initial
+    for t, available in enumerate(bat.available):
+        if available:
+            eta = eta_c
+            if bat.c_eta_series is not None:
+                eta = bat.c_eta_series[t]
+            elif bat.c_eta_curve:
+                eta = max(p.eta for p in bat.c_eta_curve)
+            s = min(bat.s_capacity, s + bat.c_max * time_series.dt[t] / 3600. * eta)
+        elif bat.p_demand is not None and bat.p_demand[t] > 0:
+            api.abort(400, f"Battery {i} has a charge demand at unavailable time step {t}")
+
+        if bat.s_goal is not None and bat.s_goal[t] > s + 1e-6:
+            api.abort(400, f"Battery {i} cannot reach its goal at time step {t} with the given availability")
+
+
+def request_warnings(strategy, batteries, time_series):
+    """
+    Collect hints on inputs that are valid but likely not intended.
+    """
+    warnings = []
+    for i, bat in enumerate(batteries):
+        if not bat.enabled:
+            warnings.append(f"Battery {i} is disabled")
+            continue
+        if bat.s_initial < bat.s_min or bat.s_initial > bat.s_max:
+            warnings.append(f"Battery {i} starts outside s_min and s_max and is forced back into range")
+        if bat.s_goal is not None and max(bat.s_goal) > bat.s_max:
+            warnings.append(f"Battery {i} has goals above s_max that cannot be met")
+        if bat.c_max == 0 and bat.d_max == 0:
+            warnings.append(f"Battery {i} can neither charge nor discharge")
+        if strategy.objective == 'cost' and bat.p_a > max(time_series.p_N):
+            warnings.append(f"Battery {i} values stored energy above all import prices and is never discharged")
+    return warnings
+
+
+def parse_optimization_input(data):
+    """
+    Parse and validate an optimization input payload. Returns strategy, grid, batteries and time series.
+    """
+    # Parse strategy items with default values
+    strat_data = data.get('strategy', {})
+    strategy = OptimizationStrategy(
+        charging_strategy=strat_data.get('charging_strategy', 'none'),
+        discharging_strategy=strat_data.get('discharging_strategy', 'none'),
+        objective=strat_data.get('objective', 'cost'),
+        spike_guard_hours=strat_data.get('spike_guard_hours', 0)
+    )
+
+    # parse grid configuration
+    grid_data = data.get('grid', {})
+    grid = GridConfig(
+        p_max_imp=grid_data.get('p_max_imp', None),
+        p_max_exp=grid_data.get('p_max_exp', None),
+        p_conn=grid_data.get('p_conn', None),
+        prc_p_exc_imp=grid_data.get('prc_p_exc_imp', None),
+        e_imp_tier=grid_data.get('e_imp_tier', None),
+        e_imp_to_date=grid_data.get('e_imp_to_date', 0),
+        prc_e_exc_tier=grid_data.get('prc_e_exc_tier', None),
+        t_tier_reset=grid_data.get('t_tier_reset', None),
+        prc_e_dev=grid_data.get('prc_e_dev', None),
+        e_exp_cap=grid_data.get('e_exp_cap', None),
+        e_exp_to_date=grid_data.get('e_exp_to_date', 0),
+        t_cap_reset=grid_data.get('t_cap_reset', None),
+        prc_p_peak=parse_peak_price_curve(grid_data.get('prc_p_peak')),
+        p_peak_to_date=grid_data.get('p_peak_to_date', 0),
+        prc_e_net_imp=grid_data.get('prc_e_net_imp', None),
+        prc_e_net_exp=grid_data.get('prc_e_net_exp', 0),
+        e_net_to_date=grid_data.get('e_net_to_date', 0),
+        t_net_reset=grid_data.get('t_net_reset', None),
+    )
+
+    # a tiered tariff requires both the allowance and the surcharge
+    if (grid.e_imp_tier is None) != (grid.prc_e_exc_tier is None):
+        api.abort(400, "Tiered tariff requires both e_imp_tier and prc_e_exc_tier")
+    if grid.e_imp_tier is None and (grid.t_tier_reset is not None or 'e_imp_to_date' in grid_data):
+        api.abort(400, "e_imp_to_date and t_tier_reset require a tiered tariff")
+    if grid.e_exp_cap is None and (grid.t_cap_reset is not None or 'e_exp_to_date' in grid_data):
+        api.abort(400, "e_exp_to_date and t_cap_reset require an export cap")
+    if grid.prc_e_net_imp is None and any(k in grid_data for k in ('prc_e_net_exp', 'e_net_to_date', 't_net_reset')):
+        api.abort(400, "prc_e_net_exp, e_net_to_date and t_net_reset require net metering by prc_e_net_imp")
+    if grid.prc_e_net_imp is not None and grid.prc_e_net_exp > grid.prc_e_net_imp:
+        api.abort(400, "Net export credit prc_e_net_exp must not exceed the net import price prc_e_net_imp")
+    if grid.prc_p_peak is None and grid.prc_p_exc_imp is None and 'p_peak_to_date' in grid_data:
+        api.abort(400, "p_peak_to_date requires a peak price curve or a demand rate")
+    if grid.prc_p_peak is not None and grid.prc_p_exc_imp is not None:
+        api.abort(400, "Peak price curve cannot be combined with the demand rate prc_p_exc_imp")
+
+    # Parse battery configurations
+    batteries = []
+    for bat_data in data['batteries']:
+        batteries.append(BatteryConfig(
+            charge_from_grid=bat_data.get('charge_from_grid', False),
+            discharge_to_grid=bat_data.get('discharge_to_grid', False),
+            s_capacity=bat_data.get('s_capacity', bat_data['s_max']),
+            s_min=bat_data['s_min'],
+            s_max=bat_data['s_max'],
+            s_initial=bat_data['s_initial'],
+            p_demand=bat_data.get('p_demand'),
+            s_goal=bat_data.get('s_goal'),
+            c_min=bat_data['c_min'],
+            c_max=bat_data['c_max'],
+            d_max=bat_data['d_max'],
+            p_a=bat_data['p_a'],
+            c_priority=bat_data.get('c_priority', 0),
+            goal_priority=bat_data.get('goal_priority', 0),
+            c_eta_curve=parse_efficiency_curve(bat_data.get('c_eta_curve')),
+            d_eta_curve=parse_efficiency_curve(bat_data.get('d_eta_curve')),
+            enabled=bat_data.get('enabled', True),
+            c_eta_series=bat_data.get('c_eta_series'),
+            id=bat_data.get('id'),
+            name=bat_data.get('name'),
+            s_initial_stddev=bat_data.get('s_initial_stddev', 0),
+            available=bat_data.get('available'),
+            preconditioning=parse_preconditioning(bat_data.get('preconditioning')),
+            departures=parse_departures(bat_data.get('departures')),
+            calibration=parse_calibration(bat_data.get('calibration')),
+        ))
+
+    # checked here as well as templates are merged without schema validation
+    for i, bat in enumerate(batteries):
+        if not bat.id or not bat.name:
+            api.abort(400, f"Battery {i} requires an id and a name")
+
+    ids = [bat.id for bat in batteries]
+    if len(ids) != len(set(ids)):
+        api.abort(400, "Battery ids must be unique")
+
+    if batteries and max(goal_weights(batteries)) > MAX_GOAL_WEIGHT:
+        api.abort(400, "Too many goal priority levels for the number of goal time steps, use fewer levels")
+
+    # Parse time series data, demand and generation default to none for pure arbitrage
+    ts_data = data['time_series']
+    time_series = TimeSeriesData(
+        dt=ts_data['dt'],
+        gt=ts_data.get('gt') or [0.0] * len(ts_data['dt']),
+        ft=ts_data.get('ft') or [0.0] * len(ts_data['dt']),
+        p_N=data['time_series']['p_N'],
+        p_E=data['time_series']['p_E'],
+        em_N=data['time_series'].get('em_N'),
+        r_curt=data['time_series'].get('r_curt'),
+        w_E=data['time_series'].get('w_E'),
+        n_commit=data['time_series'].get('n_commit'),
+        p_max_imp=data['time_series'].get('p_max_imp'),
+        p_G=data['time_series'].get('p_G'),
+        q_H=data['time_series'].get('q_H'),
+    )
+
+    # Validate time series lengths
+    lengths = [len(time_series.gt), len(time_series.ft),
+               len(time_series.p_N), len(time_series.p_E)]
+
+    # Validate p_demand if provided
+    for bat in batteries:
+        if bat.p_demand is not None:
+            lengths.append(len(bat.p_demand))
+
+    # Validate s_goal if provided
+    for bat in batteries:
+        if bat.s_goal is not None:
+            lengths.append(len(bat.s_goal))
+
+    # Validate emissions if provided
+    if time_series.em_N is not None:
+        lengths.append(len(time_series.em_N))
+
+    # Validate export preference if provided
+    if time_series.w_E is not None:
+        lengths.append(len(time_series.w_E))
+        if any(w < 0 for w in time_series.w_E):
+            api.abort(400, "Export preference weights must not be negative")
+
+    # Validate import limits if provided, e.g. curtailment windows of the grid operator
+    if time_series.p_max_imp is not None:
+        lengths.append(len(time_series.p_max_imp))
+        if any(p < 0 for p in time_series.p_max_imp):
+            api.abort(400, "Import limits must not be negative")
+    if grid.p_conn is not None and grid.p_conn < 0:
+        api.abort(400, "Grid connection capacity must not be negative")
+    if grid.prc_p_exc_imp is not None and (time_series.p_max_imp is not None or grid.p_conn is not None):
+        api.abort(400, "Demand rate prc_p_exc_imp cannot be combined with time_series.p_max_imp or grid.p_conn")
+
+    # Validate gas prices and heat demand if provided
+    for series in (time_series.p_G, time_series.q_H):
+        if series is not None:
+            lengths.append(len(series))
+
+    # Validate committed schedule if provided, deviations are only priced with both given
+    if (time_series.n_commit is None) != (grid.prc_e_dev is None):
+        api.abort(400, "Committed schedule requires both time_series.n_commit and grid.prc_e_dev")
+    if time_series.n_commit is not None:
+        lengths.append(len(time_series.n_commit))
+
+    # Validate curtailment risk if provided
+    if time_series.r_curt is not None:
+        lengths.append(len(time_series.r_curt))
+        if any(r < 0 or r > 1 for r in time_series.r_curt):
+            api.abort(400, "Curtailment risk values must be between 0 and 1")
+
+    # Validate charging efficiency series if provided
+    for bat in batteries:
+        if bat.c_eta_series is not None:
+            lengths.append(len(bat.c_eta_series))
+            if any(eta <= 0 for eta in bat.c_eta_series):
+                api.abort(400, "Charging efficiency series values must be greater than 0")
+            if bat.c_eta_curve:
+                api.abort(400, "c_eta_series and c_eta_curve are mutually exclusive")
+
+    # Validate availability if provided
+    for bat in batteries:
+        if bat.available is not None:
+            lengths.append(len(bat.available))
+
+    if len(set(lengths)) > 1:
+        api.abort(400, "All time series must have the same length")
+
+    for i, bat in enumerate(batteries):
+        if bat.available is not None:
+            validate_availability(i, bat, time_series, data.get('eta_c', 0.95))
+
+    # preconditioning must fit into its window within the horizon
+    for i, bat in enumerate(batteries):
+        pre = bat.preconditioning
+        if pre is None:
+            continue
+        if pre.power < 0 or pre.steps < 1:
+            api.abort(400, f"Battery {i} preconditioning requires a non-negative power and at least one step")
+        if pre.t_start < 0 or pre.t_end > len(time_series.dt) or pre.t_end - pre.t_start < pre.steps:
+            api.abort(400, f"Battery {i} preconditioning does not fit into its window within the horizon")
+
+    # possible departures are alternatives within the horizon
+    for i, bat in enumerate(batteries):
+        if bat.departures is None:
+            continue
+        if any(d.t < 0 or d.t >= len(time_series.dt) for d in bat.departures):
+            api.abort(400, f"Battery {i} departures must be within the horizon")
+        if sum(d.probability for d in bat.departures) > 1 + 1e-6:
+            api.abort(400, f"Battery {i} departure probabilities must not exceed 1 in total")
+
+    # calibration intervals must be positive
+    for i, bat in enumerate(batteries):
+        if bat.calibration is not None and (bat.calibration.interval <= 0 or bat.calibration.elapsed < 0):
+            api.abort(400, f"Battery {i} calibration requires a positive interval and a non-negative elapsed time")
+
+    # the energy and emissions objectives have no currency, cost related inputs cannot be considered
+    if strategy.objective == 'emissions' and time_series.em_N is None:
+        api.abort(400, "Emissions objective requires time_series.em_N")
+    if strategy.objective != 'cost' and (grid.prc_p_exc_imp is not None or grid.e_imp_tier is not None
+                                         or grid.prc_e_dev is not None or grid.prc_p_peak is not None
+                                         or grid.prc_e_net_imp is not None or data.get('cost_budget') is not None):
+        api.abort(400, f"{strategy.objective} objective cannot be combined with demand rate, peak price, tiered tariff, net metering, "
+                       "deviation price or cost budget")
+
+    # negative demand is uncontrolled generation, whereas negative generation has no meaning
+    if any(f < 0 for f in time_series.ft):
+        api.abort(400, "ft must not be negative, uncontrolled generation can be given as negative gt")
+
+    return strategy, grid, batteries, time_series
+
+
 # Namespace for the API
 ns = api.namespace('optimize', description='EV Charging Optimization Operations')
 
 # Input models for API documentation
 strategy_model = api.model('OptimizationStrategy', {
     'charging_strategy': fields.String(required=False, description='Sets a strategy for charging in situations where choices are cost neutral.'),
-    'discharging_strategy': fields.String(required=False, description='Sets a strategy for discharging in situations where choices are cost neutral.')
+    'discharging_strategy': fields.String(required=False, description='Sets a strategy for discharging in situations where choices are cost neutral.'),
+    'objective': fields.String(required=False, enum=list(OBJECTIVE_UNITS), description='Quantity to optimize: cost (default), grid import energy or emissions.'),
+    'spike_guard_hours': fields.Float(required=False, min=0, default=0, description='Keep enough stored energy for this many of the most expensive import hours of the next day.')
+})
+
+peak_price_point_model = api.model('PeakPricePoint', {
+    'power': fields.Float(required=True, min=0, description='Peak import power from which the price applies (W)'),
+    'price': fields.Float(required=True, min=0, description='Price per W of peak import power above this power')
 })
 
 grid_model = api.model('GridConfig', {
     'p_max_imp': fields.Float(required=False, description='Maximum grid import power in W'),
     'p_max_exp': fields.Float(required=False, description='Maximum grid export power in W'),
-    'prc_p_exc_imp': fields.Float(required=False, description='price per W to consider in case the import limit is exceeded. ')
+    'p_conn': fields.Float(required=False, min=0, description='Capacity of the grid connection in W, limiting import and export'),
+    'prc_p_exc_imp': fields.Float(required=False, description='price per W to consider in case the import limit is exceeded. '),
+    'e_imp_tier': fields.Float(required=False, min=0, description='Import energy allowance at base price per billing period in Wh'),
+    'e_imp_to_date': fields.Float(required=False, min=0, description='Energy imported so far in the current billing period in Wh'),
+    'prc_e_exc_tier': fields.Float(required=False, min=0, description='Price surcharge per Wh imported beyond the tier allowance'),
+    't_tier_reset': fields.Integer(required=False, min=0, description='Index of the first time step of the next billing period'),
+    'prc_e_dev': fields.Float(required=False, min=0, description='Price per Wh deviating from the committed grid exchange time_series.n_commit'),
+    'e_exp_cap': fields.Float(required=False, min=0, description='Remunerated export energy per cap period in Wh'),
+    'e_exp_to_date': fields.Float(required=False, min=0, description='Energy exported so far in the current cap period in Wh'),
+    't_cap_reset': fields.Integer(required=False, min=0, description='Index of the first time step of the next cap period'),
+    'prc_p_peak': fields.List(fields.Nested(peak_price_point_model), required=False,
+                              description='Convex piecewise linear price of the peak import power of the horizon'),
+    'p_peak_to_date': fields.Float(required=False, min=0, description='Peak import power reached so far in the current billing period in W. '
+                                     'Only raising the peak above it is charged by peak prices and the demand rate'),
+    'prc_e_net_imp': fields.Float(required=False, min=0, description='Price per Wh of the net import per netting period, enables net metering'),
+    'prc_e_net_exp': fields.Float(required=False, min=0, description='Credit per Wh of the net export per netting period'),
+    'e_net_to_date': fields.Float(required=False, description='Net import so far in the current netting period in Wh, negative for net export'),
+    't_net_reset': fields.Integer(required=False, min=0, description='Index of the first time step of the next netting period'),
+})
+
+efficiency_point_model = api.model('EfficiencyPoint', {
+    'power': fields.Float(required=True, min=0, description='Charge or discharge power (W)'),
+    'eta': fields.Float(required=True, min=0, exclusiveMin=True, max=1, description='Efficiency at this power (0 to 1)')
+})
+
+preconditioning_model = api.model('Preconditioning', {
+    'power': fields.Float(required=True, min=0, description='Preconditioning power, e.g. cabin heating (W)'),
+    'steps': fields.Integer(required=True, min=1, description='Number of contiguous time steps to run for'),
+    't_start': fields.Integer(required=False, min=0, default=0, description='Index of the first time step the preconditioning may run in'),
+    't_end': fields.Integer(required=True, min=1, description='Index of the time step the preconditioning must be finished by, e.g. departure')
+})
+
+departure_model = api.model('Departure', {
+    't': fields.Integer(required=True, min=0, description='Index of the time step the vehicle may depart after'),
+    'probability': fields.Float(required=True, min=0, max=1, description='Probability of departing after this time step'),
+    's_goal': fields.Float(required=True, min=0, description='Goal state of charge at this departure (Wh)')
+})
+
+calibration_model = api.model('Calibration', {
+    'interval': fields.Float(required=True, min=0, exclusiveMin=True, description='Maximum time between full charges, e.g. 14 days (s)'),
+    'elapsed': fields.Float(required=False, min=0, default=0, description='Time since the last full charge at the start of the horizon (s)')
 })
 
 battery_config_model = api.model('BatteryConfig', {
+    'id': fields.String(required=True, min_length=1, description='Stable identifier of the battery, returned with its result'),
+    'name': fields.String(required=True, min_length=1, description='Human-readable name of the battery, returned with its result'),
     'charge_from_grid': fields.Boolean(required=False, description='Controls whether the battery can be charged from the grid.'),
     'discharge_to_grid': fields.Boolean(required=False, description='Controls whether the battery can discharge to grid.'),
     's_capacity': fields.Float(required=False, description='Capacity at 100% state of charge (Wh)'),
     's_min': fields.Float(required=True, description='Minimum state of charge (Wh)'),
     's_max': fields.Float(required=True, description='Maximum state of charge (Wh)'),
     's_initial': fields.Float(required=True, description='Initial state of charge (Wh)'),
+    's_initial_stddev': fields.Float(required=False, min=0, default=0, description='Standard deviation of the measured initial state of charge (Wh)'),
+    'available': fields.List(fields.Boolean, required=False, description='Availability at each time step. Unavailable batteries neither charge nor discharge.'),
     'p_demand': fields.List(fields.Float, required=False, description='Minimum charge demand per time step (Wh)'),
     's_goal': fields.List(fields.Float, required=False, description='Goal state of charge at each time step (Wh)'),
     'c_min': fields.Float(required=True, description='Minimum charge power (W)'),
     'c_max': fields.Float(required=True, description='Maximum charge power (W)'),
     'd_max': fields.Float(required=True, description='Maximum discharge power (W)'),
     'p_a': fields.Float(required=True, description='Monetary value per Wh at end of the optimization horizon'),
-    'c_priority': fields.Integer(required=False, description='Charging and discharging priority compared to other batteries. 2 = highest priority.')
+    'c_priority': fields.Integer(required=False, min=0, description='Charging and discharging priority compared to other batteries. Higher value = higher priority.'),
+    'goal_priority': fields.Integer(required=False, min=0, default=0,
+                                    description='Priority of the goals compared to the goals of other batteries. Goals of higher priority '
+                                                'are met first when goals compete for limited power.'),
+    'c_eta_curve': fields.List(fields.Nested(efficiency_point_model), required=False,
+                               description='Piecewise linear charging efficiency as function of charge power. Overrides eta_c.'),
+    'd_eta_curve': fields.List(fields.Nested(efficiency_point_model), required=False,
+                               description='Piecewise linear discharging efficiency as function of discharge power. Overrides eta_d.'),
+    'c_eta_series': fields.List(fields.Float, required=False,
+                                description='Charging efficiency at each time step, e.g. heat pump COP from a temperature forecast. Overrides eta_c.'),
+    'enabled': fields.Boolean(required=False, default=True, description='Include the battery in the optimization. Disabled batteries keep their index and get zeroed result series.'),
+    'preconditioning': fields.Nested(preconditioning_model, required=False, allow_null=True,
+                                     description='Vehicle preconditioning load scheduled within a window before departure. '
+                                                 'It is supplied by the charger while available and drawn from the battery otherwise.'),
+    'departures': fields.List(fields.Nested(departure_model), required=False,
+                              description='Possible departures with their probabilities. The expected shortfall of their goals is minimized.'),
+    'calibration': fields.Nested(calibration_model, required=False, allow_null=True,
+                                 description='Periodic full charge required by the battery management system. Once due within the horizon, '
+                                             'the battery reaches s_max in the cheapest time step before.')
 })
 
 time_series_model = api.model('TimeSeries', {
     'dt': fields.List(fields.Float, required=True, description='duration in seconds for each time step (s)'),
-    'gt': fields.List(fields.Float, required=True, description='Required energy for home consumption at each time step (Wh)'),
-    'ft': fields.List(fields.Float, required=True, description='Forecasted solar generation at each time step (Wh)'),
+    'gt': fields.List(fields.Float, required=False, description='Required energy for home consumption at each time step (Wh). Negative values denote uncontrolled generation exceeding the demand. Defaults to none.'),
+    'ft': fields.List(fields.Float, required=False, description='Forecasted solar generation at each time step (Wh). Defaults to none.'),
     'p_N': fields.List(fields.Float, required=True, description='Price per Wh taken from grid at each time step'),
     'p_E': fields.List(fields.Float, required=True, description='Remuneration per Wh fed into grid at each time step'),
+    'em_N': fields.List(fields.Float, required=False, description='Emissions per Wh taken from grid at each time step (gCO2/Wh)'),
+    'r_curt': fields.List(fields.Float, required=False, description='Probability of export curtailment by the grid operator at each time step (0 to 1)'),
+    'w_E': fields.List(fields.Float, required=False, description='Export preference bonus per Wh exported at each time step, biasing without forcing export'),
+    'p_max_imp': fields.List(fields.Float, required=False, description='Maximum grid import power at each time step in W, '
+                             'e.g. curtailment windows of controllable devices'),
+    'n_commit': fields.List(fields.Float, required=False, description='Committed net grid import at each time step, negative for export (Wh)'),
+    'p_G': fields.List(fields.Float, required=False, description='Gas price per Wh of fuel at each time step, requires chp'),
+    'q_H': fields.List(fields.Float, required=False, description='Heat demand at each time step (Wh), requires chp'),
+})
+
+output_options_model = api.model('OutputOptions', {
+    'precision': fields.Integer(required=False, min=0, max=6, description='Round result series to this number of decimals, energy series conserving their sums'),
+    'fields': fields.List(fields.String, required=False, description='Top-level result fields to return, status is always returned'),
+})
+
+objective_weights_model = api.model('ObjectiveWeights', {
+    'cost': fields.Float(required=True, min=0, description='Weight of the economic benefit of the strategy objective'),
+    'battery_wear': fields.Float(required=True, min=0, description='Weight of the battery throughput, valued at the mean import price'),
+    'self_consumption': fields.Float(required=True, min=0, description='Weight of the grid import and export, valued at the mean import price'),
+    'peak_shaving': fields.Float(required=True, min=0, description='Weight of the peak import power over one hour, valued at the mean import price'),
+})
+
+chp_model = api.model('ChpConfig', {
+    'p_el_max': fields.Float(required=True, min=0, description='Maximum electric power of the combined heat and power unit (W)'),
+    'eta_el': fields.Float(required=True, min=0, max=1, description='Electric efficiency, electricity per fuel energy'),
+    'eta_th': fields.Float(required=True, min=0, max=1, description='Thermal efficiency, heat per fuel energy'),
+    'p_el_min': fields.Float(required=False, min=0, default=0, description='Minimum electric power while running (W)'),
+    'p_boiler_max': fields.Float(required=False, min=0, default=0, description='Maximum heat output of a gas boiler (W)'),
+    'eta_boiler': fields.Float(required=False, min=0, max=1, default=0.9, description='Boiler efficiency, heat per fuel energy'),
+    'p_heat_el_max': fields.Float(required=False, min=0, default=0, description='Maximum electric power of electric heating, e.g. a heat pump (W)'),
+    'cop': fields.Float(required=False, min=0, default=1, description='Heat per electricity of electric heating'),
+})
+
+warm_start_battery_model = api.model('WarmStartBattery', {
+    'charging_power': fields.List(fields.Float, required=False, description='Previous charging energy at each time step (Wh)'),
+    'discharging_power': fields.List(fields.Float, required=False, description='Previous discharging energy at each time step (Wh)'),
+})
+
+warm_start_model = api.model('WarmStart', {
+    'batteries': fields.List(fields.Nested(warm_start_battery_model), required=False, description='Previous schedule of each battery by index'),
+    'grid_import': fields.List(fields.Float, required=False, description='Previous grid import at each time step (Wh)'),
+    'grid_export': fields.List(fields.Float, required=False, description='Previous grid export at each time step (Wh)'),
 })
 
 optimization_input_model = api.model('OptimizationInput', {
@@ -95,37 +691,139 @@ optimization_input_model = api.model('OptimizationInput', {
     'time_series': fields.Nested(time_series_model, required=True, description='Time series data'),
     'eta_c': fields.Float(required=False, default=0.95, description='Charging efficiency'),
     'eta_d': fields.Float(required=False, default=0.95, description='Discharging efficiency'),
+    'cost_budget': fields.Float(required=False, description='Maximum acceptable net cost over the horizon. Enables goal seeking mode.'),
+    'max_latency_ms': fields.Float(required=False, min=0, description='Return an approximate solution based on the LP relaxation within this latency from the receipt of the request (ms).'),
+    'simplify_on_timeout': fields.Boolean(required=False, default=False, description='Retry with the LP relaxation based approximation if the solve times out.'),
+    'time_limit': fields.Float(required=False, min=0, description='Time limit of the solve (s), capped by the time limit of the server.'),
+    'mip_gap': fields.Float(required=False, min=0, max=1, description='Relative optimality gap at which the solve stops.'),
+    'require_optimal': fields.Boolean(required=False, default=False, description='Return no plan instead of the best solution found if the solve hits the time limit.'),
+    'attribute_batteries': fields.Boolean(required=False, default=False, description='Report the contribution of each battery by solving again without it.'),
+    'warm_start': fields.Nested(warm_start_model, required=False, description='Previous schedule as starting solution of the solver'),
+    'penalties': fields.List(fields.String, required=False, description="Custom linear penalty terms added to the cost, e.g. '0.05 * grid_import[18:21]'"),
+    'objective_weights': fields.Nested(objective_weights_model, required=False, description='Weights of cost, battery wear, grid exchange and peak import'),
+    'chp': fields.Nested(chp_model, required=False, description='Combined heat and power unit covering time_series.q_H with gas at time_series.p_G'),
+    'output': fields.Nested(output_options_model, required=False, description='Options reducing the response size'),
 })
 
 # Output models
+goal_shortfall_model = api.model('GoalShortfall', {
+    't': fields.Integer(description='Index of the time step of the goal'),
+    'goal': fields.Float(description='Goal state of charge (Wh)'),
+    'shortfall': fields.Float(description='Energy missing to the goal (Wh)'),
+    'reason': fields.String(enum=['capacity', 'charge_power', 'grid_limit', 'generation', 'competing_goals', 'other'],
+                            description='Why the goal was scaled back'),
+    'competitors': fields.List(fields.Integer, description='Indices of the batteries charging instead, by descending goal priority'),
+})
+
 battery_result_model = api.model('BatteryResult', {
+    'id': fields.String(description='Identifier of the battery as given in the request'),
+    'name': fields.String(description='Name of the battery as given in the request'),
     'charging_power': fields.List(fields.Float, description='Optimal charging energy at each time step (Wh)'),
     'discharging_power': fields.List(fields.Float, description='Optimal discharging energy at each time step (Wh)'),
-    'state_of_charge': fields.List(fields.Float, description='State of charge at each time step (Wh)')
+    'state_of_charge': fields.List(fields.Float, description='State of charge at each time step (Wh)'),
+    'contribution': fields.Float(description='Objective value lost without this battery, if attribute_batteries is set'),
+    'preconditioning_power': fields.List(fields.Float, description='Preconditioning energy at each time step, if preconditioning is given (Wh)'),
+    'expected_shortfall': fields.Float(description='Probability weighted shortfall of the goals of the possible departures, if departures are given (Wh)'),
+    'calibration_step': fields.Integer(description='Time step at the end of which the battery is full for calibration, if due within the horizon'),
+    'goal_shortfalls': fields.List(fields.Nested(goal_shortfall_model),
+                                   description='Charge goals that were scaled back with the reason, if charge goals are given')
 })
 
 limit_violation_result_model = api.model('LimitViolationResult', {
     'grid_import_limit_exceeded': fields.Boolean(description='The energy demand could only be satisfied by violating the grid import limit.'),
-    'grid_export_limit_hit': fields.Boolean(description='The solar yield was reduced due to the limitation of grid export power.')
+    'grid_export_limit_hit': fields.Boolean(description='The solar yield was reduced due to the limitation of grid export power.'),
+    'cost_budget_exceeded': fields.Boolean(description='The cost budget of the goal seeking mode could not be met.'),
+    'export_cap_reached': fields.Boolean(description='Export beyond the remunerated export cap is planned.')
+})
+
+chp_result_model = api.model('ChpResult', {
+    'electricity': fields.List(fields.Float, description='Electricity generated by the unit at each time step (Wh)'),
+    'heat': fields.List(fields.Float, description='Heat produced by the unit at each time step (Wh)'),
+    'fuel': fields.List(fields.Float, description='Fuel consumed by the unit and the boiler at each time step (Wh)'),
+    'boiler_heat': fields.List(fields.Float, description='Heat produced by the boiler at each time step (Wh)'),
+    'electric_heating': fields.List(fields.Float, description='Electricity consumed by electric heating at each time step (Wh)'),
+    'fuel_cost': fields.Float(description='Fuel cost over the horizon'),
 })
 
 optimization_result_model = api.model('OptimizationResult', {
     'status': fields.String(description='Optimization status'),
     'objective_value': fields.Float(description='Optimal objective function value'),
+    'objective_unit': fields.String(description='Unit of the objective value: currency, Wh or gCO2'),
+    'latency_ms': fields.Float(description='Time spent building and solving the model (ms)'),
+    'optimality_loss': fields.Float(description='Estimated optimality loss of an approximate solution'),
+    'simplified': fields.Boolean(description='The solve timed out and the result is based on the simplified problem'),
     'limit_violations': fields.Nested(limit_violation_result_model, description='Collection of flags signalling the violation of defined limits'),
     'batteries': fields.List(fields.Nested(battery_result_model), description='Battery optimization results'),
     'grid_import': fields.List(fields.Float, description='Energy imported from grid at each time step (Wh)'),
     'grid_export': fields.List(fields.Float, description='Energy exported to grid at each time step (Wh)'),
     'flow_direction': fields.List(fields.Integer, description='Binary flow direction (1=export, 0=import)'),
     'grid_import_overshoot': fields.List(fields.Float, description='Energy above the power limit imported from grid at each time step (Wh)'),
-    'grid_export_overshoot': fields.List(fields.Float, description='Energy not exported due to hitting the grid export power limit at each time step (Wh)')
+    'grid_export_overshoot': fields.List(fields.Float, description='Energy not exported due to hitting the grid export power limit at each time step (Wh)'),
+    'curtailment_risk': fields.List(fields.Boolean, description='Intervals at risk of export curtailment'),
+    'export_preference_score': fields.Float(description='Achieved export preference bonus'),
+    'grid_deviation': fields.List(fields.Float, description='Deviation of the net grid import from the committed schedule at each time step (Wh)'),
+    'grid_import_peak': fields.Float(description='Peak import power including the peak to date, if a peak price curve is given (W)'),
+    'chp': fields.Nested(chp_result_model, allow_null=True, description='Dispatch of the combined heat and power unit, if given')
 })
 
 
+def optimize(data, progress=None, settings: OptimizerSettings | None = None):
+    """
+    Solve the charge schedule of a request, reporting the progress of each solve to progress.
+    settings replace the settings of the server, e.g. the time limit of jobs.
+    """
+    try:
+        strategy, grid, batteries, time_series = parse_optimization_input(data)
+        penalties = parse_penalties(data, batteries, time_series)
+        weights = parse_objective_weights(data)
+        chp = parse_chp(data, time_series)
+    except HTTPException:
+        raise
+    except Exception as e:
+        api.abort(400, f"Invalid data format: {str(e)}")
+
+    def solve(batteries):
+        optimizer = Optimizer(
+            strategy=strategy,
+            grid=grid,
+            batteries=batteries,
+            time_series=time_series,
+            eta_c=data.get('eta_c', 0.95),
+            eta_d=data.get('eta_d', 0.95),
+            M=1e6,
+            cost_budget=data.get('cost_budget'),
+            max_latency_ms=data.get('max_latency_ms'),
+            simplify_on_timeout=data.get('simplify_on_timeout', False),
+            time_limit=data.get('time_limit'),
+            mip_gap=data.get('mip_gap'),
+            require_optimal=data.get('require_optimal', False),
+            warm_start=data.get('warm_start'),
+            penalties=penalties,
+            weights=weights,
+            chp=chp,
+            progress=progress,
+            optimizer_settings=settings,
+            # the latency includes parsing and building the model, jobs measure from their start
+            started=g.get('audit_start'),
+        )
+        with solve_stats.track(len(time_series.dt), len(batteries)):
+            return optimizer.solve()
+
+    try:
+        # Create and solve optimizer
+        result = solve(batteries)
+        if data.get('attribute_batteries', False) and result['status'] == 'Optimal':
+            attribute_batteries(result, batteries, solve)
+        return apply_output_options(marshal(result, optimization_result_model), data.get('output'))
+
+    except Exception as e:
+        api.abort(500, f"Optimization failed: {str(e)}")
+
+
 @ns.route('/charge-schedule')
 class OptimizeCharging(Resource):
     @api.expect(optimization_input_model, validate=True)
-    @api.marshal_with(optimization_result_model)
+    @api.response(200, 'Success', optimization_result_model)
     def post(self):
         """
         Optimize EV charging schedule using MILP
@@ -133,89 +831,390 @@ class OptimizeCharging(Resource):
         This endpoint solves a Mixed Integer Linear Programming problem to optimize
         EV charging schedules considering battery constraints, grid prices, and energy demands.
         """
+        return optimize(api.payload)
+
+
+simulation_input_model = api.clone('SimulationInput', optimization_input_model, {
+    'policy': fields.String(required=False, enum=POLICIES, default='self_consumption', description='Fixed dispatch policy to simulate'),
+})
+
+
+@ns.route('/simulate')
+class Simulate(Resource):
+    @api.expect(simulation_input_model, validate=True)
+    @api.response(200, 'Success', optimization_result_model)
+    def post(self):
+        """
+        Simulate a fixed dispatch policy without optimization
+
+        Cheap baseline returning battery and grid trajectories in the shape of the
+        optimization result, e.g. for charts and for sanity checking battery parameters.
+        """
         try:
             data = api.payload
+            _, grid, batteries, time_series = parse_optimization_input(data)
+            if data.get('chp') is not None:
+                api.abort(400, "Combined heat and power units cannot be simulated")
+        except HTTPException:
+            raise
+        except Exception as e:
+            api.abort(400, f"Invalid data format: {str(e)}")
+
+        simulator = Simulator(
+            grid=grid,
+            batteries=batteries,
+            time_series=time_series,
+            eta_c=data.get('eta_c', 0.95),
+            eta_d=data.get('eta_d', 0.95),
+            policy=data.get('policy', 'self_consumption')
+        )
+        return apply_output_options(marshal(simulator.simulate(), optimization_result_model), data.get('output'))
 
-            # Parse strategy items with default values
-            strat_data = data.get('strategy', {})
-            strategy = OptimizationStrategy(
-                charging_strategy=strat_data.get('charging_strategy', 'none'),
-                discharging_strategy=strat_data.get('discharging_strategy', 'none')
-            )
-
-            # parse grid configuration
-            grid_data = data.get('grid', {})
-            grid = GridConfig(
-                p_max_imp=grid_data.get('p_max_imp', None),
-                p_max_exp=grid_data.get('p_max_exp', None),
-                prc_p_exc_imp=grid_data.get('prc_p_exc_imp', None)
-            )
-
-            # Parse battery configurations
-            batteries = []
-            for bat_data in data['batteries']:
-                batteries.append(BatteryConfig(
-                    charge_from_grid=bat_data.get('charge_from_grid', False),
-                    discharge_to_grid=bat_data.get('discharge_to_grid', False),
-                    s_capacity=bat_data.get('s_capacity', bat_data['s_max']),
-                    s_min=bat_data['s_min'],
-                    s_max=bat_data['s_max'],
-                    s_initial=bat_data['s_initial'],
-                    p_demand=bat_data.get('p_demand'),
-                    s_goal=bat_data.get('s_goal'),
-                    c_min=bat_data['c_min'],
-                    c_max=bat_data['c_max'],
-                    d_max=bat_data['d_max'],
-                    p_a=bat_data['p_a'],
-                    c_priority=bat_data.get('c_priority', 0),
-                ))
-
-            # Parse time series data
-            time_series = TimeSeriesData(
-                dt=data['time_series']['dt'],
-                gt=data['time_series']['gt'],
-                ft=data['time_series']['ft'],
-                p_N=data['time_series']['p_N'],
-                p_E=data['time_series']['p_E'],
-            )
-
-            # Validate time series lengths
-            lengths = [len(time_series.gt), len(time_series.ft),
-                       len(time_series.p_N), len(time_series.p_E)]
-
-            # Validate p_demand if provided
-            for bat in batteries:
-                if bat.p_demand is not None:
-                    lengths.append(len(bat.p_demand))
-
-            # Validate s_goal if provided
-            for bat in batteries:
-                if bat.s_goal is not None:
-                    lengths.append(len(bat.s_goal))
-
-            if len(set(lengths)) > 1:
-                api.abort(400, "All time series must have the same length")
 
+validation_result_model = api.model('ValidationResult', {
+    'request': fields.Nested(optimization_input_model, description='Request with defaults filled in'),
+    'warnings': fields.List(fields.String, description='Hints on inputs that are valid but likely not intended')
+})
+
+
+@ns.route('/validate')
+class Validate(Resource):
+    @api.expect(optimization_input_model, validate=True)
+    @api.marshal_with(validation_result_model)
+    def post(self):
+        """
+        Validate and normalize a request without solving
+
+        Applies the validation of the charge schedule and returns the request with defaults
+        filled in, so that user interfaces can check configuration changes instantly.
+        """
+        try:
+            data = api.payload
+            strategy, grid, batteries, time_series = parse_optimization_input(data)
+            parse_penalties(data, batteries, time_series)
+            weights = parse_objective_weights(data)
+            chp = parse_chp(data, time_series)
+        except HTTPException:
+            raise
         except Exception as e:
             api.abort(400, f"Invalid data format: {str(e)}")
 
+        request = {
+            'strategy': asdict(strategy),
+            'grid': asdict(grid),
+            'batteries': [asdict(bat) for bat in batteries],
+            'time_series': asdict(time_series),
+            'eta_c': data.get('eta_c', 0.95),
+            'eta_d': data.get('eta_d', 0.95),
+            'cost_budget': data.get('cost_budget'),
+            'max_latency_ms': data.get('max_latency_ms'),
+            'simplify_on_timeout': data.get('simplify_on_timeout', False),
+            'time_limit': data.get('time_limit'),
+            'mip_gap': data.get('mip_gap'),
+            'require_optimal': data.get('require_optimal', False),
+            'warm_start': data.get('warm_start'),
+            'penalties': data.get('penalties'),
+            'objective_weights': asdict(weights) if weights else None,
+            'chp': asdict(chp) if chp else None,
+            'attribute_batteries': data.get('attribute_batteries', False),
+            'output': data.get('output'),
+        }
+        return {'request': request, 'warnings': request_warnings(strategy, batteries, time_series)}
+
+
+estimate_result_model = api.model('EstimateResult', {
+    'estimated_solve_time': fields.Float(description='Estimated solve time for the given problem size (s)'),
+    'estimated_queue_wait': fields.Float(description='Estimated wait time until in-flight solves are finished (s)'),
+    'active_solves': fields.Integer(description='Number of solves currently running on this host')
+})
+
+estimate_parser = api.parser()
+estimate_parser.add_argument('time_steps', type=int, required=True, location='args', help='Number of time steps')
+estimate_parser.add_argument('batteries', type=int, required=False, default=1, location='args', help='Number of batteries (at least 1)')
+
+
+@ns.route('/estimate')
+class Estimate(Resource):
+    @api.expect(estimate_parser)
+    @api.marshal_with(estimate_result_model)
+    def get(self):
+        """
+        Estimate solve time and queue wait for a problem size
+
+        Lightweight pre-flight check allowing interactive clients to choose between
+        synchronous and asynchronous flows before submitting a problem.
+        """
+        args = estimate_parser.parse_args()
+        if args['time_steps'] < 0:
+            api.abort(400, "Number of time steps must not be negative")
+        # every problem has at least one battery, an explicit 0 is rejected
+        if args['batteries'] < 1:
+            api.abort(400, "Number of batteries must be at least 1")
+        return solve_stats.estimate(args['time_steps'], args['batteries'])
+
+
+strategy_value_model = api.model('StrategyValue', {
+    'value': fields.String(description='Value of the strategy parameter'),
+    'title': fields.String(description='Short display name'),
+    'description': fields.String(description='Description of the behaviour')
+})
+
+strategy_description_model = api.model('StrategyDescription', {
+    'parameter': fields.String(description='Name of the parameter in the strategy object of the request'),
+    'title': fields.String(description='Short display name'),
+    'description': fields.String(description='Description of the parameter'),
+    'default': fields.String(description='Value used if the parameter is omitted'),
+    'values': fields.List(fields.Nested(strategy_value_model), description='Supported values')
+})
+
+
+template_model = api.model('Template', {
+    'strategy': fields.Nested(strategy_model, required=False, description='Optimization strategy'),
+    'grid': fields.Nested(grid_model, required=False, description='Grid import and export configuration'),
+    'batteries': fields.List(fields.Raw, required=False, description='Static battery configurations, e.g. without s_initial'),
+    'eta_c': fields.Float(required=False, description='Charging efficiency'),
+    'eta_d': fields.Float(required=False, description='Discharging efficiency'),
+})
+
+template_id_model = api.model('TemplateId', {
+    'id': fields.String(description='Template id to reference in requests')
+})
+
+template_list_model = api.model('TemplateList', {
+    'ids': fields.List(fields.String, description='Ids of all templates of the caller')
+})
+
+template_input_model = api.model('TemplateInput', {
+    'time_series': fields.Nested(time_series_model, required=True, description='Time series data'),
+    'batteries': fields.List(fields.Raw, required=False, description='Dynamic battery inputs merged into the template batteries by index, e.g. s_initial'),
+    'grid': fields.Raw(required=False, description='Dynamic grid inputs merged into the template grid, e.g. e_imp_to_date'),
+})
+
+
+@ns.route('/templates')
+class Templates(Resource):
+    @api.marshal_with(template_list_model)
+    def get(self):
+        """
+        List the request templates of the caller
+        """
+        return {'ids': template_store.ids(g.get('subject'))}
+
+    @api.expect(template_model, validate=True)
+    @api.marshal_with(template_id_model, code=201)
+    def post(self):
+        """
+        Register a request template
+
+        Stores the static site definition, e.g. grid and battery configuration, so that requests
+        only carry the dynamic inputs referencing the template id. Templates cannot be modified,
+        registering a changed definition yields a new id. Templates are stored per host.
+        """
         try:
-            # Create and solve optimizer
-            optimizer = Optimizer(
-                strategy=strategy,
-                grid=grid,
-                batteries=batteries,
-                time_series=time_series,
-                eta_c=data.get('eta_c', 0.95),
-                eta_d=data.get('eta_d', 0.95),
-                M=1e6
-            )
-
-            result = optimizer.solve()
-            return result
+            return {'id': template_store.put(g.get('subject'), api.payload)}, 201
+        except ValueError as e:
+            api.abort(400, str(e))
 
-        except Exception as e:
-            api.abort(500, f"Optimization failed: {str(e)}")
+
+@ns.route('/templates/<string:template_id>')
+class Template(Resource):
+    def get(self, template_id):
+        """
+        Get a request template
+        """
+        template = template_store.get(g.get('subject'), template_id)
+        if template is None:
+            api.abort(404, "Template not found")
+        return template
+
+    def delete(self, template_id):
+        """
+        Delete a request template
+        """
+        if not template_store.delete(g.get('subject'), template_id):
+            api.abort(404, "Template not found")
+        return '', 204
+
+
+@ns.route('/templates/<string:template_id>/charge-schedule')
+class OptimizeTemplate(Resource):
+    @api.expect(template_input_model, validate=True)
+    @api.response(200, 'Success', optimization_result_model)
+    def post(self, template_id):
+        """
+        Optimize the charge schedule of a template
+
+        Merges the dynamic inputs of the request into the template and solves it like the charge
+        schedule. Responds 404 if the template is unknown on this host and needs to be registered.
+        """
+        template = template_store.get(g.get('subject'), template_id)
+        if template is None:
+            api.abort(404, "Template not found")
+
+        data = merge_template(template, api.payload)
+        optimization_input_model.validate(data, api.refresolver, api.format_checker)
+        return optimize(data)
+
+
+job_model = api.model('Job', {
+    'id': fields.String(description='Job id'),
+    'status': fields.String(enum=['queued', 'running', 'done', 'failed', 'cancelled'], description='Job status'),
+    'message': fields.String(description='Failure message, if the job failed'),
+})
+
+
+def run_job(data, progress=None, settings: OptimizerSettings | None = None):
+    """
+    Solve a job's request outside of the request context
+    """
+    with app.app_context():
+        return optimize(data, progress, settings)
+
+
+def job_settings() -> OptimizerSettings:
+    """
+    Settings of jobs, their time limit replaces the time limit of requests
+    """
+    settings = OptimizerSettings()
+    if settings.job_time_limit is not None:
+        settings = settings.model_copy(update={'time_limit': settings.job_time_limit})
+    return settings
+
+
+def run_jobs():
+    """
+    Run the queued jobs of this host until SIGTERM, the entry of the job runner process started by
+    the gunicorn master, see gunicorn_conf
+    """
+    settings = OptimizerSettings()
+    runner = JobRunner(job_store, lambda data: run_job(data, settings=job_settings()), settings.job_concurrency)
+    signal.signal(signal.SIGTERM, lambda *_: runner.stop())
+    runner.run(settings.drain_timeout)
+
+
+@ns.route('/jobs')
+class Jobs(Resource):
+    @api.expect(optimization_input_model, validate=True)
+    @api.marshal_with(job_model, code=202)
+    def post(self):
+        """
+        Submit an optimization job
+
+        Solves the charge schedule asynchronously for horizons exceeding the request timeout. Poll
+        the job for its status and fetch the result once it is done. Jobs are kept on the
+        accepting host for an hour after finishing, polls are routed to it. Jobs have a time limit
+        of their own. Responds 429 if the host has too many queued and running jobs and 503 while
+        the host is shutting down.
+        """
+        if job_store.draining:
+            api.abort(503, "Server is shutting down, retry")
+
+        data = api.payload
+        try:
+            if OptimizerSettings().job_runner:
+                return job_store.enqueue(g.get('subject'), data), 202
+            return job_store.submit(g.get('subject'), lambda: run_job(data, settings=job_settings())), 202
+        except JobLimitExceeded as e:
+            raise TooManyRequests(str(e), retry_after=JOB_RETRY_AFTER)
+
+
+@ns.route('/jobs/<string:job_id>')
+class Job(Resource):
+    @api.marshal_with(job_model)
+    def get(self, job_id):
+        """
+        Get the status of an optimization job
+        """
+        job = job_store.get(g.get('subject'), job_id)
+        if job is None:
+            api.abort(404, "Job not found")
+        return job
+
+    def delete(self, job_id):
+        """
+        Cancel an optimization job
+
+        Queued and running jobs are cancelled, a result computed meanwhile is discarded. Finished
+        jobs are not changed.
+        """
+        if job_store.cancel(g.get('subject'), job_id) is None:
+            api.abort(404, "Job not found")
+        return '', 204
+
+
+@ns.route('/jobs/<string:job_id>/result')
+class JobResult(Resource):
+    @api.response(200, 'Success', optimization_result_model)
+    def get(self, job_id):
+        """
+        Get the result of an optimization job
+
+        Responds 409 while the job is not done, or if it was cancelled. Failed jobs respond 500
+        with the failure message like the synchronous endpoint.
+        """
+        job = job_store.get(g.get('subject'), job_id)
+        if job is None:
+            api.abort(404, "Job not found")
+        if job['status'] == FAILED:
+            api.abort(500, job.get('message', 'Optimization failed'))
+        if job['status'] != DONE:
+            api.abort(409, f"Job is {job['status']}")
+        return job['result']
+
+
+def sse(event: str, data: dict) -> str:
+    return f"event: {event}\ndata: {canonical_json(data, RESPONSE_DIGITS)}\n\n"
+
+
+@ns.route('/stream')
+class Stream(Resource):
+    @api.expect(optimization_input_model, validate=True)
+    @api.produces(['text/event-stream'])
+    def post(self):
+        """
+        Optimize with progress updates
+
+        Solves the charge schedule like the synchronous endpoint and streams server-sent events:
+        progress events while solving with the solver phase, the elapsed time, the objective of
+        the incumbent solution and its relative gap to the best bound, then a single result event
+        with the optimization result or an error event with the status code and message of the
+        failure. The worker timeout still applies.
+        """
+        data = api.payload
+        events = queue.Queue()
+
+        def run():
+            try:
+                events.put(('result', run_job(data, lambda p: events.put(('progress', p)))))
+            except Exception as e:
+                # aborted requests carry their message as data
+                message = e.data.get('message', str(e)) if isinstance(getattr(e, 'data', None), dict) else str(e)
+                events.put(('error', {'code': getattr(e, 'code', 500), 'message': message}))
+
+        def generate():
+            thread = threading.Thread(target=run, daemon=True)
+            thread.start()
+            while True:
+                event, payload = events.get()
+                yield sse(event, payload)
+                if event != 'progress':
+                    return
+
+        # proxies must not buffer the events
+        return Response(generate(), mimetype='text/event-stream', headers={'Cache-Control': 'no-cache', 'X-Accel-Buffering': 'no'})
+
+
+@ns.route('/strategies')
+class Strategies(Resource):
+    @api.marshal_list_with(strategy_description_model)
+    def get(self):
+        """
+        Describe the supported strategies
+
+        Machine readable descriptions of the strategy parameters and their values, so that user
+        interfaces can render the options dynamically.
+        """
+        return STRATEGIES
 
 
 @ns.route('/health')
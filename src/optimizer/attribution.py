@@ -0,0 +1,24 @@
+"""
+Attribution of the objective value to the batteries of a site.
+"""
+
+from dataclasses import replace
+from typing import Callable, Dict, List
+
+from .optimizer import BatteryConfig
+
+
+def attribute_batteries(result: Dict, batteries: List[BatteryConfig], solve: Callable[[List[BatteryConfig]], Dict]):
+    """
+    Sets the contribution of each enabled battery to the objective value of result, i.e. the objective
+    value lost when solving again without the battery. Contributions of batteries that can substitute
+    each other do not add up to their joint contribution. Disabled batteries and batteries whose removal
+    makes the problem infeasible get no contribution.
+    """
+    for k, bat in enumerate(batteries):
+        contribution = None
+        if bat.enabled:
+            without = solve([replace(b, enabled=False) if j == k else b for j, b in enumerate(batteries)])
+            if without['status'] == 'Optimal':
+                contribution = result['objective_value'] - without['objective_value']
+        result['batteries'][k]['contribution'] = contribution
@@ -0,0 +1,101 @@
+import argparse
+import fcntl
+import hashlib
+import json
+import sys
+from datetime import datetime, timezone
+
+from tabulate import tabulate
+
+from .canonical import canonical_json
+
+
+def digest(payload) -> str:
+    """
+    Digest of a request payload independent of its formatting, identifying repeated requests.
+    Equals client.Digest of the Go client.
+    """
+    return hashlib.sha256(canonical_json(payload).encode()).hexdigest()[:16]
+
+
+def utc_now() -> str:
+    """
+    Current time of audit entries
+    """
+    return datetime.now(timezone.utc).isoformat(timespec='milliseconds')
+
+
+class AuditLog:
+    """
+    Append-only log of solve requests, one JSON line per request, in a file shared by all
+    workers of a host. Appends are guarded by an exclusive file lock to keep lines intact.
+    Logging is disabled without a path.
+    """
+
+    def __init__(self, path: str | None = None):
+        self.path = path
+
+    def record(self, entry: dict):
+        if not self.path:
+            return
+
+        line = json.dumps(entry, separators=(',', ':')) + '\n'
+        with open(self.path, 'a') as f:
+            fcntl.flock(f, fcntl.LOCK_EX)
+            try:
+                f.write(line)
+            finally:
+                fcntl.flock(f, fcntl.LOCK_UN)
+
+    def query(self, since: datetime | None = None, until: datetime | None = None, subject: str | None = None,
+              request_digest: str | None = None):
+        """
+        Entries matching all given filters in the order recorded. Lines not parsing are skipped,
+        e.g. a line partially written by a killed worker.
+        """
+        if not self.path:
+            return
+
+        with open(self.path) as f:
+            for line in f:
+                try:
+                    entry = json.loads(line)
+                    ts = datetime.fromisoformat(entry['time'])
+                except (ValueError, KeyError):
+                    continue
+
+                if ((since is not None and ts < since) or (until is not None and ts >= until)
+                        or (subject is not None and entry.get('subject') != subject)
+                        or (request_digest is not None and entry.get('digest') != request_digest)):
+                    continue
+
+                yield entry
+
+
+def main(argv: list[str] | None = None):
+    def timestamp(value: str) -> datetime:
+        ts = datetime.fromisoformat(value)
+        return ts if ts.tzinfo else ts.replace(tzinfo=timezone.utc)
+
+    parser = argparse.ArgumentParser(prog='python -m optimizer.audit', description='Query the audit log of solve requests')
+    parser.add_argument('path', help='audit log file, see OPTIMIZER_AUDIT_FILE')
+    parser.add_argument('--since', type=timestamp, help='first time to include, ISO 8601, UTC if no offset is given')
+    parser.add_argument('--until', type=timestamp, help='first time to exclude, ISO 8601, UTC if no offset is given')
+    parser.add_argument('--subject', help='token subject of the requests')
+    parser.add_argument('--digest', help='digest of the request payload')
+    parser.add_argument('--json', action='store_true', help='print JSON lines instead of a table')
+    args = parser.parse_args(argv)
+
+    entries = AuditLog(args.path).query(args.since, args.until, args.subject, args.digest)
+
+    if args.json:
+        for entry in entries:
+            sys.stdout.write(json.dumps(entry) + '\n')
+        return
+
+    columns = ['time', 'subject', 'client', 'path', 'digest', 'status', 'outcome', 'duration_ms']
+    print(tabulate([[entry.get(c) for c in columns] for entry in entries], headers=columns))
+
+
+if __name__ == '__main__':
+    main()
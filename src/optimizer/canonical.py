@@ -0,0 +1,37 @@
+import json
+import math
+
+# significant digits of response values, removing solver noise like 2999.9999999999995
+RESPONSE_DIGITS = 10
+
+# response values of smaller magnitude are solver noise and become zero
+RESPONSE_NOISE = 1e-9
+
+# floats of smaller magnitude encode as integers if integral, like in Go
+MAX_INTEGRAL = 1e16
+
+
+def normalize(value, digits: int | None = None):
+    """
+    Normalize the numbers of a JSON value, so equal values encode equally: integral floats
+    become integers and negative zero becomes zero. With digits, floats are rounded to that
+    number of significant digits and values below RESPONSE_NOISE become zero.
+    """
+    if isinstance(value, dict):
+        return {k: normalize(v, digits) for k, v in value.items()}
+    if isinstance(value, (list, tuple)):
+        return [normalize(v, digits) for v in value]
+    if isinstance(value, float) and math.isfinite(value):
+        if digits is not None:
+            value = 0. if abs(value) < RESPONSE_NOISE else float(f'{value:.{digits}g}')
+        if value.is_integer() and abs(value) < MAX_INTEGRAL:
+            return int(value)
+    return value
+
+
+def canonical_json(value, digits: int | None = None) -> str:
+    """
+    Encode a JSON value with sorted keys, without whitespace and with normalized numbers, so
+    equal values encode to equal text, see normalize
+    """
+    return json.dumps(normalize(value, digits), sort_keys=True, separators=(',', ':'), ensure_ascii=False)
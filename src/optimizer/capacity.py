@@ -0,0 +1,109 @@
+import fcntl
+import json
+import os
+import tempfile
+import time
+import uuid
+from contextlib import contextmanager
+
+
+class SolveStatistics:
+    """
+    Statistics of recent and in-flight solves, used to estimate solve time and queue wait
+    for a given problem size.
+
+    Gunicorn runs independent worker processes, each answering one request at a time. The
+    statistics are therefore kept in a file shared by all workers of a host and guarded by
+    an exclusive file lock. Estimates are best-effort: they cover the workers sharing the
+    file only and are based on the most recent solves.
+    """
+
+    # solve time per time step and battery assumed before any solve has been recorded [s]
+    DEFAULT_SECONDS_PER_CELL = 2e-3
+
+    def __init__(self, path: str | None = None, window: int = 50, max_age: float = 3600):
+        self.path = path or os.path.join(tempfile.gettempdir(), 'optimizer-solve-stats.json')
+        # number of recent solves to keep
+        self.window = window
+        # in-flight solves older than this are considered dead workers [s]
+        self.max_age = max_age
+
+    @staticmethod
+    def problem_size(time_steps: int, batteries: int) -> int:
+        """
+        Problem size as number of time step and battery combinations
+        """
+        return max(1, time_steps) * max(1, batteries)
+
+    @contextmanager
+    def _state(self):
+        """
+        Context manager yielding the shared state under an exclusive lock. Changes
+        to the yielded state are written back on exit.
+        """
+        with open(self.path, 'a+') as f:
+            fcntl.flock(f, fcntl.LOCK_EX)
+            try:
+                f.seek(0)
+                try:
+                    state = json.loads(f.read() or '{}')
+                except ValueError:
+                    state = {}
+                state.setdefault('samples', [])
+                state.setdefault('active', {})
+
+                # drop solves of workers that were killed mid-solve
+                now = time.time()
+                state['active'] = {k: v for k, v in state['active'].items() if now - v['start'] < self.max_age}
+
+                yield state
+
+                state['samples'] = state['samples'][-self.window:]
+                f.seek(0)
+                f.truncate()
+                f.write(json.dumps(state))
+            finally:
+                fcntl.flock(f, fcntl.LOCK_UN)
+
+    @contextmanager
+    def track(self, time_steps: int, batteries: int):
+        """
+        Context manager recording an in-flight solve and its duration
+        """
+        key = uuid.uuid4().hex
+        size = self.problem_size(time_steps, batteries)
+        start = time.time()
+        with self._state() as state:
+            state['active'][key] = {'start': start, 'size': size}
+        try:
+            yield
+        finally:
+            with self._state() as state:
+                state['active'].pop(key, None)
+                state['samples'].append([size, time.time() - start])
+
+    @classmethod
+    def _seconds_per_cell(cls, samples) -> float:
+        size = sum(s for s, _ in samples)
+        duration = sum(d for _, d in samples)
+        if size == 0:
+            return cls.DEFAULT_SECONDS_PER_CELL
+        return duration / size
+
+    def estimate(self, time_steps: int, batteries: int) -> dict:
+        """
+        Estimate solve time and queue wait for a problem of the given size. The queue wait
+        is the sum of the estimated remaining time of all in-flight solves.
+        """
+        with self._state() as state:
+            seconds_per_cell = self._seconds_per_cell(state['samples'])
+            active = list(state['active'].values())
+
+        now = time.time()
+        queue_wait = sum(max(0, seconds_per_cell * a['size'] - (now - a['start'])) for a in active)
+
+        return {
+            'estimated_solve_time': seconds_per_cell * self.problem_size(time_steps, batteries),
+            'estimated_queue_wait': queue_wait,
+            'active_solves': len(active),
+        }
@@ -0,0 +1,125 @@
+import gzip
+import io
+import zlib
+
+from flask import Response, request
+
+# maximum size of a decompressed request body [bytes]
+MAX_DECOMPRESSED_SIZE = 16 << 20
+
+# responses smaller than this are not worth compressing [bytes]
+MIN_COMPRESS_SIZE = 512
+
+
+class GzipRequestMiddleware:
+    """
+    WSGI middleware decompressing gzip encoded request bodies, allowing clients on metered
+    connections to compress their requests. The decompressed size is limited to protect
+    against decompression bombs.
+    """
+
+    def __init__(self, app, max_size: int = MAX_DECOMPRESSED_SIZE):
+        self.app = app
+        self.max_size = max_size
+
+    def __call__(self, environ, start_response):
+        if environ.get('HTTP_CONTENT_ENCODING', '').lower() != 'gzip':
+            return self.app(environ, start_response)
+
+        length = int(environ.get('CONTENT_LENGTH') or 0)
+        body = environ['wsgi.input'].read(length)
+
+        try:
+            # wbits for gzip header and trailer
+            decompressor = zlib.decompressobj(16 + zlib.MAX_WBITS)
+            data = decompressor.decompress(body, self.max_size)
+            if decompressor.unconsumed_tail:
+                return Response('{"message": "Decompressed request body too large"}', 413,
+                                mimetype='application/json')(environ, start_response)
+        except zlib.error:
+            return Response('{"message": "Invalid gzip request body"}', 400,
+                            mimetype='application/json')(environ, start_response)
+
+        environ['wsgi.input'] = io.BytesIO(data)
+        environ['CONTENT_LENGTH'] = str(len(data))
+        del environ['HTTP_CONTENT_ENCODING']
+
+        return self.app(environ, start_response)
+
+
+def compress_response(response):
+    """
+    Compress JSON responses for clients accepting gzip encoding
+    """
+    if ('gzip' not in request.headers.get('Accept-Encoding', '').lower()
+            or response.direct_passthrough
+            or response.mimetype != 'application/json'
+            or 'Content-Encoding' in response.headers
+            or (response.content_length or 0) < MIN_COMPRESS_SIZE):
+        return response
+
+    response.set_data(gzip.compress(response.get_data(), compresslevel=6))
+    response.headers['Content-Encoding'] = 'gzip'
+    response.vary.add('Accept-Encoding')
+
+    return response
+
+
+# energy per time step series, rounded without accumulating rounding errors
+ENERGY_SERIES = {'charging_power', 'discharging_power', 'preconditioning_power', 'grid_import', 'grid_export',
+                 'grid_import_overshoot', 'grid_export_overshoot', 'grid_deviation'}
+
+
+def round_series(value, precision: int, key: str | None = None):
+    """
+    Round all numbers in lists of value to the given number of decimals, recursing into
+    dicts and lists. Scalars outside lists are kept. Energy series are rounded by
+    round_conserving.
+    """
+    if isinstance(value, dict):
+        return {k: round_series(v, precision, k) for k, v in value.items()}
+    if isinstance(value, list):
+        if key in ENERGY_SERIES and all(isinstance(v, (int, float)) for v in value):
+            return round_conserving(value, precision)
+        return [_round(v, precision) if isinstance(v, float) else round_series(v, precision) for v in value]
+    return value
+
+
+def round_conserving(values: list, precision: int) -> list:
+    """
+    Round a series to the given number of decimals without accumulating rounding errors. The
+    running sums are rounded instead of the values, so the running sum of the rounded series
+    deviates by at most half a unit of the last decimal and the total equals the rounded total.
+    """
+    result = []
+    total = 0.
+    prev = 0.
+    for v in values:
+        total += v
+        rounded = round(total, precision)
+        result.append(_round(rounded - prev, precision))
+        prev = rounded
+    return result
+
+
+def _round(value: float, precision: int):
+    rounded = round(value, precision)
+    # integers encode shorter than floats with zero decimals
+    return int(rounded) if precision <= 0 else rounded
+
+
+def apply_output_options(result: dict, output: dict | None) -> dict:
+    """
+    Apply the response size options of the request: field selection and reduced precision
+    of the result series.
+    """
+    if not output:
+        return result
+
+    if output.get('fields'):
+        result = {k: v for k, v in result.items() if k in output['fields'] or k == 'status'}
+
+    if output.get('precision') is not None:
+        result = round_series(result, output['precision'])
+
+    return result
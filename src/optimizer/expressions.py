@@ -0,0 +1,148 @@
+import ast
+from typing import Dict, List, Tuple
+
+# maximum length of a penalty expression [characters]
+MAX_EXPRESSION_LENGTH = 1000
+
+# series of the grid, indexed by time step
+GRID_SERIES = ('grid_import', 'grid_export')
+# series of the batteries, indexed by battery index or id first, then by time step
+BATTERY_SERIES = ('charge', 'discharge', 'soc')
+
+
+class ExpressionError(ValueError):
+    pass
+
+
+# linear term of a series at a time step: (series, battery index or None, time step)
+Term = Tuple[str, int | None, int]
+
+
+class Penalty:
+    """
+    Linear penalty term compiled from an expression: coefficients per series and time step plus
+    a constant. Battery indices refer to all batteries of the request including disabled ones.
+    """
+
+    def __init__(self, terms: Dict[Term, float] | None = None, constant: float = 0):
+        self.terms = terms or {}
+        self.constant = constant
+
+    def add(self, other: 'Penalty', sign: float = 1) -> 'Penalty':
+        terms = dict(self.terms)
+        for key, coef in other.terms.items():
+            terms[key] = terms.get(key, 0) + sign * coef
+        return Penalty(terms, self.constant + sign * other.constant)
+
+    def scale(self, factor: float) -> 'Penalty':
+        return Penalty({key: factor * coef for key, coef in self.terms.items()}, factor * self.constant)
+
+
+def compile_penalty(expression: str, T: int, battery_ids: List[str | None]) -> Penalty:
+    """
+    Compile a penalty expression over the series of a plan with T time steps, e.g.
+
+        0.05 * grid_import[18:21] - 0.01 * soc['car'][23]
+
+    Series are grid_import, grid_export [Wh] and charge[i], discharge[i], soc[i] [Wh] of battery
+    i given by index or id. A series with a time step is its value at that step, with a slice
+    the sum over the steps, without either the sum over the horizon. Numbers, +, -, * and / by
+    numbers and parentheses are allowed, anything else, e.g. products of series, is rejected.
+    """
+    if len(expression) > MAX_EXPRESSION_LENGTH:
+        raise ExpressionError(f"expression exceeds {MAX_EXPRESSION_LENGTH} characters")
+
+    try:
+        tree = ast.parse(expression, mode='eval')
+    except SyntaxError as e:
+        raise ExpressionError(f"invalid syntax: {e.msg}") from None
+
+    def number(node) -> float:
+        if isinstance(node, ast.Constant) and type(node.value) in (int, float):
+            return float(node.value)
+        if isinstance(node, ast.UnaryOp) and isinstance(node.op, (ast.USub, ast.UAdd)):
+            value = number(node.operand)
+            return -value if isinstance(node.op, ast.USub) else value
+        raise ExpressionError("expected a number")
+
+    def time_steps(node) -> range:
+        if isinstance(node, ast.Slice):
+            if node.step is not None:
+                raise ExpressionError("slice steps are not supported")
+            lower = 0 if node.lower is None else index(node.lower)
+            upper = T if node.upper is None else index(node.upper)
+            if not 0 <= lower <= upper <= T:
+                raise ExpressionError(f"time steps {lower}:{upper} are outside of the horizon of {T} steps")
+            return range(lower, upper)
+        t = index(node)
+        if not 0 <= t < T:
+            raise ExpressionError(f"time step {t} is outside of the horizon of {T} steps")
+        return range(t, t + 1)
+
+    def index(node) -> int:
+        value = number(node)
+        if value != int(value):
+            raise ExpressionError("time steps must be integers")
+        return int(value)
+
+    def battery(node) -> int:
+        if isinstance(node, ast.Constant) and isinstance(node.value, str):
+            if node.value not in battery_ids:
+                raise ExpressionError(f"unknown battery id '{node.value}'")
+            return battery_ids.index(node.value)
+        i = index(node)
+        if not 0 <= i < len(battery_ids):
+            raise ExpressionError(f"battery {i} does not exist")
+        return i
+
+    def series(name: str, i: int | None, steps: range) -> Penalty:
+        return Penalty({(name, i, t): 1. for t in steps})
+
+    def visit(node) -> Penalty:
+        if isinstance(node, ast.Constant):
+            return Penalty(constant=number(node))
+
+        if isinstance(node, ast.UnaryOp) and isinstance(node.op, (ast.USub, ast.UAdd)):
+            value = visit(node.operand)
+            return value.scale(-1) if isinstance(node.op, ast.USub) else value
+
+        if isinstance(node, ast.BinOp):
+            left, right = visit(node.left), visit(node.right)
+            if isinstance(node.op, ast.Add):
+                return left.add(right)
+            if isinstance(node.op, ast.Sub):
+                return left.add(right, -1)
+            if isinstance(node.op, ast.Mult):
+                if not left.terms:
+                    return right.scale(left.constant)
+                if not right.terms:
+                    return left.scale(right.constant)
+                raise ExpressionError("products of series are not linear")
+            if isinstance(node.op, ast.Div):
+                if right.terms or right.constant == 0:
+                    raise ExpressionError("division is only allowed by non-zero numbers")
+                return left.scale(1 / right.constant)
+            raise ExpressionError(f"operator {type(node.op).__name__} is not supported")
+
+        if isinstance(node, ast.Name):
+            if node.id in GRID_SERIES:
+                return series(node.id, None, range(T))
+            if node.id in BATTERY_SERIES:
+                raise ExpressionError(f"{node.id} requires a battery index or id, e.g. {node.id}[0]")
+            raise ExpressionError(f"unknown series '{node.id}'")
+
+        if isinstance(node, ast.Subscript):
+            # battery series with battery and time steps
+            if isinstance(node.value, ast.Subscript) and isinstance(node.value.value, ast.Name) \
+                    and node.value.value.id in BATTERY_SERIES:
+                return series(node.value.value.id, battery(node.value.slice), time_steps(node.slice))
+            if isinstance(node.value, ast.Name):
+                if node.value.id in GRID_SERIES:
+                    return series(node.value.id, None, time_steps(node.slice))
+                if node.value.id in BATTERY_SERIES:
+                    return series(node.value.id, battery(node.slice), range(T))
+                raise ExpressionError(f"unknown series '{node.value.id}'")
+
+        raise ExpressionError(f"{type(node).__name__} is not supported")
+
+    return visit(tree.body)
@@ -0,0 +1,38 @@
+"""
+Gunicorn configuration starting the job runner next to the workers, so that jobs are not
+interrupted by recycling the workers accepting them. Use with
+
+    gunicorn --config python:optimizer.gunicorn_conf optimizer.app:app
+
+and OPTIMIZER_JOB_RUNNER=true. On shutdown the runner drains its jobs after the workers finished
+their requests.
+"""
+import multiprocessing
+
+from optimizer.settings import OptimizerSettings
+
+_runner = None
+
+
+def _run_jobs():
+    # imported in the runner process only, the master does not load the app
+    from optimizer.app import run_jobs
+    run_jobs()
+
+
+def when_ready(server):
+    global _runner
+    if not OptimizerSettings().job_runner:
+        return
+    _runner = multiprocessing.get_context('spawn').Process(target=_run_jobs, name='job-runner')
+    _runner.start()
+    server.log.info("Started job runner (pid: %s)", _runner.pid)
+
+
+def on_exit(server):
+    if _runner is None or not _runner.is_alive():
+        return
+    _runner.terminate()
+    _runner.join(OptimizerSettings().drain_timeout + 5)
+    if _runner.is_alive():
+        _runner.kill()
@@ -0,0 +1,311 @@
+import fcntl
+import glob
+import hashlib
+import json
+import os
+import secrets
+import tempfile
+import threading
+import time
+from contextlib import contextmanager
+
+# finished jobs are kept for this long [s]
+JOB_TTL = 3600
+
+# interval in which the job runner looks for queued jobs [s]
+JOB_POLL_INTERVAL = 0.5
+
+QUEUED = 'queued'
+RUNNING = 'running'
+DONE = 'done'
+FAILED = 'failed'
+CANCELLED = 'cancelled'
+
+
+class JobLimitExceeded(Exception):
+    """
+    Raised when creating a job while the number of queued and running jobs of the host is at its limit
+    """
+
+
+def instance_id() -> str | None:
+    """
+    Id of the machine serving the request on Fly.io, polls of jobs of other machines are replayed
+    to their machine
+    """
+    machine = os.environ.get('FLY_MACHINE_ID', '')
+    return machine if machine.isalnum() else None
+
+
+class JobStore:
+    """
+    Asynchronous optimization jobs for horizons exceeding the request timeout. Job state is kept
+    in a directory shared by all workers of a host, one file per job, job ids carry the machine
+    of the host so that polls are routed to it.
+
+    Jobs run in the job runner process started by the gunicorn master, independent of the
+    recycling of the workers accepting them, see JobRunner. Without runner, e.g. in development,
+    jobs run in a thread of the accepting worker.
+
+    Cancelling a running job discards its result, the solver itself runs to completion.
+
+    On shutdown the runner, or the worker running jobs in threads, drains its jobs, jobs not
+    finished within the drain window fail and need to be resubmitted.
+    """
+
+    def __init__(self, path: str | None = None, max_jobs: int | None = None):
+        self.path = path or os.path.join(tempfile.gettempdir(), 'optimizer-jobs')
+        # maximum number of queued and running jobs of the host, unlimited if None
+        self.max_jobs = max_jobs
+        self.instance = instance_id()
+        self._lock = threading.Lock()
+        # job threads of this worker by job file
+        self._threads: dict[str, threading.Thread] = {}
+        self.draining = False
+
+    @staticmethod
+    def _owner_dir(owner: str | None) -> str:
+        return hashlib.sha256((owner or '').encode()).hexdigest()[:16]
+
+    def owner_instance(self, job_id: str) -> str | None:
+        """
+        Machine of the host keeping the job, None for jobs of hosts without machine id
+        """
+        machine, sep, _ = job_id.rpartition('-')
+        return machine if sep and machine.isalnum() else None
+
+    def _file(self, owner: str | None, job_id: str) -> str | None:
+        # ids are random hex tokens prefixed by the machine, anything else cannot be a job
+        token = job_id.rpartition('-')[2]
+        if len(token) != 16 or any(c not in '0123456789abcdef' for c in token) or self.owner_instance(job_id) != self.instance:
+            return None
+        return os.path.join(self.path, self._owner_dir(owner), job_id + '.json')
+
+    @contextmanager
+    def _locked(self):
+        """
+        Serialize job transitions of all workers and the runner of the host
+        """
+        os.makedirs(self.path, exist_ok=True)
+        with self._lock, open(os.path.join(self.path, '.lock'), 'w') as f:
+            fcntl.flock(f, fcntl.LOCK_EX)
+            try:
+                yield
+            finally:
+                fcntl.flock(f, fcntl.LOCK_UN)
+
+    def _write(self, path: str, job: dict):
+        # write atomically, readers in other workers never see partial files
+        fd, tmp = tempfile.mkstemp(dir=os.path.dirname(path))
+        with os.fdopen(fd, 'w') as f:
+            json.dump(job, f)
+        os.replace(tmp, path)
+
+    def _files(self) -> list[str]:
+        return glob.glob(os.path.join(self.path, '*', '*.json'))
+
+    def _prune(self):
+        """
+        Remove jobs finished more than JOB_TTL ago
+        """
+        deadline = time.time() - JOB_TTL
+        for path in self._files():
+            try:
+                if os.path.getmtime(path) < deadline:
+                    os.remove(path)
+            except FileNotFoundError:
+                pass
+
+    def _active(self) -> list[str]:
+        """
+        Files of the queued and running jobs of the host
+        """
+        return [path for path in self._files() if (job := self._read(path)) is not None and job['status'] in (QUEUED, RUNNING)]
+
+    def _create(self, owner: str | None, job: dict) -> tuple[str, dict]:
+        """
+        Create a queued job, fails while draining or if the host is at its job limit
+        """
+        if self.draining:
+            raise RuntimeError('Draining jobs for shutdown')
+
+        token = secrets.token_hex(8)
+        job_id = f'{self.instance}-{token}' if self.instance else token
+        path = self._file(owner, job_id)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+
+        with self._locked():
+            self._prune()
+            if self.max_jobs is not None and len(self._active()) >= self.max_jobs:
+                raise JobLimitExceeded(f'Too many jobs, at most {self.max_jobs} are queued or running')
+            self._write(path, {'id': job_id, 'status': QUEUED, **job})
+
+        return path, {'id': job_id, 'status': QUEUED}
+
+    def enqueue(self, owner: str | None, request: dict) -> dict:
+        """
+        Create a job solving request in the job runner
+        """
+        return self._create(owner, {'request': request})[1]
+
+    def submit(self, owner: str | None, run) -> dict:
+        """
+        Create a job and run it in a background thread of this worker. run returns the result or
+        raises an exception with the failure message.
+        """
+        path, job = self._create(owner, {})
+
+        def target():
+            self.execute(path, run)
+            with self._lock:
+                self._threads.pop(path, None)
+
+        thread = threading.Thread(target=target, daemon=True)
+        with self._lock:
+            self._threads[path] = thread
+        thread.start()
+
+        return job
+
+    def execute(self, path: str, run):
+        """
+        Run a queued job unless it was cancelled meanwhile
+        """
+        if not self._transition(path, (QUEUED,), {'status': RUNNING}):
+            return
+        try:
+            update = {'status': DONE, 'result': run()}
+        except Exception as e:
+            # aborted requests carry their message as data
+            data = getattr(e, 'data', None)
+            update = {'status': FAILED, 'message': str(data.get('message', e) if isinstance(data, dict) else e)}
+        # the request is not needed any more
+        self._transition(path, (RUNNING,), update, drop=('request',))
+
+    def _transition(self, path: str, states: tuple, update: dict, drop: tuple = ()) -> bool:
+        """
+        Update a job if it is in one of states, False if it was cancelled or deleted meanwhile
+        """
+        with self._locked():
+            job = self._read(path)
+            if job is None or job['status'] not in states:
+                return False
+            self._write(path, {k: v for k, v in {**job, **update}.items() if k not in drop})
+            return True
+
+    @staticmethod
+    def _read(path: str | None) -> dict | None:
+        if path is None:
+            return None
+        try:
+            with open(path) as f:
+                return json.load(f)
+        except FileNotFoundError:
+            return None
+
+    def get(self, owner: str | None, job_id: str) -> dict | None:
+        """
+        Load a job including its result, None if it does not exist
+        """
+        job = self._read(self._file(owner, job_id))
+        if job is not None:
+            job.pop('request', None)
+        return job
+
+    def cancel(self, owner: str | None, job_id: str) -> dict | None:
+        """
+        Cancel a queued or running job, finished jobs are kept. None if it does not exist
+        """
+        path = self._file(owner, job_id)
+        if path is None:
+            return None
+        self._transition(path, (QUEUED, RUNNING), {'status': CANCELLED}, drop=('request',))
+        return self.get(owner, job_id)
+
+    def running(self) -> list[str]:
+        """
+        Files of the running jobs of the host
+        """
+        return [path for path in self._files() if (job := self._read(path)) is not None and job['status'] == RUNNING]
+
+    def queued(self) -> list[tuple[str, dict]]:
+        """
+        Files and requests of the jobs queued for the runner, oldest first
+        """
+        jobs = []
+        for path in self._files():
+            job = self._read(path)
+            if job is not None and job['status'] == QUEUED and 'request' in job:
+                jobs.append((os.path.getmtime(path), path, job['request']))
+        return [(path, request) for _, path, request in sorted(jobs)]
+
+    def fail(self, paths, message: str):
+        """
+        Fail the jobs of paths still queued or running
+        """
+        for path in paths:
+            self._transition(path, (QUEUED, RUNNING), {'status': FAILED, 'message': message}, drop=('request',))
+
+    def drain(self, timeout: float):
+        """
+        Stop accepting jobs and wait up to timeout [s] for the job threads of this worker to
+        finish. Jobs still queued or running afterwards fail, clients polling them resubmit instead
+        of waiting for a result that never comes.
+        """
+        self.draining = True
+        deadline = time.monotonic() + timeout
+
+        with self._lock:
+            threads = dict(self._threads)
+
+        for thread in threads.values():
+            thread.join(max(0., deadline - time.monotonic()))
+
+        self.fail([path for path, thread in threads.items() if thread.is_alive()], 'Interrupted by server shutdown, resubmit the job')
+
+
+class JobRunner:
+    """
+    Runs the queued jobs of a store, at most concurrency at a time, in a process of its own so
+    that jobs outlive the workers accepting them. solve returns the result of a request or raises
+    an exception with the failure message.
+    """
+
+    def __init__(self, store: JobStore, solve, concurrency: int = 1):
+        self.store = store
+        self.solve = solve
+        self.concurrency = max(1, concurrency)
+        self._stop = threading.Event()
+        self._threads: dict[str, threading.Thread] = {}
+
+    def stop(self):
+        self._stop.set()
+
+    def run(self, drain_timeout: float):
+        """
+        Run jobs until stopped, then drain the running jobs for up to drain_timeout [s]. Jobs left
+        running by a previous runner fail, they were interrupted.
+        """
+        self.store.fail(self.store.running(), 'Interrupted by server restart, resubmit the job')
+
+        while not self._stop.is_set():
+            self._threads = {path: thread for path, thread in self._threads.items() if thread.is_alive()}
+
+            for path, request in self.store.queued():
+                if len(self._threads) >= self.concurrency:
+                    break
+                if path in self._threads:
+                    continue
+                thread = threading.Thread(target=self.store.execute, args=(path, lambda r=request: self.solve(r)), daemon=True)
+                self._threads[path] = thread
+                thread.start()
+
+            self._stop.wait(JOB_POLL_INTERVAL)
+
+        self.store.draining = True
+        deadline = time.monotonic() + drain_timeout
+        for thread in self._threads.values():
+            thread.join(max(0., deadline - time.monotonic()))
+
+        self.store.fail([path for path, _ in self.store.queued()] + [path for path, thread in self._threads.items() if thread.is_alive()],
+                        'Interrupted by server shutdown, resubmit the job')
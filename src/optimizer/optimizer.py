@@ -1,10 +1,14 @@
-from dataclasses import dataclass
+import os
+import time
+from dataclasses import dataclass, replace
 from tempfile import TemporaryDirectory
-from typing import Dict, List, Optional
+from typing import Callable, Dict, List, Optional
 
 import numpy as np
 import pulp
 
+from .expressions import Penalty
+from .progress import MODEL, RESULT, SolverProgress
 from .settings import OptimizerSettings
 
 
@@ -12,6 +16,55 @@ from .settings import OptimizerSettings
 class OptimizationStrategy:
     charging_strategy: str
     discharging_strategy: str
+    objective: str = 'cost'  # quantity to optimize: cost, energy or emissions
+    spike_guard_hours: float = 0  # hours of the most expensive import of the next day to keep a reserve for
+
+
+@dataclass
+class ObjectiveWeights:
+    cost: float = 1  # weight of the economic benefit of the strategy objective
+    battery_wear: float = 0  # weight of the battery throughput, charge plus discharge [Wh]
+    self_consumption: float = 0  # weight of the grid exchange, import plus export [Wh]
+    peak_shaving: float = 0  # weight of the peak import power [W], i.e. its energy over one hour
+
+
+# curtailment probability from which an interval is flagged at risk
+CURTAILMENT_RISK_THRESHOLD = 0.5
+
+# state of charge bounds are tightened by this multiple of the initial state of charge uncertainty
+S_INITIAL_STDDEV_FACTOR = 2
+
+# time after which the initial state of charge uncertainty is no longer considered, as the plan
+# will have been re-optimized with fresh measurements [s]
+S_INITIAL_UNCERTAINTY_HORIZON = 4 * 3600
+
+# margin by which a Wh charged for the goals of a goal priority level outweighs a Wh charged for the
+# goals of the levels below, covering the different efficiencies of the batteries, see goal_weights
+GOAL_PRIORITY_FACTOR = 10
+
+# largest goal penalty factor of goal_weights, keeping the goal penalties within the numerical range of
+# the solver
+MAX_GOAL_WEIGHT = 1e4
+
+# share of the time limit for the MILP solve if simplify_on_timeout is set, the approximation is
+# solved within the rest
+SIMPLIFY_MILP_SHARE = 0.8
+
+# shortfalls of charge goals below this energy are not reported [Wh]
+GOAL_SHORTFALL_TOLERANCE = 1.
+
+# unit of the objective value per objective
+OBJECTIVE_UNITS = {
+    'cost': 'currency',
+    'energy': 'Wh',
+    'emissions': 'gCO2',
+}
+
+
+@dataclass
+class PeakPricePoint:
+    power: float  # peak import power from which the price applies [W]
+    price: float  # price per W of peak import power above this power [currency unit/W]
 
 
 @dataclass
@@ -19,6 +72,48 @@ class GridConfig:
     p_max_imp: float
     p_max_exp: float
     prc_p_exc_imp: float
+    e_imp_tier: Optional[float] = None  # import energy allowance at base price per billing period [Wh]
+    e_imp_to_date: float = 0  # energy imported so far in the current billing period [Wh]
+    prc_e_exc_tier: Optional[float] = None  # price surcharge for import beyond the allowance [currency unit/Wh]
+    t_tier_reset: Optional[int] = None  # first time step of the next billing period
+    prc_e_dev: Optional[float] = None  # price for deviating from the committed grid exchange [currency unit/Wh]
+    e_exp_cap: Optional[float] = None  # remunerated export energy per cap period [Wh]
+    e_exp_to_date: float = 0  # energy exported so far in the current cap period [Wh]
+    t_cap_reset: Optional[int] = None  # first time step of the next cap period
+    prc_p_peak: Optional[List[PeakPricePoint]] = None  # convex piecewise linear price of the peak import power
+    p_peak_to_date: float = 0  # peak import power reached so far in the current billing period, for peak prices and the demand rate [W]
+    prc_e_net_imp: Optional[float] = None  # price of the net import per netting period, enables net metering [currency unit/Wh]
+    prc_e_net_exp: float = 0  # credit for the net export per netting period [currency unit/Wh]
+    e_net_to_date: float = 0  # net import so far in the current netting period, negative for net export [Wh]
+    t_net_reset: Optional[int] = None  # first time step of the next netting period
+    p_conn: Optional[float] = None  # capacity of the grid connection, limiting import and export [W]
+
+
+@dataclass
+class EfficiencyPoint:
+    power: float  # charge or discharge power [W]
+    eta: float  # efficiency at this power [0..1]
+
+
+@dataclass
+class Preconditioning:
+    power: float  # preconditioning power, e.g. cabin heating [W]
+    steps: int  # number of contiguous time steps to run for
+    t_start: int  # first time step the preconditioning may run in
+    t_end: int  # time step the preconditioning must be finished by, e.g. departure
+
+
+@dataclass
+class Departure:
+    t: int  # time step the vehicle may depart after
+    probability: float  # probability of departing after this time step [0..1]
+    s_goal: float  # goal state of charge at this departure [Wh]
+
+
+@dataclass
+class Calibration:
+    interval: float  # maximum time between full charges, e.g. 14 days [s]
+    elapsed: float  # time since the last full charge at the start of the horizon [s]
 
 
 @dataclass
@@ -36,15 +131,81 @@ class BatteryConfig:
     p_demand: Optional[List[float]] = None  # Minimum charge demand (Wh)
     s_goal: Optional[List[float]] = None  # Goal state of charge (Wh)
     c_priority: int = 0
+    c_eta_curve: Optional[List[EfficiencyPoint]] = None  # charging efficiency vs. power
+    d_eta_curve: Optional[List[EfficiencyPoint]] = None  # discharging efficiency vs. power
+    enabled: bool = True  # disabled batteries are excluded from the optimization
+    c_eta_series: Optional[List[float]] = None  # charging efficiency per time step, e.g. heat pump COP
+    id: Optional[str] = None  # stable identifier returned with the result
+    name: Optional[str] = None  # human-readable name returned with the result
+    s_initial_stddev: float = 0  # standard deviation of the measured initial state of charge [Wh]
+    available: Optional[List[bool]] = None  # availability per time step, unavailable batteries have zero power
+    preconditioning: Optional[Preconditioning] = None  # vehicle preconditioning load before departure
+    departures: Optional[List[Departure]] = None  # possible departures, the expected shortfall is penalized
+    calibration: Optional[Calibration] = None  # periodic full charge required by the BMS
+    goal_priority: int = 0  # goals of higher priority are met first when goals compete
+
+
+@dataclass
+class ChpConfig:
+    p_el_max: float  # maximum electric power of the combined heat and power unit [W]
+    eta_el: float  # electric efficiency, electricity per fuel energy [0..1]
+    eta_th: float  # thermal efficiency, heat per fuel energy [0..1]
+    p_el_min: float = 0  # minimum electric power while running [W]
+    p_boiler_max: float = 0  # maximum heat output of a gas boiler [W]
+    eta_boiler: float = 0.9  # boiler efficiency, heat per fuel energy [0..1]
+    p_heat_el_max: float = 0  # maximum electric power of electric heating, e.g. a heat pump [W]
+    cop: float = 1  # heat per electricity of electric heating, e.g. heat pump COP
 
 
 @dataclass
 class TimeSeriesData:
     dt: List[int]  # time step length [s]
-    gt: List[float]  # Required total energy [Wh]
+    gt: List[float]  # Required total energy [Wh], negative for uncontrolled generation exceeding the demand
     ft: List[float]  # Forecasted production [Wh]
     p_N: List[float]  # Import prices [currency unit/Wh]
     p_E: List[float]  # Export prices [currency unit/Wh]
+    em_N: Optional[List[float]] = None  # Emissions of grid import [gCO2/Wh]
+    r_curt: Optional[List[float]] = None  # Probability of export curtailment by the grid operator [0..1]
+    w_E: Optional[List[float]] = None  # Export preference weight, bonus per Wh exported [currency unit/Wh]
+    n_commit: Optional[List[float]] = None  # Committed net grid import, negative for export [Wh]
+    p_max_imp: Optional[List[float]] = None  # Maximum grid import power, e.g. in dimming windows [W]
+    p_G: Optional[List[float]] = None  # Gas prices per fuel energy [currency unit/Wh]
+    q_H: Optional[List[float]] = None  # Heat demand [Wh]
+
+
+def grid_power_limits(grid: GridConfig, time_series: TimeSeriesData):
+    """
+    Grid import and export power limits at each time step [W], the lowest of the grid limit, the import
+    limit series and the grid connection capacity. None if unlimited.
+    """
+    def limits(scalar, series):
+        values = [[v for v in (scalar, series[t] if series is not None else None, grid.p_conn) if v is not None]
+                  for t in range(len(time_series.dt))]
+        return [min(v) for v in values] if values and all(values) else None
+
+    return limits(grid.p_max_imp, time_series.p_max_imp), limits(grid.p_max_exp, None)
+
+
+def goal_weights(batteries: List[BatteryConfig]) -> List[float]:
+    """
+    Goal penalty factor of each battery by its goal priority, so that goals of higher priority are met
+    first when goals compete for limited power. A Wh charged into a battery reduces the shortfall at each
+    of its goal time steps and departures, so a level is weighted by GOAL_PRIORITY_FACTOR times the
+    largest sum of weighted penalties a Wh reduces at the levels below. Goals at a single time step weigh
+    1, 10, 100 and so on by level.
+    """
+    def terms(bat: BatteryConfig) -> float:
+        n = sum(1 for g in bat.s_goal if g > 0) if bat.s_goal is not None else 0
+        n += sum(dep.probability for dep in bat.departures or [])
+        return max(n, 1)
+
+    weight = {}
+    below = 0.
+    for level in sorted(set(bat.goal_priority for bat in batteries)):
+        weight[level] = GOAL_PRIORITY_FACTOR * below if below > 0 else 1.
+        below = max([below] + [weight[level] * terms(bat) for bat in batteries if bat.goal_priority == level])
+
+    return [weight[bat.goal_priority] for bat in batteries]
 
 
 class Optimizer:
@@ -54,20 +215,69 @@ class Optimizer:
     """
 
     def __init__(self, strategy: OptimizationStrategy, grid: GridConfig, batteries: List[BatteryConfig], time_series: TimeSeriesData,
-                 eta_c: float = 0.95, eta_d: float = 0.95, M: float = 1e6, optimizer_settings: OptimizerSettings | None = None):
+                 eta_c: float = 0.95, eta_d: float = 0.95, M: float = 1e6, optimizer_settings: OptimizerSettings | None = None,
+                 cost_budget: float | None = None, max_latency_ms: float | None = None, simplify_on_timeout: bool = False,
+                 time_limit: float | None = None, mip_gap: float | None = None, require_optimal: bool = False,
+                 warm_start: dict | None = None, penalties: List[Penalty] | None = None,
+                 weights: ObjectiveWeights | None = None, chp: ChpConfig | None = None,
+                 progress: Callable[[dict], None] | None = None, started: float | None = None):
         """
         Optimizer Constructor
         """
 
         self.settings = optimizer_settings or OptimizerSettings()
 
+        # the energy and emissions objectives replace the tariff by grid import energy or its
+        # emissions. Export is not credited and the final state of charge is valued at the import
+        # it avoids at least.
+        self.objective_unit = OBJECTIVE_UNITS[strategy.objective]
+        if strategy.objective != 'cost':
+            if strategy.objective == 'energy':
+                p_N = [1.] * len(time_series.gt)
+            else:
+                p_N = list(time_series.em_N)
+            time_series = replace(time_series, p_N=p_N, p_E=[0.] * len(time_series.gt))
+            batteries = [replace(bat, p_a=np.min(p_N) * eta_d) for bat in batteries]
+
         self.strategy = strategy
         self.grid = grid
-        self.batteries = batteries
+        # disabled batteries are not modelled, the result keeps the indices of all batteries
+        self.all_batteries = batteries
+        self.batteries = [bat for bat in batteries if bat.enabled]
         self.time_series = time_series
         self.eta_c = eta_c
         self.eta_d = eta_d
         self.M = M
+        # maximum acceptable net cost over the horizon. If given, the optimizer minimizes battery
+        # wear subject to this budget instead of minimizing cost.
+        self.cost_budget = cost_budget
+        # if given, an approximate solution based on the LP relaxation is returned within this
+        # latency instead of solving the MILP
+        self.max_latency_ms = max_latency_ms
+        # monotonic time the latency is measured from, e.g. the receipt of the request. Defaults
+        # to the start of the solve.
+        self.started = started
+        # if the MILP solve hits the time limit without a solution, retry with the approximation
+        # of the bounded latency mode within the rest of the time limit
+        self.simplify_on_timeout = simplify_on_timeout
+        # time limit of the solve [s], capped by the time limit of the server
+        limits = [v for v in (time_limit, self.settings.time_limit) if v is not None]
+        self.time_limit = min(limits) if limits else None
+        # relative optimality gap at which the solver stops
+        self.mip_gap = mip_gap
+        # if set, a solution found when the time limit is hit is not returned
+        self.require_optimal = require_optimal
+        # previous schedule as starting solution of the solver, batteries by index of all batteries
+        self.warm_start = warm_start or None
+        # custom linear penalty terms of the request [currency unit]
+        self.penalties = penalties or []
+        # weights of the economic benefit against battery wear, grid exchange and peak import
+        self.weights = weights or ObjectiveWeights()
+        # combined heat and power unit with gas boiler and electric heating covering the heat demand
+        self.chp = chp
+        # callback receiving the progress of the solve, see SolverProgress
+        self.progress = progress
+        self._progress = None
         # number of time steps
         self.T = len(time_series.gt)
         # time step range
@@ -81,17 +291,35 @@ class Optimizer:
         self.min_import_price = np.min(self.time_series.p_N)
         self.max_import_price = np.max(self.time_series.p_N)
 
-        # scaling for penalty parameters. Make sure goal_penalty is always positive
-        self.prc_e_goal_pen = np.min([self.max_import_price, 0.1e-3]) * 10e1
-        self.prc_p_goal_pen = np.min([self.max_import_price, 0.1e-3]) * np.max(self.time_series.dt) / 3600 * 10e1
-        self.prc_soc_exc_pen = np.min([self.max_import_price, 0.1e-3]) * 10e2
+        # price per Wh at which the weighted battery wear, grid exchange and peak import are valued,
+        # so that their weights are comparable to the weight of the cost
+        self.prc_e_weighted = np.mean(np.abs(self.time_series.p_N)) or 0.1e-3
+
+        # scaling for penalty parameters: multiples of the largest import price of the objective, so
+        # that penalties exceed the import cost of the cost, energy and emissions objectives alike.
+        # Penalties are always positive.
+        self.prc_e_pen = float(np.max(np.abs(self.time_series.p_N))) or 0.1e-3
+        self.prc_e_goal_pen = self.prc_e_pen * 10e1
+        self.prc_p_goal_pen = self.prc_e_pen * np.max(self.time_series.dt) / 3600 * 10e1
+        self.prc_soc_exc_pen = self.prc_e_pen * 10e2
+
+        # goal penalty factor per battery by its goal priority
+        self.goal_weight = goal_weights(self.batteries)
 
         # penalty for exceeding grid import limit. Result shall not become infeasible but report the violation
         # with helpful information
-        self.prc_e_grid_imp_pen = np.min([self.max_import_price, 0.1e-3]) * 10e1
+        self.prc_e_grid_imp_pen = self.prc_e_pen * 10e1
         # penalty for exceeding the grid export limit. Result shall not become infeasible but report the 'lost'
         # solar power
-        self.prc_e_grid_exp_pen = np.min([self.max_import_price, 0.1e-3]) * 10e1
+        self.prc_e_grid_exp_pen = self.prc_e_pen * 10e1
+
+        # goal seeking mode: price per Wh of battery throughput as measure for battery wear, and
+        # penalty per currency unit exceeding the cost budget
+        self.prc_e_wear = self.prc_e_pen * 10e-3
+        self.prc_budget_pen = 10e2
+
+        # grid import and export power limits at each time step, None if unlimited [W]
+        self.p_max_imp, self.p_max_exp = grid_power_limits(self.grid, self.time_series)
 
         # if there is a demand rate given in the input, the grid import limit will be interpreted as the
         # threshold beyond wich the demand rate is to be applied. Compute a demand rate flag for use in the
@@ -100,6 +328,39 @@ class Optimizer:
         if self.grid.p_max_imp is not None and self.grid.prc_p_exc_imp is not None:
             self.is_grid_demand_rate_active = True
 
+        # if an import allowance with a surcharge beyond it is given, the tariff is tiered. The remaining
+        # allowance is what is left in the current billing period after the energy imported to date.
+        self.is_grid_tier_active = False
+        if self.grid.e_imp_tier is not None and self.grid.prc_e_exc_tier is not None:
+            self.is_grid_tier_active = True
+            self.e_imp_tier_remaining = max(0, self.grid.e_imp_tier - self.grid.e_imp_to_date)
+            # time steps of the current billing period. Import after the reset counts against
+            # the full allowance of the next billing period.
+            self.t_tier_reset = self.T if self.grid.t_tier_reset is None else min(self.grid.t_tier_reset, self.T)
+
+        # if remunerated export is capped per period, export beyond the remaining cap earns nothing
+        self.is_grid_export_cap_active = False
+        if self.grid.e_exp_cap is not None:
+            self.is_grid_export_cap_active = True
+            self.e_exp_cap_remaining = max(0, self.grid.e_exp_cap - self.grid.e_exp_to_date)
+            self.t_cap_reset = self.T if self.grid.t_cap_reset is None else min(self.grid.t_cap_reset, self.T)
+
+        # if a net import price is given, import and export offset each other within the netting period
+        # before pricing. Import and export after the reset are netted in the next period.
+        self.is_grid_net_metering_active = self.grid.prc_e_net_imp is not None
+        if self.is_grid_net_metering_active:
+            self.t_net_reset = self.T if self.grid.t_net_reset is None else min(self.grid.t_net_reset, self.T)
+
+        # if a peak price curve is given, the peak import power of the horizon is charged with increasing
+        # marginal prices, e.g. capacity based tariff components
+        self.is_grid_peak_price_active = bool(self.grid.prc_p_peak)
+
+        # if a committed grid exchange schedule with a deviation price is given, deviations from the
+        # schedule are charged, e.g. as imbalance cost of a flexibility contract
+        self.is_grid_commitment_active = False
+        if self.time_series.n_commit is not None and self.grid.prc_e_dev is not None:
+            self.is_grid_commitment_active = True
+
     def create_model(self):
         """
         Create and initialize the MILP model
@@ -112,6 +373,8 @@ class Optimizer:
         self._setup_target_function()
         self._add_energy_balance_constraints()
         self._add_battery_constraints()
+        self._add_cost_budget_constraints()
+        self._add_spike_guard_constraints()
 
     def _setup_variables(self):
         """
@@ -122,7 +385,7 @@ class Optimizer:
         self.variables['c'] = {}
         for i, bat in enumerate(self.batteries):
             self.variables['c'][i] = [
-                pulp.LpVariable(f"c_{i}_{t}", lowBound=0, upBound=bat.c_max * self.time_series.dt[t] / 3600.)
+                pulp.LpVariable(f"c_{i}_{t}", lowBound=0, upBound=bat.c_max * self.time_series.dt[t] / 3600. if self._available(bat, t) else 0)
                 for t in self.time_steps
             ]
 
@@ -130,7 +393,7 @@ class Optimizer:
         self.variables['d'] = {}
         for i, bat in enumerate(self.batteries):
             self.variables['d'][i] = [
-                pulp.LpVariable(f"d_{i}_{t}", lowBound=0, upBound=bat.d_max * self.time_series.dt[t] / 3600.)
+                pulp.LpVariable(f"d_{i}_{t}", lowBound=0, upBound=bat.d_max * self.time_series.dt[t] / 3600. if self._available(bat, t) else 0)
                 for t in self.time_steps
             ]
 
@@ -154,6 +417,17 @@ class Optimizer:
 
         # penalty variable for not being able to charge with the required power
         self.variables['p_demand_pen'] = [[None for t in self.time_steps] for i in range(len(self.batteries))]
+
+        # shortfall of the goal of each possible departure [Wh]
+        self.variables['departure_pen'] = {
+            i: [pulp.LpVariable(f"departure_pen_{i}_{k}", lowBound=0) for k in range(len(bat.departures))]
+            for i, bat in enumerate(self.batteries) if bat.departures
+        }
+        # shortfall of the full charge of due calibrations [Wh]
+        self.variables['calibration_pen'] = {
+            i: pulp.LpVariable(f"calibration_pen_{i}", lowBound=0)
+            for i in range(len(self.batteries)) if self._calibration_window(i)
+        }
         # binary variable to allow one out of two alternative constraints
         self.variables['z_p_demand'] = [[None for t in self.time_steps] for i in range(len(self.batteries))]
         for i, bat in enumerate(self.batteries):
@@ -172,22 +446,93 @@ class Optimizer:
 
         # penalty variables for exceeding grid power limits (W)
         # for grid import
-        if self.grid.p_max_imp is not None:
+        if self.p_max_imp is not None:
             self.variables['e_imp_lim_exc'] = [pulp.LpVariable(f"p_imp_pen_{t}", lowBound=0) for t in self.time_steps]
             # binary variable to allow limit exceeding only if the regular import actually hits the limit
             # this is required to avoid that limit exceeds are shifted to other time steps
             self.variables['z_imp_lim'] = [pulp.LpVariable(f"z_imp_lim_{t}", cat='Binary') for t in self.time_steps]
 
         # for grid export
-        if self.grid.p_max_exp is not None:
+        if self.p_max_exp is not None:
             self.variables['e_exp_lim_exc'] = [pulp.LpVariable(f"e_exp_lim_exc_{t}", lowBound=0) for t in self.time_steps]
             # binary variable to allow limit exceeding only if the regular export actually hits the limit
             self.variables['z_exp_lim'] = [pulp.LpVariable(f"z_exp_lim_{t}", cat='Binary') for t in self.time_steps]
 
         # for demand rate calculation, we need to track the actual maximum import power
-        # within the time horizon (W)
+        # within the time horizon (W). The excess reached so far in the billing period is
+        # already paid for.
         if self.is_grid_demand_rate_active:
-            self.variables['p_max_imp_exc'] = pulp.LpVariable("p_max_imp_exc", lowBound=0)
+            self.variables['p_max_imp_exc'] = pulp.LpVariable("p_max_imp_exc", lowBound=self._p_max_imp_exc_to_date())
+
+        # goal seeking mode: cost exceeding the budget [currency unit]
+        if self.cost_budget is not None:
+            self.variables['cost_budget_exc'] = pulp.LpVariable("cost_budget_exc", lowBound=0)
+
+        # net import and net export of the current and the next netting period (Wh)
+        if self.is_grid_net_metering_active:
+            self.variables['e_net_imp'] = [pulp.LpVariable(f"e_net_imp_{k}", lowBound=0) for k in range(2)]
+            self.variables['e_net_exp'] = [pulp.LpVariable(f"e_net_exp_{k}", lowBound=0) for k in range(2)]
+
+        # for tiered tariffs, we need to track the import energy beyond the remaining allowance (Wh)
+        if self.is_grid_tier_active:
+            self.variables['e_imp_tier_exc'] = pulp.LpVariable("e_imp_tier_exc", lowBound=0)
+            self.variables['e_imp_tier_exc_next'] = pulp.LpVariable("e_imp_tier_exc_next", lowBound=0)
+
+        # for capped export, we need to track the remunerated part of the export (Wh)
+        if self.is_grid_export_cap_active:
+            self.variables['e_rem'] = [pulp.LpVariable(f"e_rem_{t}", lowBound=0) for t in self.time_steps]
+
+        # penalty variables for falling short of the price spike reserve (Wh)
+        self.variables['spike_guard_pen'] = [pulp.LpVariable(f"spike_guard_pen_{k}", lowBound=0)
+                                             for k in range(len(self._spike_guard_intervals()))]
+
+        # for peak prices, we need to track the peak import power and its part above each curve point (W)
+        if self.is_grid_peak_price_active:
+            self.variables['p_peak'] = pulp.LpVariable("p_peak", lowBound=self.grid.p_peak_to_date)
+            self.variables['p_peak_seg'] = [pulp.LpVariable(f"p_peak_seg_{k}", lowBound=0)
+                                            for k in range(len(self.grid.prc_p_peak))]
+
+        # for peak shaving, we need to track the peak import power of the horizon (W)
+        if self.weights.peak_shaving > 0:
+            self.variables['p_imp_peak'] = pulp.LpVariable("p_imp_peak", lowBound=0)
+
+        # for committed schedules, we need to track the deviation above and below the commitment (Wh)
+        if self.is_grid_commitment_active:
+            self.variables['e_dev_pos'] = [pulp.LpVariable(f"e_dev_pos_{t}", lowBound=0) for t in self.time_steps]
+            self.variables['e_dev_neg'] = [pulp.LpVariable(f"e_dev_neg_{t}", lowBound=0) for t in self.time_steps]
+
+        # combined heat and power: fuel of the unit and heat of the boiler and electric heating (Wh). The
+        # binary switches the unit on if it has a minimum power.
+        if self.chp is not None:
+            self.variables['chp_f'] = [pulp.LpVariable(f"chp_f_{t}", lowBound=0,
+                                                       upBound=self.chp.p_el_max * self.time_series.dt[t] / 3600 / self.chp.eta_el)
+                                       for t in self.time_steps]
+            self.variables['chp_b'] = [pulp.LpVariable(f"chp_b_{t}", lowBound=0,
+                                                       upBound=self.chp.p_boiler_max * self.time_series.dt[t] / 3600)
+                                       for t in self.time_steps]
+            self.variables['chp_h'] = [pulp.LpVariable(f"chp_h_{t}", lowBound=0,
+                                                       upBound=self.chp.p_heat_el_max * self.time_series.dt[t] / 3600)
+                                       for t in self.time_steps]
+            if self.chp.p_el_min > 0:
+                self.variables['z_chp'] = [pulp.LpVariable(f"z_chp_{t}", cat='Binary') for t in self.time_steps]
+
+        # Binary variables selecting the first time step of the contiguous preconditioning run, one
+        # per feasible start within the window
+        self.variables['z_pre'] = {}
+        for i, bat in enumerate(self.batteries):
+            pre = bat.preconditioning
+            if pre is not None:
+                self.variables['z_pre'][i] = {
+                    tau: pulp.LpVariable(f"z_pre_{i}_{tau}", cat='Binary')
+                    for tau in range(pre.t_start, min(pre.t_end, self.T) - pre.steps + 1)
+                }
+
+        # Binary variables selecting the time step at the end of which a due calibration reaches the
+        # full state of charge
+        self.variables['z_cal'] = {
+            i: {tau: pulp.LpVariable(f"z_cal_{i}_{tau}", cat='Binary') for tau in self._calibration_window(i)}
+            for i in self.variables['calibration_pen']
+        }
 
         # Binary variable: power flow direction to / from grid variables
         # these variables
@@ -216,6 +561,176 @@ class Optimizer:
                 for t in self.time_steps
             ]
 
+        # Convex combination weights and segment selection binaries for power dependent
+        # charging and discharging efficiency curves. Only batteries with a curve get variables.
+        for key in ['c', 'd']:
+            self.variables[f'{key}_eta_w'] = {}
+            self.variables[f'{key}_eta_z'] = {}
+            for i, bat in enumerate(self.batteries):
+                curve = self._efficiency_curve(bat, key)
+                if curve is None:
+                    continue
+                self.variables[f'{key}_eta_w'][i] = [
+                    [pulp.LpVariable(f"{key}_eta_w_{i}_{t}_{k}", lowBound=0, upBound=1) for k in range(len(curve))]
+                    for t in self.time_steps
+                ]
+                self.variables[f'{key}_eta_z'][i] = [
+                    [pulp.LpVariable(f"{key}_eta_z_{i}_{t}_{k}", cat='Binary') for k in range(len(curve) - 1)]
+                    for t in self.time_steps
+                ]
+
+    @staticmethod
+    def _available(bat: BatteryConfig, t: int) -> bool:
+        """
+        Returns whether battery bat can charge or discharge in time step t
+        """
+        return bat.available is None or bat.available[t]
+
+    @staticmethod
+    def _efficiency_curve(bat: BatteryConfig, key: str) -> Optional[List[EfficiencyPoint]]:
+        """
+        Returns the efficiency curve sorted by power for charging ('c') or discharging ('d'),
+        extended by a zero power point if required. Returns None if no curve is given.
+        """
+        curve = bat.c_eta_curve if key == 'c' else bat.d_eta_curve
+        if not curve:
+            return None
+        curve = sorted(curve, key=lambda p: p.power)
+        if curve[0].power > 0:
+            curve = [EfficiencyPoint(power=0, eta=curve[0].eta)] + curve
+        return curve
+
+    def _charge_to_soc(self, i: int, t: int):
+        """
+        Energy added to the state of charge of battery i in time step t [Wh]
+        """
+        curve = self._efficiency_curve(self.batteries[i], 'c')
+        if curve is None:
+            bat = self.batteries[i]
+            eta_c = bat.c_eta_series[t] if bat.c_eta_series is not None else self.eta_c
+            return eta_c * self.variables['c'][i][t]
+        return pulp.lpSum(w * p.power * p.eta * self.time_series.dt[t] / 3600.
+                          for w, p in zip(self.variables['c_eta_w'][i][t], curve))
+
+    def _discharge_from_soc(self, i: int, t: int):
+        """
+        Energy removed from the state of charge of battery i in time step t [Wh]
+        """
+        curve = self._efficiency_curve(self.batteries[i], 'd')
+        if curve is None:
+            return (1 / self.eta_d) * self.variables['d'][i][t]
+        return pulp.lpSum(w * p.power / p.eta * self.time_series.dt[t] / 3600.
+                          for w, p in zip(self.variables['d_eta_w'][i][t], curve))
+
+    def _p_max_imp_exc_to_date(self) -> float:
+        """
+        Import power beyond the demand rate threshold reached so far in the current billing
+        period [W]. Only exceeding it is charged.
+        """
+        return max(0., self.grid.p_peak_to_date - self.grid.p_max_imp)
+
+    def _calibration_window(self, i: int) -> List[int]:
+        """
+        Time steps a calibration of battery i may complete in: the steps ending before it is due, or
+        all steps if it is overdue. Empty without calibration or if it is due beyond the horizon,
+        later horizons plan it.
+        """
+        cal = self.batteries[i].calibration
+        if cal is None:
+            return []
+
+        due = cal.interval - cal.elapsed
+        end = np.cumsum(self.time_series.dt)
+        if self.T == 0 or due > end[-1]:
+            return []
+        return [t for t in self.time_steps if end[t] <= due] or list(self.time_steps)
+
+    def _calibration_step(self, i: int) -> int | None:
+        """
+        Time step at the end of which the due calibration of battery i is planned, None if none is due
+        """
+        z = self.variables['z_cal'].get(i)
+        if not z:
+            return None
+        return max(z, key=lambda tau: pulp.value(z[tau]))
+
+    def _goal_shortfalls(self, i: int) -> Optional[List[Dict]]:
+        """
+        Charge goals of battery i scaled back by the optimizer with the reason:
+        - capacity: the goal exceeds s_max
+        - charge_power: the battery charged at maximum power whenever available
+        - grid_limit: the grid import limit was reached while the battery could charge more
+        - generation: the battery only charges from surplus generation, which was not sufficient
+        - competing_goals: other batteries were charged instead, e.g. for goals of higher priority
+        - other: none of the above, e.g. conflicting constraints
+        Competitors are the indices of the other batteries charging while this battery could
+        charge more, by descending goal priority. None if the battery has no charge goals.
+        """
+        bat = self.batteries[i]
+        if bat.s_goal is None:
+            return None
+
+        enabled = [k for k, b in enumerate(self.all_batteries) if b.enabled]
+        c = [[pulp.value(var) for var in self.variables['c'][j]] for j in range(len(self.batteries))]
+
+        shortfalls = []
+        for t in range(1, self.T):
+            pen = self.variables['s_goal_pen'][i][t]
+            if pen is None or pulp.value(pen) < GOAL_SHORTFALL_TOLERANCE:
+                continue
+
+            # time steps up to the goal in which the battery could have charged more
+            free = [tau for tau in range(t + 1) if self._available(bat, tau)
+                    and c[i][tau] < bat.c_max * self.time_series.dt[tau] / 3600. - GOAL_SHORTFALL_TOLERANCE]
+
+            competitors = sorted((j for j in range(len(self.batteries))
+                                  if j != i and any(c[j][tau] > GOAL_SHORTFALL_TOLERANCE for tau in free)),
+                                 key=lambda j: -self.batteries[j].goal_priority)
+
+            if bat.s_goal[t] > bat.s_max + GOAL_SHORTFALL_TOLERANCE:
+                reason = 'capacity'
+            elif not free:
+                reason = 'charge_power'
+            elif self.p_max_imp is not None and any(
+                    pulp.value(self.variables['n'][tau])
+                    >= self.p_max_imp[tau] * self.time_series.dt[tau] / 3600 - GOAL_SHORTFALL_TOLERANCE for tau in free):
+                reason = 'grid_limit'
+            elif not bat.charge_from_grid:
+                reason = 'generation'
+            elif competitors:
+                reason = 'competing_goals'
+            else:
+                reason = 'other'
+
+            shortfalls.append({
+                't': t,
+                'goal': bat.s_goal[t],
+                'shortfall': pulp.value(pen),
+                'reason': reason,
+                'competitors': [enabled[j] for j in competitors],
+            })
+
+        return shortfalls
+
+    def _preconditioning(self, i: int, t: int):
+        """
+        Preconditioning energy of battery i in time step t [Wh]. While the vehicle is available, it is
+        supplied by the charger, otherwise it is drawn from the vehicle battery.
+        """
+        pre = self.batteries[i].preconditioning
+        if pre is None:
+            return 0
+        return pre.power * self.time_series.dt[t] / 3600. * pulp.lpSum(
+            z for tau, z in self.variables['z_pre'][i].items() if tau <= t < tau + pre.steps)
+
+    def _preconditioning_from_soc(self, i: int, t: int):
+        """
+        Preconditioning energy drawn from the state of charge of battery i in time step t [Wh]
+        """
+        if self._available(self.batteries[i], t):
+            return 0
+        return self._preconditioning(i, t)
+
     def _setup_target_function(self):
         """
         Gather all target function contributions and instantiate the objective
@@ -233,7 +748,7 @@ class Optimizer:
             # for energy cost. If only an import limit is given, there should never be power
             # import beyond p_max_imp, however, if the limit gets violated, we account for
             # the energy cost as well to stay consistent.
-            if self.grid.p_max_imp is not None:
+            if self.p_max_imp is not None:
                 objective -= (
                     # grid import up to the demand rate threshold
                     self.variables['n'][t]
@@ -244,18 +759,69 @@ class Optimizer:
                 # standard case
                 objective -= self.variables['n'][t] * self.time_series.p_N[t]
 
-        # Grid export revenue [currency unit]
+        # Grid export revenue [currency unit]. If export may be curtailed by the grid operator,
+        # only the expected revenue is considered, favouring storage over export in risky intervals.
         for t in self.time_steps:
-            objective += self.variables['e'][t] * self.time_series.p_E[t]
+            r_curt = self.time_series.r_curt[t] if self.time_series.r_curt is not None else 0
+            objective += self._e_exp_remunerated(t) * self.time_series.p_E[t] * (1 - r_curt)
+
+        # soft export preference: a bonus for export in preferred intervals biases the dispatch
+        # without forcing it. The bonus is not part of the reported objective value.
+        if self.time_series.w_E is not None:
+            for t in self.time_steps:
+                objective += self.variables['e'][t] * self.time_series.w_E[t]
 
         # Final state of charge value [currency unit]
         for i, bat in enumerate(self.batteries):
             objective += self.variables['s'][i][-1] * bat.p_a
 
+        # fuel cost of the combined heat and power unit and the boiler [currency unit]
+        if self.chp is not None:
+            objective += - self._fuel_cost()
+
         # charge for import power demand rate. The demand rate is applied to the maximum
         # power draw beyond the threshold within the time horizon.
         if self.is_grid_demand_rate_active:
-            objective += - self.grid.prc_p_exc_imp * self.variables['p_max_imp_exc']
+            objective += - self.grid.prc_p_exc_imp * (self.variables['p_max_imp_exc'] - self._p_max_imp_exc_to_date())
+
+        # surcharge for import energy beyond the tier allowance of the billing period
+        if self.is_grid_tier_active:
+            objective += - self.grid.prc_e_exc_tier * (self.variables['e_imp_tier_exc'] + self.variables['e_imp_tier_exc_next'])
+
+        # charge for the net import, credit for the net export of the netting periods
+        if self.is_grid_net_metering_active:
+            objective += - self._net_metering_charge()
+
+        # charge for the peak import power
+        if self.is_grid_peak_price_active:
+            objective += - self._peak_charge()
+
+        # charge for deviating from the committed grid exchange in either direction
+        if self.is_grid_commitment_active:
+            objective += - self.grid.prc_e_dev * pulp.lpSum(self.variables['e_dev_pos'][t] + self.variables['e_dev_neg'][t]
+                                                            for t in self.time_steps)
+
+        # objective weights: the economic benefit is traded against battery wear, grid exchange and
+        # peak import. The weighted terms are not part of the reported objective value.
+        if self.weights != ObjectiveWeights():
+            objective = self.weights.cost * objective - self._weighted_terms()
+
+        # goal seeking mode: the cost is limited by the budget constraint. Instead of maximizing the
+        # economic benefit, battery wear is minimized and exceeding the budget is penalized.
+        if self.cost_budget is not None:
+            objective = 0
+            for i, bat in enumerate(self.batteries):
+                for t in self.time_steps:
+                    objective += - self.prc_e_wear * (self.variables['c'][i][t] + self.variables['d'][i][t])
+            objective += - self.prc_budget_pen * self.variables['cost_budget_exc']
+
+        # Penalties for falling short of the price spike reserve
+        for pen in self.variables['spike_guard_pen']:
+            objective += - self.prc_e_goal_pen * pen
+
+        # custom penalty terms of the request. They are not part of the reported objective value.
+        for penalty in self.penalties:
+            objective += - self._penalty(penalty)
 
         ############################################################################
         # Penalties for exceeding battery SOC limits at start
@@ -271,7 +837,15 @@ class Optimizer:
                 for t in self.time_steps:
                     if self.batteries[i].s_goal[t] > 0:
                         # negative target function contribution in a maximizing optimization
-                        objective += - self.prc_e_goal_pen * self.variables['s_goal_pen'][i][t]
+                        objective += - self.prc_e_goal_pen * self.goal_weight[i] * self.variables['s_goal_pen'][i][t]
+            # expected shortfall over the possible departures
+            if bat.departures:
+                for k, dep in enumerate(bat.departures):
+                    objective += - self.prc_e_goal_pen * self.goal_weight[i] * dep.probability \
+                        * self.variables['departure_pen'][i][k]
+            # shortfall of the full charge of a due calibration
+            if i in self.variables['calibration_pen']:
+                objective += - self.prc_e_goal_pen * self.variables['calibration_pen'][i]
             # unmet charging demand due to battery reaching maximum SOC with incentive to do charging early
             if bat.p_demand is not None:
                 for t in self.time_steps:
@@ -283,12 +857,12 @@ class Optimizer:
         for t in self.time_steps:
 
             # penalty for exceeding the given import limit
-            if self.grid.p_max_imp is not None and not self.is_grid_demand_rate_active:
+            if self.p_max_imp is not None and not self.is_grid_demand_rate_active:
                 # negative target function contribution in a maximizing optimization
                 objective += - self.prc_e_grid_imp_pen * self.variables['e_imp_lim_exc'][t]
 
             # penalty for exceeding the grid export limit
-            if self.grid.p_max_exp is not None:
+            if self.p_max_exp is not None:
                 # negative target function contribution in a maximizing optimization
                 # decrease penalty slightly over time to push limit exceeding to late times
                 objective += - self.prc_e_grid_exp_pen * (1.0 - t * 1e-5) * self.variables['e_exp_lim_exc'][t]
@@ -314,11 +888,16 @@ class Optimizer:
                 for t in self.time_steps:
                     objective += - self.variables['n'][t] * self.min_import_price * 5e-6 * (self.T - t)
 
-        # charging and discharging priorities
+        # charging and discharging priorities. Priorities are mapped to their rank among the distinct
+        # priority levels, allowing an explicit order of any number of batteries while keeping the gap
+        # between adjacent levels at the size of the gap between priority 0 and 1. The lowest level is
+        # not penalized.
+        prio_levels = sorted(set(bat.c_priority for bat in self.batteries))
         for i, bat in enumerate(self.batteries):
+            prio_weight = prio_levels.index(bat.c_priority)
             for t in self.time_steps:
-                objective += self.variables['c'][i][t] * self.min_import_price * 5e-5 * (self.T - t) * bat.c_priority
-                objective += self.variables['d'][i][t] * self.min_import_price * 5e-5 * (self.T - t) * bat.c_priority
+                objective += self.variables['c'][i][t] * self.min_import_price * 5e-5 * (self.T - t) * prio_weight
+                objective += self.variables['d'][i][t] * self.min_import_price * 5e-5 * (self.T - t) * prio_weight
 
         self.problem += objective
 
@@ -339,12 +918,15 @@ class Optimizer:
             for i, bat in enumerate(self.batteries):
                 battery_net_discharge += (- self.variables['c'][i][t]
                                           + self.variables['d'][i][t])
+                # preconditioning of available vehicles is an additional load
+                if self._available(bat, t):
+                    battery_net_discharge -= self._preconditioning(i, t)
 
             # grid import: if there is an import power limit, the power exceeding the limit
             # is going to the penalty variable. If a demand rate is active, it is applied
             # to power drawn beyond the p_max_imp threshold.
             e_grid_imp = self.variables['n'][t]
-            if self.grid.p_max_imp is not None:
+            if self.p_max_imp is not None:
                 if self.is_grid_demand_rate_active:
                     # demand rate calculation
                     e_grid_imp = self.variables['n'][t]+self.variables['e_imp_lim_exc'][t]
@@ -355,15 +937,28 @@ class Optimizer:
             # grid export: if there is a limit, the power exceeding the limit
             # is going to the penalty variable
             e_grid_exp = self.variables['e'][t]
-            if self.grid.p_max_exp is not None:
+            if self.p_max_exp is not None:
                 e_grid_exp = self.variables['e'][t]+self.variables['e_exp_lim_exc'][t]
 
             self.problem += (battery_net_discharge
                              + self.time_series.ft[t]
+                             + self._chp_net_generation(t)
                              + e_grid_imp
                              == e_grid_exp
                              + self.time_series.gt[t])
 
+        # heat balance: the heat demand is covered by the combined heat and power unit, the boiler and
+        # electric heating. Without heat storage, heat cannot be produced beyond the demand.
+        if self.chp is not None:
+            for t in self.time_steps:
+                self.problem += (self.chp.eta_th * self.variables['chp_f'][t] + self.variables['chp_b'][t]
+                                 + self.chp.cop * self.variables['chp_h'][t] == self.time_series.q_H[t])
+                if self.chp.p_el_min > 0:
+                    self.problem += (self.variables['chp_f'][t]
+                                     <= self.variables['chp_f'][t].upBound * self.variables['z_chp'][t])
+                    self.problem += (self.chp.eta_el * self.variables['chp_f'][t]
+                                     >= self.chp.p_el_min * self.time_series.dt[t] / 3600 * self.variables['z_chp'][t])
+
         # Constraints (4)-(5): Grid flow direction
         for t in self.time_steps:
             # Export constraint
@@ -372,29 +967,29 @@ class Optimizer:
             self.problem += self.variables['n'][t] <= self.M * (1 - self.variables['y'][t])
 
         # limit regular grid import power
-        if self.grid.p_max_imp is not None:
+        if self.p_max_imp is not None:
             if self.is_grid_demand_rate_active:
                 # limit the demand rate free portion of the power
                 for t in self.time_steps:
-                    self.problem += self.variables['n'][t] <= self.grid.p_max_imp * self.time_series.dt[t] / 3600
-                    self.problem += (self.grid.p_max_imp * self.time_series.dt[t] / 3600 - self.variables['n'][t]
+                    self.problem += self.variables['n'][t] <= self.p_max_imp[t] * self.time_series.dt[t] / 3600
+                    self.problem += (self.p_max_imp[t] * self.time_series.dt[t] / 3600 - self.variables['n'][t]
                                      <= self.M * self.variables['z_imp_lim'][t])
                     self.problem += (self.variables['e_imp_lim_exc'][t]
                                      <= self.M * (1 - self.variables['z_imp_lim'][t]))
             else:
                 # limit the actual import power
                 for t in self.time_steps:
-                    self.problem += self.variables['n'][t] <= self.grid.p_max_imp * self.time_series.dt[t] / 3600
-                    self.problem += (self.grid.p_max_imp * self.time_series.dt[t] / 3600 - self.variables['n'][t]
+                    self.problem += self.variables['n'][t] <= self.p_max_imp[t] * self.time_series.dt[t] / 3600
+                    self.problem += (self.p_max_imp[t] * self.time_series.dt[t] / 3600 - self.variables['n'][t]
                                      <= self.M * self.variables['z_imp_lim'][t])
                     self.problem += (self.variables['e_imp_lim_exc'][t]
                                      <= self.M * (1 - self.variables['z_imp_lim'][t]))
 
         # limit regular grid export power
-        if self.grid.p_max_exp is not None:
+        if self.p_max_exp is not None:
             for t in self.time_steps:
-                self.problem += self.variables['e'][t] <= self.grid.p_max_exp * self.time_series.dt[t] / 3600
-                self.problem += (self.grid.p_max_exp * self.time_series.dt[t] / 3600 - self.variables['e'][t]
+                self.problem += self.variables['e'][t] <= self.p_max_exp[t] * self.time_series.dt[t] / 3600
+                self.problem += (self.p_max_exp[t] * self.time_series.dt[t] / 3600 - self.variables['e'][t]
                                  <= self.M * self.variables['z_exp_lim'][t])
                 self.problem += (self.variables['e_exp_lim_exc'][t]
                                  <= self.M * (1 - self.variables['z_exp_lim'][t]))
@@ -406,6 +1001,118 @@ class Optimizer:
                 self.problem += self.variables['e_imp_lim_exc'][t] \
                     <= self.variables['p_max_imp_exc'] * self.time_series.dt[t] / 3600
 
+        # tiered tariff: import energy beyond the remaining allowance of the current billing period
+        # is subject to the surcharge. If the billing period ends within the horizon, import after
+        # the reset is checked against the full allowance of the next billing period.
+        if self.is_grid_tier_active:
+            e_grid_imp_current = 0
+            e_grid_imp_next = 0
+            for t in self.time_steps:
+                e_grid_imp = self.variables['n'][t]
+                if self.p_max_imp is not None:
+                    e_grid_imp += self.variables['e_imp_lim_exc'][t]
+                if t < self.t_tier_reset:
+                    e_grid_imp_current += e_grid_imp
+                else:
+                    e_grid_imp_next += e_grid_imp
+            self.problem += self.variables['e_imp_tier_exc'] >= e_grid_imp_current - self.e_imp_tier_remaining
+            self.problem += self.variables['e_imp_tier_exc_next'] >= e_grid_imp_next - self.grid.e_imp_tier
+
+        # net metering: import and export offset each other within the netting period. The net import
+        # so far counts towards the current period. The net import and export variables split the
+        # balance, the price of the net import not being below the export credit keeps one of them zero.
+        if self.is_grid_net_metering_active:
+            balance = [self.grid.e_net_to_date, 0]
+            for t in self.time_steps:
+                e_grid_imp = self.variables['n'][t]
+                if self.p_max_imp is not None:
+                    e_grid_imp += self.variables['e_imp_lim_exc'][t]
+                balance[0 if t < self.t_net_reset else 1] += e_grid_imp - self.variables['e'][t]
+            for k in range(2):
+                self.problem += self.variables['e_net_imp'][k] - self.variables['e_net_exp'][k] == balance[k]
+
+        # export cap: remunerated export is part of the export and limited by the remaining cap of
+        # the current period. If the period ends within the horizon, export after the reset is
+        # limited by the full cap of the next period. Export at negative prices is billed in full
+        # and does not use up the cap.
+        if self.is_grid_export_cap_active:
+            capped = [t for t in self.time_steps if self._is_export_capped(t)]
+            for t in capped:
+                self.problem += self.variables['e_rem'][t] <= self.variables['e'][t]
+            self.problem += pulp.lpSum(self.variables['e_rem'][t] for t in capped if t < self.t_cap_reset) <= self.e_exp_cap_remaining
+            self.problem += pulp.lpSum(self.variables['e_rem'][t] for t in capped if t >= self.t_cap_reset) <= self.grid.e_exp_cap
+
+        # peak price: the peak is the maximum import power of all time steps. The price curve is convex,
+        # so the part of the peak above each curve point is minimal at the peak minus the point.
+        if self.is_grid_peak_price_active:
+            for t in self.time_steps:
+                e_grid_imp = self.variables['n'][t]
+                if self.p_max_imp is not None:
+                    e_grid_imp += self.variables['e_imp_lim_exc'][t]
+                self.problem += e_grid_imp * 3600 / self.time_series.dt[t] <= self.variables['p_peak']
+            for k, point in enumerate(self.grid.prc_p_peak):
+                self.problem += self.variables['p_peak_seg'][k] >= self.variables['p_peak'] - point.power
+
+        # peak shaving: the peak is the maximum import power of all time steps
+        if self.weights.peak_shaving > 0:
+            for t in self.time_steps:
+                e_grid_imp = self.variables['n'][t]
+                if self.p_max_imp is not None:
+                    e_grid_imp += self.variables['e_imp_lim_exc'][t]
+                self.problem += e_grid_imp * 3600 / self.time_series.dt[t] <= self.variables['p_imp_peak']
+
+        # committed schedule: the net grid import is the commitment plus the deviation. Energy not
+        # exported due to the export limit is not part of the net grid exchange.
+        if self.is_grid_commitment_active:
+            for t in self.time_steps:
+                e_grid_imp = self.variables['n'][t]
+                if self.p_max_imp is not None:
+                    e_grid_imp += self.variables['e_imp_lim_exc'][t]
+                self.problem += (e_grid_imp - self.variables['e'][t]
+                                 == self.time_series.n_commit[t] + self.variables['e_dev_pos'][t] - self.variables['e_dev_neg'][t])
+
+    def _peak_charge(self):
+        """
+        Charge for raising the peak import power above the peak reached so far [currency unit]. Each curve
+        point adds its price increase to the marginal price of the peak power above it.
+        """
+        charge = 0
+        previous = 0
+        for k, point in enumerate(self.grid.prc_p_peak):
+            increase = point.price - previous
+            charge += increase * (self.variables['p_peak_seg'][k] - max(0., self.grid.p_peak_to_date - point.power))
+            previous = point.price
+        return charge
+
+    def _is_export_capped(self, t: int) -> bool:
+        """
+        Whether the remuneration of grid export in time step t is capped. The cap limits payments
+        for export, export at negative prices is always billed.
+        """
+        return self.is_grid_export_cap_active and self.time_series.p_E[t] >= 0
+
+    def _e_exp_remunerated(self, t: int):
+        """
+        Remunerated grid export in time step t [Wh]. If the export is capped, the optimizer assigns
+        the remaining cap to the best paid intervals of the period.
+        """
+        if self._is_export_capped(t):
+            return self.variables['e_rem'][t]
+        return self.variables['e'][t]
+
+    def _soc_margin(self, i: int, t: int) -> float:
+        """
+        Margin tightening the SOC bounds of battery i at the end of time step t [Wh]. The margin
+        decreases linearly from the initial SOC uncertainty to zero over the uncertainty horizon
+        and never exceeds half of the SOC range.
+        """
+        bat = self.batteries[i]
+        if bat.s_initial_stddev <= 0:
+            return 0.
+        elapsed = sum(self.time_series.dt[:t + 1])
+        margin = S_INITIAL_STDDEV_FACTOR * bat.s_initial_stddev * max(0., 1 - elapsed / S_INITIAL_UNCERTAINTY_HORIZON)
+        return min(margin, max(0., bat.s_max - bat.s_min) / 2)
+
     def _add_battery_constraints(self):
         """
         Add constraints related to battery behavior to the model.
@@ -413,10 +1120,12 @@ class Optimizer:
         # constraint for the max and min SOC. If the battery starts with an initial SOC
         # greater than the maximum SOC or lesser than min SOC, maximum discharging is forced until the max.
         # SOC is reached or max. charing will be forced until min SOC is reached.
+        # Near-term bounds are tightened by the uncertainty of the initial SOC.
         for i, bat in enumerate(self.batteries):
             for t in range(0, self.T):
-                self.problem += (self.variables['s_max_pen'][i][t] >= self.variables['s'][i][t] - bat.s_max)
-                self.problem += (self.variables['s_min_pen'][i][t] >= bat.s_min - self.variables['s'][i][t])
+                margin = self._soc_margin(i, t)
+                self.problem += (self.variables['s_max_pen'][i][t] >= self.variables['s'][i][t] - (bat.s_max - margin))
+                self.problem += (self.variables['s_min_pen'][i][t] >= (bat.s_min + margin) - self.variables['s'][i][t])
 
         # Constraint (3): Battery dynamics
         for i, bat in enumerate(self.batteries):
@@ -424,15 +1133,41 @@ class Optimizer:
             if len(self.time_steps) > 0:
                 self.problem += (self.variables['s'][i][0]
                                  == bat.s_initial
-                                 + self.eta_c * self.variables['c'][i][0]
-                                 - (1 / self.eta_d) * self.variables['d'][i][0])
+                                 + self._charge_to_soc(i, 0)
+                                 - self._discharge_from_soc(i, 0)
+                                 - self._preconditioning_from_soc(i, 0))
 
             # State of charge evolution
             for t in range(1, self.T):
                 self.problem += (self.variables['s'][i][t]
                                  == self.variables['s'][i][t - 1]
-                                 + self.eta_c * self.variables['c'][i][t]
-                                 - (1 / self.eta_d) * self.variables['d'][i][t])
+                                 + self._charge_to_soc(i, t)
+                                 - self._discharge_from_soc(i, t)
+                                 - self._preconditioning_from_soc(i, t))
+
+            # Preconditioning runs exactly once within its window
+            if bat.preconditioning is not None:
+                self.problem += pulp.lpSum(self.variables['z_pre'][i].values()) == 1
+
+            # Power dependent efficiency: charge and discharge energy are a convex combination of
+            # two adjacent curve points (SOS2 condition expressed by segment selection binaries)
+            for key in ['c', 'd']:
+                curve = self._efficiency_curve(bat, key)
+                if curve is None:
+                    continue
+                K = len(curve)
+                for t in self.time_steps:
+                    w = self.variables[f'{key}_eta_w'][i][t]
+                    z = self.variables[f'{key}_eta_z'][i][t]
+                    self.problem += (self.variables[key][i][t]
+                                     == pulp.lpSum(w[k] * curve[k].power * self.time_series.dt[t] / 3600. for k in range(K)))
+                    self.problem += pulp.lpSum(w) == 1
+                    self.problem += pulp.lpSum(z) == 1
+                    # only weights adjacent to the selected segment may be non-zero
+                    self.problem += w[0] <= z[0]
+                    for k in range(1, K - 1):
+                        self.problem += w[k] <= z[k - 1] + z[k]
+                    self.problem += w[K - 1] <= z[K - 2]
 
             # Constraint (6): Battery SOC goal constraints (for t > 0)
             if bat.s_goal is not None:
@@ -441,6 +1176,21 @@ class Optimizer:
                         self.problem += (self.variables['s'][i][t]
                                          + self.variables['s_goal_pen'][i][t] >= bat.s_goal[t])
 
+            # Constraint: shortfall of the goals of possible departures
+            if bat.departures:
+                for k, dep in enumerate(bat.departures):
+                    self.problem += (self.variables['s'][i][dep.t]
+                                     + self.variables['departure_pen'][i][k] >= dep.s_goal)
+
+            # Constraint: a due calibration reaches s_max at the end of one of its time steps, the
+            # cheapest by the objective
+            if i in self.variables['z_cal']:
+                z = self.variables['z_cal'][i]
+                self.problem += pulp.lpSum(z.values()) == 1
+                for tau, z_tau in z.items():
+                    self.problem += (self.variables['s'][i][tau]
+                                     + self.variables['calibration_pen'][i] >= bat.s_max * z_tau)
+
             # Constraint: Minimum battery charge demand (for t > 0)
             if bat.p_demand is not None:
                 for t in self.time_steps:
@@ -488,27 +1238,366 @@ class Optimizer:
                 # Charge constraint
                 self.problem += self.variables['c'][i][t] <= self.M * (1 - self.variables['z_cd'][i][t])
 
+    def _net_metering_charge(self):
+        """
+        Charge for the net import minus the credit for the net export of the netting periods
+        [currency unit]. The charge of the net import to date is already incurred and not included.
+        """
+        charge = pulp.lpSum(self.grid.prc_e_net_imp * self.variables['e_net_imp'][k]
+                            - self.grid.prc_e_net_exp * self.variables['e_net_exp'][k] for k in range(2))
+        to_date = self.grid.e_net_to_date
+        return charge - (self.grid.prc_e_net_imp * max(0, to_date) - self.grid.prc_e_net_exp * max(0, -to_date))
+
+    def _chp_net_generation(self, t: int):
+        """
+        Electricity generated by the combined heat and power unit less the electricity of electric
+        heating in time step t [Wh]
+        """
+        if self.chp is None:
+            return 0
+        return self.chp.eta_el * self.variables['chp_f'][t] - self.variables['chp_h'][t]
+
+    def _fuel_cost(self):
+        """
+        Fuel cost of the combined heat and power unit and the boiler over the horizon [currency unit]
+        """
+        return pulp.lpSum((self.variables['chp_f'][t] + self.variables['chp_b'][t] / self.chp.eta_boiler)
+                          * self.time_series.p_G[t] for t in self.time_steps)
+
+    def _penalty(self, penalty: Penalty):
+        """
+        Linear expression of a custom penalty term. Terms of disabled batteries are dropped, they
+        do not charge or discharge and their state of charge is constant.
+        """
+        enabled = {k: i for i, k in enumerate(k for k, bat in enumerate(self.all_batteries) if bat.enabled)}
+        battery_vars = {'charge': 'c', 'discharge': 'd', 'soc': 's'}
+
+        terms = []
+        for (name, k, t), coef in penalty.terms.items():
+            if name == 'grid_import':
+                terms.append(coef * self.variables['n'][t])
+                if self.p_max_imp is not None:
+                    terms.append(coef * self.variables['e_imp_lim_exc'][t])
+            elif name == 'grid_export':
+                terms.append(coef * self.variables['e'][t])
+            elif k in enabled:
+                terms.append(coef * self.variables[battery_vars[name]][enabled[k]][t])
+        return pulp.lpSum(terms)
+
+    def _weighted_terms(self):
+        """
+        Battery wear, grid exchange and peak import weighted by the objective weights and valued at the
+        mean import price [currency unit]. The peak import power is valued as its energy over one hour.
+        """
+        terms = 0
+        if self.weights.battery_wear > 0:
+            terms += self.weights.battery_wear * pulp.lpSum(self.variables['c'][i][t] + self.variables['d'][i][t]
+                                                            for i in range(len(self.batteries))
+                                                            for t in self.time_steps)
+        if self.weights.self_consumption > 0:
+            exchange = 0
+            for t in self.time_steps:
+                exchange += self.variables['n'][t] + self.variables['e'][t]
+                if self.p_max_imp is not None:
+                    exchange += self.variables['e_imp_lim_exc'][t]
+            terms += self.weights.self_consumption * exchange
+        if self.weights.peak_shaving > 0:
+            terms += self.weights.peak_shaving * self.variables['p_imp_peak']
+        return self.prc_e_weighted * terms
+
+    def _net_cost(self):
+        """
+        Net cost of grid exchange over the horizon [currency unit]: import cost including demand rate
+        and tier surcharge minus export revenue, plus the fuel cost of a combined heat and power unit.
+        """
+        cost = 0
+        for t in self.time_steps:
+            cost += self.variables['n'][t] * self.time_series.p_N[t]
+            if self.p_max_imp is not None:
+                cost += self.variables['e_imp_lim_exc'][t] * self.time_series.p_N[t]
+            cost -= self._e_exp_remunerated(t) * self.time_series.p_E[t]
+        if self.is_grid_demand_rate_active:
+            cost += self.grid.prc_p_exc_imp * (self.variables['p_max_imp_exc'] - self._p_max_imp_exc_to_date())
+        if self.is_grid_tier_active:
+            cost += self.grid.prc_e_exc_tier * (self.variables['e_imp_tier_exc'] + self.variables['e_imp_tier_exc_next'])
+        if self.is_grid_net_metering_active:
+            cost += self._net_metering_charge()
+        if self.is_grid_peak_price_active:
+            cost += self._peak_charge()
+        if self.is_grid_commitment_active:
+            cost += self.grid.prc_e_dev * pulp.lpSum(self.variables['e_dev_pos'][t] + self.variables['e_dev_neg'][t]
+                                                     for t in self.time_steps)
+        if self.chp is not None:
+            cost += self._fuel_cost()
+        return cost
+
+    def _add_cost_budget_constraints(self):
+        """
+        Add the cost budget constraint of the goal seeking mode to the model.
+        """
+        if self.cost_budget is None:
+            return
+
+        # net cost may only exceed the budget by the penalized excess
+        self.problem += self._net_cost() - self.variables['cost_budget_exc'] <= self.cost_budget
+
+    def _spike_guard_intervals(self) -> List[int]:
+        """
+        Time steps of the most expensive import of the next day covering the spike guard hours,
+        in chronological order
+        """
+        if self.strategy.spike_guard_hours <= 0:
+            return []
+
+        # time steps starting within the next day
+        candidates = []
+        elapsed = 0
+        for t in self.time_steps:
+            if elapsed >= 24 * 3600:
+                break
+            candidates.append(t)
+            elapsed += self.time_series.dt[t]
+
+        chosen = []
+        covered = 0
+        for t in sorted(candidates, key=lambda t: -self.time_series.p_N[t]):
+            if covered >= self.strategy.spike_guard_hours * 3600:
+                break
+            chosen.append(t)
+            covered += self.time_series.dt[t]
+
+        return sorted(chosen)
+
+    def _add_spike_guard_constraints(self):
+        """
+        Add the price spike guard to the model: at the start of each of the most expensive time steps,
+        the batteries able to discharge keep enough energy to cover the net demand of this and all
+        later guarded time steps, protecting against forecast errors around price spikes.
+        """
+        chosen = self._spike_guard_intervals()
+        batteries = [(i, bat) for i, bat in enumerate(self.batteries) if bat.d_max > 0]
+
+        for k, tau in enumerate(chosen):
+            demand = sum(max(0., self.time_series.gt[t] - self.time_series.ft[t]) for t in chosen[k:])
+            stored = pulp.lpSum((self.variables['s'][i][tau - 1] if tau > 0 else bat.s_initial) - bat.s_min
+                                for i, bat in batteries)
+            self.problem += stored + self.variables['spike_guard_pen'][k] >= demand / self.eta_d
+
+    def _solve(self, time_limit: float | None):
+        """
+        Run the solver on the problem
+        """
+        with TemporaryDirectory() as tmpdir:
+            # the solver log is only written to follow the progress
+            log_path = os.path.join(tmpdir, 'cbc.log') if self._progress is not None else None
+            solver = pulp.PULP_CBC_CMD(
+                msg=0,
+                threads=self.settings.num_threads,
+                timeLimit=time_limit,
+                gapRel=self.mip_gap,
+                warmStart=self.warm_start is not None,
+                logPath=log_path,
+            )
+            solver.tmpDir = tmpdir
+            if log_path is None:
+                self.problem.solve(solver)
+            else:
+                with self._progress.watch(log_path):
+                    self.problem.solve(solver)
+
+    def _set_warm_start(self):
+        """
+        Set the initial values of the grid and battery variables and the binaries indicating
+        their direction from the warm start hint. Values are clipped to the bounds of the
+        variables, time steps beyond the hinted series are left to the solver.
+        """
+        def hint(var, value):
+            low = var.lowBound if var.lowBound is not None else value
+            up = var.upBound if var.upBound is not None else value
+            var.setInitialValue(min(max(value, low), up))
+
+        hint_n = self.warm_start.get('grid_import') or []
+        hint_e = self.warm_start.get('grid_export') or []
+        for t in range(min(len(hint_n), len(hint_e), self.T)):
+            hint(self.variables['n'][t], hint_n[t])
+            hint(self.variables['e'][t], hint_e[t])
+            hint(self.variables['y'][t], int(hint_e[t] > hint_n[t]))
+
+        # hints are given by index of all batteries, disabled batteries are not modelled
+        hints = self.warm_start.get('batteries') or []
+        enabled = [k for k, bat in enumerate(self.all_batteries) if bat.enabled]
+        for i, k in enumerate(enabled):
+            if k >= len(hints):
+                break
+            hint_c = hints[k].get('charging_power') or []
+            hint_d = hints[k].get('discharging_power') or []
+            for t in range(min(len(hint_c), len(hint_d), self.T)):
+                hint(self.variables['c'][i][t], hint_c[t])
+                hint(self.variables['d'][i][t], hint_d[t])
+                hint(self.variables['z_cd'][i][t], int(hint_d[t] > hint_c[t]))
+                if self.variables['z_c'][i] is not None:
+                    hint(self.variables['z_c'][i][t], int(hint_c[t] > 0))
+
+    def _solve_approximate(self, start: float, latency_ms: float) -> float | None:
+        """
+        Bounded latency approximation: solve the LP relaxation, round the integer variables and
+        solve the resulting LP again with the integer variables fixed. Returns the estimated
+        optimality loss as the difference to the objective of the relaxation, which bounds the
+        MILP optimum. Returns None if rounding did not yield a feasible solution or the latency
+        measured from start is used up, the problem is not solved then.
+        """
+        def solve() -> bool:
+            # the solver is not started once the latency is used up, e.g. by building the model
+            remaining = latency_ms / 1000 - (time.monotonic() - start)
+            if remaining <= 0:
+                self.problem.status = pulp.LpStatusNotSolved
+                return False
+            self._solve(remaining)
+            return self.problem.status == pulp.LpStatusOptimal
+
+        integers = [v for v in self.problem.variables() if v.cat == pulp.LpInteger]
+        bounds = [(v.lowBound, v.upBound) for v in integers]
+
+        for v in integers:
+            v.cat = pulp.LpContinuous
+        try:
+            if not solve():
+                return None
+            relaxed = pulp.value(self.problem.objective)
+
+            # fix the integer variables to their rounded relaxation values
+            rounded = self._round_relaxation()
+            for v in integers:
+                v.lowBound = v.upBound = rounded.get(v.name, round(v.varValue))
+            if not solve():
+                return None
+
+            return max(0., relaxed - pulp.value(self.problem.objective))
+        finally:
+            for v, (low, up) in zip(integers, bounds):
+                v.cat = pulp.LpInteger
+                v.lowBound, v.upBound = low, up
+
+    def _round_relaxation(self) -> Dict[str, int]:
+        """
+        Rounding heuristics for the binary variables of a solved LP relaxation. Big-M indicator
+        variables take tiny fractional values in the relaxation and are therefore set according
+        to the continuous variables they indicate instead of being rounded to the nearest integer.
+        Variables not covered keep nearest integer rounding.
+        """
+        eps = 1e-6
+        value = pulp.value
+        rounded = {}
+
+        for t in self.time_steps:
+            # flow direction follows the dominating grid flow
+            rounded[self.variables['y'][t].name] = int(value(self.variables['e'][t]) > value(self.variables['n'][t]) + eps)
+            # limits are active while there is no energy beyond them
+            if self.p_max_imp is not None:
+                rounded[self.variables['z_imp_lim'][t].name] = int(value(self.variables['e_imp_lim_exc'][t]) <= eps)
+            if self.p_max_exp is not None:
+                rounded[self.variables['z_exp_lim'][t].name] = int(value(self.variables['e_exp_lim_exc'][t]) <= eps)
+            # the combined heat and power unit runs as soon as it burns fuel
+            if 'z_chp' in self.variables:
+                rounded[self.variables['z_chp'][t].name] = int(value(self.variables['chp_f'][t]) > eps)
+
+        for i, bat in enumerate(self.batteries):
+            for t in self.time_steps:
+                c = value(self.variables['c'][i][t])
+                d = value(self.variables['d'][i][t])
+                # charging and discharging lock follows the dominating direction
+                rounded[self.variables['z_cd'][i][t].name] = int(d > c + eps)
+                # minimum charge power applies as soon as there is charging
+                if self.variables['z_c'][i] is not None:
+                    rounded[self.variables['z_c'][i][t].name] = int(c > eps)
+                # efficiency curves use the segment with the largest selection weight
+                for key in ['c', 'd']:
+                    if i in self.variables[f'{key}_eta_z']:
+                        z = self.variables[f'{key}_eta_z'][i][t]
+                        k = int(np.argmax([value(v) for v in z]))
+                        for j, v in enumerate(z):
+                            rounded[v.name] = int(j == k)
+            # preconditioning starts at the start with the largest selection weight
+            if i in self.variables['z_pre']:
+                z = self.variables['z_pre'][i]
+                k = max(z, key=lambda tau: value(z[tau]))
+                for tau, v in z.items():
+                    rounded[v.name] = int(tau == k)
+            # calibration completes at the fullest state of charge of its window
+            if i in self.variables['z_cal']:
+                z = self.variables['z_cal'][i]
+                k = max(z, key=lambda tau: value(self.variables['s'][i][tau]))
+                for tau, v in z.items():
+                    rounded[v.name] = int(tau == k)
+
+        return rounded
+
     def solve(self) -> Dict:
         """
         Creates the MILP model if none exists and solves the optimization problem.
         Returns a dictionary with the optimization results
         """
 
+        start = time.monotonic()
+
+        if self.progress is not None:
+            self._progress = SolverProgress(self.progress, start)
+            self._progress.report(MODEL)
+
         if self.problem is None:
             self.create_model()
 
         # Solve the problem
-        solver = pulp.PULP_CBC_CMD(
-            msg=0,
-            threads=self.settings.num_threads,
-            timeLimit=self.settings.time_limit,
-        )
-        with TemporaryDirectory() as tmpdir:
-            solver.tmpDir = tmpdir
-            self.problem.solve(solver)
+        optimality_loss = None
+        simplified = False
+        if self.max_latency_ms is None:
+            if self.warm_start is not None:
+                self._set_warm_start()
+            simplify = self.simplify_on_timeout and self.time_limit is not None
+            self._solve(self.time_limit * SIMPLIFY_MILP_SHARE if simplify else self.time_limit)
+            # CBC reports a time limit with a solution as optimal with a feasible solution only
+            if self.require_optimal and self.problem.sol_status == pulp.LpSolutionIntegerFeasible:
+                self.problem.status = pulp.LpStatusNotSolved
+            # CBC reports a time limit without solution as not solved
+            if simplify and self.problem.status == pulp.LpStatusNotSolved:
+                simplified = True
+                optimality_loss = self._solve_approximate(start, self.time_limit * 1000)
+        else:
+            optimality_loss = self._solve_approximate(start if self.started is None else self.started, self.max_latency_ms)
 
-        # Extract results
+        latency_ms = (time.monotonic() - start) * 1000
+
+        if self._progress is not None:
+            self._progress.report(RESULT)
+
+        # Extract results, the variables have no values if the solver was not run
         status = pulp.LpStatus[self.problem.status]
+        if status != 'Optimal':
+            return {
+                'status': status,
+                'objective_value': None,
+                'objective_unit': self.objective_unit,
+                'latency_ms': latency_ms,
+                'optimality_loss': None,
+                'simplified': simplified,
+                'limit_violations': {
+                    'grid_import_limit_exceeded': False,
+                    'grid_export_limit_hit': False,
+                    'cost_budget_exceeded': False,
+                    'export_cap_reached': False
+                },
+                'batteries': [],
+                'grid_import': [],
+                'grid_export': [],
+                'flow_direction': [],
+                'grid_import_overshoot': [],
+                'grid_export_overshoot': [],
+                'curtailment_risk': [],
+                'export_preference_score': None,
+                'grid_deviation': [],
+                'grid_import_peak': None,
+                'chp': None
+            }
 
         # grid import and export if no demand rate is active
         # if a limit is set and exceeded, this is the part that is actually imported / exported.
@@ -524,64 +1613,138 @@ class Optimizer:
         # grid import limit
         grid_imp_limit_violated = False
         e_grid_imp_overshoot = []
-        if self.grid.p_max_imp is not None:
+        if self.p_max_imp is not None:
             grid_imp_limit_violated = (np.max([pulp.value(var) for var in self.variables['e_imp_lim_exc']]) > 0)
             e_grid_imp_overshoot = [pulp.value(var) for var in self.variables['e_imp_lim_exc']]
         # grid export limit
         grid_exp_limit_hit = False
         e_grid_exp_overshoot = []
-        if self.grid.p_max_exp is not None:
+        if self.p_max_exp is not None:
             grid_exp_limit_hit = (np.max([pulp.value(var) for var in self.variables['e_exp_lim_exc']]) > 0)
             e_grid_exp_overshoot = [pulp.value(var) for var in self.variables['e_exp_lim_exc']]
 
-        if status == 'Optimal':
-            result = {
-                'status': status,
-                'objective_value': self.get_clean_objective_value(),
-                'limit_violations': {
-                    'grid_import_limit_exceeded': grid_imp_limit_violated,
-                    'grid_export_limit_hit': grid_exp_limit_hit
-                },
-                'batteries': [],
-                'grid_import': e_grid_import,
-                'grid_export': e_grid_export,
-                'flow_direction': [],
-                'grid_import_overshoot': e_grid_imp_overshoot,
-                'grid_export_overshoot': e_grid_exp_overshoot
+        # export cap
+        export_cap_reached = False
+        if self.is_grid_export_cap_active:
+            export_cap_reached = sum(pulp.value(self.variables['e'][t]) - pulp.value(self.variables['e_rem'][t])
+                                     for t in self.time_steps if self._is_export_capped(t)) > 1e-6
+
+        # cost budget of the goal seeking mode
+        cost_budget_exceeded = False
+        if self.cost_budget is not None:
+            cost_budget_exceeded = pulp.value(self.variables['cost_budget_exc']) > 1e-6
+
+        result = {
+            'status': status,
+            'objective_value': self.get_clean_objective_value(),
+            'objective_unit': self.objective_unit,
+            'latency_ms': latency_ms,
+            'optimality_loss': optimality_loss,
+            'simplified': simplified,
+            'limit_violations': {
+                'grid_import_limit_exceeded': grid_imp_limit_violated,
+                'grid_export_limit_hit': grid_exp_limit_hit,
+                'cost_budget_exceeded': cost_budget_exceeded,
+                'export_cap_reached': export_cap_reached
+            },
+            'batteries': [],
+            'grid_import': e_grid_import,
+            'grid_export': e_grid_export,
+            'flow_direction': [],
+            'grid_import_overshoot': e_grid_imp_overshoot,
+            'grid_export_overshoot': e_grid_exp_overshoot,
+            'curtailment_risk': self._curtailment_risk(self.time_series),
+            'export_preference_score': self._export_preference_score(self.time_series, e_grid_export),
+            'grid_deviation': self._grid_deviation(),
+            'grid_import_peak': pulp.value(self.variables['p_peak']) if self.is_grid_peak_price_active else None,
+            'chp': self._chp_result()
+        }
+
+        # Extract battery results, disabled batteries get zeroed series
+        i = 0
+        for bat in self.all_batteries:
+            if not bat.enabled:
+                result['batteries'].append({
+                    'id': bat.id,
+                    'name': bat.name,
+                    'charging_power': [0.] * self.T,
+                    'discharging_power': [0.] * self.T,
+                    'state_of_charge': [0.] * self.T
+                })
+                continue
+
+            battery_result = {
+                'id': bat.id,
+                'name': bat.name,
+                'charging_power': [pulp.value(var) for var in self.variables['c'][i]],
+                'discharging_power': [pulp.value(var) for var in self.variables['d'][i]],
+                'state_of_charge': [pulp.value(var) for var in self.variables['s'][i]],
+                'preconditioning_power': [pulp.value(self._preconditioning(i, t)) for t in self.time_steps]
+                if bat.preconditioning is not None else None,
+                'expected_shortfall': sum(dep.probability * pulp.value(pen)
+                                          for dep, pen in zip(bat.departures, self.variables['departure_pen'][i]))
+                if bat.departures else None,
+                'calibration_step': self._calibration_step(i),
+                'goal_shortfalls': self._goal_shortfalls(i)
             }
+            result['batteries'].append(battery_result)
+            i += 1
 
-            # Extract battery results
-            for i, bat in enumerate(self.batteries):
-                battery_result = {
-                    'charging_power': [pulp.value(var) for var in self.variables['c'][i]],
-                    'discharging_power': [pulp.value(var) for var in self.variables['d'][i]],
-                    'state_of_charge': [pulp.value(var) for var in self.variables['s'][i]]
-                }
-                result['batteries'].append(battery_result)
+        # Extract flow direction
+        for y_var in self.variables['y']:
+            if y_var is not None:
+                result['flow_direction'].append(int(pulp.value(y_var)))
+            else:
+                result['flow_direction'].append(0)  # Default to import when constraint not active
 
-            # Extract flow direction
-            for y_var in self.variables['y']:
-                if y_var is not None:
-                    result['flow_direction'].append(int(pulp.value(y_var)))
-                else:
-                    result['flow_direction'].append(0)  # Default to import when constraint not active
+        return result
 
-            return result
-        else:
-            return {
-                'status': status,
-                'objective_value': None,
-                'limit_violations': {
-                    'grid_import_limit_exceeded': False,
-                    'grid_export_limit_hit': False
-                },
-                'batteries': [],
-                'grid_import': [],
-                'grid_export': [],
-                'flow_direction': [],
-                'grid_import_overshoot': [],
-                'grid_export_overshoot': []
-            }
+    @staticmethod
+    def _curtailment_risk(time_series: TimeSeriesData) -> List[bool]:
+        """
+        Intervals at risk of export curtailment. Empty if no curtailment forecast is given.
+        """
+        if time_series.r_curt is None:
+            return []
+        return [r >= CURTAILMENT_RISK_THRESHOLD for r in time_series.r_curt]
+
+    @staticmethod
+    def _export_preference_score(time_series: TimeSeriesData, grid_export: List[float]) -> Optional[float]:
+        """
+        Achieved export preference as sum of the export preference bonus [currency unit].
+        None if no export preference is given.
+        """
+        if time_series.w_E is None:
+            return None
+        return sum(e * w for e, w in zip(grid_export, time_series.w_E))
+
+    def _grid_deviation(self) -> List[float]:
+        """
+        Deviation of the net grid import from the committed schedule at each time step [Wh].
+        Empty if no committed schedule is given.
+        """
+        if not self.is_grid_commitment_active:
+            return []
+        return [pulp.value(self.variables['e_dev_pos'][t]) - pulp.value(self.variables['e_dev_neg'][t])
+                for t in self.time_steps]
+
+    def _chp_result(self) -> Optional[Dict]:
+        """
+        Dispatch of the combined heat and power unit, the boiler and electric heating at each time step
+        [Wh] and the fuel cost [currency unit]. None if no unit is given.
+        """
+        if self.chp is None:
+            return None
+        fuel = [pulp.value(var) for var in self.variables['chp_f']]
+        boiler = [pulp.value(var) for var in self.variables['chp_b']]
+        return {
+            'electricity': [self.chp.eta_el * f for f in fuel],
+            'heat': [self.chp.eta_th * f for f in fuel],
+            'fuel': [f + b / self.chp.eta_boiler for f, b in zip(fuel, boiler)],
+            'boiler_heat': boiler,
+            'electric_heating': [pulp.value(var) for var in self.variables['chp_h']],
+            'fuel_cost': pulp.value(self._fuel_cost()),
+        }
 
     def get_clean_objective_value(self):
         '''
@@ -590,7 +1753,7 @@ class Optimizer:
         clean_objective = 0
         # Grid import cost (negative because we want to minimize cost) [currency unit]
         for t in self.time_steps:
-            if self.grid.p_max_imp is not None:
+            if self.p_max_imp is not None:
                 clean_objective -= (
                     # grid import up to the demand rate threshold
                     pulp.value(self.variables['n'][t])
@@ -603,17 +1766,40 @@ class Optimizer:
 
         # Grid export revenue [currency unit]
         for t in self.time_steps:
-            clean_objective += pulp.value(self.variables['e'][t]) * self.time_series.p_E[t]
+            clean_objective += pulp.value(self._e_exp_remunerated(t)) * self.time_series.p_E[t]
 
         # Final state of charge value [currency unit]
         for i, bat in enumerate(self.batteries):
             clean_objective += (pulp.value(self.variables['s'][i][self.T-1])
                                 - pulp.value(self.variables['s'][i][0])) * bat.p_a
 
+        # fuel cost of the combined heat and power unit and the boiler
+        if self.chp is not None:
+            clean_objective += - pulp.value(self._fuel_cost())
+
         # charge for import power demand rate. The demand rate is applied to the maximum
         # power draw beyond the threshold within the time horizon.
         if self.is_grid_demand_rate_active:
             clean_objective += - self.grid.prc_p_exc_imp \
-                * pulp.value(self.variables['p_max_imp_exc'])
+                * (pulp.value(self.variables['p_max_imp_exc']) - self._p_max_imp_exc_to_date())
+
+        # surcharge for import energy beyond the tier allowance
+        if self.is_grid_tier_active:
+            clean_objective += - self.grid.prc_e_exc_tier \
+                * (pulp.value(self.variables['e_imp_tier_exc']) + pulp.value(self.variables['e_imp_tier_exc_next']))
+
+        # charge for the net import, credit for the net export
+        if self.is_grid_net_metering_active:
+            clean_objective += - pulp.value(self._net_metering_charge())
+
+        # charge for the peak import power
+        if self.is_grid_peak_price_active:
+            clean_objective += - pulp.value(self._peak_charge())
+
+        # charge for deviating from the committed grid exchange
+        if self.is_grid_commitment_active:
+            clean_objective += - self.grid.prc_e_dev \
+                * sum(pulp.value(self.variables['e_dev_pos'][t]) + pulp.value(self.variables['e_dev_neg'][t])
+                      for t in self.time_steps)
 
         return clean_objective
@@ -0,0 +1,108 @@
+import re
+import threading
+import time
+from contextlib import contextmanager
+
+# phases of a solve
+MODEL = 'model'  # building the model
+RELAXATION = 'relaxation'  # solving the LP relaxation at the root node
+SEARCH = 'search'  # branch and bound
+RESULT = 'result'  # extracting the result
+
+# interval of progress reports while the solver runs [s]
+PROGRESS_INTERVAL = 1.0
+
+# CBC reports the objective of a missing incumbent as infinity
+NO_SOLUTION = 1e50
+
+# CBC log lines of new incumbents and of the branch and bound progress
+INCUMBENT = re.compile(r'Cbc00(?:04|12)I Integer solution of (\S+)')
+NODES = re.compile(r'Cbc0010I After \d+ nodes, \d+ on tree, (\S+) best solution, best possible (\S+)')
+RELAXED = re.compile(r'Continuous objective value is (\S+)')
+
+
+class SolverProgress:
+    """
+    Reports the progress of a solve to a callback: the phase, the elapsed time and, once the
+    solver found a solution, the objective of the incumbent and its relative gap to the best
+    bound, both parsed from the CBC log. The objective is in the terms of the solver including
+    penalty and weight terms, only its change is meaningful. CBC buffers its log, incumbents may
+    be reported late or not at all for short solves.
+    """
+
+    def __init__(self, callback, start: float):
+        self.callback = callback
+        self.start = start
+        self.phase = MODEL
+        self.objective = None
+        self.bound = None
+        self._lock = threading.Lock()
+
+    def gap(self) -> float | None:
+        if self.objective is None or self.bound is None:
+            return None
+        return abs(self.objective - self.bound) / max(abs(self.objective), 1e-10)
+
+    def report(self, phase: str | None = None):
+        with self._lock:
+            if phase is not None:
+                self.phase = phase
+            self.callback({
+                'phase': self.phase,
+                'elapsed': round(time.monotonic() - self.start, 3),
+                'objective': self.objective,
+                'gap': self.gap(),
+            })
+
+    def parse(self, line: str):
+        """
+        Update the state from a line of the CBC log
+        """
+        try:
+            if m := RELAXED.search(line):
+                self.bound = float(m.group(1))
+            elif m := INCUMBENT.search(line):
+                self.phase = SEARCH
+                self.objective = float(m.group(1))
+            elif m := NODES.search(line):
+                self.phase = SEARCH
+                objective, bound = float(m.group(1)), float(m.group(2))
+                if abs(objective) < NO_SOLUTION:
+                    self.objective = objective
+                self.bound = bound
+        except ValueError:
+            pass
+
+    @contextmanager
+    def watch(self, log_path: str):
+        """
+        Follow the solver log at log_path and report every PROGRESS_INTERVAL until the context
+        exits, the reports double as keep-alive while the solver is silent
+        """
+        self.report(RELAXATION)
+        stop = threading.Event()
+
+        def follow():
+            offset, partial = 0, ''
+            while not stop.wait(PROGRESS_INTERVAL):
+                try:
+                    with open(log_path) as f:
+                        f.seek(offset)
+                        chunk = f.read()
+                        offset = f.tell()
+                except FileNotFoundError:
+                    chunk = ''
+
+                lines = (partial + chunk).split('\n')
+                partial = lines.pop()
+                for line in lines:
+                    self.parse(line)
+                self.report()
+
+        thread = threading.Thread(target=follow, daemon=True)
+        thread.start()
+        try:
+            yield
+        finally:
+            stop.set()
+            thread.join()
@@ -1,9 +1,35 @@
+import os
+
 from pydantic import Field
-from pydantic_settings import BaseSettings, SettingsConfigDict
+from pydantic_settings import BaseSettings, PydanticBaseSettingsSource, SettingsConfigDict
 
 
 class OptimizerSettings(BaseSettings):
-    model_config = SettingsConfigDict(env_prefix="OPTIMIZER_")
+    """
+    Settings from OPTIMIZER_ environment variables, overridden by the env file at
+    OPTIMIZER_CONFIG_FILE if given. Settings are loaded per solve and per request, changes of the
+    file apply to the next solve without restarting the workers. Directories and files shared by
+    the workers are set up at startup, reload them by sending SIGHUP to gunicorn, which replaces
+    the workers gracefully.
+    """
+    model_config = SettingsConfigDict(env_prefix="OPTIMIZER_", env_file=os.environ.get('OPTIMIZER_CONFIG_FILE'), extra='ignore')
+
+    @classmethod
+    def settings_customise_sources(cls, settings_cls: type[BaseSettings], init_settings: PydanticBaseSettingsSource,
+                                   env_settings: PydanticBaseSettingsSource, dotenv_settings: PydanticBaseSettingsSource,
+                                   file_secret_settings: PydanticBaseSettingsSource):
+        # the env file is edited at runtime, it overrides the environment of the image
+        return init_settings, dotenv_settings, env_settings, file_secret_settings
 
     num_threads: int | None = Field(default=None, description="Number of threads to use for optimization")
     time_limit: float | None = Field(default=None, description="Time limit for the optimization process in seconds")
+    stats_file: str | None = Field(default=None, description="File shared by all workers for solve statistics, defaults to a file in the temp directory")
+    template_dir: str | None = Field(default=None, description="Directory shared by all workers for request templates, defaults to a directory in the temp directory")
+    job_dir: str | None = Field(default=None, description="Directory shared by all workers for asynchronous jobs, defaults to a directory in the temp directory")
+    job_runner: bool = Field(default=False, description="Run jobs in the job runner process started by the gunicorn master with --config python:optimizer.gunicorn_conf instead of threads of the accepting worker")
+    job_time_limit: float | None = Field(default=None, description="Time limit for jobs in seconds, replacing the time limit of requests, defaults to the time limit")
+    job_concurrency: int = Field(default=1, ge=1, description="Number of jobs the job runner solves at a time")
+    max_jobs: int | None = Field(default=None, ge=1, description="Number of queued and running jobs of a host at which submitting jobs responds 429, unlimited if not set")
+    drain_timeout: float = Field(default=20, description="Time in seconds the job runner, or a worker running jobs in threads, waits for running jobs on shutdown, must fit into the shutdown timeout of the host")
+    audit_file: str | None = Field(default=None, description="Append-only JSON lines file shared by all workers recording solve requests, disabled if not set")
+    jwt_token_secret: str | None = Field(default=None, validation_alias='JWT_TOKEN_SECRET', description="Secret of the HS256 tokens authorizing requests, requests are not authorized if not set")
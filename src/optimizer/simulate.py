@@ -0,0 +1,170 @@
+from typing import Dict, List
+
+import numpy as np
+
+from .optimizer import BatteryConfig, GridConfig, Optimizer, TimeSeriesData, grid_power_limits
+
+# dispatch policies supported by the simulation
+POLICIES = ['self_consumption']
+
+
+class Simulator:
+    """
+    Simulates battery and grid trajectories for a fixed dispatch policy without optimization.
+    The result has the shape of the optimization result and serves as baseline for charts and
+    for sanity checking battery parameters.
+
+    Policy self_consumption: surplus generation charges the batteries, deficits are covered by
+    discharging the batteries, both in order of descending priority. Batteries never charge from
+    or discharge to the grid, charge demands and goals are ignored. Unavailable batteries are
+    skipped. Generation beyond the grid export limit is curtailed. Preconditioning runs right
+    before the end of its window.
+    """
+
+    def __init__(self, grid: GridConfig, batteries: List[BatteryConfig], time_series: TimeSeriesData,
+                 eta_c: float = 0.95, eta_d: float = 0.95, policy: str = 'self_consumption'):
+        self.grid = grid
+        self.batteries = batteries
+        self.time_series = time_series
+        self.eta_c = eta_c
+        self.eta_d = eta_d
+        self.policy = policy
+        self.T = len(time_series.gt)
+        # grid import and export power limits at each time step, None if unlimited [W]
+        self.p_max_imp, self.p_max_exp = grid_power_limits(grid, time_series)
+
+    def _eta(self, bat: BatteryConfig, key: str, t: int, energy: float) -> float:
+        """
+        Efficiency of battery bat charging (c) or discharging (d) the given energy in time step t
+        """
+        if key == 'c' and bat.c_eta_series is not None:
+            return bat.c_eta_series[t]
+        curve = Optimizer._efficiency_curve(bat, key)
+        if curve is None:
+            return self.eta_c if key == 'c' else self.eta_d
+        power = energy * 3600. / self.time_series.dt[t]
+        return float(np.interp(power, [p.power for p in curve], [p.eta for p in curve]))
+
+    def simulate(self) -> Dict:
+        """
+        Runs the simulation and returns a dictionary with the optimization result fields
+        """
+        n = len(self.batteries)
+        charge = [[0.] * self.T for _ in range(n)]
+        discharge = [[0.] * self.T for _ in range(n)]
+        soc = [[0.] * self.T for _ in range(n)]
+        preconditioning = [[0.] * self.T if bat.preconditioning is not None else None for bat in self.batteries]
+        grid_import = [0.] * self.T
+        grid_export = [0.] * self.T
+        export_overshoot = [0.] * self.T
+        import_overshoot = [0.] * self.T
+
+        s = [bat.s_initial for bat in self.batteries]
+        order = sorted((i for i, bat in enumerate(self.batteries) if bat.enabled),
+                       key=lambda i: -self.batteries[i].c_priority)
+
+        for t in range(self.T):
+            dt = self.time_series.dt[t] / 3600.
+            residual = self.time_series.ft[t] - self.time_series.gt[t]
+
+            # preconditioning is a load while available and drawn from the battery otherwise
+            for i in order:
+                pre = self.batteries[i].preconditioning
+                t_end = min(pre.t_end, self.T) if pre is not None else 0
+                if pre is None or not t_end - pre.steps <= t < t_end:
+                    continue
+                preconditioning[i][t] = pre.power * dt
+                if Optimizer._available(self.batteries[i], t):
+                    residual -= preconditioning[i][t]
+                else:
+                    # like the optimizer, the battery is not drawn below s_min
+                    s[i] = max(min(self.batteries[i].s_min, s[i]), s[i] - preconditioning[i][t])
+
+            for i in order:
+                bat = self.batteries[i]
+                if not Optimizer._available(bat, t):
+                    continue
+                if residual > 0:
+                    energy = min(residual, bat.c_max * dt)
+                    energy = max(0., min(energy, (bat.s_max - s[i]) / self._eta(bat, 'c', t, energy)))
+                    s[i] += energy * self._eta(bat, 'c', t, energy)
+                    charge[i][t] = energy
+                    residual -= energy
+                elif residual < 0:
+                    energy = min(-residual, bat.d_max * dt)
+                    energy = max(0., min(energy, (s[i] - bat.s_min) * self._eta(bat, 'd', t, energy)))
+                    s[i] -= energy / self._eta(bat, 'd', t, energy)
+                    discharge[i][t] = energy
+                    residual += energy
+
+            for i in range(n):
+                soc[i][t] = s[i] if self.batteries[i].enabled else 0.
+
+            if residual > 0:
+                grid_export[t] = residual
+                if self.p_max_exp is not None:
+                    grid_export[t] = min(residual, self.p_max_exp[t] * dt)
+                    export_overshoot[t] = residual - grid_export[t]
+            else:
+                grid_import[t] = -residual
+                if self.p_max_imp is not None:
+                    import_overshoot[t] = max(0., -residual - self.p_max_imp[t] * dt)
+
+        # remunerated export, a cap is used up in chronological order. Export at negative prices is
+        # billed in full and does not use up the cap.
+        remunerated = list(grid_export)
+        if self.grid.e_exp_cap is not None:
+            cap = max(0., self.grid.e_exp_cap - self.grid.e_exp_to_date)
+            for t in range(self.T):
+                if t == self.grid.t_cap_reset:
+                    cap = self.grid.e_exp_cap
+                if self.time_series.p_E[t] < 0:
+                    continue
+                remunerated[t] = min(grid_export[t], cap)
+                cap -= remunerated[t]
+
+        # objective as reported by the optimizer for comparability
+        objective = sum(remunerated[t] * self.time_series.p_E[t] - grid_import[t] * self.time_series.p_N[t]
+                        for t in range(self.T))
+        if self.T > 0:
+            objective += sum((soc[i][-1] - soc[i][0]) * bat.p_a for i, bat in enumerate(self.batteries))
+
+        # charge for raising the peak import power above the peak reached so far
+        peak = None
+        if self.grid.prc_p_peak:
+            peak = max([self.grid.p_peak_to_date] + [grid_import[t] * 3600 / self.time_series.dt[t] for t in range(self.T)])
+            previous = 0
+            for point in self.grid.prc_p_peak:
+                objective -= (point.price - previous) * (max(0., peak - point.power)
+                                                         - max(0., self.grid.p_peak_to_date - point.power))
+                previous = point.price
+
+        return {
+            'status': 'Simulated',
+            'objective_value': objective,
+            'objective_unit': 'currency',
+            'limit_violations': {
+                'grid_import_limit_exceeded': max(import_overshoot, default=0) > 0,
+                'grid_export_limit_hit': max(export_overshoot, default=0) > 0,
+                'cost_budget_exceeded': False,
+                'export_cap_reached': sum(remunerated) < sum(grid_export) - 1e-6
+            },
+            'batteries': [
+                {
+                    'id': self.batteries[i].id,
+                    'name': self.batteries[i].name,
+                    'charging_power': charge[i],
+                    'discharging_power': discharge[i],
+                    'state_of_charge': soc[i],
+                    'preconditioning_power': preconditioning[i]
+                } for i in range(n)
+            ],
+            'grid_import': grid_import,
+            'grid_export': grid_export,
+            'flow_direction': [int(e > 0) for e in grid_export],
+            'grid_import_overshoot': import_overshoot if self.p_max_imp is not None else [],
+            'grid_export_overshoot': export_overshoot if self.p_max_exp is not None else [],
+            'curtailment_risk': Optimizer._curtailment_risk(self.time_series),
+            'export_preference_score': Optimizer._export_preference_score(self.time_series, grid_export),
+            'grid_import_peak': peak
+        }
@@ -0,0 +1,51 @@
+# Machine readable descriptions of the strategy parameters of the optimization request, so that
+# user interfaces can render the options without hardcoding them.
+STRATEGIES = [
+    {
+        'parameter': 'charging_strategy',
+        'title': 'Charging strategy',
+        'description': 'Strategy for charging in situations where choices are cost neutral.',
+        'default': 'none',
+        'values': [
+            {'value': 'none', 'title': 'None', 'description': 'No strategy set.'},
+            {'value': 'charge_before_export', 'title': 'Charge before export',
+             'description': 'Charge batteries before exporting to grid.'},
+            {'value': 'attenuate_grid_peaks', 'title': 'Attenuate grid peaks',
+             'description': 'Charge at times with high solar yield to reduce the grid load.'},
+        ],
+    },
+    {
+        'parameter': 'discharging_strategy',
+        'title': 'Discharging strategy',
+        'description': 'Strategy for discharging in situations where choices are cost neutral.',
+        'default': 'none',
+        'values': [
+            {'value': 'none', 'title': 'None', 'description': 'No strategy set.'},
+            {'value': 'discharge_before_import', 'title': 'Discharge before import',
+             'description': 'Discharge batteries before importing from grid.'},
+        ],
+    },
+    {
+        'parameter': 'objective',
+        'title': 'Objective',
+        'description': 'Quantity to optimize. Energy and emissions cannot be combined with demand rate, '
+                       'tiered tariff, deviation price or cost budget.',
+        'default': 'cost',
+        'values': [
+            {'value': 'cost', 'title': 'Cost', 'description': 'Economic benefit based on import and export prices.'},
+            {'value': 'energy', 'title': 'Grid energy',
+             'description': 'Grid import energy, e.g. if no tariff is available.'},
+            {'value': 'emissions', 'title': 'Emissions',
+             'description': 'Emissions of grid import, requires an emissions forecast.'},
+        ],
+    },
+    {
+        'parameter': 'spike_guard_hours',
+        'title': 'Price spike guard',
+        'description': 'Number of the most expensive import hours of the next day for which enough energy '
+                       'is kept stored, protecting against forecast errors around price spikes. Any '
+                       'non-negative number, 0 disables the guard.',
+        'default': '0',
+        'values': [],
+    },
+]
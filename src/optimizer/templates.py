@@ -0,0 +1,112 @@
+import hashlib
+import json
+import os
+import tempfile
+
+# maximum size of a stored template [bytes]
+MAX_TEMPLATE_SIZE = 64 << 10
+
+
+class TemplateStore:
+    """
+    Static site definitions registered once and referenced by id from requests carrying the
+    dynamic inputs only. Templates are kept in a directory shared by all workers of a host, one
+    file per template. Ids are derived from the owner and the content, so registering the same
+    template twice yields the same id and templates are never modified in place.
+
+    Templates are not shared between hosts. Clients re-register a template if a request
+    referencing it fails as not found.
+    """
+
+    def __init__(self, path: str | None = None):
+        self.path = path or os.path.join(tempfile.gettempdir(), 'optimizer-templates')
+
+    @staticmethod
+    def _owner_dir(owner: str | None) -> str:
+        return hashlib.sha256((owner or '').encode()).hexdigest()[:16]
+
+    def _file(self, owner: str | None, template_id: str) -> str | None:
+        # ids are hex digests, anything else cannot be a stored template
+        if len(template_id) != 16 or any(c not in '0123456789abcdef' for c in template_id):
+            return None
+        return os.path.join(self.path, self._owner_dir(owner), template_id + '.json')
+
+    def put(self, owner: str | None, template: dict) -> str:
+        """
+        Store a template and return its id
+        """
+        data = json.dumps(template, sort_keys=True, separators=(',', ':'))
+        if len(data) > MAX_TEMPLATE_SIZE:
+            raise ValueError(f"Template exceeds {MAX_TEMPLATE_SIZE} bytes")
+
+        template_id = hashlib.sha256(((owner or '') + '\n' + data).encode()).hexdigest()[:16]
+        path = self._file(owner, template_id)
+        os.makedirs(os.path.dirname(path), exist_ok=True)
+
+        # write atomically, concurrent writes of the same id have the same content
+        fd, tmp = tempfile.mkstemp(dir=os.path.dirname(path))
+        with os.fdopen(fd, 'w') as f:
+            f.write(data)
+        os.replace(tmp, path)
+
+        return template_id
+
+    def get(self, owner: str | None, template_id: str) -> dict | None:
+        """
+        Load a template, None if it does not exist
+        """
+        path = self._file(owner, template_id)
+        if path is None:
+            return None
+        try:
+            with open(path) as f:
+                return json.load(f)
+        except FileNotFoundError:
+            return None
+
+    def delete(self, owner: str | None, template_id: str) -> bool:
+        """
+        Delete a template, False if it did not exist
+        """
+        path = self._file(owner, template_id)
+        if path is None:
+            return False
+        try:
+            os.remove(path)
+            return True
+        except FileNotFoundError:
+            return False
+
+    def ids(self, owner: str | None) -> list[str]:
+        """
+        Ids of all templates of the owner
+        """
+        try:
+            names = os.listdir(os.path.join(self.path, self._owner_dir(owner)))
+        except FileNotFoundError:
+            return []
+        return sorted(name[:-5] for name in names if name.endswith('.json'))
+
+
+def merge_template(template: dict, data: dict) -> dict:
+    """
+    Merge the dynamic inputs of a request into its template. Objects are merged recursively with
+    the request taking precedence, batteries are merged by index.
+    """
+    result = dict(template)
+    for key, value in data.items():
+        if key == 'batteries' and isinstance(value, list) and isinstance(result.get(key), list):
+            batteries = list(result[key])
+            for i, bat in enumerate(value):
+                if i >= len(batteries):
+                    batteries.append(bat)
+                elif isinstance(bat, dict) and isinstance(batteries[i], dict):
+                    batteries[i] = {**batteries[i], **bat}
+                else:
+                    batteries[i] = bat
+            result[key] = batteries
+        elif isinstance(value, dict) and isinstance(result.get(key), dict):
+            result[key] = merge_template(result[key], value)
+        else:
+            result[key] = value
+    return result
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// bolt buckets of the key value store and the logs are prefixed to keep their names apart
+const (
+	boltBucketPrefix = "kv/"
+	boltLogPrefix    = "log/"
+)
+
+// Bolt is a Backend persisting to a bbolt database file, e.g. for single host deployments.
+// The file is locked by the opening process.
+type Bolt struct {
+	db *bolt.DB
+}
+
+var _ Backend = (*Bolt)(nil)
+
+// OpenBolt opens or creates the bbolt database at path.
+func OpenBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	return &Bolt{db: db}, nil
+}
+
+func (b *Bolt) Get(_ context.Context, bucket, key string) ([]byte, error) {
+	var res []byte
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(boltBucketPrefix + bucket))
+		if bk == nil {
+			return ErrNotFound
+		}
+
+		v := bk.Get([]byte(key))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		// values are only valid during the transaction
+		res = slices.Clone(v)
+		return nil
+	})
+
+	return res, err
+}
+
+func (b *Bolt) Put(_ context.Context, bucket, key string, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk, err := tx.CreateBucketIfNotExists([]byte(boltBucketPrefix + bucket))
+		if err != nil {
+			return err
+		}
+		// bbolt stores nil as missing key
+		return bk.Put([]byte(key), append([]byte{}, value...))
+	})
+}
+
+func (b *Bolt) Delete(_ context.Context, bucket, key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(boltBucketPrefix + bucket))
+		if bk == nil {
+			return nil
+		}
+		return bk.Delete([]byte(key))
+	})
+}
+
+func (b *Bolt) Keys(_ context.Context, bucket string) ([]string, error) {
+	keys := []string{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(boltBucketPrefix + bucket))
+		if bk == nil {
+			return nil
+		}
+		// keys are sorted byte-wise
+		return bk.ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+
+	return keys, err
+}
+
+func (b *Bolt) Append(_ context.Context, log string, value []byte) (uint64, error) {
+	var seq uint64
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bk, err := tx.CreateBucketIfNotExists([]byte(boltLogPrefix + log))
+		if err != nil {
+			return err
+		}
+
+		if seq, err = bk.NextSequence(); err != nil {
+			return err
+		}
+		return bk.Put(binary.BigEndian.AppendUint64(nil, seq), append([]byte{}, value...))
+	})
+
+	return seq, err
+}
+
+func (b *Bolt) Read(_ context.Context, log string, after uint64, limit int) ([]Entry, error) {
+	var res []Entry
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(boltLogPrefix + log))
+		if bk == nil {
+			return nil
+		}
+
+		// big endian sequence numbers sort in order
+		c := bk.Cursor()
+		for k, v := c.Seek(binary.BigEndian.AppendUint64(nil, after+1)); k != nil; k, v = c.Next() {
+			if limit > 0 && len(res) == limit {
+				break
+			}
+			res = append(res, Entry{Seq: binary.BigEndian.Uint64(k), Value: slices.Clone(v)})
+		}
+		return nil
+	})
+
+	return res, err
+}
+
+func (b *Bolt) Truncate(_ context.Context, log string, before uint64) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(boltLogPrefix + log))
+		if bk == nil {
+			return nil
+		}
+
+		// deleting moves the cursor, collect the keys first
+		var keys [][]byte
+		c := bk.Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k) < before; k, _ = c.Next() {
+			keys = append(keys, slices.Clone(k))
+		}
+
+		for _, k := range keys {
+			if err := bk.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,206 @@
+// Package storage provides a persistence backend for stateful features, a key value store of
+// buckets and append-only logs. Backends are interchangeable, deployments pick one for all
+// features, e.g. the store of runs, the schedule journal, shadow operation and the plan
+// revisions served by handlers:
+//
+//	b, err := storage.Open("sqlite:optimizer.db")
+//	err = b.Put(ctx, "templates", id, data)
+//	seq, err := b.Append(ctx, "plans", data)
+//	err = b.Truncate(ctx, "plans", seq)
+//
+// Logs grow until truncated, features recording to logs remove entries outside of their
+// retention, see Prune.
+package storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned by Get if the key does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Entry is a log entry, sequence numbers start at 1 and increase by 1 per log.
+type Entry struct {
+	Seq   uint64
+	Value []byte
+}
+
+// Backend persists values by bucket and key and entries of append-only logs. Implementations
+// are safe for concurrent use.
+type Backend interface {
+	// Get returns the value of key, ErrNotFound if it does not exist.
+	Get(ctx context.Context, bucket, key string) ([]byte, error)
+	// Put creates or replaces the value of key.
+	Put(ctx context.Context, bucket, key string, value []byte) error
+	// Delete removes key, deleting a missing key is not an error.
+	Delete(ctx context.Context, bucket, key string) error
+	// Keys returns the keys of bucket in ascending order.
+	Keys(ctx context.Context, bucket string) ([]string, error)
+	// Append adds value to log and returns its sequence number.
+	Append(ctx context.Context, log string, value []byte) (uint64, error)
+	// Read returns up to limit entries of log after the sequence number after, all if limit is 0.
+	Read(ctx context.Context, log string, after uint64, limit int) ([]Entry, error)
+	// Truncate removes the entries of log before the sequence number before. Sequence numbers
+	// of removed entries are not reused.
+	Truncate(ctx context.Context, log string, before uint64) error
+	// Close releases the backend.
+	Close() error
+}
+
+// Open opens the backend of uri, a Postgres URL starting with postgres:// or postgresql://,
+// sqlite: followed by the path of a SQLite database, or the path of a bbolt database.
+func Open(uri string) (Backend, error) {
+	switch {
+	case strings.HasPrefix(uri, "postgres://"), strings.HasPrefix(uri, "postgresql://"):
+		return OpenPostgres(uri)
+	case strings.HasPrefix(uri, "sqlite:"):
+		return OpenSQLite(strings.TrimPrefix(uri, "sqlite:"))
+	case uri == "":
+		return nil, errors.New("missing storage uri")
+	default:
+		return OpenBolt(strings.TrimPrefix(uri, "bolt:"))
+	}
+}
+
+// Prune truncates log before its first entry kept by keep, e.g. the first entry recorded
+// within the retention period of a feature. Entries are checked in the order of recording, all
+// are removed if none is kept.
+func Prune(ctx context.Context, b Backend, log string, keep func(Entry) (bool, error)) error {
+	var after uint64
+
+	for {
+		entries, err := b.Read(ctx, log, after, pruneReadPage)
+		if err != nil {
+			return err
+		}
+
+		var ok bool
+		for _, e := range entries {
+			if ok, err = keep(e); err != nil {
+				return err
+			}
+			if ok {
+				break
+			}
+			after = e.Seq
+		}
+
+		if ok || len(entries) < pruneReadPage {
+			if after == 0 {
+				// nothing to remove
+				return nil
+			}
+			return b.Truncate(ctx, log, after+1)
+		}
+	}
+}
+
+// pruneReadPage is the number of entries read at once when pruning
+const pruneReadPage = 100
+
+// Memory is a Backend keeping all values in memory, e.g. for tests and single-process
+// deployments without persistence.
+type Memory struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+	logs    map[string][]Entry
+	seqs    map[string]uint64 // last sequence number by log
+}
+
+var _ Backend = (*Memory)(nil)
+
+// NewMemory creates an empty in-memory backend.
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]map[string][]byte),
+		logs:    make(map[string][]Entry),
+		seqs:    make(map[string]uint64),
+	}
+}
+
+func (m *Memory) Get(_ context.Context, bucket, key string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	v, ok := m.buckets[bucket][key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return slices.Clone(v), nil
+}
+
+func (m *Memory) Put(_ context.Context, bucket, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.buckets[bucket] == nil {
+		m.buckets[bucket] = make(map[string][]byte)
+	}
+	m.buckets[bucket][key] = slices.Clone(value)
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, bucket, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.buckets[bucket], key)
+	return nil
+}
+
+func (m *Memory) Keys(_ context.Context, bucket string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]string, 0, len(m.buckets[bucket]))
+	for k := range m.buckets[bucket] {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func (m *Memory) Append(_ context.Context, log string, value []byte) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.seqs[log]++
+	m.logs[log] = append(m.logs[log], Entry{Seq: m.seqs[log], Value: slices.Clone(value)})
+	return m.seqs[log], nil
+}
+
+func (m *Memory) Read(_ context.Context, log string, after uint64, limit int) ([]Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := m.logs[log]
+	i, _ := slices.BinarySearchFunc(entries, after+1, func(e Entry, seq uint64) int { return cmp.Compare(e.Seq, seq) })
+
+	var res []Entry
+	for _, e := range entries[i:] {
+		if limit > 0 && len(res) == limit {
+			break
+		}
+		res = append(res, Entry{Seq: e.Seq, Value: slices.Clone(e.Value)})
+	}
+	return res, nil
+}
+
+func (m *Memory) Truncate(_ context.Context, log string, before uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := m.logs[log]
+	i, _ := slices.BinarySearchFunc(entries, before, func(e Entry, seq uint64) int { return cmp.Compare(e.Seq, seq) })
+	m.logs[log] = slices.Clone(entries[i:])
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,343 @@
+// Package store keeps the optimization requests and results of a client, so that past runs can
+// be inspected and replayed, e.g. when charging looked different yesterday:
+//
+//	cph, err := crypt.FromEnv()
+//	s := store.New(backend, store.WithCipher(cph))
+//	c, err := client.New(uri, client.WithMiddleware(s.Middleware(nil)))
+//	runs, err := s.List(ctx, time.Now().Add(-24*time.Hour))
+//	rep, err := s.Replay(ctx, other, runs[0].ID)
+//
+// Runs are kept in an append-only log of a storage backend, sealed by the cipher of WithCipher
+// and removed once older than the retention of WithRetention.
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evcc-io/optimizer/analysis"
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/clock"
+	"github.com/evcc-io/optimizer/crypt"
+	"github.com/evcc-io/optimizer/storage"
+)
+
+// runsLog is the log of the runs
+const runsLog = "runs"
+
+// readPage is the number of entries read at once when listing runs
+const readPage = 100
+
+// Run is a request and its outcome.
+type Run struct {
+	// ID is assigned when recording, starting at 1.
+	ID       uint64                   `json:"id"`
+	Time     time.Time                `json:"time"`
+	Duration time.Duration            `json:"duration"`
+	Request  client.OptimizationInput `json:"request"`
+	// Result is nil for failed requests.
+	Result *client.OptimizationResult `json:"result,omitempty"`
+	// StatusCode is zero if no response was received.
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Store records and replays runs. It is safe for concurrent use.
+type Store struct {
+	backend   storage.Backend
+	cipher    *crypt.Cipher
+	clock     clock.Clock
+	retention time.Duration
+}
+
+// Option configures a store.
+type Option func(*Store)
+
+// WithCipher seals the recorded runs, e.g. with the cipher of crypt.FromEnv. Runs recorded
+// before are read unchanged. Defaults to no encryption.
+func WithCipher(c *crypt.Cipher) Option {
+	return func(s *Store) {
+		s.cipher = c
+	}
+}
+
+// WithClock sets the clock timing the recorded runs. Defaults to the system clock.
+func WithClock(clk clock.Clock) Option {
+	return func(s *Store) {
+		s.clock = clk
+	}
+}
+
+// WithRetention removes runs recorded more than d ago when recording. Defaults to keeping all
+// runs.
+func WithRetention(d time.Duration) Option {
+	return func(s *Store) {
+		s.retention = d
+	}
+}
+
+// New creates a store keeping runs in backend.
+func New(backend storage.Backend, opts ...Option) *Store {
+	s := &Store{backend: backend, clock: clock.Real}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Record stores run and returns its id. The id of run is ignored.
+func (s *Store) Record(ctx context.Context, run Run) (uint64, error) {
+	run.ID = 0
+
+	b, err := json.Marshal(run)
+	if err != nil {
+		return 0, err
+	}
+
+	if b, err = s.cipher.Seal(b); err != nil {
+		return 0, err
+	}
+
+	id, err := s.backend.Append(ctx, runsLog, b)
+	if err != nil || s.retention <= 0 {
+		return id, err
+	}
+
+	return id, s.Prune(ctx, s.clock.Now().Add(-s.retention))
+}
+
+// Prune removes the runs recorded before before.
+func (s *Store) Prune(ctx context.Context, before time.Time) error {
+	return storage.Prune(ctx, s.backend, runsLog, func(e storage.Entry) (bool, error) {
+		run, err := s.decode(e)
+		return !run.Time.Before(before), err
+	})
+}
+
+func (s *Store) decode(e storage.Entry) (Run, error) {
+	b, err := s.cipher.Open(e.Value)
+	if err != nil {
+		return Run{}, fmt.Errorf("run %d: %w", e.Seq, err)
+	}
+
+	var run Run
+	if err := json.Unmarshal(b, &run); err != nil {
+		return Run{}, fmt.Errorf("run %d: %w", e.Seq, err)
+	}
+	run.ID = e.Seq
+	return run, nil
+}
+
+// Get returns the run id, storage.ErrNotFound if it does not exist.
+func (s *Store) Get(ctx context.Context, id uint64) (Run, error) {
+	if id == 0 {
+		return Run{}, fmt.Errorf("run %d: %w", id, storage.ErrNotFound)
+	}
+
+	entries, err := s.backend.Read(ctx, runsLog, id-1, 1)
+	if err != nil {
+		return Run{}, err
+	}
+	if len(entries) == 0 {
+		return Run{}, fmt.Errorf("run %d: %w", id, storage.ErrNotFound)
+	}
+
+	return s.decode(entries[0])
+}
+
+// List returns the runs recorded at or after since in the order of recording.
+func (s *Store) List(ctx context.Context, since time.Time) ([]Run, error) {
+	var (
+		res   []Run
+		after uint64
+	)
+
+	for {
+		entries, err := s.backend.Read(ctx, runsLog, after, readPage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			run, err := s.decode(e)
+			if err != nil {
+				return nil, err
+			}
+			if !run.Time.Before(since) {
+				res = append(res, run)
+			}
+			after = e.Seq
+		}
+
+		if len(entries) < readPage {
+			return res, nil
+		}
+	}
+}
+
+// Middleware records the synchronous optimizations sent by a client. Runs are recorded after
+// the response body has been read, failures to record are passed to onError if not nil and do
+// not fail the request. Requests compressed by other middleware are recorded decompressed.
+func (s *Store) Middleware(onError func(error)) client.Middleware {
+	return func(next client.HttpRequestDoer) client.HttpRequestDoer {
+		return client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/optimize/charge-schedule") {
+				return next.Do(req)
+			}
+
+			run := Run{Time: s.clock.Now()}
+
+			if req.Body != nil {
+				body, err := io.ReadAll(req.Body)
+				_ = req.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = io.NopCloser(bytes.NewReader(body))
+				req.GetBody = func() (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(body)), nil
+				}
+
+				// the request is recorded before encoding by other middleware
+				if err := decodeRequest(req.Header.Get("Content-Encoding"), body, &run.Request); err != nil {
+					report(onError, fmt.Errorf("record: request: %w", err))
+					return next.Do(req)
+				}
+			}
+
+			resp, err := next.Do(req)
+			if err == nil {
+				run.StatusCode = resp.StatusCode
+
+				var body []byte
+				body, err = io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+
+				switch {
+				case err != nil:
+				case resp.StatusCode == http.StatusOK:
+					var res client.OptimizationResult
+					if json.Unmarshal(body, &res) == nil {
+						run.Result = &res
+					}
+				default:
+					run.Error = strings.TrimSpace(string(body))
+				}
+			}
+
+			if err != nil {
+				run.Error = err.Error()
+			}
+			run.Duration = s.clock.Now().Sub(run.Time)
+
+			// the request may be cancelled once the response has been read
+			if _, recErr := s.Record(context.WithoutCancel(req.Context()), run); recErr != nil {
+				report(onError, fmt.Errorf("record: %w", recErr))
+			}
+
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		})
+	}
+}
+
+// decodeRequest decodes a request body sent with the content encoding
+func decodeRequest(encoding string, body []byte, req *client.OptimizationInput) error {
+	switch encoding {
+	case "":
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if body, err = io.ReadAll(zr); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	return json.Unmarshal(body, req)
+}
+
+func report(onError func(error), err error) {
+	if onError != nil {
+		onError(err)
+	}
+}
+
+// Replay is the outcome of replaying a run.
+type Replay struct {
+	Run Run
+	// Result of the replayed request, nil if it failed with Err.
+	Result *client.OptimizationResult
+	Err    error
+	Diff   Diff
+}
+
+// Diff compares the replayed result with the recorded one. It is zero if either result is
+// missing.
+type Diff struct {
+	StatusChanged bool
+	Objective     float64 // replayed minus recorded objective value
+	NetCost       float64 // replayed minus recorded import cost less export revenue [currency]
+	Import        float64 // replayed minus recorded grid import [Wh]
+	Export        float64 // replayed minus recorded grid export [Wh]
+	// MaxSoc is the largest absolute difference of a battery's state of charge [Wh].
+	MaxSoc float64
+}
+
+// Replay sends the request of run id using c, e.g. a client of a new optimizer version, and
+// compares the result with the recorded one. Failures of the replayed request are returned in
+// Replay.Err.
+func (s *Store) Replay(ctx context.Context, c *client.ClientWithResponses, id uint64) (Replay, error) {
+	run, err := s.Get(ctx, id)
+	if err != nil {
+		return Replay{}, err
+	}
+
+	rep := Replay{Run: run}
+	rep.Result, rep.Err = c.Solve(ctx, run.Request)
+
+	if rep.Result != nil && run.Result != nil {
+		rep.Diff = compare(run.Request, *run.Result, *rep.Result)
+	}
+
+	return rep, nil
+}
+
+// compare returns the difference of res to the recorded result prev
+func compare(req client.OptimizationInput, prev, res client.OptimizationResult) Diff {
+	d := Diff{
+		StatusChanged: prev.Status != res.Status,
+		Objective:     float64(res.ObjectiveValue - prev.ObjectiveValue),
+	}
+
+	if a, err := analysis.Analyze(req, prev); err == nil {
+		if b, err := analysis.Analyze(req, res); err == nil {
+			d.NetCost = (b.ImportCost - b.ExportRevenue) - (a.ImportCost - a.ExportRevenue)
+			d.Import = b.Import - a.Import
+			d.Export = b.Export - a.Export
+		}
+	}
+
+	for i := range min(len(prev.Batteries), len(res.Batteries)) {
+		p, r := prev.Batteries[i].StateOfCharge, res.Batteries[i].StateOfCharge
+		for t := range min(len(p), len(r)) {
+			d.MaxSoc = max(d.MaxSoc, math.Abs(float64(r[t]-p[t])))
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,34 @@
+package tariff
+
+import "time"
+
+// HorizonConfig configures the dynamic optimization horizon.
+type HorizonConfig struct {
+	// Tail is the estimated period appended after the last known rate, e.g. to bridge
+	// until the next price publication.
+	Tail time.Duration
+	// Min is the minimum horizon length.
+	Min time.Duration
+	// Max is the maximum horizon length. Zero means unlimited.
+	Max time.Duration
+}
+
+// Horizon returns the end of the optimization horizon: the end of the known rates plus
+// the configured estimated tail, bounded by the configured minimum and maximum length.
+// This avoids both truncated evenings when tomorrow's prices are not yet published and
+// over-long uncertain tails when they are.
+func Horizon(now time.Time, rates Rates, cfg HorizonConfig) time.Time {
+	end := rates.KnownUntil(now).Add(cfg.Tail)
+
+	if minEnd := now.Add(cfg.Min); end.Before(minEnd) {
+		end = minEnd
+	}
+
+	if cfg.Max > 0 {
+		if maxEnd := now.Add(cfg.Max); end.After(maxEnd) {
+			end = maxEnd
+		}
+	}
+
+	return end
+}
@@ -0,0 +1,105 @@
+package tariff
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Interpolation returns the price of the part [start, end) of rate r, e.g. Step or Linear.
+// prev and next are the adjacent rates if they are contiguous with r, nil otherwise.
+type Interpolation func(r Rate, prev, next *Rate, start, end time.Time) float64
+
+// Step keeps the price of the rate for all its parts.
+func Step(r Rate, _, _ *Rate, _, _ time.Time) float64 {
+	return r.Price
+}
+
+// Linear interpolates linearly between the prices at the centers of adjacent rates, e.g. for
+// smooth quarter hourly prices from hourly feeds. Parts take the interpolated price at their
+// center, rates without contiguous neighbour keep their price towards the gap. The mean price of
+// a rate is not preserved.
+func Linear(r Rate, prev, next *Rate, start, end time.Time) float64 {
+	at := start.Add(end.Sub(start) / 2)
+	center := r.Start.Add(r.End.Sub(r.Start) / 2)
+
+	other := next
+	if at.Before(center) {
+		other = prev
+	}
+	if other == nil || at.Equal(center) {
+		return r.Price
+	}
+
+	oc := other.Start.Add(other.End.Sub(other.Start) / 2)
+	frac := float64(at.Sub(center)) / float64(oc.Sub(center))
+
+	return r.Price + frac*(other.Price-r.Price)
+}
+
+// Overlay takes the prices of known finer rates where they cover a part completely, e.g.
+// quarter hourly prices published alongside an hourly feed, and interp elsewhere.
+func Overlay(known Rates, interp Interpolation) Interpolation {
+	known = slices.Clone(known)
+	known.Sort()
+
+	return func(r Rate, prev, next *Rate, start, end time.Time) float64 {
+		for _, k := range known {
+			if !k.Start.After(start) && !k.End.Before(end) {
+				return k.Price
+			}
+		}
+		return interp(r, prev, next, start, end)
+	}
+}
+
+// ParseInterpolation returns the interpolation of the given name, step or linear, e.g. for
+// configuration files.
+func ParseInterpolation(name string) (Interpolation, error) {
+	switch name {
+	case "", "step":
+		return Step, nil
+	case "linear":
+		return Linear, nil
+	default:
+		return nil, fmt.Errorf("invalid interpolation %q, expected step or linear", name)
+	}
+}
+
+// Resample splits rates longer than step into parts of at most step, aligned to multiples of
+// step since the zero time like the intervals of timeseries.Grid, and prices them by interp.
+// Rates not longer than step are kept, so that mixed resolutions, e.g. hourly rates until a
+// market moves to quarter hourly settlement and quarter hourly rates after, result in rates of
+// at most step throughout. The result is sorted.
+func (r Rates) Resample(step time.Duration, interp Interpolation) Rates {
+	rates := slices.Clone(r)
+	rates.Sort()
+
+	var res Rates
+	for i, rate := range rates {
+		if rate.End.Sub(rate.Start) <= step {
+			res = append(res, rate)
+			continue
+		}
+
+		var prev, next *Rate
+		if i > 0 && rates[i-1].End.Equal(rate.Start) {
+			prev = &rates[i-1]
+		}
+		if i+1 < len(rates) && rates[i+1].Start.Equal(rate.End) {
+			next = &rates[i+1]
+		}
+
+		for start := rate.Start; start.Before(rate.End); {
+			end := start.Truncate(step).Add(step)
+			if end.After(rate.End) {
+				end = rate.End
+			}
+
+			res = append(res, Rate{Start: start, End: end, Price: interp(rate, prev, next, start, end)})
+			start = end
+		}
+	}
+
+	return res
+}
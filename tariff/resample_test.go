@@ -0,0 +1,100 @@
+package tariff
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// day returns hourly rates until noon and quarter hourly rates after, priced by their index
+func day() Rates {
+	start := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	var rates Rates
+	for h := range 12 {
+		rates = append(rates, Rate{Start: start.Add(time.Duration(h) * time.Hour), End: start.Add(time.Duration(h+1) * time.Hour), Price: float64(h)})
+	}
+	noon := start.Add(12 * time.Hour)
+	for q := range 48 {
+		rates = append(rates, Rate{Start: noon.Add(time.Duration(q) * 15 * time.Minute), End: noon.Add(time.Duration(q+1) * 15 * time.Minute), Price: float64(100 + q)})
+	}
+
+	// resampling sorts
+	rates[0], rates[len(rates)-1] = rates[len(rates)-1], rates[0]
+	return rates
+}
+
+func TestResampleStep(t *testing.T) {
+	res := day().Resample(15*time.Minute, Step)
+
+	if len(res) != 96 {
+		t.Fatalf("expected 96 rates, got %d", len(res))
+	}
+
+	for i, r := range res {
+		if d := r.End.Sub(r.Start); d != 15*time.Minute {
+			t.Errorf("rate %d: expected 15m, got %v", i, d)
+		}
+		if i > 0 && !res[i-1].End.Equal(r.Start) {
+			t.Errorf("rate %d: not contiguous", i)
+		}
+
+		expected := float64(i / 4)
+		if i >= 48 {
+			expected = float64(100 + i - 48)
+		}
+		if r.Price != expected {
+			t.Errorf("rate %d: expected price %v, got %v", i, expected, r.Price)
+		}
+	}
+}
+
+func TestResampleLinearBoundary(t *testing.T) {
+	res := day().Resample(15*time.Minute, Linear)
+
+	if len(res) != 96 {
+		t.Fatalf("expected 96 rates, got %d", len(res))
+	}
+
+	for _, tc := range []struct {
+		i        int
+		expected float64
+	}{
+		// the first hour has no predecessor and keeps its price towards the start of the day
+		{0, 0},
+		{1, 0},
+		// parts of the second hour are interpolated at their centers, 01:22:30 being 7.5 of 60
+		// minutes from 01:30 towards 00:30
+		{5, 1 - 0.125},
+		{6, 1 + 0.125},
+		// the last part of the last hourly rate is interpolated towards the first quarter hour,
+		// its center 11:52:30 being 22.5 of 37.5 minutes from 11:30 to 12:07:30
+		{46, 11 + (100-11)*7.5/37.5},
+		{47, 11 + (100-11)*22.5/37.5},
+		// quarter hourly rates are kept
+		{48, 100},
+		{95, 147},
+	} {
+		if r := res[tc.i]; math.Abs(r.Price-tc.expected) > 1e-9 {
+			t.Errorf("rate %d at %s: expected price %v, got %v", tc.i, r.Start.Format("15:04"), tc.expected, r.Price)
+		}
+	}
+}
+
+func TestResampleUnaligned(t *testing.T) {
+	start := time.Date(2025, 10, 1, 0, 10, 0, 0, time.UTC)
+	rates := Rates{{Start: start, End: start.Add(time.Hour), Price: 1}}
+
+	res := rates.Resample(15*time.Minute, Step)
+
+	// parts are aligned to the step, the first and last part are shorter
+	expected := []time.Duration{5 * time.Minute, 15 * time.Minute, 15 * time.Minute, 15 * time.Minute, 10 * time.Minute}
+	if len(res) != len(expected) {
+		t.Fatalf("expected %d rates, got %d", len(expected), len(res))
+	}
+	for i, r := range res {
+		if d := r.End.Sub(r.Start); d != expected[i] {
+			t.Errorf("rate %d: expected %v, got %v", i, expected[i], d)
+		}
+	}
+}
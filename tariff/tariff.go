@@ -0,0 +1,66 @@
+// Package tariff provides helpers for turning published tariff rates into optimizer inputs.
+package tariff
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Rate is a price valid for the interval [Start, End).
+type Rate struct {
+	Start time.Time
+	End   time.Time
+	Price float64
+}
+
+// Rates is a list of rates.
+type Rates []Rate
+
+// Sort sorts the rates by start time.
+func (r Rates) Sort() {
+	slices.SortFunc(r, func(a, b Rate) int {
+		return a.Start.Compare(b.Start)
+	})
+}
+
+// KnownUntil returns the end of the contiguous block of known rates starting at now.
+// It returns now if no rate covers now.
+func (r Rates) KnownUntil(now time.Time) time.Time {
+	rates := slices.Clone(r)
+	rates.Sort()
+
+	end := now
+	for _, rate := range rates {
+		if !rate.End.After(end) {
+			continue
+		}
+		if rate.Start.After(end) {
+			// gap in published rates
+			break
+		}
+		end = rate.End
+	}
+
+	return end
+}
+
+// Series returns the prices of consecutive intervals of dt seconds starting at start, e.g. for
+// the p_N or p_E time series. Intervals take the price of the rate covering their start.
+func (r Rates) Series(start time.Time, dt []int) ([]float32, error) {
+	res := make([]float32, len(dt))
+
+	ts := start
+	for t, d := range dt {
+		i := slices.IndexFunc(r, func(rate Rate) bool {
+			return !ts.Before(rate.Start) && ts.Before(rate.End)
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("no rate at %s", ts.Format(time.RFC3339))
+		}
+		res[t] = float32(r[i].Price)
+		ts = ts.Add(time.Duration(d) * time.Second)
+	}
+
+	return res, nil
+}
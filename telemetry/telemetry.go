@@ -0,0 +1,351 @@
+// Package telemetry collects anonymized statistics of optimizations, so that maintainers can
+// prioritize solver work by the distribution of real problems. Collection is opt-in, only
+// clients using the middleware of a reporter are observed:
+//
+//	r := telemetry.New(telemetry.Config{File: "telemetry.jsonl", Endpoint: url})
+//	c, err := client.New(uri, client.WithMiddleware(r.Middleware()))
+//	go r.Run(ctx, time.Hour)
+//
+// Statistics describe the size and outcome of problems only. Series, prices, limits and ids of
+// requests and results are never recorded, times are truncated to the hour.
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/clock"
+)
+
+// Stats are the anonymized statistics of an optimization.
+type Stats struct {
+	Time      time.Time `json:"time"` // truncated to the hour
+	Intervals int       `json:"intervals"`
+	Batteries int       `json:"batteries"`
+	Horizon   int       `json:"horizon"` // [s]
+	// StatusCode of the response, zero if no response was received.
+	StatusCode int `json:"status_code,omitempty"`
+	// Status of the result, empty for failed requests.
+	Status client.OptimizationResultStatus `json:"status,omitempty"`
+	// MipGap and TimeLimit [s] are the requested solver settings, zero for the defaults.
+	MipGap    float64 `json:"mip_gap,omitempty"`
+	TimeLimit float64 `json:"time_limit,omitempty"`
+	// OptimalityLoss of approximate solutions, Simplified if the solve timed out.
+	OptimalityLoss float64 `json:"optimality_loss,omitempty"`
+	Simplified     bool    `json:"simplified,omitempty"`
+	// SolveTime is the time spent building and solving the model as reported by the server,
+	// Duration the duration of the request.
+	SolveTime time.Duration `json:"solve_time,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// NewStats returns the statistics of req sent at now and its result res, which is nil for failed
+// requests.
+func NewStats(now time.Time, req client.OptimizationInput, res *client.OptimizationResult) Stats {
+	s := Stats{
+		Time:      now.UTC().Truncate(time.Hour),
+		Intervals: len(req.TimeSeries.Dt),
+		Batteries: len(req.Batteries),
+		MipGap:    float64(req.MipGap),
+		TimeLimit: float64(req.TimeLimit),
+	}
+
+	for _, dt := range req.TimeSeries.Dt {
+		s.Horizon += dt
+	}
+
+	if res != nil {
+		s.Status = res.Status
+		s.OptimalityLoss = float64(res.OptimalityLoss)
+		s.Simplified = res.Simplified
+		s.SolveTime = time.Duration(float64(res.LatencyMs) * float64(time.Millisecond))
+	}
+
+	return s
+}
+
+// Config configures a reporter.
+type Config struct {
+	// File keeps all statistics as JSON lines for the local summary, not kept if empty.
+	File string
+	// Endpoint receives the statistics as JSON, not submitted if empty.
+	Endpoint string
+	// HTTPClient submits the statistics. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+	// OnError receives failures to record or submit, if not nil. They never fail requests.
+	OnError func(error)
+	// Clock times the requests. Defaults to the system clock.
+	Clock clock.Clock
+}
+
+// Reporter records statistics and submits them in batches. It is safe for concurrent use.
+type Reporter struct {
+	cfg Config
+
+	mu      sync.Mutex
+	pending []Stats
+}
+
+// New creates a reporter.
+func New(cfg Config) *Reporter {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real
+	}
+	return &Reporter{cfg: cfg}
+}
+
+func (r *Reporter) report(err error) {
+	if r.cfg.OnError != nil {
+		r.cfg.OnError(err)
+	}
+}
+
+// Record keeps s in the file and for the next submission.
+func (r *Reporter) Record(s Stats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cfg.File != "" {
+		if err := appendFile(r.cfg.File, s); err != nil {
+			r.report(fmt.Errorf("telemetry: %w", err))
+		}
+	}
+
+	if r.cfg.Endpoint != "" {
+		r.pending = append(r.pending, s)
+	}
+}
+
+func appendFile(path string, s Stats) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(b, '\n'))
+	return errors.Join(err, f.Close())
+}
+
+// Middleware records the statistics of the synchronous optimizations sent by a client.
+func (r *Reporter) Middleware() client.Middleware {
+	return func(next client.HttpRequestDoer) client.HttpRequestDoer {
+		return client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodPost || req.Body == nil || !strings.HasSuffix(req.URL.Path, "/optimize/charge-schedule") {
+				return next.Do(req)
+			}
+
+			body, err := io.ReadAll(req.Body)
+			_ = req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+
+			var in client.OptimizationInput
+			if err := json.Unmarshal(body, &in); err != nil {
+				return next.Do(req)
+			}
+
+			start := r.cfg.Clock.Now()
+			resp, err := next.Do(req)
+			if err != nil {
+				s := NewStats(start, in, nil)
+				s.Duration = r.cfg.Clock.Now().Sub(start)
+				r.Record(s)
+				return nil, err
+			}
+
+			b, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+
+			var res *client.OptimizationResult
+			if resp.StatusCode == http.StatusOK {
+				var out client.OptimizationResult
+				if json.Unmarshal(b, &out) == nil {
+					res = &out
+				}
+			}
+
+			s := NewStats(start, in, res)
+			s.StatusCode = resp.StatusCode
+			s.Duration = r.cfg.Clock.Now().Sub(start)
+			r.Record(s)
+
+			return resp, nil
+		})
+	}
+}
+
+// Flush submits the pending statistics to the endpoint. Statistics failing to submit are kept
+// for the next flush.
+func (r *Reporter) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := r.submit(ctx, pending)
+	if err != nil {
+		r.mu.Lock()
+		r.pending = append(pending, r.pending...)
+		r.mu.Unlock()
+	}
+
+	return err
+}
+
+func (r *Reporter) submit(ctx context.Context, stats []Stats) error {
+	b, err := json.Marshal(map[string]any{"stats": stats})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry: %s", resp.Status)
+	}
+	return nil
+}
+
+// Run flushes periodically until ctx is cancelled. Failures are passed to OnError.
+func (r *Reporter) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				r.report(err)
+			}
+		}
+	}
+}
+
+// Load reads the statistics recorded in file.
+func Load(file string) ([]Stats, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var res []Stats
+
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+
+		var s Stats
+		if err := json.Unmarshal(sc.Bytes(), &s); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, line, err)
+		}
+		res = append(res, s)
+	}
+
+	return res, sc.Err()
+}
+
+// Summary summarizes statistics.
+type Summary struct {
+	Count  int
+	Failed int // requests without result
+	Status map[client.OptimizationResultStatus]int
+	// Quantiles of the sizes and durations of all requests.
+	Intervals, Batteries Quantiles[int]
+	Duration, SolveTime  Quantiles[time.Duration]
+}
+
+// Quantiles are the median, 95th percentile and maximum of values.
+type Quantiles[T int | time.Duration] struct {
+	P50, P95, Max T
+}
+
+func quantiles[T int | time.Duration](values []T) Quantiles[T] {
+	if len(values) == 0 {
+		return Quantiles[T]{}
+	}
+
+	slices.Sort(values)
+	at := func(q float64) T {
+		return values[int(q*float64(len(values)-1))]
+	}
+
+	return Quantiles[T]{P50: at(0.5), P95: at(0.95), Max: values[len(values)-1]}
+}
+
+// Summarize summarizes stats. Solve times are summarized over results reporting them.
+func Summarize(stats []Stats) Summary {
+	res := Summary{Count: len(stats), Status: make(map[client.OptimizationResultStatus]int)}
+
+	var (
+		intervals, batteries []int
+		duration, solveTime  []time.Duration
+	)
+
+	for _, s := range stats {
+		if s.Status == "" {
+			res.Failed++
+		} else {
+			res.Status[s.Status]++
+		}
+
+		intervals = append(intervals, s.Intervals)
+		batteries = append(batteries, s.Batteries)
+		duration = append(duration, s.Duration)
+		if s.SolveTime > 0 {
+			solveTime = append(solveTime, s.SolveTime)
+		}
+	}
+
+	res.Intervals, res.Batteries = quantiles(intervals), quantiles(batteries)
+	res.Duration, res.SolveTime = quantiles(duration), quantiles(solveTime)
+
+	return res
+}
@@ -1,11 +1,23 @@
 
+import copy
+import gzip
 import json
 import pathlib
+import threading
+import time
 
 import numpy
+import pulp
 import pytest
 
+import optimizer.app
 from optimizer.app import app
+from optimizer.audit import AuditLog, digest
+from optimizer.canonical import canonical_json
+from optimizer.compression import round_conserving, round_series
+from optimizer.jobs import JobLimitExceeded, JobRunner, JobStore
+from optimizer.optimizer import BatteryConfig, Optimizer, goal_weights
+from optimizer.settings import OptimizerSettings
 
 
 @pytest.mark.parametrize('test_case', pathlib.Path('test_cases').glob('*.json'))
@@ -34,3 +46,1295 @@ def test_optimizer(test_case: pathlib.Path):
                              expected_objective_value,
                              rtol=1e-05, atol=1e-08, equal_nan=False), \
             f"objective value: {actual_objective_value}, expected was: {expected_objective_value}"
+
+
+def test_battery_priority_order():
+    """Cost neutral surplus must go to batteries in order of their priority."""
+    client = app.test_client()
+
+    test_data = json.loads(pathlib.Path('test_cases/024-battery-priority-order.json').read_text())
+
+    response = client.post("/optimize/charge-schedule", json=test_data["request"])
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+
+    for actual, expected in zip(response.json["batteries"], test_data["expected_response"]["batteries"]):
+        assert numpy.allclose(actual["charging_power"], expected["charging_power"], rtol=1e-05, atol=1e-03), \
+            f"charging power: {actual['charging_power']}, expected was: {expected['charging_power']}"
+
+
+@pytest.mark.parametrize('curve', [
+    [{"power": 1000, "eta": 0}],
+    [{"power": 0, "eta": 0.9}],
+])
+def test_invalid_efficiency_curve(curve):
+    client = app.test_client()
+
+    request = json.loads(pathlib.Path('test_cases/022-power-dependent-efficiency.json').read_text())["request"]
+    request["batteries"][0]["c_eta_curve"] = curve
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+@pytest.mark.parametrize('grid', [
+    {"e_imp_tier": 250000},
+    {"prc_e_exc_tier": 0.0001},
+    {"t_tier_reset": 2},
+])
+def test_incomplete_tiered_tariff(grid):
+    client = app.test_client()
+
+    request = json.loads(pathlib.Path('test_cases/023-tiered-import-tariff.json').read_text())["request"]
+    request["grid"] = grid
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+@pytest.mark.parametrize('cost_budget, exceeded', [
+    (2.1, False),
+    # discharging the full battery only reduces the cost to 2.02
+    (1.0, True),
+])
+def test_cost_budget(cost_budget, exceeded):
+    client = app.test_client()
+
+    request = json.loads(pathlib.Path('test_cases/029-cost-budget.json').read_text())["request"]
+    request["cost_budget"] = cost_budget
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["limit_violations"]["cost_budget_exceeded"] == exceeded
+
+
+def test_negative_load():
+    """Negative demand must behave like the same amount of generation."""
+    client = app.test_client()
+
+    request = json.loads(pathlib.Path('test_cases/022-power-dependent-efficiency.json').read_text())["request"]
+    request["time_series"]["gt"][0] -= 5000
+
+    shifted = json.loads(pathlib.Path('test_cases/022-power-dependent-efficiency.json').read_text())["request"]
+    shifted["time_series"]["ft"][0] += 5000
+
+    response = client.post("/optimize/charge-schedule", json=request)
+    expected = client.post("/optimize/charge-schedule", json=shifted)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert expected.status_code == 200, f"shifted request returned with status {expected.status_code}"
+    assert response.json["status"] == "Optimal"
+    assert expected.json["status"] == "Optimal"
+    assert numpy.isclose(response.json["objective_value"], expected.json["objective_value"], rtol=1e-05, atol=1e-08)
+    assert numpy.allclose(response.json["grid_export"], expected.json["grid_export"], rtol=1e-05, atol=1e-03)
+
+
+def test_negative_generation():
+    client = app.test_client()
+
+    request = json.loads(pathlib.Path('test_cases/030-negative-load.json').read_text())["request"]
+    request["time_series"]["ft"][0] = -1000
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_disabled_battery():
+    """Disabled batteries keep their index with zeroed series and do not affect the result."""
+    client = app.test_client()
+
+    test_data = json.loads(pathlib.Path('test_cases/024-battery-priority-order.json').read_text())
+
+    request = test_data["request"]
+    disabled = dict(request["batteries"][0], id="disabled", enabled=False)
+    request["batteries"].insert(0, disabled)
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert len(response.json["batteries"]) == len(request["batteries"])
+
+    for series in response.json["batteries"][0].values():
+        assert not any(series)
+
+    for actual, expected in zip(response.json["batteries"][1:], test_data["expected_response"]["batteries"]):
+        assert numpy.allclose(actual["charging_power"], expected["charging_power"], rtol=1e-05, atol=1e-03), \
+            f"charging power: {actual['charging_power']}, expected was: {expected['charging_power']}"
+
+
+def test_charging_efficiency_series():
+    """A charging efficiency series must be applied per time step."""
+    client = app.test_client()
+
+    request = json.loads(pathlib.Path('test_cases/030-negative-load.json').read_text())["request"]
+    battery = request["batteries"][0]
+    battery.update(s_max=10000, s_initial=0, c_max=1000, p_a=0.001, charge_from_grid=True, c_eta_series=[3, 2])
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+
+    result = response.json["batteries"][0]
+    for t, eta in enumerate(battery["c_eta_series"]):
+        soc = result["state_of_charge"][t] - (result["state_of_charge"][t - 1] if t > 0 else 0)
+        assert numpy.isclose(soc, eta * result["charging_power"][t], rtol=1e-05, atol=1e-03)
+
+
+def test_emissions_objective():
+    client = app.test_client()
+
+    request = json.loads(pathlib.Path('test_cases/030-negative-load.json').read_text())["request"]
+    request["strategy"] = {"objective": "emissions"}
+
+    response = client.post("/optimize/charge-schedule", json=request)
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+    # 2000 Wh imported at 0.4 gCO2/Wh
+    request["time_series"]["em_N"] = [0.2, 0.4]
+
+    response = client.post("/optimize/charge-schedule", json=request)
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["objective_unit"] == "gCO2"
+    assert numpy.isclose(response.json["objective_value"], -800)
+
+
+def test_energy_objective_goal():
+    """Reachable goals are met under the energy objective, its penalties exceed the import energy."""
+    client = app.test_client()
+
+    request = {
+        "strategy": {"objective": "energy"},
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 10000, "s_initial": 0, "c_min": 0, "c_max": 5000, "d_max": 0, "p_a": 0,
+                       "charge_from_grid": True, "s_goal": [0, 4000]}],
+        "eta_c": 1,
+        "eta_d": 1,
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [0, 0],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0, 0],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    battery = response.json["batteries"][0]
+    assert numpy.isclose(battery["state_of_charge"][1], 4000, atol=1e-03)
+    assert battery["goal_shortfalls"] == []
+
+
+def test_export_cap_negative_price():
+    """Export at negative prices is billed even when the remunerated export is capped."""
+    client = app.test_client()
+
+    request = {
+        "grid": {"e_exp_cap": 1000, "e_exp_to_date": 1000},
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 0, "c_min": 0, "c_max": 0, "d_max": 0, "p_a": 0}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [0, 0],
+            "ft": [1000, 1000],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [-0.0001, 0.0001],
+        },
+    }
+
+    for path in ("/optimize/charge-schedule", "/optimize/simulate"):
+        response = client.post(path, json=request)
+
+        assert response.status_code == 200, f"request returned with status {response.status_code}"
+        # the cap is used up, the export at the negative price is billed
+        assert numpy.isclose(response.json["objective_value"], -0.1, atol=1e-06), path
+
+
+def test_max_latency():
+    """Rounding must recover the optimum if the only integer decisions are the grid flow directions."""
+    client = app.test_client()
+
+    test_data = json.loads(pathlib.Path('test_cases/030-negative-load.json').read_text())
+    request = test_data["request"]
+    request["max_latency_ms"] = 500
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["status"] == "Optimal"
+    assert numpy.isclose(response.json["objective_value"], test_data["expected_response"]["objective_value"])
+    assert response.json["optimality_loss"] is not None
+    assert response.json["latency_ms"] > 0
+
+
+def test_max_latency_used_up(monkeypatch):
+    """The solver is not started once the latency is used up."""
+    client = app.test_client()
+
+    def solve(*args):
+        raise AssertionError("solver started")
+
+    monkeypatch.setattr(Optimizer, "_solve", solve)
+
+    request = json.loads(pathlib.Path('test_cases/030-negative-load.json').read_text())["request"]
+    request["max_latency_ms"] = 0
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["status"] == "Not Solved"
+    assert response.json["batteries"] == []
+
+
+def test_simplify_on_timeout(monkeypatch):
+    """A solve timing out without a solution is approximated within the rest of the time limit."""
+    client = app.test_client()
+
+    solve = Optimizer._solve
+    limits = []
+
+    def timeout(self, time_limit):
+        limits.append(time_limit)
+        if len(limits) == 1:
+            # the MILP solve hits the time limit without a solution
+            self.problem.status = pulp.LpStatusNotSolved
+            return
+        solve(self, time_limit)
+
+    monkeypatch.setattr(Optimizer, "_solve", timeout)
+
+    test_data = json.loads(pathlib.Path('test_cases/030-negative-load.json').read_text())
+    request = test_data["request"]
+    request["time_limit"] = 10
+    request["simplify_on_timeout"] = True
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["status"] == "Optimal"
+    assert response.json["simplified"]
+    assert numpy.isclose(response.json["objective_value"], test_data["expected_response"]["objective_value"])
+
+    # the relaxation and the fixed LP share the rest of the time limit
+    assert len(limits) == 3
+    assert limits[0] == 8
+    assert all(limit <= 2 for limit in limits[1:])
+
+
+def test_solver_settings(monkeypatch):
+    """Time limits are capped by the server, solutions found within the time limit are returned unless optimal ones are required."""
+    monkeypatch.setenv("OPTIMIZER_TIME_LIMIT", "5")
+    client = app.test_client()
+
+    solve = Optimizer._solve
+    limits = []
+
+    def feasible(self, time_limit):
+        limits.append(time_limit)
+        solve(self, time_limit)
+        # the solve hits the time limit with a solution
+        self.problem.sol_status = pulp.LpSolutionIntegerFeasible
+
+    monkeypatch.setattr(Optimizer, "_solve", feasible)
+
+    request = json.loads(pathlib.Path('test_cases/030-negative-load.json').read_text())["request"]
+    request["time_limit"] = 60
+    request["mip_gap"] = 0.01
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["status"] == "Optimal"
+    assert limits == [5]
+
+    request["require_optimal"] = True
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["status"] == "Not Solved"
+
+    request["simplify_on_timeout"] = True
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["status"] == "Optimal"
+    assert response.json["simplified"]
+
+
+@pytest.mark.parametrize('r_curt, charged', [
+    ([0, 0], False),
+    # expected export revenue falls below the value of stored energy
+    ([0.8, 0], True),
+])
+def test_curtailment_risk(r_curt, charged):
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 0, "c_min": 0, "c_max": 1000, "d_max": 0, "p_a": 0.0001}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [0, 0],
+            "ft": [1000, 0],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0.0002, 0.0002],
+            "r_curt": r_curt,
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["curtailment_risk"] == [r >= 0.5 for r in r_curt]
+    assert numpy.isclose(response.json["batteries"][0]["charging_power"][0], 1000 if charged else 0, atol=1e-03)
+
+
+def test_battery_ids():
+    client = app.test_client()
+
+    request = json.loads(pathlib.Path('test_cases/024-battery-priority-order.json').read_text())["request"]
+    ids = [battery["id"] for battery in request["batteries"]]
+    names = [battery["name"] for battery in request["batteries"]]
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert [b["id"] for b in response.json["batteries"]] == ids
+    assert [b["name"] for b in response.json["batteries"]] == names
+
+    duplicate = copy.deepcopy(request)
+    duplicate["batteries"][1]["id"] = ids[0]
+
+    response = client.post("/optimize/charge-schedule", json=duplicate)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+    for field in ["id", "name"]:
+        missing = copy.deepcopy(request)
+        del missing["batteries"][0][field]
+
+        response = client.post("/optimize/charge-schedule", json=missing)
+
+        assert response.status_code == 400, f"request without {field} returned with status {response.status_code}"
+
+
+def test_export_preference():
+    """Export of otherwise indifferent timing moves to the preferred interval."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 1000, "c_min": 0, "c_max": 0, "d_max": 1000, "p_a": 0,
+                       "discharge_to_grid": True}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [0, 0],
+            "ft": [0, 0],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0.0001, 0.0001],
+            "w_E": [0, 0.00005],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.allclose(response.json["grid_export"], [0, 950], atol=1e-03)
+    assert numpy.isclose(response.json["export_preference_score"], 950 * 0.00005)
+
+
+def test_low_bandwidth():
+    """Compressed requests and responses with reduced result fields and precision."""
+    client = app.test_client()
+
+    request = json.loads(pathlib.Path('test_cases/024-battery-priority-order.json').read_text())["request"]
+    request["output"] = {"precision": 0, "fields": ["batteries", "grid_import"]}
+
+    response = client.post("/optimize/charge-schedule", data=gzip.compress(json.dumps(request).encode()),
+                           headers={"Content-Type": "application/json", "Content-Encoding": "gzip",
+                                    "Accept-Encoding": "gzip"})
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+
+    result = json.loads(gzip.decompress(response.data)) if response.headers.get("Content-Encoding") == "gzip" else response.json
+    assert set(result.keys()) == {"status", "batteries", "grid_import"}
+    assert all(isinstance(v, int) for v in result["grid_import"])
+    assert all(isinstance(v, int) for v in result["batteries"][0]["state_of_charge"])
+
+    response = client.post("/optimize/charge-schedule", data=b"not gzip",
+                           headers={"Content-Type": "application/json", "Content-Encoding": "gzip"})
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_deviation_pricing():
+    """Deviations from the committed schedule are minimized and reported."""
+    client = app.test_client()
+
+    request = json.loads(pathlib.Path('test_cases/033-deviation-pricing.json').read_text())["request"]
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.allclose(response.json["grid_deviation"], [50, 0], atol=1e-03)
+
+    del request["grid"]
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_initial_soc_uncertainty():
+    """Near-term state of charge bounds are tightened by the initial state of charge uncertainty."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 500, "s_max": 2000, "s_initial": 1000, "c_min": 0, "c_max": 0, "d_max": 1000, "p_a": 0,
+                       "s_initial_stddev": 100}],
+        "time_series": {
+            "dt": [3600],
+            "gt": [1000],
+            "ft": [0],
+            "p_N": [0.0003],
+            "p_E": [0.0001],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    # margin after one of four hours: 2 * 100 Wh * 3/4
+    assert numpy.isclose(response.json["batteries"][0]["state_of_charge"][0], 650, atol=1e-03)
+
+
+def test_availability():
+    """Unavailable batteries have zero power, unreachable goals are rejected."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 2000, "s_initial": 1000, "c_min": 0, "c_max": 1000, "d_max": 1000, "p_a": 0,
+                       "available": [False, True]}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [1000, 1000],
+            "ft": [0, 0],
+            "p_N": [0.0003, 0.0001],
+            "p_E": [0.0001, 0.0001],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["batteries"][0]["discharging_power"][0] == 0
+    assert response.json["batteries"][0]["discharging_power"][1] > 0
+
+    request["batteries"][0]["s_goal"] = [1500, 0]
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_strategies():
+    """Strategy descriptions cover all supported objectives."""
+    client = app.test_client()
+
+    response = client.get("/optimize/strategies")
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    strategies = {s["parameter"]: s for s in response.json}
+    assert set(strategies) == {"charging_strategy", "discharging_strategy", "objective", "spike_guard_hours"}
+    assert [v["value"] for v in strategies["objective"]["values"]] == ["cost", "energy", "emissions"]
+
+
+def test_spike_guard():
+    """Energy for the most expensive hour is kept even if exporting it earlier pays more."""
+    client = app.test_client()
+
+    request = {
+        "strategy": {"spike_guard_hours": 1},
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 1000, "c_min": 0, "c_max": 0, "d_max": 1000, "p_a": 0,
+                       "discharge_to_grid": True}],
+        "time_series": {
+            "dt": [3600, 3600, 3600],
+            "gt": [0, 0, 500],
+            "ft": [0, 0, 0],
+            "p_N": [0.0003, 0.0003, 0.0005],
+            "p_E": [0.00055, 0.0001, 0.0001],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.isclose(response.json["batteries"][0]["state_of_charge"][1], 500 / 0.95, atol=1e-03)
+    assert numpy.isclose(response.json["grid_import"][2], 0, atol=1e-03)
+
+
+def test_battery_attribution():
+    """Batteries report the objective value lost without them, disabled batteries none."""
+    client = app.test_client()
+
+    battery = {"name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 0, "c_min": 0, "c_max": 1000, "d_max": 1000,
+               "p_a": 0, "charge_from_grid": True}
+    request = {
+        "attribute_batteries": True,
+        "batteries": [dict(battery, id="a"), dict(battery, id="b", enabled=False)],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [0, 1000],
+            "p_N": [0.0001, 0.0003],
+            "p_E": [0, 0],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.isclose(response.json["batteries"][0]["contribution"], 0.17075, atol=1e-04)
+    assert response.json["batteries"][1]["contribution"] is None
+
+
+def test_validate():
+    """Validation fills in defaults and warns about unreachable goals without solving."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 500, "c_min": 0, "c_max": 1000, "d_max": 1000, "p_a": 0,
+                       "s_goal": [0, 1200]}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0.0001, 0.0001],
+        },
+    }
+
+    response = client.post("/optimize/validate", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["request"]["eta_c"] == 0.95
+    assert response.json["request"]["batteries"][0]["s_capacity"] == 1000
+    assert response.json["request"]["time_series"]["gt"] == [0, 0]
+    assert response.json["warnings"] == ["Battery 0 has goals above s_max that cannot be met"]
+
+    del request["time_series"]["p_E"][0]
+
+    response = client.post("/optimize/validate", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_peak_price():
+    """Only raising the peak above the peak to date is charged."""
+    client = app.test_client()
+
+    request = {
+        "grid": {"prc_p_peak": [{"power": 1000, "price": 0.001}], "p_peak_to_date": 1800},
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 1000, "c_min": 0, "c_max": 0, "d_max": 1000, "p_a": 0}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [2000, 2000],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0.0001, 0.0001],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.isclose(response.json["grid_import_peak"], 1800, atol=1e-03)
+    assert numpy.isclose(response.json["objective_value"], -0.915, atol=1e-04)
+
+    request["grid"]["prc_p_peak"].append({"power": 2000, "price": 0.0005})
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_preconditioning():
+    """Preconditioning runs in the cheapest contiguous steps and is drawn from the battery while unavailable."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 500, "c_min": 0, "c_max": 0, "d_max": 0, "p_a": 0,
+                       "preconditioning": {"power": 1000, "steps": 2, "t_end": 4}}],
+        "time_series": {
+            "dt": [3600, 3600, 3600, 3600],
+            "p_N": [0.0004, 0.0001, 0.0002, 0.0003],
+            "p_E": [0.0001, 0.0001, 0.0001, 0.0001],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.allclose(response.json["batteries"][0]["preconditioning_power"], [0, 1000, 1000, 0], atol=1e-03)
+    assert numpy.isclose(response.json["objective_value"], -0.3, atol=1e-04)
+
+    request["batteries"][0]["available"] = [False] * 4
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.isclose(response.json["batteries"][0]["state_of_charge"][-1], 300, atol=1e-03)
+    assert numpy.allclose(response.json["grid_import"], [0, 0, 0, 0], atol=1e-03)
+
+    request["batteries"][0]["preconditioning"]["t_end"] = 5
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_templates():
+    """Requests referencing a template solve like the merged full request."""
+    client = app.test_client()
+
+    template = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "c_min": 0, "c_max": 1000, "d_max": 1000, "p_a": 0}],
+    }
+    dynamic = {
+        "batteries": [{"s_initial": 1000}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [500, 500],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0.0001, 0.0001],
+        },
+    }
+
+    response = client.post("/optimize/templates", json=template)
+
+    assert response.status_code == 201, f"request returned with status {response.status_code}"
+    template_id = response.json["id"]
+    assert template_id in client.get("/optimize/templates").json["ids"]
+    assert client.get(f"/optimize/templates/{template_id}").json == template
+
+    response = client.post(f"/optimize/templates/{template_id}/charge-schedule", json=dynamic)
+    full = client.post("/optimize/charge-schedule", json={
+        "batteries": [{**template["batteries"][0], **dynamic["batteries"][0]}],
+        "time_series": dynamic["time_series"],
+    })
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.isclose(response.json["objective_value"], full.json["objective_value"], atol=1e-06)
+
+    assert client.delete(f"/optimize/templates/{template_id}").status_code == 204
+
+    response = client.post(f"/optimize/templates/{template_id}/charge-schedule", json=dynamic)
+
+    assert response.status_code == 404, f"request returned with status {response.status_code}"
+
+
+def test_round_conserving():
+    """Rounded energy series keep the rounded sums of the exact series."""
+    assert round_conserving([0.4, 0.4, 0.4, 0.4, 0.4], 0) == [0, 1, 0, 1, 0]
+    assert round_conserving([1.26, 1.26, 1.26], 1) == [1.3, 1.2, 1.3]
+
+    result = round_series({"grid_import": [0.6, 0.6], "batteries": [{"state_of_charge": [0.6, 0.6]}]}, 0)
+    assert result == {"grid_import": [1, 0], "batteries": [{"state_of_charge": [1, 1]}]}
+
+
+def test_demand_rate_peak_to_date():
+    """The demand rate only charges exceeding the peak reached so far in the billing period."""
+    client = app.test_client()
+
+    request = {
+        "grid": {"p_max_imp": 1000, "prc_p_exc_imp": 0.001, "p_peak_to_date": 1800},
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 1000, "c_min": 0, "c_max": 0, "d_max": 1000, "p_a": 0}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [2000, 2000],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0.0001, 0.0001],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.isclose(response.json["objective_value"], -0.915, atol=1e-04)
+
+    del request["grid"]["p_peak_to_date"]
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.isclose(response.json["objective_value"], -1.44, atol=1e-04)
+
+
+def test_jobs():
+    """Asynchronous jobs solve like the synchronous request."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 1000, "c_min": 0, "c_max": 1000, "d_max": 1000, "p_a": 0}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [500, 500],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0.0001, 0.0001],
+        },
+    }
+
+    response = client.post("/optimize/jobs", json=request)
+
+    assert response.status_code == 202, f"request returned with status {response.status_code}"
+    job_id = response.json["id"]
+
+    deadline = time.time() + 30
+    while (job := client.get(f"/optimize/jobs/{job_id}").json)["status"] in ("queued", "running"):
+        assert time.time() < deadline, "job did not finish"
+        time.sleep(0.1)
+
+    assert job["status"] == "done", job.get("message")
+
+    response = client.get(f"/optimize/jobs/{job_id}/result")
+    full = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.isclose(response.json["objective_value"], full.json["objective_value"], atol=1e-06)
+
+    # finished jobs are not cancelled
+    assert client.delete(f"/optimize/jobs/{job_id}").status_code == 204
+    assert client.get(f"/optimize/jobs/{job_id}").json["status"] == "done"
+
+    assert client.get("/optimize/jobs/0000000000000000").status_code == 404
+    assert client.get("/optimize/jobs/0000000000000000/result").status_code == 404
+    assert client.delete("/optimize/jobs/0000000000000000").status_code == 404
+
+
+def test_jobs_drain(tmp_path):
+    """Jobs not finished within the drain window fail, no jobs are accepted afterwards."""
+    store = JobStore(str(tmp_path))
+    release = threading.Event()
+
+    job = store.submit(None, lambda: release.wait(5))
+    store.drain(0.1)
+
+    assert store.get(None, job["id"])["status"] == "failed"
+    with pytest.raises(RuntimeError):
+        store.submit(None, lambda: None)
+
+    release.set()
+
+
+def test_job_runner(tmp_path):
+    """The runner solves queued jobs outside the accepting worker, submitting beyond the limit fails."""
+    store = JobStore(str(tmp_path), max_jobs=1)
+
+    job = store.enqueue(None, {"value": 1})
+    with pytest.raises(JobLimitExceeded):
+        store.enqueue(None, {"value": 2})
+
+    runner = JobRunner(store, lambda request: {"value": request["value"] + 1})
+    thread = threading.Thread(target=runner.run, args=(1,))
+    thread.start()
+
+    deadline = time.time() + 5
+    while store.get(None, job["id"])["status"] != "done":
+        assert time.time() < deadline, "job did not finish"
+        time.sleep(0.05)
+
+    runner.stop()
+    thread.join(5)
+
+    assert store.get(None, job["id"]) == {"id": job["id"], "status": "done", "result": {"value": 2}}
+    # finished jobs do not count towards the limit
+    store.enqueue(None, {"value": 3})
+
+
+def test_job_replay(monkeypatch):
+    """Requests for jobs of other machines are replayed to their machine."""
+    client = app.test_client()
+    monkeypatch.setattr(optimizer.app.job_store, "instance", "m1")
+
+    response = client.get("/optimize/jobs/m2-0123456789abcdef")
+    assert response.headers["fly-replay"] == "instance=m2"
+
+    response = client.get("/optimize/jobs/m1-0123456789abcdef")
+    assert response.status_code == 404
+    assert "fly-replay" not in response.headers
+
+
+def test_settings_file(tmp_path, monkeypatch):
+    """The config file overrides the environment and is read on each instantiation."""
+    monkeypatch.setenv("OPTIMIZER_TIME_LIMIT", "25")
+    config = tmp_path / "optimizer.env"
+
+    config.write_text("OPTIMIZER_TIME_LIMIT=5\n")
+    assert OptimizerSettings(_env_file=str(config)).time_limit == 5
+
+    config.write_text("OPTIMIZER_TIME_LIMIT=10\nJWT_TOKEN_SECRET=secret\n")
+    settings = OptimizerSettings(_env_file=str(config))
+    assert settings.time_limit == 10
+    assert settings.jwt_token_secret == "secret"
+
+
+def test_audit_log(tmp_path, monkeypatch):
+    """Solve requests are recorded with their digest and outcome, other requests are not."""
+    log = AuditLog(str(tmp_path / "audit.jsonl"))
+    monkeypatch.setattr(optimizer.app, "audit_log", log)
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 500, "c_min": 0, "c_max": 1000, "d_max": 1000, "p_a": 0}],
+        "time_series": {"dt": [3600], "gt": [500], "p_N": [0.0003], "p_E": [0.0001]},
+    }
+
+    assert client.post("/optimize/charge-schedule", json=request).status_code == 200
+    assert client.post("/optimize/charge-schedule", json={"batteries": []}).status_code == 400
+    client.get("/optimize/health")
+
+    entries = list(log.query())
+
+    assert [e["status"] for e in entries] == [200, 400]
+    assert entries[0]["outcome"] == "Optimal"
+    assert entries[0]["digest"] == digest(request)
+    assert entries[0]["path"] == "/optimize/charge-schedule"
+    assert list(log.query(request_digest=digest(request))) == entries[:1]
+
+
+def test_warm_start():
+    """A previous schedule as warm start hint does not change the optimum, also if it is shorter or out of bounds."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 5000, "s_initial": 1000, "c_min": 1000, "c_max": 3000, "d_max": 3000, "p_a": 0.0002}],
+        "time_series": {
+            "dt": [3600, 3600, 3600, 3600],
+            "gt": [500, 1500, 500, 1000],
+            "ft": [2000, 0, 0, 3000],
+            "p_N": [0.0003, 0.0004, 0.0001, 0.0003],
+            "p_E": [0.0001, 0.0001, 0.0001, 0.0001],
+        },
+    }
+
+    cold = client.post("/optimize/charge-schedule", json=request).json
+    warm = client.post("/optimize/charge-schedule", json={**request, "warm_start": {
+        "batteries": [{"charging_power": [1500, 0, 9000], "discharging_power": [0, 1500, 0]}],
+        "grid_import": [0, 0, 0],
+        "grid_export": [0, 0, 0],
+    }}).json
+
+    assert warm["status"] == "Optimal"
+    assert numpy.isclose(warm["objective_value"], cold["objective_value"], atol=1e-06)
+
+
+def test_departures():
+    """A likely early departure makes the plan charge before it instead of waiting for cheaper prices."""
+    client = app.test_client()
+
+    def request(departures):
+        return {
+            "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 10000, "s_initial": 0, "c_min": 0, "c_max": 6000, "d_max": 0, "p_a": 0,
+                           "departures": departures}],
+            "time_series": {
+                "dt": [3600, 3600, 3600, 3600],
+                "gt": [0, 0, 0, 0],
+                "p_N": [0.0004, 0.0004, 0.0001, 0.0001],
+                "p_E": [0, 0, 0, 0],
+            },
+        }
+
+    late = client.post("/optimize/charge-schedule", json=request([{"t": 3, "probability": 1, "s_goal": 10000}])).json
+    uncertain = client.post("/optimize/charge-schedule", json=request([
+        {"t": 1, "probability": 0.3, "s_goal": 10000},
+        {"t": 3, "probability": 0.7, "s_goal": 10000},
+    ])).json
+
+    # charged at the cheap prices after the expected departure
+    assert numpy.isclose(late["batteries"][0]["state_of_charge"][1], 0, atol=1e-3)
+    assert numpy.isclose(late["batteries"][0]["expected_shortfall"], 0, atol=1e-3)
+    # charged for the early departure
+    assert numpy.isclose(uncertain["batteries"][0]["state_of_charge"][1], 10000, atol=1e-3)
+    assert numpy.isclose(uncertain["batteries"][0]["expected_shortfall"], 0, atol=1e-3)
+
+    response = client.post("/optimize/charge-schedule", json=request([
+        {"t": 1, "probability": 0.6, "s_goal": 10000},
+        {"t": 3, "probability": 0.6, "s_goal": 10000},
+    ]))
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_net_metering():
+    """Surplus export is offset against later import within the netting period."""
+    client = app.test_client()
+
+    request = {
+        "grid": {"prc_e_net_imp": 0.0003, "prc_e_net_exp": 0.0001},
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 0, "c_min": 0, "c_max": 0, "d_max": 0, "p_a": 0}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [0, 3000],
+            "ft": [2000, 0],
+            "p_N": [0, 0],
+            "p_E": [0, 0],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.isclose(response.json["objective_value"], -0.3, atol=1e-04)
+
+    # export and import in separate netting periods
+    request["grid"]["t_net_reset"] = 1
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.isclose(response.json["objective_value"], -0.7, atol=1e-04)
+
+    request["grid"]["prc_e_net_exp"] = 0.0004
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_penalties():
+    """A custom penalty term moves import out of the penalized interval without changing the cost."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 1000, "c_min": 0, "c_max": 0, "d_max": 1000, "p_a": 0}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [1000, 1000],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0.0001, 0.0001],
+        },
+        "penalties": ["0.001 * grid_import[1]", "0 * soc['home'][0:2]"],
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.allclose(response.json["grid_import"], [1000, 0], atol=1e-03)
+    assert numpy.isclose(response.json["objective_value"], -0.3, atol=1e-04)
+
+    for penalty in ["grid_import * grid_export", "grid_import[2]", "soc['car']", "__import__('os')"]:
+        request["penalties"] = [penalty]
+
+        response = client.post("/optimize/charge-schedule", json=request)
+
+        assert response.status_code == 400, f"{penalty} returned with status {response.status_code}"
+
+
+def test_objective_weights():
+    """A battery wear weight stops cycling for small arbitrage gains, invalid weights are rejected."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 0, "c_min": 0, "c_max": 1000, "d_max": 1000, "p_a": 0}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [0, 1000],
+            "p_N": [0.0001, 0.0004],
+            "p_E": [0, 0],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert sum(response.json["batteries"][0]["charging_power"]) > 500
+
+    request["objective_weights"] = {"cost": 1, "battery_wear": 10, "self_consumption": 0, "peak_shaving": 0}
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert numpy.allclose(response.json["batteries"][0]["charging_power"], [0, 0], atol=1e-03)
+    assert numpy.allclose(response.json["grid_import"], [0, 1000], atol=1e-03)
+    assert numpy.isclose(response.json["objective_value"], -0.4, atol=1e-04)
+
+    for weights in [{"cost": 0, "battery_wear": 0, "self_consumption": 0, "peak_shaving": 0},
+                    {"cost": 1, "battery_wear": -1, "self_consumption": 0, "peak_shaving": 0}]:
+        request["objective_weights"] = weights
+
+        response = client.post("/optimize/charge-schedule", json=request)
+
+        assert response.status_code == 400, f"{weights} returned with status {response.status_code}"
+
+    request["objective_weights"] = {"cost": 1, "battery_wear": 1, "self_consumption": 0, "peak_shaving": 0}
+    request["cost_budget"] = 1
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"cost budget returned with status {response.status_code}"
+
+
+def test_import_limit_series():
+    """An import limit window is bridged by the battery, combining limits with a demand rate is rejected."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 2000, "s_initial": 0, "c_min": 0, "c_max": 2000, "d_max": 2000, "p_a": 0}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [0, 1000],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0, 0],
+            "p_max_imp": [11000, 400],
+        },
+        "grid": {"p_conn": 17000},
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["grid_import"][1] <= 400 + 1e-03
+    assert numpy.allclose(response.json["grid_import_overshoot"], [0, 0], atol=1e-03)
+    assert response.json["batteries"][0]["charging_power"][0] > 600
+
+    for grid, p_max_imp in [({"p_max_imp": 5000, "prc_p_exc_imp": 0.01}, [11000, 400]),
+                            ({"p_max_imp": 5000, "prc_p_exc_imp": 0.01, "p_conn": 17000}, None),
+                            ({}, [11000, -1]),
+                            ({}, [11000])]:
+        request["grid"] = grid
+        request["time_series"].pop("p_max_imp", None)
+        if p_max_imp is not None:
+            request["time_series"]["p_max_imp"] = p_max_imp
+
+        response = client.post("/optimize/charge-schedule", json=request)
+
+        assert response.status_code == 400, f"{grid}, {p_max_imp} returned with status {response.status_code}"
+
+
+def test_chp():
+    """Heat is produced from gas while electricity is expensive and by the heat pump while it is cheap."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 0, "c_min": 0, "c_max": 1000, "d_max": 1000, "p_a": 0}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [1000, 1000],
+            "p_N": [0.0004, 0.0001],
+            "p_E": [0, 0],
+            "p_G": [0.0001, 0.0002],
+            "q_H": [2000, 2000],
+        },
+        "chp": {"p_el_max": 1000, "eta_el": 0.3, "eta_th": 0.6, "p_heat_el_max": 2000, "cop": 3},
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    chp = response.json["chp"]
+    assert numpy.allclose(chp["electricity"], [1000, 0], atol=1e-03)
+    assert numpy.allclose(chp["heat"], [2000, 0], atol=1e-03)
+    assert numpy.allclose(chp["electric_heating"], [0, 2000 / 3], atol=1e-03)
+    assert numpy.isclose(chp["fuel_cost"], 2000 / 0.6 * 0.0001, atol=1e-06)
+    assert numpy.allclose(response.json["grid_import"], [0, 1000 + 2000 / 3], atol=1e-03)
+
+    for chp in [{"p_el_max": 1000, "eta_el": 0.5, "eta_th": 0.6}, {"p_el_max": 100, "eta_el": 0.3, "eta_th": 0.6}]:
+        invalid = dict(request, chp=chp)
+
+        response = client.post("/optimize/charge-schedule", json=invalid)
+
+        assert response.status_code == 400, f"{chp} returned with status {response.status_code}"
+
+    del request["chp"]
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"gas prices without unit returned with status {response.status_code}"
+
+
+def test_stream():
+    """The event stream reports progress and ends with the result of the synchronous request."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 1000, "c_min": 0, "c_max": 1000, "d_max": 1000, "p_a": 0}],
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [500, 500],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0.0001, 0.0001],
+        },
+    }
+
+    response = client.post("/optimize/stream", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.mimetype == "text/event-stream"
+
+    events = []
+    for block in response.get_data(as_text=True).strip().split("\n\n"):
+        lines = dict(line.split(": ", 1) for line in block.split("\n"))
+        events.append((lines["event"], json.loads(lines["data"])))
+
+    assert events[0][0] == "progress"
+    assert events[0][1]["phase"] == "model"
+    assert all(e == "progress" for e, _ in events[:-1])
+
+    event, result = events[-1]
+    full = client.post("/optimize/charge-schedule", json=request)
+
+    assert event == "result"
+    assert numpy.isclose(result["objective_value"], full.json["objective_value"], atol=1e-06)
+
+    # invalid inputs beyond the schema fail in the stream
+    request["time_series"]["gt"] = [500]
+    events = client.post("/optimize/stream", json=request).get_data(as_text=True)
+
+    assert "event: error" in events
+    assert '"code": 400' in events
+
+
+def test_calibration():
+    """A due calibration fully charges the battery in the cheapest time step before it is due."""
+    client = app.test_client()
+
+    request = {
+        "batteries": [{"id": "home", "name": "Home", "s_min": 0, "s_max": 1000, "s_initial": 200, "c_min": 0, "c_max": 1000, "d_max": 1000, "p_a": 0,
+                       "charge_from_grid": True,
+                       # due at the end of the third time step
+                       "calibration": {"interval": 14 * 86400, "elapsed": 14 * 86400 - 3 * 3600}}],
+        "time_series": {
+            "dt": [3600, 3600, 3600, 3600],
+            "gt": [0, 0, 1000, 0],
+            "p_N": [0.0004, 0.0001, 0.0002, 0.0003],
+            "p_E": [0, 0, 0, 0],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    battery = response.json["batteries"][0]
+    assert battery["calibration_step"] == 1
+    assert numpy.isclose(battery["state_of_charge"][1], 1000, atol=1e-03)
+
+    # not due within the horizon
+    request["batteries"][0]["calibration"]["elapsed"] = 0
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["batteries"][0]["calibration_step"] is None
+
+    request["batteries"][0]["calibration"]["interval"] = 0
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_goal_priority():
+    """Goals competing for a limited grid import are met by priority, scaled back goals are reported."""
+    client = app.test_client()
+
+    vehicle = {"name": "EV", "s_min": 0, "s_max": 10000, "s_initial": 0, "c_min": 0, "c_max": 5000, "d_max": 0, "p_a": 0,
+               "charge_from_grid": True, "s_goal": [0, 4000]}
+    request = {
+        "batteries": [dict(vehicle, id="a"), dict(vehicle, id="b", goal_priority=1)],
+        "grid": {"p_max_imp": 3000},
+        "eta_c": 1,
+        "eta_d": 1,
+        "time_series": {
+            "dt": [3600, 3600],
+            "gt": [0, 0],
+            "p_N": [0.0003, 0.0003],
+            "p_E": [0, 0],
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    a, b = response.json["batteries"]
+    assert numpy.isclose(b["state_of_charge"][1], 4000, atol=1e-03)
+    assert b["goal_shortfalls"] == []
+    assert len(a["goal_shortfalls"]) == 1
+    shortfall = a["goal_shortfalls"][0]
+    assert shortfall["t"] == 1
+    assert numpy.isclose(shortfall["shortfall"], 2000, atol=1e-03)
+    assert shortfall["reason"] == "grid_limit"
+    assert shortfall["competitors"] == [1]
+
+
+def test_goal_priority_conflicting():
+    """Higher goal priorities are met first even against lower goals reduced at many time steps."""
+    client = app.test_client()
+
+    vehicle = {"name": "EV", "s_min": 0, "s_max": 10000, "s_initial": 0, "c_min": 0, "c_max": 11000, "d_max": 0, "p_a": 0,
+               "charge_from_grid": True}
+    n = 12
+    request = {
+        "batteries": [
+            # each Wh charged early reduces the shortfall at all 12 time steps
+            dict(vehicle, id="c", s_goal=[6000] * n),
+            dict(vehicle, id="a", goal_priority=1, s_goal=[0] * (n - 1) + [8000]),
+            dict(vehicle, id="b", goal_priority=2, s_goal=[0] * (n - 1) + [6000]),
+        ],
+        "grid": {"p_max_imp": 4000},
+        "eta_c": 1,
+        "eta_d": 1,
+        "time_series": {
+            "dt": [900] * n,
+            "gt": [0] * n,
+            "p_N": [0.0003] * n,
+            "p_E": [0] * n,
+        },
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    c, a, b = response.json["batteries"]
+    # 12 kWh of grid import: the goal of b is met, a gets the rest and c nothing
+    assert numpy.isclose(b["state_of_charge"][-1], 6000, atol=1e-02)
+    assert numpy.isclose(a["state_of_charge"][-1], 6000, atol=1e-02)
+    assert numpy.isclose(c["state_of_charge"][-1], 0, atol=1e-02)
+
+
+def test_goal_weights():
+    """Goal levels outweigh the largest penalty a Wh reduces below them, too many levels are rejected."""
+    def battery(goal_priority, s_goal):
+        return BatteryConfig(charge_from_grid=True, discharge_to_grid=False, s_capacity=10000, s_min=0, s_max=10000,
+                             s_initial=0, c_min=0, c_max=5000, d_max=0, p_a=0, s_goal=s_goal, goal_priority=goal_priority)
+
+    assert goal_weights([battery(0, [0, 1]), battery(3, [0, 1]), battery(5, [0, 1])]) == [1, 10, 100]
+    assert goal_weights([battery(0, [1] * 12), battery(1, [0, 1]), battery(0, None)]) == [1, 120, 1]
+
+    client = app.test_client()
+    vehicle = {"name": "EV", "s_min": 0, "s_max": 10000, "s_initial": 0, "c_min": 0, "c_max": 5000, "d_max": 0, "p_a": 0,
+               "charge_from_grid": True, "s_goal": [0, 4000]}
+    request = {
+        "batteries": [dict(vehicle, id=str(level), goal_priority=level) for level in range(6)],
+        "time_series": {"dt": [3600, 3600], "gt": [0, 0], "p_N": [0.0003, 0.0003], "p_E": [0, 0]},
+    }
+
+    response = client.post("/optimize/charge-schedule", json=request)
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+    assert "goal priority levels" in response.json["message"]
+
+
+def test_canonical_json():
+    """Responses encode with sorted keys and normalized numbers, equal requests have equal digests."""
+    assert canonical_json({"b": [2000.0, -0.0, 1e-05], "a": 0.1}) == '{"a":0.1,"b":[2000,0,1e-05]}'
+    assert canonical_json([2999.9999999999995, 1e-13], 10) == '[3000,0]'
+    assert digest({"dt": [3600.0], "p_N": 0.3}) == digest({"p_N": 0.3, "dt": [3600]})
+
+    client = app.test_client()
+    test_data = json.loads(pathlib.Path('test_cases/024-battery-priority-order.json').read_text())
+
+    first = client.post("/optimize/charge-schedule", json=test_data["request"])
+    second = client.post("/optimize/charge-schedule", json=test_data["request"])
+
+    assert first.status_code == 200, f"request returned with status {first.status_code}"
+    assert first.data == second.data
+    assert first.data.decode().rstrip() == canonical_json(first.json)
+    assert b"-0.0" not in first.data
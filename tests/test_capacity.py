@@ -0,0 +1,79 @@
+import time
+
+import pytest
+
+from optimizer import app as app_module
+from optimizer.app import app
+from optimizer.capacity import SolveStatistics
+
+
+@pytest.fixture
+def stats(tmp_path):
+    return SolveStatistics(str(tmp_path / 'stats.json'))
+
+
+def test_default_estimate(stats):
+    estimate = stats.estimate(48, 2)
+
+    assert estimate['estimated_solve_time'] == pytest.approx(SolveStatistics.DEFAULT_SECONDS_PER_CELL * 96)
+    assert estimate['estimated_queue_wait'] == 0
+    assert estimate['active_solves'] == 0
+
+
+def test_estimate_after_tracked_solves(stats):
+    with stats.track(10, 1):
+        time.sleep(0.05)
+
+    estimate = stats.estimate(20, 1)
+
+    # twice the size of the recorded solve
+    assert estimate['estimated_solve_time'] >= 0.1
+    assert estimate['estimated_solve_time'] < 1
+    assert estimate['active_solves'] == 0
+
+
+def test_queue_wait_subtracts_elapsed_time(stats):
+    with stats.track(10, 1):
+        time.sleep(0.05)
+
+    with stats.track(100, 1):
+        # the in-flight solve is expected to take about ten times the recorded one
+        first = stats.estimate(1, 1)
+        time.sleep(0.1)
+        second = stats.estimate(1, 1)
+
+    assert first['active_solves'] == 1
+    assert second['estimated_queue_wait'] < first['estimated_queue_wait']
+    assert stats.estimate(1, 1)['active_solves'] == 0
+
+
+def test_shared_between_instances(stats):
+    other = SolveStatistics(stats.path)
+
+    with stats.track(10, 1):
+        assert other.estimate(1, 1)['active_solves'] == 1
+
+
+@pytest.mark.parametrize('query', [
+    'time_steps=-1',
+    'time_steps=48&batteries=0',
+    'batteries=1',
+])
+def test_estimate_endpoint_invalid(query):
+    client = app.test_client()
+
+    response = client.get(f"/optimize/estimate?{query}")
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_estimate_endpoint_default(monkeypatch, stats):
+    monkeypatch.setattr(app_module, 'solve_stats', stats)
+    client = app.test_client()
+
+    response = client.get("/optimize/estimate?time_steps=48")
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json['estimated_solve_time'] == pytest.approx(SolveStatistics.DEFAULT_SECONDS_PER_CELL * 48)
+    assert response.json['estimated_queue_wait'] == 0
+    assert response.json['active_solves'] == 0
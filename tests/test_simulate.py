@@ -0,0 +1,55 @@
+import pytest
+
+from optimizer.app import app
+
+REQUEST = {
+    "batteries": [
+        {"id": "home", "name": "Home", "s_min": 0, "s_max": 2000, "s_initial": 0, "c_min": 0, "c_max": 1000, "d_max": 1000, "p_a": 0.0001},
+    ],
+    "time_series": {
+        "dt": [3600, 3600, 3600],
+        "gt": [0, 500, 2000],
+        "ft": [1500, 0, 0],
+        "p_N": [0.0003, 0.0003, 0.0003],
+        "p_E": [0.0001, 0.0001, 0.0001],
+    },
+}
+
+
+def test_self_consumption():
+    client = app.test_client()
+
+    response = client.post("/optimize/simulate", json=REQUEST)
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+    assert response.json["status"] == "Simulated"
+
+    battery = response.json["batteries"][0]
+    # surplus beyond the charge power is exported
+    assert battery["charging_power"] == pytest.approx([1000, 0, 0])
+    assert response.json["grid_export"] == pytest.approx([500, 0, 0])
+    # discharge limited by the energy stored
+    assert battery["discharging_power"] == pytest.approx([0, 500, 950 * 0.95 - 500])
+    assert battery["state_of_charge"] == pytest.approx([950, 950 - 500 / 0.95, 0])
+    assert response.json["grid_import"] == pytest.approx([0, 0, 2000 - (950 * 0.95 - 500)])
+
+
+def test_invalid_policy():
+    client = app.test_client()
+
+    response = client.post("/optimize/simulate", json=dict(REQUEST, policy="arbitrage"))
+
+    assert response.status_code == 400, f"request returned with status {response.status_code}"
+
+
+def test_preconditioning_s_min():
+    client = app.test_client()
+
+    battery = dict(REQUEST["batteries"][0], s_min=500, s_initial=1000, available=[False, False, False],
+                   preconditioning={"power": 400, "steps": 2, "t_end": 3})
+    response = client.post("/optimize/simulate", json=dict(REQUEST, batteries=[battery]))
+
+    assert response.status_code == 200, f"request returned with status {response.status_code}"
+
+    # preconditioning is drawn from the unavailable battery down to s_min only
+    assert response.json["batteries"][0]["state_of_charge"] == pytest.approx([1000, 600, 500])
@@ -0,0 +1,223 @@
+// Package timeseries converts real-world inputs like 15 minute price feeds, hourly solar
+// forecasts or irregular demand samples into the series of an optimization request. Intervals
+// are given by the start of the first interval and the durations dt in seconds as in the
+// request, typically created by Grid:
+//
+//	end := timeseries.Until(now, prices, solar)
+//	start, dt := timeseries.Grid(now, end, 15*time.Minute)
+//	pN, err := timeseries.Mean(prices, start, dt)
+//	ft, err := timeseries.Integrate(solar, start, dt)
+package timeseries
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/evcc-io/optimizer/tariff"
+)
+
+// Slot is a value valid for the period [Start, End), e.g. a price or an average power.
+type Slot struct {
+	Start time.Time
+	End   time.Time
+	Value float64
+}
+
+// Point is a value sampled at a point in time, e.g. a measured power.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// FromRates converts tariff rates to slots.
+func FromRates(rates tariff.Rates) []Slot {
+	res := make([]Slot, len(rates))
+	for i, r := range rates {
+		res[i] = Slot{Start: r.Start, End: r.End, Value: r.Price}
+	}
+	return res
+}
+
+// Grid returns the start and the durations of intervals of length step from now to end, e.g.
+// for the dt series. The start is now truncated to full seconds. The first interval is partial
+// and ends at the next multiple of step since the zero time, e.g. the next full quarter hour,
+// unless now is aligned. The last interval is partial if end is not aligned.
+func Grid(now, end time.Time, step time.Duration) (time.Time, []int) {
+	start := now.Truncate(time.Second)
+
+	var dt []int
+	for ts := start; ts.Before(end); {
+		te := ts.Truncate(step).Add(step)
+		if te.After(end) {
+			te = end
+		}
+
+		if d := int(te.Sub(ts) / time.Second); d > 0 {
+			dt = append(dt, d)
+		}
+		ts = te
+	}
+
+	return start, dt
+}
+
+// Until returns the end of the period from start covered by all inputs without gaps, i.e. the
+// common horizon of the inputs. It returns start if an input does not cover start.
+func Until(start time.Time, inputs ...[]Slot) time.Time {
+	end := start
+
+	for i, slots := range inputs {
+		sorted := slices.Clone(slots)
+		slices.SortFunc(sorted, func(a, b Slot) int { return a.Start.Compare(b.Start) })
+
+		e := start
+		for _, s := range sorted {
+			if !s.End.After(e) {
+				continue
+			}
+			if s.Start.After(e) {
+				// gap in the input
+				break
+			}
+			e = s.End
+		}
+
+		if i == 0 || e.Before(end) {
+			end = e
+		}
+	}
+
+	return end
+}
+
+// overlap returns the duration of the overlap of [a0, a1) and [b0, b1)
+func overlap(a0, a1, b0, b1 time.Time) time.Duration {
+	return max(0, minTime(a1, b1).Sub(maxTime(a0, b0)))
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// resample aggregates the slots overlapping each interval. Every interval must be covered
+// exactly once.
+func resample(slots []Slot, start time.Time, dt []int, value func(s Slot, overlap, d time.Duration) float64) ([]float32, error) {
+	res := make([]float32, len(dt))
+
+	ts := start
+	for t, sec := range dt {
+		d := time.Duration(sec) * time.Second
+		te := ts.Add(d)
+
+		var (
+			sum     float64
+			covered time.Duration
+		)
+		for _, s := range slots {
+			if o := overlap(ts, te, s.Start, s.End); o > 0 {
+				sum += value(s, o, d)
+				covered += o
+			}
+		}
+
+		switch {
+		case covered < d:
+			return nil, fmt.Errorf("interval %d at %s: not covered", t, ts.Format(time.RFC3339))
+		case covered > d:
+			return nil, fmt.Errorf("interval %d at %s: overlapping slots", t, ts.Format(time.RFC3339))
+		}
+
+		res[t] = float32(sum)
+		ts = te
+	}
+
+	return res, nil
+}
+
+// Mean returns the time weighted mean of the slots per interval, e.g. prices for the p_N and p_E
+// series. Intervals must be covered by the slots.
+func Mean(slots []Slot, start time.Time, dt []int) ([]float32, error) {
+	return resample(slots, start, dt, func(s Slot, o, d time.Duration) float64 {
+		return s.Value * float64(o) / float64(d)
+	})
+}
+
+// Integrate returns the energy per interval [Wh] of slots of average power [W], e.g. hourly
+// solar forecasts for the ft series. Intervals must be covered by the slots.
+func Integrate(slots []Slot, start time.Time, dt []int) ([]float32, error) {
+	return resample(slots, start, dt, func(s Slot, o, _ time.Duration) float64 {
+		return s.Value * o.Hours()
+	})
+}
+
+// Distribute returns the energy per interval [Wh] of slots of energy [Wh], assuming constant
+// power within each slot. Intervals must be covered by the slots.
+func Distribute(slots []Slot, start time.Time, dt []int) ([]float32, error) {
+	return resample(slots, start, dt, func(s Slot, o, _ time.Duration) float64 {
+		return s.Value * float64(o) / float64(s.End.Sub(s.Start))
+	})
+}
+
+// Interpolate returns the energy per interval [Wh] of power samples [W], e.g. irregular demand
+// measurements for the gt series. The power is interpolated linearly between samples and held
+// constant before the first and after the last sample.
+func Interpolate(points []Point, start time.Time, dt []int) ([]float32, error) {
+	if len(points) == 0 {
+		return nil, errors.New("no samples")
+	}
+
+	sorted := slices.Clone(points)
+	slices.SortFunc(sorted, func(a, b Point) int { return a.Time.Compare(b.Time) })
+
+	at := func(ts time.Time) float64 {
+		i, _ := slices.BinarySearchFunc(sorted, ts, func(p Point, ts time.Time) int { return p.Time.Compare(ts) })
+		switch {
+		case i == 0:
+			return sorted[0].Value
+		case i == len(sorted):
+			return sorted[i-1].Value
+		}
+
+		a, b := sorted[i-1], sorted[i]
+		f := float64(ts.Sub(a.Time)) / float64(b.Time.Sub(a.Time))
+		return a.Value + f*(b.Value-a.Value)
+	}
+
+	res := make([]float32, len(dt))
+
+	ts := start
+	for t, sec := range dt {
+		te := ts.Add(time.Duration(sec) * time.Second)
+
+		// the power is linear between the interval bounds and the samples within
+		bounds := []time.Time{ts}
+		for _, p := range sorted {
+			if p.Time.After(ts) && p.Time.Before(te) {
+				bounds = append(bounds, p.Time)
+			}
+		}
+		bounds = append(bounds, te)
+
+		var e float64
+		for k := 1; k < len(bounds); k++ {
+			e += (at(bounds[k-1]) + at(bounds[k])) / 2 * bounds[k].Sub(bounds[k-1]).Hours()
+		}
+
+		res[t] = float32(e)
+		ts = te
+	}
+
+	return res, nil
+}
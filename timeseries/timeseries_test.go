@@ -0,0 +1,141 @@
+package timeseries
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func equal(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if math.Abs(float64(a[k]-b[k])) > 1e-3 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGrid(t *testing.T) {
+	start, dt := Grid(t0.Add(5*time.Minute+500*time.Millisecond), t0.Add(50*time.Minute), 15*time.Minute)
+
+	if !start.Equal(t0.Add(5 * time.Minute)) {
+		t.Errorf("expected start truncated to seconds, got %v", start)
+	}
+
+	// partial first and last interval
+	expected := []int{600, 900, 900, 300}
+	if len(dt) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, dt)
+	}
+	for k := range expected {
+		if dt[k] != expected[k] {
+			t.Fatalf("expected %v, got %v", expected, dt)
+		}
+	}
+}
+
+func TestUntil(t *testing.T) {
+	prices := []Slot{
+		{Start: t0, End: t0.Add(time.Hour), Value: 1},
+		{Start: t0.Add(time.Hour), End: t0.Add(2 * time.Hour), Value: 2},
+		// after a gap
+		{Start: t0.Add(3 * time.Hour), End: t0.Add(4 * time.Hour), Value: 3},
+	}
+	solar := []Slot{{Start: t0.Add(-time.Hour), End: t0.Add(90 * time.Minute), Value: 100}}
+
+	if end := Until(t0, prices); !end.Equal(t0.Add(2 * time.Hour)) {
+		t.Errorf("expected end at the gap, got %v", end)
+	}
+	if end := Until(t0, prices, solar); !end.Equal(t0.Add(90 * time.Minute)) {
+		t.Errorf("expected the common horizon, got %v", end)
+	}
+	if end := Until(t0.Add(-time.Minute), prices); !end.Equal(t0.Add(-time.Minute)) {
+		t.Errorf("expected start if not covered, got %v", end)
+	}
+}
+
+func TestResample(t *testing.T) {
+	// hourly slots, quarter hourly intervals starting at half past
+	slots := []Slot{
+		{Start: t0, End: t0.Add(time.Hour), Value: 1000},
+		{Start: t0.Add(time.Hour), End: t0.Add(2 * time.Hour), Value: 2000},
+	}
+	start := t0.Add(30 * time.Minute)
+	dt := []int{900, 900, 1800}
+
+	mean, err := Mean(slots, start, dt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(mean, []float32{1000, 1000, 2000}) {
+		t.Errorf("mean: got %v", mean)
+	}
+
+	// average power [W] into energy [Wh]
+	energy, err := Integrate(slots, start, dt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(energy, []float32{250, 250, 1000}) {
+		t.Errorf("integrate: got %v", energy)
+	}
+
+	// energy per slot [Wh] spread evenly
+	dist, err := Distribute(slots, start, dt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(dist, []float32{250, 250, 1000}) {
+		t.Errorf("distribute: got %v", dist)
+	}
+
+	// intervals spanning two slots are weighted by the overlap
+	mean, err = Mean(slots, t0.Add(30*time.Minute), []int{3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(mean, []float32{1500}) {
+		t.Errorf("spanning mean: got %v", mean)
+	}
+
+	if _, err := Mean(slots, t0.Add(90*time.Minute), []int{3600}); err == nil {
+		t.Error("expected error for uncovered interval")
+	}
+	if _, err := Mean(append(slots, slots[0]), t0, []int{900}); err == nil {
+		t.Error("expected error for overlapping slots")
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	points := []Point{
+		{Time: t0.Add(time.Hour), Value: 2000},
+		{Time: t0, Value: 0},
+	}
+
+	// ramp from 0 to 2000 W over the first hour, held after the last sample
+	energy, err := Interpolate(points, t0, []int{1800, 1800, 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(energy, []float32{250, 750, 2000}) {
+		t.Errorf("got %v", energy)
+	}
+
+	// held before the first sample
+	energy, err = Interpolate(points, t0.Add(-time.Hour), []int{3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(energy, []float32{0}) {
+		t.Errorf("got %v", energy)
+	}
+
+	if _, err := Interpolate(nil, t0, []int{900}); err == nil {
+		t.Error("expected error without samples")
+	}
+}
@@ -1,7 +1,7 @@
 //go:build tools
 // +build tools
 
-package main
+package tools
 
 import (
 	_ "github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen"
@@ -0,0 +1,177 @@
+// Package tracker compares measured battery state of charge against a planned trajectory
+// and emits drift events, signalling that a plan should be re-optimized early.
+package tracker
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Event is emitted when the measured state of charge drifts from the plan beyond a threshold.
+type Event struct {
+	Battery  int
+	Time     time.Time
+	Interval int
+	Planned  float64 // planned state of charge [Wh]
+	Measured float64 // measured state of charge [Wh]
+	Drift    float64 // measured - planned [Wh]
+}
+
+// Tracker tracks plan execution for all batteries of an optimization result.
+type Tracker struct {
+	mu        sync.Mutex
+	start     time.Time
+	dt        []int
+	initial   []float64
+	soc       [][]float32
+	threshold float64
+	relative  float64
+	capacity  []float64
+	callback  func(Event)
+	events    chan Event
+	alarmed   []bool
+}
+
+// Option configures the tracker.
+type Option func(*Tracker)
+
+// WithThreshold sets the absolute drift threshold in Wh. Default is 500 Wh.
+func WithThreshold(wh float64) Option {
+	return func(t *Tracker) {
+		t.threshold = wh
+	}
+}
+
+// WithRelativeThreshold sets the drift threshold as fraction of the battery's s_max.
+// If both thresholds are set, the smaller one applies.
+func WithRelativeThreshold(fraction float64) Option {
+	return func(t *Tracker) {
+		t.relative = fraction
+	}
+}
+
+// WithCallback registers a callback invoked synchronously for every drift event.
+func WithCallback(fn func(Event)) Option {
+	return func(t *Tracker) {
+		t.callback = fn
+	}
+}
+
+// New creates a tracker for the plan res computed for req, with the first interval starting at start.
+func New(req client.OptimizationInput, res client.OptimizationResult, start time.Time, opts ...Option) *Tracker {
+	t := &Tracker{
+		start:     start,
+		dt:        req.TimeSeries.Dt,
+		threshold: 500,
+		events:    make(chan Event, 16),
+		alarmed:   make([]bool, len(res.Batteries)),
+	}
+
+	for i, b := range res.Batteries {
+		t.soc = append(t.soc, b.StateOfCharge)
+		if i < len(req.Batteries) {
+			t.initial = append(t.initial, float64(req.Batteries[i].SInitial))
+			t.capacity = append(t.capacity, float64(req.Batteries[i].SMax))
+		} else {
+			t.initial = append(t.initial, 0)
+			t.capacity = append(t.capacity, 0)
+		}
+	}
+
+	for _, o := range opts {
+		o(t)
+	}
+
+	return t
+}
+
+// Events returns the channel of drift events. Events are dropped if the channel is not drained.
+func (t *Tracker) Events() <-chan Event {
+	return t.events
+}
+
+// Planned returns the planned state of charge of the battery at the given time, interpolated
+// linearly within intervals, and the interval index. ok is false outside the planned horizon.
+func (t *Tracker) Planned(battery int, at time.Time) (soc float64, interval int, ok bool) {
+	if battery < 0 || battery >= len(t.soc) || at.Before(t.start) {
+		return 0, 0, false
+	}
+
+	from := t.start
+	prev := t.initial[battery]
+
+	for i, dt := range t.dt {
+		if i >= len(t.soc[battery]) {
+			break
+		}
+
+		to := from.Add(time.Duration(dt) * time.Second)
+		next := float64(t.soc[battery][i])
+
+		if at.Before(to) {
+			frac := float64(at.Sub(from)) / float64(to.Sub(from))
+			return prev + frac*(next-prev), i, true
+		}
+
+		from, prev = to, next
+	}
+
+	return 0, 0, false
+}
+
+func (t *Tracker) limit(battery int) float64 {
+	limit := t.threshold
+	if t.relative > 0 {
+		if rel := t.relative * t.capacity[battery]; limit <= 0 || rel < limit {
+			limit = rel
+		}
+	}
+	return limit
+}
+
+// Observe records a measured state of charge. It returns the drift event and true if the
+// drift exceeds the threshold. An event is emitted once per excursion; the alarm re-arms
+// when the drift returns within the threshold.
+func (t *Tracker) Observe(battery int, at time.Time, measured float64) (Event, bool) {
+	planned, interval, ok := t.Planned(battery, at)
+	if !ok {
+		return Event{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	drift := measured - planned
+	if math.Abs(drift) <= t.limit(battery) {
+		t.alarmed[battery] = false
+		return Event{}, false
+	}
+
+	ev := Event{
+		Battery:  battery,
+		Time:     at,
+		Interval: interval,
+		Planned:  planned,
+		Measured: measured,
+		Drift:    drift,
+	}
+
+	if t.alarmed[battery] {
+		return ev, true
+	}
+	t.alarmed[battery] = true
+
+	if t.callback != nil {
+		t.callback(ev)
+	}
+
+	select {
+	case t.events <- ev:
+	default:
+	}
+
+	return ev, true
+}
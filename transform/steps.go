@@ -0,0 +1,282 @@
+package transform
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/evcc-io/optimizer/client"
+	"github.com/evcc-io/optimizer/smooth"
+	"github.com/evcc-io/optimizer/units"
+)
+
+// Units are the units of a request given in other units than W, Wh and currency unit per Wh,
+// zero for the base units.
+type Units struct {
+	Energy units.Energy
+	Power  units.Power
+	Price  units.Price
+}
+
+// NormalizeUnits converts a request given in u to the base units: energies of the series,
+// battery states of charge, goals and charge demands, grid allowances and counters to date and
+// the warm start, powers of the battery, grid, efficiency curve, peak price curve and combined
+// heat and power limits, and prices of the series, stored energy and grid tariffs. Prices per
+// power, the demand rate and peak prices, are converted by the power unit, emissions per energy
+// by the energy unit. Requests with penalties are rejected if energies or prices are converted,
+// their coefficients have no unit to convert.
+func NormalizeUnits(u Units) Func {
+	return func(req *client.OptimizationInput) error {
+		scale := func(f float64, values ...*float32) {
+			if f == 0 || f == 1 {
+				return
+			}
+			for _, v := range values {
+				*v = float32(float64(*v) * f)
+			}
+		}
+		scaleSeries := func(f float64, series ...[]float32) {
+			for _, s := range series {
+				for t := range s {
+					scale(f, &s[t])
+				}
+			}
+		}
+
+		energy, power, price := float64(u.Energy), float64(u.Power), float64(u.Price)
+
+		// per power and per energy quantities scale inversely
+		var perPower, perEnergy float64
+		if power != 0 {
+			perPower = 1 / power
+		}
+		if energy != 0 {
+			perEnergy = 1 / energy
+		}
+
+		converted := func(f float64) bool { return f != 0 && f != 1 }
+		if len(req.Penalties) > 0 && (converted(energy) || converted(price)) {
+			return errors.New("penalties cannot be converted to base units")
+		}
+
+		ts := &req.TimeSeries
+		ts.Ft, ts.Gt, ts.QH, ts.NCommit = slices.Clone(ts.Ft), slices.Clone(ts.Gt), slices.Clone(ts.QH), slices.Clone(ts.NCommit)
+		ts.PN, ts.PE, ts.PG, ts.WE = slices.Clone(ts.PN), slices.Clone(ts.PE), slices.Clone(ts.PG), slices.Clone(ts.WE)
+		ts.PMaxImp, ts.EmN = slices.Clone(ts.PMaxImp), slices.Clone(ts.EmN)
+		scaleSeries(energy, ts.Ft, ts.Gt, ts.QH, ts.NCommit)
+		scaleSeries(price, ts.PN, ts.PE, ts.PG, ts.WE)
+		scaleSeries(power, ts.PMaxImp)
+		scaleSeries(perEnergy, ts.EmN)
+
+		g := &req.Grid
+		scale(power, &g.PMaxImp, &g.PMaxExp, &g.PConn, &g.PPeakToDate)
+		scale(energy, &g.EImpTier, &g.EImpToDate, &g.EExpCap, &g.EExpToDate, &g.ENetToDate)
+		scale(price, &g.PrcEDev, &g.PrcEExcTier, &g.PrcENetImp, &g.PrcENetExp)
+		scale(perPower, &g.PrcPExcImp)
+		g.PrcPPeak = slices.Clone(g.PrcPPeak)
+		for k := range g.PrcPPeak {
+			scale(power, &g.PrcPPeak[k].Power)
+			scale(perPower, &g.PrcPPeak[k].Price)
+		}
+
+		req.Batteries = slices.Clone(req.Batteries)
+		for i := range req.Batteries {
+			b := &req.Batteries[i]
+			b.SGoal, b.PDemand = slices.Clone(b.SGoal), slices.Clone(b.PDemand)
+			scale(energy, &b.SMin, &b.SMax, &b.SInitial, &b.SCapacity, &b.SInitialStddev)
+			scaleSeries(energy, b.SGoal, b.PDemand)
+			scale(power, &b.CMin, &b.CMax, &b.DMax)
+			scale(price, &b.PA)
+
+			b.CEtaCurve, b.DEtaCurve = slices.Clone(b.CEtaCurve), slices.Clone(b.DEtaCurve)
+			for _, curve := range [][]client.EfficiencyPoint{b.CEtaCurve, b.DEtaCurve} {
+				for k := range curve {
+					scale(power, &curve[k].Power)
+				}
+			}
+
+			b.Departures = slices.Clone(b.Departures)
+			for k := range b.Departures {
+				scale(energy, &b.Departures[k].SGoal)
+			}
+			if b.Preconditioning != nil {
+				pre := *b.Preconditioning
+				scale(power, &pre.Power)
+				b.Preconditioning = &pre
+			}
+		}
+
+		if req.Chp != nil {
+			chp := *req.Chp
+			scale(power, &chp.PElMax, &chp.PElMin, &chp.PBoilerMax, &chp.PHeatElMax)
+			req.Chp = &chp
+		}
+
+		if req.WarmStart != nil {
+			ws := *req.WarmStart
+			ws.GridImport, ws.GridExport = slices.Clone(ws.GridImport), slices.Clone(ws.GridExport)
+			scaleSeries(energy, ws.GridImport, ws.GridExport)
+			ws.Batteries = slices.Clone(ws.Batteries)
+			for k := range ws.Batteries {
+				wb := &ws.Batteries[k]
+				wb.ChargingPower, wb.DischargingPower = slices.Clone(wb.ChargingPower), slices.Clone(wb.DischargingPower)
+				scaleSeries(energy, wb.ChargingPower, wb.DischargingPower)
+			}
+			req.WarmStart = &ws
+		}
+
+		return nil
+	}
+}
+
+// Densify expands series given as a single value to all intervals and fills omitted forecast
+// and demand with zeros, e.g. for flat tariffs or pure arbitrage.
+func Densify() Func {
+	return func(req *client.OptimizationInput) error {
+		n := len(req.TimeSeries.Dt)
+		expand := func(s []float32) []float32 {
+			if len(s) == 1 && n > 1 {
+				return slices.Repeat(s, n)
+			}
+			return s
+		}
+
+		ts := &req.TimeSeries
+		ts.Ft, ts.Gt, ts.PN, ts.PE, ts.EmN = expand(ts.Ft), expand(ts.Gt), expand(ts.PN), expand(ts.PE), expand(ts.EmN)
+		ts.PMaxImp, ts.RCurt, ts.WE, ts.PG, ts.QH = expand(ts.PMaxImp), expand(ts.RCurt), expand(ts.WE), expand(ts.PG), expand(ts.QH)
+
+		if len(ts.Ft) == 0 {
+			ts.Ft = make([]float32, n)
+		}
+		if len(ts.Gt) == 0 {
+			ts.Gt = make([]float32, n)
+		}
+
+		req.Batteries = slices.Clone(req.Batteries)
+		for i := range req.Batteries {
+			b := &req.Batteries[i]
+			b.PDemand, b.CEtaSeries = expand(b.PDemand), expand(b.CEtaSeries)
+			if len(b.Available) == 1 && n > 1 {
+				b.Available = slices.Repeat(b.Available, n)
+			}
+		}
+
+		return nil
+	}
+}
+
+// TrimHorizon restricts the request to the intervals starting within d. Departures and
+// preconditioning beyond the restricted horizon are dropped.
+func TrimHorizon(d time.Duration) Func {
+	return func(req *client.OptimizationInput) error {
+		var k int
+		var elapsed time.Duration
+		for _, dt := range req.TimeSeries.Dt {
+			if elapsed >= d {
+				break
+			}
+			elapsed += time.Duration(dt) * time.Second
+			k++
+		}
+		if k == 0 {
+			return errors.New("no interval within horizon")
+		}
+
+		cut := func(s []float32) []float32 {
+			if len(s) > k {
+				return s[:k:k]
+			}
+			return s
+		}
+
+		ts := &req.TimeSeries
+		ts.Dt = ts.Dt[:k:k]
+		ts.Ft, ts.Gt, ts.NCommit, ts.PMaxImp, ts.QH = cut(ts.Ft), cut(ts.Gt), cut(ts.NCommit), cut(ts.PMaxImp), cut(ts.QH)
+		ts.PN, ts.PE, ts.EmN, ts.RCurt, ts.WE, ts.PG = cut(ts.PN), cut(ts.PE), cut(ts.EmN), cut(ts.RCurt), cut(ts.WE), cut(ts.PG)
+
+		req.Batteries = slices.Clone(req.Batteries)
+		for i := range req.Batteries {
+			b := &req.Batteries[i]
+			b.PDemand, b.SGoal, b.CEtaSeries = cut(b.PDemand), cut(b.SGoal), cut(b.CEtaSeries)
+			if len(b.Available) > k {
+				b.Available = b.Available[:k:k]
+			}
+			b.Departures = slices.DeleteFunc(slices.Clone(b.Departures), func(d client.Departure) bool { return d.T >= k })
+			if pre := b.Preconditioning; pre != nil && pre.TEnd > k {
+				b.Preconditioning = nil
+			}
+		}
+
+		return nil
+	}
+}
+
+// ExtendPrices extends import and export prices and emissions not covering all intervals by
+// the value of the interval starting a day earlier, or by the last value for horizons shorter
+// than a day, e.g. for tariffs published until midnight.
+func ExtendPrices() Func {
+	return func(req *client.OptimizationInput) error {
+		dt := req.TimeSeries.Dt
+
+		// start of each interval [s]
+		starts := make([]int, len(dt))
+		for t := 1; t < len(dt); t++ {
+			starts[t] = starts[t-1] + dt[t-1]
+		}
+
+		extend := func(s []float32) []float32 {
+			if len(s) == 0 || len(s) >= len(dt) {
+				return s
+			}
+
+			s = slices.Clone(s)
+			for t := len(s); t < len(dt); t++ {
+				prev := t - 1
+				if day := starts[t] - 24*3600; day >= 0 {
+					// the interval covering the same time of the previous day
+					k, ok := slices.BinarySearch(starts, day)
+					if !ok {
+						k--
+					}
+					prev = k
+				}
+				s = append(s, s[prev])
+			}
+			return s
+		}
+
+		ts := &req.TimeSeries
+		ts.PN, ts.PE, ts.EmN = extend(ts.PN), extend(ts.PE), extend(ts.EmN)
+
+		return nil
+	}
+}
+
+// Smooth averages the forecast and demand with a centered moving average of window intervals,
+// see smooth.MovingAverage, e.g. for noisy forecasts.
+func Smooth(window int) Func {
+	filter := smooth.MovingAverage(window)
+
+	return func(req *client.OptimizationInput) error {
+		apply := func(s []float32) []float32 {
+			if len(s) == 0 {
+				return s
+			}
+
+			in := make([]float64, len(s))
+			for t, v := range s {
+				in[t] = float64(v)
+			}
+
+			out := make([]float32, len(s))
+			for t, v := range filter(in) {
+				out[t] = float32(v)
+			}
+			return out
+		}
+
+		req.TimeSeries.Ft, req.TimeSeries.Gt = apply(req.TimeSeries.Ft), apply(req.TimeSeries.Gt)
+
+		return nil
+	}
+}
@@ -0,0 +1,112 @@
+// Package transform prepares optimization requests in a pipeline of named steps, making the
+// preprocessing composable instead of hard-wired. Custom steps are inserted between the
+// provided ones:
+//
+//	p := transform.New(
+//		transform.Step{Name: "units", Func: transform.NormalizeUnits(transform.Units{Energy: units.KWh, Power: units.KW, Price: units.PerKWh})},
+//		transform.Step{Name: "densify", Func: transform.Densify()},
+//		transform.Step{Name: "prices", Func: transform.ExtendPrices()},
+//		transform.Step{Name: "trim", Func: transform.TrimHorizon(24 * time.Hour)},
+//	)
+//	err := p.InsertAfter("densify", transform.Step{Name: "clip", Func: clipForecast})
+//	c, err := client.New(uri, client.WithTransform(p.Apply))
+package transform
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/evcc-io/optimizer/client"
+)
+
+// Func transforms a request in place, returning an error to reject it.
+type Func func(req *client.OptimizationInput) error
+
+// Step is a named transform of a pipeline.
+type Step struct {
+	Name string
+	Func Func
+}
+
+// Pipeline applies its steps in order. Steps are addressed by name, the first step of a name
+// if names repeat. Pipelines are not safe for concurrent modification, modify them before use.
+type Pipeline struct {
+	steps []Step
+}
+
+// New creates a pipeline of steps.
+func New(steps ...Step) *Pipeline {
+	return &Pipeline{steps: slices.Clone(steps)}
+}
+
+// Names returns the names of the steps in order.
+func (p *Pipeline) Names() []string {
+	names := make([]string, len(p.steps))
+	for i, s := range p.steps {
+		names[i] = s.Name
+	}
+	return names
+}
+
+func (p *Pipeline) index(name string) (int, error) {
+	i := slices.IndexFunc(p.steps, func(s Step) bool { return s.Name == name })
+	if i < 0 {
+		return 0, fmt.Errorf("step %q not found", name)
+	}
+	return i, nil
+}
+
+// Append adds steps at the end.
+func (p *Pipeline) Append(steps ...Step) {
+	p.steps = append(p.steps, steps...)
+}
+
+// InsertBefore adds steps before the step of name.
+func (p *Pipeline) InsertBefore(name string, steps ...Step) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+	p.steps = slices.Insert(p.steps, i, steps...)
+	return nil
+}
+
+// InsertAfter adds steps after the step of name.
+func (p *Pipeline) InsertAfter(name string, steps ...Step) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+	p.steps = slices.Insert(p.steps, i+1, steps...)
+	return nil
+}
+
+// Replace replaces the transform of the step of name.
+func (p *Pipeline) Replace(name string, fn Func) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+	p.steps[i].Func = fn
+	return nil
+}
+
+// Remove removes the step of name.
+func (p *Pipeline) Remove(name string) error {
+	i, err := p.index(name)
+	if err != nil {
+		return err
+	}
+	p.steps = slices.Delete(p.steps, i, i+1)
+	return nil
+}
+
+// Apply applies the steps to req in order, stopping at the first error.
+func (p *Pipeline) Apply(req *client.OptimizationInput) error {
+	for _, s := range p.steps {
+		if err := s.Func(req); err != nil {
+			return fmt.Errorf("%s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package units provides typed power, energy and price quantities. Requests and results use
+// W, Wh per interval and currency unit per Wh, values in other units are converted by
+// multiplying with the unit constants and read back by the unit methods:
+//
+//	p := 11 * units.KW
+//	price := 0.30 * units.PerKWh
+//	fmt.Println(p.W(), price.PerWh()) // 11000 0.0003
+package units
+
+import "time"
+
+// Power is a power [W].
+type Power float64
+
+const (
+	W  Power = 1
+	KW Power = 1000
+)
+
+// W returns the power in W.
+func (p Power) W() float64 {
+	return float64(p)
+}
+
+// KW returns the power in kW.
+func (p Power) KW() float64 {
+	return float64(p / KW)
+}
+
+// Over returns the energy of the power sustained for d.
+func (p Power) Over(d time.Duration) Energy {
+	return Energy(float64(p) * d.Hours())
+}
+
+// Energy is an energy [Wh].
+type Energy float64
+
+const (
+	Wh  Energy = 1
+	KWh Energy = 1000
+)
+
+// Wh returns the energy in Wh.
+func (e Energy) Wh() float64 {
+	return float64(e)
+}
+
+// KWh returns the energy in kWh.
+func (e Energy) KWh() float64 {
+	return float64(e / KWh)
+}
+
+// Per returns the average power of the energy spread over d.
+func (e Energy) Per(d time.Duration) Power {
+	return Power(float64(e) / d.Hours())
+}
+
+// Price is a price per energy [currency unit/Wh].
+type Price float64
+
+const (
+	PerWh  Price = 1
+	PerKWh Price = 1. / 1000
+	PerMWh Price = 1. / 1000000
+)
+
+// PerWh returns the price per Wh.
+func (p Price) PerWh() float64 {
+	return float64(p)
+}
+
+// PerKWh returns the price per kWh.
+func (p Price) PerKWh() float64 {
+	return float64(p / PerKWh)
+}
+
+// Cost returns the cost of the energy at the price [currency unit].
+func (p Price) Cost(e Energy) float64 {
+	return float64(p) * float64(e)
+}
+
+// Energies converts energies per interval of a request or result [Wh].
+func Energies(series []float32) []Energy {
+	res := make([]Energy, len(series))
+	for t, v := range series {
+		res[t] = Energy(v)
+	}
+	return res
+}
+
+// Powers converts energies per interval of a request or result [Wh] into average powers, dt
+// being the interval durations in seconds.
+func Powers(series []float32, dt []int) []Power {
+	res := make([]Power, min(len(series), len(dt)))
+	for t := range res {
+		res[t] = Energy(series[t]).Per(time.Duration(dt[t]) * time.Second)
+	}
+	return res
+}
+
+// Prices converts prices per interval of a request [currency unit/Wh].
+func Prices(series []float32) []Price {
+	res := make([]Price, len(series))
+	for t, v := range series {
+		res[t] = Price(v)
+	}
+	return res
+}